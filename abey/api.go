@@ -25,9 +25,14 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/abeychain/go-abey/abey/chainstats"
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/diskusage"
 	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/consensus/election"
 	"github.com/abeychain/go-abey/core"
 	"github.com/abeychain/go-abey/core/rawdb"
 	"github.com/abeychain/go-abey/core/state"
@@ -89,6 +94,93 @@ func (api *PublicAbeychainAPI) GetCurrentState() map[string]interface{} {
 	return api.e.agent.GetCommitteeStatus()
 }
 
+// ClockDrift returns the last measured drift of the system clock against the
+// configured NTP server. Drifts beyond the threshold get locally produced blocks
+// and fruits rejected by the network.
+func (api *PublicAbeychainAPI) ClockDrift() (map[string]interface{}, error) {
+	if api.e.clock == nil {
+		return nil, errors.New("clock drift monitoring disabled")
+	}
+	status := api.e.clock.Status()
+	result := map[string]interface{}{
+		"server":    status.Server,
+		"drift":     status.Drift.String(),
+		"driftMs":   int64(status.Drift / time.Millisecond),
+		"threshold": status.Threshold.String(),
+		"healthy":   status.Healthy,
+		"checked":   nil,
+	}
+	if !status.Checked.IsZero() {
+		result["checked"] = status.Checked.Unix()
+	}
+	if status.Error != "" {
+		result["error"] = status.Error
+	}
+	return result, nil
+}
+
+// DiskUsage returns the size and daily growth of the chain data and the state,
+// the free space left on the volume holding them and the projected number of
+// days until it runs full.
+func (api *PublicAbeychainAPI) DiskUsage() (*diskusage.Status, error) {
+	if api.e.disk == nil {
+		return nil, errors.New("disk usage monitoring disabled")
+	}
+	status := api.e.disk.Status()
+	return &status, nil
+}
+
+// FruitLag returns how far the latest fruit included in the snail chain lags
+// behind the fast head, in blocks and in fast block time.
+func (api *PublicAbeychainAPI) FruitLag() FruitLagStatus {
+	return api.e.fruitLag.Status()
+}
+
+// ChainStats returns the daily statistics of the fast and snail chains for the
+// given number of days up to today (UTC), 7 if unspecified. Days without blocks
+// are omitted.
+func (api *PublicAbeychainAPI) ChainStats(days *int) []*chainstats.Stats {
+	n := 7
+	if days != nil {
+		n = *days
+	}
+	return api.e.stats.Stats(n)
+}
+
+// GetEpochRewardSummary returns the committee reward summary of an epoch: the
+// total and per-validator rewards, the delegator counts and the effective APR.
+func (api *PublicAbeychainAPI) GetEpochRewardSummary(epochID uint64) (map[string]interface{}, error) {
+	summary := api.e.blockchain.GetEpochRewardSummary(epochID)
+	if summary == nil {
+		return nil, fmt.Errorf("no reward summary of epoch %d", epochID)
+	}
+	return summary.ToJSON(), nil
+}
+
+// GetRotationPause returns the rotation pause recorded for a committee, if any.
+func (api *PublicAbeychainAPI) GetRotationPause(committeeID uint64) (*types.RotationPause, error) {
+	return api.e.election.RotationPause(committeeID)
+}
+
+// GetCommitteeHistory returns the recorded starts, stops, switchovers and
+// member updates of the committees from one epoch to another, both inclusive.
+func (api *PublicAbeychainAPI) GetCommitteeHistory(fromEpoch, toEpoch uint64) ([]*election.CommitteeRecord, error) {
+	return api.e.election.CommitteeHistory(fromEpoch, toEpoch)
+}
+
+// RotationPauseHash returns the hash validators sign with their committee keys
+// to approve postponing the switch from a committee up to the given fast block.
+// The signatures are submitted to the pauseRotation method of the staking
+// contract after TIP14.
+func (api *PublicAbeychainAPI) RotationPauseHash(committeeID uint64, until uint64, reason string) common.Hash {
+	pause := &types.RotationPause{
+		CommitteeID: new(big.Int).SetUint64(committeeID),
+		Until:       new(big.Int).SetUint64(until),
+		Reason:      reason,
+	}
+	return pause.SigHash(api.e.blockchain.Config().ChainID)
+}
+
 // Hashrate returns the POW hashrate
 func (api *PublicAbeychainAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().HashRate())
@@ -312,6 +404,12 @@ func (api *PrivateMinerAPI) GetHashRate() uint64 {
 	return uint64(api.e.Miner().HashRate())
 }
 
+// GetStaleReport returns the locally mined snail blocks and fruits that ended
+// up non-canonical, together with the resulting orphan rates.
+func (api *PrivateMinerAPI) GetStaleReport() *miner.StaleReport {
+	return api.e.Miner().StaleReport()
+}
+
 // PrivateAdminAPI is the collection of Abeychain full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -405,6 +503,36 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AllowDeepReorg allows or forbids snail chain reorgs beyond the finality
+// barrier. It should only be enabled to recover from a known minority fork.
+func (api *PrivateAdminAPI) AllowDeepReorg(allow bool) bool {
+	api.abey.SnailBlockChain().SetFinalityOverride(allow)
+	return true
+}
+
+// PublicCommitteeAPI provides an API to monitor the part the node plays in
+// the committee.
+type PublicCommitteeAPI struct {
+	e *Abeychain
+}
+
+// NewPublicCommitteeAPI creates a new committee monitoring API.
+func NewPublicCommitteeAPI(e *Abeychain) *PublicCommitteeAPI {
+	return &PublicCommitteeAPI{e}
+}
+
+// SelfStatus reports whether the node is a member of the current committee,
+// the last fast block it signed, its round participation over the given
+// number of recent fast blocks (100 if unspecified) and the committee switch
+// pending, if any.
+func (api *PublicCommitteeAPI) SelfStatus(window *uint64) *CommitteeSelfStatus {
+	n := uint64(defaultParticipationWindow)
+	if window != nil {
+		n = *window
+	}
+	return api.e.agent.SelfStatus(n)
+}
+
 // PublicDebugAPI is the collection of Abeychain full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -493,6 +621,26 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// GetSideHeads returns the tips of the fast side chains currently stored,
+// highest first. Side-chain blocks are pruned once they fall out of the
+// retention window below the head.
+func (api *PrivateDebugAPI) GetSideHeads() []core.SideHead {
+	return api.abey.BlockChain().SideHeads()
+}
+
+// DbSchema returns the key families stored in the chain database, with their
+// prefixes and layouts.
+func (api *PrivateDebugAPI) DbSchema() []abeydb.KeySchema {
+	return rawdb.DatabaseSchema()
+}
+
+// GetSnailSideHeads returns the tips of the snail side chains currently stored,
+// highest first. Side-chain blocks are pruned once they fall beyond the finality
+// barrier, or out of the default retention window if none is set.
+func (api *PrivateDebugAPI) GetSnailSideHeads() []core.SideHead {
+	return api.abey.SnailBlockChain().SideHeads()
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/common"
@@ -36,6 +37,7 @@ import (
 	"github.com/abeychain/go-abey/event"
 	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rpc"
+	"github.com/abeychain/go-abey/tracing"
 )
 
 // ABEYAPIBackend implements ethapi.Backend for full nodes
@@ -49,6 +51,21 @@ func (b *ABEYAPIBackend) ChainConfig() *params.ChainConfig {
 	return b.abey.chainConfig
 }
 
+// RPCEVMTimeout returns the time after which RPC calls into the EVM are aborted.
+func (b *ABEYAPIBackend) RPCEVMTimeout() time.Duration {
+	return b.abey.config.RPCEVMTimeout
+}
+
+// RPCGasCap returns the gas allowance cap of RPC calls into the EVM.
+func (b *ABEYAPIBackend) RPCGasCap() uint64 {
+	return b.abey.config.RPCGasCap
+}
+
+// RPCStateReadLimit returns the state read budget of RPC calls into the EVM.
+func (b *ABEYAPIBackend) RPCStateReadLimit() uint64 {
+	return b.abey.config.RPCStateReadLimit
+}
+
 // CurrentBlock return the fast chain current Block
 func (b *ABEYAPIBackend) CurrentBlock() *types.Block {
 	return b.abey.blockchain.CurrentBlock()
@@ -185,6 +202,13 @@ func (b *ABEYAPIBackend) GetFruit(ctx context.Context, fastblockHash common.Hash
 	return b.abey.snailblockchain.GetFruit(fastblockHash), nil
 }
 
+// GetSnailBlockByFastHash returns the snail block including the fruit of the
+// given fast block and the fruit's index in it.
+func (b *ABEYAPIBackend) GetSnailBlockByFastHash(ctx context.Context, fastblockHash common.Hash) (*types.SnailBlock, uint64, error) {
+	block, index := b.abey.snailblockchain.GetFruitByFastHash(fastblockHash)
+	return block, index, nil
+}
+
 // GetReceipts returns the Receipt details by txhash
 func (b *ABEYAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
 	if number := rawdb.ReadHeaderNumber(b.abey.chainDb, hash); number != nil {
@@ -193,6 +217,15 @@ func (b *ABEYAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (typ
 	return nil, nil
 }
 
+// GetReceipt returns the receipt of the transaction at the given index of a block.
+func (b *ABEYAPIBackend) GetReceipt(ctx context.Context, hash common.Hash, index uint64) (*types.Receipt, error) {
+	receipts, err := b.GetReceipts(ctx, hash)
+	if err != nil || uint64(len(receipts)) <= index {
+		return nil, err
+	}
+	return receipts[index], nil
+}
+
 // GetLogs returns the logs by txhash
 func (b *ABEYAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	number := rawdb.ReadHeaderNumber(b.abey.chainDb, hash)
@@ -315,13 +348,42 @@ func (b *ABEYAPIBackend) GetCommittee(number rpc.BlockNumber) (map[string]interf
 	return b.abey.election.GetCommitteeById(big.NewInt(number.Int64())), nil
 }
 
+// GetCommitteeProof returns the committee of a fast block along with the data
+// needed to verify it starting from the checkpoint block, or from genesis if
+// the checkpoint is nil.
+func (b *ABEYAPIBackend) GetCommitteeProof(ctx context.Context, number rpc.BlockNumber, checkpoint *big.Int) (*types.CommitteeProof, error) {
+	header, err := b.HeaderByNumber(ctx, number)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return b.abey.election.GetCommitteeProof(header.Number, checkpoint)
+}
+
+// VerifyPbftSign returns the committee member who made the sign, nil if the
+// signer is not a member of the committee at the sign's height.
+func (b *ABEYAPIBackend) VerifyPbftSign(sign *types.PbftSign) (*types.CommitteeMember, error) {
+	return b.abey.election.VerifySign(sign)
+}
+
 func (b *ABEYAPIBackend) GetCurrentCommitteeNumber() *big.Int {
 	return b.abey.election.GetCurrentCommitteeNumber()
 }
 
 // SendTx returns nil by success to add local txpool
 func (b *ABEYAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.abey.txPool.AddLocal(signedTx)
+	if b.abey.config.ReadOnly {
+		return ErrReadOnly
+	}
+	_, span := tracing.Start(ctx, "txpool.add", tracing.Attr("tx", signedTx.Hash()))
+	defer span.End()
+
+	err := b.abey.txPool.AddLocal(signedTx)
+	if err != nil {
+		span.SetError(err)
+		return err
+	}
+	tracing.Remember(signedTx.Hash(), span.Context())
+	return nil
 }
 
 // GetPoolTransactions returns Transactions by pending state in txpool
@@ -356,11 +418,25 @@ func (b *ABEYAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.abey.TxPool().Content()
 }
 
+func (b *ABEYAPIBackend) TxPoolOldest(n int) map[common.Address][]*core.TxAge {
+	return b.abey.TxPool().OldestQueued(n)
+}
+
 // SubscribeNewTxsEvent returns the subscript event of new tx
 func (b *ABEYAPIBackend) SubscribeNewTxsEvent(ch chan<- types.NewTxsEvent) event.Subscription {
 	return b.abey.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+// SubscribeDroppedTxsEvent returns the subscript event of txs evicted from the pool
+func (b *ABEYAPIBackend) SubscribeDroppedTxsEvent(ch chan<- types.DroppedTxsEvent) event.Subscription {
+	return b.abey.TxPool().SubscribeDroppedTxsEvent(ch)
+}
+
+// SubscribeNewFruitEvent returns the subscript event of fruits accepted into the pool
+func (b *ABEYAPIBackend) SubscribeNewFruitEvent(ch chan<- types.NewFruitsEvent) event.Subscription {
+	return b.abey.SnailPool().SubscribeNewFruitEvent(ch)
+}
+
 // Downloader returns the fast downloader
 func (b *ABEYAPIBackend) Downloader() *downloader.Downloader {
 	return b.abey.Downloader()
@@ -0,0 +1,190 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/rpc"
+)
+
+var errInvalidSampleStep = errors.New("sample step must be positive")
+
+// hashFetcher retrieves the canonical block hash at the given height.
+type hashFetcher func(ctx context.Context, number uint64) (common.Hash, error)
+
+// ChainDivergence describes how far the local and a remote chain agree.
+type ChainDivergence struct {
+	LocalHead       hexutil.Uint64  `json:"localHead"`
+	RemoteHead      hexutil.Uint64  `json:"remoteHead"`
+	Checked         int             `json:"checked"`
+	Diverged        bool            `json:"diverged"`
+	LastCommon      *hexutil.Uint64 `json:"lastCommon"`
+	FirstDivergence *hexutil.Uint64 `json:"firstDivergence,omitempty"`
+	LocalHash       *common.Hash    `json:"localHash,omitempty"`
+	RemoteHash      *common.Hash    `json:"remoteHash,omitempty"`
+}
+
+// ChainComparison is the result of comparing both local chains against a remote node.
+type ChainComparison struct {
+	Fast  *ChainDivergence `json:"fast"`
+	Snail *ChainDivergence `json:"snail"`
+}
+
+// remoteBlock is the subset of a remote block needed for comparison.
+type remoteBlock struct {
+	Number *hexutil.Big `json:"number"`
+	Hash   common.Hash  `json:"hash"`
+}
+
+// CompareChain samples the canonical fast and snail chains every everyN blocks
+// against the node reachable at url and reports the first divergent block.
+func (api *PrivateAdminAPI) CompareChain(ctx context.Context, url string, everyN uint64) (*ChainComparison, error) {
+	if everyN == 0 {
+		return nil, errInvalidSampleStep
+	}
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	fastchain, snailchain := api.abey.BlockChain(), api.abey.SnailBlockChain()
+	fast, err := compareRemoteChain(ctx, client, "abey_getBlockByNumber", fastchain.CurrentHeader().Number.Uint64(), everyN,
+		func(ctx context.Context, number uint64) (common.Hash, error) {
+			header := fastchain.GetHeaderByNumber(number)
+			if header == nil {
+				return common.Hash{}, fmt.Errorf("local block #%d not found", number)
+			}
+			return header.Hash(), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	snail, err := compareRemoteChain(ctx, client, "abey_getSnailBlockByNumber", snailchain.CurrentHeader().Number.Uint64(), everyN,
+		func(ctx context.Context, number uint64) (common.Hash, error) {
+			header := snailchain.GetHeaderByNumber(number)
+			if header == nil {
+				return common.Hash{}, fmt.Errorf("local snail block #%d not found", number)
+			}
+			return header.Hash(), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &ChainComparison{Fast: fast, Snail: snail}, nil
+}
+
+// compareRemoteChain compares a local chain with the remote one served by method.
+func compareRemoteChain(ctx context.Context, client *rpc.Client, method string, localHead, everyN uint64, local hashFetcher) (*ChainDivergence, error) {
+	fetch := func(ctx context.Context, arg interface{}) (*remoteBlock, error) {
+		var block *remoteBlock
+		if err := client.CallContext(ctx, &block, method, arg, false); err != nil {
+			return nil, err
+		}
+		if block == nil || block.Number == nil {
+			return nil, fmt.Errorf("remote block %v not found", arg)
+		}
+		return block, nil
+	}
+	head, err := fetch(ctx, "latest")
+	if err != nil {
+		return nil, err
+	}
+	remote := func(ctx context.Context, number uint64) (common.Hash, error) {
+		block, err := fetch(ctx, hexutil.EncodeUint64(number))
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return block.Hash, nil
+	}
+	return compareHashes(ctx, localHead, head.Number.ToInt().Uint64(), everyN, local, remote)
+}
+
+// compareHashes samples both chains every everyN blocks up to the lower head and
+// bisects between the last matching and the first mismatching sample to find
+// the exact block at which the chains diverge.
+func compareHashes(ctx context.Context, localHead, remoteHead, everyN uint64, local, remote hashFetcher) (*ChainDivergence, error) {
+	result := &ChainDivergence{
+		LocalHead:  hexutil.Uint64(localHead),
+		RemoteHead: hexutil.Uint64(remoteHead),
+	}
+	same := func(number uint64) (bool, common.Hash, common.Hash, error) {
+		result.Checked++
+		lhash, err := local(ctx, number)
+		if err != nil {
+			return false, lhash, common.Hash{}, err
+		}
+		rhash, err := remote(ctx, number)
+		if err != nil {
+			return false, lhash, rhash, err
+		}
+		return lhash == rhash, lhash, rhash, nil
+	}
+	top := localHead
+	if remoteHead < top {
+		top = remoteHead
+	}
+	var (
+		lastCommon = int64(-1)
+		number     uint64
+		lastCheck  bool
+	)
+	for !lastCheck {
+		if number >= top {
+			number, lastCheck = top, true
+		}
+		match, lhash, rhash, err := same(number)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			// Bisect between the last common sample and the mismatch
+			lo, hi := lastCommon, int64(number)
+			for hi-lo > 1 {
+				mid := lo + (hi-lo)/2
+				ok, l, r, err := same(uint64(mid))
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					lo = mid
+				} else {
+					hi, lhash, rhash = mid, l, r
+				}
+			}
+			lastCommon = lo
+			first := hexutil.Uint64(hi)
+			result.Diverged, result.FirstDivergence = true, &first
+			result.LocalHash, result.RemoteHash = &lhash, &rhash
+			log.Warn("Chain divergence detected", "number", hi, "local", lhash, "remote", rhash)
+			break
+		}
+		lastCommon = int64(number)
+		number += everyN
+	}
+	if lastCommon >= 0 {
+		last := hexutil.Uint64(lastCommon)
+		result.LastCommon = &last
+	}
+	return result, nil
+}
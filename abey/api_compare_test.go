@@ -0,0 +1,73 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/common"
+)
+
+// testHashes returns a fetcher serving a chain that forks off at the given height.
+func testHashes(fork uint64, seed byte) hashFetcher {
+	return func(ctx context.Context, number uint64) (common.Hash, error) {
+		hash := common.BigToHash(new(big.Int).SetUint64(number))
+		if number >= fork {
+			hash[0] = seed
+		}
+		return hash, nil
+	}
+}
+
+func TestCompareHashes(t *testing.T) {
+	tests := []struct {
+		local, remote uint64
+		fork          uint64
+		step          uint64
+		diverged      bool
+		lastCommon    uint64
+	}{
+		{local: 100, remote: 120, fork: 1000, step: 7, diverged: false, lastCommon: 100},
+		{local: 120, remote: 100, fork: 1000, step: 200, diverged: false, lastCommon: 100},
+		{local: 100, remote: 100, fork: 43, step: 10, diverged: true, lastCommon: 42},
+		{local: 100, remote: 100, fork: 40, step: 10, diverged: true, lastCommon: 39},
+		{local: 100, remote: 100, fork: 0, step: 10, diverged: true},
+		{local: 100, remote: 100, fork: 100, step: 30, diverged: true, lastCommon: 99},
+	}
+	for i, tt := range tests {
+		res, err := compareHashes(context.Background(), tt.local, tt.remote, tt.step, testHashes(tt.fork, 1), testHashes(tt.fork, 2))
+		if err != nil {
+			t.Fatalf("test %d: comparison failed: %v", i, err)
+		}
+		if res.Diverged != tt.diverged {
+			t.Errorf("test %d: divergence mismatch: have %v, want %v", i, res.Diverged, tt.diverged)
+			continue
+		}
+		if tt.diverged && uint64(*res.FirstDivergence) != tt.fork {
+			t.Errorf("test %d: first divergence mismatch: have %d, want %d", i, *res.FirstDivergence, tt.fork)
+		}
+		if tt.fork == 0 {
+			if res.LastCommon != nil {
+				t.Errorf("test %d: unexpected common block %d", i, *res.LastCommon)
+			}
+		} else if res.LastCommon == nil || uint64(*res.LastCommon) != tt.lastCommon {
+			t.Errorf("test %d: last common mismatch: have %v, want %d", i, res.LastCommon, tt.lastCommon)
+		}
+	}
+}
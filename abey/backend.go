@@ -28,13 +28,20 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/abeychain/go-abey/abey/chainstats"
 	"github.com/abeychain/go-abey/abey/downloader"
+	"github.com/abeychain/go-abey/abey/fastdownloader"
 	"github.com/abeychain/go-abey/abey/filters"
 	"github.com/abeychain/go-abey/abey/gasprice"
+	"github.com/abeychain/go-abey/abey/snap"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/crash"
+	"github.com/abeychain/go-abey/common/diskusage"
 	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/common/mempressure"
+	"github.com/abeychain/go-abey/common/ntp"
 	"github.com/abeychain/go-abey/consensus"
 	elect "github.com/abeychain/go-abey/consensus/election"
 	ethash "github.com/abeychain/go-abey/consensus/minerva"
@@ -51,11 +58,16 @@ import (
 	"github.com/abeychain/go-abey/miner"
 	"github.com/abeychain/go-abey/node"
 	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/capture"
 	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
 	"github.com/abeychain/go-abey/rpc"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// ErrReadOnly is returned for operations modifying the chain on a read-only node.
+var ErrReadOnly = errors.New("node is in read-only mode")
+
 type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
@@ -105,6 +117,17 @@ type Abeychain struct {
 
 	pbftServer *tbft.Node
 
+	clock  *ntp.Monitor         // System clock drift monitor, nil if disabled
+	memory *mempressure.Monitor // Memory pressure monitor, nil if the limit is unknown
+	disk   *diskusage.Monitor   // Disk usage forecaster, nil if disabled
+
+	fruitLag *fruitLagMonitor // Lag of the snail chain fruits behind the fast head
+
+	rpcCache *rpc.ResponseCache  // Cache of the RPC responses about final chain data, nil if disabled
+	stats    *chainstats.Service // Daily chain statistics aggregator
+
+	alerter *committeeAlerter // Committee participation alerter, nil if disabled
+
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
 
@@ -126,6 +149,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Abeychain, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	// Split the downloader buffers between the snail and fast chain queues
+	if config.DownloaderCache > 0 {
+		downloader.SetBlockCacheMemory(config.DownloaderCache * 1024 * 1024 * 2 / 3)
+		fastdownloader.SetBlockCacheMemory(config.DownloaderCache * 1024 * 1024 / 3)
+	}
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	//chainDb, err := CreateDB(ctx, config, path)
 	if err != nil {
@@ -182,6 +210,14 @@ func New(ctx *node.ServiceContext, config *Config) (*Abeychain, error) {
 	if err != nil {
 		return nil, err
 	}
+	abey.snailblockchain.SetFinality(config.SnailFinality)
+
+	if config.NTPServer != "" {
+		abey.clock = ntp.NewMonitor(config.NTPServer, ntp.DefaultThreshold, ntp.DefaultInterval)
+	}
+	abey.memory = newMemoryMonitor(config, abey.blockchain, abey.snailblockchain)
+	abey.disk = newDiskMonitor(ctx, config, chainDb)
+	abey.rpcCache = newResponseCache(config, chainDb, abey.blockchain, abey.snailblockchain)
 
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
@@ -203,6 +239,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Abeychain, error) {
 	//sv := chain.NewBlockValidator(abey.chainConfig, abey.blockchain, abey.snailblockchain, abey.engine)
 	//abey.snailblockchain.SetValidator(sv)
 
+	// Read-only nodes must not persist the local transactions and fruits
+	if config.ReadOnly {
+		config.TxPool.Journal, config.SnailPool.Journal = "", ""
+	}
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -221,6 +261,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Abeychain, error) {
 	//abey.snailblockchain.Validator().SetElection(abey.election, abey.blockchain)
 
 	abey.engine.SetElection(abey.election)
+	abey.stats = chainstats.New(chainDb, abey.blockchain, abey.snailblockchain, abey.election)
 	abey.engine.SetSnailChainReader(abey.snailblockchain)
 	abey.election.SetEngine(abey.engine)
 
@@ -234,9 +275,28 @@ func New(ctx *node.ServiceContext, config *Config) (*Abeychain, error) {
 		chainDb, abey.agent); err != nil {
 		return nil, err
 	}
+	abey.protocolManager.sealed = newSealedSet(ctx.ResolvePath(sealedJournal), abey.snailblockchain)
+	abey.protocolManager.syncStallTimeout = config.SyncStallTimeout
+	if config.NetCapture != "" {
+		path := ctx.ResolvePath(config.NetCapture)
+		if abey.protocolManager.capture, err = capture.NewRecorder(path); err != nil {
+			return nil, err
+		}
+		log.Warn("Recording received protocol messages", "path", path)
+	}
+	abey.fruitLag = newFruitLagMonitor(abey.blockchain, abey.snailblockchain, abey.protocolManager.downloader.Synchronising, config.FruitLagBlocks, config.FruitLagTime)
+
+	// Describe the node state in crash bundles, without any key material
+	crash.RegisterInfo("head", abey.crashHeadInfo)
+	crash.RegisterInfo("config", func() interface{} {
+		redacted := *config
+		redacted.Genesis, redacted.PrivateKey, redacted.CommitteeKey = nil, nil, nil
+		return redacted
+	})
 	log.Info("end NewProtocolManager")
 	abey.miner = miner.New(abey, abey.chainConfig, abey.EventMux(), abey.engine, abey.election, abey.Config().MineFruit, abey.Config().NodeType, abey.Config().RemoteMine, abey.Config().Mine)
 	abey.miner.SetExtra(makeExtraData(config.ExtraData))
+	abey.miner.SetCoinbaseProof(config.CoinbaseProof)
 
 	committeeKey, err := crypto.ToECDSA(abey.config.CommitteeKey)
 	if err == nil {
@@ -278,6 +338,10 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (abeydb.Dat
 	if db, ok := db.(*abeydb.LDBDatabase); ok {
 		db.Meter("abey/db/chaindata/")
 	}
+	if err := abeydb.Migrate(db, core.Migrations); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -310,6 +374,7 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *ethash.Config, chai
 			DatasetDir:     config.DatasetDir,
 			DatasetsInMem:  config.DatasetsInMem,
 			DatasetsOnDisk: config.DatasetsOnDisk,
+			VerifyWorkers:  config.VerifyWorkers,
 		})
 		engine.SetThreads(-1) // Disable CPU mining
 		return engine
@@ -376,6 +441,11 @@ func (s *Abeychain) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "committee",
+			Version:   "1.0",
+			Service:   NewPublicCommitteeAPI(s),
+			Public:    true,
 		},
 	}...)
 }
@@ -422,6 +492,9 @@ func (s *Abeychain) SetEtherbase(etherbase common.Address) {
 }
 
 func (s *Abeychain) StartMining(local bool) error {
+	if s.config.ReadOnly {
+		return ErrReadOnly
+	}
 	eb, err := s.Etherbase()
 	if err != nil {
 		log.Error("Cannot start mining without coinbase", "err", err)
@@ -474,13 +547,27 @@ func (s *Abeychain) Downloader() *downloader.Downloader { return s.protocolManag
 func (s *Abeychain) Synced() bool                       { return atomic.LoadUint32(&s.protocolManager.acceptTxs) == 1 }
 func (s *Abeychain) ArchiveMode() bool                  { return s.config.NoPruning }
 
+// crashHeadInfo reports the chain heads for crash bundles.
+func (s *Abeychain) crashHeadInfo() interface{} {
+	fast, snail := s.blockchain.CurrentBlock(), s.snailblockchain.CurrentBlock()
+	return map[string]interface{}{
+		"fastNumber":  fast.NumberU64(),
+		"fastHash":    fast.Hash(),
+		"snailNumber": snail.NumberU64(),
+		"snailHash":   snail.Hash(),
+		"progress":    s.Downloader().Progress(),
+	}
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Abeychain) Protocols() []p2p.Protocol {
+	protos := append([]p2p.Protocol{}, s.protocolManager.SubProtocols...)
+	protos = append(protos, snap.MakeProtocols(s.blockchain)...)
 	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+		return protos
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+	return append(protos, s.lesServer.Protocols()...)
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -490,6 +577,28 @@ func (s *Abeychain) Start(srvr *p2p.Server) error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers()
 
+	// Start checking the clock, drifts get blocks and fruits rejected as future ones
+	if s.clock != nil {
+		s.clock.Start()
+	}
+	// Start watching the memory use, shedding caches before the OOM killer strikes
+	if s.memory != nil {
+		s.memory.Start()
+	}
+	// Start forecasting the disk usage, warning weeks before the volume runs full
+	if s.disk != nil {
+		s.disk.Start()
+	}
+	// Start measuring how far the fruits in the snail chain lag behind the fast head
+	s.fruitLag.Start()
+
+	// Drop the cached RPC responses whenever final chain data is rewound
+	if s.rpcCache != nil {
+		go s.responseCacheLoop()
+	}
+	// Start aggregating the daily chain statistics
+	s.stats.Start()
+
 	// Start the RPC service
 	s.netRPCService = abeyapi.NewPublicNetAPI(srvr, s.NetVersion())
 
@@ -503,14 +612,24 @@ func (s *Abeychain) Start(srvr *p2p.Server) error {
 	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
-	s.startPbftServer()
-	if s.pbftServer == nil {
-		log.Error("start pbft server failed.")
-		return errors.New("start pbft server failed.")
+
+	// Read-only nodes never take part in the committee
+	if !s.config.ReadOnly {
+		s.startPbftServer()
+		if s.pbftServer == nil {
+			log.Error("start pbft server failed.")
+			return errors.New("start pbft server failed.")
+		}
+		s.agent.server = s.pbftServer
+		log.Info("", "server", s.agent.server)
+		s.agent.Start()
+
+		// Alert the operator when the node stops taking part in the rounds
+		if s.config.CommitteeAlertURL != "" {
+			s.alerter = newCommitteeAlerter(s.agent, s.config.CommitteeAlertURL, s.config.CommitteeAlertThreshold)
+			s.alerter.Start()
+		}
 	}
-	s.agent.server = s.pbftServer
-	log.Info("", "server", s.agent.server)
-	s.agent.Start()
 
 	s.election.Start()
 
@@ -529,17 +648,35 @@ func (s *Abeychain) Start(srvr *p2p.Server) error {
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Abeychain protocol.
 func (s *Abeychain) Stop() error {
+	s.election.Stop()
+	if s.alerter != nil {
+		s.alerter.Stop()
+	}
 	s.stopPbftServer()
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.snailblockchain.Stop()
 	s.protocolManager.Stop()
+	if s.protocolManager.capture != nil {
+		s.protocolManager.capture.Close()
+	}
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
 	s.txPool.Stop()
 	s.snailPool.Stop()
 	s.miner.Stop()
+	if s.clock != nil {
+		s.clock.Stop()
+	}
+	if s.memory != nil {
+		s.memory.Stop()
+	}
+	if s.disk != nil {
+		s.disk.Stop()
+	}
+	s.fruitLag.Stop()
+	s.stats.Stop()
 	s.eventMux.Stop()
 
 	s.chainDb.Close()
@@ -548,6 +685,70 @@ func (s *Abeychain) Stop() error {
 	return nil
 }
 
+// newMemoryMonitor creates the memory pressure monitor shedding the chain caches
+// and shrinking the downloader buffers, nil if no memory limit is known.
+func newMemoryMonitor(config *Config, blockchain *core.BlockChain, snailchain *chain.SnailBlockChain) *mempressure.Monitor {
+	limit := uint64(config.MemoryLimit) * 1024 * 1024
+	if limit == 0 {
+		if limit = mempressure.SystemLimit(); limit == 0 {
+			log.Warn("Failed to detect memory limit, pressure monitor disabled")
+			return nil
+		}
+	}
+	monitor := mempressure.NewMonitor(limit, mempressure.DefaultInterval)
+	monitor.Register("blockchain", blockchain.PurgeCaches, nil)
+	monitor.Register("snailchain", snailchain.PurgeCaches, nil)
+	monitor.Register("downloader", func() {
+		downloader.ShrinkBlockCache(true)
+		fastdownloader.ShrinkBlockCache(true)
+	}, func() {
+		downloader.ShrinkBlockCache(false)
+		fastdownloader.ShrinkBlockCache(false)
+	})
+	return monitor
+}
+
+// chainPrefixes are the key prefixes of the fast and snail chain data, all
+// other keys of the chain database are accounted to the state.
+var chainPrefixes = []string{"h", "H", "b", "r", "l", "B", "c", "sh", "sH", "sb", "sr", "sl", "sB"}
+
+// newDiskMonitor creates the disk usage forecaster of the chain database,
+// tracking the chain data and the state separately. Nil is returned if it is
+// disabled or the database doesn't live on disk.
+func newDiskMonitor(ctx *node.ServiceContext, config *Config, chainDb abeydb.Database) *diskusage.Monitor {
+	ldb, ok := chainDb.(*abeydb.LDBDatabase)
+	if config.DiskWarnDays <= 0 || !ok {
+		return nil
+	}
+	ranges := make([]util.Range, len(chainPrefixes))
+	for i, prefix := range chainPrefixes {
+		ranges[i] = *util.BytesPrefix([]byte(prefix))
+	}
+	// State trie nodes are keyed by their hash without a prefix, so the sizes are
+	// an estimate: the few of them falling into the chain ranges are miscounted.
+	chainSize := func() (uint64, error) {
+		sizes, err := ldb.LDB().SizeOf(ranges)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(sizes.Sum()), nil
+	}
+	monitor := diskusage.NewMonitor(ldb.Path(), config.DiskWarnDays, diskusage.DefaultInterval)
+	monitor.Register("chain", chainSize)
+	monitor.Register("state", func() (uint64, error) {
+		total, err := diskusage.DirSize(ldb.Path())
+		if err != nil {
+			return 0, err
+		}
+		chain, err := chainSize()
+		if err != nil || chain > total {
+			return 0, err
+		}
+		return total - chain, nil
+	})
+	return monitor
+}
+
 func (s *Abeychain) startPbftServer() error {
 	priv, err := crypto.ToECDSA(s.config.CommitteeKey)
 	if err != nil {
@@ -557,6 +758,8 @@ func (s *Abeychain) startPbftServer() error {
 	cfg := config.DefaultConfig()
 	cfg.P2P.ListenAddress1 = "tcp://0.0.0.0:" + strconv.Itoa(s.config.Port)
 	cfg.P2P.ListenAddress2 = "tcp://0.0.0.0:" + strconv.Itoa(s.config.StandbyPort)
+	cfg.Consensus.SignMessages = s.config.BFTSignMsgs
+	cfg.Consensus.RequireSignedMessages = s.config.BFTRequireSignedMsgs
 
 	n1, err := tbft.NewNode(cfg, "1", priv, s.agent)
 	if err != nil {
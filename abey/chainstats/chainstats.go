@@ -0,0 +1,352 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainstats aggregates daily statistics of the fast and snail chains,
+// persisting them so basic dashboards don't need an external data warehouse.
+package chainstats
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+const (
+	// MaxDays is the maximum number of days returned by a single query.
+	MaxDays = 366
+
+	day           = 24 * 60 * 60 // Length of a day in seconds, the unit of aggregation
+	flushInterval = time.Minute  // Time interval between persisting the running aggregates
+	eventChanSize = 256          // Size of the chain event channels
+)
+
+// statsPrefix + day (uint64 big endian) -> daily aggregate
+var statsPrefix = []byte("chainstats-")
+
+// FastChain is the fast chain the statistics are gathered from.
+type FastChain interface {
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription
+}
+
+// SnailChain is the snail chain the statistics are gathered from.
+type SnailChain interface {
+	SubscribeChainEvent(ch chan<- types.SnailChainEvent) event.Subscription
+}
+
+// CommitteeReader retrieves the committee signing a fast block.
+type CommitteeReader interface {
+	GetCommittee(fastNumber *big.Int) []*types.CommitteeMember
+}
+
+// record is the aggregate of the blocks of a single day, as persisted.
+type record struct {
+	FastBlocks  uint64
+	FastTime    uint64 // Sum of the fast block times in seconds
+	GasUsed     uint64
+	GasLimit    uint64
+	Signs       uint64 // Agreeing committee signatures
+	Seats       uint64 // Committee members entitled to sign
+	SnailBlocks uint64
+	Fruits      uint64
+	Difficulty  *big.Int // Sum of the snail block difficulties
+}
+
+// Stats is the digest of the blocks of a single day.
+type Stats struct {
+	Day               string       `json:"day"` // UTC date of the block timestamps
+	FastBlocks        uint64       `json:"fastBlocks"`
+	AvgFastBlockTime  float64      `json:"avgFastBlockTime"`  // Seconds
+	GasUsedPercent    float64      `json:"gasUsedPercent"`    // Gas used to gas limit
+	SignParticipation float64      `json:"signParticipation"` // Agreeing signatures to committee seats, percent
+	SnailBlocks       uint64       `json:"snailBlocks"`
+	FruitsPerBlock    float64      `json:"fruitsPerBlock"`
+	AvgDifficulty     *hexutil.Big `json:"avgDifficulty"`
+	DifficultyChange  float64      `json:"difficultyChange"` // Average difficulty change to the previous day, percent
+}
+
+// Service aggregates the statistics of the imported blocks in the background.
+type Service struct {
+	db        abeydb.Database
+	fastchain FastChain
+	snail     SnailChain
+	committee CommitteeReader
+
+	records  map[uint64]*record // Aggregates modified since the last flush
+	lastFast *types.Header      // Last aggregated fast header, for block times
+	lock     sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a statistics service over the given chains, persisting into db.
+func New(db abeydb.Database, fastchain FastChain, snail SnailChain, committee CommitteeReader) *Service {
+	return &Service{
+		db:        db,
+		fastchain: fastchain,
+		snail:     snail,
+		committee: committee,
+		records:   make(map[uint64]*record),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start launches the aggregation of the imported blocks.
+func (s *Service) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the aggregation, persisting the running aggregates.
+func (s *Service) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// loop aggregates the chain events until termination.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	fastCh := make(chan types.FastChainEvent, eventChanSize)
+	fastSub := s.fastchain.SubscribeChainEvent(fastCh)
+	defer fastSub.Unsubscribe()
+
+	snailCh := make(chan types.SnailChainEvent, eventChanSize)
+	snailSub := s.snail.SubscribeChainEvent(snailCh)
+	defer snailSub.Unsubscribe()
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+
+	for {
+		select {
+		case ev := <-fastCh:
+			s.addFast(ev.Block)
+		case ev := <-snailCh:
+			s.addSnail(ev.Block)
+		case <-flush.C:
+			s.flush()
+		case <-fastSub.Err():
+			s.flush()
+			return
+		case <-snailSub.Err():
+			s.flush()
+			return
+		case <-s.quit:
+			s.flush()
+			return
+		}
+	}
+}
+
+// addFast aggregates an imported fast block.
+func (s *Service) addFast(block *types.Block) {
+	header := block.Header()
+
+	// Retrieve the parent for the block time, unless it was just aggregated
+	parent := s.lastFast
+	if parent == nil || parent.Hash() != header.ParentHash {
+		if header.Number.Sign() > 0 {
+			parent = s.fastchain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		}
+	}
+	var signs, seats uint64
+	for _, sign := range block.Signs() {
+		if sign.Result == types.VoteAgree {
+			signs++
+		}
+	}
+	if signs > 0 {
+		seats = uint64(len(s.committee.GetCommittee(header.Number)))
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r := s.record(header.Time.Uint64() / day)
+	r.FastBlocks++
+	if parent != nil && header.Time.Cmp(parent.Time) >= 0 {
+		r.FastTime += header.Time.Uint64() - parent.Time.Uint64()
+	}
+	r.GasUsed += header.GasUsed
+	r.GasLimit += header.GasLimit
+	r.Signs += signs
+	r.Seats += seats
+
+	s.lastFast = header
+}
+
+// addSnail aggregates an imported snail block.
+func (s *Service) addSnail(block *types.SnailBlock) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r := s.record(block.Time().Uint64() / day)
+	r.SnailBlocks++
+	r.Fruits += uint64(len(block.Fruits()))
+	r.Difficulty.Add(r.Difficulty, block.BlockDifficulty())
+}
+
+// record returns the running aggregate of a day, loading it from the database
+// if it was already persisted. The caller must hold the lock.
+func (s *Service) record(number uint64) *record {
+	if r, ok := s.records[number]; ok {
+		return r
+	}
+	r := readRecord(s.db, number)
+	if r == nil {
+		r = &record{Difficulty: new(big.Int)}
+	}
+	s.records[number] = r
+	return r
+}
+
+// flush persists the running aggregates, keeping only the latest one in memory.
+func (s *Service) flush() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var latest uint64
+	for number, r := range s.records {
+		if err := writeRecord(s.db, number, r); err != nil {
+			log.Warn("Failed to store chain statistics", "day", formatDay(number), "err", err)
+		}
+		if number > latest {
+			latest = number
+		}
+	}
+	for number := range s.records {
+		if number != latest {
+			delete(s.records, number)
+		}
+	}
+}
+
+// Stats returns the statistics of the given number of days, up to the current
+// day in UTC, oldest first. Days without blocks are omitted.
+func (s *Service) Stats(days int) []*Stats {
+	if days <= 0 {
+		return nil
+	}
+	if days > MaxDays {
+		days = MaxDays
+	}
+	today := uint64(time.Now().Unix()) / day
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var (
+		stats []*Stats
+		prev  *big.Int // Average difficulty of the previous day with snail blocks
+	)
+	for number := today - uint64(days); number <= today; number++ {
+		r := s.records[number]
+		if r == nil {
+			r = readRecord(s.db, number)
+		}
+		if r == nil {
+			continue
+		}
+		st, avg := r.digest(number, prev)
+		if avg != nil {
+			prev = avg
+		}
+		// The extra leading day only serves the difficulty change of the first one
+		if number > today-uint64(days) {
+			stats = append(stats, st)
+		}
+	}
+	return stats
+}
+
+// digest derives the statistics of a day's aggregate, given the average snail
+// difficulty of the previous day. It also returns the average difficulty, nil
+// if there were no snail blocks.
+func (r *record) digest(number uint64, prev *big.Int) (*Stats, *big.Int) {
+	st := &Stats{
+		Day:         formatDay(number),
+		FastBlocks:  r.FastBlocks,
+		SnailBlocks: r.SnailBlocks,
+	}
+	if r.FastBlocks > 0 {
+		st.AvgFastBlockTime = float64(r.FastTime) / float64(r.FastBlocks)
+	}
+	if r.GasLimit > 0 {
+		st.GasUsedPercent = 100 * float64(r.GasUsed) / float64(r.GasLimit)
+	}
+	if r.Seats > 0 {
+		st.SignParticipation = 100 * float64(r.Signs) / float64(r.Seats)
+	}
+	if r.SnailBlocks == 0 {
+		return st, nil
+	}
+	st.FruitsPerBlock = float64(r.Fruits) / float64(r.SnailBlocks)
+
+	avg := new(big.Int).Div(r.Difficulty, new(big.Int).SetUint64(r.SnailBlocks))
+	st.AvgDifficulty = (*hexutil.Big)(avg)
+	if prev != nil && prev.Sign() > 0 {
+		change, _ := new(big.Float).Quo(new(big.Float).SetInt(new(big.Int).Sub(avg, prev)), new(big.Float).SetInt(prev)).Float64()
+		st.DifficultyChange = 100 * change
+	}
+	return st, avg
+}
+
+// formatDay returns the UTC date of a day number.
+func formatDay(number uint64) string {
+	return time.Unix(int64(number*day), 0).UTC().Format("2006-01-02")
+}
+
+// statsKey = statsPrefix + day (uint64 big endian)
+func statsKey(number uint64) []byte {
+	key := make([]byte, len(statsPrefix)+8)
+	copy(key, statsPrefix)
+	binary.BigEndian.PutUint64(key[len(statsPrefix):], number)
+	return key
+}
+
+// readRecord retrieves the persisted aggregate of a day, nil if none.
+func readRecord(db abeydb.Database, number uint64) *record {
+	data, _ := db.Get(statsKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	r := new(record)
+	if err := rlp.DecodeBytes(data, r); err != nil {
+		log.Error("Invalid chain statistics RLP", "day", formatDay(number), "err", err)
+		return nil
+	}
+	return r
+}
+
+// writeRecord persists the aggregate of a day.
+func writeRecord(db abeydb.Database, number uint64, r *record) error {
+	data, err := rlp.EncodeToBytes(r)
+	if err != nil {
+		return err
+	}
+	return db.Put(statsKey(number), data)
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package chainstats
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/event"
+)
+
+type testChain struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *testChain) SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription {
+	return nil
+}
+
+type testCommittee int
+
+func (c testCommittee) GetCommittee(fastNumber *big.Int) []*types.CommitteeMember {
+	return make([]*types.CommitteeMember, c)
+}
+
+// Tests that the daily aggregates are digested into the right statistics and
+// survive a flush into the database.
+func TestStats(t *testing.T) {
+	db := abeydb.NewMemDatabase()
+	start := (uint64(time.Now().Unix())/day - 1) * day // Start of yesterday
+
+	chain := &testChain{headers: make(map[common.Hash]*types.Header)}
+	stats := New(db, chain, nil, testCommittee(4))
+
+	// Yesterday: one snail block of difficulty 100
+	stats.addSnail(types.NewSnailBlockWithHeader(&types.SnailHeader{
+		Number:     big.NewInt(1),
+		Time:       new(big.Int).SetUint64(start),
+		Difficulty: big.NewInt(100),
+	}))
+	// Today: fast blocks 2 seconds apart, 3 of 4 members agreeing, and two snail blocks
+	parent := &types.Header{Number: big.NewInt(0), Time: new(big.Int).SetUint64(start + day)}
+	chain.headers[parent.Hash()] = parent
+	for i := 1; i <= 2; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       new(big.Int).SetUint64(start + day + uint64(2*i)),
+			GasUsed:    25,
+			GasLimit:   100,
+		}
+		var signs []*types.PbftSign
+		for j := 0; j < 3; j++ {
+			signs = append(signs, &types.PbftSign{FastHeight: header.Number, Result: types.VoteAgree})
+		}
+		stats.addFast(types.NewBlockWithHeader(header).WithBody(nil, signs, nil))
+		parent = header
+	}
+	for i := 0; i < 2; i++ {
+		stats.addSnail(types.NewSnailBlockWithHeader(&types.SnailHeader{
+			Number:     big.NewInt(int64(2 + i)),
+			Time:       new(big.Int).SetUint64(start + day),
+			Difficulty: big.NewInt(150),
+		}))
+	}
+	stats.flush()
+
+	result := stats.Stats(2)
+	if len(result) != 2 {
+		t.Fatalf("day count mismatch: have %d, want 2", len(result))
+	}
+	today := result[1]
+	if today.FastBlocks != 2 || today.AvgFastBlockTime != 2 || today.GasUsedPercent != 25 || today.SignParticipation != 75 {
+		t.Errorf("fast statistics mismatch: %+v", today)
+	}
+	if today.SnailBlocks != 2 || today.AvgDifficulty.ToInt().Int64() != 150 || today.DifficultyChange != 50 {
+		t.Errorf("snail statistics mismatch: %+v", today)
+	}
+	// Reloading from the database must yield the same statistics
+	reloaded := New(db, chain, nil, testCommittee(4)).Stats(2)
+	if len(reloaded) != 2 {
+		t.Fatalf("reloaded day count mismatch: have %d, want 2", len(reloaded))
+	}
+	if have := reloaded[1]; have.FastBlocks != today.FastBlocks || have.SignParticipation != today.SignParticipation || have.DifficultyChange != today.DifficultyChange {
+		t.Fatalf("reloaded statistics mismatch: have %+v, want %+v", have, today)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The Abeychain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/abeychain/go-abey/common/crash"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/log"
+)
+
+const (
+	// defaultParticipationWindow is the number of fast blocks the round
+	// participation is measured over if not specified otherwise.
+	defaultParticipationWindow = 100
+
+	// maxParticipationWindow caps the window, each block costs a committee
+	// lookup and the recovery of its signatures.
+	maxParticipationWindow = 1024
+
+	// alertCheckInterval is the number of fast blocks between two
+	// participation checks of the alerter.
+	alertCheckInterval = 20
+
+	alertTimeout = 5 * time.Second
+)
+
+// CommitteeSelfStatus reports the part this node plays in the committee.
+type CommitteeSelfStatus struct {
+	Member           bool                `json:"member"`
+	CommitteeID      uint64              `json:"committeeId"`
+	LastSignedHeight uint64              `json:"lastSignedHeight"`
+	Participation    *RoundParticipation `json:"participation"`
+	Switchover       *PendingSwitchover  `json:"pendingSwitchover"`
+}
+
+// RoundParticipation is the share of the recent fast blocks signed by this
+// node out of those produced while it was a committee member.
+type RoundParticipation struct {
+	Window   uint64  `json:"window"`
+	Expected uint64  `json:"expected"`
+	Signed   uint64  `json:"signed"`
+	Ratio    float64 `json:"ratio"`
+}
+
+// PendingSwitchover describes the committee taking over next, if known.
+type PendingSwitchover struct {
+	CommitteeID   uint64 `json:"committeeId"`
+	StartHeight   uint64 `json:"startHeight"`
+	EndFastNumber uint64 `json:"currentEndHeight"`
+	Member        bool   `json:"member"`
+}
+
+// SelfStatus returns the committee status of this node, measuring the round
+// participation over the given number of fast blocks.
+func (agent *PbftAgent) SelfStatus(window uint64) *CommitteeSelfStatus {
+	status := &CommitteeSelfStatus{
+		Member:           agent.isCurrentCommitteeMember,
+		CommitteeID:      agent.CommitteeNumber(),
+		LastSignedHeight: atomic.LoadUint64(&agent.lastSignedHeight),
+		Participation:    agent.participation(window),
+	}
+	if next := agent.nextCommitteeInfo; next != nil && next.Id != nil {
+		status.Switchover = &PendingSwitchover{
+			CommitteeID: next.Id.Uint64(),
+			Member:      agent.isCommitteeMember(next),
+		}
+		if next.StartHeight != nil {
+			status.Switchover.StartHeight = next.StartHeight.Uint64()
+		}
+		if end := agent.endFastNumber[status.CommitteeID]; end != nil {
+			status.Switchover.EndFastNumber = end.Uint64()
+		}
+	}
+	return status
+}
+
+// participation counts the fast blocks among the last window ones that were
+// produced while this node was a committee member, and how many of those
+// carry its signature.
+func (agent *PbftAgent) participation(window uint64) *RoundParticipation {
+	if window == 0 {
+		window = defaultParticipationWindow
+	}
+	if window > maxParticipationWindow {
+		window = maxParticipationWindow
+	}
+	stats := &RoundParticipation{Window: window}
+	pubkey := agent.committeeNode.Publickey
+
+	block := agent.fastChain.CurrentBlock()
+	for i := uint64(0); i < window && block != nil && block.NumberU64() > 0; i++ {
+		members := agent.election.GetCommittee(block.Number())
+		if agent.election.IsCommitteeMember(members, pubkey) {
+			stats.Expected++
+			if signedBy(block.Signs(), pubkey) {
+				stats.Signed++
+			}
+		}
+		block = agent.fastChain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	if stats.Expected > 0 {
+		stats.Ratio = float64(stats.Signed) / float64(stats.Expected)
+	}
+	return stats
+}
+
+// signedBy reports whether any of the signs was made by the given key.
+func signedBy(signs []*types.PbftSign, pubkey []byte) bool {
+	for _, sign := range signs {
+		pub, err := crypto.SigToPub(sign.HashWithNoSign().Bytes(), sign.Sign)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(crypto.FromECDSAPub(pub), pubkey) {
+			return true
+		}
+	}
+	return false
+}
+
+// committeeAlert is the payload posted to the alert webhook.
+type committeeAlert struct {
+	Node          string              `json:"node"`
+	CommitteeID   uint64              `json:"committeeId"`
+	Height        uint64              `json:"height"`
+	Healthy       bool                `json:"healthy"`
+	Threshold     float64             `json:"threshold"`
+	Participation *RoundParticipation `json:"participation"`
+}
+
+// committeeAlerter watches the round participation of this node and notifies
+// a webhook when it drops below the threshold, and again once it recovers.
+type committeeAlerter struct {
+	agent     *PbftAgent
+	url       string
+	threshold float64
+	client    *http.Client
+
+	headCh  chan types.FastChainHeadEvent
+	headSub event.Subscription
+	alerted bool
+}
+
+func newCommitteeAlerter(agent *PbftAgent, url string, threshold float64) *committeeAlerter {
+	return &committeeAlerter{
+		agent:     agent,
+		url:       url,
+		threshold: threshold,
+		client:    &http.Client{Timeout: alertTimeout},
+		headCh:    make(chan types.FastChainHeadEvent, chainHeadSize),
+	}
+}
+
+// Start begins watching the fast chain.
+func (a *committeeAlerter) Start() {
+	a.headSub = a.agent.fastChain.SubscribeChainHeadEvent(a.headCh)
+	go crash.Run("committee-alerter", false, a.loop)
+}
+
+// Stop terminates the alerter.
+func (a *committeeAlerter) Stop() {
+	a.headSub.Unsubscribe()
+}
+
+func (a *committeeAlerter) loop() {
+	for {
+		select {
+		case ev := <-a.headCh:
+			number := ev.Block.NumberU64()
+			if number%alertCheckInterval != 0 {
+				continue
+			}
+			a.check(number)
+		case <-a.headSub.Err():
+			return
+		}
+	}
+}
+
+// check measures the participation and posts an alert on every transition
+// between healthy and unhealthy.
+func (a *committeeAlerter) check(number uint64) {
+	if !a.agent.isCurrentCommitteeMember {
+		return
+	}
+	stats := a.agent.participation(defaultParticipationWindow)
+	if stats.Expected == 0 {
+		return
+	}
+	healthy := stats.Ratio >= a.threshold
+	if healthy != a.alerted {
+		return // no transition, healthy and not alerted or still unhealthy
+	}
+	a.alerted = !healthy
+	if !healthy {
+		log.Warn("Committee participation below threshold", "ratio", stats.Ratio, "threshold", a.threshold, "signed", stats.Signed, "expected", stats.Expected)
+	}
+	alert := &committeeAlert{
+		Node:          fmt.Sprintf("%x", a.agent.committeeNode.Publickey),
+		CommitteeID:   a.agent.CommitteeNumber(),
+		Height:        number,
+		Healthy:       healthy,
+		Threshold:     a.threshold,
+		Participation: stats,
+	}
+	if err := a.post(alert); err != nil {
+		log.Warn("Failed to post committee alert", "url", a.url, "err", err)
+	}
+}
+
+func (a *committeeAlerter) post(alert *committeeAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", res.Status)
+	}
+	return nil
+}
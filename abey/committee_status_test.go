@@ -0,0 +1,70 @@
+package abey
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// Tests that the signs made by the agent are attributed to it and that the
+// last signed height is tracked.
+func TestSelfSignTracking(t *testing.T) {
+	self, other := NewPbftAgetTest(), NewPbftAgetTest()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(42)})
+
+	sign, err := self.GenerateSign(block)
+	if err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+	if height := atomic.LoadUint64(&self.lastSignedHeight); height != 42 {
+		t.Errorf("last signed height mismatch: have %d, want 42", height)
+	}
+	otherSign, _ := other.GenerateSign(block)
+
+	if !signedBy([]*types.PbftSign{otherSign, sign}, self.committeeNode.Publickey) {
+		t.Errorf("own sign not detected")
+	}
+	if signedBy([]*types.PbftSign{otherSign}, self.committeeNode.Publickey) {
+		t.Errorf("foreign sign attributed to self")
+	}
+}
+
+// Tests that alerts are posted to the webhook as JSON.
+func TestCommitteeAlertPost(t *testing.T) {
+	received := make(chan *committeeAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alert := new(committeeAlert)
+		if err := json.NewDecoder(r.Body).Decode(alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	alerter := newCommitteeAlerter(NewPbftAgetTest(), server.URL, 0.9)
+	alert := &committeeAlert{
+		CommitteeID:   3,
+		Height:        100,
+		Threshold:     0.9,
+		Participation: &RoundParticipation{Window: 100, Expected: 50, Signed: 20, Ratio: 0.4},
+	}
+	if err := alerter.post(alert); err != nil {
+		t.Fatalf("failed to post alert: %v", err)
+	}
+	have := <-received
+	if have.CommitteeID != 3 || have.Healthy || have.Participation.Signed != 20 {
+		t.Errorf("alert mismatch: have %+v", have)
+	}
+	// Failing webhooks are reported
+	alerter.url = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	if err := alerter.post(alert); err == nil {
+		t.Errorf("expected error from failing webhook")
+	}
+}
@@ -30,7 +30,9 @@ import (
 	"github.com/abeychain/go-abey/abey/downloader"
 	"github.com/abeychain/go-abey/abey/gasprice"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/diskusage"
 	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/common/ntp"
 	"github.com/abeychain/go-abey/consensus/minerva"
 	"github.com/abeychain/go-abey/core"
 	"github.com/abeychain/go-abey/core/snailchain"
@@ -38,7 +40,15 @@ import (
 
 // DefaultConfig contains default settings for use on the ABEY chain main net.
 var DefaultConfig = Config{
-	SyncMode: downloader.FullSync,
+	SyncMode:         downloader.FullSync,
+	SnailFinality:    params.SnailFinalityThreshold,
+	SyncStallTimeout: 10 * time.Minute,
+	NTPServer:        ntp.DefaultServer,
+	DiskWarnDays:     diskusage.DefaultWarnDays,
+	FruitLagBlocks:   1000,
+	FruitLagTime:     time.Hour,
+	RPCEVMTimeout:    5 * time.Second,
+	RPCGasCap:        25000000,
 	MinervaHash: minerva.Config{
 		CacheDir:       "minerva",
 		CachesInMem:    2,
@@ -46,14 +56,15 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
-	NetworkId:     179,
-	LightPeers:    20,
-	DatabaseCache: 768,
-	TrieCache:     256,
-	TrieTimeout:   60 * time.Minute,
-	MinerGasFloor: 16000000,
-	MinerGasCeil:  20000000,
-	GasPrice:      big.NewInt(10 * params.GWei),
+	NetworkId:       179,
+	LightPeers:      20,
+	DatabaseCache:   768,
+	TrieCache:       256,
+	TrieTimeout:     60 * time.Minute,
+	DownloaderCache: 192,
+	MinerGasFloor:   16000000,
+	MinerGasCeil:    20000000,
+	GasPrice:        big.NewInt(10 * params.GWei),
 
 	//GasPrice: big.NewInt(1 * params.Szabo),
 
@@ -66,6 +77,8 @@ var DefaultConfig = Config{
 	MinerThreads: 2,
 	Port:         30310,
 	StandbyPort:  30311,
+
+	CommitteeAlertThreshold: 0.9,
 }
 
 func init() {
@@ -104,6 +117,34 @@ type Config struct {
 	NoPruning    bool
 	DeletedState bool
 
+	// Number of snail blocks below the head that may not be reorganised (0 = disabled)
+	SnailFinality uint64
+
+	// Time without sync progress after which the session is restarted with
+	// another master peer (0 = disabled)
+	SyncStallTimeout time.Duration
+
+	// NTP server to measure the system clock drift against (empty = disabled)
+	NTPServer string
+
+	// File recording the protocol messages received from peers for replay in
+	// tests (empty = disabled)
+	NetCapture string `toml:",omitempty"`
+
+	// Execution time after which abey_call, estimateGas and tracing requests
+	// are aborted (0 = unlimited)
+	RPCEVMTimeout time.Duration
+
+	// Gas allowance cap of abey_call and estimateGas requests (0 = no cap)
+	RPCGasCap uint64
+
+	// State reads budget of abey_call and estimateGas requests (0 = unlimited)
+	RPCStateReadLimit uint64
+
+	// Serve RPC from a read-only database, without mining, transaction
+	// submission or peer-to-peer sync
+	ReadOnly bool `toml:",omitempty"`
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -130,6 +171,17 @@ type Config struct {
 	// StandByPort is the TCP port number on which to start the pbft server.
 	StandbyPort int `toml:",omitempty"`
 
+	// BFTSignMsgs wraps the consensus messages sent to the committee into
+	// envelopes signed with the committee key. BFTRequireSignedMsgs in
+	// addition rejects messages from members that do not sign theirs.
+	BFTSignMsgs          bool `toml:",omitempty"`
+	BFTRequireSignedMsgs bool `toml:",omitempty"`
+
+	// CommitteeAlertURL is the webhook notified when the share of the recent
+	// fast blocks signed by this committee member drops below the threshold.
+	CommitteeAlertURL       string  `toml:",omitempty"`
+	CommitteeAlertThreshold float64 `toml:",omitempty"`
+
 	// Ultra Light client options
 	ULC *ULCConfig `toml:",omitempty"`
 
@@ -140,10 +192,27 @@ type Config struct {
 	TrieCache          int
 	TrieTimeout        time.Duration
 
+	// Downloader options
+	DownloaderCache int // Megabytes of block results buffered by the snail and fast downloaders
+
+	// Memory pressure options
+	MemoryLimit int // Megabytes of memory the node may use before shedding caches, zero to detect
+
+	// Disk usage options
+	DiskWarnDays int // Projected days until the disk is full below which warnings are raised, zero to disable
+
+	// Fruit lag options
+	FruitLagBlocks uint64        // Fast blocks the latest included fruit may lag behind the head, zero to disable
+	FruitLagTime   time.Duration // Time the latest included fruit may lag behind the head, zero to disable
+
+	// RPC options
+	RPCCache int // Megabytes of RPC responses about final chain data to cache, zero to disable
+
 	// Mining-related options
 	Etherbase     common.Address `toml:",omitempty"`
 	MinerThreads  int            `toml:",omitempty"`
 	ExtraData     []byte         `toml:",omitempty"`
+	CoinbaseProof bool           `toml:",omitempty"` // Sign mined fruits and blocks with the coinbase key after TIP13
 	MinerGasFloor uint64
 	MinerGasCeil  uint64
 	GasPrice      *big.Int
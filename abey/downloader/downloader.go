@@ -29,6 +29,7 @@ import (
 
 	"github.com/abeychain/go-abey"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/crash"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/abey/fastdownloader"
 	abey "github.com/abeychain/go-abey/abey/types"
@@ -43,6 +44,7 @@ var (
 	MaxHashFetch       = 512 // Amount of hashes to be fetched per retrieval request
 	MaxBlockFetch      = 128 // Amount of blocks to be fetched per retrieval request
 	MaxHeaderFetch     = 192 // Amount of block headers to be fetched per retrieval request
+	MinHeaderFetch     = 16  // Amount of block headers to be fetched per request from a peer of unknown throughput
 	MaxFastHeaderFetch = 600 // Amount of fast block headers to be fetched per retrieval request
 	MaxSkeletonSize    = 128 // Number of header fetches to need for a skeleton assembly
 	MaxBodyFetch       = 128 // Amount of block bodies to be fetched per retrieval request
@@ -239,8 +241,8 @@ func New(mode SyncMode, checkpoint uint64, stateDb abeydb.Database, mux *event.T
 		trackStateReq: make(chan *stateReq),
 	}
 
-	go dl.qosTuner()
-	go dl.stateFetcher()
+	go crash.Run("downloader/qos", true, dl.qosTuner)
+	go crash.Run("downloader/state", true, dl.stateFetcher)
 	return dl
 
 }
@@ -1037,7 +1039,7 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.SnailHead
 			return d.queue.ReserveHeaders(p, count), false, nil
 		}
 		fetch = func(p abey.PeerConnection, req *abey.FetchRequest) error {
-			return p.FetchHeaders(req.From, MaxHeaderFetch)
+			return p.FetchHeaders(req.From, req.Count)
 		}
 		capacity = func(p abey.PeerConnection) int { return p.HeaderCapacity(d.requestRTT()) }
 		setIdle  = func(p abey.PeerConnection, accepted int) { p.SetHeadersIdle(accepted) }
@@ -1365,8 +1367,9 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				}
 				chunk := headers[:limit]
 
-				// If we've reached the allowed number of pending headers, stall a bit
-				for d.queue.PendingBlocks() >= maxQueuedHeaders {
+				// If we've reached the allowed number of pending headers or the queued
+				// results reached the memory limit, stall a bit
+				for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.ShouldThrottleHeaders() {
 					select {
 					case <-d.cancelCh:
 						return errCancelHeaderProcessing
@@ -1499,11 +1502,12 @@ func (d *Downloader) importBlockResults(results []*abey.FetchResult, p abey.Peer
 
 func (d *Downloader) importBlockAndSyncFast(blocks []*types.SnailBlock, p abey.PeerConnection, hash common.Hash) error {
 	firstB := blocks[0]
-	fbNumber := firstB.Fruits()[0].FastNumber().Uint64()
-
 	result := blocks[len(blocks)-1]
-	fruitLen := uint64(len(result.Fruits()))
-	fbLastNumber := result.Fruits()[fruitLen-1].FastNumber().Uint64()
+	if len(firstB.Fruits()) == 0 || len(result.Fruits()) == 0 {
+		return errInvalidBody
+	}
+	fbNumber := firstB.MinFruitNumber().Uint64()
+	fbLastNumber := result.MaxFruitNumber().Uint64()
 	log.Info("Sync fast blocks", "fbNumber", fbNumber, "fbLastNumber", fbLastNumber, "first snail", firstB.Number(), "last snail", result.Number(), "mode", d.mode)
 	if err := d.SyncFast(p.GetID(), hash, fbLastNumber, d.mode); err != nil {
 		return err
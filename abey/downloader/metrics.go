@@ -33,7 +33,12 @@ var (
 	bodyDropMeter    = metrics.NewRegisteredMeter("abey/downloader/bodies/drop", nil)
 	bodyTimeoutMeter = metrics.NewRegisteredMeter("abey/downloader/bodies/timeout", nil)
 
+	queuedBytesGauge = metrics.NewRegisteredGauge("abey/downloader/queue/bytes", nil)
+
 
 	stateInMeter   = metrics.NewRegisteredMeter("abey/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("abey/downloader/states/drop", nil)
+
+	stateNodeMeter  = metrics.NewRegisteredMeter("abey/downloader/states/nodes", nil)
+	stateBytesMeter = metrics.NewRegisteredMeter("abey/downloader/states/bytes", nil)
 )
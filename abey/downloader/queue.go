@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/abeychain/go-abey/common"
@@ -39,6 +40,34 @@ var (
 	blockCacheSizeWeight = 0.1               // Multiplier to approximate the average block size based on past ones
 )
 
+// SetBlockCacheMemory sets the maximum amount of memory in bytes to use for
+// block caching. It must be called before any synchronisation is started.
+func SetBlockCacheMemory(size int) {
+	blockCacheMemory = size
+}
+
+// blockCacheShrunk is non-zero while memory pressure shrinks the block cache.
+var blockCacheShrunk int32
+
+// ShrinkBlockCache quarters the memory used for block caching while the node is
+// under memory pressure, throttling the download until it is relieved.
+func ShrinkBlockCache(shrink bool) {
+	if shrink {
+		atomic.StoreInt32(&blockCacheShrunk, 1)
+	} else {
+		atomic.StoreInt32(&blockCacheShrunk, 0)
+	}
+}
+
+// blockCacheLimit returns the amount of memory in bytes currently allowed for
+// block caching.
+func blockCacheLimit() common.StorageSize {
+	if atomic.LoadInt32(&blockCacheShrunk) != 0 {
+		return common.StorageSize(blockCacheMemory / 4)
+	}
+	return common.StorageSize(blockCacheMemory)
+}
+
 var (
 	errNoFetchesPending = errors.New("Snail no fetches pending")
 	errStaleDelivery    = errors.New("Snail stale delivery")
@@ -51,7 +80,7 @@ type queue struct {
 	// Headers are "special", they download in batches, supported by a skeleton chain
 	headerHead      common.Hash                    // [eth/62] Hash of the last queued header to verify order
 	headerTaskPool  map[uint64]*types.SnailHeader  // [eth/62] Pending header retrieval tasks, mapping starting indexes to skeleton headers
-	headerTaskQueue *prque.Prque                   // [eth/62] Priority queue of the skeleton indexes to fetch the filling headers for
+	headerTaskQueue *prque.Prque                   // [eth/62] Priority queue of the first unfilled indexes of the skeleton gaps
 	headerPeerMiss  map[string]map[uint64]struct{} // [eth/62] Set of per-peer header batches known to be unavailable
 	headerPendPool  map[string]*abey.FetchRequest // [eth/62] Currently pending header retrieval operations
 	headerResults   []*types.SnailHeader           // [eth/62] Result cache accumulating the completed headers
@@ -68,6 +97,7 @@ type queue struct {
 	resultCache  []*abey.FetchResult // Downloaded but not yet delivered fetch results
 	resultOffset uint64               // Offset of the first cached fetch result in the block chain
 	resultSize   common.StorageSize   // Approximate size of a block (exponential moving average)
+	resultBytes  common.StorageSize   // Size of the delivered fetch results held in the cache
 
 	lock       *sync.Mutex
 	active     *sync.Cond
@@ -110,6 +140,8 @@ func (q *queue) Reset() {
 
 	q.resultCache = make([]*abey.FetchResult, blockCacheItems)
 	q.resultOffset = 0
+	q.resultBytes = 0
+	queuedBytesGauge.Update(0)
 }
 
 // Close marks the end of the sync, unblocking WaitResults.
@@ -176,14 +208,39 @@ func (q *queue) ShouldThrottleBlocks() bool {
 	return q.resultSlots(q.blockPendPool, q.blockDonePool) <= 0
 }
 
+// ShouldThrottleHeaders checks if the delivered fetch results reached the memory
+// limit of the result cache, in which case no new headers should be scheduled
+// until they are processed.
+func (q *queue) ShouldThrottleHeaders() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.resultBytes >= blockCacheLimit()
+}
+
 // resultSlots calculates the number of results slots available for requests
 // whilst adhering to both the item and the memory limit too of the results
 // cache.
 func (q *queue) resultSlots(pendPool map[string]*abey.FetchRequest, donePool map[common.Hash]struct{}) int {
-	// Calculate the maximum length capped by the memory limit
-	limit := len(q.resultCache)
-	if common.StorageSize(len(q.resultCache))*q.resultSize > common.StorageSize(blockCacheMemory) {
-		limit = int((common.StorageSize(blockCacheMemory) + q.resultSize - 1) / q.resultSize)
+	// Calculate the maximum length capped by the memory limit. Delivered results
+	// are accounted by their actual size, as fruit heavy blocks vary hugely, the
+	// rest by the estimated one. The first slot is always allowed, otherwise the
+	// results could never be drained.
+	var (
+		limit  = len(q.resultCache)
+		memory = blockCacheLimit()
+		used   common.StorageSize
+	)
+	for i, result := range q.resultCache {
+		if result != nil && result.Pending == 0 {
+			used += q.fetchResultSize(result)
+		} else {
+			used += q.resultSize
+		}
+		if used > memory && i > 0 {
+			limit = i
+			break
+		}
 	}
 	// Calculate the number of slots already finished
 	finished := 0
@@ -318,21 +375,29 @@ func (q *queue) Results(block bool) []*abey.FetchResult {
 
 		// Recalculate the result item weights to prevent memory exhaustion
 		for _, result := range results {
-			size := result.Sheader.Size()
-			for _, fruit := range result.Fruits {
-				if q.mode == LightSync {
-					size += fruit.Header().Size()
-				} else {
-					size += fruit.Size()
-				}
-			}
+			size := q.fetchResultSize(result)
+			q.resultBytes -= size
 
 			q.resultSize = common.StorageSize(blockCacheSizeWeight)*size + (1-common.StorageSize(blockCacheSizeWeight))*q.resultSize
 		}
+		queuedBytesGauge.Update(int64(q.resultBytes))
 	}
 	return results
 }
 
+// fetchResultSize returns the amount of memory held by a fetch result.
+func (q *queue) fetchResultSize(result *abey.FetchResult) common.StorageSize {
+	size := result.Sheader.Size()
+	for _, fruit := range result.Fruits {
+		if q.mode == LightSync {
+			size += fruit.Header().Size()
+		} else {
+			size += fruit.Size()
+		}
+	}
+	return size
+}
+
 // countProcessableItems counts the processable items.
 func (q *queue) countProcessableItems() int {
 	for i, result := range q.resultCache {
@@ -344,8 +409,21 @@ func (q *queue) countProcessableItems() int {
 	return len(q.resultCache)
 }
 
+// headerGap returns the starting index of the skeleton gap containing index.
+func (q *queue) headerGap(index uint64) uint64 {
+	return index - (index-q.headerOffset)%uint64(MaxHeaderFetch)
+}
+
+// headerExtends reports whether header extends the headers already filled into
+// its skeleton gap.
+func (q *queue) headerExtends(header *types.SnailHeader) bool {
+	parent := q.headerResults[header.Number.Uint64()-q.headerOffset-1]
+	return parent != nil && parent.Hash() == header.ParentHash
+}
+
 // ReserveHeaders reserves a set of headers for the given peer, skipping any
-// previously failed batches.
+// previously failed batches. Gaps of the skeleton are filled in pieces of up to
+// count headers, but at least MinHeaderFetch, so slow peers are not timed out.
 func (q *queue) ReserveHeaders(p abey.PeerConnection, count int) *abey.FetchRequest {
 	q.lock.Lock()
 	defer q.lock.Unlock()
@@ -360,7 +438,7 @@ func (q *queue) ReserveHeaders(p abey.PeerConnection, count int) *abey.FetchRequ
 	for send == 0 && !q.headerTaskQueue.Empty() {
 		from, _ := q.headerTaskQueue.Pop()
 		if q.headerPeerMiss[p.GetID()] != nil {
-			if _, ok := q.headerPeerMiss[p.GetID()][from.(uint64)]; ok {
+			if _, ok := q.headerPeerMiss[p.GetID()][q.headerGap(from.(uint64))]; ok {
 				skip = append(skip, from.(uint64))
 				continue
 			}
@@ -375,10 +453,17 @@ func (q *queue) ReserveHeaders(p abey.PeerConnection, count int) *abey.FetchRequ
 	if send == 0 {
 		return nil
 	}
+	if count < MinHeaderFetch {
+		count = MinHeaderFetch
+	}
+	if left := int(q.headerGap(send) + uint64(MaxHeaderFetch) - send); count > left {
+		count = left
+	}
 	request := &abey.FetchRequest{
-		Peer: p,
-		From: send,
-		Time: time.Now(),
+		Peer:  p,
+		From:  send,
+		Count: count,
+		Time:  time.Now(),
 	}
 	q.headerPendPool[p.GetID()] = request
 	return request
@@ -448,6 +533,9 @@ func (q *queue) reserveHeaders(p abey.PeerConnection, count int, taskPool map[co
 
 			space, proc = space-1, proc-1
 			q.resultCache[index].Pending--
+			if q.resultCache[index].Pending == 0 {
+				q.resultBytes += q.fetchResultSize(q.resultCache[index])
+			}
 			progress = true
 			continue
 		}
@@ -572,7 +660,9 @@ func (q *queue) expire(timeout time.Duration, pendPool map[string]*abey.FetchReq
 
 // DeliverHeaders injects a header retrieval response into the header results
 // cache. This method either accepts all headers it received, or none of them
-// if they do not map correctly to the skeleton.
+// if they do not map correctly to the skeleton. Headers filling a gap only in
+// part are checked against the skeleton once the last piece of it arrives, the
+// whole gap is refetched if it fails to match then.
 //
 // If the headers are accepted, the method makes an attempt to deliver the set
 // of ready headers to the processor to keep the pipeline full. However it will
@@ -590,14 +680,19 @@ func (q *queue) DeliverHeaders(id string, headers []*types.SnailHeader, headerPr
 	delete(q.headerPendPool, id)
 
 	// Ensure headers can be mapped onto the skeleton chain
-	target := q.headerTaskPool[request.From].Hash()
+	gap := q.headerGap(request.From)
+	target := q.headerTaskPool[gap].Hash()
+	end := gap + uint64(MaxHeaderFetch)
 
-	accepted := len(headers) == MaxHeaderFetch
+	accepted := len(headers) > 0 && request.From+uint64(len(headers)) <= end
 	if accepted {
 		if headers[0].Number.Uint64() != request.From {
 			log.Trace("Snail First header broke chain ordering", "peer", id, "number", headers[0].Number, "hash", headers[0].Hash(), request.From)
 			accepted = false
-		} else if headers[len(headers)-1].Hash() != target {
+		} else if request.From > gap && !q.headerExtends(headers[0]) {
+			log.Trace("Snail First header broke gap ancestry", "peer", id, "number", headers[0].Number, "hash", headers[0].Hash())
+			accepted = false
+		} else if request.From+uint64(len(headers)) == end && headers[len(headers)-1].Hash() != target {
 			log.Trace("Snail Last header broke skeleton structure ", "peer", id, "number", headers[len(headers)-1].Number, "hash", headers[len(headers)-1].Hash(), "expected", target)
 			accepted = false
 		}
@@ -626,17 +721,30 @@ func (q *queue) DeliverHeaders(id string, headers []*types.SnailHeader, headerPr
 			q.headerPeerMiss[id] = make(map[uint64]struct{})
 			miss = q.headerPeerMiss[id]
 		}
-		miss[request.From] = struct{}{}
+		miss[gap] = struct{}{}
 
-		q.headerTaskQueue.Push(request.From, -int64(request.From))
+		// The pieces already filled in may be the ones off the skeleton
+		from := request.From
+		if len(headers) > 0 && request.From+uint64(len(headers)) == end {
+			for i := gap; i < request.From; i++ {
+				q.headerResults[i-q.headerOffset] = nil
+			}
+			from = gap
+		}
+		q.headerTaskQueue.Push(from, -int64(from))
 		return 0, errors.New("Snail delivery not accepted")
 	}
 	// Clean up a successful fetch and try to deliver any sub-results
 	copy(q.headerResults[request.From-q.headerOffset:], headers)
-	delete(q.headerTaskPool, request.From)
+	if next := request.From + uint64(len(headers)); next < end {
+		q.headerTaskQueue.Push(next, -int64(next))
+		return len(headers), nil
+	}
+	delete(q.headerTaskPool, gap)
 
+	// Gaps are only completed once their last header matched the skeleton
 	ready := 0
-	for q.headerProced+ready < len(q.headerResults) && q.headerResults[q.headerProced+ready] != nil {
+	for q.headerProced+ready < len(q.headerResults) && q.headerResults[q.headerProced+ready+MaxHeaderFetch-1] != nil {
 		ready += MaxHeaderFetch
 	}
 	if ready > 0 {
@@ -723,6 +831,9 @@ func (q *queue) deliver(id string, taskPool map[common.Hash]*types.SnailHeader,
 
 		donePool[hash] = struct{}{}
 		q.resultCache[index].Pending--
+		if q.resultCache[index].Pending == 0 {
+			q.resultBytes += q.fetchResultSize(q.resultCache[index])
+		}
 		useful = true
 		accepted++
 
@@ -738,6 +849,7 @@ func (q *queue) deliver(id string, taskPool map[common.Hash]*types.SnailHeader,
 	}
 	// Wake up WaitResults
 	if accepted > 0 {
+		queuedBytesGauge.Update(int64(q.resultBytes))
 		q.active.Signal()
 	}
 	// If none of the data was good, it's a stale delivery
@@ -0,0 +1,183 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	dtypes "github.com/abeychain/go-abey/abey/types"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+)
+
+// newQueueTestHeader creates a snail header with all size relevant fields set.
+func newQueueTestHeader(number uint64, extra []byte) *types.SnailHeader {
+	return &types.SnailHeader{
+		Number:          new(big.Int).SetUint64(number),
+		FastNumber:      new(big.Int),
+		PointerNumber:   new(big.Int),
+		Difficulty:      new(big.Int),
+		FruitDifficulty: new(big.Int),
+		Time:            new(big.Int),
+		Extra:           extra,
+	}
+}
+
+// Tests that the queue accounts the delivered results by their actual size,
+// throttling both body and header scheduling once the memory limit is reached.
+func TestQueueByteAccounting(t *testing.T) {
+	defer func(memory int) { blockCacheMemory = memory }(blockCacheMemory)
+	blockCacheMemory = 64 * 1024
+
+	q := newQueue(nil)
+	q.Prepare(1, FullSync)
+	q.resultSize = 1024
+
+	// Schedule a few headers and prepare their results as if bodies were reserved
+	headers := make([]*types.SnailHeader, 8)
+	for i := range headers {
+		headers[i] = newQueueTestHeader(uint64(i+1), []byte{byte(i)})
+		q.resultCache[i] = &dtypes.FetchResult{Pending: 1, Hash: headers[i].Hash(), Sheader: headers[i]}
+	}
+	if q.ShouldThrottleHeaders() {
+		t.Fatalf("empty queue throttles headers")
+	}
+	// Deliver fruit heavy bodies for the first three blocks, exceeding the limit
+	fruits := make(types.SnailBlocks, 16)
+	for i := range fruits {
+		fruits[i] = types.NewSnailBlockWithHeader(newQueueTestHeader(1, make([]byte, 2048)))
+	}
+	q.blockPendPool["peer"] = &dtypes.FetchRequest{Sheaders: headers[:3], Time: time.Now()}
+	accepted, err := q.deliver("peer", q.blockTaskPool, q.blockTaskQueue, q.blockPendPool, q.blockDonePool, bodyReqTimer, 3,
+		func(header *types.SnailHeader, index int, result *dtypes.FetchResult) error {
+			result.Fruits = fruits
+			return nil
+		})
+	if err != nil || accepted != 3 {
+		t.Fatalf("failed to deliver bodies: accepted %d, err %v", accepted, err)
+	}
+	size := q.fetchResultSize(q.resultCache[0])
+	if q.resultBytes != 3*size {
+		t.Fatalf("queued bytes mismatch: have %v, want %v", q.resultBytes, 3*size)
+	}
+	if !q.ShouldThrottleHeaders() {
+		t.Errorf("headers not throttled at %v queued bytes", q.resultBytes)
+	}
+	if !q.ShouldThrottleBlocks() {
+		t.Errorf("bodies not throttled at %v queued bytes", q.resultBytes)
+	}
+	// Draining the results must release the throttle
+	if results := q.Results(false); len(results) != 3 {
+		t.Fatalf("result count mismatch: have %d, want 3", len(results))
+	}
+	if q.resultBytes != 0 {
+		t.Errorf("queued bytes not released: have %v", q.resultBytes)
+	}
+	if q.ShouldThrottleHeaders() || q.ShouldThrottleBlocks() {
+		t.Errorf("queue still throttled after draining")
+	}
+}
+
+// newQueueTestChain creates a chain of n snail headers on top of parent.
+func newQueueTestChain(parent *types.SnailHeader, n int, extra byte) []*types.SnailHeader {
+	headers := make([]*types.SnailHeader, n)
+	for i := range headers {
+		headers[i] = newQueueTestHeader(parent.Number.Uint64()+1, []byte{extra})
+		headers[i].ParentHash = parent.Hash()
+		parent = headers[i]
+	}
+	return headers
+}
+
+// Tests that the gaps of a header skeleton can be filled in pieces sized to the
+// peers, and that a gap is refetched if its pieces fail to match the skeleton.
+func TestQueuePartialSkeletonFill(t *testing.T) {
+	defer func(max, min int) { MaxHeaderFetch, MinHeaderFetch = max, min }(MaxHeaderFetch, MinHeaderFetch)
+	MaxHeaderFetch, MinHeaderFetch = 8, 2
+
+	chain := newQueueTestChain(newQueueTestHeader(0, nil), 16, 0)
+	q := newQueue(nil)
+	q.ScheduleSkeleton(1, []*types.SnailHeader{chain[7], chain[15]})
+	procCh := make(chan []*types.SnailHeader, 2)
+
+	slow := newPeerConnection("slow", 62, nil, log.Root())
+	fast := newPeerConnection("fast", 62, nil, log.Root())
+	deliver := func(p *peerConnection, count int, headers func(from uint64, count int) []*types.SnailHeader) (*dtypes.FetchRequest, int, error) {
+		request := q.ReserveHeaders(p, count)
+		if request == nil {
+			return nil, 0, nil
+		}
+		accepted, err := q.DeliverHeaders(p.GetID(), headers(request.From, request.Count), procCh)
+		return request, accepted, err
+	}
+	canonical := func(from uint64, count int) []*types.SnailHeader { return chain[from-1 : from-1+uint64(count)] }
+
+	// Pieces are never smaller than the minimum nor span multiple gaps
+	tests := []struct {
+		capacity int
+		from     uint64
+		count    int
+	}{
+		{1, 1, 2}, {3, 3, 3}, {100, 6, 3},
+	}
+	for i, tt := range tests {
+		request, accepted, err := deliver(slow, tt.capacity, canonical)
+		if request == nil || err != nil {
+			t.Fatalf("test %d: piece not delivered: %v", i, err)
+		}
+		if request.From != tt.from || request.Count != tt.count || accepted != tt.count {
+			t.Errorf("test %d: piece mismatch: have from %d count %d accepted %d, want from %d count %d",
+				i, request.From, request.Count, accepted, tt.from, tt.count)
+		}
+	}
+	// The completed gap is forwarded at once
+	select {
+	case headers := <-procCh:
+		if len(headers) != MaxHeaderFetch || headers[len(headers)-1].Hash() != chain[7].Hash() {
+			t.Fatalf("forwarded headers mismatch: have %d headers", len(headers))
+		}
+	default:
+		t.Fatalf("completed gap not forwarded")
+	}
+	// A gap whose last piece mismatches the skeleton is refetched as a whole
+	if _, accepted, err := deliver(slow, 4, canonical); err != nil || accepted != 4 {
+		t.Fatalf("failed to deliver piece: accepted %d, err %v", accepted, err)
+	}
+	forged := func(from uint64, count int) []*types.SnailHeader {
+		return newQueueTestChain(chain[from-2], count, 1)
+	}
+	if _, _, err := deliver(slow, 4, forged); err == nil {
+		t.Fatalf("forged piece accepted")
+	}
+	if request := q.ReserveHeaders(slow, 4); request != nil {
+		t.Fatalf("gap reserved by peer failing it: from %d", request.From)
+	}
+	request, accepted, err := deliver(fast, MaxHeaderFetch, canonical)
+	if err != nil || request.From != 9 || accepted != MaxHeaderFetch {
+		t.Fatalf("failed to refetch gap: request %v, accepted %d, err %v", request, accepted, err)
+	}
+	select {
+	case headers := <-procCh:
+		if headers[0].Hash() != chain[8].Hash() || headers[len(headers)-1].Hash() != chain[15].Hash() {
+			t.Errorf("forwarded headers mismatch")
+		}
+	default:
+		t.Errorf("refetched gap not forwarded")
+	}
+}
@@ -235,6 +235,8 @@ type stateSync struct {
 	numUncommitted   int
 	bytesUncommitted int
 
+	peerStats map[string]*statePeerStats // Delivery quality of the peers served requests
+
 	deliver    chan *stateReq // Delivery channel multiplexing peer responses
 	cancel     chan struct{}  // Channel to signal a termination request
 	cancelOnce sync.Once      // Ensures cancel only ever gets called once
@@ -248,6 +250,36 @@ type stateTask struct {
 	attempts map[string]struct{}
 }
 
+// statePeerStats tracks the delivery quality of a single peer during a state
+// sync, allowing unreliable peers to be assigned smaller batches.
+type statePeerStats struct {
+	requested int // Number of trie nodes requested from the peer
+	delivered int // Number of useful trie nodes delivered by the peer
+	timeouts  int // Number of requests the peer failed to answer in time
+}
+
+// quality returns the ratio of requested trie nodes the peer delivered, with
+// the impact of past requests decaying as new ones are made.
+func (st *statePeerStats) quality() float64 {
+	if st.requested == 0 {
+		return 1
+	}
+	return float64(st.delivered) / float64(st.requested)
+}
+
+// update accounts a finished request, halving the weight of the history once
+// enough nodes were requested to keep the quality responsive.
+func (st *statePeerStats) update(requested, delivered int, timedOut bool) {
+	if st.requested > 4*MaxStateFetch {
+		st.requested, st.delivered = st.requested/2, st.delivered/2
+	}
+	st.requested += requested
+	st.delivered += delivered
+	if timedOut {
+		st.timeouts++
+	}
+}
+
 // newStateSync creates a new state trie download scheduler. This method does not
 // yet start the sync. The user needs to call run to initiate.
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
@@ -255,8 +287,9 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 		d:       d,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewLegacyKeccak256(),
-		tasks:   make(map[common.Hash]*stateTask),
-		deliver: make(chan *stateReq),
+		tasks:     make(map[common.Hash]*stateTask),
+		peerStats: make(map[string]*statePeerStats),
+		deliver:   make(chan *stateReq),
 		cancel:  make(chan struct{}),
 		done:    make(chan struct{}),
 	}
@@ -336,6 +369,7 @@ func (s *stateSync) loop() (err error) {
 				log.Warn("Node data write error", "err", err)
 				return err
 			}
+			s.stats(req.peer.GetID()).update(len(req.items), delivered, !req.dropped && req.timedOut())
 			req.peer.SetNodeDataIdle(delivered)
 		}
 	}
@@ -360,20 +394,35 @@ func (s *stateSync) commit(force bool) error {
 	return nil
 }
 
+// stats returns the delivery quality tracker of a peer, creating it if needed.
+func (s *stateSync) stats(id string) *statePeerStats {
+	st := s.peerStats[id]
+	if st == nil {
+		st = new(statePeerStats)
+		s.peerStats[id] = st
+	}
+	return st
+}
+
 // assignTasks attempts to assign new tasks to all idle peers, either from the
 // batch currently being retried, or fetching new data from the trie sync itself.
 func (s *stateSync) assignTasks() {
 	// Iterate over all idle peers and try to assign them state fetches
 	peers, _ := s.d.peers.NodeDataIdlePeers()
 	for _, p := range peers {
-		// Assign a batch of fetches proportional to the estimated latency/bandwidth
-		cap := p.NodeDataCapacity(s.d.requestRTT())
+		// Assign a batch of fetches proportional to the estimated latency/bandwidth,
+		// shrunk by the share of nodes the peer failed to deliver recently
+		st := s.stats(p.GetID())
+		cap := int(float64(p.NodeDataCapacity(s.d.requestRTT())) * st.quality())
+		if cap < 2 {
+			cap = 2
+		}
 		req := &stateReq{peer: p, timeout: s.d.requestTTL()}
 		s.fillTasks(cap, req)
 
 		// If the peer was assigned tasks to fetch, send the network request
 		if len(req.items) > 0 {
-			req.peer.GetLog().Trace("Requesting new batch of data", "type", "state", "count", len(req.items))
+			req.peer.GetLog().Trace("Requesting new batch of data", "type", "state", "count", len(req.items), "quality", st.quality(), "timeouts", st.timeouts)
 			select {
 			case s.d.trackStateReq <- req:
 				req.peer.FetchNodeData(req.items)
@@ -387,9 +436,19 @@ func (s *stateSync) assignTasks() {
 // fillTasks fills the given request object with a maximum of n state download
 // tasks to send to the remote peer.
 func (s *stateSync) fillTasks(n int, req *stateReq) {
-	// Refill available tasks from the scheduler.
-	if len(s.tasks) < n {
-		new := s.sched.Missing(n - len(s.tasks))
+	// Refill available tasks from the scheduler. Retried tasks the peer already
+	// failed to deliver don't count, otherwise a retry queue full of them would
+	// leave the peer idle while the trie still has plenty of missing nodes.
+	available := 0
+	for _, t := range s.tasks {
+		if _, ok := t.attempts[req.peer.GetID()]; !ok {
+			if available++; available == n {
+				break
+			}
+		}
+	}
+	if available < n {
+		new := s.sched.Missing(n - available)
 		for _, hash := range new {
 			s.tasks[hash] = &stateTask{make(map[string]struct{})}
 		}
@@ -440,6 +499,9 @@ func (s *stateSync) process(req *stateReq) (int, error) {
 			s.bytesUncommitted += len(blob)
 			progress = progress || prog
 			successful++
+
+			stateNodeMeter.Mark(1)
+			stateBytesMeter.Mark(int64(len(blob)))
 		case trie.ErrNotRequested:
 			unexpected++
 		case trie.ErrAlreadyProcessed:
@@ -0,0 +1,105 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/log"
+	"golang.org/x/crypto/sha3"
+)
+
+// Tests that peers keep being assigned fresh trie nodes even if the retry queue
+// is full of nodes they already failed to deliver.
+func TestStateFillTasksSkipsAttempted(t *testing.T) {
+	// Create a state trie with plenty of nodes below the root
+	srcDb := abeydb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(srcDb))
+	for i := 0; i < 256; i++ {
+		statedb.AddBalance(common.BytesToAddress([]byte{byte(i), 1}), big.NewInt(int64(i+1)))
+	}
+	root, _ := statedb.Commit(false)
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	s := &stateSync{
+		sched:     state.NewStateSync(root, abeydb.NewMemDatabase()),
+		keccak:    sha3.NewLegacyKeccak256(),
+		tasks:     make(map[common.Hash]*stateTask),
+		peerStats: make(map[string]*statePeerStats),
+	}
+	// Deliver the root so its children become missing
+	for _, hash := range s.sched.Missing(1) {
+		blob, _ := srcDb.Get(hash[:])
+		if _, _, err := s.processNodeData(blob); err != nil {
+			t.Fatalf("failed to process root: %v", err)
+		}
+	}
+	// Fill the retry queue with nodes the first peer failed to deliver
+	failer := newPeerConnection("failer", 63, nil, log.New())
+	failed := make(map[common.Hash]struct{})
+	for _, hash := range s.sched.Missing(4) {
+		s.tasks[hash] = &stateTask{attempts: map[string]struct{}{failer.GetID(): {}}}
+		failed[hash] = struct{}{}
+	}
+	req := &stateReq{peer: failer}
+	s.fillTasks(4, req)
+	if len(req.items) != 4 {
+		t.Fatalf("failing peer assigned %d tasks, want 4", len(req.items))
+	}
+	for _, hash := range req.items {
+		if _, ok := failed[hash]; ok {
+			t.Errorf("failing peer reassigned node %x", hash)
+		}
+	}
+	if len(s.tasks) != 4 {
+		t.Errorf("retry queue size mismatch: have %d, want 4", len(s.tasks))
+	}
+	// Another peer must pick up the retried nodes
+	req = &stateReq{peer: newPeerConnection("other", 63, nil, log.New())}
+	s.fillTasks(4, req)
+	if len(req.items) != 4 || len(s.tasks) != 0 {
+		t.Errorf("retried nodes not reassigned: assigned %d, left %d", len(req.items), len(s.tasks))
+	}
+}
+
+// Tests that the delivery quality of a peer tracks its recent behaviour.
+func TestStatePeerQuality(t *testing.T) {
+	st := new(statePeerStats)
+	if q := st.quality(); q != 1 {
+		t.Fatalf("unknown peer quality mismatch: have %v, want 1", q)
+	}
+	st.update(100, 50, false)
+	if q := st.quality(); q != 0.5 {
+		t.Fatalf("partial delivery quality mismatch: have %v, want 0.5", q)
+	}
+	st.update(100, 0, true)
+	if q := st.quality(); q != 0.25 || st.timeouts != 1 {
+		t.Fatalf("timeout quality mismatch: have %v/%d, want 0.25/1", q, st.timeouts)
+	}
+	// Good deliveries must recover the quality as the history decays
+	for i := 0; i < 64; i++ {
+		st.update(MaxStateFetch, MaxStateFetch, false)
+	}
+	if q := st.quality(); q < 0.99 {
+		t.Fatalf("quality not recovered: have %v", q)
+	}
+}
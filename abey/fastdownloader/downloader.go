@@ -29,6 +29,7 @@ import (
 	abey "github.com/abeychain/go-abey/abey/types"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/crash"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/event"
 	"github.com/abeychain/go-abey/log"
@@ -40,6 +41,7 @@ var (
 	MaxHashFetch    = 512 // Amount of hashes to be fetched per retrieval request
 	MaxBlockFetch   = 128 // Amount of blocks to be fetched per retrieval request
 	MaxHeaderFetch  = 192 // Amount of block headers to be fetched per retrieval request
+	MinHeaderFetch  = 16  // Amount of block headers to be fetched per request from a peer of unknown throughput
 	MaxBodyFetch    = 128 // Amount of block bodies to be fetched per retrieval request
 	MaxReceiptFetch = 256 // Amount of transaction receipts to allow fetching per request
 	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
@@ -212,7 +214,7 @@ func New(mode SyncMode, stateDb abeydb.Database, mux *event.TypeMux, chain Block
 		quitCh:        make(chan struct{}),
 	}
 
-	go dl.qosTuner()
+	go crash.Run("fastdownloader/qos", true, dl.qosTuner)
 	return dl
 }
 
@@ -613,19 +615,43 @@ func (d *Downloader) fetchHeaders(p abey.PeerConnection, from uint64, height int
 	<-timeout.C                 // timeout channel should be initially empty
 	defer timeout.Stop()
 
+	// Release the peer if its last request is left unanswered, otherwise it is
+	// never asked for headers again. Its throughput was not measured, so keep it.
+	pending := false
+	defer func() {
+		if pending {
+			throughput := p.GetHeaderThroughput()
+			p.SetHeadersIdle(0)
+			p.SetHeaderThroughput(throughput)
+		}
+	}()
+
 	var ttl time.Duration
-	getHeaders := func(from uint64) {
+	getHeaders := func(from uint64) error {
 		request = time.Now()
 
 		ttl = d.requestTTL()
 		timeout.Reset(ttl)
-		p.GetLog().Trace("Fetching full headers", "count", MaxHeaderFetch, "from", from)
-		go p.GetPeer().RequestHeadersByNumber(from, MaxHeaderFetch, 0, false, true)
 
+		// Size the request to the measured throughput of the peer, but never below
+		// the mini reorg protection, which would stall the sync on delayed headers
+		count := p.HeaderCapacity(d.requestRTT())
+		if count < MinHeaderFetch {
+			count = MinHeaderFetch
+		}
+		p.GetLog().Trace("Fetching full headers", "count", count, "from", from)
+		if err := p.FetchHeaders(from, count); err != nil {
+			p.GetLog().Debug("Failed to fetch fast headers", "from", from, "err", err)
+			return err
+		}
+		pending = true
+		return nil
 	}
 
 	// Start pulling the header chain skeleton until all is done
-	getHeaders(from)
+	if err := getHeaders(from); err != nil {
+		return err
+	}
 
 	for {
 		select {
@@ -641,6 +667,10 @@ func (d *Downloader) fetchHeaders(p abey.PeerConnection, from uint64, height int
 			headerReqTimer.UpdateSince(request)
 			timeout.Stop()
 
+			// Update the throughput of the peer, sizing the next request
+			p.SetHeadersIdle(packet.Items())
+			pending = false
+
 			// If the skeleton's finished, pull any remaining head headers directly from the origin
 			// If no more headers are inbound, notify the content fetchers and return
 			if packet.Items() == 0 {
@@ -704,14 +734,18 @@ func (d *Downloader) fetchHeaders(p abey.PeerConnection, from uint64, height int
 				}
 				from += uint64(len(headers[:index+1]))
 				p.GetLog().Trace("Scheduling getHeaders", "count", len(headers), "from", from, "headerProcCh", len(d.headerProcCh))
-				getHeaders(from)
+				if err := getHeaders(from); err != nil {
+					return err
+				}
 			} else {
 				// No headers delivered, or all of them being delayed, sleep a bit and retry
 				p.GetLog().Trace("All snail headers delayed, waiting", "from", from)
 				select {
 				case <-time.After(fsHeaderContCheck):
 					p.GetLog().Trace("All snail headers delayed, waiting fsHeaderContCheck", "from", from)
-					getHeaders(from)
+					if err := getHeaders(from); err != nil {
+						return err
+					}
 					continue
 				case <-d.cancelCh:
 					return errCancelHeaderFetch
@@ -730,6 +764,8 @@ func (d *Downloader) fetchHeaders(p abey.PeerConnection, from uint64, height int
 			headerTimeoutMeter.Mark(1)
 			p.GetLog().Trace("drop peer fast fetchHeaders timout ", "id", p.GetID())
 			d.dropPeer(p.GetID(), types.DownloaderFetchCall)
+			p.SetHeadersIdle(0)
+			pending = false
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.bodyWakeCh, d.receiptWakeCh} {
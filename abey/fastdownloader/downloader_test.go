@@ -0,0 +1,105 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package fastdownloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	abey "github.com/abeychain/go-abey/abey/types"
+	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/log"
+)
+
+// headerPeer is a remote peer recording the sizes of the header requests.
+type headerPeer struct {
+	abey.Peer
+	requests chan int
+}
+
+func (p *headerPeer) RequestHeadersByNumber(from uint64, count int, skip int, reverse bool, isFastchain bool) error {
+	p.requests <- count
+	return nil
+}
+
+func newHeaderTester() (*Downloader, *peerConnection, chan int) {
+	requests := make(chan int, 1)
+	d := New(FullSync, abeydb.NewMemDatabase(), new(event.TypeMux), nil, nil, nil)
+	p := newPeerConnection("origin", 63, &headerPeer{requests: requests}, log.Root())
+	return d, p, requests
+}
+
+// Tests that header requests are sized to the throughput of the peer, and that
+// the peer is released for later syncs when the fetch is aborted.
+func TestFetchHeadersCapacity(t *testing.T) {
+	tests := []struct {
+		throughput float64
+		count      int
+	}{
+		{0, MinHeaderFetch},
+		{1, 19},
+		{100, MaxHeaderFetch},
+	}
+	for i, tt := range tests {
+		d, p, requests := newHeaderTester()
+		p.SetHeaderThroughput(tt.throughput)
+
+		errc := make(chan error, 1)
+		go func() { errc <- d.fetchHeaders(p, 1, 1000, 0) }()
+
+		select {
+		case count := <-requests:
+			if count != tt.count {
+				t.Errorf("test %d: request size mismatch: have %d, want %d", i, count, tt.count)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("test %d: headers not requested", i)
+		}
+		// Headers from other peers are ignored, the origin stays busy
+		d.headerCh <- &headerPack{peerID: "other"}
+		d.cancel()
+		if err := <-errc; err != errCancelHeaderFetch {
+			t.Errorf("test %d: fetch error mismatch: have %v, want %v", i, err, errCancelHeaderFetch)
+		}
+		if p.GetHeaderIdle() != 0 {
+			t.Errorf("test %d: peer left busy after cancellation", i)
+		}
+		if have := p.GetHeaderThroughput(); have != tt.throughput {
+			t.Errorf("test %d: throughput mismatch: have %v, want %v", i, have, tt.throughput)
+		}
+		d.Terminate()
+	}
+}
+
+// Tests that failing to request headers from a busy peer aborts the fetch.
+func TestFetchHeadersBusyPeer(t *testing.T) {
+	d, p, requests := newHeaderTester()
+	defer d.Terminate()
+
+	if err := p.FetchHeaders(1, MinHeaderFetch); err != nil {
+		t.Fatalf("failed to request headers: %v", err)
+	}
+	<-requests
+
+	if err := d.fetchHeaders(p, 1, 1000, 0); err != errAlreadyFetching {
+		t.Errorf("fetch error mismatch: have %v, want %v", err, errAlreadyFetching)
+	}
+	if p.GetHeaderIdle() != 1 {
+		t.Errorf("request of another fetch released")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	abey "github.com/abeychain/go-abey/abey/types"
@@ -39,6 +40,34 @@ var (
 	blockCacheSizeWeight = 0.1              // Multiplier to approximate the average block size based on past ones
 )
 
+// SetBlockCacheMemory sets the maximum amount of memory in bytes to use for
+// block caching. It must be called before any synchronisation is started.
+func SetBlockCacheMemory(size int) {
+	blockCacheMemory = size
+}
+
+// blockCacheShrunk is non-zero while memory pressure shrinks the block cache.
+var blockCacheShrunk int32
+
+// ShrinkBlockCache quarters the memory used for block caching while the node is
+// under memory pressure, throttling the download until it is relieved.
+func ShrinkBlockCache(shrink bool) {
+	if shrink {
+		atomic.StoreInt32(&blockCacheShrunk, 1)
+	} else {
+		atomic.StoreInt32(&blockCacheShrunk, 0)
+	}
+}
+
+// blockCacheLimit returns the amount of memory in bytes currently allowed for
+// block caching.
+func blockCacheLimit() common.StorageSize {
+	if atomic.LoadInt32(&blockCacheShrunk) != 0 {
+		return common.StorageSize(blockCacheMemory / 4)
+	}
+	return common.StorageSize(blockCacheMemory)
+}
+
 var (
 	errNoFetchesPending = errors.New("Fast no fetches pending")
 	errStaleDelivery    = errors.New("Fast stale delivery")
@@ -220,8 +249,8 @@ func (q *queue) ShouldThrottleReceipts() bool {
 func (q *queue) resultSlots(pendPool map[string]*abey.FetchRequest, donePool map[common.Hash]struct{}) int {
 	// Calculate the maximum length capped by the memory limit
 	limit := len(q.resultCache)
-	if common.StorageSize(len(q.resultCache))*q.resultSize > common.StorageSize(blockCacheMemory) {
-		limit = int((common.StorageSize(blockCacheMemory) + q.resultSize - 1) / q.resultSize)
+	if memory := blockCacheLimit(); common.StorageSize(len(q.resultCache))*q.resultSize > memory {
+		limit = int((memory + q.resultSize - 1) / q.resultSize)
 	}
 	// Calculate the number of slots already finished
 	finished := 0
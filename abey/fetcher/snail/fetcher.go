@@ -38,10 +38,19 @@ const (
 	maxQueueDist  = 32                     // Maximum allowed distance from the chain head to queue
 	hashLimit     = 256                    // Maximum number of unique blocks a peer may have announced
 	blockLimit    = 64                     // Maximum number of unique blocks a peer may have delivered
+	bodyLimit     = 8                      // Maximum number of block bodies concurrently fetched from a peer
+	sealWorkers   = 4                      // Number of goroutines verifying the seals of announced headers
+	sealQueue     = 64                     // Maximum number of announced headers waiting for seal verification
+	maxFutureTime = 15 * time.Second       // Maximum time an announced header may be ahead of the local clock
 )
 
 var (
-	errTerminated = errors.New("terminated")
+	errTerminated        = errors.New("terminated")
+	errInvalidNumber     = errors.New("invalid block number")
+	errInvalidDifficulty = errors.New("non-positive difficulty")
+	errInvalidFruitsHash = errors.New("empty fruits hash")
+	errMissingPointer    = errors.New("missing pointer or fast block")
+	errFutureHeader      = errors.New("header timestamp too far in the future")
 )
 
 // blockRetrievalFn is a callback type for retrieving a block from the local chain.
@@ -71,6 +80,12 @@ type peerDropFn func(id string, call uint32)
 // getFruitsHash is a callback type for get fruit hash.
 type getFruitsHash func(header *types.SnailHeader, fruits []*types.SnailBlock) common.Hash
 
+// headerVerification is the result of checking the seal of an announced header.
+type headerVerification struct {
+	announce *announce // Announcement with the header verified
+	err      error     // Verification failure, nil if the seal is valid
+}
+
 // announce is the hash notification of the availability of a new block in the
 // network.
 type announce struct {
@@ -116,8 +131,10 @@ type Fetcher struct {
 	headerFilter chan chan *headerFilterTask
 	bodyFilter   chan chan *bodyFilterTask
 
-	done chan common.Hash
-	quit chan struct{}
+	done     chan common.Hash
+	verify   chan *announce           // Announced headers queued for seal verification
+	verified chan *headerVerification // Seal verification results
+	quit     chan struct{}
 
 	// Announce states
 	announces  map[string]int              // Per peer announce counts to prevent memory exhaustion
@@ -134,6 +151,7 @@ type Fetcher struct {
 	// Callbacks
 	getBlock       blockRetrievalFn   // Retrieves a block from the local chain
 	verifyHeader   headerVerifierFn   // Checks if a block's headers have a valid proof of work
+	verifySeal     headerVerifierFn   // Checks the proof of work of an announced header before fetching the body
 	broadcastBlock blockBroadcasterFn // Broadcasts a block to connected peers
 	chainHeight    chainHeightFn      // Retrieves the current chain's height
 	insertChain    chainInsertFn      // Injects a batch of blocks into the chain
@@ -150,13 +168,15 @@ type Fetcher struct {
 }
 
 // New creates a block fetcher to retrieve blocks based on hash announcements.
-func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBlock blockBroadcasterFn, chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn, fruitsHash getFruitsHash) *Fetcher {
+func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, verifySeal headerVerifierFn, broadcastBlock blockBroadcasterFn, chainHeight chainHeightFn, insertChain chainInsertFn, dropPeer peerDropFn, fruitsHash getFruitsHash) *Fetcher {
 	return &Fetcher{
 		notify:         make(chan *announce),
 		inject:         make(chan *inject),
 		headerFilter:   make(chan chan *headerFilterTask),
 		bodyFilter:     make(chan chan *bodyFilterTask),
 		done:           make(chan common.Hash),
+		verify:         make(chan *announce, sealQueue),
+		verified:       make(chan *headerVerification),
 		quit:           make(chan struct{}),
 		announces:      make(map[string]int),
 		announced:      make(map[common.Hash][]*announce),
@@ -168,6 +188,7 @@ func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBloc
 		queued:         make(map[common.Hash]*inject),
 		getBlock:       getBlock,
 		verifyHeader:   verifyHeader,
+		verifySeal:     verifySeal,
 		broadcastBlock: broadcastBlock,
 		chainHeight:    chainHeight,
 		insertChain:    insertChain,
@@ -180,6 +201,9 @@ func New(getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBloc
 // Start boots up the announcement based synchroniser, accepting and processing
 // hash notifications and block fetches until termination requested.
 func (f *Fetcher) Start() {
+	for i := 0; i < sealWorkers; i++ {
+		go f.verifyLoop()
+	}
 	go f.loop()
 }
 
@@ -293,6 +317,12 @@ func (f *Fetcher) loop() {
 				f.forgetHash(hash)
 			}
 		}
+		// Clean up any expired body fetches, freeing up the peer's fetch slots
+		for hash, announce := range f.completing {
+			if f.queued[hash] == nil && time.Since(announce.time) > fetchTimeout {
+				f.forgetHash(hash)
+			}
+		}
 		// Import any queued blocks that could potentially fit
 		for !f.queue.Empty() {
 			height := f.chainHeight()
@@ -408,17 +438,38 @@ func (f *Fetcher) loop() {
 			f.rescheduleFetch(fetchTimer)
 
 		case <-completeTimer.C:
-			// At least one header's timer ran out, retrieve everything
+			// At least one header's timer ran out, retrieve everything the
+			// announcing peers have fetch slots left for
 			request := make(map[string][]common.Hash)
 
+			pending := make(map[string]int)
+			for hash, announce := range f.completing {
+				if f.queued[hash] == nil {
+					pending[announce.origin]++
+				}
+			}
+			throttled := false
 			for hash, announces := range f.fetched {
-				// Pick a random peer to retrieve from, reset all others
-				announce := announces[rand.Intn(len(announces))]
+				// Pick a random peer with free slots to retrieve from, reset all others
+				var candidates []*announce
+				for _, announce := range announces {
+					if pending[announce.origin] < bodyLimit {
+						candidates = append(candidates, announce)
+					}
+				}
+				if len(candidates) == 0 {
+					throttled = true
+					continue
+				}
+				announce := candidates[rand.Intn(len(candidates))]
 				f.forgetHash(hash)
 
 				// If the block still didn't arrive, queue for completion
 				if f.getBlock(hash) == nil {
 					request[announce.origin] = append(request[announce.origin], hash)
+					pending[announce.origin]++
+
+					announce.time = time.Now()
 					f.completing[hash] = announce
 				}
 			}
@@ -433,8 +484,14 @@ func (f *Fetcher) loop() {
 				bodyFetchMeter.Mark(int64(len(hashes)))
 				go f.completing[hashes[0]].fetchBodies(hashes, false, types.FetcherCall)
 			}
-			// Schedule the next fetch if blocks are still pending
-			f.rescheduleComplete(completeTimer)
+			// Schedule the next fetch if blocks are still pending, retrying the
+			// throttled ones only after some slots had time to free up
+			if throttled {
+				bodyThrottleMeter.Mark(1)
+				completeTimer.Reset(gatherSlack)
+			} else {
+				f.rescheduleComplete(completeTimer)
+			}
 
 		case filter := <-f.headerFilter:
 			// Headers arrived from a remote peer. Extract those that were explicitly
@@ -455,7 +512,7 @@ func (f *Fetcher) loop() {
 				hash := header.Hash()
 
 				// Filter fetcher-requested headers from other synchronisation algorithms
-				if announce := f.fetching[hash]; announce != nil && announce.origin == task.peer && f.fetched[hash] == nil && f.completing[hash] == nil && f.queued[hash] == nil {
+				if announce := f.fetching[hash]; announce != nil && announce.origin == task.peer && announce.header == nil && f.fetched[hash] == nil && f.completing[hash] == nil && f.queued[hash] == nil {
 					// If the delivered header does not match the promised number, drop the announcer
 					if header.Number.Uint64() != announce.number {
 						log.Trace("Invalid block number fetched", "peer", announce.origin, "hash", header.Hash(), "announced", announce.number, "provided", header.Number)
@@ -496,15 +553,15 @@ func (f *Fetcher) loop() {
 			case <-f.quit:
 				return
 			}
-			// Schedule the retrieved headers for body completion
+			// Verify the retrieved headers before spending bandwidth on their bodies
 			for _, announce := range incomplete {
-				hash := announce.header.Hash()
-				if _, ok := f.completing[hash]; ok {
-					continue
-				}
-				f.fetched[hash] = append(f.fetched[hash], announce)
-				if len(f.fetched) == 1 {
-					f.rescheduleComplete(completeTimer)
+				select {
+				case f.verify <- announce:
+				default:
+					// Verifiers are saturated, let a later announcement retry
+					log.Debug("Seal verification queue full", "peer", announce.origin, "number", announce.number, "hash", announce.hash)
+					propAnnounceThrottleMeter.Mark(1)
+					f.forgetHash(announce.hash)
 				}
 			}
 			// Schedule the header-only blocks for import
@@ -514,6 +571,30 @@ func (f *Fetcher) loop() {
 				}
 			}
 
+		case result := <-f.verified:
+			// An announced header was verified, drop the announcer if it's invalid
+			announce := result.announce
+			hash := announce.header.Hash()
+
+			if f.fetching[hash] != announce {
+				break // Expired or imported by other means meanwhile
+			}
+			if result.err != nil {
+				log.Debug("Invalid announced header", "peer", announce.origin, "number", announce.number, "hash", hash, "err", result.err)
+				propAnnounceBadMeter.Mark(1)
+				f.dropPeer(announce.origin, types.SFetcherSealCall)
+				f.forgetHash(hash)
+				break
+			}
+			// Schedule the header for body completion
+			if _, ok := f.completing[hash]; ok {
+				break
+			}
+			f.fetched[hash] = append(f.fetched[hash], announce)
+			if len(f.fetched) == 1 {
+				f.rescheduleComplete(completeTimer)
+			}
+
 		case filter := <-f.bodyFilter:
 			// Block bodies arrived, extract any explicitly requested blocks, return the rest
 			var task *bodyFilterTask
@@ -571,6 +652,49 @@ func (f *Fetcher) loop() {
 	}
 }
 
+// verifyLoop checks the plausibility and the proof of work of the queued
+// announced headers, reporting the results back to the fetcher loop.
+func (f *Fetcher) verifyLoop() {
+	for {
+		select {
+		case announce := <-f.verify:
+			err := plausibleHeader(announce.header, time.Now())
+			if err == nil {
+				err = f.verifySeal(announce.header)
+			}
+			select {
+			case f.verified <- &headerVerification{announce: announce, err: err}:
+			case <-f.quit:
+				return
+			}
+
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// plausibleHeader checks the fields of a header that can be validated without
+// its parent or body, against the local time now.
+func plausibleHeader(header *types.SnailHeader, now time.Time) error {
+	if header.Number == nil || header.Number.Sign() <= 0 {
+		return errInvalidNumber
+	}
+	if header.Difficulty == nil || header.Difficulty.Sign() <= 0 || header.FruitDifficulty == nil || header.FruitDifficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	if header.FruitsHash == (common.Hash{}) {
+		return errInvalidFruitsHash
+	}
+	if header.PointerNumber == nil || header.PointerHash == (common.Hash{}) || header.FastNumber == nil {
+		return errMissingPointer
+	}
+	if header.Time == nil || header.Time.Cmp(big.NewInt(now.Add(maxFutureTime).Unix())) > 0 {
+		return errFutureHeader
+	}
+	return nil
+}
+
 // rescheduleFetch resets the specified fetch timer to the next announce timeout.
 func (f *Fetcher) rescheduleFetch(fetch *time.Timer) {
 	// Short circuit if no blocks are announced
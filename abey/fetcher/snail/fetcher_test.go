@@ -46,10 +46,21 @@ var (
 	engine       = minerva.NewFaker()
 	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	testAddress  = crypto.PubkeyToAddress(testKey.PublicKey)
-	unknownBlock = types.NewSnailBlock(&types.SnailHeader{Number: big.NewInt(0), Difficulty: big.NewInt(150), FruitDifficulty: big.NewInt(3)}, nil, nil, nil, nil)
+	unknownFruit = types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(0), FastNumber: big.NewInt(0), Time: big.NewInt(0)})
+	unknownBlock = types.NewSnailBlock(&types.SnailHeader{Number: big.NewInt(0), Difficulty: big.NewInt(150), FruitDifficulty: big.NewInt(3)}, []*types.SnailBlock{unknownFruit}, nil, nil, testConfig)
+
+	// The genesis commit initializes the staking state, which needs the forks
+	// configured, committees are still elected by the snail chain though
+	testConfig = func() *params.ChainConfig {
+		config := *params.TestChainConfig
+		config.TIP7 = &params.BlockConfig{FastNumber: big.NewInt(0)}
+		config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(1000000), CID: big.NewInt(1000)}
+		config.TIP9 = &params.BlockConfig{FastNumber: big.NewInt(1000000), SnailNumber: big.NewInt(1000)}
+		return &config
+	}()
 
 	gspec = &core.Genesis{
-		Config:     params.TestChainConfig,
+		Config:     testConfig,
 		Alloc:      types.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000)}},
 		Difficulty: big.NewInt(20000),
 	}
@@ -71,8 +82,11 @@ func makeChain(n int, seed byte, parent *types.SnailBlock) ([]common.Hash, map[c
 		panic(err)
 	}
 
-	blocks := snailchain.GenerateChain(params.TestChainConfig, blockchain, []*types.SnailBlock{parent}, n, 7, func(i int, block *snailchain.BlockGen) {
+	// Announced headers are checked for plausibility, so fill them in as mined
+	blocks := snailchain.GenerateChain(testConfig, blockchain, []*types.SnailBlock{parent}, n, 7, func(i int, block *snailchain.BlockGen) {
 		block.SetCoinbase(common.Address{seed})
+		block.SetPointer(block.PrevBlock(-1).Header())
+		block.SetFruitDifficulty(params.MinimumFruitDifficulty)
 	})
 
 	hashes := make([]common.Hash, n+1)
@@ -93,6 +107,7 @@ type fetcherTester struct {
 	hashes []common.Hash                     // Hash chain belonging to the tester
 	blocks map[common.Hash]*types.SnailBlock // Blocks belonging to the tester
 	drops  map[string]bool                   // Map of peers dropped by the fetcher
+	seals  map[common.Hash]bool              // Announced headers with an invalid seal
 
 	lock sync.RWMutex
 }
@@ -103,13 +118,14 @@ func newTester() *fetcherTester {
 		hashes: []common.Hash{genesis.Hash()},
 		blocks: map[common.Hash]*types.SnailBlock{genesis.Hash(): genesis},
 		drops:  make(map[string]bool),
+		seals:  make(map[common.Hash]bool),
 	}
 
 	fruitHash := func(header *types.SnailHeader, fruits []*types.SnailBlock) common.Hash {
 		return types.DeriveSha(types.Fruits(fruits))
 	}
 
-	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer, fruitHash)
+	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.verifySeal, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer, fruitHash)
 	tester.fetcher.Start()
 
 	return tester
@@ -128,6 +144,17 @@ func (f *fetcherTester) verifyHeader(header *types.SnailHeader) error {
 	return nil
 }
 
+// verifySeal rejects the headers marked as badly sealed.
+func (f *fetcherTester) verifySeal(header *types.SnailHeader) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.seals[header.Hash()] {
+		return errors.New("invalid seal")
+	}
+	return nil
+}
+
 // broadcastBlock is a nop placeholder for the block broadcasting.
 func (f *fetcherTester) broadcastBlock(block *types.SnailBlock, propagate bool) {
 }
@@ -783,3 +810,113 @@ func TestBlockMemoryExhaustionAttack(t *testing.T) {
 	}
 	verifyImportDone(t, imported)
 }
+
+// Tests that announced headers with an invalid seal get their announcer dropped
+// before any body is requested.
+func TestInvalidSealAnnouncement(t *testing.T) {
+	hashes, blocks := makeChain(1, 0, genesis)
+
+	tester := newTester()
+	tester.seals[hashes[0]] = true
+
+	completing := make(chan []common.Hash)
+	tester.fetcher.completingHook = func(hashes []common.Hash) { completing <- hashes }
+
+	imported := make(chan *types.SnailBlock)
+	tester.fetcher.importedHook = func(block *types.SnailBlock) { imported <- block }
+
+	// Announce a badly sealed block, check for a drop without a body fetch
+	badHeaderFetcher := tester.makeHeaderFetcher("bad", blocks, -gatherSlack)
+	badBodyFetcher := tester.makeBodyFetcher("bad", blocks, 0)
+	tester.fetcher.Notify("bad", hashes[0], 1, time.Now().Add(-arriveTimeout), badHeaderFetcher, badBodyFetcher)
+	verifyCompletingEvent(t, completing, false)
+	verifyImportEvent(t, imported, false)
+
+	tester.lock.Lock()
+	dropped := tester.drops["bad"]
+	tester.seals[hashes[0]] = false
+	tester.lock.Unlock()
+
+	if !dropped {
+		t.Fatalf("peer with invalid sealed announcement not dropped")
+	}
+	// Make sure a valid seal passes through to the body fetch and import
+	goodHeaderFetcher := tester.makeHeaderFetcher("good", blocks, -gatherSlack)
+	goodBodyFetcher := tester.makeBodyFetcher("good", blocks, 0)
+	tester.fetcher.Notify("good", hashes[0], 1, time.Now().Add(-arriveTimeout), goodHeaderFetcher, goodBodyFetcher)
+	verifyCompletingEvent(t, completing, true)
+	verifyImportEvent(t, imported, true)
+
+	tester.lock.RLock()
+	dropped = tester.drops["good"]
+	tester.lock.RUnlock()
+
+	if dropped {
+		t.Fatalf("peer with valid sealed announcement dropped")
+	}
+	verifyImportDone(t, imported)
+}
+
+// Tests that a peer not delivering block bodies is never asked for more than
+// bodyLimit of them at once.
+func TestBodyFetchLimit(t *testing.T) {
+	hashes, blocks := makeChain(bodyLimit+4, 0, genesis)
+
+	tester := newTester()
+	headerFetcher := tester.makeHeaderFetcher("stalling", blocks, -gatherSlack)
+	bodyFetcher := func(hashes []common.Hash, fast bool, call uint32) error { return nil }
+
+	var requested int32
+	tester.fetcher.completingHook = func(hashes []common.Hash) { atomic.AddInt32(&requested, int32(len(hashes))) }
+
+	for i := len(hashes) - 2; i >= 0; i-- {
+		tester.fetcher.Notify("stalling", hashes[i], uint64(len(hashes)-i-1), time.Now().Add(-arriveTimeout), headerFetcher, bodyFetcher)
+	}
+	// Give the fetcher a few retries, but less than the fetch timeout
+	time.Sleep(10 * gatherSlack)
+	if have := atomic.LoadInt32(&requested); have != bodyLimit {
+		t.Fatalf("requested body count mismatch: have %d, want %d", have, bodyLimit)
+	}
+}
+
+// Tests that announced headers are checked for plausibility before their seal.
+func TestPlausibleHeader(t *testing.T) {
+	now := time.Now()
+	valid := func() *types.SnailHeader {
+		return &types.SnailHeader{
+			Number:          big.NewInt(1),
+			Difficulty:      big.NewInt(150),
+			FruitDifficulty: big.NewInt(3),
+			FruitsHash:      common.Hash{1},
+			PointerHash:     common.Hash{2},
+			PointerNumber:   big.NewInt(0),
+			FastNumber:      big.NewInt(10),
+			Time:            big.NewInt(now.Unix()),
+		}
+	}
+	tests := []struct {
+		mutate func(h *types.SnailHeader)
+		err    error
+	}{
+		{func(h *types.SnailHeader) {}, nil},
+		{func(h *types.SnailHeader) { h.Number = nil }, errInvalidNumber},
+		{func(h *types.SnailHeader) { h.Number = big.NewInt(0) }, errInvalidNumber},
+		{func(h *types.SnailHeader) { h.Difficulty = nil }, errInvalidDifficulty},
+		{func(h *types.SnailHeader) { h.Difficulty = big.NewInt(0) }, errInvalidDifficulty},
+		{func(h *types.SnailHeader) { h.FruitDifficulty = big.NewInt(-1) }, errInvalidDifficulty},
+		{func(h *types.SnailHeader) { h.FruitsHash = common.Hash{} }, errInvalidFruitsHash},
+		{func(h *types.SnailHeader) { h.PointerHash = common.Hash{} }, errMissingPointer},
+		{func(h *types.SnailHeader) { h.PointerNumber = nil }, errMissingPointer},
+		{func(h *types.SnailHeader) { h.FastNumber = nil }, errMissingPointer},
+		{func(h *types.SnailHeader) { h.Time = nil }, errFutureHeader},
+		{func(h *types.SnailHeader) { h.Time = big.NewInt(now.Add(maxFutureTime).Unix()) }, nil},
+		{func(h *types.SnailHeader) { h.Time = big.NewInt(now.Add(maxFutureTime + time.Second).Unix()) }, errFutureHeader},
+	}
+	for i, tt := range tests {
+		header := valid()
+		tt.mutate(header)
+		if err := plausibleHeader(header, now); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -23,16 +23,19 @@ import (
 )
 
 var (
-	propAnnounceInMeter    = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/in", nil)
-	propAnnounceOutTimer   = metrics.NewRegisteredTimer("abey/sfetcher/prop/announces/out", nil)
-	propAnnounceDropMeter  = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/drop", nil)
-	propAnnounceDOSMeter   = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/dos", nil)
-	propBroadcastInMeter   = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/in", nil)
-	propBroadcastOutTimer  = metrics.NewRegisteredTimer("abey/sfetcher/prop/broadcasts/out", nil)
-	propBroadcastDropMeter = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/drop", nil)
-	propBroadcastDOSMeter  = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/dos", nil)
-	headerFetchMeter       = metrics.NewRegisteredMeter("abey/sfetcher/fetch/headers", nil)
-	bodyFetchMeter         = metrics.NewRegisteredMeter("abey/sfetcher/fetch/bodies", nil)
+	propAnnounceInMeter       = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/in", nil)
+	propAnnounceOutTimer      = metrics.NewRegisteredTimer("abey/sfetcher/prop/announces/out", nil)
+	propAnnounceDropMeter     = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/drop", nil)
+	propAnnounceDOSMeter      = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/dos", nil)
+	propAnnounceBadMeter      = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/bad", nil)
+	propAnnounceThrottleMeter = metrics.NewRegisteredMeter("abey/sfetcher/prop/announces/throttled", nil)
+	propBroadcastInMeter      = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/in", nil)
+	propBroadcastOutTimer     = metrics.NewRegisteredTimer("abey/sfetcher/prop/broadcasts/out", nil)
+	propBroadcastDropMeter    = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/drop", nil)
+	propBroadcastDOSMeter     = metrics.NewRegisteredMeter("abey/sfetcher/prop/broadcasts/dos", nil)
+	headerFetchMeter          = metrics.NewRegisteredMeter("abey/sfetcher/fetch/headers", nil)
+	bodyFetchMeter            = metrics.NewRegisteredMeter("abey/sfetcher/fetch/bodies", nil)
+	bodyThrottleMeter         = metrics.NewRegisteredMeter("abey/sfetcher/fetch/bodies/throttled", nil)
 
 	headerFilterInMeter  = metrics.NewRegisteredMeter("abey/sfetcher/filter/headers/in", nil)
 	headerFilterOutMeter = metrics.NewRegisteredMeter("abey/sfetcher/filter/headers/out", nil)
@@ -168,6 +168,81 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// droppedTransaction is the notification sent for a transaction evicted from
+// the transaction pool.
+type droppedTransaction struct {
+	Hash   common.Hash `json:"hash"`
+	Reason string      `json:"reason"`
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is evicted from the transaction pool, reporting its hash and the
+// reason (underpriced, replaced, timedout, invalidnonce, unpayable, ratelimited).
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan types.DroppedTxsEvent, 128)
+		droppedTxSub := api.events.SubscribeDroppedTxs(drops)
+
+		for {
+			select {
+			case ev := <-drops:
+				for _, tx := range ev.Txs {
+					notifier.Notify(rpcSub.ID, &droppedTransaction{Hash: tx.Hash(), Reason: ev.Reason})
+				}
+			case <-rpcSub.Err():
+				droppedTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				droppedTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// NewFruits creates a subscription that is triggered each time fruits are
+// accepted into the fruit pool, sending the header of every fruit. Pools can
+// match the headers against their shares to learn which ones made it on-chain.
+func (api *PublicFilterAPI) NewFruits(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		fruits := make(chan []*types.SnailHeader, 128)
+		fruitsSub := api.events.SubscribeNewFruits(fruits)
+
+		for {
+			select {
+			case headers := <-fruits:
+				for _, h := range headers {
+					notifier.Notify(rpcSub.ID, h)
+				}
+			case <-rpcSub.Err():
+				fruitsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				fruitsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with abey_getFilterChanges.
 //
@@ -38,6 +38,8 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeNewTxsEvent(chan<- types.NewTxsEvent) event.Subscription
+	SubscribeDroppedTxsEvent(chan<- types.DroppedTxsEvent) event.Subscription
+	SubscribeNewFruitEvent(chan<- types.NewFruitsEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- types.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
@@ -52,6 +52,12 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// DroppedTransactionsSubscription queries transactions evicted from the
+	// transaction pool
+	DroppedTransactionsSubscription
+	// FruitsSubscription queries the headers of fruits accepted into the fruit
+	// pool
+	FruitsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -67,6 +73,10 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// dropsChanSize is the size of channel listening to DroppedTxsEvent.
+	dropsChanSize = 256
+	// fruitsChanSize is the size of channel listening to NewFruitsEvent.
+	fruitsChanSize = 256
 )
 
 var (
@@ -81,6 +91,8 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	drops     chan types.DroppedTxsEvent
+	fruits    chan []*types.SnailHeader
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -98,6 +110,8 @@ type EventSystem struct {
 	logsSub       event.Subscription         // Subscription for new log event
 	rmLogsSub     event.Subscription         // Subscription for removed log event
 	chainSub      event.Subscription         // Subscription for new chain event
+	dropsSub      event.Subscription         // Subscription for dropped transaction event
+	fruitsSub     event.Subscription         // Subscription for new fruit event
 	pendingLogSub *event.TypeMuxSubscription // Subscription for pending log event
 
 	// Channels
@@ -107,6 +121,8 @@ type EventSystem struct {
 	logsCh    chan []*types.Log           // Channel to receive new log event
 	rmLogsCh  chan types.RemovedLogsEvent // Channel to receive removed log event
 	chainCh   chan types.FastChainEvent   // Channel to receive new chain event
+	dropsCh   chan types.DroppedTxsEvent  // Channel to receive dropped transactions event
+	fruitsCh  chan types.NewFruitsEvent   // Channel to receive new fruits event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -126,6 +142,8 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 		logsCh:    make(chan []*types.Log, logsChanSize),
 		rmLogsCh:  make(chan types.RemovedLogsEvent, rmLogsChanSize),
 		chainCh:   make(chan types.FastChainEvent, chainEvChanSize),
+		dropsCh:   make(chan types.DroppedTxsEvent, dropsChanSize),
+		fruitsCh:  make(chan types.NewFruitsEvent, fruitsChanSize),
 	}
 
 	// Subscribe events
@@ -133,12 +151,14 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 	m.logsSub = m.backend.SubscribeLogsEvent(m.logsCh)
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
+	m.dropsSub = m.backend.SubscribeDroppedTxsEvent(m.dropsCh)
+	m.fruitsSub = m.backend.SubscribeNewFruitEvent(m.fruitsCh)
 	// TODO(rjl493456442): use feed to subscribe pending log event
 	m.pendingLogSub = m.mux.Subscribe(types.PendingLogsEvent{})
 
 	// Make sure none of the subscriptions are empty
 	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil ||
-		m.pendingLogSub.Closed() {
+		m.dropsSub == nil || m.fruitsSub == nil || m.pendingLogSub.Closed() {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -174,6 +194,8 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.drops:
+			case <-sub.f.fruits:
 			}
 		}
 
@@ -241,6 +263,8 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit abeychain.FilterQuery, log
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    make(chan []*types.SnailHeader),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -258,6 +282,8 @@ func (es *EventSystem) subscribeLogs(crit abeychain.FilterQuery, logs chan []*ty
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    make(chan []*types.SnailHeader),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -275,6 +301,8 @@ func (es *EventSystem) subscribePendingLogs(crit abeychain.FilterQuery, logs cha
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    make(chan []*types.SnailHeader),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -291,6 +319,8 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    make(chan []*types.SnailHeader),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -307,6 +337,44 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    make(chan []*types.SnailHeader),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeDroppedTxs creates a subscription that writes the transactions
+// evicted from the transaction pool, along with the reason of the eviction.
+func (es *EventSystem) SubscribeDroppedTxs(drops chan types.DroppedTxsEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       DroppedTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		drops:     drops,
+		fruits:    make(chan []*types.SnailHeader),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeNewFruits creates a subscription that writes the headers of the
+// fruits accepted into the fruit pool.
+func (es *EventSystem) SubscribeNewFruits(fruits chan []*types.SnailHeader) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FruitsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		drops:     make(chan types.DroppedTxsEvent),
+		fruits:    fruits,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -355,6 +423,18 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		for _, f := range filters[PendingTransactionsSubscription] {
 			f.hashes <- hashes
 		}
+	case types.DroppedTxsEvent:
+		for _, f := range filters[DroppedTransactionsSubscription] {
+			f.drops <- e
+		}
+	case types.NewFruitsEvent:
+		headers := make([]*types.SnailHeader, 0, len(e.Fruits))
+		for _, fruit := range e.Fruits {
+			headers = append(headers, fruit.Header())
+		}
+		for _, f := range filters[FruitsSubscription] {
+			f.fruits <- headers
+		}
 	case types.FastChainEvent:
 		for _, f := range filters[BlocksSubscription] {
 			f.headers <- e.Block.Header()
@@ -453,6 +533,8 @@ func (es *EventSystem) eventLoop() {
 		es.logsSub.Unsubscribe()
 		es.rmLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.dropsSub.Unsubscribe()
+		es.fruitsSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -471,6 +553,10 @@ func (es *EventSystem) eventLoop() {
 			es.broadcast(index, ev)
 		case ev := <-es.chainCh:
 			es.broadcast(index, ev)
+		case ev := <-es.dropsCh:
+			es.broadcast(index, ev)
+		case ev := <-es.fruitsCh:
+			es.broadcast(index, ev)
 		case ev, active := <-es.pendingLogSub.Chan():
 			if !active { // system stopped
 				return
@@ -506,6 +592,10 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.dropsSub.Err():
+			return
+		case <-es.fruitsSub.Err():
+			return
 		}
 	}
 }
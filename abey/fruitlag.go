@@ -0,0 +1,185 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+const (
+	// fruitLagInterval is the time between two fruit lag measurements.
+	fruitLagInterval = 10 * time.Second
+
+	// fruitLagWarnRepeat is the time between two warnings while the lag stays
+	// above the thresholds.
+	fruitLagWarnRepeat = 10 * time.Minute
+)
+
+var (
+	fruitLagBlocksGauge  = metrics.NewRegisteredGauge("abey/fruitlag/blocks", nil)
+	fruitLagSecondsGauge = metrics.NewRegisteredGauge("abey/fruitlag/seconds", nil)
+	fruitLagAlertGauge   = metrics.NewRegisteredGauge("abey/fruitlag/alert", nil)
+)
+
+// fruitLagFastChain is the part of the fast chain the fruit lag is measured on.
+type fruitLagFastChain interface {
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// fruitLagSnailChain is the part of the snail chain the fruit lag is measured on.
+type fruitLagSnailChain interface {
+	CurrentBlock() *types.SnailBlock
+}
+
+// FruitLagStatus is the outcome of the last fruit lag measurement.
+type FruitLagStatus struct {
+	FastNumber  uint64    `json:"fastNumber"`  // Current fast head
+	FruitNumber uint64    `json:"fruitNumber"` // Fast block of the latest fruit included in the snail chain
+	Blocks      uint64    `json:"blocks"`      // Fast blocks the latest fruit lags behind the head
+	Seconds     uint64    `json:"seconds"`     // Fast block time the latest fruit lags behind the head
+	WarnBlocks  uint64    `json:"warnBlocks"`  // Block lag above which alerts are raised, zero if disabled
+	WarnSeconds uint64    `json:"warnSeconds"` // Time lag above which alerts are raised, zero if disabled
+	Alert       bool      `json:"alert"`
+	Syncing     bool      `json:"syncing"` // Alerts are suppressed while syncing
+	Checked     time.Time `json:"checked"` // Zero until the first measurement finished
+}
+
+// fruitLagMonitor periodically measures how far the fruits included in the
+// snail chain lag behind the fast head. A growing gap means the fast blocks
+// are not mined into fruits or the fruits not into snail blocks anymore, long
+// before the fast chain itself stalls on the missing rewards.
+type fruitLagMonitor struct {
+	fastchain   fruitLagFastChain
+	snailchain  fruitLagSnailChain
+	syncing     func() bool // Whether the node is still catching up with the network
+	warnBlocks  uint64
+	warnSeconds uint64
+
+	status FruitLagStatus
+	warned time.Time // Time of the last warning, limiting their frequency
+
+	lock sync.RWMutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFruitLagMonitor creates a fruit lag monitor alerting when the lag exceeds
+// either of the thresholds, zero disabling the respective one.
+func newFruitLagMonitor(fastchain fruitLagFastChain, snailchain fruitLagSnailChain, syncing func() bool, warnBlocks uint64, warnTime time.Duration) *fruitLagMonitor {
+	warnSeconds := uint64(warnTime / time.Second)
+	return &fruitLagMonitor{
+		fastchain:   fastchain,
+		snailchain:  snailchain,
+		syncing:     syncing,
+		warnBlocks:  warnBlocks,
+		warnSeconds: warnSeconds,
+		status:      FruitLagStatus{WarnBlocks: warnBlocks, WarnSeconds: warnSeconds},
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start launches the background measurements.
+func (m *fruitLagMonitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the background measurements.
+func (m *fruitLagMonitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Status returns the outcome of the last measurement.
+func (m *fruitLagMonitor) Status() FruitLagStatus {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.status
+}
+
+// loop measures the fruit lag every interval until termination.
+func (m *fruitLagMonitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(fruitLagInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(time.Now())
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check executes a single measurement, updating the gauges and warning if the
+// lag exceeds the thresholds.
+func (m *fruitLagMonitor) check(now time.Time) {
+	head := m.fastchain.CurrentHeader()
+	if head == nil {
+		return
+	}
+	// The latest fruit is the last one of the snail head, genesis has none
+	var number uint64
+	if block := m.snailchain.CurrentBlock(); block != nil {
+		if fruits := block.Fruits(); len(fruits) > 0 {
+			number = fruits[len(fruits)-1].FastNumber().Uint64()
+		}
+	}
+	var blocks, seconds uint64
+	if head.Number.Uint64() > number {
+		blocks = head.Number.Uint64() - number
+		if fruit := m.fastchain.GetHeaderByNumber(number); fruit != nil && head.Time.Cmp(fruit.Time) > 0 {
+			seconds = head.Time.Uint64() - fruit.Time.Uint64()
+		}
+	}
+	syncing := m.syncing != nil && m.syncing()
+	alert := !syncing && ((m.warnBlocks > 0 && blocks > m.warnBlocks) || (m.warnSeconds > 0 && seconds > m.warnSeconds))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.status.FastNumber = head.Number.Uint64()
+	m.status.FruitNumber = number
+	m.status.Blocks = blocks
+	m.status.Seconds = seconds
+	m.status.Alert = alert
+	m.status.Syncing = syncing
+	m.status.Checked = now
+
+	fruitLagBlocksGauge.Update(int64(blocks))
+	fruitLagSecondsGauge.Update(int64(seconds))
+	if !alert {
+		fruitLagAlertGauge.Update(0)
+		m.warned = time.Time{}
+		return
+	}
+	fruitLagAlertGauge.Update(1)
+	if now.Sub(m.warned) >= fruitLagWarnRepeat {
+		m.warned = now
+		log.Warn("Snail chain fruits lagging behind the fast head", "head", head.Number, "fruit", number, "blocks", blocks, "elapsed", time.Duration(seconds)*time.Second)
+	}
+}
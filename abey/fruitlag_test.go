@@ -0,0 +1,89 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// fruitLagTestChain is a fast chain of headers five seconds apart and a snail
+// head holding fruits up to a given fast block.
+type fruitLagTestChain struct {
+	head  uint64
+	fruit uint64
+}
+
+func (c *fruitLagTestChain) header(number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number), Time: new(big.Int).SetUint64(1000 + 5*number)}
+}
+
+func (c *fruitLagTestChain) CurrentHeader() *types.Header { return c.header(c.head) }
+
+func (c *fruitLagTestChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number > c.head {
+		return nil
+	}
+	return c.header(number)
+}
+
+func (c *fruitLagTestChain) CurrentBlock() *types.SnailBlock {
+	block := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(1)})
+	if c.fruit == 0 {
+		return block
+	}
+	fruit := types.NewSnailBlockWithHeader(&types.SnailHeader{FastNumber: new(big.Int).SetUint64(c.fruit)})
+	return block.WithBody([]*types.SnailBlock{fruit}, nil)
+}
+
+// Tests that the fruit lag is measured in blocks and seconds, and alerts are
+// raised above either threshold unless the node is syncing.
+func TestFruitLag(t *testing.T) {
+	var (
+		chain   = &fruitLagTestChain{head: 100, fruit: 90}
+		syncing bool
+		monitor = newFruitLagMonitor(chain, chain, func() bool { return syncing }, 50, 400*time.Second)
+	)
+	tests := []struct {
+		head, fruit     uint64
+		syncing         bool
+		blocks, seconds uint64
+		alert           bool
+	}{
+		{100, 90, false, 10, 50, false},
+		{100, 0, false, 100, 500, true},  // No fruits yet, lagging behind genesis
+		{160, 100, false, 60, 300, true}, // Block threshold exceeded
+		{160, 100, true, 60, 300, false}, // Suppressed while syncing
+		{100, 100, false, 0, 0, false},   // Fully covered
+		{100, 120, false, 0, 0, false},   // Fruits ahead of a rewound head
+	}
+	for i, tt := range tests {
+		chain.head, chain.fruit, syncing = tt.head, tt.fruit, tt.syncing
+		monitor.check(time.Now())
+
+		status := monitor.Status()
+		if status.Blocks != tt.blocks || status.Seconds != tt.seconds {
+			t.Errorf("test %d: lag mismatch: have %d blocks %ds, want %d blocks %ds", i, status.Blocks, status.Seconds, tt.blocks, tt.seconds)
+		}
+		if status.Alert != tt.alert {
+			t.Errorf("test %d: alert mismatch: have %v, want %v", i, status.Alert, tt.alert)
+		}
+	}
+}
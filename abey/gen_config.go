@@ -4,6 +4,7 @@ package abey
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/common/hexutil"
@@ -20,6 +21,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
+		SnailFinality           uint64
+		SyncStallTimeout        time.Duration
+		NTPServer               string
+		NetCapture              string `toml:",omitempty"`
+		RPCEVMTimeout           time.Duration
+		RPCGasCap               uint64
+		RPCStateReadLimit       uint64
+		ReadOnly                bool          `toml:",omitempty"`
 		LightServ               int           `toml:",omitempty"`
 		LightPeers              int           `toml:",omitempty"`
 		EnableElection          bool          `toml:",omitempty"`
@@ -27,12 +36,24 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Host                    string        `toml:",omitempty"`
 		Port                    int           `toml:",omitempty"`
 		StandbyPort             int           `toml:",omitempty"`
+		BFTSignMsgs             bool          `toml:",omitempty"`
+		BFTRequireSignedMsgs    bool          `toml:",omitempty"`
+		CommitteeAlertURL       string        `toml:",omitempty"`
+		CommitteeAlertThreshold float64       `toml:",omitempty"`
+		ULC                     *ULCConfig    `toml:",omitempty"`
 		SkipBcVersionCheck      bool          `toml:"-"`
 		DatabaseHandles         int           `toml:"-"`
 		DatabaseCache           int
+		DownloaderCache         int
+		MemoryLimit             int
+		DiskWarnDays            int
+		FruitLagBlocks          uint64
+		FruitLagTime            time.Duration
+		RPCCache                int
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
+		CoinbaseProof           bool           `toml:",omitempty"`
 		GasPrice                *big.Int
 		MinervaHash             minerva.Config
 		TxPool                  core.TxPoolConfig
@@ -44,6 +65,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.SnailFinality = c.SnailFinality
+	enc.SyncStallTimeout = c.SyncStallTimeout
+	enc.NTPServer = c.NTPServer
+	enc.NetCapture = c.NetCapture
+	enc.RPCEVMTimeout = c.RPCEVMTimeout
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCStateReadLimit = c.RPCStateReadLimit
+	enc.ReadOnly = c.ReadOnly
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.EnableElection = c.EnableElection
@@ -51,12 +80,24 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Host = c.Host
 	enc.Port = c.Port
 	enc.StandbyPort = c.StandbyPort
+	enc.BFTSignMsgs = c.BFTSignMsgs
+	enc.BFTRequireSignedMsgs = c.BFTRequireSignedMsgs
+	enc.CommitteeAlertURL = c.CommitteeAlertURL
+	enc.CommitteeAlertThreshold = c.CommitteeAlertThreshold
+	enc.ULC = c.ULC
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.DownloaderCache = c.DownloaderCache
+	enc.MemoryLimit = c.MemoryLimit
+	enc.DiskWarnDays = c.DiskWarnDays
+	enc.FruitLagBlocks = c.FruitLagBlocks
+	enc.FruitLagTime = c.FruitLagTime
+	enc.RPCCache = c.RPCCache
 	enc.Etherbase = c.Etherbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
+	enc.CoinbaseProof = c.CoinbaseProof
 	enc.GasPrice = c.GasPrice
 	enc.MinervaHash = c.MinervaHash
 	enc.TxPool = c.TxPool
@@ -71,19 +112,39 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
+		SnailFinality           *uint64
+		SyncStallTimeout        *time.Duration
+		NTPServer               *string
+		NetCapture              *string `toml:",omitempty"`
+		RPCEVMTimeout           *time.Duration
+		RPCGasCap               *uint64
+		RPCStateReadLimit       *uint64
+		ReadOnly                *bool          `toml:",omitempty"`
 		EnableElection          *bool          `toml:",omitempty"`
 		CommitteeKey            *hexutil.Bytes `toml:",omitempty"`
 		Host                    *string        `toml:",omitempty"`
 		Port                    *int           `toml:",omitempty"`
 		StandbyPort             *int           `toml:",omitempty"`
+		BFTSignMsgs             *bool          `toml:",omitempty"`
+		BFTRequireSignedMsgs    *bool          `toml:",omitempty"`
+		CommitteeAlertURL       *string        `toml:",omitempty"`
+		CommitteeAlertThreshold *float64       `toml:",omitempty"`
 		LightServ               *int           `toml:",omitempty"`
 		LightPeers              *int           `toml:",omitempty"`
+		ULC                     *ULCConfig     `toml:",omitempty"`
 		SkipBcVersionCheck      *bool          `toml:"-"`
 		DatabaseHandles         *int           `toml:"-"`
 		DatabaseCache           *int
+		DownloaderCache         *int
+		MemoryLimit             *int
+		DiskWarnDays            *int
+		FruitLagBlocks          *uint64
+		FruitLagTime            *time.Duration
+		RPCCache                *int
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
+		CoinbaseProof           *bool           `toml:",omitempty"`
 		GasPrice                *big.Int
 		MinervaHash             *minerva.Config
 		TxPool                  *core.TxPoolConfig
@@ -104,6 +165,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.SnailFinality != nil {
+		c.SnailFinality = *dec.SnailFinality
+	}
+	if dec.SyncStallTimeout != nil {
+		c.SyncStallTimeout = *dec.SyncStallTimeout
+	}
+	if dec.NTPServer != nil {
+		c.NTPServer = *dec.NTPServer
+	}
+	if dec.NetCapture != nil {
+		c.NetCapture = *dec.NetCapture
+	}
+	if dec.RPCEVMTimeout != nil {
+		c.RPCEVMTimeout = *dec.RPCEVMTimeout
+	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCStateReadLimit != nil {
+		c.RPCStateReadLimit = *dec.RPCStateReadLimit
+	}
+	if dec.ReadOnly != nil {
+		c.ReadOnly = *dec.ReadOnly
+	}
 	if dec.EnableElection != nil {
 		c.EnableElection = *dec.EnableElection
 	}
@@ -119,12 +204,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StandbyPort != nil {
 		c.StandbyPort = *dec.StandbyPort
 	}
+	if dec.BFTSignMsgs != nil {
+		c.BFTSignMsgs = *dec.BFTSignMsgs
+	}
+	if dec.BFTRequireSignedMsgs != nil {
+		c.BFTRequireSignedMsgs = *dec.BFTRequireSignedMsgs
+	}
+	if dec.CommitteeAlertURL != nil {
+		c.CommitteeAlertURL = *dec.CommitteeAlertURL
+	}
+	if dec.CommitteeAlertThreshold != nil {
+		c.CommitteeAlertThreshold = *dec.CommitteeAlertThreshold
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.ULC != nil {
+		c.ULC = dec.ULC
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -134,6 +234,24 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.DownloaderCache != nil {
+		c.DownloaderCache = *dec.DownloaderCache
+	}
+	if dec.MemoryLimit != nil {
+		c.MemoryLimit = *dec.MemoryLimit
+	}
+	if dec.DiskWarnDays != nil {
+		c.DiskWarnDays = *dec.DiskWarnDays
+	}
+	if dec.FruitLagBlocks != nil {
+		c.FruitLagBlocks = *dec.FruitLagBlocks
+	}
+	if dec.FruitLagTime != nil {
+		c.FruitLagTime = *dec.FruitLagTime
+	}
+	if dec.RPCCache != nil {
+		c.RPCCache = *dec.RPCCache
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -143,6 +261,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.ExtraData != nil {
 		c.ExtraData = *dec.ExtraData
 	}
+	if dec.CoinbaseProof != nil {
+		c.CoinbaseProof = *dec.CoinbaseProof
+	}
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
@@ -41,6 +41,7 @@ import (
 	"github.com/abeychain/go-abey/event"
 	"github.com/abeychain/go-abey/log"
 	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/capture"
 	"github.com/abeychain/go-abey/p2p/enode"
 	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
@@ -64,6 +65,24 @@ const (
 	fruitPackSize     = 3
 )
 
+// banReasons describes the misbehaviour behind the peer drops of the various
+// call sites. Peers dropped for any of them are banned for a cool-down period,
+// others are only disconnected.
+var banReasons = map[uint32]string{
+	types.FetcherCall:          "invalid propagated fast block",
+	types.FetcherHeadCall:      "fast header not matching announcement",
+	types.SFetcherCall:         "invalid propagated snail block",
+	types.SFetcherHeadCall:     "snail header not matching announcement",
+	types.SFetcherSealCall:     "implausible announced snail header",
+	types.DownloaderCall:       "fast chain sync failure",
+	types.DownloaderFetchCall:  "fast header request timeout",
+	types.DownloaderPartCall:   "stalling fast chain delivery",
+	types.SDownloaderCall:      "snail chain sync failure",
+	types.SDownloaderFetchCall: "snail header request timeout",
+	types.SDownloaderPartCall:  "stalling snail chain delivery",
+	types.SDownloaderLoopCall:  "stalling state sync",
+}
+
 // errIncompatibleConfig is returned if the requested protocols and configs are
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
@@ -115,6 +134,8 @@ type ProtocolManager struct {
 
 	//minedsnailBlock
 	minedSnailBlockSub *event.TypeMuxSubscription
+	sealed             *sealedSet        // Locally sealed items to rebroadcast, nil if not tracked
+	capture            *capture.Recorder // Recorder of the messages received from peers, nil if disabled
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
 	txsyncCh    chan *txsync
@@ -132,6 +153,10 @@ type ProtocolManager struct {
 	lock     *sync.Mutex
 
 	synchronising int32
+
+	syncStallTimeout time.Duration // Time without sync progress before the session is restarted, zero if disabled
+	stalledPeer      atomic.Value  // Master peer of the last stalled session, avoided by the next one
+	syncRestartCh    chan struct{} // Restarts synchronisation right after a stalled session
 }
 
 // NewProtocolManager returns a new Abeychain sub protocol manager. The Abeychain sub protocol manages peers capable
@@ -156,6 +181,8 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		agentProxy:  agent,
 		syncWg:      sync.NewCond(lock),
 		lock:        lock,
+
+		syncRestartCh: make(chan struct{}, 1),
 	}
 	// Figure out whether to allow fast sync or not
 	// TODO: add downloader func later
@@ -189,6 +216,9 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			Version: version,
 			Length:  ProtocolLengths[i],
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				if manager.capture != nil {
+					rw = manager.capture.Wrap(p.ID(), version, rw)
+				}
 				peer := manager.newPeer(int(version), p, rw)
 				select {
 				case manager.newPeerCh <- peer:
@@ -264,9 +294,12 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	fruitHash := func(header *types.SnailHeader, fruits []*types.SnailBlock) common.Hash {
 		return snailchain.GetFruitsHash(header, fruits)
 	}
+	snailSealVerifier := func(header *types.SnailHeader) error {
+		return engine.VerifySnailSeal(snailchain, header, false)
+	}
 
 	manager.fetcherFast = fetcher.New(blockchain.GetBlockByHash, fastValidator, manager.BroadcastFastBlock, fastHeighter, fastInserter, manager.removePeer, agent, manager.BroadcastPbSign)
-	manager.fetcherSnail = snailfetcher.New(snailchain.GetBlockByHash, snailValidator, manager.BroadcastSnailBlock, snailHeighter, snailInserter, manager.removePeer, fruitHash)
+	manager.fetcherSnail = snailfetcher.New(snailchain.GetBlockByHash, snailValidator, snailSealVerifier, manager.BroadcastSnailBlock, snailHeighter, snailInserter, manager.removePeer, fruitHash)
 
 	return manager, nil
 }
@@ -291,9 +324,13 @@ func (pm *ProtocolManager) removePeer(id string, call uint32) {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
 
-	// Hard disconnect at the networking layer
+	// Hard disconnect at the networking layer, refusing misbehaving peers for a while
 	log.Info("Removing peer  Disconnect", "call", call, "peer", id, "remoteAddr", peer.RemoteAddr())
-	peer.Peer.Disconnect(p2p.DiscUselessPeer)
+	if reason, ok := banReasons[call]; ok {
+		peer.Peer.Ban(reason)
+	} else {
+		peer.Peer.Disconnect(p2p.DiscUselessPeer)
+	}
 }
 
 func (pm *ProtocolManager) Start2(maxPeers int) {
@@ -328,7 +365,7 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	go pm.pbNodeInfoBroadcastLoop()
 
 	//broadcast mined snailblock
-	pm.minedSnailBlockSub = pm.eventMux.Subscribe(types.NewMinedBlockEvent{})
+	pm.minedSnailBlockSub = pm.eventMux.Subscribe(types.NewSealedBlockEvent{}, types.NewMinedBlockEvent{}, types.NewMinedFruitEvent{})
 	go pm.minedSnailBlockLoop()
 
 	//go pm.checkHandlMsg()
@@ -457,6 +494,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	// after this will be sent via broadcasts.
 	pm.syncTransactions(p)
 	pm.syncFruits(p)
+	pm.syncSealed(p)
 
 	// main loop. handle incoming messages.
 	for {
@@ -828,6 +866,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		fruits := make([][]*types.SnailBlock, len(request.BodiesData))
 
 		for i, body := range request.BodiesData {
+			if err := body.sanityCheck(); err != nil {
+				return errResp(ErrDecode, "body %d: %v", i, err)
+			}
 			fruits[i] = body.Fruits
 		}
 
@@ -1083,6 +1124,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if fruit == nil {
 				return errResp(ErrDecode, "fruit %d is nil", i)
 			}
+			if len(fruit.Fruits()) > 0 {
+				return errResp(ErrDecode, "fruit %d carries fruits", i)
+			}
 			p.MarkFruit(fruit.Hash())
 			log.Debug("Add fruit from p2p", "id", p.id, "number", fruit.FastNumber(), "hash", fruit.Hash())
 		}
@@ -1161,7 +1205,7 @@ func (pm *ProtocolManager) BroadcastFastBlock(block *types.Block, propagate bool
 		if transferLen > len(peers) {
 			transferLen = len(peers)
 		}
-		transfer := peers[:transferLen]
+		transfer := selectBroadcastPeers(peers, transferLen)
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, nil, nil, true)
 		}
@@ -1210,7 +1254,7 @@ func (pm *ProtocolManager) BroadcastPbNodeInfo(nodeInfo *types.EncryptNodeMessag
 	if transferLen > len(peers) {
 		transferLen = len(peers)
 	}
-	transfer := peers[:transferLen]
+	transfer := selectBroadcastPeers(peers, transferLen)
 
 	for _, peer := range transfer {
 		peer.AsyncSendNodeInfo(nodeInfo)
@@ -1246,7 +1290,7 @@ func (pm *ProtocolManager) BroadcastSnailBlock(snailBlock *types.SnailBlock, pro
 		if transferLen > len(peers) {
 			transferLen = len(peers)
 		}
-		transfer := peers[:transferLen]
+		transfer := selectBroadcastPeers(peers, transferLen)
 		for _, peer := range transfer {
 			log.Debug("AsyncSendNewSnailBlock begin", "peer", peer.RemoteAddr(), "number", snailBlock.NumberU64(), "hash", snailBlock.Hash())
 			peer.AsyncSendNewBlock(nil, snailBlock, td, false)
@@ -1335,10 +1379,20 @@ func (pm *ProtocolManager) minedSnailBlockLoop() {
 	// automatically stops if unsubscribe
 	for obj := range pm.minedSnailBlockSub.Chan() {
 		switch ev := obj.Data.(type) {
+		case types.NewSealedBlockEvent:
+			// Propagate to peers without waiting for the block to be written
+			pm.BroadcastSnailBlock(ev.Block, true)
 		case types.NewMinedBlockEvent:
 			atomic.StoreUint32(&pm.acceptFruits, 1) // Mark initial sync done on any fetcher import
-			pm.BroadcastSnailBlock(ev.Block, true)  // First propagate fruit to peers
-			pm.BroadcastSnailBlock(ev.Block, false) // Only then announce to the rest
+			pm.BroadcastSnailBlock(ev.Block, false) // Announce to the rest once written
+			if pm.sealed != nil {
+				pm.sealed.add(ev.Block)
+			}
+		case types.NewMinedFruitEvent:
+			// Fruits are broadcast by the snail pool, only track them here
+			if pm.sealed != nil {
+				pm.sealed.add(ev.Block)
+			}
 		}
 	}
 }
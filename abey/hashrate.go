@@ -0,0 +1,262 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/consensus/minerva"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+const (
+	// maxHashrateBlocks caps the snail blocks a hashrate estimate spans.
+	maxHashrateBlocks = minerva.EpochLength
+
+	// defaultHashrateWindow is the number of snail blocks each smoothed
+	// hashrate sample is measured over if not specified otherwise.
+	defaultHashrateWindow = 24
+
+	// maxHashrateEpochs caps the epochs of a single history query, each of
+	// them costs reading all its snail headers.
+	maxHashrateEpochs = 8
+)
+
+var errNoSnailBlocks = errors.New("not enough snail blocks")
+
+// snailHeaderReader retrieves the canonical snail headers.
+type snailHeaderReader interface {
+	CurrentHeader() *types.SnailHeader
+	GetHeaderByNumber(number uint64) *types.SnailHeader
+}
+
+// NetworkHashrate is the hashrate of the network estimated over a range of
+// snail blocks, along with samples smoothed over a shorter trailing window.
+type NetworkHashrate struct {
+	From     hexutil.Uint64    `json:"from"`
+	To       hexutil.Uint64    `json:"to"`
+	Hashrate *hexutil.Big      `json:"hashrate"` // Hashes per second over the range
+	Window   hexutil.Uint64    `json:"window"`
+	Samples  []*HashrateSample `json:"samples"`
+}
+
+// HashrateSample is the hashrate measured over the window of snail blocks
+// ending at a given block.
+type HashrateSample struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+	Hashrate  *hexutil.Big   `json:"hashrate"`
+}
+
+// EpochHashrate is the hashrate of the network over a minerva epoch.
+type EpochHashrate struct {
+	Epoch         hexutil.Uint64 `json:"epoch"`
+	From          hexutil.Uint64 `json:"from"`
+	To            hexutil.Uint64 `json:"to"` // Current head for the running epoch
+	AvgDifficulty *hexutil.Big   `json:"avgDifficulty"`
+	AvgBlockTime  float64        `json:"avgBlockTime"` // Seconds
+	Hashrate      *hexutil.Big   `json:"hashrate"`
+}
+
+// hashrate estimates the hashes per second that mined the given consecutive
+// snail headers: the difficulty of all but the first one, which only marks the
+// start of the measured interval, over the time elapsed since the first one.
+func hashrate(headers []*types.SnailHeader) *big.Int {
+	if len(headers) < 2 {
+		return nil
+	}
+	work := new(big.Int)
+	for _, header := range headers[1:] {
+		work.Add(work, header.Difficulty)
+	}
+	return work.Div(work, elapsed(headers[0], headers[len(headers)-1]))
+}
+
+// elapsed returns the seconds between two snail headers, at least one so
+// blocks sharing a timestamp don't divide by zero.
+func elapsed(first, last *types.SnailHeader) *big.Int {
+	seconds := new(big.Int).Sub(last.Time, first.Time)
+	if seconds.Sign() <= 0 {
+		seconds.SetUint64(1)
+	}
+	return seconds
+}
+
+// hashrateSamples measures the hashrate over the trailing window of every
+// header preceded by at least window others.
+func hashrateSamples(headers []*types.SnailHeader, window int) []*HashrateSample {
+	if window <= 0 || len(headers) <= window {
+		return nil
+	}
+	// Prefix sums of the difficulties keep each sample constant time
+	sums := make([]*big.Int, len(headers))
+	sums[0] = new(big.Int)
+	for i := 1; i < len(headers); i++ {
+		sums[i] = new(big.Int).Add(sums[i-1], headers[i].Difficulty)
+	}
+	samples := make([]*HashrateSample, 0, len(headers)-window)
+	for i := window; i < len(headers); i++ {
+		work := new(big.Int).Sub(sums[i], sums[i-window])
+		samples = append(samples, &HashrateSample{
+			Number:    hexutil.Uint64(headers[i].Number.Uint64()),
+			Timestamp: hexutil.Uint64(headers[i].Time.Uint64()),
+			Hashrate:  (*hexutil.Big)(work.Div(work, elapsed(headers[i-window], headers[i]))),
+		})
+	}
+	return samples
+}
+
+// readSnailHeaders retrieves the canonical snail headers from first to last
+// inclusive.
+func readSnailHeaders(chain snailHeaderReader, first, last uint64) ([]*types.SnailHeader, error) {
+	headers := make([]*types.SnailHeader, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("snail block #%d not found", number)
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// networkHashrate estimates the hashrate over the given number of snail blocks
+// up to the head, sampling it over the trailing window of each of them.
+func networkHashrate(chain snailHeaderReader, blocks, window uint64) (*NetworkHashrate, error) {
+	if blocks > maxHashrateBlocks {
+		blocks = maxHashrateBlocks
+	}
+	head := chain.CurrentHeader().Number.Uint64()
+	if blocks == 0 || head < 1 {
+		return nil, errNoSnailBlocks
+	}
+	if blocks > head {
+		blocks = head
+	}
+	if window > blocks {
+		window = blocks
+	}
+	// Load the window preceding the range too, so each block gets a sample
+	from := head - blocks
+	first := from
+	if window > 0 {
+		if from+1 > window {
+			first = from + 1 - window
+		} else {
+			first = 0
+		}
+	}
+	headers, err := readSnailHeaders(chain, first, head)
+	if err != nil {
+		return nil, err
+	}
+	offset := int(from - first)
+	return &NetworkHashrate{
+		From:     hexutil.Uint64(from + 1),
+		To:       hexutil.Uint64(head),
+		Hashrate: (*hexutil.Big)(hashrate(headers[offset:])),
+		Window:   hexutil.Uint64(window),
+		Samples:  hashrateSamples(headers, int(window)),
+	}, nil
+}
+
+// epochHashrate measures the hashrate over a minerva epoch, up to the head if
+// the epoch is still running.
+func epochHashrate(chain snailHeaderReader, epoch uint64) (*EpochHashrate, error) {
+	head := chain.CurrentHeader().Number.Uint64()
+	from, to := epoch*minerva.EpochLength, (epoch+1)*minerva.EpochLength-1
+	if from > head {
+		return nil, fmt.Errorf("epoch %d not reached, head is #%d", epoch, head)
+	}
+	if to > head {
+		to = head
+	}
+	// The last block of the previous epoch marks the start of the interval
+	first := from
+	if first > 0 {
+		first--
+	}
+	headers, err := readSnailHeaders(chain, first, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) < 2 {
+		return nil, errNoSnailBlocks
+	}
+	measured := uint64(len(headers) - 1)
+	rate := hashrate(headers)
+	seconds := elapsed(headers[0], headers[len(headers)-1])
+
+	// Difficulty times blocks over seconds is the hashrate
+	avg := new(big.Int).Mul(rate, seconds)
+	avg.Div(avg, new(big.Int).SetUint64(measured))
+
+	return &EpochHashrate{
+		Epoch:         hexutil.Uint64(epoch),
+		From:          hexutil.Uint64(from),
+		To:            hexutil.Uint64(to),
+		AvgDifficulty: (*hexutil.Big)(avg),
+		AvgBlockTime:  float64(seconds.Uint64()) / float64(measured),
+		Hashrate:      (*hexutil.Big)(rate),
+	}, nil
+}
+
+// GetNetworkHashrate estimates the network hashrate from the difficulty and
+// the intervals of the given number of latest snail blocks (a difficulty
+// period if unspecified), along with samples smoothed over a trailing window
+// of blocks (24 if unspecified).
+func (api *PublicAbeychainAPI) GetNetworkHashrate(blocks *hexutil.Uint64, window *hexutil.Uint64) (*NetworkHashrate, error) {
+	n, w := params.DifficultyPeriod.Uint64(), uint64(defaultHashrateWindow)
+	if blocks != nil {
+		n = uint64(*blocks)
+	}
+	if window != nil {
+		w = uint64(*window)
+	}
+	return networkHashrate(api.e.snailblockchain, n, w)
+}
+
+// GetHashrateHistory returns the network hashrate of each minerva epoch in the
+// given range, the last one up to the head if it is still running.
+func (api *PublicAbeychainAPI) GetHashrateHistory(from, to hexutil.Uint64) ([]*EpochHashrate, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid epoch range %d-%d", from, to)
+	}
+	if to-from >= maxHashrateEpochs {
+		return nil, fmt.Errorf("epoch range exceeds %d epochs", maxHashrateEpochs)
+	}
+	var (
+		history []*EpochHashrate
+		head    = api.e.snailblockchain.CurrentHeader().Number.Uint64()
+	)
+	for epoch := uint64(from); epoch <= uint64(to); epoch++ {
+		// Epochs not reached yet are omitted, unless none was
+		if len(history) > 0 && epoch*minerva.EpochLength > head {
+			break
+		}
+		rate, err := epochHashrate(api.e.snailblockchain, epoch)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, rate)
+	}
+	return history, nil
+}
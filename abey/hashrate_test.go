@@ -0,0 +1,113 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/consensus/minerva"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// testHashrateChain is a snail chain stub of headers mined at a fixed rate.
+type testHashrateChain []*types.SnailHeader
+
+func (c testHashrateChain) CurrentHeader() *types.SnailHeader { return c[len(c)-1] }
+
+func (c testHashrateChain) GetHeaderByNumber(number uint64) *types.SnailHeader {
+	if number >= uint64(len(c)) {
+		return nil
+	}
+	return c[number]
+}
+
+// newTestHashrateChain creates a chain of the given length, the blocks before
+// split mined every 10 seconds at difficulty 1000, the others every 20 seconds
+// at difficulty 4000.
+func newTestHashrateChain(length, split int) testHashrateChain {
+	chain := make(testHashrateChain, length)
+	var now int64
+	for i := range chain {
+		diff, interval := int64(1000), int64(10)
+		if i >= split {
+			diff, interval = 4000, 20
+		}
+		if i > 0 {
+			now += interval
+		}
+		chain[i] = &types.SnailHeader{Number: big.NewInt(int64(i)), Time: big.NewInt(now), Difficulty: big.NewInt(diff)}
+	}
+	return chain
+}
+
+func TestNetworkHashrate(t *testing.T) {
+	chain := newTestHashrateChain(101, 50)
+
+	// The second part runs at 4000 / 20 = 200 hashes per second
+	rate, err := networkHashrate(chain, 40, 10)
+	if err != nil {
+		t.Fatalf("failed to estimate hashrate: %v", err)
+	}
+	if rate.From != 61 || rate.To != 100 || rate.Hashrate.ToInt().Int64() != 200 {
+		t.Errorf("estimate mismatch: have #%d-#%d at %v, want #61-#100 at 200", rate.From, rate.To, rate.Hashrate)
+	}
+	if len(rate.Samples) != 40 || rate.Samples[0].Number != 61 || rate.Samples[39].Number != 100 {
+		t.Fatalf("samples mismatch: have %d", len(rate.Samples))
+	}
+	// Across both parts the samples move from 100 to 200 hashes per second
+	rate, err = networkHashrate(chain, 100, 10)
+	if err != nil {
+		t.Fatalf("failed to estimate hashrate: %v", err)
+	}
+	if have := rate.Samples[0].Hashrate.ToInt().Int64(); have != 100 {
+		t.Errorf("first sample mismatch: have %d, want 100", have)
+	}
+	if have := rate.Samples[len(rate.Samples)-1].Hashrate.ToInt().Int64(); have != 200 {
+		t.Errorf("last sample mismatch: have %d, want 200", have)
+	}
+	// Work 49*1000 + 51*4000 over 49*10 + 51*20 seconds
+	if have := rate.Hashrate.ToInt().Int64(); have != 167 {
+		t.Errorf("range estimate mismatch: have %d, want 167", have)
+	}
+	if _, err := networkHashrate(chain[:1], 10, 10); err != errNoSnailBlocks {
+		t.Errorf("genesis only: have error %v, want %v", err, errNoSnailBlocks)
+	}
+}
+
+func TestEpochHashrate(t *testing.T) {
+	chain := newTestHashrateChain(minerva.EpochLength+101, minerva.EpochLength)
+
+	// The first epoch ends just before the difficulty change
+	rate, err := epochHashrate(chain, 0)
+	if err != nil {
+		t.Fatalf("failed to measure epoch: %v", err)
+	}
+	if rate.To != minerva.EpochLength-1 || rate.Hashrate.ToInt().Int64() != 100 || rate.AvgDifficulty.ToInt().Int64() != 1000 || rate.AvgBlockTime != 10 {
+		t.Errorf("epoch 0 mismatch: have #%d-#%d, hashrate %v, difficulty %v, block time %v", rate.From, rate.To, rate.Hashrate, rate.AvgDifficulty, rate.AvgBlockTime)
+	}
+	// The running epoch is measured up to the head
+	if rate, err = epochHashrate(chain, 1); err != nil {
+		t.Fatalf("failed to measure epoch: %v", err)
+	}
+	if rate.From != minerva.EpochLength || int(rate.To) != len(chain)-1 {
+		t.Errorf("epoch 1 range mismatch: have #%d-#%d", rate.From, rate.To)
+	}
+	if _, err := epochHashrate(chain, 2); err == nil {
+		t.Errorf("future epoch measured")
+	}
+}
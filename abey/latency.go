@@ -0,0 +1,67 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// broadcastDiversity is the inverse share of the propagation recipients picked
+// at random instead of by latency, so that blocks still reach the far regions
+// first hand and a set of fast but colluding peers can't isolate the node.
+const broadcastDiversity = 3
+
+// selectBroadcastPeers picks n of the peers to propagate a block to, the
+// lowest latency ones and a random diversity quota of the others.
+func selectBroadcastPeers(peers []*peer, n int) []*peer {
+	rtts := make([]time.Duration, len(peers))
+	for i, p := range peers {
+		rtts[i] = p.RTT()
+	}
+	selected := make([]*peer, 0, n)
+	for _, i := range latencyOrder(rtts, n) {
+		selected = append(selected, peers[i])
+	}
+	return selected
+}
+
+// latencyOrder returns the indexes of n round trip times to prefer, the lowest
+// known ones followed by a random choice of the remaining ones.
+func latencyOrder(rtts []time.Duration, n int) []int {
+	if n > len(rtts) {
+		n = len(rtts)
+	}
+	order := make([]int, len(rtts))
+	for i := range order {
+		order[i] = i
+	}
+	// Unknown round trip times are zero and rank behind all measured ones
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := rtts[order[i]], rtts[order[j]]
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	fastest := n - n/broadcastDiversity
+	rest := order[fastest:]
+	rand.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+
+	return order[:n]
+}
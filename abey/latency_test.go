@@ -0,0 +1,62 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that propagation prefers the lowest latency peers while leaving a
+// diversity quota to the others.
+func TestLatencyOrder(t *testing.T) {
+	ms := time.Millisecond
+	rtts := []time.Duration{300 * ms, 0, 20 * ms, 120 * ms, 5 * ms, 0, 60 * ms, 250 * ms, 40 * ms}
+
+	seen := make(map[int]bool)
+	for run := 0; run < 200; run++ {
+		order := latencyOrder(rtts, 6)
+		if len(order) != 6 {
+			t.Fatalf("selection size mismatch: have %d, want 6", len(order))
+		}
+		// The four fastest go first, two random others fill the quota
+		for i, want := range []int{4, 2, 8, 6} {
+			if order[i] != want {
+				t.Fatalf("run %d: preferred peer %d mismatch: have %d, want %d", run, i, order[i], want)
+			}
+		}
+		picked := make(map[int]bool)
+		for _, i := range order {
+			if picked[i] {
+				t.Fatalf("run %d: peer %d selected twice", run, i)
+			}
+			picked[i] = true
+		}
+		for _, i := range order[4:] {
+			seen[i] = true
+		}
+	}
+	// Every other peer, unmeasured ones included, gets a chance
+	for _, i := range []int{0, 1, 3, 5, 7} {
+		if !seen[i] {
+			t.Errorf("peer %d never picked for diversity", i)
+		}
+	}
+	if order := latencyOrder(rtts[:2], 6); len(order) != 2 || order[0] != 0 {
+		t.Errorf("short selection mismatch: have %v, want [0 1]", order)
+	}
+}
@@ -105,6 +105,8 @@ var (
 	miscInTrafficMeter  = metrics.NewRegisteredMeter("abey/misc/in/traffic", nil)
 	miscOutPacketsMeter = metrics.NewRegisteredMeter("abey/misc/out/packets", nil)
 	miscOutTrafficMeter = metrics.NewRegisteredMeter("abey/misc/out/traffic", nil)
+
+	syncStallMeter = metrics.NewRegisteredMeter("abey/sync/stalls", nil)
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -17,12 +17,14 @@
 package abey
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/abeychain/go-abey/consensus/tbft/help"
@@ -31,6 +33,7 @@ import (
 	"fmt"
 
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/crash"
 	"github.com/abeychain/go-abey/consensus"
 	elect "github.com/abeychain/go-abey/consensus/election"
 	"github.com/abeychain/go-abey/core"
@@ -45,6 +48,7 @@ import (
 	"github.com/abeychain/go-abey/metrics"
 	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/tracing"
 )
 
 const (
@@ -83,6 +87,8 @@ type Backend interface {
 
 // PbftAgent receive events from election and communicate with pbftServer
 type PbftAgent struct {
+	lastSignedHeight uint64 // atomic, fast height last signed by this node, must stay first for alignment
+
 	config     *params.ChainConfig
 	fastChain  *core.BlockChain
 	snailChain *snailchain.SnailBlockChain
@@ -234,10 +240,11 @@ func (agent *PbftAgent) initNodeWork() {
 
 //Start means receive events from election and send pbftNode infomation
 func (agent *PbftAgent) Start() {
+	// The loops drop their subscriptions on exit, so they can't be restarted
 	if agent.singleNode { //single node model start
-		go agent.singleloop()
+		go crash.Run("pbftagent", false, agent.singleloop)
 	} else {
-		go agent.loop()
+		go crash.Run("pbftagent", false, agent.loop)
 	}
 }
 
@@ -628,11 +635,37 @@ func (agent *PbftAgent) loop() {
 					}
 				}
 
-				if next == epoch.BeginHeight {
+				// A rotation pause recorded on chain keeps the committee in charge
+				// past its term, move the end of its bft accordingly
+				charge, err := agent.election.CommitteeEpoch(next)
+				if err != nil {
+					log.Error("Resolve committee in charge failed", "block", num, "err", err)
+					continue
+				}
+				pause, err := agent.election.RotationPause(charge.EpochID)
+				if err != nil {
+					log.Error("Fetch rotation pause failed", "id", charge.EpochID, "block", num, "err", err)
+					continue
+				}
+				if pause != nil {
+					if end := agent.endFastNumber[charge.EpochID]; end == nil || end.Cmp(pause.Until) < 0 {
+						log.Warn("Committee rotation paused", "id", charge.EpochID, "until", pause.Until, "block", num)
+						agent.endFastNumber[charge.EpochID] = new(big.Int).Set(pause.Until)
+						help.CheckAndPrintError(agent.server.SetCommitteeStop(new(big.Int).SetUint64(charge.EpochID), pause.Until.Uint64()))
+					}
+				}
+
+				// The switch may have been postponed by a rotation pause, in which
+				// case the previous epoch stays in charge until it ends
+				switchNumber, err := agent.election.SwitchNumber(epoch)
+				if err != nil {
+					log.Error("Resolve committee switch failed", "id", epoch.EpochID, "block", num, "err", err)
+					continue
+				}
+				if next == switchNumber {
 					// Stop current epoch and bft
-					epoch := types.GetEpochFromHeight(num.Uint64())
-					log.Info("Stop epoch", "id", epoch.EpochID, "block", num)
-					committeeID := new(big.Int).SetUint64(epoch.EpochID)
+					log.Info("Stop epoch", "id", epoch.EpochID-1, "block", num)
+					committeeID := new(big.Int).SetUint64(epoch.EpochID - 1)
 					if !agent.verifyCommitteeID(types.CommitteeStop, committeeID) {
 						continue
 					}
@@ -643,12 +676,12 @@ func (agent *PbftAgent) loop() {
 					agent.stopSend()
 				}
 
-				if next == epoch.BeginHeight {
+				if next == switchNumber {
 					// Start New Epoch
 					log.Info("New epoch id", "id", epoch.EpochID, "block", num)
 					committee := &types.CommitteeInfo{
 						Id:          new(big.Int).SetUint64(epoch.EpochID),
-						StartHeight: new(big.Int).SetUint64(epoch.BeginHeight),
+						StartHeight: new(big.Int).SetUint64(switchNumber),
 						EndHeight:   new(big.Int).SetUint64(epoch.EndHeight),
 					}
 
@@ -916,16 +949,31 @@ func (agent *PbftAgent) GetFastLastProposer() common.Address {
 }
 
 //FetchFastBlock  generate fastBlock as leader
-func (agent *PbftAgent) FetchFastBlock(committeeID *big.Int, infos []*types.CommitteeMember) (*types.Block, error) {
+func (agent *PbftAgent) FetchFastBlock(committeeID *big.Int, infos []*types.CommitteeMember) (fastBlock *types.Block, err error) {
 	agent.mu.Lock()
 	defer agent.mu.Unlock()
+
+	// Trace the block packing, linked to the traces of the packed transactions
+	span := tracing.StartLinked("pbft.fetchBlock", nil)
+	defer func() {
+		if fastBlock != nil {
+			span.SetAttributes(tracing.Attr("number", fastBlock.NumberU64()), tracing.Attr("txs", len(fastBlock.Transactions())))
+			for _, tx := range fastBlock.Transactions() {
+				if sc, ok := tracing.Recall(tx.Hash()); ok {
+					span.AddLink(sc)
+				}
+			}
+			tracing.Remember(fastBlock.Hash(), span.Context())
+		}
+		span.SetError(err)
+		span.End()
+	}()
 	if agent.fastChain.IsFallback() {
 		return nil, core.ErrIsFallback
 	}
 	var (
 		parent       = agent.fastChain.CurrentBlock()
 		parentNumber = parent.Number()
-		fastBlock    *types.Block
 		feeAmount    = big.NewInt(0)
 		tstamp       = time.Now().Unix()
 	)
@@ -1102,6 +1150,8 @@ func (agent *PbftAgent) GenerateSignWithVote(fb *types.Block, vote uint32, resul
 	voteSign.Sign, err = crypto.Sign(signHash, agent.privateKey)
 	if err != nil {
 		log.Error("fb GenerateSign error ", "err", err)
+	} else {
+		atomic.StoreUint64(&agent.lastSignedHeight, fb.NumberU64())
 	}
 	return voteSign, err
 }
@@ -1113,6 +1163,14 @@ func (agent *PbftAgent) GenerateSign(fb *types.Block) (*types.PbftSign, error) {
 
 //VerifyFastBlock  committee member  verify fastBlock  and vote agree or disagree sign
 func (agent *PbftAgent) VerifyFastBlock(fb *types.Block, result bool) (*types.PbftSign, error) {
+	span := traceRound("pbft.verifyBlock", fb)
+	sign, err := agent.verifyFastBlock(fb, result)
+	span.SetError(err)
+	span.End()
+	return sign, err
+}
+
+func (agent *PbftAgent) verifyFastBlock(fb *types.Block, result bool) (*types.PbftSign, error) {
 	if agent.fastChain.IsFallback() {
 		voteSign, _ := agent.GenerateSignWithVote(fb, types.VoteAgreeAgainst, result)
 		return voteSign, core.ErrIsFallback
@@ -1233,9 +1291,13 @@ func (agent *PbftAgent) BroadcastConsensus(fb *types.Block) error {
 	agent.mu.Lock()
 	defer agent.mu.Unlock()
 
+	span := traceRound("pbft.commitBlock", fb)
+	defer span.End()
+
 	//insert bockchain
 	err := agent.handleConsensusBlock(fb)
 	if err != nil {
+		span.SetError(err)
 		return err
 	}
 	//record consensus time  of committee
@@ -1245,6 +1307,15 @@ func (agent *PbftAgent) BroadcastConsensus(fb *types.Block) error {
 	return nil
 }
 
+// traceRound starts the span of a pbft round step on a block, joining the trace
+// the block was packed in if it was proposed locally.
+func traceRound(name string, fb *types.Block) *tracing.Span {
+	sc, _ := tracing.Recall(fb.Hash())
+	_, span := tracing.Start(tracing.ContextWithSpan(context.Background(), sc), name,
+		tracing.Attr("number", fb.NumberU64()), tracing.Attr("hash", fb.Hash()))
+	return span
+}
+
 func (agent *PbftAgent) makeCurrent(parent *types.Block, header *types.Header) error {
 	state, err := agent.fastChain.StateAt(parent.Root())
 	if err != nil {
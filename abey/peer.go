@@ -969,6 +969,12 @@ func (ps *peerSet) PeersWithoutSnailBlock(hash common.Hash) []*peer {
 
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
+	return ps.BestPeerExcept("")
+}
+
+// BestPeerExcept retrieves the known peer with the currently highest total
+// difficulty, other than the one with the given id.
+func (ps *peerSet) BestPeerExcept(id string) *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
@@ -977,6 +983,9 @@ func (ps *peerSet) BestPeer() *peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
+		if p.id == id {
+			continue
+		}
 		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p, td
 		}
@@ -26,6 +26,7 @@ import (
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
 )
 
@@ -277,6 +278,15 @@ type snailBlockBody struct {
 	Signs  []*types.PbftSign
 }
 
+// sanityCheck verifies that the body does not exceed the fruit limit of a
+// valid snail block. Nested fruits are already rejected while decoding.
+func (body *snailBlockBody) sanityCheck() error {
+	if len(body.Fruits) > params.MaximumFruits {
+		return fmt.Errorf("too many fruits: %d > %d", len(body.Fruits), params.MaximumFruits)
+	}
+	return nil
+}
+
 // blockBodiesData is the network packet for block content distribution.
 type snailBlockBodiesData struct {
 	BodiesData []*snailBlockBody
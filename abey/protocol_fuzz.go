@@ -0,0 +1,59 @@
+// +build gofuzz
+
+package abey
+
+import (
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+// Fuzz decodes the input as the payload of a snail chain protocol message.
+// The first byte selects the message code, the rest is the RLP payload.
+func Fuzz(input []byte) int {
+	if len(input) == 0 {
+		return -1
+	}
+	code, payload := uint64(input[0]), input[1:]
+
+	switch code {
+	case NewFruitMsg:
+		var fruits []*types.SnailBlock
+		if rlp.DecodeBytes(payload, &fruits) != nil {
+			return 0
+		}
+		for _, fruit := range fruits {
+			fruit.Hash()
+		}
+	case SnailBlockBodiesMsg:
+		var request snailBlockBodiesData
+		if rlp.DecodeBytes(payload, &request) != nil {
+			return 0
+		}
+		for _, body := range request.BodiesData {
+			if body.sanityCheck() != nil {
+				return 0
+			}
+		}
+	case NewSnailBlockMsg, NewFastBlockMsg:
+		var request newBlockData
+		if rlp.DecodeBytes(payload, &request) != nil {
+			return 0
+		}
+		for _, block := range request.SnailBlock {
+			block.Hash()
+		}
+	case SnailBlockHeadersMsg:
+		var headerData BlockHeadersData
+		if rlp.DecodeBytes(payload, &headerData) != nil {
+			return 0
+		}
+	case GetSnailBlockHeadersMsg:
+		var query getBlockHeadersData
+		if rlp.DecodeBytes(payload, &query) != nil {
+			return 0
+		}
+	default:
+		return -1
+	}
+	return 1
+}
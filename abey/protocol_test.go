@@ -18,6 +18,7 @@ package abey
 
 import (
 	"fmt"
+	"math/big"
 	"sync"
 	"testing"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/abey/downloader"
 	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/params"
 )
 
 func init() {
@@ -356,3 +358,53 @@ func testSendFruits(t *testing.T, protocol int) {
 	}
 	wg.Wait()
 }
+
+// Tests that snail blocks received from the network are rejected when they
+// exceed the decoding limits, e.g. fruits carrying fruits of their own.
+func TestSnailBlockDecodeLimits(t *testing.T) {
+	header := &types.SnailHeader{Number: big.NewInt(1)}
+	fruit := types.NewSnailBlockWithHeader(header)
+
+	// A well formed block round-trips through the decoder
+	block := types.NewSnailBlockWithHeader(header).WithBody([]*types.SnailBlock{fruit, fruit}, nil)
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	dec := new(types.SnailBlock)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("failed to decode valid block: %v", err)
+	}
+	if len(dec.Fruits()) != 2 {
+		t.Fatalf("fruit count mismatch: have %d, want 2", len(dec.Fruits()))
+	}
+	// Fruits nested into fruits must be rejected
+	nested := types.NewSnailBlockWithHeader(header).WithBody([]*types.SnailBlock{block}, nil)
+	if enc, err = rlp.EncodeToBytes(nested); err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, new(types.SnailBlock)); err != types.ErrNestedFruits {
+		t.Errorf("nested fruits: have error %v, want %v", err, types.ErrNestedFruits)
+	}
+	// Blocks with more fruits than allowed must be rejected
+	fruits := make([]*types.SnailBlock, params.MaximumFruits+1)
+	for i := range fruits {
+		fruits[i] = fruit
+	}
+	oversized := types.NewSnailBlockWithHeader(header).WithBody(fruits, nil)
+	if enc, err = rlp.EncodeToBytes(oversized); err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	if err := rlp.DecodeBytes(enc, new(types.SnailBlock)); err != types.ErrTooManyFruits {
+		t.Errorf("oversized block: have error %v, want %v", err, types.ErrTooManyFruits)
+	}
+	// Bodies delivered over the wire are subject to the same limit
+	body := &snailBlockBody{Fruits: fruits}
+	if err := body.sanityCheck(); err == nil {
+		t.Errorf("oversized body passed sanity check")
+	}
+	body.Fruits = fruits[:params.MaximumFruits]
+	if err := body.sanityCheck(); err != nil {
+		t.Errorf("valid body failed sanity check: %v", err)
+	}
+}
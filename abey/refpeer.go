@@ -0,0 +1,299 @@
+// Copyright 2018 The Abeychain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/snailchain"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/p2p"
+)
+
+// refRequestTimeout is the time a reference peer is given to answer a request.
+const refRequestTimeout = 15 * time.Second
+
+var (
+	errRefNotConnected = errors.New("reference peer not connected")
+	errRefTimeout      = errors.New("reference peer request timed out")
+	errRefDisconnected = errors.New("reference peer disconnected")
+)
+
+// RefPeer is a single abey protocol connection to a reference node, used by
+// offline tooling to retrieve fast blocks and receipts from it without
+// running a protocol manager. Requests are served one at a time.
+type RefPeer struct {
+	networkID uint64
+	fchain    *core.BlockChain
+	schain    *snailchain.SnailBlockChain
+
+	connected chan struct{} // Closed once the handshake with the peer succeeded
+	closed    chan struct{} // Closed once the connection to the peer is lost
+	once      sync.Once
+	peer      *peer
+
+	reqLock   sync.Mutex // Serializes requests, responses are matched by message code
+	headerCh  chan []*types.Header
+	bodyCh    chan []*blockBody
+	receiptCh chan [][]*types.Receipt
+}
+
+// NewRefPeer creates a reference peer handshaking with the state of the given
+// local chains.
+func NewRefPeer(networkID uint64, fchain *core.BlockChain, schain *snailchain.SnailBlockChain) *RefPeer {
+	return &RefPeer{
+		networkID: networkID,
+		fchain:    fchain,
+		schain:    schain,
+		connected: make(chan struct{}),
+		closed:    make(chan struct{}),
+		headerCh:  make(chan []*types.Header, 1),
+		bodyCh:    make(chan []*blockBody, 1),
+		receiptCh: make(chan [][]*types.Receipt, 1),
+	}
+}
+
+// Protocols returns the abey protocol versions to register with the p2p server.
+// Only the first peer to complete the handshake is used.
+func (r *RefPeer) Protocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, 0, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version
+		protocols = append(protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return r.run(int(version), p, rw)
+			},
+		})
+	}
+	return protocols
+}
+
+// WaitConnected blocks until the handshake with the reference peer succeeded
+// or the timeout expired.
+func (r *RefPeer) WaitConnected(timeout time.Duration) error {
+	select {
+	case <-r.connected:
+		return nil
+	case <-time.After(timeout):
+		return errRefNotConnected
+	}
+}
+
+// FastHeight returns the fast chain height announced by the reference peer.
+func (r *RefPeer) FastHeight() uint64 {
+	select {
+	case <-r.connected:
+	default:
+		return 0
+	}
+	return r.peer.FastHeight().Uint64()
+}
+
+func (r *RefPeer) run(version int, p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	var (
+		fastHead = r.fchain.CurrentHeader()
+		genesis  = r.schain.Genesis()
+		head     = r.schain.CurrentHeader()
+		td       = r.schain.GetTd(head.Hash(), head.Number.Uint64())
+		peer     = newPeer(version, p, rw, nil)
+		err      error
+	)
+	if version >= abey64 {
+		err = peer.SnapHandshake(r.networkID, td, head.Hash(), genesis.Hash(), fastHead.Hash(), fastHead.Number, r.fchain.CurrentGcHeight(), r.fchain.CurrentCommitHeight())
+	} else {
+		err = peer.Handshake(r.networkID, td, head.Hash(), genesis.Hash(), fastHead.Hash(), fastHead.Number)
+	}
+	if err != nil {
+		return err
+	}
+	return r.serve(peer)
+}
+
+// serve delivers the messages of a handshaked peer until it disconnects.
+func (r *RefPeer) serve(peer *peer) error {
+	first := false
+	r.once.Do(func() {
+		r.peer, first = peer, true
+		close(r.connected)
+	})
+	if !first {
+		return p2p.DiscTooManyPeers
+	}
+	defer close(r.closed)
+
+	for {
+		if err := r.handleMsg(peer); err != nil {
+			return err
+		}
+	}
+}
+
+// handleMsg delivers responses to the pending request and drops everything
+// else the reference peer sends.
+func (r *RefPeer) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case FastBlockHeadersMsg:
+		var data BlockHeadersData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		select {
+		case r.headerCh <- data.Headers:
+		default:
+		}
+	case FastBlockBodiesMsg:
+		var data blockBodiesData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		select {
+		case r.bodyCh <- data.BodiesData:
+		default:
+		}
+	case ReceiptsMsg:
+		var receipts [][]*types.Receipt
+		if err := msg.Decode(&receipts); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		select {
+		case r.receiptCh <- receipts:
+		default:
+		}
+	}
+	return nil
+}
+
+// request sends a request to the reference peer and waits for the response to
+// be delivered on the given channel.
+func (r *RefPeer) request(send func(p *peer) error, wait func(timeout <-chan time.Time) error) error {
+	select {
+	case <-r.connected:
+	default:
+		return errRefNotConnected
+	}
+	r.reqLock.Lock()
+	defer r.reqLock.Unlock()
+
+	// Drop late responses to earlier requests that timed out
+	select {
+	case <-r.headerCh:
+	case <-r.bodyCh:
+	case <-r.receiptCh:
+	default:
+	}
+	if err := send(r.peer); err != nil {
+		return err
+	}
+	timeout := time.NewTimer(refRequestTimeout)
+	defer timeout.Stop()
+	return wait(timeout.C)
+}
+
+// HeaderByNumber retrieves the canonical fast header of the reference peer at
+// the given number.
+func (r *RefPeer) HeaderByNumber(number uint64) (*types.Header, error) {
+	var headers []*types.Header
+	err := r.request(func(p *peer) error {
+		return p.Send(GetFastBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Number: number}, Amount: 1, Call: types.DownloaderCall})
+	}, func(timeout <-chan time.Time) error {
+		select {
+		case headers = <-r.headerCh:
+			return nil
+		case <-r.closed:
+			return errRefDisconnected
+		case <-timeout:
+			return errRefTimeout
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 || headers[0].Number.Uint64() != number {
+		return nil, fmt.Errorf("reference peer has no header #%d", number)
+	}
+	return headers[0], nil
+}
+
+// Block retrieves the body of the given header from the reference peer and
+// assembles the full block.
+func (r *RefPeer) Block(header *types.Header) (*types.Block, error) {
+	var bodies []*blockBody
+	err := r.request(func(p *peer) error {
+		return p.Send(GetFastBlockBodiesMsg, []getBlockBodiesData{{header.Hash(), types.DownloaderCall}})
+	}, func(timeout <-chan time.Time) error {
+		select {
+		case bodies = <-r.bodyCh:
+			return nil
+		case <-r.closed:
+			return errRefDisconnected
+		case <-timeout:
+			return errRefTimeout
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(bodies) == 0 {
+		return nil, fmt.Errorf("reference peer has no body for #%d [%x…]", header.Number, header.Hash().Bytes()[:4])
+	}
+	block := types.NewBlockWithHeader(header).WithBody(bodies[0].Transactions, bodies[0].Signs, bodies[0].Infos)
+	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
+		return nil, fmt.Errorf("reference peer body mismatch for #%d: tx root %x, want %x", header.Number, hash, header.TxHash)
+	}
+	return block, nil
+}
+
+// Receipts retrieves the receipts of the given block from the reference peer.
+func (r *RefPeer) Receipts(hash common.Hash) (types.Receipts, error) {
+	var receipts [][]*types.Receipt
+	err := r.request(func(p *peer) error {
+		return p.Send(GetReceiptsMsg, []common.Hash{hash})
+	}, func(timeout <-chan time.Time) error {
+		select {
+		case receipts = <-r.receiptCh:
+			return nil
+		case <-r.closed:
+			return errRefDisconnected
+		case <-timeout:
+			return errRefTimeout
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) == 0 {
+		return nil, fmt.Errorf("reference peer has no receipts for %x", hash)
+	}
+	return receipts[0], nil
+}
@@ -0,0 +1,136 @@
+package abey
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/params"
+)
+
+// serveRefRequests answers the requests of a reference peer from the given
+// blocks, acting as the remote node.
+func serveRefRequests(rw p2p.MsgReadWriter, blocks map[uint64]*types.Block, receipts map[common.Hash]types.Receipts) {
+	byHash := make(map[common.Hash]*types.Block)
+	for _, block := range blocks {
+		byHash[block.Hash()] = block
+	}
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return
+		}
+		switch msg.Code {
+		case GetFastBlockHeadersMsg:
+			var query getBlockHeadersData
+			msg.Decode(&query)
+			data := &BlockHeadersData{Call: query.Call}
+			if block := blocks[query.Origin.Number]; block != nil {
+				data.Headers = append(data.Headers, block.Header())
+			}
+			p2p.Send(rw, FastBlockHeadersMsg, data)
+
+		case GetFastBlockBodiesMsg:
+			var query []getBlockBodiesData
+			msg.Decode(&query)
+			data := &blockBodiesData{}
+			for _, q := range query {
+				if block := byHash[q.Hash]; block != nil {
+					data.BodiesData = append(data.BodiesData, &blockBody{Transactions: block.Transactions(), Signs: block.Signs()})
+				}
+			}
+			p2p.Send(rw, FastBlockBodiesMsg, data)
+
+		case GetReceiptsMsg:
+			var query []common.Hash
+			msg.Decode(&query)
+			var data [][]*types.Receipt
+			for _, hash := range query {
+				data = append(data, receipts[hash])
+			}
+			p2p.Send(rw, ReceiptsMsg, data)
+
+		default:
+			msg.Discard()
+		}
+	}
+}
+
+// Tests that a reference peer retrieves headers, bodies and receipts and
+// verifies bodies against their headers.
+func TestRefPeer(t *testing.T) {
+	signer := types.NewTIP1Signer(params.AllMinervaProtocolChanges.ChainID)
+
+	blocks := make(map[uint64]*types.Block)
+	receipts := make(map[common.Hash]types.Receipts)
+	for i := uint64(1); i <= 4; i++ {
+		tx, _ := types.SignTx(types.NewTransaction(i, testBank, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: params.TxGas * i}
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, []*types.Transaction{tx}, []*types.Receipt{receipt}, nil, nil)
+		blocks[i] = block
+		receipts[block.Hash()] = types.Receipts{receipt}
+	}
+	// Serve a body under a header not committing to its transactions
+	forged := types.CopyHeader(blocks[2].Header())
+	forged.TxHash = common.Hash{1}
+	blocks[0] = types.NewBlockWithHeader(forged).WithBody(blocks[2].Transactions(), nil, nil)
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	go serveRefRequests(app, blocks, receipts)
+
+	var id enode.ID
+	rand.Read(id[:])
+	peer := newPeer(abey63, p2p.NewPeer(id, "ref", nil), net, nil)
+	peer.fastHeight = big.NewInt(4)
+
+	ref := NewRefPeer(DefaultConfig.NetworkId, nil, nil)
+	if _, err := ref.HeaderByNumber(1); err != errRefNotConnected {
+		t.Errorf("request before connecting: have error %v, want %v", err, errRefNotConnected)
+	}
+	go ref.serve(peer)
+
+	if err := ref.WaitConnected(time.Second); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if height := ref.FastHeight(); height != 4 {
+		t.Errorf("fast height mismatch: have %d, want %d", height, 4)
+	}
+	for number := uint64(1); number <= 4; number++ {
+		want := blocks[number]
+
+		header, err := ref.HeaderByNumber(number)
+		if err != nil {
+			t.Fatalf("block #%d: failed to retrieve header: %v", number, err)
+		}
+		if header.Hash() != want.Hash() {
+			t.Errorf("block #%d: header mismatch: have %x, want %x", number, header.Hash(), want.Hash())
+		}
+		block, err := ref.Block(header)
+		if err != nil {
+			t.Fatalf("block #%d: failed to retrieve body: %v", number, err)
+		}
+		if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != want.Transactions()[0].Hash() {
+			t.Errorf("block #%d: transactions mismatch", number)
+		}
+		have, err := ref.Receipts(header.Hash())
+		if err != nil {
+			t.Fatalf("block #%d: failed to retrieve receipts: %v", number, err)
+		}
+		if hash := types.DeriveSha(have); hash != header.ReceiptHash {
+			t.Errorf("block #%d: receipts root mismatch: have %x, want %x", number, hash, header.ReceiptHash)
+		}
+	}
+	if _, err := ref.HeaderByNumber(100); err == nil {
+		t.Errorf("unknown header retrieved")
+	}
+	// Bodies not matching the header are rejected
+	if _, err := ref.Block(forged); err == nil {
+		t.Errorf("mismatching body accepted")
+	}
+}
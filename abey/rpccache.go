@@ -0,0 +1,160 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/rawdb"
+	chain "github.com/abeychain/go-abey/core/snailchain"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/rpc"
+)
+
+// Methods whose results only depend on the fast block they are keyed by. Fast
+// blocks are final once committed by the committee.
+var fastNumberMethods = map[string]bool{
+	"abey_getBlockByNumber":                       true,
+	"abey_getHeaderByNumber":                      true,
+	"abey_getBlockTransactionCountByNumber":       true,
+	"abey_getTransactionByBlockNumberAndIndex":    true,
+	"abey_getRawTransactionByBlockNumberAndIndex": true,
+	"debug_traceBlockByNumber":                    true,
+}
+
+// Methods whose results only depend on the snail block they are keyed by, final
+// once below the finality barrier.
+var snailNumberMethods = map[string]bool{
+	"abey_getSnailBlockByNumber":         true,
+	"abey_getSnailHeaderByNumber":        true,
+	"abey_getSnailHashByNumber":          true,
+	"abey_getBlockFruitCountByNumber":    true,
+	"abey_getFruitByBlockNumberAndIndex": true,
+}
+
+// Methods whose results only depend on the content of the block with the hash.
+var blockHashMethods = map[string]bool{
+	"abey_getBlockByHash":                       true,
+	"abey_getBlockTransactionCountByHash":       true,
+	"abey_getTransactionByBlockHashAndIndex":    true,
+	"abey_getRawTransactionByBlockHashAndIndex": true,
+	"abey_getSnailBlockByHash":                  true,
+	"abey_getBlockFruitCountByHash":             true,
+	"abey_getFruitByBlockHashAndIndex":          true,
+	"debug_traceBlockByHash":                    true,
+}
+
+// Methods whose results only depend on the transaction with the hash, final
+// once it was included in a fast block.
+var txHashMethods = map[string]bool{
+	"abey_getTransactionByHash":    true,
+	"abey_getRawTransactionByHash": true,
+	"abey_getTransactionReceipt":   true,
+	"debug_traceTransaction":       true,
+}
+
+// responseCachePolicy decides which RPC calls are about final chain data.
+type responseCachePolicy struct {
+	chainDb    abeydb.Database
+	blockchain *core.BlockChain
+	snailchain *chain.SnailBlockChain
+	finality   uint64 // Depth below which snail blocks are final (0 = never)
+}
+
+// cacheable implements rpc.CachePolicy.
+func (p *responseCachePolicy) cacheable(method string, args []interface{}) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch {
+	case fastNumberMethods[method]:
+		number, ok := args[0].(rpc.BlockNumber)
+		return ok && number >= 0 && uint64(number) <= p.blockchain.CurrentBlock().NumberU64()
+
+	case snailNumberMethods[method]:
+		number, ok := args[0].(rpc.BlockNumber)
+		if !ok || number < 0 || p.finality == 0 {
+			return false
+		}
+		return uint64(number)+p.finality <= p.snailchain.CurrentBlock().NumberU64()
+
+	case blockHashMethods[method]:
+		_, ok := args[0].(common.Hash)
+		return ok
+
+	case txHashMethods[method]:
+		hash, ok := args[0].(common.Hash)
+		if !ok {
+			return false
+		}
+		// Pending transactions change once included, only cache included ones
+		blockHash, _, _ := rawdb.ReadTxLookupEntry(p.chainDb, hash)
+		return blockHash != (common.Hash{})
+	}
+	return false
+}
+
+// newResponseCache creates the cache of the RPC responses about final chain
+// data, nil if disabled.
+func newResponseCache(config *Config, chainDb abeydb.Database, blockchain *core.BlockChain, snailchain *chain.SnailBlockChain) *rpc.ResponseCache {
+	if config.RPCCache <= 0 {
+		return nil
+	}
+	policy := &responseCachePolicy{
+		chainDb:    chainDb,
+		blockchain: blockchain,
+		snailchain: snailchain,
+		finality:   config.SnailFinality,
+	}
+	return rpc.NewResponseCache(config.RPCCache*1024*1024, policy.cacheable)
+}
+
+// ResponseCache implements node.ResponseCacher, returning the cache of the RPC
+// responses about final chain data.
+func (s *Abeychain) ResponseCache() *rpc.ResponseCache {
+	return s.rpcCache
+}
+
+// responseCacheLoop drops the cached RPC responses whenever final chain data is
+// rewound, until the chains are stopped.
+func (s *Abeychain) responseCacheLoop() {
+	var (
+		fastCh   = make(chan types.FinalizedReorgEvent, 1)
+		snailCh  = make(chan types.FinalizedReorgEvent, 1)
+		fastSub  = s.blockchain.SubscribeFinalizedReorgEvent(fastCh)
+		snailSub = s.snailblockchain.SubscribeFinalizedReorgEvent(snailCh)
+	)
+	defer fastSub.Unsubscribe()
+	defer snailSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-fastCh:
+			log.Info("Fast chain rewound, dropping cached RPC responses", "number", ev.Number)
+			s.rpcCache.Purge()
+		case ev := <-snailCh:
+			log.Info("Snail chain reorganised beyond finality, dropping cached RPC responses", "number", ev.Number)
+			s.rpcCache.Purge()
+		case <-fastSub.Err():
+			return
+		case <-snailSub.Err():
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+const (
+	// sealedJournal is the file locally sealed but unconfirmed items are kept in.
+	sealedJournal = "sealed.rlp"
+
+	// sealedBlockFreshness is the number of snail blocks a locally sealed block
+	// is rebroadcast for after it was sealed, deeper ones are settled.
+	sealedBlockFreshness = 12
+)
+
+// sealedChain is the part of the snail chain needed to judge sealed items.
+type sealedChain interface {
+	CurrentBlock() *types.SnailBlock
+	GetBlockByNumber(number uint64) *types.SnailBlock
+	GetFruitByFastHash(fastHash common.Hash) (*types.SnailBlock, uint64)
+}
+
+// sealedRLP is the on disk format of the sealed journal.
+type sealedRLP struct {
+	Blocks []*types.SnailBlock
+	Fruits []*types.SnailBlock
+}
+
+// sealedSet tracks the snail blocks and fruits sealed by the local miner until
+// they are confirmed by the network, so items sealed while the node was
+// partitioned can be rebroadcast when peers reconnect.
+type sealedSet struct {
+	path   string // Journal file, empty to keep the items in memory only
+	chain  sealedChain
+	blocks map[common.Hash]*types.SnailBlock
+	fruits map[common.Hash]*types.SnailBlock
+	lock   sync.Mutex
+}
+
+// newSealedSet creates a sealed item tracker, loading any items left in the
+// journal by a previous run.
+func newSealedSet(path string, chain sealedChain) *sealedSet {
+	s := &sealedSet{
+		path:   path,
+		chain:  chain,
+		blocks: make(map[common.Hash]*types.SnailBlock),
+		fruits: make(map[common.Hash]*types.SnailBlock),
+	}
+	if path == "" {
+		return s
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to read sealed journal", "err", err)
+		}
+		return s
+	}
+	var items sealedRLP
+	if err := rlp.DecodeBytes(blob, &items); err != nil {
+		log.Warn("Failed to decode sealed journal", "err", err)
+		return s
+	}
+	for _, block := range items.Blocks {
+		s.blocks[block.Hash()] = block
+	}
+	for _, fruit := range items.Fruits {
+		s.fruits[fruit.Hash()] = fruit
+	}
+	log.Info("Loaded sealed journal", "blocks", len(s.blocks), "fruits", len(s.fruits))
+	return s
+}
+
+// add starts tracking a locally sealed snail block or fruit.
+func (s *sealedSet) add(item *types.SnailBlock) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if item.IsFruit() {
+		s.fruits[item.Hash()] = item
+	} else {
+		s.blocks[item.Hash()] = item
+	}
+	s.save()
+}
+
+// pending drops the items confirmed by or no longer acceptable to the network
+// and returns the remaining ones, which are still worth broadcasting.
+func (s *sealedSet) pending() ([]*types.SnailBlock, []*types.SnailBlock) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var (
+		head    = s.chain.CurrentBlock().NumberU64()
+		dropped int
+		blocks  []*types.SnailBlock
+		fruits  []*types.SnailBlock
+	)
+	for hash, block := range s.blocks {
+		// Blocks reorged out or settled deep enough need no more help
+		canon := s.chain.GetBlockByNumber(block.NumberU64())
+		if canon == nil || canon.Hash() != hash || head > block.NumberU64()+sealedBlockFreshness {
+			delete(s.blocks, hash)
+			dropped++
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	for hash, fruit := range s.fruits {
+		// Fruits are done once their fast block is covered by any fruit, or
+		// when they got too stale to be included by the next snail block
+		if included, _ := s.chain.GetFruitByFastHash(fruit.FastHash()); included != nil {
+			delete(s.fruits, hash)
+			dropped++
+			continue
+		}
+		fresh := new(big.Int).Sub(new(big.Int).SetUint64(head+1), fruit.PointNumber())
+		if fresh.Cmp(params.FruitFreshness) > 0 {
+			delete(s.fruits, hash)
+			dropped++
+			continue
+		}
+		fruits = append(fruits, fruit)
+	}
+	if dropped > 0 {
+		s.save()
+	}
+	return blocks, fruits
+}
+
+// save writes the tracked items into the journal. The caller must hold the lock.
+func (s *sealedSet) save() {
+	if s.path == "" {
+		return
+	}
+	items := sealedRLP{
+		Blocks: make([]*types.SnailBlock, 0, len(s.blocks)),
+		Fruits: make([]*types.SnailBlock, 0, len(s.fruits)),
+	}
+	for _, block := range s.blocks {
+		items.Blocks = append(items.Blocks, block)
+	}
+	for _, fruit := range s.fruits {
+		items.Fruits = append(items.Fruits, fruit)
+	}
+	blob, err := rlp.EncodeToBytes(&items)
+	if err != nil {
+		log.Warn("Failed to encode sealed journal", "err", err)
+		return
+	}
+	// Write into a temporary file first so a crash can't corrupt the journal
+	tmp := s.path + ".new"
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		log.Warn("Failed to create sealed journal directory", "err", err)
+		return
+	}
+	if err := ioutil.WriteFile(tmp, blob, 0644); err != nil {
+		log.Warn("Failed to write sealed journal", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		log.Warn("Failed to replace sealed journal", "err", err)
+	}
+}
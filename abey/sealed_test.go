@@ -0,0 +1,114 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abey
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+// testSealedChain is a snail chain stub with a canonical chain of headers and
+// a set of fast blocks already covered by fruits.
+type testSealedChain struct {
+	canon    map[uint64]*types.SnailBlock
+	head     uint64
+	included map[common.Hash]bool
+}
+
+func (c *testSealedChain) CurrentBlock() *types.SnailBlock { return c.canon[c.head] }
+
+func (c *testSealedChain) GetBlockByNumber(number uint64) *types.SnailBlock { return c.canon[number] }
+
+func (c *testSealedChain) GetFruitByFastHash(fastHash common.Hash) (*types.SnailBlock, uint64) {
+	if c.included[fastHash] {
+		return c.canon[c.head], 0
+	}
+	return nil, 0
+}
+
+func newSealedTestFruit(fastNumber, pointer uint64) *types.SnailBlock {
+	return types.NewSnailBlockWithHeader(&types.SnailHeader{
+		Number:        new(big.Int),
+		FastNumber:    new(big.Int).SetUint64(fastNumber),
+		FastHash:      common.BigToHash(new(big.Int).SetUint64(fastNumber)),
+		PointerNumber: new(big.Int).SetUint64(pointer),
+	})
+}
+
+func newSealedTestBlock(number uint64, fruit *types.SnailBlock) *types.SnailBlock {
+	header := &types.SnailHeader{Number: new(big.Int).SetUint64(number), Extra: []byte("sealed")}
+	return types.NewSnailBlock(header, []*types.SnailBlock{fruit}, nil, nil, params.TestChainConfig)
+}
+
+func TestSealedSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := &testSealedChain{canon: make(map[uint64]*types.SnailBlock), included: make(map[common.Hash]bool)}
+	for i := uint64(0); i <= 20; i++ {
+		chain.canon[i] = types.NewSnailBlockWithHeader(&types.SnailHeader{Number: new(big.Int).SetUint64(i)})
+	}
+	chain.head = 20
+
+	var (
+		fresh    = newSealedTestFruit(100, 15)
+		stale    = newSealedTestFruit(101, 2)
+		covered  = newSealedTestFruit(102, 15)
+		canon    = newSealedTestBlock(18, fresh)
+		reorged  = newSealedTestBlock(19, fresh)
+		settled  = newSealedTestBlock(5, fresh)
+		path     = filepath.Join(dir, sealedJournal)
+		sealed   = newSealedSet(path, chain)
+		wantKept = map[common.Hash]bool{fresh.Hash(): true, canon.Hash(): true}
+	)
+	chain.canon[18] = canon
+	chain.canon[5] = settled
+	chain.included[covered.FastHash()] = true
+
+	for _, item := range []*types.SnailBlock{fresh, stale, covered, canon, reorged, settled} {
+		sealed.add(item)
+	}
+	// Reload the journal to check the items survive restarts
+	sealed = newSealedSet(path, chain)
+	if len(sealed.blocks) != 3 || len(sealed.fruits) != 3 {
+		t.Fatalf("reloaded item count mismatch: have %d blocks %d fruits, want 3 and 3", len(sealed.blocks), len(sealed.fruits))
+	}
+	blocks, fruits := sealed.pending()
+	if len(blocks) != 1 || len(fruits) != 1 {
+		t.Fatalf("pending item count mismatch: have %d blocks %d fruits, want 1 and 1", len(blocks), len(fruits))
+	}
+	for _, item := range append(blocks, fruits...) {
+		if !wantKept[item.Hash()] {
+			t.Errorf("unexpected pending item %x", item.Hash())
+		}
+	}
+	// Dropped items must not come back after a restart
+	sealed = newSealedSet(path, chain)
+	if len(sealed.blocks) != 1 || len(sealed.fruits) != 1 {
+		t.Fatalf("pruned item count mismatch: have %d blocks %d fruits, want 1 and 1", len(sealed.blocks), len(sealed.fruits))
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+
+	// maxTrieNodeLookups is the maximum number of state trie nodes to serve. This
+	// number is there to limit the number of disk lookups.
+	maxTrieNodeLookups = 1024
+
+	// maxTrieNodeTimeSpent is the maximum time we should spend on looking up trie
+	// nodes. If we spend too much time, then it's a fairly high chance of timing
+	// out at the remote side, which means all the work is in vain.
+	maxTrieNodeTimeSpent = 5 * time.Second
+)
+
+// emptyCode is the known hash of the empty EVM bytecode.
+var emptyCode = crypto.Keccak256Hash(nil)
+
+// maxHash is the last possible hash, the default limit of range requests.
+var maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+// Backend provides the state the snap protocol is served from.
+type Backend interface {
+	// StateCache returns the state database holding the tries and bytecodes.
+	StateCache() state.Database
+}
+
+// MakeProtocols constructs the snap protocols serving the state of the backend.
+// Peers only send state requests after negotiating the snap capability.
+func MakeProtocols(backend Backend) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return Handle(backend, p, rw)
+			},
+		}
+	}
+	return protocols
+}
+
+// Handle is the callback invoked to manage the life cycle of a snap peer. When
+// this function terminates, the peer is disconnected.
+func Handle(backend Backend, peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	for {
+		if err := handleMessage(backend, peer, rw); err != nil {
+			log.Debug("Message handling failed in `snap`", "peer", peer.ID(), "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer on the `snap` protocol.
+func handleMessage(backend Backend, peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%v: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	defer msg.Discard()
+
+	triedb := backend.StateCache().TrieDB()
+	start := time.Now()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		accounts, proofs := ServiceGetAccountRangeQuery(triedb, &req)
+		log.Trace("Served snap account range", "peer", peer.ID(), "accounts", len(accounts), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		return p2p.Send(rw, AccountRangeMsg, &AccountRangePacket{
+			ID:       req.ID,
+			Accounts: accounts,
+			Proof:    proofs,
+		})
+
+	case GetStorageRangesMsg:
+		var req GetStorageRangesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		slots, proofs := ServiceGetStorageRangesQuery(triedb, &req)
+		log.Trace("Served snap storage ranges", "peer", peer.ID(), "accounts", len(slots), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		return p2p.Send(rw, StorageRangesMsg, &StorageRangesPacket{
+			ID:    req.ID,
+			Slots: slots,
+			Proof: proofs,
+		})
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		codes := ServiceGetByteCodesQuery(triedb, &req)
+		log.Trace("Served snap bytecodes", "peer", peer.ID(), "codes", len(codes), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		return p2p.Send(rw, ByteCodesMsg, &ByteCodesPacket{
+			ID:    req.ID,
+			Codes: codes,
+		})
+
+	case GetTrieNodesMsg:
+		var req GetTrieNodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%v: message %v: %v", errDecode, msg, err)
+		}
+		nodes := ServiceGetTrieNodesQuery(triedb, &req, start)
+		log.Trace("Served snap trie nodes", "peer", peer.ID(), "nodes", len(nodes), "elapsed", common.PrettyDuration(time.Since(start)))
+
+		return p2p.Send(rw, TrieNodesMsg, &TrieNodesPacket{
+			ID:    req.ID,
+			Nodes: nodes,
+		})
+
+	case AccountRangeMsg, StorageRangesMsg, ByteCodesMsg, TrieNodesMsg:
+		// This node only serves state, it never requested any
+		return fmt.Errorf("%v: unsolicited response %v", errBadRequest, msg.Code)
+
+	default:
+		return fmt.Errorf("%v: %v", errInvalidMsgCode, msg.Code)
+	}
+}
+
+// proofList collects the trie nodes of merkle proofs, in order. It implements
+// abeydb.Putter.
+type proofList [][]byte
+
+// Put appends a trie node to the list.
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// ServiceGetAccountRangeQuery assembles the response to an account range query.
+// It returns the consecutive accounts from the origin, including the first one
+// at or beyond the limit, along with the proofs of the range edges.
+func ServiceGetAccountRangeQuery(triedb *trie.Database, req *GetAccountRangePacket) ([]*AccountData, [][]byte) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	// Retrieve the requested state, bail out if it's not available anymore
+	tr, err := trie.New(req.Root, triedb)
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		accounts []*AccountData
+		size     uint64
+		last     common.Hash
+	)
+	it := trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		last = hash
+
+		accounts = append(accounts, &AccountData{Hash: hash, Body: common.CopyBytes(it.Value)})
+		size += uint64(common.HashLength + len(it.Value))
+
+		// If we've exceeded the request threshold, abort
+		if bytes.Compare(hash[:], req.Limit[:]) >= 0 || size > req.Bytes {
+			break
+		}
+	}
+	if it.Err != nil {
+		return nil, nil
+	}
+	// Generate the Merkle proofs for the first and last account
+	var proof proofList
+	if err := tr.Prove(req.Origin[:], 0, &proof); err != nil {
+		log.Warn("Failed to prove account range", "origin", req.Origin, "err", err)
+		return nil, nil
+	}
+	if last != (common.Hash{}) {
+		if err := tr.Prove(last[:], 0, &proof); err != nil {
+			log.Warn("Failed to prove account range", "last", last, "err", err)
+			return nil, nil
+		}
+	}
+	return accounts, proof
+}
+
+// ServiceGetStorageRangesQuery assembles the response to a storage ranges query.
+// The origin applies to the first account only and the limit to the last one.
+// If the slots of an account don't fit into the response, it's the last one
+// served and the proofs of its range edges are included.
+func ServiceGetStorageRangesQuery(triedb *trie.Database, req *GetStorageRangesPacket) ([][]*StorageData, [][]byte) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	accTrie, err := trie.New(req.Root, triedb)
+	if err != nil {
+		return nil, nil
+	}
+	var (
+		slots  [][]*StorageData
+		proofs [][]byte
+		size   uint64
+	)
+	for i, account := range req.Accounts {
+		// If we've exceeded the requested data limit, abort without opening
+		// a new storage range (that we'd need to prove due to exceeded size)
+		if size >= req.Bytes {
+			break
+		}
+		origin, limit := common.Hash{}, maxHash
+		if i == 0 && len(req.Origin) > 0 {
+			origin = common.BytesToHash(req.Origin)
+		}
+		if i == len(req.Accounts)-1 && len(req.Limit) > 0 {
+			limit = common.BytesToHash(req.Limit)
+		}
+		// Retrieve the storage trie of the account, bail out if it's unknown
+		blob, err := accTrie.TryGet(account[:])
+		if err != nil || len(blob) == 0 {
+			return nil, nil
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return nil, nil
+		}
+		stTrie, err := trie.New(acc.Root, triedb)
+		if err != nil {
+			return nil, nil
+		}
+		var (
+			storage []*StorageData
+			last    common.Hash
+			abort   bool
+		)
+		it := trie.NewIterator(stTrie.NodeIterator(origin[:]))
+		for it.Next() {
+			if size >= req.Bytes {
+				abort = true
+				break
+			}
+			hash := common.BytesToHash(it.Key)
+			last = hash
+
+			storage = append(storage, &StorageData{Hash: hash, Body: common.CopyBytes(it.Value)})
+			size += uint64(common.HashLength + len(it.Value))
+
+			if bytes.Compare(hash[:], limit[:]) >= 0 {
+				break
+			}
+		}
+		if it.Err != nil {
+			return nil, nil
+		}
+		slots = append(slots, storage)
+
+		// Generate the Merkle proofs for the first and last storage slot, but
+		// only if the response was capped. If the entire storage trie included
+		// in the response, no need for any proofs.
+		if origin != (common.Hash{}) || (abort && len(storage) > 0) {
+			var proof proofList
+			if err := stTrie.Prove(origin[:], 0, &proof); err != nil {
+				log.Warn("Failed to prove storage range", "origin", origin, "err", err)
+				return nil, nil
+			}
+			if last != (common.Hash{}) {
+				if err := stTrie.Prove(last[:], 0, &proof); err != nil {
+					log.Warn("Failed to prove storage range", "last", last, "err", err)
+					return nil, nil
+				}
+			}
+			proofs = proof
+
+			// Proof terminates the reply as proofs are only added if a node
+			// refuses to serve more data (exception when a contract fetch is
+			// finishing, but that's that).
+			break
+		}
+	}
+	return slots, proofs
+}
+
+// ServiceGetByteCodesQuery assembles the response to a bytecode query. Unknown
+// codes are skipped.
+func ServiceGetByteCodesQuery(triedb *trie.Database, req *GetByteCodesPacket) [][]byte {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if len(req.Hashes) > maxCodeLookups {
+		req.Hashes = req.Hashes[:maxCodeLookups]
+	}
+	var (
+		codes [][]byte
+		bytes uint64
+	)
+	for _, hash := range req.Hashes {
+		if hash == emptyCode {
+			// Peers should not request the empty code, but if they do, at
+			// least sent them back a correct response without db lookups
+			codes = append(codes, []byte{})
+		} else if blob, err := triedb.Node(hash); err == nil {
+			codes = append(codes, blob)
+			bytes += uint64(len(blob))
+		}
+		if bytes > req.Bytes {
+			break
+		}
+	}
+	return codes
+}
+
+// ServiceGetTrieNodesQuery assembles the response to a trie node query. Unknown
+// nodes are skipped, and nothing is served if the root itself is unknown.
+func ServiceGetTrieNodesQuery(triedb *trie.Database, req *GetTrieNodesPacket, start time.Time) [][]byte {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if _, err := triedb.Node(req.Root); err != nil {
+		return nil
+	}
+	var (
+		nodes [][]byte
+		bytes uint64
+		loads int // Trie hash expansions to count database reads
+	)
+	for _, hash := range req.Hashes {
+		if blob, err := triedb.Node(hash); err == nil {
+			nodes = append(nodes, blob)
+			bytes += uint64(len(blob))
+		}
+		loads++
+		if bytes > req.Bytes || loads > maxTrieNodeLookups || time.Since(start) > maxTrieNodeTimeSpent {
+			break
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/trie"
+)
+
+// makeTestState creates a state of the given number of accounts, the first of
+// which is a contract with code and storage slots.
+func makeTestState(t *testing.T, accounts, slots int) (*trie.Database, common.Hash, common.Address) {
+	sdb := state.NewDatabase(abeydb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	contract := common.BigToAddress(big.NewInt(1))
+	statedb.SetCode(contract, []byte{0x60, 0x00, 0x60, 0x00, 0xf3})
+	for i := 0; i < slots; i++ {
+		statedb.SetState(contract, common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i+1))))
+	}
+	for i := 1; i < accounts; i++ {
+		statedb.AddBalance(common.BigToAddress(big.NewInt(int64(i+1))), big.NewInt(int64(i)))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	return sdb.TrieDB(), root, contract
+}
+
+// proofDatabase loads a list of proof nodes into a database keyed by their hash.
+func proofDatabase(proof [][]byte) *abeydb.MemDatabase {
+	db := abeydb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// Tests that account ranges are served consecutively within the size limit,
+// with proofs for both edges of the range.
+func TestAccountRange(t *testing.T) {
+	triedb, root, _ := makeTestState(t, 64, 0)
+
+	// Retrieve the entire state in one go
+	all, proof := ServiceGetAccountRangeQuery(triedb, &GetAccountRangePacket{Root: root, Limit: maxHash, Bytes: softResponseLimit})
+	if len(all) != 64 {
+		t.Fatalf("account count mismatch: have %d, want 64", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if bytes.Compare(all[i-1].Hash[:], all[i].Hash[:]) >= 0 {
+			t.Fatalf("accounts %d and %d out of order", i-1, i)
+		}
+	}
+	if len(proof) == 0 {
+		t.Fatalf("missing range proof")
+	}
+	// Retrieve a capped range from the middle and check the edge proofs
+	origin := all[10].Hash
+	capped, proof := ServiceGetAccountRangeQuery(triedb, &GetAccountRangePacket{Root: root, Origin: origin, Limit: maxHash, Bytes: 1})
+	if len(capped) != 1 || capped[0].Hash != origin {
+		t.Fatalf("capped range mismatch: have %d accounts", len(capped))
+	}
+	value, _, err := trie.VerifyProof(root, origin[:], proofDatabase(proof))
+	if err != nil {
+		t.Fatalf("failed to verify edge proof: %v", err)
+	}
+	if !bytes.Equal(value, capped[0].Body) {
+		t.Fatalf("proven account mismatch: have %x, want %x", value, capped[0].Body)
+	}
+	// The range must stop at the first account beyond the limit
+	limited, _ := ServiceGetAccountRangeQuery(triedb, &GetAccountRangePacket{Root: root, Limit: all[5].Hash, Bytes: softResponseLimit})
+	if len(limited) != 6 {
+		t.Fatalf("limited range mismatch: have %d accounts, want 6", len(limited))
+	}
+	// Unknown roots must not be served
+	if accounts, proof := ServiceGetAccountRangeQuery(triedb, &GetAccountRangePacket{Root: common.HexToHash("0x01"), Limit: maxHash, Bytes: softResponseLimit}); accounts != nil || proof != nil {
+		t.Fatalf("served unknown root")
+	}
+}
+
+// Tests that storage ranges are proven only if they are capped.
+func TestStorageRanges(t *testing.T) {
+	triedb, root, contract := makeTestState(t, 4, 32)
+	account := crypto.Keccak256Hash(contract[:])
+
+	slots, proof := ServiceGetStorageRangesQuery(triedb, &GetStorageRangesPacket{Root: root, Accounts: []common.Hash{account}, Bytes: softResponseLimit})
+	if len(slots) != 1 || len(slots[0]) != 32 {
+		t.Fatalf("storage range mismatch: have %v", slots)
+	}
+	if len(proof) != 0 {
+		t.Fatalf("complete storage range proven")
+	}
+	slots, proof = ServiceGetStorageRangesQuery(triedb, &GetStorageRangesPacket{Root: root, Accounts: []common.Hash{account}, Bytes: 1})
+	if len(slots) != 1 || len(slots[0]) != 1 {
+		t.Fatalf("capped storage range mismatch: have %v", slots)
+	}
+	if len(proof) == 0 {
+		t.Fatalf("capped storage range not proven")
+	}
+}
+
+// Tests that bytecodes and trie nodes are served by hash, skipping unknown ones.
+func TestByteCodesAndTrieNodes(t *testing.T) {
+	triedb, root, _ := makeTestState(t, 4, 0)
+
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	codes := ServiceGetByteCodesQuery(triedb, &GetByteCodesPacket{
+		Hashes: []common.Hash{crypto.Keccak256Hash(code), common.HexToHash("0x01"), emptyCode},
+		Bytes:  softResponseLimit,
+	})
+	if len(codes) != 2 || !bytes.Equal(codes[0], code) || len(codes[1]) != 0 {
+		t.Fatalf("bytecodes mismatch: have %x", codes)
+	}
+	nodes := ServiceGetTrieNodesQuery(triedb, &GetTrieNodesPacket{Root: root, Hashes: []common.Hash{root, common.HexToHash("0x01")}, Bytes: softResponseLimit}, time.Now())
+	if len(nodes) != 1 || crypto.Keccak256Hash(nodes[0]) != root {
+		t.Fatalf("trie nodes mismatch: have %x", nodes)
+	}
+}
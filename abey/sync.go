@@ -83,6 +83,28 @@ func (pm *ProtocolManager) syncFruits(p *peer) {
 	}
 }
 
+// syncSealed resends the still pending locally sealed snail blocks and fruits
+// to the given peer, recovering the ones sealed while the node was partitioned.
+func (pm *ProtocolManager) syncSealed(p *peer) {
+	if pm.sealed == nil {
+		return
+	}
+	blocks, fruits := pm.sealed.pending()
+	for _, block := range blocks {
+		td := pm.snailchain.GetTd(block.Hash(), block.NumberU64())
+		if td == nil {
+			continue
+		}
+		p.AsyncSendNewBlock(nil, block, td, false)
+	}
+	if len(fruits) > 0 {
+		p.AsyncSendFruits(fruits)
+	}
+	if len(blocks) > 0 || len(fruits) > 0 {
+		log.Debug("Resent sealed items", "peer", p.id, "blocks", len(blocks), "fruits", len(fruits))
+	}
+}
+
 // txsyncLoop takes care of the initial transaction sync for each new
 // connection. When a new peer appears, we relay all currently pending
 // transactions. In order to minimise egress bandwidth usage, we send
@@ -247,11 +269,15 @@ func (pm *ProtocolManager) syncer() {
 			if pm.peers.Len() < minDesiredPeerCount {
 				break
 			}
-			go pm.synchronise(pm.peers.BestPeer())
+			go pm.synchronise(pm.syncPeer())
 
 		case <-forceSync.C:
 			// Force a sync even if not enough peers are present
-			go pm.synchronise(pm.peers.BestPeer())
+			go pm.synchronise(pm.syncPeer())
+
+		case <-pm.syncRestartCh:
+			// The last session stalled, retry with another peer right away
+			go pm.synchronise(pm.syncPeer())
 
 		case <-pm.noMorePeers:
 			return
@@ -260,6 +286,55 @@ func (pm *ProtocolManager) syncer() {
 	}
 }
 
+// syncPeer returns the peer to synchronise with, avoiding the master peer of
+// the last stalled session unless it is the only one.
+func (pm *ProtocolManager) syncPeer() *peer {
+	if stalled, _ := pm.stalledPeer.Load().(string); stalled != "" {
+		if p := pm.peers.BestPeerExcept(stalled); p != nil {
+			return p
+		}
+	}
+	return pm.peers.BestPeer()
+}
+
+// syncWatchdog monitors the progress of a sync session with the given master
+// peer until done is closed. If no progress is made for the stall timeout, the
+// session is cancelled and true returned, so that it can be restarted with
+// another peer.
+func (pm *ProtocolManager) syncWatchdog(peer *peer, done chan struct{}) bool {
+	ticker := time.NewTicker(pm.syncStallTimeout / 10)
+	defer ticker.Stop()
+
+	var (
+		last     = pm.downloader.Progress()
+		progress = time.Now()
+	)
+	for {
+		select {
+		case <-ticker.C:
+			// Any block imported or state entry pulled counts as progress
+			if current := pm.downloader.Progress(); current != last {
+				last, progress = current, time.Now()
+				continue
+			}
+			if time.Since(progress) < pm.syncStallTimeout {
+				continue
+			}
+			log.Warn("Synchronisation stalled, restarting", "peer", peer.id, "elapsed", common.PrettyDuration(time.Since(progress)),
+				"snail", last.CurrentSnailBlock, "fast", last.CurrentFastBlock, "states", last.PulledStates)
+			syncStallMeter.Mark(1)
+
+			pm.stalledPeer.Store(peer.id)
+			pm.downloader.Cancel()
+			pm.fdownloader.Cancel()
+			return true
+
+		case <-done:
+			return false
+		}
+	}
+}
+
 // synchronise tries to sync up our local block chain with a remote peer.
 func (pm *ProtocolManager) synchronise(peer *peer) {
 	// Short circuit if no peers are available
@@ -267,7 +342,16 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		log.Debug("synchronise snail busy")
 		return
 	}
-	defer atomic.StoreInt32(&pm.synchronising, 0)
+	var stalled bool
+	defer func() {
+		atomic.StoreInt32(&pm.synchronising, 0)
+		if stalled {
+			select {
+			case pm.syncRestartCh <- struct{}{}:
+			default:
+			}
+		}
+	}()
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
 	defer log.Debug("synchronise exit")
@@ -276,6 +360,16 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		log.Debug("synchronise peer nil")
 		return
 	}
+	if pm.syncStallTimeout > 0 {
+		done, result := make(chan struct{}), make(chan bool, 1)
+		go func() { result <- pm.syncWatchdog(peer, done) }()
+		defer func() {
+			close(done)
+			if stalled = <-result; !stalled {
+				pm.stalledPeer.Store("")
+			}
+		}()
+	}
 	var err error
 	sendEvent := func() {
 		// reset on error
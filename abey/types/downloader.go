@@ -363,6 +363,7 @@ func (ps *PeerSet) MedianRTT() time.Duration {
 type FetchRequest struct {
 	Peer     PeerConnection       // Peer to which the request was sent
 	From     uint64               // [eth/62] Requested chain element index (used for skeleton fills only)
+	Count    int                  // [eth/62] Requested number of chain elements (used for skeleton fills only)
 	Sheaders []*types.SnailHeader // [eth/62] Requested headers, sorted by request order
 	Fheaders []*types.Header      // [eth/62] Requested headers, sorted by request order
 	Time     time.Time            // Time when the request was made
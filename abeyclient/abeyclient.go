@@ -170,6 +170,7 @@ func (ec *Client) getFruit(ctx context.Context, method string, args ...interface
 }
 
 type rpcSnailHeader struct {
+	Version    hexutil.Uint     `json:"version"`
 	Hash       common.Hash      `json:"hash"`
 	Number     *hexutil.Big     `json:"number"`
 	ParentHash common.Hash      `json:"parentHash"`
@@ -287,7 +288,7 @@ func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.He
 // nil, the latest known header is returned.
 func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
 	var head *types.Header
-	err := ec.c.CallContext(ctx, &head, "abey_getBlockByNumber", toBlockNumArg(number), false)
+	err := ec.c.CallContext(ctx, &head, "abey_getHeaderByNumber", toBlockNumArg(number))
 	if err == nil && head == nil {
 		err = abeychain.NotFound
 	}
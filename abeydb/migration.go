@@ -0,0 +1,302 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abeydb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var (
+	// schemaVersionKey tracks the last schema migration applied to the database.
+	schemaVersionKey = []byte("SchemaVersion")
+
+	// migrationBackupPrefix + version (uint64 big endian) + key -> value before the migration
+	migrationBackupPrefix = []byte("migration-backup-")
+
+	// migrationPendingPrefix + version (uint64 big endian) -> marker of a complete backup
+	migrationPendingPrefix = []byte("migration-pending-")
+)
+
+var (
+	// ErrSchemaTooNew is returned if the database was migrated by a newer
+	// release than the running one.
+	ErrSchemaTooNew = errors.New("database schema newer than supported")
+
+	// ErrReadOnlyMigration is returned if a read-only database has migrations
+	// pending, which would only be applied to its memory overlay.
+	ErrReadOnlyMigration = errors.New("database migration pending on read-only database")
+
+	errUnorderedMigrations = errors.New("migrations not in ascending version order")
+	errNoIterator          = errors.New("database can't back up key ranges")
+)
+
+// Iteratee is implemented by databases able to iterate over key ranges.
+type Iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Migration is a change of the database format. Migrations are applied in
+// version order, each exactly once per database.
+type Migration struct {
+	Version  uint64               // Schema version the database is at after the migration
+	Name     string               // Human readable description for the logs
+	Prefixes [][]byte             // Key ranges the migration modifies, backed up while it runs
+	Migrate  func(Database) error // Converts the data, must not touch keys outside Prefixes
+}
+
+// ReadSchemaVersion returns the schema version of the database, zero if no
+// migration was ever applied.
+func ReadSchemaVersion(db Database) uint64 {
+	data, _ := db.Get(schemaVersionKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteSchemaVersion stores the schema version of the database.
+func WriteSchemaVersion(db Putter, version uint64) error {
+	return db.Put(schemaVersionKey, encodeVersion(version))
+}
+
+func encodeVersion(version uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, version)
+	return enc
+}
+
+// Migrate applies the migrations the database hasn't seen yet, in order. The
+// key ranges touched by a migration are backed up first and restored if it
+// fails, also if it was interrupted by a crash on the previous run, so a failed
+// migration leaves the database as it was before.
+func Migrate(db Database, migrations []Migration) error {
+	var latest uint64
+	for _, m := range migrations {
+		if m.Version <= latest {
+			return errUnorderedMigrations
+		}
+		latest = m.Version
+	}
+	current := ReadSchemaVersion(db)
+	if current > latest {
+		return fmt.Errorf("%w: have %d, support %d", ErrSchemaTooNew, current, latest)
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			// Drop backups left behind if the last run crashed right after migrating
+			if ok, _ := db.Has(pendingKey(m.Version)); ok {
+				if err := dropBackup(db, m); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if _, ok := db.(*ReadOnlyDatabase); ok {
+			return fmt.Errorf("%w: version %d (%s)", ErrReadOnlyMigration, m.Version, m.Name)
+		}
+		if err := recoverMigration(db, m); err != nil {
+			return fmt.Errorf("failed to recover interrupted migration %d (%s): %v", m.Version, m.Name, err)
+		}
+		log.Warn("Migrating database schema", "version", m.Version, "name", m.Name)
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		log.Info("Migrated database schema", "version", m.Version, "name", m.Name)
+		current = m.Version
+	}
+	return nil
+}
+
+// applyMigration runs a migration on top of a backup of the key ranges it
+// touches, restoring them if it fails.
+func applyMigration(db Database, m Migration) error {
+	if err := backupRanges(db, m); err != nil {
+		return err
+	}
+	if err := m.Migrate(db); err != nil {
+		if rerr := restoreRanges(db, m); rerr != nil {
+			log.Error("Failed to restore database after migration failure", "version", m.Version, "err", rerr)
+		}
+		return err
+	}
+	if err := WriteSchemaVersion(db, m.Version); err != nil {
+		return err
+	}
+	return dropBackup(db, m)
+}
+
+// recoverMigration restores the backup of a migration that was interrupted.
+func recoverMigration(db Database, m Migration) error {
+	if ok, _ := db.Has(pendingKey(m.Version)); !ok {
+		return nil
+	}
+	log.Warn("Restoring database after interrupted migration", "version", m.Version, "name", m.Name)
+	return restoreRanges(db, m)
+}
+
+func pendingKey(version uint64) []byte {
+	return append(append([]byte{}, migrationPendingPrefix...), encodeVersion(version)...)
+}
+
+func backupPrefix(version uint64) []byte {
+	return append(append([]byte{}, migrationBackupPrefix...), encodeVersion(version)...)
+}
+
+// iteratee returns the database to iterate key ranges in, nil if the migration
+// doesn't need a backup.
+func iteratee(db Database, m Migration) (Iteratee, error) {
+	if len(m.Prefixes) == 0 {
+		return nil, nil
+	}
+	// Backups of read-only databases would be lost with their overlay
+	if _, ok := db.(*ReadOnlyDatabase); ok {
+		return nil, ErrReadOnlyMigration
+	}
+	it, ok := db.(Iteratee)
+	if !ok {
+		return nil, errNoIterator
+	}
+	return it, nil
+}
+
+// flushBatch writes the batch out once it grew large enough, or if forced.
+func flushBatch(batch Batch, force bool) error {
+	if batch.ValueSize() < IdealBatchSize && !force {
+		return nil
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+	return nil
+}
+
+// backupRanges copies the key ranges of a migration into the backup area and
+// marks the backup complete.
+func backupRanges(db Database, m Migration) error {
+	source, err := iteratee(db, m)
+	if source == nil {
+		return err
+	}
+	var (
+		batch  = db.NewBatch()
+		prefix = backupPrefix(m.Version)
+		count  int
+	)
+	for _, r := range m.Prefixes {
+		it := source.NewIteratorWithPrefix(r)
+		for it.Next() {
+			if err := batch.Put(append(append([]byte{}, prefix...), it.Key()...), it.Value()); err != nil {
+				it.Release()
+				return err
+			}
+			count++
+			if err := flushBatch(batch, false); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	if err := batch.Put(pendingKey(m.Version), []byte{1}); err != nil {
+		return err
+	}
+	if err := flushBatch(batch, true); err != nil {
+		return err
+	}
+	log.Info("Backed up database before migration", "version", m.Version, "keys", count)
+	return nil
+}
+
+// restoreRanges replaces the key ranges of a migration with their backup.
+func restoreRanges(db Database, m Migration) error {
+	source, err := iteratee(db, m)
+	if source == nil {
+		return err
+	}
+	batch := db.NewBatch()
+	for _, r := range m.Prefixes {
+		it := source.NewIteratorWithPrefix(r)
+		for it.Next() {
+			if err := batch.Delete(append([]byte{}, it.Key()...)); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	if err := flushBatch(batch, true); err != nil {
+		return err
+	}
+	prefix := backupPrefix(m.Version)
+	it := source.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	for it.Next() {
+		if err := batch.Put(append([]byte{}, it.Key()[len(prefix):]...), it.Value()); err != nil {
+			return err
+		}
+		if err := flushBatch(batch, false); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := flushBatch(batch, true); err != nil {
+		return err
+	}
+	return dropBackup(db, m)
+}
+
+// dropBackup deletes the backup of a migration, the completion marker first.
+func dropBackup(db Database, m Migration) error {
+	source, err := iteratee(db, m)
+	if source == nil {
+		return err
+	}
+	if err := db.Delete(pendingKey(m.Version)); err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	it := source.NewIteratorWithPrefix(backupPrefix(m.Version))
+	defer it.Release()
+
+	for it.Next() {
+		if err := batch.Delete(append([]byte{}, it.Key()...)); err != nil {
+			return err
+		}
+		if err := flushBatch(batch, false); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return flushBatch(batch, true)
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abeydb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// rewrite returns a migration replacing the values under the prefix, failing
+// halfway if requested.
+func rewrite(version uint64, prefix, value []byte, fail bool) Migration {
+	return Migration{
+		Version:  version,
+		Name:     "rewrite",
+		Prefixes: [][]byte{prefix},
+		Migrate: func(db Database) error {
+			it := db.(Iteratee).NewIteratorWithPrefix(prefix)
+			defer it.Release()
+
+			for it.Next() {
+				db.Put(append([]byte{}, it.Key()...), value)
+				if fail {
+					db.Put(append(append([]byte{}, prefix...), "new"...), value)
+					return errors.New("failed")
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func checkValues(t *testing.T, db Database, prefix, want []byte, count int) {
+	t.Helper()
+
+	it := db.(Iteratee).NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	n := 0
+	for it.Next() {
+		if !bytes.Equal(it.Value(), want) {
+			t.Errorf("key %q: have %q, want %q", it.Key(), it.Value(), want)
+		}
+		n++
+	}
+	if n != count {
+		t.Errorf("key count mismatch: have %d, want %d", n, count)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := NewMemDatabase()
+	for _, key := range []string{"a1", "a2", "a3", "b1"} {
+		db.Put([]byte(key), []byte("old"))
+	}
+	migrations := []Migration{
+		rewrite(1, []byte("a"), []byte("v1"), false),
+		rewrite(2, []byte("b"), []byte("v2"), false),
+	}
+	if err := Migrate(db, migrations); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if v := ReadSchemaVersion(db); v != 2 {
+		t.Fatalf("schema version mismatch: have %d, want 2", v)
+	}
+	checkValues(t, db, []byte("a"), []byte("v1"), 3)
+	checkValues(t, db, []byte("b"), []byte("v2"), 1)
+	checkValues(t, db, migrationBackupPrefix, nil, 0)
+
+	// Applied migrations must not run again
+	migrations[0] = rewrite(1, []byte("a"), []byte("again"), false)
+	if err := Migrate(db, migrations); err != nil {
+		t.Fatalf("failed to rerun migrations: %v", err)
+	}
+	checkValues(t, db, []byte("a"), []byte("v1"), 3)
+
+	// Failed migrations must be rolled back, keeping the schema version
+	if err := Migrate(db, append(migrations, rewrite(3, []byte("a"), []byte("v3"), true))); err == nil {
+		t.Fatalf("failed migration succeeded")
+	}
+	if v := ReadSchemaVersion(db); v != 2 {
+		t.Fatalf("schema version after failure mismatch: have %d, want 2", v)
+	}
+	checkValues(t, db, []byte("a"), []byte("v1"), 3)
+	checkValues(t, db, migrationBackupPrefix, nil, 0)
+
+	// Newer databases and unordered migrations must be refused
+	if err := Migrate(db, migrations[:1]); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("downgrade error mismatch: have %v, want %v", err, ErrSchemaTooNew)
+	}
+	if err := Migrate(db, []Migration{migrations[1], migrations[0]}); err != errUnorderedMigrations {
+		t.Fatalf("order error mismatch: have %v, want %v", err, errUnorderedMigrations)
+	}
+}
+
+// Tests that a migration interrupted by a crash is rolled back before rerunning.
+func TestMigrateInterrupted(t *testing.T) {
+	db := NewMemDatabase()
+	for _, key := range []string{"a1", "a2"} {
+		db.Put([]byte(key), []byte("old"))
+	}
+	m := rewrite(1, []byte("a"), []byte("v1"), false)
+	if err := backupRanges(db, m); err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	// Simulate a crash halfway through the migration
+	db.Put([]byte("a1"), []byte("half"))
+	db.Put([]byte("a9"), []byte("half"))
+
+	checking := m
+	checking.Migrate = func(db Database) error {
+		checkValues(t, db, []byte("a"), []byte("old"), 2)
+		return m.Migrate(db)
+	}
+	if err := Migrate(db, []Migration{checking}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	checkValues(t, db, []byte("a"), []byte("v1"), 2)
+	checkValues(t, db, migrationPendingPrefix, nil, 0)
+}
+
+// Tests that pending migrations are refused on read-only databases, instead of
+// being applied to the discarded memory overlay only.
+func TestMigrateReadOnly(t *testing.T) {
+	base := NewMemDatabase()
+	base.Put([]byte("a1"), []byte("old"))
+
+	migrations := []Migration{rewrite(1, []byte("a"), []byte("v1"), false)}
+	if err := Migrate(NewReadOnlyDatabase(base), migrations); !errors.Is(err, ErrReadOnlyMigration) {
+		t.Fatalf("read-only error mismatch: have %v, want %v", err, ErrReadOnlyMigration)
+	}
+	if v := ReadSchemaVersion(base); v != 0 {
+		t.Fatalf("schema version mismatch: have %d, want 0", v)
+	}
+	// Up to date databases can be opened read-only
+	if err := Migrate(base, migrations); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := Migrate(NewReadOnlyDatabase(base), migrations); err != nil {
+		t.Fatalf("failed to check read-only database: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abeydb
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/abeychain/go-abey/common"
+)
+
+// ReadOnlyDatabase serves reads from a database that must not be modified,
+// keeping all writes in memory on top of it. It allows running a node on a
+// snapshot of another node's data without ever touching it. Writes are lost
+// when the database is closed.
+type ReadOnlyDatabase struct {
+	base   Database
+	writes map[string][]byte // Overlay of written values, nil for deleted ones
+	lock   sync.RWMutex
+}
+
+// NewReadOnlyDatabase wraps a database, keeping all writes in memory.
+func NewReadOnlyDatabase(base Database) *ReadOnlyDatabase {
+	return &ReadOnlyDatabase{
+		base:   base,
+		writes: make(map[string][]byte),
+	}
+}
+
+// Put stores the value in the memory overlay.
+func (db *ReadOnlyDatabase) Put(key []byte, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.writes[string(key)] = common.CopyBytes(value)
+	return nil
+}
+
+// Has checks the memory overlay first, then the underlying database.
+func (db *ReadOnlyDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	value, ok := db.writes[string(key)]
+	db.lock.RUnlock()
+
+	if ok {
+		return value != nil, nil
+	}
+	return db.base.Has(key)
+}
+
+// Get retrieves the value from the memory overlay first, then the underlying
+// database.
+func (db *ReadOnlyDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	value, ok := db.writes[string(key)]
+	db.lock.RUnlock()
+
+	if ok {
+		if value == nil {
+			return nil, errors.New("not found")
+		}
+		return common.CopyBytes(value), nil
+	}
+	return db.base.Get(key)
+}
+
+// Delete hides the key in the memory overlay.
+func (db *ReadOnlyDatabase) Delete(key []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.writes[string(key)] = nil
+	return nil
+}
+
+// Close drops the memory overlay and closes the underlying database.
+func (db *ReadOnlyDatabase) Close() {
+	db.lock.Lock()
+	db.writes = make(map[string][]byte)
+	db.lock.Unlock()
+
+	db.base.Close()
+}
+
+// NewBatch creates a batch applied to the memory overlay.
+func (db *ReadOnlyDatabase) NewBatch() Batch {
+	return &readOnlyBatch{db: db}
+}
+
+// Base returns the underlying database.
+func (db *ReadOnlyDatabase) Base() Database {
+	return db.base
+}
+
+type readOnlyBatch struct {
+	db     *ReadOnlyDatabase
+	writes []kv
+	size   int
+}
+
+func (b *readOnlyBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), common.CopyBytes(value)})
+	b.size += len(value)
+	return nil
+}
+
+func (b *readOnlyBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, kv{common.CopyBytes(key), nil})
+	b.size += 1
+	return nil
+}
+
+func (b *readOnlyBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	for _, kv := range b.writes {
+		b.db.writes[string(kv.k)] = kv.v
+	}
+	return nil
+}
+
+func (b *readOnlyBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *readOnlyBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abeydb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/hexutil"
+)
+
+// maxUnknownSamples is the number of unknown keys an inspection reports.
+const maxUnknownSamples = 16
+
+// KeySchema describes a family of keys stored in the database.
+type KeySchema struct {
+	Name   string        `json:"name"`             // Short name of the stored data
+	Owner  string        `json:"owner"`            // Package writing the keys
+	Prefix hexutil.Bytes `json:"prefix"`           // Common prefix of the keys
+	Suffix hexutil.Bytes `json:"suffix,omitempty"` // Common suffix of the keys, if any
+	Length int           `json:"length,omitempty"` // Exact length of the keys, zero if variable
+	Layout string        `json:"layout"`           // Human readable layout of the keys
+
+	// Match optionally checks entries the key shape can't tell apart
+	Match func(key, value []byte) bool `json:"-"`
+}
+
+// matches checks whether an entry belongs to the key family.
+func (s *KeySchema) matches(key, value []byte) bool {
+	if !bytes.HasPrefix(key, s.Prefix) {
+		return false
+	}
+	if s.Length != 0 && len(key) != s.Length {
+		return false
+	}
+	if len(s.Suffix) > 0 && (len(key) < len(s.Prefix)+len(s.Suffix) || !bytes.HasSuffix(key, s.Suffix)) {
+		return false
+	}
+	return s.Match == nil || s.Match(key, value)
+}
+
+// Schema lists the keys written by this package.
+var Schema = []KeySchema{
+	{Name: "schema version", Owner: "abeydb", Prefix: schemaVersionKey, Length: len(schemaVersionKey), Layout: "SchemaVersion"},
+	{Name: "migration backup", Owner: "abeydb", Prefix: migrationBackupPrefix, Layout: "migration-backup- + version (uint64 big endian) + key"},
+	{Name: "migration pending", Owner: "abeydb", Prefix: migrationPendingPrefix, Length: len(migrationPendingPrefix) + 8, Layout: "migration-pending- + version (uint64 big endian)"},
+}
+
+// KeyStats counts the entries of a key family.
+type KeyStats struct {
+	Name  string             `json:"name"`
+	Count uint64             `json:"count"`
+	Size  common.StorageSize `json:"size"` // Total size of keys and values
+}
+
+func (s *KeyStats) add(key, value []byte) {
+	s.Count++
+	s.Size += common.StorageSize(len(key) + len(value))
+}
+
+// Inspection is the result of scanning a database against its schema.
+type Inspection struct {
+	Known          []*KeyStats     `json:"known"`   // Entries by key family, in schema order
+	Unknown        KeyStats        `json:"unknown"` // Entries matching no key family
+	UnknownSamples []hexutil.Bytes `json:"unknownSamples"`
+}
+
+// Inspect scans the whole database, attributing every entry to the key family
+// it belongs to. Families with longer prefixes take precedence.
+func Inspect(db Iteratee, schema []KeySchema) *Inspection {
+	result := &Inspection{Unknown: KeyStats{Name: "unknown"}}
+	order := make([]int, len(schema))
+	for i := range schema {
+		order[i] = i
+		result.Known = append(result.Known, &KeyStats{Name: schema[i].Name})
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(schema[order[i]].Prefix) > len(schema[order[j]].Prefix)
+	})
+	it := db.NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+
+		known := false
+		for _, i := range order {
+			if schema[i].matches(key, value) {
+				result.Known[i].add(key, value)
+				known = true
+				break
+			}
+		}
+		if !known {
+			result.Unknown.add(key, value)
+			if len(result.UnknownSamples) < maxUnknownSamples {
+				result.UnknownSamples = append(result.UnknownSamples, common.CopyBytes(key))
+			}
+		}
+	}
+	return result
+}
+
+// printablePrefix renders a key prefix as a quoted string if it is plain text,
+// as hex otherwise.
+func printablePrefix(prefix []byte) string {
+	if len(prefix) == 0 {
+		return "-"
+	}
+	for _, b := range prefix {
+		if b < 0x20 || b > 0x7e {
+			return hexutil.Encode(prefix)
+		}
+	}
+	return strconv.Quote(string(prefix))
+}
+
+// WriteSchemaDoc writes the schema as a markdown table, sorted by prefix so the
+// output is the same for the same schema.
+func WriteSchemaDoc(w io.Writer, schema []KeySchema) error {
+	sorted := make([]KeySchema, len(schema))
+	copy(sorted, schema)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := bytes.Compare(sorted[i].Prefix, sorted[j].Prefix); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if _, err := fmt.Fprintln(w, "| Prefix | Name | Owner | Length | Layout |\n|---|---|---|---|---|"); err != nil {
+		return err
+	}
+	for _, s := range sorted {
+		length := "variable"
+		if s.Length != 0 {
+			length = strconv.Itoa(s.Length)
+		}
+		if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", printablePrefix(s.Prefix), s.Name, s.Owner, length, s.Layout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
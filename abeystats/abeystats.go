@@ -95,10 +95,9 @@ type Service struct {
 // New returns a monitoring service ready for stats reporting.
 func New(url string, ethServ *abey.Abeychain, lesServ *les.LightAbey) (*Service, error) {
 	// Parse the netstats connection url
-	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
-	parts := re.FindStringSubmatch(url)
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
+	name, pass, host, err := ParseURL(url)
+	if err != nil {
+		return nil, err
 	}
 	// Assemble and return the stats service
 	var engine consensus.Engine
@@ -111,9 +110,9 @@ func New(url string, ethServ *abey.Abeychain, lesServ *les.LightAbey) (*Service,
 		abey:        ethServ,
 		les:         lesServ,
 		engine:      engine,
-		node:        parts[1],
-		pass:        parts[3],
-		host:        parts[4],
+		node:        name,
+		pass:        pass,
+		host:        host,
 		pongCh:      make(chan struct{}),
 		histCh:      make(chan []uint64, 1),
 		snailHistCh: make(chan []uint64, 1),
@@ -231,37 +230,10 @@ func (s *Service) loop() {
 	}()
 	// Loop reporting until termination
 	for {
-		// Resolve the URL, defaulting to TLS, but falling back to none too
-		path := fmt.Sprintf("%s/api", s.host)
-		urls := []string{path}
-
-		if !strings.Contains(path, "://") { // url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
-			urls = []string{"wss://" + path, "ws://" + path}
-		}
-		// Establish a websocket connection to the server on any supported URL
-		var (
-			conf *websocket.Config
-			conn *websocket.Conn
-			err  error
-		)
-		for _, url := range urls {
-			if conf, err = websocket.NewConfig(url, "http://localhost/"); err != nil {
-				continue
-			}
-			conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
-			if conn, err = websocket.DialConfig(conf); err == nil {
-				break
-			}
-		}
+		// Establish an authenticated connection to the server
+		conn, err := Connect(s.host, s.node, s.pass, s.nodeInfo())
 		if err != nil {
-			log.Warn("Stats server unreachable", "err", err)
-			time.Sleep(10 * time.Second)
-			continue
-		}
-		// Authenticate the client with the server
-		if err = s.login(conn); err != nil {
-			log.Warn("Stats login failed", "err", err)
-			conn.Close()
+			log.Warn("Stats server connection failed", "err", err)
 			time.Sleep(10 * time.Second)
 			continue
 		}
@@ -417,9 +389,9 @@ func handleHistCh(msg map[string][]interface{}, s *Service, command string) stri
 	return ""
 }
 
-// nodeInfo is the collection of metainformation about a node that is displayed
+// NodeInfo is the collection of metainformation about a node that is displayed
 // on the monitoring page.
-type nodeInfo struct {
+type NodeInfo struct {
 	Name     string `json:"name"`
 	Node     string `json:"node"`
 	IP       string `json:"ip"`
@@ -436,43 +408,51 @@ type nodeInfo struct {
 // authMsg is the authentication infos needed to login to a monitoring server.
 type authMsg struct {
 	ID     string   `json:"id"`
-	Info   nodeInfo `json:"info"`
+	Info   NodeInfo `json:"info"`
 	Secret string   `json:"secret"`
 }
 
-// login tries to authorize the client at the remote server.
-func (s *Service) login(conn *websocket.Conn) error {
-	// Construct and send the login authentication
-	infos := s.server.NodeInfo()
+// ParseURL splits a stats server url of the form nodename:secret@host:port.
+func ParseURL(url string) (name, secret, host string, err error) {
+	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
+	parts := re.FindStringSubmatch(url)
+	if len(parts) != 5 {
+		return "", "", "", fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
+	}
+	return parts[1], parts[3], parts[4], nil
+}
 
-	var network, protocol string
-	if info := infos.Protocols["abey"]; info != nil {
-		network = fmt.Sprintf("%d", info.(*abey.NodeInfo).Network)
-		protocol = fmt.Sprintf("abey/%d", abey.ProtocolVersions[0])
-	} else {
-		network = fmt.Sprintf("%d", infos.Protocols["les"].(*les.NodeInfo).Network)
-		protocol = fmt.Sprintf("les/%d", les.ClientProtocolVersions[0])
+// Dial opens a websocket connection to the stats server, defaulting to TLS
+// but falling back to none too.
+func Dial(host string) (*websocket.Conn, error) {
+	path := fmt.Sprintf("%s/api", host)
+	urls := []string{path}
+
+	if !strings.Contains(path, "://") { // url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
+		urls = []string{"wss://" + path, "ws://" + path}
 	}
-	//fmt.Println("infos.IP= ",infos.IP)
-	auth := &authMsg{
-		ID: s.node,
-		Info: nodeInfo{
-			Name:     s.node,
-			Node:     infos.Name,
-			IP:       infos.IP,
-			Port:     infos.Ports.Listener,
-			Network:  network,
-			Protocol: protocol,
-			API:      "No",
-			Os:       runtime.GOOS,
-			OsVer:    runtime.GOARCH,
-			Client:   "0.1.1",
-			History:  true,
-		},
-		Secret: s.pass,
+	// Establish a websocket connection to the server on any supported URL
+	var (
+		conf *websocket.Config
+		conn *websocket.Conn
+		err  error
+	)
+	for _, url := range urls {
+		if conf, err = websocket.NewConfig(url, "http://localhost/"); err != nil {
+			continue
+		}
+		conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
+		if conn, err = websocket.DialConfig(conf); err == nil {
+			return conn, nil
+		}
 	}
+	return nil, err
+}
+
+// Login tries to authorize the client at the remote server.
+func Login(conn *websocket.Conn, id, secret string, info NodeInfo) error {
 	login := map[string][]interface{}{
-		"emit": {"hello", auth},
+		"emit": {"hello", &authMsg{ID: id, Info: info, Secret: secret}},
 	}
 	if err := websocket.JSON.Send(conn, login); err != nil {
 		return err
@@ -485,6 +465,48 @@ func (s *Service) login(conn *websocket.Conn) error {
 	return nil
 }
 
+// Connect dials the stats server and authorizes the client, closing the
+// connection again if the login fails. Callers reconnect by calling it anew
+// once a connection breaks.
+func Connect(host, id, secret string, info NodeInfo) (*websocket.Conn, error) {
+	conn, err := Dial(host)
+	if err != nil {
+		return nil, err
+	}
+	if err := Login(conn, id, secret, info); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// nodeInfo assembles the infos of the local node displayed on the monitoring page.
+func (s *Service) nodeInfo() NodeInfo {
+	infos := s.server.NodeInfo()
+
+	var network, protocol string
+	if info := infos.Protocols["abey"]; info != nil {
+		network = fmt.Sprintf("%d", info.(*abey.NodeInfo).Network)
+		protocol = fmt.Sprintf("abey/%d", abey.ProtocolVersions[0])
+	} else {
+		network = fmt.Sprintf("%d", infos.Protocols["les"].(*les.NodeInfo).Network)
+		protocol = fmt.Sprintf("les/%d", les.ClientProtocolVersions[0])
+	}
+	return NodeInfo{
+		Name:     s.node,
+		Node:     infos.Name,
+		IP:       infos.IP,
+		Port:     infos.Ports.Listener,
+		Network:  network,
+		Protocol: protocol,
+		API:      "No",
+		Os:       runtime.GOOS,
+		OsVer:    runtime.GOARCH,
+		Client:   "0.1.1",
+		History:  true,
+	}
+}
+
 // report collects all possible data to report and send it to the stats server.
 // This should only be used on reconnects or rarely to avoid overloading the
 // server. Use the individual methods for reporting subscribed events.
@@ -541,17 +563,24 @@ func (s *Service) reportLatency(conn *websocket.Conn) error {
 	return websocket.JSON.Send(conn, stats)
 }
 
-// blockStats is the information to report about individual blocks.
+// blockStats is the information to report about individual blocks. Fast blocks
+// are not mined, the proposer is reported as miner and the difficulty and uncle
+// fields are filled with neutral values for classic ethstats dashboards.
 type blockStats struct {
-	Number     *big.Int    `json:"number"`
-	Hash       common.Hash `json:"hash"`
-	ParentHash common.Hash `json:"parentHash"`
-	Timestamp  *big.Int    `json:"timestamp"`
-	GasUsed    uint64      `json:"gasUsed"`
-	GasLimit   uint64      `json:"gasLimit"`
-	Txs        []txStats   `json:"transactions"`
-	TxHash     common.Hash `json:"transactionsRoot"`
-	Root       common.Hash `json:"stateRoot"`
+	Number      *big.Int       `json:"number"`
+	Hash        common.Hash    `json:"hash"`
+	ParentHash  common.Hash    `json:"parentHash"`
+	Timestamp   *big.Int       `json:"timestamp"`
+	Miner       common.Address `json:"miner"`
+	GasUsed     uint64         `json:"gasUsed"`
+	GasLimit    uint64         `json:"gasLimit"`
+	Diff        string         `json:"difficulty"`
+	TotalDiff   string         `json:"totalDifficulty"`
+	Txs         []txStats      `json:"transactions"`
+	TxHash      common.Hash    `json:"transactionsRoot"`
+	Root        common.Hash    `json:"stateRoot"`
+	Uncles      []struct{}     `json:"uncles"`
+	SnailNumber *big.Int       `json:"snailNumber"`
 }
 
 // blockStats is the information to report about individual blocks.
@@ -652,15 +681,20 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		txs = []txStats{}
 	}
 	return &blockStats{
-		Number:     header.Number,
-		Hash:       header.Hash(),
-		ParentHash: header.ParentHash,
-		Timestamp:  header.Time,
-		GasUsed:    header.GasUsed,
-		GasLimit:   header.GasLimit,
-		Txs:        txs,
-		TxHash:     header.TxHash,
-		Root:       header.Root,
+		Number:      header.Number,
+		Hash:        header.Hash(),
+		ParentHash:  header.ParentHash,
+		Timestamp:   header.Time,
+		Miner:       header.Proposer,
+		GasUsed:     header.GasUsed,
+		GasLimit:    header.GasLimit,
+		Diff:        "0",
+		TotalDiff:   "0",
+		Txs:         txs,
+		TxHash:      header.TxHash,
+		Root:        header.Root,
+		Uncles:      []struct{}{},
+		SnailNumber: header.SnailNumber,
 	}
 }
 
@@ -848,15 +882,19 @@ func (s *Service) reportPending(conn *websocket.Conn) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active            bool `json:"active"`
-	Syncing           bool `json:"syncing"`
-	Mining            bool `json:"mining"`
-	IsCommitteeMember bool `json:"isCommitteeMember"`
-	IsLeader          bool `json:"isLeader"`
-	Hashrate          int  `json:"hashrate"`
-	Peers             int  `json:"peers"`
-	GasPrice          int  `json:"gasPrice"`
-	Uptime            int  `json:"uptime"`
+	Active            bool   `json:"active"`
+	Syncing           bool   `json:"syncing"`
+	Mining            bool   `json:"mining"`
+	IsCommitteeMember bool   `json:"isCommitteeMember"`
+	IsLeader          bool   `json:"isLeader"`
+	CommitteeID       uint64 `json:"committeeId"`
+	CommitteeSize     int    `json:"committeeSize"`
+	SnailHeight       uint64 `json:"snailHeight"`
+	SnailSyncing      bool   `json:"snailSyncing"`
+	Hashrate          int    `json:"hashrate"`
+	Peers             int    `json:"peers"`
+	GasPrice          int    `json:"gasPrice"`
+	Uptime            int    `json:"uptime"`
 }
 
 // reportPending retrieves various stats about the node at the networking and
@@ -867,6 +905,10 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		mining            bool
 		isCommitteeMember bool
 		isLeader          bool
+		committeeID       uint64
+		committeeSize     int
+		snailHeight       uint64
+		snailSyncing      bool
 		hashrate          int
 		syncing           bool
 		gasprice          int
@@ -876,16 +918,21 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		hashrate = int(s.abey.Miner().HashRate())
 
 		sync := s.abey.Downloader().Progress()
-		syncing = s.abey.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestFastBlock
+		syncing = s.abey.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestFastBlock
 
 		price, _ := s.abey.APIBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
 
+		snailHeight = s.abey.SnailBlockChain().CurrentBlock().NumberU64()
+		snailSyncing = snailHeight < sync.HighestSnailBlock
+
 		isCommitteeMember = s.abey.PbftAgent().IsCommitteeMember()
 		isLeader = s.abey.PbftAgent().IsLeader()
+		committeeID = s.abey.PbftAgent().CommitteeNumber()
+		committeeSize = len(s.abey.PbftAgent().GetCurrentCommittee())
 	} else {
 		sync := s.les.Downloader().Progress()
-		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestFastBlock
+		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestFastBlock
 	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to abeystats")
@@ -899,6 +946,10 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		Uptime:            100,
 		IsCommitteeMember: isCommitteeMember,
 		IsLeader:          isLeader,
+		CommitteeID:       committeeID,
+		CommitteeSize:     committeeSize,
+		SnailHeight:       snailHeight,
+		SnailSyncing:      snailSyncing,
 	}
 	stats := map[string]interface{}{
 		"id":    s.node,
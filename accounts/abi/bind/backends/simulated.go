@@ -790,6 +790,12 @@ func (fb *filterBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*ty
 func (fb *filterBackend) SubscribeNewTxsEvent(ch chan<- types.NewTxsEvent) event.Subscription {
 	return nullSubscription()
 }
+func (fb *filterBackend) SubscribeDroppedTxsEvent(ch chan<- types.DroppedTxsEvent) event.Subscription {
+	return nullSubscription()
+}
+func (fb *filterBackend) SubscribeNewFruitEvent(ch chan<- types.NewFruitsEvent) event.Subscription {
+	return nullSubscription()
+}
 func (fb *filterBackend) SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription {
 	return fb.bc.SubscribeChainEvent(ch)
 }
@@ -0,0 +1,141 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	crand "crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/math"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+	"github.com/pborman/uuid"
+)
+
+var (
+	unlockTimer         = metrics.NewRegisteredTimer("accounts/keystore/unlock", nil)
+	derivedKeyHitMeter  = metrics.NewRegisteredMeter("accounts/keystore/cache/hit", nil)
+	derivedKeyMissMeter = metrics.NewRegisteredMeter("accounts/keystore/cache/miss", nil)
+)
+
+// derivedKeyCache keeps recently decrypted keys for a limited time, so that
+// repeated decryptions with the same passphrase skip the scrypt derivation.
+// The private keys are held in memory-locked buffers where the platform
+// supports it and are zeroed on expiry.
+type derivedKeyCache struct {
+	ttl  time.Duration
+	salt []byte
+
+	keys map[common.Address]*derivedKey
+	mu   sync.Mutex
+}
+
+type derivedKey struct {
+	id     uuid.UUID
+	auth   common.Hash // Salted hash of the passphrase that decrypted the key
+	secret []byte      // Private key bytes, memory-locked if possible
+	timer  *time.Timer
+}
+
+func newDerivedKeyCache(ttl time.Duration) *derivedKeyCache {
+	salt := make([]byte, 32)
+	if _, err := crand.Read(salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	return &derivedKeyCache{
+		ttl:  ttl,
+		salt: salt,
+		keys: make(map[common.Address]*derivedKey),
+	}
+}
+
+func (c *derivedKeyCache) authHash(auth string) common.Hash {
+	return crypto.Keccak256Hash(c.salt, []byte(auth))
+}
+
+// get returns a fresh copy of the cached key if the passphrase matches.
+func (c *derivedKeyCache) get(addr common.Address, auth string) *Key {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dk, ok := c.keys[addr]
+	if !ok || dk.auth != c.authHash(auth) {
+		derivedKeyMissMeter.Mark(1)
+		return nil
+	}
+	priv, err := crypto.ToECDSA(dk.secret)
+	if err != nil {
+		derivedKeyMissMeter.Mark(1)
+		return nil
+	}
+	derivedKeyHitMeter.Mark(1)
+	return &Key{Id: dk.id, Address: addr, PrivateKey: priv}
+}
+
+// put caches a copy of the key decrypted with the given passphrase.
+func (c *derivedKeyCache) put(key *Key, auth string) {
+	secret := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	if err := lockMemory(secret); err != nil {
+		log.Debug("Failed to lock derived key memory", "err", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.drop(key.Address)
+	dk := &derivedKey{id: key.Id, auth: c.authHash(auth), secret: secret}
+	dk.timer = time.AfterFunc(c.ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.keys[key.Address] == dk {
+			c.drop(key.Address)
+		}
+	})
+	c.keys[key.Address] = dk
+}
+
+// remove evicts the key of the given address, if cached.
+func (c *derivedKeyCache) remove(addr common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drop(addr)
+}
+
+// purge evicts all cached keys.
+func (c *derivedKeyCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr := range c.keys {
+		c.drop(addr)
+	}
+}
+
+// drop zeroes and evicts a cached key. It assumes the lock is held.
+func (c *derivedKeyCache) drop(addr common.Address) {
+	dk, ok := c.keys[addr]
+	if !ok {
+		return
+	}
+	dk.timer.Stop()
+	for i := range dk.secret {
+		dk.secret[i] = 0
+	}
+	unlockMemory(dk.secret)
+	delete(c.keys, addr)
+}
@@ -61,6 +61,7 @@ type KeyStore struct {
 	cache    *accountCache                // In-memory account cache over the filesystem storage
 	changes  chan struct{}                // Channel receiving change notifications from the cache
 	unlocked map[common.Address]*unlocked // Currently unlocked account (decrypted private keys)
+	derived  *derivedKeyCache             // Recently decrypted keys, skipping the KDF (nil = disabled)
 
 	wallets     []accounts.Wallet       // Wallet wrappers around the individual key files
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
@@ -92,6 +93,23 @@ func NewPlaintextKeyStore(keydir string) *KeyStore {
 	return ks
 }
 
+// SetKeyCacheTTL keeps decrypted keys cached for the given duration, so that
+// decrypting them again with the same passphrase skips the scrypt derivation.
+// A zero duration disables the cache. It should be called before the keystore
+// is used.
+func (ks *KeyStore) SetKeyCacheTTL(ttl time.Duration) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.derived != nil {
+		ks.derived.purge()
+		ks.derived = nil
+	}
+	if ttl > 0 {
+		ks.derived = newDerivedKeyCache(ttl)
+	}
+}
+
 func (ks *KeyStore) init(keydir string) {
 	// Lock the mutex since the account cache might call back with events
 	ks.mu.Lock()
@@ -241,6 +259,7 @@ func (ks *KeyStore) Delete(a accounts.Account, passphrase string) error {
 	if err != nil {
 		return err
 	}
+	ks.forgetDerivedKey(a.Address)
 	// The order is crucial here. The key is dropped from the
 	// cache after the file is gone so that a reload happening in
 	// between won't insert it into the cache again.
@@ -386,10 +405,33 @@ func (ks *KeyStore) getDecryptedKey(a accounts.Account, auth string) (accounts.A
 	if err != nil {
 		return a, nil, err
 	}
+	ks.mu.RLock()
+	derived := ks.derived
+	ks.mu.RUnlock()
+
+	if derived != nil {
+		if key := derived.get(a.Address, auth); key != nil {
+			return a, key, nil
+		}
+	}
+	start := time.Now()
 	key, err := ks.storage.GetKey(a.Address, a.URL.Path, auth)
+	unlockTimer.UpdateSince(start)
+	if err == nil && derived != nil {
+		derived.put(key, auth)
+	}
 	return a, key, err
 }
 
+// forgetDerivedKey drops the cached decrypted key of the given address.
+func (ks *KeyStore) forgetDerivedKey(addr common.Address) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if ks.derived != nil {
+		ks.derived.remove(addr)
+	}
+}
+
 func (ks *KeyStore) expire(addr common.Address, u *unlocked, timeout time.Duration) {
 	t := time.NewTimer(timeout)
 	defer t.Stop()
@@ -476,6 +518,7 @@ func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return err
 	}
+	ks.forgetDerivedKey(a.Address)
 	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
@@ -148,6 +148,58 @@ func TestTimedUnlock(t *testing.T) {
 	}
 }
 
+func TestKeyCacheTTL(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+	ks.SetKeyCacheTTL(100 * time.Millisecond)
+
+	pass := "foo"
+	a1, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := func() bool {
+		ks.derived.mu.Lock()
+		defer ks.derived.mu.Unlock()
+		_, ok := ks.derived.keys[a1.Address]
+		return ok
+	}
+	// Unlocking caches the decrypted key
+	if err := ks.TimedUnlock(a1, pass, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !cached() {
+		t.Fatal("decrypted key not cached after unlock")
+	}
+	ks.Lock(a1.Address)
+
+	// The cached key only serves the right passphrase
+	if err := ks.TimedUnlock(a1, "bar", 0); err != ErrDecrypt {
+		t.Fatalf("unlock with wrong passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := ks.SignHashWithPassphrase(a1, pass, testSigData); err != nil {
+		t.Fatal("Signing with cached key failed: ", err)
+	}
+	// Changing the passphrase drops the cached key
+	if err := ks.Update(a1, pass, "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if cached() {
+		t.Fatal("decrypted key still cached after passphrase change")
+	}
+	if err := ks.TimedUnlock(a1, pass, 0); err != ErrDecrypt {
+		t.Fatalf("unlock with old passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	// The cached key expires after the TTL
+	if err := ks.TimedUnlock(a1, "bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if cached() {
+		t.Fatal("decrypted key still cached after expiry")
+	}
+}
+
 func TestOverrideUnlock(t *testing.T) {
 	dir, ks := tmpKeyStore(t, false)
 	defer os.RemoveAll(dir)
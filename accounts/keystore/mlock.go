@@ -0,0 +1,31 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package keystore
+
+import "syscall"
+
+// lockMemory prevents the given buffer from being swapped to disk.
+func lockMemory(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+// unlockMemory releases a buffer locked by lockMemory.
+func unlockMemory(b []byte) {
+	syscall.Munlock(b)
+}
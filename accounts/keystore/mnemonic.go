@@ -0,0 +1,115 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/abeychain/go-abey/common/math"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	mnemonicKeyLen  = 32 // Length of the encrypted private key
+	mnemonicSaltLen = 16 // Length of the random scrypt salt
+	mnemonicMACLen  = 16 // Length of the truncated keccak256 MAC
+
+	// mnemonicWords is the number of words of an encrypted mnemonic: 24 words
+	// for the encrypted key followed by 24 words for the salt and the MAC.
+	mnemonicWords = 48
+)
+
+// ErrInvalidMnemonic is returned if a mnemonic has unknown words or a bad checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// EncryptKeyMnemonic encodes the private key as a 48 word mnemonic. The first 24
+// BIP39 words hold the key encrypted with the scrypt stream of the passphrase
+// and a random salt, the last 24 the salt and a MAC of the encrypted key. The
+// word checksums guard against transcription errors and the MAC against a
+// wrong passphrase.
+func EncryptKeyMnemonic(key *ecdsa.PrivateKey, auth string) (string, error) {
+	return encryptKeyMnemonic(key, auth, StandardScryptN, StandardScryptP)
+}
+
+// DecryptKeyMnemonic decodes a mnemonic created by EncryptKeyMnemonic, failing
+// with ErrDecrypt if the MAC doesn't match.
+func DecryptKeyMnemonic(mnemonic, auth string) (*ecdsa.PrivateKey, error) {
+	return decryptKeyMnemonic(mnemonic, auth, StandardScryptN, StandardScryptP)
+}
+
+func encryptKeyMnemonic(key *ecdsa.PrivateKey, auth string, scryptN, scryptP int) (string, error) {
+	salt := make([]byte, mnemonicSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey, err := scrypt.Key([]byte(auth), salt, scryptN, scryptR, scryptP, 2*mnemonicKeyLen)
+	if err != nil {
+		return "", err
+	}
+	cipherText := xorBytes(math.PaddedBigBytes(key.D, mnemonicKeyLen), derivedKey[:mnemonicKeyLen])
+	mac := crypto.Keccak256(derivedKey[mnemonicKeyLen:], cipherText)[:mnemonicMACLen]
+
+	keyWords, err := bip39.NewMnemonic(cipherText)
+	if err != nil {
+		return "", err
+	}
+	sealWords, err := bip39.NewMnemonic(append(salt, mac...))
+	if err != nil {
+		return "", err
+	}
+	return keyWords + " " + sealWords, nil
+}
+
+func decryptKeyMnemonic(mnemonic, auth string, scryptN, scryptP int) (*ecdsa.PrivateKey, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) != mnemonicWords {
+		return nil, ErrInvalidMnemonic
+	}
+	cipherText, err := bip39.EntropyFromMnemonic(strings.Join(words[:mnemonicWords/2], " "))
+	if err != nil || len(cipherText) != mnemonicKeyLen {
+		return nil, ErrInvalidMnemonic
+	}
+	seal, err := bip39.EntropyFromMnemonic(strings.Join(words[mnemonicWords/2:], " "))
+	if err != nil || len(seal) != mnemonicSaltLen+mnemonicMACLen {
+		return nil, ErrInvalidMnemonic
+	}
+	derivedKey, err := scrypt.Key([]byte(auth), seal[:mnemonicSaltLen], scryptN, scryptR, scryptP, 2*mnemonicKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[mnemonicKeyLen:], cipherText)[:mnemonicMACLen]
+	if !bytes.Equal(mac, seal[mnemonicSaltLen:]) {
+		return nil, ErrDecrypt
+	}
+	return crypto.ToECDSA(xorBytes(cipherText, derivedKey[:mnemonicKeyLen]))
+}
+
+// xorBytes returns the byte-wise xor of two slices of the same length.
+func xorBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
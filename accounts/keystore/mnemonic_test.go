@@ -0,0 +1,73 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abeychain/go-abey/crypto"
+)
+
+func TestKeyMnemonic(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	mnemonic, err := encryptKeyMnemonic(key, "foo", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := strings.Fields(mnemonic)
+	if len(words) != mnemonicWords {
+		t.Fatalf("mnemonic length mismatch: have %d, want %d", len(words), mnemonicWords)
+	}
+	dec, err := decryptKeyMnemonic(mnemonic, "foo", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := crypto.PubkeyToAddress(dec.PublicKey); have != addr {
+		t.Fatalf("address mismatch: have %x, want %x", have, addr)
+	}
+	// Every encryption uses a new salt
+	if again, _ := encryptKeyMnemonic(key, "foo", veryLightScryptN, veryLightScryptP); again == mnemonic {
+		t.Fatal("mnemonic encrypted twice with the same salt")
+	}
+	other, _ := encryptKeyMnemonic(key, "bar", veryLightScryptN, veryLightScryptP)
+	otherWords := strings.Fields(other)
+
+	swapped := append([]string{}, words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	tests := []struct {
+		name     string
+		mnemonic string
+		auth     string
+		err      error
+	}{
+		{"wrong passphrase", mnemonic, "bar", ErrDecrypt},
+		{"foreign key words", strings.Join(append(otherWords[:24:24], words[24:]...), " "), "foo", ErrDecrypt},
+		{"foreign seal words", strings.Join(append(words[:24:24], otherWords[24:]...), " "), "foo", ErrDecrypt},
+		{"swapped words", strings.Join(swapped, " "), "foo", ErrInvalidMnemonic},
+		{"missing seal", strings.Join(words[:24], " "), "foo", ErrInvalidMnemonic},
+		{"empty", "", "foo", ErrInvalidMnemonic},
+	}
+	for _, tt := range tests {
+		if _, err := decryptKeyMnemonic(tt.mnemonic, tt.auth, veryLightScryptN, veryLightScryptP); err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/consensus/election"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/params"
+)
+
+const (
+	electionBenchMiners   = 60 // Miners with enough fruits in the period to be candidates
+	electionBenchDefaults = 4  // Genesis committee members
+)
+
+// electionBenchChain is an election period of snail blocks and their fruits.
+type electionBenchChain struct {
+	headers []*types.SnailHeader
+	fruits  [][]*types.SnailHeader
+}
+
+func (c *electionBenchChain) GetFruitsHead(number uint64) []*types.SnailHeader {
+	if number < uint64(len(c.fruits)) {
+		return c.fruits[number]
+	}
+	return nil
+}
+
+func (c *electionBenchChain) GetHeaderByNumber(number uint64) *types.SnailHeader {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+// prepareCommitteeElect measures the election of a committee from a full period
+// of snail blocks, the fruits of every miner above the election threshold.
+func prepareCommitteeElect() (func() error, error) {
+	var (
+		period = params.ElectionPeriodNumber.Uint64()
+		fruits = int(params.ElectionFruitsThreshold) + 20
+		chain  = &electionBenchChain{
+			headers: make([]*types.SnailHeader, period+1),
+			fruits:  make([][]*types.SnailHeader, period+1),
+		}
+	)
+	for number := range chain.headers {
+		chain.headers[number] = &types.SnailHeader{Number: big.NewInt(int64(number)), Time: big.NewInt(snailBenchTime + 600*int64(number))}
+	}
+	// Spread the fruits of every miner evenly over the period
+	for miner := 0; miner < electionBenchMiners; miner++ {
+		key, err := benchKey("miner", miner)
+		if err != nil {
+			return nil, err
+		}
+		pubkey := crypto.FromECDSAPub(&key.PublicKey)
+		for i := 0; i < fruits; i++ {
+			number := 1 + uint64(miner*fruits+i)%period
+			fruit := &types.SnailHeader{
+				Coinbase:        crypto.PubkeyToAddress(key.PublicKey),
+				Publickey:       pubkey,
+				FastNumber:      big.NewInt(int64(miner*fruits + i + 1)),
+				FruitDifficulty: snailBenchConfig.Minerva.MinimumFruitDifficulty,
+				MixDigest:       crypto.Keccak256Hash(pubkey, big.NewInt(int64(i)).Bytes()),
+			}
+			chain.fruits[number] = append(chain.fruits[number], fruit)
+		}
+	}
+	defaults := make([]*types.CommitteeMember, electionBenchDefaults)
+	for i := range defaults {
+		key, err := benchKey("committee", i)
+		if err != nil {
+			return nil, err
+		}
+		defaults[i] = &types.CommitteeMember{
+			Coinbase:      crypto.PubkeyToAddress(key.PublicKey),
+			CommitteeBase: crypto.PubkeyToAddress(key.PublicKey),
+			Publickey:     crypto.FromECDSAPub(&key.PublicKey),
+			Flag:          types.StateUsedFlag,
+			MType:         types.TypeFixed,
+		}
+	}
+	begin, end := common.Big1, new(big.Int).SetUint64(period)
+
+	return func() error {
+		committee := election.ElectCommittee(chain, defaults, begin, end)
+		if len(committee.Members) != params.ProposalCommitteeNumber {
+			return fmt.Errorf("elected %d members, want %d", len(committee.Members), params.ProposalCommitteeNumber)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/core/vm"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/params"
+)
+
+const (
+	executeBenchSenders = 100 // Funded accounts sending the transfers
+	executeBenchTxs     = 400 // Transfers in the executed block
+)
+
+// executeBenchTransfers signs a block worth of value transfers from the given
+// senders to fresh accounts, in nonce order per sender.
+func executeBenchTransfers(config *params.ChainConfig, keys []*ecdsa.PrivateKey, count int) (types.Transactions, error) {
+	signer := types.MakeSigner(config, common.Big1)
+
+	txs := make(types.Transactions, 0, count)
+	for i := 0; i < count; i++ {
+		to := common.BytesToAddress(crypto.Keccak256([]byte(fmt.Sprintf("benchmark recipient %d", i))))
+		tx := types.NewTransaction(uint64(i/len(keys)), to, big.NewInt(1000), params.TxGas, big.NewInt(params.GWei), nil)
+		signed, err := types.SignTx(tx, signer, keys[i%len(keys)])
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, signed)
+	}
+	return txs, nil
+}
+
+// prepareBlockExecute measures the execution of a block of value transfers on
+// top of a committed state, the state root of the block included.
+func prepareBlockExecute() (func() error, error) {
+	var (
+		config = params.TestChainConfig
+		sdb    = state.NewDatabase(abeydb.NewMemDatabase())
+		keys   = make([]*ecdsa.PrivateKey, executeBenchSenders)
+	)
+	statedb, err := state.New(common.Hash{}, sdb)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i], err = benchKey("sender", i); err != nil {
+			return nil, err
+		}
+		statedb.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000)))
+	}
+	root, err := statedb.Commit(true)
+	if err != nil {
+		return nil, err
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		return nil, err
+	}
+	txs, err := executeBenchTransfers(config, keys, executeBenchTxs)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		Number:      common.Big1,
+		SnailNumber: common.Big0,
+		GasLimit:    params.GenesisGasLimit * 100,
+		Time:        big.NewInt(snailBenchTime),
+	}
+	// Every execution starts over from the committed state and must end up in
+	// the same one
+	var want common.Hash
+	return func() error {
+		statedb, err := state.New(root, sdb)
+		if err != nil {
+			return err
+		}
+		var (
+			gp      = new(core.GasPool).AddGas(header.GasLimit)
+			usedGas uint64
+			fees    = new(big.Int)
+		)
+		for i, tx := range txs {
+			statedb.Prepare(tx.Hash(), common.Hash{}, i)
+			if _, err := core.ApplyTransaction(config, nil, gp, statedb, header, tx, &usedGas, fees, vm.Config{}); err != nil {
+				return fmt.Errorf("transaction %d: %v", i, err)
+			}
+		}
+		have := statedb.IntermediateRoot(true)
+		if want == (common.Hash{}) {
+			want = have
+		} else if have != want {
+			return fmt.Errorf("state root mismatch: have %x, want %x", have, want)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+const (
+	ingestBenchBlocks = 64 // Blocks of a downloaded batch
+	ingestBenchTxs    = 50 // Transactions per block
+)
+
+// prepareDownloaderIngest measures the work done on a batch of blocks fetched
+// by the fast sync downloader once their bodies and receipts arrived: checking
+// them against the headers as the download queue does, then deriving the
+// receipt fields and storing the bodies, receipts and lookup entries as the
+// receipt chain insertion does.
+func prepareDownloaderIngest() (func() error, error) {
+	config := params.TestChainConfig
+
+	keys := make([]*ecdsa.PrivateKey, executeBenchSenders)
+	for i := range keys {
+		var err error
+		if keys[i], err = benchKey("sender", i); err != nil {
+			return nil, err
+		}
+	}
+	txs, err := executeBenchTransfers(config, keys, ingestBenchBlocks*ingestBenchTxs)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		blocks   = make([]*types.Block, ingestBenchBlocks)
+		receipts = make([]types.Receipts, ingestBenchBlocks)
+		parent   common.Hash
+	)
+	for i := range blocks {
+		body := txs[i*ingestBenchTxs : (i+1)*ingestBenchTxs]
+		for j, tx := range body {
+			receipt := types.NewReceipt(nil, false, uint64(j+1)*params.TxGas)
+			receipt.TxHash = tx.Hash()
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipts[i] = append(receipts[i], receipt)
+		}
+		header := &types.Header{
+			ParentHash:  parent,
+			Number:      big.NewInt(int64(i + 1)),
+			SnailNumber: common.Big0,
+			GasLimit:    params.GenesisGasLimit,
+			GasUsed:     uint64(len(body)) * params.TxGas,
+			Time:        big.NewInt(int64(snailBenchTime + 5*(i+1))),
+		}
+		blocks[i] = types.NewBlock(header, body, receipts[i], nil, nil)
+		parent = blocks[i].Hash()
+	}
+	return func() error {
+		db := abeydb.NewMemDatabase()
+		batch := db.NewBatch()
+		for i, block := range blocks {
+			header := block.Header()
+			if types.DeriveSha(block.Transactions()) != header.TxHash {
+				return fmt.Errorf("block %d: transaction root mismatch", header.Number)
+			}
+			if types.DeriveSha(receipts[i]) != header.ReceiptHash {
+				return fmt.Errorf("block %d: receipt root mismatch", header.Number)
+			}
+			if err := core.SetReceiptsData(config, block, receipts[i]); err != nil {
+				return err
+			}
+			rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
+			rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts[i])
+			rawdb.WriteTxLookupEntries(batch, block)
+
+			if batch.ValueSize() >= abeydb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if rawdb.ReadBody(db, parent, ingestBenchBlocks) == nil {
+			return errors.New("batch not stored")
+		}
+		return nil
+	}, nil
+}
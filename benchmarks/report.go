@@ -0,0 +1,117 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abeychain/go-abey/params"
+)
+
+// Report is the outcome of a run of the suite with the environment it was
+// taken in, as saved to compare later runs against.
+type Report struct {
+	Version   string    `json:"version"`
+	GoVersion string    `json:"goVersion"`
+	Platform  string    `json:"platform"`
+	CPUs      int       `json:"cpus"`
+	Date      time.Time `json:"date"`
+	Results   []*Result `json:"results"`
+}
+
+// NewReport creates a report of the results taken on this machine.
+func NewReport(results []*Result) *Report {
+	return &Report{
+		Version:   params.VersionWithMeta,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Date:      time.Now().UTC(),
+		Results:   results,
+	}
+}
+
+// LoadReport reads a report saved by Save.
+func LoadReport(file string) (*Report, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	report := new(Report)
+	if err := json.Unmarshal(blob, report); err != nil {
+		return nil, fmt.Errorf("invalid benchmark report %s: %v", file, err)
+	}
+	return report, nil
+}
+
+// Save writes the report as JSON.
+func (r *Report) Save(file string) error {
+	blob, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, blob, 0644)
+}
+
+// result returns the result of the named benchmark, nil if not run.
+func (r *Report) result(name string) *Result {
+	for _, result := range r.Results {
+		if result.Name == name {
+			return result
+		}
+	}
+	return nil
+}
+
+// Print writes the report as a table, with the change of the time per operation
+// against the baseline report if one is given.
+func (r *Report) Print(w io.Writer, baseline *Report) error {
+	fmt.Fprintf(w, "gabey %s, %s %s, %d CPUs, %s\n", r.Version, r.GoVersion, r.Platform, r.CPUs, r.Date.Format(time.RFC3339))
+	if baseline != nil {
+		fmt.Fprintf(w, "baseline gabey %s, %s %s, %d CPUs, %s\n", baseline.Version, baseline.GoVersion, baseline.Platform, baseline.CPUs, baseline.Date.Format(time.RFC3339))
+		if baseline.Platform != r.Platform || baseline.CPUs != r.CPUs {
+			fmt.Fprintln(w, "warning: the baseline was taken on a different machine, the changes are not comparable")
+		}
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	if baseline != nil {
+		fmt.Fprintln(tw, "benchmark\tops\tns/op\tallocs/op\tB/op\tbaseline ns/op\tchange\t")
+	} else {
+		fmt.Fprintln(tw, "benchmark\tops\tns/op\tallocs/op\tB/op\t")
+	}
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t", result.Name, result.Ops, result.NsPerOp, result.AllocsPerOp, result.BytesPerOp)
+		if baseline != nil {
+			if base := baseline.result(result.Name); base != nil && base.NsPerOp > 0 {
+				change := float64(result.NsPerOp-base.NsPerOp) / float64(base.NsPerOp) * 100
+				fmt.Fprintf(tw, "%d\t%+.1f%%\t", base.NsPerOp, change)
+			} else {
+				fmt.Fprint(tw, "-\t-\t")
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,282 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/consensus/minerva"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+const (
+	snailBenchLength     = 144 // Snail blocks preceding the verified ones, a full difficulty period
+	snailBenchCandidates = 16  // Distinct headers and fruits verified in turn
+	snailBenchTime       = 1546300800
+)
+
+var maxUint128 = new(big.Int).Lsh(common.Big1, 128)
+
+// snailBenchConfig is the chain configuration of the snail benchmarks. The
+// difficulties are lowered to the minimum so that the inputs can be mined while
+// preparing, verifying a seal costs the same regardless of the difficulty.
+var snailBenchConfig = func() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.Minerva = &params.MinervaConfig{
+		MinimumDifficulty:      big.NewInt(2),
+		MinimumFruitDifficulty: big.NewInt(2),
+		DurationLimit:          params.DurationLimit,
+	}
+	return &config
+}()
+
+// snailBenchChain is an in-memory snail chain and the fast chain its fruits
+// point into, serving the header lookups of the verification.
+type snailBenchChain struct {
+	snail  []*types.SnailHeader
+	fast   []*types.Header
+	hashes map[common.Hash]*types.SnailHeader
+}
+
+func (c *snailBenchChain) Config() *params.ChainConfig { return snailBenchConfig }
+
+func (c *snailBenchChain) CurrentHeader() *types.SnailHeader { return c.snail[len(c.snail)-1] }
+
+func (c *snailBenchChain) GetHeader(hash common.Hash, number uint64) *types.SnailHeader {
+	if header := c.hashes[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *snailBenchChain) GetHeaderByNumber(number uint64) *types.SnailHeader {
+	if number < uint64(len(c.snail)) {
+		return c.snail[number]
+	}
+	return nil
+}
+
+func (c *snailBenchChain) GetHeaderByHash(hash common.Hash) *types.SnailHeader { return c.hashes[hash] }
+
+func (c *snailBenchChain) GetBlock(hash common.Hash, number uint64) *types.SnailBlock { return nil }
+
+// snailBenchFastChain exposes the fast headers of the chain to the fruit
+// verification.
+type snailBenchFastChain struct{ *snailBenchChain }
+
+func (c snailBenchFastChain) CurrentHeader() *types.Header { return c.fast[len(c.fast)-1] }
+
+func (c snailBenchFastChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c snailBenchFastChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.fast)) {
+		return c.fast[number]
+	}
+	return nil
+}
+
+func (c snailBenchFastChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.fast {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c snailBenchFastChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+func (c snailBenchFastChain) GetBlockReward(snumber uint64) *types.BlockReward { return nil }
+
+// snailBench is the environment shared by the snail benchmarks, generated once
+// as the truehash dataset takes a while.
+type snailBench struct {
+	engine  *minerva.Minerva
+	dataset []uint64
+	chain   *snailBenchChain
+	headers []*types.SnailHeader // Children of the chain head, not part of the chain
+	fruits  []*types.SnailHeader // Fruits pointing into the chain
+}
+
+var (
+	snailBenchOnce sync.Once
+	snailBenchEnv  *snailBench
+	snailBenchErr  error
+)
+
+// newSnailBench returns the shared snail benchmark environment.
+func newSnailBench() (*snailBench, error) {
+	snailBenchOnce.Do(func() {
+		dataset := minerva.NewDataset(0).(*minerva.Dataset)
+		dataset.Generate(0, new([minerva.STARTUPDATENUM][]byte))
+
+		env := &snailBench{
+			engine:  minerva.New(minerva.Config{PowMode: minerva.ModeNormal}),
+			dataset: dataset.GetDataSet(),
+			chain:   &snailBenchChain{hashes: make(map[common.Hash]*types.SnailHeader)},
+		}
+		snailBenchErr = env.generate()
+		snailBenchEnv = env
+	})
+	return snailBenchEnv, snailBenchErr
+}
+
+// generate mines the snail chain, the candidate headers on top of it and the
+// fruits pointing into it.
+func (env *snailBench) generate() error {
+	chain := env.chain
+	for i := 0; i <= snailBenchLength; i++ {
+		chain.fast = append(chain.fast, &types.Header{
+			Number:      big.NewInt(int64(i)),
+			SnailNumber: common.Big0,
+			Time:        big.NewInt(int64(snailBenchTime + 5*i)),
+		})
+		if i > 0 {
+			chain.fast[i].ParentHash = chain.fast[i-1].Hash()
+		}
+	}
+	genesis := &types.SnailHeader{
+		Number:          common.Big0,
+		Time:            big.NewInt(snailBenchTime),
+		Difficulty:      snailBenchConfig.Minerva.MinimumDifficulty,
+		FruitDifficulty: snailBenchConfig.Minerva.MinimumFruitDifficulty,
+	}
+	chain.snail = append(chain.snail, genesis)
+	chain.hashes[genesis.Hash()] = genesis
+
+	for i := 1; i <= snailBenchLength; i++ {
+		header, err := env.mineHeader(chain.snail[i-1], 0)
+		if err != nil {
+			return err
+		}
+		chain.snail = append(chain.snail, header)
+		chain.hashes[header.Hash()] = header
+	}
+	head := chain.CurrentHeader()
+	for i := 0; i < snailBenchCandidates; i++ {
+		header, err := env.mineHeader(head, int64(i))
+		if err != nil {
+			return err
+		}
+		env.headers = append(env.headers, header)
+
+		fruit, err := env.mineFruit(head, chain.fast[i+1])
+		if err != nil {
+			return err
+		}
+		env.fruits = append(env.fruits, fruit)
+	}
+	return nil
+}
+
+// mineHeader creates a valid child of parent, offset in time to tell siblings
+// apart.
+func (env *snailBench) mineHeader(parent *types.SnailHeader, offset int64) (*types.SnailHeader, error) {
+	header := &types.SnailHeader{
+		ParentHash:      parent.Hash(),
+		Number:          new(big.Int).Add(parent.Number, common.Big1),
+		Time:            new(big.Int).Add(parent.Time, big.NewInt(params.DurationLimit.Int64()+offset)),
+		FruitDifficulty: snailBenchConfig.Minerva.MinimumFruitDifficulty,
+		PointerNumber:   common.Big0,
+		FastNumber:      common.Big0,
+	}
+	parents := minerva.GetParents(env.chain, header)
+	if parents == nil {
+		return nil, errors.New("missing snail parents")
+	}
+	header.Difficulty = minerva.CalcDifficulty(snailBenchConfig, header.Time.Uint64(), parents)
+
+	return header, env.seal(header, false)
+}
+
+// mineFruit creates a valid fruit of a fast block pointing at pointer.
+func (env *snailBench) mineFruit(pointer *types.SnailHeader, fast *types.Header) (*types.SnailHeader, error) {
+	fruit := &types.SnailHeader{
+		PointerHash:   pointer.Hash(),
+		PointerNumber: pointer.Number,
+		FastHash:      fast.Hash(),
+		FastNumber:    fast.Number,
+		Number:        new(big.Int).Add(pointer.Number, common.Big1),
+		Time:          new(big.Int).Add(fast.Time, common.Big1),
+		Difficulty:    pointer.Difficulty,
+	}
+	fruit.FruitDifficulty = minerva.CalcFruitDifficulty(snailBenchConfig, fruit.Time.Uint64(), fast.Time.Uint64(), pointer)
+
+	return fruit, env.seal(fruit, true)
+}
+
+// seal searches the nonce meeting the block or fruit difficulty of a header.
+func (env *snailBench) seal(header *types.SnailHeader, isFruit bool) error {
+	hash := header.HashNoNonce().Bytes()
+	for nonce := uint64(0); nonce < 1024; nonce++ {
+		digest, result := minerva.TruehashLight(env.dataset, hash, nonce)
+
+		var value, target *big.Int
+		if isFruit {
+			value, target = new(big.Int).SetBytes(result[16:]), new(big.Int).Div(maxUint128, header.FruitDifficulty)
+		} else {
+			value, target = new(big.Int).SetBytes(result[:16]), new(big.Int).Div(maxUint128, header.Difficulty)
+		}
+		if value.Cmp(target) <= 0 {
+			header.Nonce = types.EncodeNonce(nonce)
+			header.MixDigest = common.BytesToHash(digest)
+			return nil
+		}
+	}
+	return errors.New("no seal found")
+}
+
+// prepareSnailHeaderVerify measures the verification of a snail header, the
+// difficulty recalculated over a full period of parents and the seal checked.
+func prepareSnailHeaderVerify() (func() error, error) {
+	env, err := newSnailBench()
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+	return func() error {
+		header := env.headers[i%len(env.headers)]
+		i++
+		return env.engine.VerifySnailHeader(env.chain, nil, header, true, false)
+	}, nil
+}
+
+// prepareFruitVerify measures the verification of a fruit against its pointer
+// and fast block, the seal included.
+func prepareFruitVerify() (func() error, error) {
+	env, err := newSnailBench()
+	if err != nil {
+		return nil, err
+	}
+	fastchain := snailBenchFastChain{env.chain}
+
+	i := 0
+	return func() error {
+		fruit := env.fruits[i%len(env.fruits)]
+		i++
+		return env.engine.VerifySnailHeader(env.chain, fastchain, fruit, true, true)
+	}, nil
+}
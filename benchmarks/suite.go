@@ -0,0 +1,147 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package benchmarks contains a fixed suite of benchmarks of the consensus
+// critical paths, runnable with `go test -bench` and by the `gabey bench`
+// command. The inputs of every benchmark are generated deterministically, so
+// that the reports of two releases taken on the same machine are comparable.
+package benchmarks
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/abeychain/go-abey/crypto"
+)
+
+// Benchmark is a single benchmark of the suite.
+type Benchmark struct {
+	Name        string
+	Description string
+
+	// Prepare generates the inputs of the benchmark outside of the measurement,
+	// returning the operation measured. The operation must be repeatable any
+	// number of times and fails if the inputs are rejected.
+	Prepare func() (func() error, error)
+}
+
+// Suite is the list of benchmarks in the order they are reported. Names must
+// stay stable across releases for their reports to be comparable.
+var Suite = []Benchmark{
+	{"SnailHeaderVerify", "Verify a snail header including its truehash seal", prepareSnailHeaderVerify},
+	{"FruitVerify", "Verify a fruit including its truehash seal", prepareFruitVerify},
+	{"CommitteeElect", "Elect a committee from an election period of fruits", prepareCommitteeElect},
+	{"BlockExecute", "Execute a block of value transfers and hash the state", prepareBlockExecute},
+	{"TrieCommit", "Update and commit a state trie to the database", prepareTrieCommit},
+	{"DownloaderIngest", "Validate and store a batch of downloaded blocks and receipts", prepareDownloaderIngest},
+}
+
+// Result is the outcome of running a benchmark.
+type Result struct {
+	Name        string        `json:"name"`
+	Ops         int           `json:"ops"`
+	Elapsed     time.Duration `json:"elapsed"`
+	NsPerOp     int64         `json:"nsPerOp"`
+	AllocsPerOp int64         `json:"allocsPerOp"`
+	BytesPerOp  int64         `json:"bytesPerOp"`
+}
+
+// Select returns the benchmarks of the suite whose name matches the pattern,
+// all of them if it is empty.
+func Select(pattern string) ([]Benchmark, error) {
+	if pattern == "" {
+		return Suite, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var selected []Benchmark
+	for _, bench := range Suite {
+		if re.MatchString(bench.Name) {
+			selected = append(selected, bench)
+		}
+	}
+	return selected, nil
+}
+
+// Run measures a benchmark, repeating its operation with a growing count until
+// it ran for at least the given duration, the same way `go test -bench` does.
+func Run(bench Benchmark, duration time.Duration) (*Result, error) {
+	op, err := bench.Prepare()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", bench.Name, err)
+	}
+	n := 1
+	for {
+		result, err := measure(op, n)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", bench.Name, err)
+		}
+		if result.Elapsed >= duration || n >= 1e9 {
+			result.Name = bench.Name
+			return result, nil
+		}
+		// Predict the count reaching the duration, growing by 20% more to
+		// not fall just short of it, and at most a hundredfold per round
+		last := n
+		if ns := result.Elapsed.Nanoseconds(); ns > 0 {
+			n = int(duration.Nanoseconds() * int64(last) / ns)
+		} else {
+			n = 100 * last
+		}
+		n += n / 5
+		if n > 100*last {
+			n = 100 * last
+		}
+		if n <= last {
+			n = last + 1
+		}
+	}
+}
+
+// measure executes the operation n times, accounting its time and allocations.
+func measure(op func() error, n int) (*Result, error) {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if err := op(); err != nil {
+			return nil, err
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return &Result{
+		Ops:         n,
+		Elapsed:     elapsed,
+		NsPerOp:     elapsed.Nanoseconds() / int64(n),
+		AllocsPerOp: int64(after.Mallocs-before.Mallocs) / int64(n),
+		BytesPerOp:  int64(after.TotalAlloc-before.TotalAlloc) / int64(n),
+	}, nil
+}
+
+// benchKey derives the deterministic key of an account playing a role in the
+// generated inputs.
+func benchKey(role string, index int) (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("benchmark %s %d", role, index))))
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func BenchmarkSnailHeaderVerify(b *testing.B) { benchmarkSuite(b, "SnailHeaderVerify") }
+func BenchmarkFruitVerify(b *testing.B)       { benchmarkSuite(b, "FruitVerify") }
+func BenchmarkCommitteeElect(b *testing.B)    { benchmarkSuite(b, "CommitteeElect") }
+func BenchmarkBlockExecute(b *testing.B)      { benchmarkSuite(b, "BlockExecute") }
+func BenchmarkTrieCommit(b *testing.B)        { benchmarkSuite(b, "TrieCommit") }
+func BenchmarkDownloaderIngest(b *testing.B)  { benchmarkSuite(b, "DownloaderIngest") }
+
+func benchmarkSuite(b *testing.B, name string) {
+	benches, err := Select("^" + name + "$")
+	if err != nil || len(benches) != 1 {
+		b.Fatalf("benchmark %s not in the suite", name)
+	}
+	op, err := benches[0].Prepare()
+	if err != nil {
+		b.Fatalf("failed to prepare: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := op(); err != nil {
+			b.Fatalf("run %d failed: %v", i, err)
+		}
+	}
+}
+
+// Tests that the inputs of every benchmark are accepted, repeatedly.
+func TestSuite(t *testing.T) {
+	for _, bench := range Suite {
+		op, err := bench.Prepare()
+		if err != nil {
+			t.Errorf("%s: failed to prepare: %v", bench.Name, err)
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			if err := op(); err != nil {
+				t.Errorf("%s: run %d failed: %v", bench.Name, i, err)
+				break
+			}
+		}
+	}
+}
+
+// Tests that a report lists every result and its change against a baseline.
+func TestReportPrint(t *testing.T) {
+	bench := Benchmark{Name: "Sleep", Prepare: func() (func() error, error) {
+		return func() error { time.Sleep(100 * time.Microsecond); return nil }, nil
+	}}
+	result, err := Run(bench, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+	if result.Ops < 2 || result.Elapsed < 10*time.Millisecond {
+		t.Fatalf("run too short: %d ops in %v", result.Ops, result.Elapsed)
+	}
+	baseline := NewReport([]*Result{{Name: "Sleep", NsPerOp: 2 * result.NsPerOp}})
+	report := NewReport([]*Result{result, {Name: "Missing"}})
+
+	var out bytes.Buffer
+	if err := report.Print(&out, baseline); err != nil {
+		t.Fatalf("failed to print: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("report line count mismatch: have %d, want 6\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[4], "Sleep") || !strings.Contains(lines[4], "%") {
+		t.Errorf("missing change of the baselined result: %q", lines[4])
+	}
+	if !strings.Contains(lines[5], "Missing") || !strings.HasSuffix(strings.TrimSpace(lines[5]), "-") {
+		t.Errorf("missing placeholder of the unbaselined result: %q", lines[5])
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package benchmarks
+
+import (
+	"encoding/binary"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/trie"
+)
+
+const (
+	trieBenchEntries = 10000 // Entries of the committed trie
+	trieBenchUpdates = 1000  // Entries updated before every commit
+)
+
+// trieBenchValue is the value of an entry in a given version, sized like an
+// encoded account.
+func trieBenchValue(index int, version uint64) []byte {
+	value := make([]byte, 72)
+	binary.BigEndian.PutUint64(value, uint64(index))
+	binary.BigEndian.PutUint64(value[8:], version)
+	copy(value[16:], crypto.Keccak256(value[:16]))
+	return value
+}
+
+// prepareTrieCommit measures updating a share of a committed trie, hashing and
+// committing it and flushing the new nodes to the database, as done with the
+// state of every block.
+func prepareTrieCommit() (func() error, error) {
+	var (
+		diskdb = abeydb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		keys   = make([][]byte, trieBenchEntries)
+	)
+	tr, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i] = crypto.Keccak256(trieBenchValue(i, 0)[:8])
+		if err := tr.TryUpdate(keys[i], trieBenchValue(i, 0)); err != nil {
+			return nil, err
+		}
+	}
+	base, err := tr.Commit(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := triedb.Commit(base, false); err != nil {
+		return nil, err
+	}
+	// Alternate between two versions of the updated entries, so the database
+	// doesn't grow with the number of runs
+	var run uint64
+	return func() error {
+		run++
+		tr, err := trie.New(base, triedb)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < trieBenchUpdates; i++ {
+			index := (i * (trieBenchEntries / trieBenchUpdates)) % trieBenchEntries
+			if err := tr.TryUpdate(keys[index], trieBenchValue(index, 1+run%2)); err != nil {
+				return err
+			}
+		}
+		root, err := tr.Commit(nil)
+		if err != nil {
+			return err
+		}
+		return triedb.Commit(root, false)
+	}, nil
+}
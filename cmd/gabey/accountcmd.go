@@ -1,19 +1,44 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
 
-	"github.com/abeychain/go-abey/crypto"
-	"github.com/abeychain/go-abey/log"
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/accounts/keystore"
 	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/console"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
 	"gopkg.in/urfave/cli.v1"
 )
 
+// Supported key formats of account import and export.
+const (
+	keyFormatHex      = "hex"
+	keyFormatKeystore = "keystore"
+	keyFormatMnemonic = "mnemonic"
+)
+
 var (
+	keyFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: `Key file format ("hex", "keystore" or "mnemonic")`,
+		Value: keyFormatHex,
+	}
+	dryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Print the address of the key without importing it",
+	}
+	expectAddressFlag = cli.StringFlag{
+		Name:  "address",
+		Usage: "Address the imported key must belong to",
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage abeychain presale wallets",
@@ -66,7 +91,8 @@ safe environments.
 Make sure you remember the password you gave when creating a new account (with
 either new or import). Without it you are not able to unlock your account.
 
-Note that exporting your key in unencrypted format is NOT supported.
+Keys can be imported from and exported to raw hex, web3 keystore and encrypted
+mnemonic files.
 
 Keys are stored under <DATADIR>/keystore.
 It is safe to transfer the entire directory or the individual keys therein
@@ -148,15 +174,26 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					keyFormatFlag,
+					expectAddressFlag,
+					dryRunFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
-	gabey account import <keyfile>
+	gabey account import [--format hex|keystore|mnemonic] <keyfile>
 
-Imports an unencrypted private key from <keyfile> and creates a new account.
+Imports a private key from <keyfile> and creates a new account.
 Prints the address.
 
-The keyfile is assumed to contain an unencrypted private key in hexadecimal format.
+By default the keyfile is assumed to contain an unencrypted private key in
+hexadecimal format. With --format keystore it is read as a web3 keystore file
+and with --format mnemonic as an encrypted mnemonic written by account export;
+both prompt for the passphrase of the keyfile first.
+
+The key is checked before import: hex keys must be valid secp256k1 keys,
+keystore files and mnemonics must pass their MAC, and mnemonics their word
+checksums. The --address flag additionally requires the key to belong to the
+given address. With --dry-run the address is printed and nothing is imported.
 
 The account is saved in encrypted format, you are prompted for a passphrase.
 
@@ -170,6 +207,30 @@ Note:
 As you can directly copy your encrypted accounts to another abeychain instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "export",
+				Usage:  "Export the private key of an account",
+				Action: utils.MigrateFlags(accountExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					keyFormatFlag,
+				},
+				ArgsUsage: "<address> <keyFile>",
+				Description: `
+	gabey account export [--format hex|keystore|mnemonic] <address> <keyfile>
+
+Exports the private key of an existing account into the new file <keyfile>.
+
+You are prompted for the passphrase of the account. The keystore and mnemonic
+formats are encrypted with a new passphrase you are prompted for next, the hex
+format writes the private key unencrypted.
+
+The written file is read back and checked to decode to the exported address.
 `,
 			},
 		},
@@ -346,12 +407,31 @@ func accountImport(ctx *cli.Context) error {
 	if len(keyfile) == 0 {
 		utils.Fatalf("keyfile must be given as argument")
 	}
-	key, err := crypto.LoadECDSA(keyfile)
+	passwords := utils.MakePasswordList(ctx)
+	key, err := loadKeyFile(keyfile, ctx.String(keyFormatFlag.Name), passwords)
 	if err != nil {
 		utils.Fatalf("Failed to load the private key: %v", err)
 	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	if expect := ctx.String(expectAddressFlag.Name); expect != "" {
+		if !common.IsHexAddress(expect) {
+			utils.Fatalf("Invalid expected address %q", expect)
+		}
+		if common.HexToAddress(expect) != address {
+			utils.Fatalf("Key belongs to address {%x}, not %s", address, expect)
+		}
+	}
+	if ctx.Bool(dryRunFlag.Name) {
+		fmt.Printf("Address: {%x}\n", address)
+		return nil
+	}
 	stack, _ := makeConfigNode(ctx)
-	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+	// Encrypted key files consumed the first password
+	index := 0
+	if ctx.String(keyFormatFlag.Name) != keyFormatHex {
+		index = 1
+	}
+	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, index, passwords)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	acct, err := ks.ImportECDSA(key, passphrase)
@@ -361,3 +441,93 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// loadKeyFile reads a private key from a file in the given format.
+func loadKeyFile(file, format string, passwords []string) (*ecdsa.PrivateKey, error) {
+	switch format {
+	case keyFormatHex:
+		return crypto.LoadECDSA(file)
+	case keyFormatKeystore:
+		keyJSON, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase := getPassPhrase("Please give the password of the keystore file.", false, 0, passwords)
+		key, err := keystore.DecryptKey(keyJSON, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return key.PrivateKey, nil
+	case keyFormatMnemonic:
+		mnemonic, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase := getPassPhrase("Please give the password of the mnemonic.", false, 0, passwords)
+		return keystore.DecryptKeyMnemonic(strings.Join(strings.Fields(string(mnemonic)), " "), passphrase)
+	}
+	return nil, fmt.Errorf("unknown key format %q", format)
+}
+
+// accountExport writes the private key of an account into a new file.
+func accountExport(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires two arguments.")
+	}
+	addr, keyfile := ctx.Args().Get(0), ctx.Args().Get(1)
+	format := ctx.String(keyFormatFlag.Name)
+	if format != keyFormatHex && format != keyFormatKeystore && format != keyFormatMnemonic {
+		utils.Fatalf("Unknown key format %q", format)
+	}
+	if _, err := os.Stat(keyfile); err == nil {
+		utils.Fatalf("Key file %s already exists", keyfile)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	passwords := utils.MakePasswordList(ctx)
+	account, password := unlockAccount(ctx, ks, addr, 0, passwords)
+	account, err := ks.Find(account)
+	if err != nil {
+		utils.Fatalf("Could not find the account: %v", err)
+	}
+	keyJSON, err := ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		utils.Fatalf("Could not read the key file: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, password)
+	if err != nil {
+		utils.Fatalf("Could not decrypt the key: %v", err)
+	}
+	var (
+		content    []byte
+		passphrase string
+	)
+	switch format {
+	case keyFormatHex:
+		content = []byte(common.Bytes2Hex(crypto.FromECDSA(key.PrivateKey)))
+	case keyFormatKeystore:
+		passphrase = getPassPhrase("Please give a password for the exported keystore file.", true, 1, passwords)
+		if content, err = ks.Export(account, password, passphrase); err != nil {
+			utils.Fatalf("Could not export the key: %v", err)
+		}
+	case keyFormatMnemonic:
+		passphrase = getPassPhrase("Please give a password for the exported mnemonic.", true, 1, passwords)
+		mnemonic, err := keystore.EncryptKeyMnemonic(key.PrivateKey, passphrase)
+		if err != nil {
+			utils.Fatalf("Could not export the key: %v", err)
+		}
+		content = []byte(mnemonic + "\n")
+	}
+	if err := ioutil.WriteFile(keyfile, content, 0600); err != nil {
+		utils.Fatalf("Could not write the key file: %v", err)
+	}
+	// Read the key back to make sure it was written intact
+	written, err := loadKeyFile(keyfile, format, []string{passphrase})
+	if err != nil || crypto.PubkeyToAddress(written.PublicKey) != account.Address {
+		os.Remove(keyfile)
+		utils.Fatalf("Exported key file failed verification: %v", err)
+	}
+	fmt.Printf("Address: {%x}\n", account.Address)
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abeychain/go-abey/benchmarks"
+	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	benchCommandFilterFlag = cli.StringFlag{
+		Name:  "filter",
+		Usage: "Regular expression selecting the benchmarks to run",
+	}
+	benchCommandTimeFlag = cli.DurationFlag{
+		Name:  "benchtime",
+		Value: time.Second,
+		Usage: "Minimum time to run each benchmark for",
+	}
+	benchCommandOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to save the report to as JSON",
+	}
+	benchCommandBaselineFlag = cli.StringFlag{
+		Name:  "baseline",
+		Usage: "Report of an earlier run to compare against",
+	}
+	benchCommand = cli.Command{
+		Action:    utils.MigrateFlags(bench),
+		Name:      "bench",
+		Usage:     "Benchmark the consensus critical paths",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The bench command runs a fixed suite of benchmarks of the consensus critical
+paths on generated inputs: snail header and fruit verification, committee
+election, block execution, trie commits and the ingestion of downloaded blocks.
+The same suite is runnable with 'go test -bench . ./benchmarks'.
+
+Save the report of a release with --out and pass it as --baseline to a later
+one on the same machine to measure the change of every benchmark.`,
+		Flags: []cli.Flag{
+			benchCommandFilterFlag,
+			benchCommandTimeFlag,
+			benchCommandOutFlag,
+			benchCommandBaselineFlag,
+		},
+	}
+)
+
+// bench runs the selected benchmarks of the suite and prints their report.
+func bench(ctx *cli.Context) error {
+	selected, err := benchmarks.Select(ctx.String(benchCommandFilterFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid benchmark filter: %v", err)
+	}
+	if len(selected) == 0 {
+		utils.Fatalf("No benchmark matches the filter")
+	}
+	var baseline *benchmarks.Report
+	if file := ctx.String(benchCommandBaselineFlag.Name); file != "" {
+		if baseline, err = benchmarks.LoadReport(file); err != nil {
+			utils.Fatalf("Failed to load baseline: %v", err)
+		}
+	}
+	// The benchmarked code logs as if running in a node, keep the report readable
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlWarn, log.Root().GetHandler()))
+
+	duration := ctx.Duration(benchCommandTimeFlag.Name)
+	results := make([]*benchmarks.Result, 0, len(selected))
+	for _, b := range selected {
+		fmt.Fprintf(os.Stderr, "Running %-20s %s\n", b.Name, b.Description)
+		result, err := benchmarks.Run(b, duration)
+		if err != nil {
+			utils.Fatalf("Benchmark failed: %v", err)
+		}
+		results = append(results, result)
+	}
+	report := benchmarks.NewReport(results)
+	if file := ctx.String(benchCommandOutFlag.Name); file != "" {
+		if err := report.Save(file); err != nil {
+			utils.Fatalf("Failed to save report: %v", err)
+		}
+	}
+	fmt.Println()
+	return report.Print(os.Stdout, baseline)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -13,13 +16,15 @@ import (
 	"github.com/abeychain/go-abey/log"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/consensus/election"
 	"github.com/abeychain/go-abey/console"
 	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/rawdb"
 	"github.com/abeychain/go-abey/core/snailchain"
-	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/abey/downloader"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/rlp"
 	"github.com/abeychain/go-abey/trie"
 	"gopkg.in/urfave/cli.v1"
 
@@ -144,8 +149,8 @@ Remove blockchain and state databases`,
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
 		Name:      "dump",
-		Usage:     "Dump a specific block from storage",
-		ArgsUsage: "[<blockHash> | <blockNum>]...",
+		Usage:     "Dump the state at a fast block into file",
+		ArgsUsage: "<filename> [<blockHash> | <blockNum>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
 			utils.CacheFlag,
@@ -153,8 +158,83 @@ Remove blockchain and state databases`,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-The arguments are interpreted as block numbers or hashes.
-Use "abeychain dump 0" to dump the genesis block.`,
+The dump command writes the accounts, code and storage of the state at a fast
+block, the head if none is given, into an RLP stream ordered by the hashed
+addresses, gzipped if the file name ends with .gz. Dumps of the same state are
+identical. Use "abey restore-state" to start a new network from it.`,
+	}
+	restoreStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(restoreState),
+		Name:      "restore-state",
+		Usage:     "Bootstrap a new genesis block on top of a state dump",
+		ArgsUsage: "<genesisPath> <filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The restore-state command rebuilds the state of a dump in a fresh database and
+writes the genesis block of the given definition on top of it, replacing its
+alloc. The accounts, balances, code and storage of the dumped network, including
+the staking, are preserved while its history is dropped.`,
+	}
+	exportElectionCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportElection),
+		Name:      "export-election",
+		Usage:     "Export the inputs of a committee election into file",
+		ArgsUsage: "<committeeId> <filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-election command writes the snail headers and fruit headers of the
+election window of a committee into an RLP encoded file, gzipped if the file
+name ends with .gz. The file can be checked with verify-election. Committees
+chosen by the staking contract since TIP8 have no election to export.`,
+	}
+	dbSchemaCommand = cli.Command{
+		Action:    utils.MigrateFlags(dbSchema),
+		Name:      "db-schema",
+		Usage:     "Print the key layout of the chain database",
+		ArgsUsage: " ",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The db-schema command prints all key families stored in the chain database as a
+markdown table, sorted by key prefix.`,
+	}
+	inspectDBCommand = cli.Command{
+		Action:    utils.MigrateFlags(inspectDB),
+		Name:      "inspect-db",
+		Usage:     "Report the disk usage of the chain database by key family",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The inspect-db command scans the whole chain database, counting the entries and
+their size by key family (see db-schema). Entries matching no known key family
+are reported with a sample of their keys.`,
+	}
+	verifyElectionCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyElection),
+		Name:      "verify-election",
+		Usage:     "Re-run a committee election from file and compare it with the chain",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The verify-election command checks the file written by export-election, elects
+the committee again from it and compares the result with the switch infos of
+the committee's switch block in the local chain. Committees chosen by the
+staking contract since TIP8 are rejected.`,
 	}
 )
 
@@ -480,28 +560,76 @@ func removeDB(ctx *cli.Context) error {
 }
 
 func dump(ctx *cli.Context) error {
-	stack := makeFullNode(ctx)
-	_, schain, chainDb := utils.MakeChain(ctx, stack)
-	for _, arg := range ctx.Args() {
-		var block *types.SnailBlock
-		if hashish(arg) {
-			block = schain.GetBlockByHash(common.HexToHash(arg))
-		} else {
-			num, _ := strconv.Atoi(arg)
-			block = schain.GetBlockByNumber(uint64(num))
-		}
-		if block == nil {
-			fmt.Println("{}")
-			utils.Fatalf("block not found")
-		} else {
-			//state, err := state.New(block.Root(), state.NewDatabase(chainDb))
-			//if err != nil {
-			//	utils.Fatalf("could not create new state: %v", err)
-			//}
-			//fmt.Printf("%s\n", state.Dump())
+	if len(ctx.Args()) < 1 || len(ctx.Args()) > 2 {
+		utils.Fatalf("This command requires the file name and an optional block.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	var hash common.Hash
+	switch arg := ctx.Args().Get(1); {
+	case arg == "":
+		hash = rawdb.ReadHeadBlockHash(chainDb)
+	case hashish(arg):
+		hash = common.HexToHash(arg)
+	default:
+		num, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid block number %q: %v", arg, err)
 		}
+		hash = rawdb.ReadCanonicalHash(chainDb, num)
+	}
+	number := rawdb.ReadHeaderNumber(chainDb, hash)
+	if number == nil {
+		utils.Fatalf("Block not found")
+	}
+	header := rawdb.ReadHeader(chainDb, hash, *number)
+	if header == nil {
+		utils.Fatalf("Block not found")
+	}
+	start := time.Now()
+	if err := utils.ExportState(chainDb, header, ctx.Args().First()); err != nil {
+		utils.Fatalf("Dump error: %v", err)
+	}
+	fmt.Printf("Dump done in %v\n", time.Since(start))
+	return nil
+}
+
+// restoreState rebuilds a dumped state in a fresh database and writes a new
+// genesis block on top of it.
+func restoreState(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires the genesis file and the dump file.")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	if stored := rawdb.ReadCanonicalHash(chainDb, 0); stored != (common.Hash{}) {
+		utils.Fatalf("Database already initialized with genesis %x", stored)
 	}
-	chainDb.Close()
+	start := time.Now()
+	header, err := utils.ImportState(chainDb, ctx.Args().Get(1))
+	if err != nil {
+		utils.Fatalf("Restore error: %v", err)
+	}
+	fastBlock, snailBlock, err := genesis.CommitRegenesis(chainDb, header.Root)
+	if err != nil {
+		utils.Fatalf("Failed to write genesis block: %v", err)
+	}
+	log.Info("Successfully wrote genesis on restored state", "from", header.Number, "root", header.Root, "fastHash", fastBlock.Hash(), "snail", snailBlock.Hash())
+	fmt.Printf("Restore done in %v\n", time.Since(start))
 	return nil
 }
 
@@ -510,3 +638,128 @@ func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
 	return err != nil
 }
+
+// dbSchema prints the key families of the chain database.
+func dbSchema(ctx *cli.Context) error {
+	return abeydb.WriteSchemaDoc(os.Stdout, rawdb.DatabaseSchema())
+}
+
+// inspectDB scans the chain database, reporting its usage by key family.
+func inspectDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	it, ok := db.(abeydb.Iteratee)
+	if !ok {
+		utils.Fatalf("Database can't be iterated")
+	}
+	start := time.Now()
+	result := abeydb.Inspect(it, rawdb.DatabaseSchema())
+
+	var (
+		total common.StorageSize
+		count uint64
+	)
+	fmt.Printf("%-32s %12s %12s\n", "Key family", "Entries", "Size")
+	for _, stats := range append(result.Known, &result.Unknown) {
+		if stats.Count == 0 {
+			continue
+		}
+		fmt.Printf("%-32s %12d %12s\n", stats.Name, stats.Count, stats.Size.String())
+		total += stats.Size
+		count += stats.Count
+	}
+	fmt.Printf("%-32s %12d %12s\n", "total", count, total.String())
+
+	for _, key := range result.UnknownSamples {
+		fmt.Println("Unknown key:", key)
+	}
+	log.Info("Inspected database", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportElection writes the election inputs of a committee into a file.
+func exportElection(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires a committee id and a file name.")
+	}
+	id, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid committee id: %v", err)
+	}
+	stack, cfg := makeConfigNode(ctx)
+	fchain, schain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	snap, err := election.NewElection(fchain.Config(), fchain, schain, &cfg.Abey).ExportElection(id)
+	if err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	fn := ctx.Args().Get(1)
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := rlp.Encode(writer, snap); err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	fmt.Printf("Exported election of committee %d (snail %d-%d, switch block %d)\n", snap.Committee, snap.Begin, snap.End, snap.SwitchNumber)
+	return nil
+}
+
+// verifyElection re-runs a committee election from file and compares it with
+// the switch infos in the local chain.
+func verifyElection(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	fn := ctx.Args().First()
+	fh, err := os.Open(fn)
+	if err != nil {
+		utils.Fatalf("Verify error: %v", err)
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			utils.Fatalf("Verify error: %v", err)
+		}
+	}
+	snap := new(election.ElectionSnapshot)
+	if err := rlp.Decode(reader, snap); err != nil {
+		utils.Fatalf("Verify error: %v", err)
+	}
+
+	stack, cfg := makeConfigNode(ctx)
+	fchain, schain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	committee, err := election.NewElection(fchain.Config(), fchain, schain, &cfg.Abey).VerifyElection(snap)
+	if err == election.ErrStakedCommittee {
+		utils.Fatalf("Committee %d is chosen by the staking contract, there is no election to verify", snap.Committee)
+	}
+	if err != nil {
+		utils.Fatalf("Invalid election file: %v", err)
+	}
+	if header := schain.GetHeaderByNumber(snap.End); header == nil || header.Hash() != snap.Headers[len(snap.Headers)-1].Hash() {
+		utils.Fatalf("Election window does not match the local snail chain")
+	}
+	block := fchain.GetBlockByNumber(snap.SwitchNumber)
+	if block == nil {
+		utils.Fatalf("Switch block %d not found", snap.SwitchNumber)
+	}
+	if err := election.CompareSwitchInfos(committee, block.SwitchInfos()); err != nil {
+		utils.Fatalf("Committee %d verification failed: %v", snap.Committee, err)
+	}
+	fmt.Printf("Committee %d verified: %d members, %d backups\n", snap.Committee, len(committee.Members), len(committee.Backups))
+	return nil
+}
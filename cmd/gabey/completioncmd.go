@@ -0,0 +1,306 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/abeychain/go-abey/cmd/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	helpJSONFlag = cli.BoolFlag{
+		Name:  "help-json",
+		Usage: "Print the commands and options with their defaults as JSON and exit",
+	}
+
+	completionCommand = cli.Command{
+		Action:    completion,
+		Name:      "completion",
+		Usage:     "Print a shell completion script",
+		ArgsUsage: "<bash|zsh|fish>",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The completion command prints a completion script for the given shell, covering
+all the commands and options of this release. For example:
+
+    source <(abey completion bash)
+    abey completion fish > ~/.config/fish/completions/abey.fish`,
+	}
+)
+
+// flagHelp describes a command line option in the --help-json output.
+type flagHelp struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Type     string   `json:"type"`
+	Usage    string   `json:"usage"`
+	Default  string   `json:"default,omitempty"`
+	EnvVar   string   `json:"envVar,omitempty"`
+	Category string   `json:"category,omitempty"`
+}
+
+// commandHelp describes a command in the --help-json output.
+type commandHelp struct {
+	Name        string         `json:"name"`
+	Aliases     []string       `json:"aliases,omitempty"`
+	Usage       string         `json:"usage"`
+	ArgsUsage   string         `json:"argsUsage,omitempty"`
+	Category    string         `json:"category,omitempty"`
+	Flags       []*flagHelp    `json:"flags,omitempty"`
+	Subcommands []*commandHelp `json:"subcommands,omitempty"`
+}
+
+// appHelp is the --help-json output.
+type appHelp struct {
+	Name     string         `json:"name"`
+	Version  string         `json:"version"`
+	Usage    string         `json:"usage"`
+	Flags    []*flagHelp    `json:"flags"`
+	Commands []*commandHelp `json:"commands"`
+}
+
+// flagNames splits the name of a flag into its name and aliases.
+func flagNames(flag cli.Flag) []string {
+	var names []string
+	for _, name := range strings.Split(flag.GetName(), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// flagHidden reports whether a flag is left out of the help.
+func flagHidden(flag cli.Flag) bool {
+	v := reflect.Indirect(reflect.ValueOf(flag))
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	hidden := v.FieldByName("Hidden")
+	return hidden.IsValid() && hidden.Kind() == reflect.Bool && hidden.Bool()
+}
+
+// describeFlag inspects a flag for the --help-json output. The flag types of
+// cli and of cmd/utils share the Usage, Value and EnvVar fields by convention.
+func describeFlag(flag cli.Flag, category string) *flagHelp {
+	names := flagNames(flag)
+	help := &flagHelp{Name: names[0], Aliases: names[1:], Category: category}
+
+	v := reflect.Indirect(reflect.ValueOf(flag))
+	help.Type = strings.TrimSuffix(v.Type().Name(), "Flag")
+	if v.Kind() != reflect.Struct {
+		return help
+	}
+	if usage := v.FieldByName("Usage"); usage.IsValid() && usage.Kind() == reflect.String {
+		help.Usage = usage.String()
+	}
+	if env := v.FieldByName("EnvVar"); env.IsValid() && env.Kind() == reflect.String {
+		help.EnvVar = env.String()
+	}
+	if value := v.FieldByName("Value"); value.IsValid() {
+		if !(value.Kind() == reflect.Ptr && value.IsNil()) && !(value.Kind() == reflect.Bool && !value.Bool()) {
+			help.Default = fmt.Sprint(value.Interface())
+		}
+	}
+	return help
+}
+
+// describeCommand inspects a command and its subcommands for the --help-json
+// output, skipping the hidden ones.
+func describeCommand(cmd cli.Command) *commandHelp {
+	help := &commandHelp{
+		Name:      cmd.Name,
+		Aliases:   cmd.Aliases,
+		Usage:     cmd.Usage,
+		ArgsUsage: strings.TrimSpace(cmd.ArgsUsage),
+		Category:  cmd.Category,
+	}
+	for _, flag := range cmd.Flags {
+		if !flagHidden(flag) {
+			help.Flags = append(help.Flags, describeFlag(flag, flagCategory(flag)))
+		}
+	}
+	for _, sub := range cmd.Subcommands {
+		if !sub.Hidden {
+			help.Subcommands = append(help.Subcommands, describeCommand(sub))
+		}
+	}
+	return help
+}
+
+// writeHelpJSON writes the machine readable description of the app.
+func writeHelpJSON(w io.Writer, app *cli.App) error {
+	help := &appHelp{
+		Name:    app.Name,
+		Version: app.Version,
+		Usage:   app.Usage,
+	}
+	for _, flag := range app.Flags {
+		if !flagHidden(flag) {
+			help.Flags = append(help.Flags, describeFlag(flag, flagCategory(flag)))
+		}
+	}
+	for _, cmd := range app.VisibleCommands() {
+		help.Commands = append(help.Commands, describeCommand(cmd))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(help)
+}
+
+// completionWords collects the visible flag names of a flag list.
+func completionWords(flags []cli.Flag) []string {
+	var words []string
+	for _, flag := range flags {
+		if flagHidden(flag) {
+			continue
+		}
+		for _, name := range flagNames(flag) {
+			if len(name) == 1 {
+				words = append(words, "-"+name)
+			} else {
+				words = append(words, "--"+name)
+			}
+		}
+	}
+	return words
+}
+
+// completionPaths flattens the command tree into the words completed after
+// every command path, the root being the empty path.
+func completionPaths(prefix string, cmds []cli.Command, flags []cli.Flag, paths map[string][]string, order *[]string) {
+	words := completionWords(flags)
+	for _, cmd := range cmds {
+		if !cmd.Hidden {
+			words = append(words, cmd.Names()...)
+		}
+	}
+	paths[prefix] = words
+	*order = append(*order, prefix)
+
+	for _, cmd := range cmds {
+		if cmd.Hidden {
+			continue
+		}
+		path := strings.TrimSpace(prefix + " " + cmd.Name)
+		completionPaths(path, cmd.Subcommands, cmd.Flags, paths, order)
+	}
+}
+
+// writeBashCompletion writes a bash completion script, which zsh also loads
+// through bashcompinit.
+func writeBashCompletion(w io.Writer, app *cli.App) {
+	var (
+		paths = make(map[string][]string)
+		order []string
+	)
+	completionPaths("", app.VisibleCommands(), app.Flags, paths, &order)
+
+	fn := "_" + strings.Replace(app.Name, "-", "_", -1) + "_complete"
+	fmt.Fprintf(w, "# bash completion for %s %s\n", app.Name, app.Version)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, `	local cur="${COMP_WORDS[COMP_CWORD]}" path="" word next opts`)
+	fmt.Fprintf(w, "\tlocal paths=\":%s:\"\n", strings.Join(order[1:], ":"))
+	fmt.Fprintln(w, `	for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do`)
+	fmt.Fprintln(w, `		next="${path:+$path }$word"`)
+	fmt.Fprintln(w, `		case "$paths" in *":$next:"*) path="$next" ;; esac`)
+	fmt.Fprintln(w, `	done`)
+	fmt.Fprintln(w, `	case "$path" in`)
+	for _, path := range order {
+		fmt.Fprintf(w, "\t%q) opts=%q ;;\n", path, strings.Join(paths[path], " "))
+	}
+	fmt.Fprintln(w, `	esac`)
+	fmt.Fprintln(w, `	COMPREPLY=($(compgen -W "$opts" -- "$cur"))`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "complete -o default -F %s %s\n", fn, app.Name)
+}
+
+// writeZshCompletion writes the bash completion script wrapped for zsh.
+func writeZshCompletion(w io.Writer, app *cli.App) {
+	fmt.Fprintf(w, "#compdef %s\n", app.Name)
+	fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+	writeBashCompletion(w, app)
+}
+
+// writeFishCompletion writes a fish completion script.
+func writeFishCompletion(w io.Writer, app *cli.App) {
+	fmt.Fprintf(w, "# fish completion for %s %s\n", app.Name, app.Version)
+	fmt.Fprintf(w, "complete -c %s -f\n", app.Name)
+
+	var walk func(parents []string, cmds []cli.Command, flags []cli.Flag)
+	walk = func(parents []string, cmds []cli.Command, flags []cli.Flag) {
+		// Restrict the completions to the command path they belong to
+		cond := "__fish_use_subcommand"
+		if len(parents) > 0 {
+			cond = "__fish_seen_subcommand_from " + parents[len(parents)-1]
+		}
+		for _, flag := range flags {
+			if flagHidden(flag) {
+				continue
+			}
+			usage := describeFlag(flag, "").Usage
+			for _, name := range flagNames(flag) {
+				opt := "-l"
+				if len(name) == 1 {
+					opt = "-s"
+				}
+				if len(parents) == 0 {
+					fmt.Fprintf(w, "complete -c %s %s %s -d %q\n", app.Name, opt, name, usage)
+				} else {
+					fmt.Fprintf(w, "complete -c %s -n %q %s %s -d %q\n", app.Name, cond, opt, name, usage)
+				}
+			}
+		}
+		for _, cmd := range cmds {
+			if cmd.Hidden {
+				continue
+			}
+			fmt.Fprintf(w, "complete -c %s -n %q -a %q -d %q\n", app.Name, cond, cmd.Name, cmd.Usage)
+		}
+		for _, cmd := range cmds {
+			if !cmd.Hidden {
+				walk(append(parents, cmd.Name), cmd.Subcommands, cmd.Flags)
+			}
+		}
+	}
+	walk(nil, app.VisibleCommands(), app.Flags)
+}
+
+// completion prints the completion script of the requested shell.
+func completion(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires the shell name: bash, zsh or fish")
+	}
+	switch shell := ctx.Args().First(); shell {
+	case "bash":
+		writeBashCompletion(os.Stdout, ctx.App)
+	case "zsh":
+		writeZshCompletion(os.Stdout, ctx.App)
+	case "fish":
+		writeFishCompletion(os.Stdout, ctx.App)
+	default:
+		utils.Fatalf("Unsupported shell %q, available: bash, zsh, fish", shell)
+	}
+	return nil
+}
@@ -13,10 +13,12 @@ import (
 
 	"github.com/naoina/toml"
 	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/common/crash"
 	"github.com/abeychain/go-abey/crypto"
 	"github.com/abeychain/go-abey/abey"
 	"github.com/abeychain/go-abey/node"
 	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/telemetry"
 )
 
 var (
@@ -61,6 +63,7 @@ type gethConfig struct {
 	Abey      abey.Config
 	Node       node.Config
 	Abeystats abeystatsConfig
+	Telemetry telemetry.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -93,6 +96,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	cfg := gethConfig{
 		Abey:     abey.DefaultConfig,
 		Node:      defaultNodeConfig(),
+		Telemetry: telemetry.DefaultConfig,
 	}
 	if ctx.GlobalBool(utils.SingleNodeFlag.Name) {
 		// set abeyconfig
@@ -128,6 +132,11 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	if ctx.GlobalIsSet(utils.AbeystatsURLFlag.Name) {
 		cfg.Abeystats.URL = ctx.GlobalString(utils.AbeystatsURLFlag.Name)
 	}
+	// The abeystats service speaks the ethstats protocol, accept both flags
+	if ctx.GlobalIsSet(utils.EthstatsURLFlag.Name) {
+		cfg.Abeystats.URL = ctx.GlobalString(utils.EthstatsURLFlag.Name)
+	}
+	utils.SetTelemetryConfig(ctx, &cfg.Telemetry)
 
 	return stack, cfg
 }
@@ -135,12 +144,19 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
 
+	// Recover subsystem panics into crash bundles instead of dying
+	crash.Setup(stack.ResolvePath("crashes"), ctx.GlobalBool(utils.CrashRestartFlag.Name))
+
 	utils.RegisterAbeyService(stack, &cfg.Abey)
 
 	// Add the Abeychain Stats daemon if requested.
 	if cfg.Abeystats.URL != "" {
 		utils.RegisterAbeystatsService(stack, cfg.Abeystats.URL)
 	}
+	// Add the telemetry reporter if the user opted in.
+	if cfg.Telemetry.Endpoint != "" {
+		utils.RegisterTelemetryService(stack, cfg.Telemetry)
+	}
 	return stack
 }
 
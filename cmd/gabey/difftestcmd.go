@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/consensus/election"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/core/vm"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// difftestConnectTimeout is the time the reference peer is given to connect.
+const difftestConnectTimeout = 30 * time.Second
+
+var (
+	difftestPeerFlag = cli.StringFlag{
+		Name:  "peer",
+		Usage: "Enode URL of the reference node",
+	}
+	difftestFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Value: 1,
+		Usage: "First fast block of the tested range",
+	}
+	difftestToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last fast block of the tested range (default = lower of the local and reference heads)",
+	}
+	difftestSamplesFlag = cli.IntFlag{
+		Name:  "samples",
+		Value: 100,
+		Usage: "Number of blocks to cross-execute, spread evenly over the range",
+	}
+	difftestCommand = cli.Command{
+		Action:    utils.MigrateFlags(difftest),
+		Name:      "difftest",
+		Usage:     "Cross-execute sampled blocks of a reference node and report divergences",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.NetworkIdFlag,
+			utils.TestnetFlag,
+			utils.DevnetFlag,
+			difftestPeerFlag,
+			difftestFromFlag,
+			difftestToFlag,
+			difftestSamplesFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The difftest command connects to the reference node given by --peer, retrieves
+a sample of fast blocks from it and executes them on top of the local state.
+The gas used, receipts root and state root of every block are compared with the
+reference header. For diverging blocks the receipts of the reference node are
+fetched to report the first differing transaction.
+
+Blocks whose parent state is not available locally are skipped, use an archive
+node to test old ranges. The command fails if any sampled block diverged.`,
+	}
+)
+
+// difftest cross-executes blocks retrieved from a reference node.
+func difftest(ctx *cli.Context) error {
+	url := ctx.String(difftestPeerFlag.Name)
+	if url == "" {
+		utils.Fatalf("A reference node is required (--%s)", difftestPeerFlag.Name)
+	}
+	remote, err := enode.ParseV4(url)
+	if err != nil {
+		utils.Fatalf("Invalid reference node %q: %v", url, err)
+	}
+	stack, cfg := makeConfigNode(ctx)
+	fchain, schain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer fchain.Stop()
+
+	// Block rewards and fees depend on the committee and snail chain
+	engine := fchain.Engine()
+	elect := election.NewElection(fchain.Config(), fchain, schain, &cfg.Abey)
+	engine.SetElection(elect)
+	engine.SetSnailChainReader(schain)
+	elect.SetEngine(engine)
+
+	// Connect to the reference node only
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate node key: %v", err)
+	}
+	ref := abey.NewRefPeer(cfg.Abey.NetworkId, fchain, schain)
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		Name:        "Gabey-difftest",
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Protocols:   ref.Protocols(),
+	}}
+	if err := srv.Start(); err != nil {
+		utils.Fatalf("Failed to start networking: %v", err)
+	}
+	defer srv.Stop()
+	srv.AddPeer(remote)
+
+	if err := ref.WaitConnected(difftestConnectTimeout); err != nil {
+		utils.Fatalf("Failed to connect to %s: %v", url, err)
+	}
+	from, to := ctx.Uint64(difftestFromFlag.Name), ctx.Uint64(difftestToFlag.Name)
+	if to == 0 {
+		to = fchain.CurrentBlock().NumberU64()
+		if height := ref.FastHeight(); height < to {
+			to = height
+		}
+	}
+	if from == 0 {
+		from = 1 // The genesis block is not executed
+	}
+	if from > to {
+		utils.Fatalf("Empty block range #%d-#%d", from, to)
+	}
+	log.Info("Cross-executing reference blocks", "peer", url, "from", from, "to", to)
+
+	var checked, skipped, diverged int
+	for _, number := range sampleRange(from, to, ctx.Int(difftestSamplesFlag.Name)) {
+		res, err := diffBlock(fchain, ref, number)
+		switch {
+		case err != nil:
+			utils.Fatalf("Block #%d: %v", number, err)
+		case res.skipped:
+			skipped++
+			fmt.Printf("#%d skipped: %s\n", number, res.report)
+		case res.diverged:
+			checked++
+			diverged++
+			fmt.Printf("#%d DIVERGED: %s\n", number, res.report)
+		default:
+			checked++
+			if res.report != "" {
+				fmt.Printf("#%d %s\n", number, res.report)
+			}
+		}
+	}
+	fmt.Printf("Checked %d blocks, skipped %d, %d diverged\n", checked, skipped, diverged)
+	if diverged > 0 {
+		return fmt.Errorf("%d of %d checked blocks diverged from the reference node", diverged, checked)
+	}
+	return nil
+}
+
+// sampleRange returns up to n block numbers spread evenly over [from, to],
+// always including both ends.
+func sampleRange(from, to uint64, n int) []uint64 {
+	span := to - from + 1
+	if n <= 0 || uint64(n) >= span {
+		numbers := make([]uint64, 0, span)
+		for number := from; number <= to; number++ {
+			numbers = append(numbers, number)
+		}
+		return numbers
+	}
+	if n == 1 {
+		return []uint64{to}
+	}
+	numbers := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		numbers = append(numbers, from+(to-from)*uint64(i)/uint64(n-1))
+	}
+	return numbers
+}
+
+// blockDiff is the outcome of cross-executing a single reference block.
+type blockDiff struct {
+	skipped  bool   // The block could not be executed locally
+	diverged bool   // The local execution result differs from the reference
+	report   string // Description of the outcome, empty for plain matches
+}
+
+// diffBlock executes the reference block at number on top of the local state
+// and compares the result with the reference header. Errors are only returned
+// for failures talking to the reference node.
+func diffBlock(fchain *core.BlockChain, ref *abey.RefPeer, number uint64) (*blockDiff, error) {
+	header, err := ref.HeaderByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	var fork string
+	if local := fchain.GetHeaderByNumber(number); local != nil && local.Hash() != header.Hash() {
+		fork = fmt.Sprintf("canonical hash %x, reference %x; ", local.Hash().Bytes()[:8], header.Hash().Bytes()[:8])
+	}
+	parent := fchain.GetBlock(header.ParentHash, number-1)
+	if parent == nil {
+		return &blockDiff{skipped: true, report: fmt.Sprintf("%sparent %x unknown locally", fork, header.ParentHash.Bytes()[:8])}, nil
+	}
+	statedb, err := fchain.StateAt(parent.Root())
+	if err != nil {
+		return &blockDiff{skipped: true, report: fork + "parent state unavailable"}, nil
+	}
+	block, err := ref.Block(header)
+	if err != nil {
+		return nil, err
+	}
+	receipts, _, usedGas, _, err := fchain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return &blockDiff{diverged: true, report: fmt.Sprintf("%sexecution failed: %v", fork, err)}, nil
+	}
+	var mismatch string
+	switch {
+	case usedGas != header.GasUsed:
+		mismatch = fmt.Sprintf("gas used %d, reference %d", usedGas, header.GasUsed)
+	case types.DeriveSha(receipts) != header.ReceiptHash:
+		mismatch = fmt.Sprintf("receipts root %x, reference %x", types.DeriveSha(receipts).Bytes()[:8], header.ReceiptHash.Bytes()[:8])
+	default:
+		if root := statedb.IntermediateRoot(true); root != header.Root {
+			mismatch = fmt.Sprintf("state root %x, reference %x", root.Bytes()[:8], header.Root.Bytes()[:8])
+		}
+	}
+	if mismatch == "" {
+		if fork != "" {
+			return &blockDiff{report: fork + "reference block executes identically"}, nil
+		}
+		return &blockDiff{}, nil
+	}
+	remote, err := ref.Receipts(header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	res := &blockDiff{diverged: true, report: fork + mismatch}
+	for i := range block.Transactions() {
+		if i >= len(receipts) || i >= len(remote) {
+			break
+		}
+		if diff := receiptDiff(receipts[i], remote[i]); diff != "" {
+			res.report += fmt.Sprintf("; first differing tx %d %x: %s", i, block.Transactions()[i].Hash(), diff)
+			return res, nil
+		}
+	}
+	if len(receipts) != len(remote) {
+		res.report += fmt.Sprintf("; %d receipts, reference %d", len(receipts), len(remote))
+	} else {
+		res.report += "; all transactions match, block finalization differs"
+	}
+	return res, nil
+}
+
+// receiptDiff describes the first consensus field in which two receipts differ.
+func receiptDiff(local, remote *types.Receipt) string {
+	switch {
+	case local.Status != remote.Status:
+		return fmt.Sprintf("status %d, reference %d", local.Status, remote.Status)
+	case !bytes.Equal(local.PostState, remote.PostState):
+		return fmt.Sprintf("post state %x, reference %x", local.PostState, remote.PostState)
+	case local.CumulativeGasUsed != remote.CumulativeGasUsed:
+		return fmt.Sprintf("cumulative gas %d, reference %d", local.CumulativeGasUsed, remote.CumulativeGasUsed)
+	case len(local.Logs) != len(remote.Logs):
+		return fmt.Sprintf("%d logs, reference %d", len(local.Logs), len(remote.Logs))
+	}
+	for i := range local.Logs {
+		have, _ := rlp.EncodeToBytes(local.Logs[i])
+		want, _ := rlp.EncodeToBytes(remote.Logs[i])
+		if !bytes.Equal(have, want) {
+			return fmt.Sprintf("log %d differs", i)
+		}
+	}
+	if local.Bloom != remote.Bloom {
+		return "bloom differs"
+	}
+	return ""
+}
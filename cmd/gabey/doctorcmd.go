@@ -0,0 +1,376 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/diskusage"
+	"github.com/abeychain/go-abey/common/fdlimit"
+	"github.com/abeychain/go-abey/common/ntp"
+	"github.com/abeychain/go-abey/core/rawdb"
+	snaildb "github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/node"
+	"github.com/abeychain/go-abey/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	doctorMinFileLimit  = 2048 // Open files below which the database and peers compete for handles
+	doctorWarnDisk      = 50   // Free disk space in GB below which a warning is raised
+	doctorMinDisk       = 5    // Free disk space in GB below which the node should not be started
+	doctorWarnMemory    = 4    // Usable memory in GB below which the lowpower profile is advised
+	doctorMinMemory     = 2    // Usable memory in GB below which the node should not be started
+	doctorNTPMeasures   = 3    // Number of measurements of the clock drift
+	doctorDatabaseCache = 16   // Megabytes of cache to open the database with
+)
+
+var doctorCommand = cli.Command{
+	Action:    utils.MigrateFlags(doctor),
+	Name:      "doctor",
+	Usage:     "Check the host and the datadir before starting the node",
+	ArgsUsage: " ",
+	Flags:     append(append([]cli.Flag{}, nodeFlags...), rpcFlags...),
+	Category:  "NODE COMMANDS",
+	Description: `
+The doctor command runs a series of self-tests with the options the node would be
+started with: datadir permissions, clock drift, open file limit, availability of
+the listening ports, a summary of the database and its genesis, and the free disk
+space and memory. Each problem is printed with a suggested fix.
+
+The node must not be running, the ports and the database it holds are reported
+as unavailable otherwise. The command exits with an error if any check failed.`,
+}
+
+// diagnosisLevel is the outcome of a single doctor check.
+type diagnosisLevel int
+
+const (
+	diagnosisOK diagnosisLevel = iota
+	diagnosisWarn
+	diagnosisFail
+)
+
+func (l diagnosisLevel) String() string {
+	switch l {
+	case diagnosisOK:
+		return "  OK"
+	case diagnosisWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// diagnosis is the result of a single doctor check, with the suggested fix for
+// anything but a passed check.
+type diagnosis struct {
+	check  string
+	level  diagnosisLevel
+	detail string
+	fix    string
+}
+
+// doctor runs all the self-tests and prints their results.
+func doctor(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+
+	var results []*diagnosis
+	results = append(results, checkDataDir(cfg.Node.DataDir))
+	results = append(results, checkClock(cfg.Abey.NTPServer))
+	results = append(results, checkFileLimit())
+	results = append(results, checkPorts(cfg)...)
+	results = append(results, checkDatabase(ctx, stack)...)
+	results = append(results, checkResources(cfg.Node.DataDir)...)
+
+	failed := 0
+	for _, r := range results {
+		fmt.Printf("[%s] %-16s %s\n", r.level, r.check, r.detail)
+		if r.level != diagnosisOK && r.fix != "" {
+			fmt.Printf("       %-16s fix: %s\n", "", r.fix)
+		}
+		if r.level == diagnosisFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// checkDataDir verifies that the datadir can be written by the node and is not
+// open to other users.
+func checkDataDir(dir string) *diagnosis {
+	d := &diagnosis{check: "datadir"}
+	if dir == "" {
+		d.level, d.detail = diagnosisWarn, "ephemeral datadir, the chain is lost on exit"
+		d.fix = "pass --" + utils.DataDirFlag.Name
+		return d
+	}
+	// Probe the nearest existing directory, the node creates the rest
+	probe := dir
+	for {
+		if _, err := os.Stat(probe); err == nil || filepath.Dir(probe) == probe {
+			break
+		}
+		probe = filepath.Dir(probe)
+	}
+	f, err := ioutil.TempFile(probe, ".doctor")
+	if err != nil {
+		d.level, d.detail = diagnosisFail, fmt.Sprintf("%s is not writable: %v", probe, err)
+		d.fix = fmt.Sprintf("chown the directory to the node user or pick another --%s", utils.DataDirFlag.Name)
+		return d
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	if info, err := os.Stat(dir); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0002 != 0 {
+		d.level, d.detail = diagnosisWarn, fmt.Sprintf("%s is world-writable (%v)", dir, info.Mode().Perm())
+		d.fix = "chmod 700 " + dir
+		return d
+	}
+	d.detail = fmt.Sprintf("%s is writable", dir)
+	return d
+}
+
+// checkClock measures the drift of the local clock against an NTP server.
+func checkClock(server string) *diagnosis {
+	d := &diagnosis{check: "clock"}
+	if server == "" {
+		server = ntp.DefaultServer
+	}
+	drift, err := ntp.Drift(net.JoinHostPort(server, "123"), doctorNTPMeasures)
+	if err != nil {
+		d.level, d.detail = diagnosisWarn, fmt.Sprintf("could not measure the drift against %s: %v", server, err)
+		d.fix = fmt.Sprintf("allow outbound UDP port 123 or pass a reachable --%s", utils.NTPServerFlag.Name)
+		return d
+	}
+	if drift < 0 {
+		drift = -drift
+	}
+	switch {
+	case drift > ntp.DefaultThreshold:
+		d.level = diagnosisFail
+	case drift > ntp.DefaultThreshold/2:
+		d.level = diagnosisWarn
+	}
+	d.detail = fmt.Sprintf("drift %v against %s", drift, server)
+	d.fix = "enable clock synchronisation, e.g. timedatectl set-ntp true"
+	return d
+}
+
+// checkFileLimit verifies that the process may open enough files for the
+// database and the peer connections.
+func checkFileLimit() *diagnosis {
+	d := &diagnosis{check: "file limit"}
+	limit, err := fdlimit.Maximum()
+	if err != nil {
+		d.level, d.detail = diagnosisWarn, fmt.Sprintf("could not retrieve the limit: %v", err)
+		return d
+	}
+	d.detail = fmt.Sprintf("%d open files allowed", limit)
+	if limit < doctorMinFileLimit {
+		d.level = diagnosisWarn
+		d.fix = "raise the limit, e.g. ulimit -n 65535 or LimitNOFILE=65535 in the service unit"
+	}
+	return d
+}
+
+// checkPorts verifies that the ports the node listens on are free.
+func checkPorts(cfg gethConfig) []*diagnosis {
+	type port struct {
+		name, network, addr, flag string
+	}
+	ports := []port{{"p2p", "tcp", cfg.Node.P2P.ListenAddr, utils.ListenPortFlag.Name}}
+	if !cfg.Node.P2P.NoDiscovery {
+		ports = append(ports, port{"discovery", "udp", cfg.Node.P2P.ListenAddr, utils.ListenPortFlag.Name})
+	}
+	if cfg.Abey.Port != 0 {
+		ports = append(ports, port{"bft", "tcp", fmt.Sprintf(":%d", cfg.Abey.Port), utils.BFTPortFlag.Name})
+	}
+	if cfg.Abey.StandbyPort != 0 {
+		ports = append(ports, port{"bft standby", "tcp", fmt.Sprintf(":%d", cfg.Abey.StandbyPort), utils.BFTStandbyPortFlag.Name})
+	}
+	if endpoint := cfg.Node.HTTPEndpoint(); endpoint != "" {
+		ports = append(ports, port{"http rpc", "tcp", endpoint, utils.RPCPortFlag.Name})
+	}
+	if endpoint := cfg.Node.WSEndpoint(); endpoint != "" {
+		ports = append(ports, port{"ws rpc", "tcp", endpoint, utils.WSPortFlag.Name})
+	}
+	var results []*diagnosis
+	for _, p := range ports {
+		d := &diagnosis{check: p.name + " port", detail: fmt.Sprintf("%s %s is free", p.network, p.addr)}
+
+		var err error
+		if p.network == "udp" {
+			var conn net.PacketConn
+			if conn, err = net.ListenPacket(p.network, p.addr); err == nil {
+				conn.Close()
+			}
+		} else {
+			var listener net.Listener
+			if listener, err = net.Listen(p.network, p.addr); err == nil {
+				listener.Close()
+			}
+		}
+		if err != nil {
+			d.level, d.detail = diagnosisFail, fmt.Sprintf("%s %s is unavailable: %v", p.network, p.addr, err)
+			d.fix = fmt.Sprintf("stop the process holding the port or change --%s", p.flag)
+		}
+		results = append(results, d)
+	}
+	return results
+}
+
+// checkDatabase summarizes the chain database and verifies that its genesis
+// matches the configured network.
+func checkDatabase(ctx *cli.Context, stack *node.Node) []*diagnosis {
+	d := &diagnosis{check: "database"}
+	path := stack.ResolvePath("chaindata")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		d.detail = "not initialized yet, created on the first start"
+		return []*diagnosis{d}
+	}
+	db, err := stack.OpenDatabase("chaindata", doctorDatabaseCache, doctorDatabaseCache)
+	if err != nil {
+		d.level, d.detail = diagnosisFail, fmt.Sprintf("could not open %s: %v", path, err)
+		d.fix = "stop any node running on this datadir, or restore the database from a backup"
+		return []*diagnosis{d}
+	}
+	defer db.Close()
+
+	// Summarize the fast and snail heads, checking the head state is present
+	var (
+		fastHash  = rawdb.ReadHeadBlockHash(db)
+		snailHash = snaildb.ReadHeadBlockHash(db)
+		fastNum   = rawdb.ReadHeaderNumber(db, fastHash)
+		snailNum  = snaildb.ReadHeaderNumber(db, snailHash)
+	)
+	switch {
+	case fastHash == (common.Hash{}) && snailHash == (common.Hash{}):
+		d.detail = "empty, initialized on the first start"
+	case fastNum == nil || rawdb.ReadHeader(db, fastHash, *fastNum) == nil:
+		d.level, d.detail = diagnosisFail, fmt.Sprintf("fast head %x is missing", fastHash)
+		d.fix = fmt.Sprintf("remove the database with the %s command and resync", removedbCommand.Name)
+	case snailNum == nil || snaildb.ReadHeader(db, snailHash, *snailNum) == nil:
+		d.level, d.detail = diagnosisFail, fmt.Sprintf("snail head %x is missing", snailHash)
+		d.fix = fmt.Sprintf("remove the database with the %s command and resync", removedbCommand.Name)
+	default:
+		head := rawdb.ReadHeader(db, fastHash, *fastNum)
+		d.detail = fmt.Sprintf("fast head #%d [%x…], snail head #%d [%x…]", *fastNum, fastHash[:4], *snailNum, snailHash[:4])
+		if ok, _ := db.Has(head.Root[:]); !ok && *fastNum > 0 {
+			d.level = diagnosisWarn
+			d.detail += ", head state missing"
+			d.fix = "the node rewinds to the last persisted state on start, shut it down cleanly to avoid this"
+		}
+	}
+	return []*diagnosis{d, checkGenesis(ctx, db)}
+}
+
+// checkGenesis compares the genesis of the database with the one of the
+// network selected on the command line.
+func checkGenesis(ctx *cli.Context, db rawdb.DatabaseReader) *diagnosis {
+	d := &diagnosis{check: "genesis"}
+	stored := snaildb.ReadCanonicalHash(db, 0)
+	if stored == (common.Hash{}) {
+		d.detail = "not initialized yet, written on the first start"
+		return d
+	}
+	networks := []struct {
+		name, flag string
+		hash       common.Hash
+	}{
+		{"mainnet", "", params.MainnetSnailGenesisHash},
+		{"testnet", utils.TestnetFlag.Name, params.TestnetSnailGenesisHash},
+		{"devnet", utils.DevnetFlag.Name, params.DevnetSnailGenesisHash},
+	}
+	selected := networks[0]
+	for _, network := range networks[1:] {
+		if ctx.GlobalBool(network.flag) {
+			selected = network
+		}
+	}
+	if stored == selected.hash {
+		d.detail = fmt.Sprintf("%s [%x…]", selected.name, stored[:4])
+		return d
+	}
+	for _, network := range networks {
+		if stored == network.hash {
+			d.level, d.detail = diagnosisFail, fmt.Sprintf("datadir holds the %s chain, %s is configured", network.name, selected.name)
+			if network.flag == "" {
+				d.fix = "drop the network flag or pick another --" + utils.DataDirFlag.Name
+			} else {
+				d.fix = fmt.Sprintf("pass --%s or pick another --%s", network.flag, utils.DataDirFlag.Name)
+			}
+			return d
+		}
+	}
+	d.level, d.detail = diagnosisWarn, fmt.Sprintf("custom genesis [%x…]", stored[:4])
+	d.fix = fmt.Sprintf("make sure --%s and the bootnodes match the private network", utils.NetworkIdFlag.Name)
+	return d
+}
+
+// checkResources verifies the free disk space of the datadir and the memory
+// available to the node.
+func checkResources(dir string) []*diagnosis {
+	disk := &diagnosis{check: "disk"}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	if free, err := diskusage.FreeSpace(dir); err != nil {
+		disk.level, disk.detail = diagnosisWarn, fmt.Sprintf("could not retrieve the free space: %v", err)
+	} else {
+		disk.detail = fmt.Sprintf("%v free on %s", common.StorageSize(free), dir)
+		switch {
+		case free < doctorMinDisk<<30:
+			disk.level = diagnosisFail
+		case free < doctorWarnDisk<<30:
+			disk.level = diagnosisWarn
+		}
+		disk.fix = "free up space or move the datadir to a larger volume"
+	}
+
+	mem := &diagnosis{check: "memory"}
+	if usable, available, err := utils.MemoryStatus(); err != nil {
+		mem.level, mem.detail = diagnosisWarn, fmt.Sprintf("could not detect the memory: %v", err)
+	} else {
+		mem.detail = fmt.Sprintf("%v usable, %v available", common.StorageSize(usable), common.StorageSize(available))
+		switch {
+		case usable < doctorMinMemory<<30:
+			mem.level = diagnosisFail
+			mem.fix = "run the node on a host or container with more memory"
+		case usable < doctorWarnMemory<<30:
+			mem.level = diagnosisWarn
+			mem.fix = fmt.Sprintf("start the node with --%s %s", utils.ProfileFlag.Name, utils.ProfileLowPower)
+		}
+	}
+	return []*diagnosis{disk, mem}
+}
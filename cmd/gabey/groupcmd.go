@@ -0,0 +1,137 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/abeychain/go-abey/cmd/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// The grouped commands multiplex the tools of the repository behind a single
+// binary. They reuse the flat commands, which stay available but hidden for
+// backwards compatibility.
+var (
+	nodeCommand = cli.Command{
+		Action:    gabey,
+		Name:      "node",
+		Usage:     "Run the full node (default when no command is given)",
+		ArgsUsage: " ",
+		Category:  "NODE COMMANDS",
+		Description: `
+The node command runs the node with the global options, it is what the binary
+does when invoked without any command.
+
+    abey node --datadir /data/abey
+
+is the same as
+
+    abey --datadir /data/abey`,
+	}
+	keyCommand      = utils.KeyCommand
+	bootnodeCommand = utils.BootnodeCommand
+	genesisCommand  = cli.Command{
+		Name:     "genesis",
+		Usage:    "Initialize the chain from a genesis definition",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			subcommand(initCommand, "init"),
+		},
+	}
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Inspect and maintain the chain database",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			subcommand(dumpCommand, "dump"),
+			subcommand(restoreStateCommand, "restore-state"),
+			subcommand(inspectDBCommand, "inspect"),
+			subcommand(dbSchemaCommand, "schema"),
+			subcommand(copydbCommand, "copy"),
+			subcommand(removedbCommand, "remove"),
+		},
+	}
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Export and import chain and preimage snapshots",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			subcommand(exportCommand, "export"),
+			subcommand(importCommand, "import"),
+			subcommand(exportPreimagesCommand, "export-preimages"),
+			subcommand(importPreimagesCommand, "import-preimages"),
+			subcommand(exportElectionCommand, "export-election"),
+		},
+	}
+	verifyCommand = cli.Command{
+		Name:     "verify",
+		Usage:    "Audit the local chain against exports and reference nodes",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			subcommand(verifyElectionCommand, "election"),
+			subcommand(difftestCommand, "difftest"),
+		},
+	}
+)
+
+// subcommand returns a copy of cmd to be served under a grouped command.
+func subcommand(cmd cli.Command, name string) cli.Command {
+	cmd.Name = name
+	cmd.Category = ""
+	return cmd
+}
+
+// legacy hides the flat commands superseded by a grouped command.
+func legacy(cmds ...cli.Command) []cli.Command {
+	for i := range cmds {
+		cmds[i].Hidden = true
+	}
+	return cmds
+}
+
+// nodeArgs drops the node command from the arguments, so that its options are
+// parsed as the global ones the node and the app setup read. The command may be
+// preceded by global options, whose values are skipped using the flag types.
+func nodeArgs(flags []cli.Flag, args []string) []string {
+	bools := make(map[string]bool)
+	for _, flag := range flags {
+		switch flag.(type) {
+		case cli.BoolFlag, cli.BoolTFlag:
+			for _, name := range strings.Split(flag.GetName(), ",") {
+				bools[strings.TrimSpace(name)] = true
+			}
+		}
+	}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args
+		case strings.HasPrefix(arg, "-"):
+			name := strings.TrimLeft(arg, "-")
+			if !strings.Contains(name, "=") && !bools[name] {
+				i++ // Skip the flag value
+			}
+		case arg == nodeCommand.Name:
+			return append(append([]string{}, args[:i]...), args[i+1:]...)
+		default:
+			return args // Some other command
+		}
+	}
+	return args
+}
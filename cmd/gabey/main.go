@@ -8,11 +8,9 @@ import (
 	"runtime"
 	godebug "runtime/debug"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/elastic/gosigar"
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/accounts/keystore"
 	"github.com/abeychain/go-abey/cmd/utils"
@@ -20,9 +18,11 @@ import (
 	"github.com/abeychain/go-abey/abey"
 	"github.com/abeychain/go-abey/abeyclient"
 	"github.com/abeychain/go-abey/internal/debug"
+	"github.com/abeychain/go-abey/internal/servicemgr"
 	"github.com/abeychain/go-abey/log"
 	"github.com/abeychain/go-abey/metrics"
 	"github.com/abeychain/go-abey/node"
+	"github.com/abeychain/go-abey/tracing"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -41,11 +41,16 @@ var (
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
+		utils.KeyCacheTTLFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.ContainerizedFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.ReadOnlyFlag,
 
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
@@ -57,11 +62,13 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolTxLifetimeFlag,
 
 		utils.SnailPoolJournalFlag,
 		utils.SnailPoolRejournalFlag,
 		utils.SnailPoolFruitCountFlag,
 		utils.SyncModeFlag,
+		utils.SyncStallTimeoutFlag,
 
 		utils.SingleNodeFlag,
 
@@ -72,14 +79,31 @@ var (
 		utils.BFTIPFlag,
 		utils.BftKeyFileFlag,
 		utils.BftKeyHexFlag,
+		utils.BFTSignMsgsFlag,
+		utils.BFTRequireSignedMsgsFlag,
+		utils.BFTAlertURLFlag,
+		utils.BFTAlertThresholdFlag,
 
 		utils.GCModeFlag,
+		utils.SnailFinalityFlag,
+		utils.NTPServerFlag,
+		utils.DiskWarnDaysFlag,
+		utils.FruitLagBlocksFlag,
+		utils.FruitLagTimeFlag,
+		utils.NetCaptureFlag,
+		utils.CrashRestartFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.ULCTrustedServersFlag,
+		utils.ULCMinTrustedFractionFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
+		utils.CacheDownloaderFlag,
+		utils.CacheMemoryLimitFlag,
+		utils.ProfileFlag,
+		utils.CacheRPCFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
@@ -108,12 +132,17 @@ var (
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
 		utils.AbeystatsURLFlag,
+		utils.EthstatsURLFlag,
+		utils.TelemetryEndpointFlag,
+		utils.TelemetryFormatFlag,
+		utils.TelemetryIntervalFlag,
 		utils.MetricsEnabledFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
 		utils.ExtraDataFlag,
+		utils.CoinbaseProofFlag,
 		configFileFlag,
 	}
 
@@ -122,11 +151,20 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCAPIKeysFlag,
+		utils.RPCQuotasFlag,
+		utils.RPCQuotaPeriodFlag,
+		utils.RPCGlobalEVMTimeoutFlag,
+		utils.RPCGlobalGasCapFlag,
+		utils.RPCStateReadLimitFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSMaxConnectionsFlag,
+		utils.WSMaxSubscriptionsFlag,
+		utils.WSSendQueueFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -138,6 +176,8 @@ var (
 		utils.MetricsInfluxDBUsernameFlag,
 		utils.MetricsInfluxDBPasswordFlag,
 		utils.MetricsInfluxDBHostTagFlag,
+		utils.TracingEndpointFlag,
+		utils.TracingSampleFlag,
 	}
 )
 
@@ -147,17 +187,18 @@ func init() {
 	app.HideVersion = true // we have a command to print the version
 	app.Copyright = "Copyright 2018-2019 The gabey Authors"
 	app.Commands = []cli.Command{
-		// See chaincmd.go:
-		initCommand,
-		importCommand,
-		exportCommand,
-		importPreimagesCommand,
-		exportPreimagesCommand,
-		copydbCommand,
-		removedbCommand,
-		dumpCommand,
+		// See groupcmd.go:
+		nodeCommand,
+		keyCommand,
+		bootnodeCommand,
+		genesisCommand,
+		dbCommand,
+		snapshotCommand,
+		verifyCommand,
 		// See monitorcmd.go:
 		monitorCommand,
+		// See servicecmd.go:
+		serviceCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -172,7 +213,30 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See completioncmd.go
+		completionCommand,
+		// See doctorcmd.go
+		doctorCommand,
+		// See benchcmd.go
+		benchCommand,
 	}
+	// The flat commands predate the grouped ones, see chaincmd.go and difftestcmd.go
+	app.Commands = append(app.Commands, legacy(
+		initCommand,
+		importCommand,
+		exportCommand,
+		importPreimagesCommand,
+		exportPreimagesCommand,
+		copydbCommand,
+		removedbCommand,
+		dumpCommand,
+		restoreStateCommand,
+		exportElectionCommand,
+		verifyElectionCommand,
+		dbSchemaCommand,
+		inspectDBCommand,
+		difftestCommand,
+	)...)
 	sort.Sort(cli.CommandsByName(app.Commands))
 
 	app.Flags = append(app.Flags, nodeFlags...)
@@ -180,22 +244,26 @@ func init() {
 	app.Flags = append(app.Flags, consoleFlags...)
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, metricsFlags...)
+	app.Flags = append(app.Flags, helpJSONFlag)
 
 	app.Before = func(ctx *cli.Context) error {
+		if ctx.GlobalBool(helpJSONFlag.Name) {
+			if err := writeHelpJSON(os.Stdout, ctx.App); err != nil {
+				return err
+			}
+			os.Exit(0)
+		}
 		runtime.GOMAXPROCS(runtime.NumCPU())
 		logdir := ""
 		if err := debug.Setup(ctx, logdir); err != nil {
 			return err
 		}
-		// Cap the cache allowance and tune the garbage colelctor
-		var mem gosigar.Mem
-		if err := mem.Get(); err == nil {
-			allowance := int(mem.Total / 1024 / 1024 / 3)
-			if cache := ctx.GlobalInt(utils.CacheFlag.Name); cache > allowance {
-				log.Warn("Sanitizing cache to Go's GC limits", "provided", cache, "updated", allowance)
-				ctx.GlobalSet(utils.CacheFlag.Name, strconv.Itoa(allowance))
-			}
-		}
+		// Apply the hardware profile before the defaults derived from it
+		utils.SetupProfile(ctx)
+
+		// Size or cap the cache allowance and tune the garbage colelctor
+		utils.SetupCache(ctx)
+
 		// Ensure Go's GC ignores the database cache for trigger percentage
 		cache := ctx.GlobalInt(utils.CacheFlag.Name)
 		gogc := math.Max(20, math.Min(100, 100/(float64(cache)/1024)))
@@ -209,10 +277,14 @@ func init() {
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
+		// Start request trace export if enabled
+		utils.SetupTracing(ctx)
+
 		return nil
 	}
 
 	app.After = func(ctx *cli.Context) error {
+		tracing.Stop()
 		debug.Exit()
 		console.Stdin.Close() // Resets terminal mode.
 		return nil
@@ -220,7 +292,8 @@ func init() {
 }
 
 func main() {
-	if err := app.Run(os.Args); err != nil {
+	run := func() error { return app.Run(nodeArgs(app.Flags, os.Args)) }
+	if err := servicemgr.Run(clientIdentifier, run); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -302,6 +375,9 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support mining")
 		}
+		if ctx.GlobalBool(utils.ReadOnlyFlag.Name) {
+			utils.Fatalf("Read-only nodes do not support mining")
+		}
 		var abeychain *abey.Abeychain
 		if err := stack.Service(&abeychain); err != nil {
 			utils.Fatalf("Abeychain service not running: %v", err)
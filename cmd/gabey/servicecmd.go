@@ -0,0 +1,177 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abeychain/go-abey/cmd/utils"
+	"github.com/abeychain/go-abey/internal/servicemgr"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	serviceNameFlag = cli.StringFlag{
+		Name:  "name",
+		Usage: "Name of the service",
+		Value: clientIdentifier,
+	}
+	serviceCommand = cli.Command{
+		Name:      "service",
+		Usage:     "Manage gabey as a native system service",
+		ArgsUsage: "",
+		Category:  "SERVICE COMMANDS",
+		Description: `
+Integrates gabey with the service manager of the operating system.
+
+On Windows the node can be registered with the service control manager, which
+starts it at boot and stops it cleanly on shutdown. On Linux a systemd unit is
+generated, under which gabey reports its readiness and pings the watchdog.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "install",
+				Usage:     "Register gabey as a Windows service",
+				ArgsUsage: "[ -- <node flags> ]",
+				Action:    utils.MigrateFlags(serviceInstall),
+				Flags:     []cli.Flag{serviceNameFlag},
+				Description: `
+    gabey service install [--name <name>] -- <node flags>
+
+Registers the running executable as an automatically started Windows service.
+The node flags following -- are passed to the node whenever the service starts.`,
+			},
+			{
+				Name:      "uninstall",
+				Usage:     "Remove the gabey Windows service",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(serviceUninstall),
+				Flags:     []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:      "start",
+				Usage:     "Start the gabey Windows service",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(serviceStart),
+				Flags:     []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:      "stop",
+				Usage:     "Stop the gabey Windows service, waiting for a clean shutdown",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(serviceStop),
+				Flags:     []cli.Flag{serviceNameFlag},
+			},
+			{
+				Name:      "systemd",
+				Usage:     "Print a systemd unit running gabey",
+				ArgsUsage: "[ -- <node flags> ]",
+				Action:    utils.MigrateFlags(serviceSystemd),
+				Flags:     []cli.Flag{serviceNameFlag},
+				Description: `
+    gabey service systemd -- <node flags> > /etc/systemd/system/gabey.service
+
+Prints a systemd unit starting the running executable with the node flags
+following --. The node notifies systemd once it is ready and pings its watchdog,
+and is given enough time to shut down cleanly when stopped.`,
+			},
+		},
+	}
+)
+
+// executable returns the absolute path of the running executable.
+func executable() string {
+	exe, err := os.Executable()
+	if err != nil {
+		utils.Fatalf("Failed to locate executable: %v", err)
+	}
+	if exe, err = filepath.Abs(exe); err != nil {
+		utils.Fatalf("Failed to locate executable: %v", err)
+	}
+	return exe
+}
+
+func serviceInstall(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := servicemgr.Install(name, executable(), ctx.Args()...); err != nil {
+		utils.Fatalf("Failed to install service %s: %v", name, err)
+	}
+	fmt.Printf("Service %s installed\n", name)
+	return nil
+}
+
+func serviceUninstall(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := servicemgr.Remove(name); err != nil {
+		utils.Fatalf("Failed to remove service %s: %v", name, err)
+	}
+	fmt.Printf("Service %s removed\n", name)
+	return nil
+}
+
+func serviceStart(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := servicemgr.Start(name); err != nil {
+		utils.Fatalf("Failed to start service %s: %v", name, err)
+	}
+	fmt.Printf("Service %s started\n", name)
+	return nil
+}
+
+func serviceStop(ctx *cli.Context) error {
+	name := ctx.String(serviceNameFlag.Name)
+	if err := servicemgr.Stop(name); err != nil {
+		utils.Fatalf("Failed to stop service %s: %v", name, err)
+	}
+	fmt.Printf("Service %s stopped\n", name)
+	return nil
+}
+
+// systemdUnit is the template of the generated systemd unit. The stop timeout
+// leaves room for flushing the state caches to disk.
+const systemdUnit = `[Unit]
+Description=Abeychain node (%s)
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart=%s
+Restart=on-failure
+RestartSec=10
+WatchdogSec=300
+KillSignal=SIGTERM
+TimeoutStopSec=600
+LimitNOFILE=65536
+
+[Install]
+WantedBy=multi-user.target
+`
+
+func serviceSystemd(ctx *cli.Context) error {
+	command := append([]string{executable()}, ctx.Args()...)
+	for i, arg := range command {
+		if strings.ContainsAny(arg, " \t\"'\\") {
+			command[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	fmt.Printf(systemdUnit, ctx.String(serviceNameFlag.Name), strings.Join(command, " "))
+	return nil
+}
@@ -29,7 +29,7 @@ AUTHOR(S):
    {{range .App.Authors}}{{ . }}{{end}}
    {{end}}{{if .App.Commands}}
 COMMANDS:
-   {{range .App.Commands}}{{join .Names ", "}}{{ "\t" }}{{.Usage}}
+   {{range .App.VisibleCommands}}{{join .Names ", "}}{{ "\t" }}{{.Usage}}
    {{end}}{{end}}{{if .FlagGroups}}
 {{range .FlagGroups}}{{.Name}} OPTIONS:
   {{range .Flags}}{{.}}
@@ -53,17 +53,33 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.ContainerizedFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.ReadOnlyFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.DevnetFlag,
 			utils.SyncModeFlag,
+			utils.SyncStallTimeoutFlag,
 			utils.GCModeFlag,
+			utils.SnailFinalityFlag,
+			utils.NTPServerFlag,
+			utils.DiskWarnDaysFlag,
+			utils.FruitLagBlocksFlag,
+			utils.FruitLagTimeFlag,
+			utils.NetCaptureFlag,
+			utils.CrashRestartFlag,
 			utils.AbeystatsURLFlag,
+			utils.EthstatsURLFlag,
+			utils.TelemetryEndpointFlag,
+			utils.TelemetryFormatFlag,
+			utils.TelemetryIntervalFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.ULCTrustedServersFlag,
+			utils.ULCMinTrustedFractionFlag,
 			utils.LightKDFFlag,
 		},
 	},
@@ -90,6 +106,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BFTStandbyPortFlag,
 			utils.BftKeyFileFlag,
 			utils.BftKeyHexFlag,
+			utils.BFTSignMsgsFlag,
+			utils.BFTRequireSignedMsgsFlag,
+			utils.BFTAlertURLFlag,
+			utils.BFTAlertThresholdFlag,
 		},
 	},
 
@@ -106,6 +126,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolTxLifetimeFlag,
 		},
 	},
 	{
@@ -114,6 +135,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheDownloaderFlag,
+			utils.CacheMemoryLimitFlag,
+			utils.ProfileFlag,
+			utils.CacheRPCFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -122,6 +147,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
+			utils.KeyCacheTTLFlag,
 		},
 	},
 	{
@@ -136,10 +164,19 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSMaxConnectionsFlag,
+			utils.WSMaxSubscriptionsFlag,
+			utils.WSSendQueueFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCAPIKeysFlag,
+			utils.RPCQuotasFlag,
+			utils.RPCQuotaPeriodFlag,
+			utils.RPCGlobalEVMTimeoutFlag,
+			utils.RPCGlobalGasCapFlag,
+			utils.RPCStateReadLimitFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -172,6 +209,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.GasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.CoinbaseProofFlag,
 		},
 	},
 	{
@@ -204,6 +242,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MetricsInfluxDBUsernameFlag,
 			utils.MetricsInfluxDBPasswordFlag,
 			utils.MetricsInfluxDBHostTagFlag,
+			utils.TracingEndpointFlag,
+			utils.TracingSampleFlag,
 		},
 	},
 	{
@@ -18,10 +18,7 @@ var (
 
 func init() {
 	app = utils.NewApp(gitCommit, gitData, "an common generate and convert address tool")
-	app.Commands = []cli.Command{
-		generateCommand,
-		convertCommand,
-	}
+	app.Commands = utils.KeyCommand.Subcommands
 	sort.Sort(cli.CommandsByName(app.Commands))
 }
 
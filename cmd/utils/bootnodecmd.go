@@ -0,0 +1,166 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/p2p/discover"
+	"github.com/abeychain/go-abey/p2p/discv5"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/p2p/nat"
+	"github.com/abeychain/go-abey/p2p/netutil"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// BootnodeCommand runs a bootstrap node for the discovery protocol, shared
+	// by the node binary and the standalone bootnode wrapper.
+	BootnodeCommand = cli.Command{
+		Action:    runBootnode,
+		Name:      "bootnode",
+		Usage:     "Run a bootstrap node for the discovery protocol",
+		ArgsUsage: " ",
+		Category:  "NETWORKING COMMANDS",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "addr",
+				Usage: "listen address",
+				Value: ":30301",
+			},
+			cli.StringFlag{
+				Name:  "genkey",
+				Usage: "generate a node key",
+			},
+			cli.BoolFlag{
+				Name:  "writeaddress",
+				Usage: "write out the node's pubkey hash and quit",
+			},
+			cli.StringFlag{
+				Name:  "nodekey",
+				Usage: "private key filename",
+			},
+			cli.StringFlag{
+				Name:  "nodekeyhex",
+				Usage: "private key as hex (for testing)",
+			},
+			cli.StringFlag{
+				Name:  "nat",
+				Usage: "port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
+				Value: "none",
+			},
+			cli.StringFlag{
+				Name:  "netrestrict",
+				Usage: "restrict network communication to the given IP networks (CIDR masks)",
+			},
+			cli.BoolFlag{
+				Name:  "v5",
+				Usage: "run a v5 topic discovery bootnode",
+			},
+		},
+		Description: `
+The bootnode command runs a discovery-only node other nodes can bootstrap from.`,
+	}
+)
+
+// runBootnode loads or generates the node key and serves the discovery
+// protocol until the process is killed.
+func runBootnode(ctx *cli.Context) error {
+	natm, err := nat.Parse(ctx.String("nat"))
+	if err != nil {
+		return fmt.Errorf("-nat: %v", err)
+	}
+	var (
+		genKey      = ctx.String("genkey")
+		writeAddr   = ctx.Bool("writeaddress")
+		nodeKeyFile = ctx.String("nodekey")
+		nodeKeyHex  = ctx.String("nodekeyhex")
+		nodeKey     *ecdsa.PrivateKey
+	)
+	switch {
+	case genKey != "":
+		if nodeKey, err = crypto.GenerateKey(); err != nil {
+			return fmt.Errorf("could not generate key: %v", err)
+		}
+		if err = crypto.SaveECDSA(genKey, nodeKey); err != nil {
+			return err
+		}
+		if !writeAddr {
+			return nil
+		}
+	case nodeKeyFile == "" && nodeKeyHex == "":
+		return fmt.Errorf("Use -nodekey or -nodekeyhex to specify a private key")
+	case nodeKeyFile != "" && nodeKeyHex != "":
+		return fmt.Errorf("Options -nodekey and -nodekeyhex are mutually exclusive")
+	case nodeKeyFile != "":
+		if nodeKey, err = crypto.LoadECDSA(nodeKeyFile); err != nil {
+			return fmt.Errorf("-nodekey: %v", err)
+		}
+	case nodeKeyHex != "":
+		if nodeKey, err = crypto.HexToECDSA(nodeKeyHex); err != nil {
+			return fmt.Errorf("-nodekeyhex: %v", err)
+		}
+	}
+	if writeAddr {
+		fmt.Printf("%x\n", crypto.FromECDSAPub(&nodeKey.PublicKey)[1:])
+		return nil
+	}
+
+	var restrictList *netutil.Netlist
+	if netrestrict := ctx.String("netrestrict"); netrestrict != "" {
+		if restrictList, err = netutil.ParseNetlist(netrestrict); err != nil {
+			return fmt.Errorf("-netrestrict: %v", err)
+		}
+	}
+	addr, err := net.ResolveUDPAddr("udp", ctx.String("addr"))
+	if err != nil {
+		return fmt.Errorf("-ResolveUDPAddr: %v", err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return fmt.Errorf("-ListenUDP: %v", err)
+	}
+	realaddr := conn.LocalAddr().(*net.UDPAddr)
+	if natm != nil {
+		if !realaddr.IP.IsLoopback() {
+			go nat.Map(natm, nil, "udp", realaddr.Port, realaddr.Port, "abeychain discovery")
+		}
+		// TODO: react to external IP changes over time.
+		if ext, err := natm.ExternalIP(); err == nil {
+			realaddr = &net.UDPAddr{IP: ext, Port: realaddr.Port}
+		}
+	}
+	if ctx.Bool("v5") {
+		if _, err := discv5.ListenUDP(nodeKey, conn, "", restrictList); err != nil {
+			return err
+		}
+	} else {
+		db, _ := enode.OpenDB("")
+		ln := enode.NewLocalNode(db, nodeKey)
+		cfg := discover.Config{
+			PrivateKey:  nodeKey,
+			NetRestrict: restrictList,
+		}
+		if _, err := discover.ListenUDP(conn, ln, cfg); err != nil {
+			return err
+		}
+	}
+	select {}
+}
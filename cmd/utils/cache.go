@@ -0,0 +1,148 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"strconv"
+
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/cgroup"
+	"github.com/abeychain/go-abey/internal/debug"
+	"github.com/abeychain/go-abey/log"
+	"github.com/elastic/gosigar"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	fallbackCacheAllowance = 1024 // Megabytes to use if the system memory cannot be detected
+	minCacheAllowance      = 256  // Lower bound of an automatically sized cache allowance
+	maxCacheAllowance      = 8192 // Upper bound of an automatically sized cache allowance
+)
+
+// systemMemory is the memory available to the process, as detected on startup.
+type systemMemory struct {
+	total     uint64 // Physical memory of the host
+	available uint64 // Memory not used by other processes (including reclaimable caches)
+	limit     uint64 // Memory limit of the cgroup, zero if unlimited or above the host memory
+}
+
+var (
+	detectedMemory *systemMemory // System memory probed by SetupCache, nil if unavailable
+	autoSizedCache bool          // Whether the cache allowance was derived from the system memory
+)
+
+// detectMemory probes the host memory and the limit of the enclosing cgroup.
+func detectMemory() (*systemMemory, error) {
+	var mem gosigar.Mem
+	if err := mem.Get(); err != nil {
+		return nil, err
+	}
+	sys := &systemMemory{total: mem.Total, available: mem.ActualFree}
+	if limit, ok := cgroup.MemoryLimit(); ok && limit < mem.Total {
+		sys.limit = limit
+		if sys.available > limit {
+			sys.available = limit
+		}
+	}
+	return sys, nil
+}
+
+// usable returns the amount of memory the process may actually consume.
+func (m *systemMemory) usable() uint64 {
+	if m.limit != 0 {
+		return m.limit
+	}
+	return m.total
+}
+
+// MemoryStatus returns the memory the process may consume, bounded by the
+// cgroup limit, and the memory currently available to it, in bytes.
+func MemoryStatus() (usable uint64, available uint64, err error) {
+	mem, err := detectMemory()
+	if err != nil {
+		return 0, 0, err
+	}
+	return mem.usable(), mem.available, nil
+}
+
+// autoCacheAllowance derives a cache allowance in megabytes from the memory of
+// the system: a quarter of the usable memory, but no more than half of what is
+// currently available.
+func autoCacheAllowance(mem *systemMemory) int {
+	allowance := int(mem.usable() / 1024 / 1024 / 4)
+	if available := int(mem.available / 1024 / 1024 / 2); available < allowance {
+		allowance = available
+	}
+	if allowance < minCacheAllowance {
+		allowance = minCacheAllowance
+	}
+	if allowance > maxCacheAllowance {
+		allowance = maxCacheAllowance
+	}
+	return allowance
+}
+
+// SetupCache sizes the global cache allowance from the detected system memory
+// if none was requested, and caps a requested one to Go's GC limits.
+func SetupCache(ctx *cli.Context) {
+	cache := ctx.GlobalInt(CacheFlag.Name)
+
+	mem, err := detectMemory()
+	if err != nil {
+		log.Warn("Failed to detect system memory", "err", err)
+		if cache == 0 {
+			autoSizedCache = true
+			ctx.GlobalSet(CacheFlag.Name, strconv.Itoa(fallbackCacheAllowance))
+		}
+		return
+	}
+	detectedMemory = mem
+	if mem.limit != 0 {
+		log.Info("Detected cgroup memory limit", "limit", common.StorageSize(mem.limit), "host", common.StorageSize(mem.total))
+	}
+	switch allowance := int(mem.usable() / 1024 / 1024 / 3); {
+	case cache == 0:
+		cache, autoSizedCache = autoCacheAllowance(mem), true
+		if cache > allowance {
+			cache = allowance
+		}
+		log.Info("Sized cache from system memory", "usable", common.StorageSize(mem.usable()), "available", common.StorageSize(mem.available), "cache", cache)
+	case cache > allowance:
+		log.Warn("Sanitizing cache to Go's GC limits", "provided", cache, "updated", allowance)
+		cache = allowance
+	}
+	ctx.GlobalSet(CacheFlag.Name, strconv.Itoa(cache))
+}
+
+// publishCacheAllocation logs the final split of the cache allowance and makes
+// it queryable through debug_cacheAllocation.
+func publishCacheAllocation(ctx *cli.Context, cfg *abey.Config) {
+	alloc := debug.CacheAllocation{
+		Auto:       autoSizedCache,
+		Allowance:  ctx.GlobalInt(CacheFlag.Name),
+		Database:   cfg.DatabaseCache,
+		Trie:       cfg.TrieCache,
+		Downloader: cfg.DownloaderCache,
+	}
+	if mem := detectedMemory; mem != nil {
+		alloc.SystemMemory, alloc.AvailableMemory, alloc.CgroupLimit = mem.total, mem.available, mem.limit
+	}
+	debug.SetCacheAllocation(alloc)
+
+	log.Info("Allocated cache", "allowance", alloc.Allowance, "database", alloc.Database, "trie", alloc.Trie, "downloader", alloc.Downloader)
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/internal/debug"
+	"github.com/abeychain/go-abey/internal/servicemgr"
 	"github.com/abeychain/go-abey/node"
 )
 
@@ -67,12 +68,19 @@ func StartNode(stack *node.Node) {
 	if err := stack.Start(); err != nil {
 		Fatalf("Error starting protocol stack: %v", err)
 	}
+	servicemgr.Ready()
+
 	go func() {
 		sigc := make(chan os.Signal, 1)
 		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
 		defer signal.Stop(sigc)
-		<-sigc
-		log.Info("Got interrupt, shutting down...")
+		select {
+		case sig := <-sigc:
+			log.Info("Got interrupt, shutting down...", "signal", sig)
+		case <-servicemgr.Shutdown():
+			log.Info("Service stop requested, shutting down...")
+		}
+		servicemgr.Stopping()
 		go stack.Stop()
 		for i := 10; i > 0; i-- {
 			<-sigc
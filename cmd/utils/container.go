@@ -0,0 +1,90 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abeychain/go-abey/common/cgroup"
+	"github.com/abeychain/go-abey/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Environment variables consulted for secrets when running containerized. The
+// *_FILE variants point to a file (e.g. a mounted docker secret) holding the value.
+const (
+	PasswordEnv     = "ABEY_PASSWORD"
+	PasswordFileEnv = "ABEY_PASSWORD_FILE"
+	BftKeyEnv       = "ABEY_BFTKEY"
+	BftKeyFileEnv   = "ABEY_BFTKEY_FILE"
+)
+
+// containerHost is the interface RPC endpoints bind to in containerized mode.
+const containerHost = "0.0.0.0"
+
+// isContainerized reports whether the node was started with --containerized.
+func isContainerized(ctx *cli.Context) bool {
+	return ctx.GlobalBool(ContainerizedFlag.Name)
+}
+
+// containerRPCHost returns the listening interface for an enabled RPC endpoint
+// in containerized mode. Binding every interface is only safe if the process
+// lives in its own network namespace, otherwise the loopback default is kept.
+func containerRPCHost(endpoint string) string {
+	if !cgroup.InContainer() {
+		log.Warn("Containerized mode outside of a container, keeping loopback", "endpoint", endpoint)
+		return "127.0.0.1"
+	}
+	return containerHost
+}
+
+// readSecret retrieves a secret from the environment, either directly from the
+// value variable or from the file the file variable points to. The boolean is
+// false if neither variable is set.
+func readSecret(valueEnv, fileEnv string) (string, bool) {
+	if path := os.Getenv(fileEnv); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			Fatalf("Failed to read secret file %s: %v", fileEnv, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true
+	}
+	if value, ok := os.LookupEnv(valueEnv); ok {
+		return value, true
+	}
+	return "", false
+}
+
+// ensureWritableDir creates the data directory if needed and verifies that the
+// current (possibly unprivileged) user can write into it. Volumes mounted into
+// containers are frequently owned by root, which would otherwise only surface
+// as an obscure database error much later.
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("cannot create data directory %s (uid %d): %v", dir, os.Getuid(), err)
+	}
+	probe, err := ioutil.TempFile(dir, ".writable")
+	if err != nil {
+		return fmt.Errorf("data directory %s is not writable by uid %d, fix the volume ownership: %v", dir, os.Getuid(), err)
+	}
+	probe.Close()
+	return os.Remove(filepath.Clean(probe.Name()))
+}
@@ -61,6 +61,9 @@ import (
 	"github.com/abeychain/go-abey/p2p/nat"
 	"github.com/abeychain/go-abey/p2p/netutil"
 	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/rpc"
+	"github.com/abeychain/go-abey/telemetry"
+	"github.com/abeychain/go-abey/tracing"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -138,6 +141,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ReadOnlyFlag = cli.BoolFlag{
+		Name:  "readonly",
+		Usage: "Open the database read-only and serve RPC only, without mining, transaction submission or peer-to-peer sync",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier",
@@ -168,6 +175,10 @@ var (
 		Name:  "light",
 		Usage: "Enable light client mode (replaced by --syncmode)",
 	}*/
+	ContainerizedFlag = cli.BoolFlag{
+		Name:  "containerized",
+		Usage: "Container friendly defaults: IPC off, RPC on all interfaces inside a network namespace, secrets from " + PasswordEnv + "/" + BftKeyEnv + "(_FILE)",
+	}
 	//SingleNodeFlag is single node setting
 	SingleNodeFlag = cli.BoolFlag{
 		Name:  "singlenode",
@@ -203,6 +214,23 @@ var (
 		Name:  "bftkeyhex",
 		Usage: "committee generate bft_privatekey as hex (for testing)",
 	}
+	BFTSignMsgsFlag = cli.BoolFlag{
+		Name:  "bftsign",
+		Usage: "Sign consensus messages sent to the committee",
+	}
+	BFTRequireSignedMsgsFlag = cli.BoolFlag{
+		Name:  "bftrequiresigned",
+		Usage: "Reject consensus messages that are not signed by a committee member (implies --bftsign)",
+	}
+	BFTAlertURLFlag = cli.StringFlag{
+		Name:  "bftalerturl",
+		Usage: "Webhook notified when the committee round participation drops below the threshold",
+	}
+	BFTAlertThresholdFlag = cli.Float64Flag{
+		Name:  "bftalertthreshold",
+		Usage: "Share of the recent fast blocks a committee member must sign before alerting",
+		Value: abey.DefaultConfig.CommitteeAlertThreshold,
+	}
 
 	defaultSyncMode = abey.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
@@ -210,6 +238,11 @@ var (
 		Usage: `Blockchain sync mode ("full", or "snapshot")`,
 		Value: &defaultSyncMode,
 	}
+	SyncStallTimeoutFlag = cli.DurationFlag{
+		Name:  "sync.stalltimeout",
+		Usage: "Time without sync progress after which the session is restarted with another peer (0 = disabled)",
+		Value: abey.DefaultConfig.SyncStallTimeout,
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
@@ -219,6 +252,39 @@ var (
 		Name:  "stategc",
 		Usage: "Delete block body and receipt",
 	}
+	SnailFinalityFlag = cli.Uint64Flag{
+		Name:  "snail.finality",
+		Usage: "Number of snail blocks below the head that may not be reorganised (0 = disabled)",
+		Value: abey.DefaultConfig.SnailFinality,
+	}
+	CrashRestartFlag = cli.BoolFlag{
+		Name:  "crash.restart",
+		Usage: "Restart subsystems that can be safely restarted after recovering from a panic",
+	}
+	NTPServerFlag = cli.StringFlag{
+		Name:  "ntp.server",
+		Usage: `NTP server to check the system clock drift against ("" = disabled)`,
+		Value: abey.DefaultConfig.NTPServer,
+	}
+	NetCaptureFlag = cli.StringFlag{
+		Name:  "netcapture",
+		Usage: "File to record the protocol messages received from peers into, for deterministic replay in tests",
+	}
+	DiskWarnDaysFlag = cli.IntFlag{
+		Name:  "disk.warndays",
+		Usage: "Warn when the disk is projected to run full within this many days (0 = disabled)",
+		Value: abey.DefaultConfig.DiskWarnDays,
+	}
+	FruitLagBlocksFlag = cli.Uint64Flag{
+		Name:  "fruitlag.blocks",
+		Usage: "Alert when the latest fruit in the snail chain lags this many fast blocks behind the head (0 = disabled)",
+		Value: abey.DefaultConfig.FruitLagBlocks,
+	}
+	FruitLagTimeFlag = cli.DurationFlag{
+		Name:  "fruitlag.time",
+		Usage: "Alert when the latest fruit in the snail chain lags this much fast block time behind the head (0 = disabled)",
+		Value: abey.DefaultConfig.FruitLagTime,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -229,6 +295,15 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: abey.DefaultConfig.LightPeers,
 	}
+	ULCTrustedServersFlag = cli.StringFlag{
+		Name:  "ulc.servers",
+		Usage: "Comma separated enode URLs of trusted les servers, enables the ultra light client mode",
+	}
+	ULCMinTrustedFractionFlag = cli.IntFlag{
+		Name:  "ulc.fraction",
+		Usage: "Minimum percentage of trusted les servers that must announce a head before it is accepted (1-100)",
+		Value: abey.DefaultULCMinTrustedFraction,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -283,6 +358,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: abey.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolTxLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.txlifetime",
+		Usage: "Maximum amount of time non-local transactions stay in the pool before being swept (0 = unlimited)",
+		Value: abey.DefaultConfig.TxPool.TxLifetime,
+	}
 	//fruit pool settings
 	SnailPoolJournalFlag = cli.StringFlag{
 		Name:  "fruitpool.journal",
@@ -302,19 +382,38 @@ var (
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
-		Usage: "Megabytes of memory allocated to internal caching",
-		Value: 1024,
+		Usage: "Megabytes of memory allocated to internal caching (0 = size from system memory)",
+		Value: 0,
 	}
 	CacheDatabaseFlag = cli.IntFlag{
 		Name:  "cache.database",
 		Usage: "Percentage of cache memory allowance to use for database io",
-		Value: 75,
+		Value: 65,
 	}
 	CacheGCFlag = cli.IntFlag{
 		Name:  "cache.gc",
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheDownloaderFlag = cli.IntFlag{
+		Name:  "cache.downloader",
+		Usage: "Percentage of cache memory allowance to use for downloader block buffers",
+		Value: 10,
+	}
+	ProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: `Hardware profile tuning the defaults ("lowpower" for Raspberry Pi-class devices)`,
+	}
+	CacheMemoryLimitFlag = cli.IntFlag{
+		Name:  "cache.memlimit",
+		Usage: "Megabytes of memory the node may use before shedding caches (0 = detect from system memory)",
+		Value: 0,
+	}
+	CacheRPCFlag = cli.IntFlag{
+		Name:  "cache.rpc",
+		Usage: "Megabytes of memory allocated to caching RPC responses about final chain data (0 = disabled)",
+		Value: 0,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -372,6 +471,10 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	CoinbaseProofFlag = cli.BoolFlag{
+		Name:  "coinbaseproof",
+		Usage: "Sign mined fruits and snail blocks with the unlocked coinbase key to prove their ownership",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -383,6 +486,18 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scryptn",
+		Usage: "Scrypt N parameter used to encrypt keys (0 = default)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scryptp",
+		Usage: "Scrypt P parameter used to encrypt keys (0 = default)",
+	}
+	KeyCacheTTLFlag = cli.DurationFlag{
+		Name:  "keystore.cachettl",
+		Usage: "Time decrypted keys stay cached after unlock to skip the scrypt KDF (0 = disabled)",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
@@ -393,6 +508,24 @@ var (
 		Name:  "abeystats",
 		Usage: "Reporting URL of a abeystats service (nodename:secret@host:port)",
 	}
+	EthstatsURLFlag = cli.StringFlag{
+		Name:  "ethstats",
+		Usage: "Reporting URL of an ethstats compatible netstats service (nodename:secret@host:port)",
+	}
+	TelemetryEndpointFlag = cli.StringFlag{
+		Name:  "telemetry",
+		Usage: "Opt in to reporting anonymized node stats signed by the node key to this endpoint",
+	}
+	TelemetryFormatFlag = cli.StringFlag{
+		Name:  "telemetry.format",
+		Usage: `Telemetry endpoint format ("json" or "ethstats" with nodename:secret@host:port)`,
+		Value: telemetry.DefaultConfig.Format,
+	}
+	TelemetryIntervalFlag = cli.DurationFlag{
+		Name:  "telemetry.interval",
+		Usage: "Time between two telemetry reports",
+		Value: telemetry.DefaultConfig.Interval,
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -431,6 +564,36 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCAPIKeysFlag = cli.StringFlag{
+		Name:  "rpcapikeys",
+		Usage: "Comma separated list of API keys accepted by the HTTP-RPC server (X-API-Key header or apikey query parameter)",
+		Value: "",
+	}
+	RPCQuotasFlag = cli.StringFlag{
+		Name:  "rpcquotas",
+		Usage: "Comma separated method=calls quotas per API key and period of the HTTP-RPC server, '*' for all other methods",
+		Value: "",
+	}
+	RPCQuotaPeriodFlag = cli.DurationFlag{
+		Name:  "rpcquotaperiod",
+		Usage: "Length of the HTTP-RPC quota period",
+		Value: rpc.DefaultQuotaPeriod,
+	}
+	RPCGlobalEVMTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.evmtimeout",
+		Usage: "Sets a timeout used for abey_call, abey_estimateGas and tracing requests (0 = infinite)",
+		Value: abey.DefaultConfig.RPCEVMTimeout,
+	}
+	RPCGlobalGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.gascap",
+		Usage: "Sets a cap on gas that can be used in abey_call/estimateGas (0 = infinite)",
+		Value: abey.DefaultConfig.RPCGasCap,
+	}
+	RPCStateReadLimitFlag = cli.Uint64Flag{
+		Name:  "rpc.statereads",
+		Usage: "Sets a cap on state reads (SLOAD, BALANCE, EXTCODE* and calls) in abey_call/estimateGas (0 = infinite)",
+		Value: abey.DefaultConfig.RPCStateReadLimit,
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -463,6 +626,21 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSMaxConnectionsFlag = cli.IntFlag{
+		Name:  "wsmaxconns",
+		Usage: "Maximum number of concurrent WS-RPC clients (0 = unlimited)",
+		Value: node.DefaultConfig.WSMaxConnections,
+	}
+	WSMaxSubscriptionsFlag = cli.IntFlag{
+		Name:  "wsmaxsubs",
+		Usage: "Maximum number of subscriptions per WS-RPC client (0 = unlimited)",
+		Value: node.DefaultConfig.WSMaxSubscriptions,
+	}
+	WSSendQueueFlag = cli.IntFlag{
+		Name:  "wssendqueue",
+		Usage: "Notifications queued per WS-RPC client before evicting it as too slow (0 = block instead)",
+		Value: node.DefaultConfig.WSSendQueue,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -586,6 +764,15 @@ var (
 		Usage: "InfluxDB `host` tag attached to all measurements",
 		Value: "localhost",
 	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "OpenTelemetry collector OTLP/HTTP endpoint to export request traces to (e.g. http://localhost:4318)",
+	}
+	TracingSampleFlag = cli.Float64Flag{
+		Name:  "tracing.sample",
+		Usage: "Ratio of new traces recorded, between 0 and 1",
+		Value: 1,
+	}
 
 	EWASMInterpreterFlag = cli.StringFlag{
 		Name:  "vm.ewasm",
@@ -653,6 +840,12 @@ func setBftCommitteeKey(ctx *cli.Context, cfg *abey.Config) {
 		err  error
 	)
 	log.Debug("", "file:", file, "hex:", hex)
+	if file == "" && hex == "" && isContainerized(ctx) {
+		file = os.Getenv(BftKeyFileEnv)
+		if file == "" {
+			hex = os.Getenv(BftKeyEnv)
+		}
+	}
 	switch {
 	case file != "" && hex != "":
 		Fatalf("Options %q and %q are mutually exclusive", BftKeyFileFlag.Name, BftKeyHexFlag.Name)
@@ -764,6 +957,8 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 		cfg.HTTPHost = "127.0.0.1"
 		if ctx.GlobalIsSet(RPCListenAddrFlag.Name) {
 			cfg.HTTPHost = ctx.GlobalString(RPCListenAddrFlag.Name)
+		} else if isContainerized(ctx) {
+			cfg.HTTPHost = containerRPCHost("http")
 		}
 	}
 
@@ -784,6 +979,26 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCAPIKeysFlag.Name) {
+		cfg.HTTPAPIKeys = splitAndTrim(ctx.GlobalString(RPCAPIKeysFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCQuotasFlag.Name) {
+		cfg.HTTPQuotas = make(map[string]uint64)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCQuotasFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid RPC quota %q, want method=calls", entry)
+			}
+			calls, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				Fatalf("Invalid RPC quota %q: %v", entry, err)
+			}
+			cfg.HTTPQuotas[parts[0]] = calls
+		}
+	}
+	if ctx.GlobalIsSet(RPCQuotaPeriodFlag.Name) {
+		cfg.HTTPQuotaPeriod = ctx.GlobalDuration(RPCQuotaPeriodFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -793,6 +1008,8 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 		cfg.WSHost = "127.0.0.1"
 		if ctx.GlobalIsSet(WSListenAddrFlag.Name) {
 			cfg.WSHost = ctx.GlobalString(WSListenAddrFlag.Name)
+		} else if isContainerized(ctx) {
+			cfg.WSHost = containerRPCHost("ws")
 		}
 	}
 
@@ -805,6 +1022,15 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSMaxConnectionsFlag.Name) {
+		cfg.WSMaxConnections = ctx.GlobalInt(WSMaxConnectionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSMaxSubscriptionsFlag.Name) {
+		cfg.WSMaxSubscriptions = ctx.GlobalInt(WSMaxSubscriptionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSSendQueueFlag.Name) {
+		cfg.WSSendQueue = ctx.GlobalInt(WSSendQueueFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -816,6 +1042,8 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 		cfg.IPCPath = ""
 	case ctx.GlobalIsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.GlobalString(IPCPathFlag.Name)
+	case isContainerized(ctx):
+		cfg.IPCPath = ""
 	}
 }
 
@@ -876,17 +1104,30 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *abey.Config) {
 	}
 }
 
-// MakePasswordList reads password lines from the file specified by the global --password flag.
+// MakePasswordList reads password lines from the file specified by the global --password flag,
+// falling back to the ABEY_PASSWORD(_FILE) environment in containerized mode.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
 	if path == "" {
-		return nil
+		if !isContainerized(ctx) {
+			return nil
+		}
+		secret, ok := readSecret(PasswordEnv, PasswordFileEnv)
+		if !ok {
+			return nil
+		}
+		return splitPasswords(secret)
 	}
 	text, err := ioutil.ReadFile(path)
 	if err != nil {
 		Fatalf("Failed to read password file: %v", err)
 	}
-	lines := strings.Split(string(text), "\n")
+	return splitPasswords(string(text))
+}
+
+// splitPasswords breaks the contents of a password file into individual lines.
+func splitPasswords(text string) []string {
+	lines := strings.Split(text, "\n")
 	// Sanitise DOS line endings.
 	for i := range lines {
 		lines[i] = strings.TrimRight(lines[i], "\r")
@@ -901,7 +1142,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setBootstrapNodes(ctx, cfg)
 	setBootstrapNodesV5(ctx, cfg)
 
-	lightClient := ctx.GlobalString(SyncModeFlag.Name) == "light"
+	lightClient := ctx.GlobalString(SyncModeFlag.Name) == "light" || ctx.GlobalIsSet(ULCTrustedServersFlag.Name)
 	lightServer := ctx.GlobalInt(LightServFlag.Name) != 0
 	lightPeers := ctx.GlobalInt(LightPeersFlag.Name)
 
@@ -975,12 +1216,27 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalBool(SingleNodeFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
 	}
+	cfg.ReadOnly = ctx.GlobalBool(ReadOnlyFlag.Name)
+	if isContainerized(ctx) && cfg.DataDir != "" && !cfg.ReadOnly {
+		if err := ensureWritableDir(cfg.DataDir); err != nil {
+			Fatalf("Invalid data directory: %v", err)
+		}
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyCacheTTLFlag.Name) {
+		cfg.KeyCacheTTL = ctx.GlobalDuration(KeyCacheTTLFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
@@ -995,6 +1251,32 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+// setULC creates the ultra light client configuration from the command line
+// flags, the mode implies light sync.
+func setULC(ctx *cli.Context, cfg *abey.Config) {
+	if !ctx.GlobalIsSet(ULCTrustedServersFlag.Name) {
+		return
+	}
+	var servers []string
+	for _, url := range strings.Split(ctx.GlobalString(ULCTrustedServersFlag.Name), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			servers = append(servers, url)
+		}
+	}
+	if len(servers) == 0 {
+		Fatalf("Option %q: no trusted servers given", ULCTrustedServersFlag.Name)
+	}
+	fraction := ctx.GlobalInt(ULCMinTrustedFractionFlag.Name)
+	if fraction <= 0 || fraction > 100 {
+		Fatalf("Option %q: must be in range 1-100", ULCMinTrustedFractionFlag.Name)
+	}
+	cfg.ULC = &abey.ULCConfig{
+		TrustedServers:     servers,
+		MinTrustedFraction: fraction,
+	}
+	cfg.SyncMode = downloader.LightSync
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
@@ -1026,10 +1308,13 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolTxLifetimeFlag.Name) {
+		cfg.TxLifetime = ctx.GlobalDuration(TxPoolTxLifetimeFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *abey.Config) {
-
+	setProfile(ctx, cfg)
 }
 
 func setSnailPool(ctx *cli.Context, cfg *snailchain.SnailPoolConfig) {
@@ -1102,6 +1387,19 @@ func SetAbeychainConfig(ctx *cli.Context, stack *node.Node, cfg *abey.Config) {
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.GlobalIsSet(SyncStallTimeoutFlag.Name) {
+		cfg.SyncStallTimeout = ctx.GlobalDuration(SyncStallTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGlobalEVMTimeoutFlag.Name) {
+		cfg.RPCEVMTimeout = ctx.GlobalDuration(RPCGlobalEVMTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGlobalGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCStateReadLimitFlag.Name) {
+		cfg.RPCStateReadLimit = ctx.GlobalUint64(RPCStateReadLimitFlag.Name)
+	}
+	setULC(ctx, cfg)
 
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
@@ -1112,6 +1410,10 @@ func SetAbeychainConfig(ctx *cli.Context, stack *node.Node, cfg *abey.Config) {
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalBool(ReadOnlyFlag.Name) {
+		cfg.ReadOnly = true
+		cfg.LightServ = 0
+	}
 
 	if ctx.GlobalBool(MineFruitFlag.Name) {
 		cfg.MineFruit = true
@@ -1134,6 +1436,18 @@ func SetAbeychainConfig(ctx *cli.Context, stack *node.Node, cfg *abey.Config) {
 	if ctx.GlobalIsSet(BFTStandbyPortFlag.Name) {
 		cfg.StandbyPort = int(ctx.GlobalUint64(BFTStandbyPortFlag.Name))
 	}
+	if ctx.GlobalIsSet(BFTSignMsgsFlag.Name) {
+		cfg.BFTSignMsgs = ctx.GlobalBool(BFTSignMsgsFlag.Name)
+	}
+	if ctx.GlobalIsSet(BFTRequireSignedMsgsFlag.Name) {
+		cfg.BFTRequireSignedMsgs = ctx.GlobalBool(BFTRequireSignedMsgsFlag.Name)
+	}
+	if ctx.GlobalIsSet(BFTAlertURLFlag.Name) {
+		cfg.CommitteeAlertURL = ctx.GlobalString(BFTAlertURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(BFTAlertThresholdFlag.Name) {
+		cfg.CommitteeAlertThreshold = ctx.GlobalFloat64(BFTAlertThresholdFlag.Name)
+	}
 
 	//set PrivateKey by config,file or hex
 	setBftCommitteeKey(ctx, cfg)
@@ -1181,10 +1495,39 @@ func SetAbeychainConfig(ctx *cli.Context, stack *node.Node, cfg *abey.Config) {
 	if ctx.GlobalIsSet(StateGCFlag.Name) || cfg.SyncMode == downloader.SnapShotSync {
 		cfg.DeletedState = true
 	}
+	if ctx.GlobalIsSet(SnailFinalityFlag.Name) {
+		cfg.SnailFinality = ctx.GlobalUint64(SnailFinalityFlag.Name)
+	}
+	if ctx.GlobalIsSet(NTPServerFlag.Name) {
+		cfg.NTPServer = ctx.GlobalString(NTPServerFlag.Name)
+	}
+	if ctx.GlobalIsSet(NetCaptureFlag.Name) {
+		cfg.NetCapture = ctx.GlobalString(NetCaptureFlag.Name)
+	}
+	if ctx.GlobalIsSet(DiskWarnDaysFlag.Name) {
+		cfg.DiskWarnDays = ctx.GlobalInt(DiskWarnDaysFlag.Name)
+	}
+	if ctx.GlobalIsSet(FruitLagBlocksFlag.Name) {
+		cfg.FruitLagBlocks = ctx.GlobalUint64(FruitLagBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(FruitLagTimeFlag.Name) {
+		cfg.FruitLagTime = ctx.GlobalDuration(FruitLagTimeFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDownloaderFlag.Name) {
+		cfg.DownloaderCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDownloaderFlag.Name) / 100
+	}
+	if ctx.GlobalIsSet(CacheMemoryLimitFlag.Name) {
+		cfg.MemoryLimit = ctx.GlobalInt(CacheMemoryLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheRPCFlag.Name) {
+		cfg.RPCCache = ctx.GlobalInt(CacheRPCFlag.Name)
+	}
+	publishCacheAllocation(ctx, cfg)
+
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
@@ -1194,6 +1537,9 @@ func SetAbeychainConfig(ctx *cli.Context, stack *node.Node, cfg *abey.Config) {
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(CoinbaseProofFlag.Name) {
+		cfg.CoinbaseProof = ctx.GlobalBool(CoinbaseProofFlag.Name)
+	}
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
@@ -1271,6 +1617,35 @@ func RegisterAbeystatsService(stack *node.Node, url string) {
 	}
 }
 
+// SetTelemetryConfig applies telemetry related command line flags to the config.
+func SetTelemetryConfig(ctx *cli.Context, cfg *telemetry.Config) {
+	if ctx.GlobalIsSet(TelemetryEndpointFlag.Name) {
+		cfg.Endpoint = ctx.GlobalString(TelemetryEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(TelemetryFormatFlag.Name) {
+		cfg.Format = ctx.GlobalString(TelemetryFormatFlag.Name)
+	}
+	if ctx.GlobalIsSet(TelemetryIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(TelemetryIntervalFlag.Name)
+	}
+}
+
+// RegisterTelemetryService configures the opt-in telemetry reporter and adds
+// it to the given node.
+func RegisterTelemetryService(stack *node.Node, cfg telemetry.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var abeyServ *abey.Abeychain
+		ctx.Service(&abeyServ)
+
+		var lesServ *les.LightAbey
+		ctx.Service(&lesServ)
+
+		return telemetry.New(cfg, ctx.ResolvePath(telemetry.SaltFile), abeyServ, lesServ)
+	}); err != nil {
+		Fatalf("Failed to register the telemetry service: %v", err)
+	}
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -1292,6 +1667,17 @@ func SetupMetrics(ctx *cli.Context) {
 	}
 }
 
+// SetupTracing enables request tracing if a collector endpoint is configured.
+func SetupTracing(ctx *cli.Context) {
+	endpoint := ctx.GlobalString(TracingEndpointFlag.Name)
+	if endpoint == "" {
+		return
+	}
+	if err := tracing.Setup(endpoint, ctx.GlobalFloat64(TracingSampleFlag.Name), "gabey"); err != nil {
+		Fatalf("Failed to enable tracing: %v", err)
+	}
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) abeydb.Database {
 	var (
@@ -0,0 +1,131 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// KeyCommand groups the key generation and address conversion tools, shared
+	// by the node binary and the standalone genKey wrapper.
+	KeyCommand = cli.Command{
+		Name:     "key",
+		Usage:    "Generate keys and convert addresses",
+		Category: "KEY COMMANDS",
+		Subcommands: []cli.Command{
+			keyGenerateCommand,
+			keyConvertCommand,
+		},
+	}
+
+	keyGenerateCommand = cli.Command{
+		Name:      "generate",
+		Usage:     "Generate new key item",
+		ArgsUsage: "",
+		Description: `
+Generate a new key item.
+`,
+		Flags: []cli.Flag{
+			cli.IntFlag{
+				Name:  "sum",
+				Usage: "key info count",
+				Value: 1,
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			count := ctx.Int("sum")
+			if count <= 0 || count > 100 {
+				count = 100
+			}
+			GenerateKeys(count)
+
+			return nil
+		},
+	}
+
+	keyConvertCommand = cli.Command{
+		Name:        "convert",
+		Usage:       "Convert between abey address and hex address",
+		Description: "Convert between abey address and hex address",
+		Subcommands: []cli.Command{
+			{
+				Name:  "hex",
+				Usage: "Convert hex address to abey address",
+				Action: func(c *cli.Context) error {
+					hexAddress := c.Args().First()
+					if hexAddress == "" {
+						return cli.NewExitError("please check the input args", -1)
+					}
+					fmt.Println("abey address: ", HexToAbey(hexAddress))
+					return nil
+				},
+			},
+			{
+				Name:  "abey",
+				Usage: "Convert abey address to hex address",
+				Action: func(c *cli.Context) error {
+					abeyAddress := c.Args().First()
+					if abeyAddress == "" {
+						return cli.NewExitError("please check the input args", -1)
+					}
+					hexAddress, err := AbeyToHex(abeyAddress)
+					if err != nil {
+						return cli.NewExitError(err.Error(), -1)
+					}
+					fmt.Println("hex address: ", hexAddress)
+					return nil
+				},
+			},
+		},
+	}
+)
+
+// GenerateKeys generates count keys and prints them with their addresses.
+func GenerateKeys(count int) {
+	for i := 0; i < count; i++ {
+		if privateKey, err := crypto.GenerateKey(); err != nil {
+			Fatalf("Error GenerateKey: %v", err)
+		} else {
+			fmt.Println("private key:", hex.EncodeToString(crypto.FromECDSA(privateKey)))
+			fmt.Println("public key:", hex.EncodeToString(crypto.FromECDSAPub(&privateKey.PublicKey)))
+			addr := crypto.PubkeyToAddress(privateKey.PublicKey)
+			fmt.Println("address-0x: ", addr.String())
+			fmt.Println("address-abey: ", HexToAbey(addr.String()))
+			fmt.Println("-------------------------------------------------------")
+		}
+	}
+}
+
+// HexToAbey converts a hex address into its abey representation.
+func HexToAbey(hex string) string {
+	return common.HexToAddress(hex).StringToAbey()
+}
+
+// AbeyToHex converts an abey address into its hex representation.
+func AbeyToHex(abey string) (string, error) {
+	a := common.Address{}
+	if err := a.FromAbeyString(abey); err != nil {
+		return "", err
+	}
+	return a.Hex(), nil
+}
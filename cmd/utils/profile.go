@@ -0,0 +1,94 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// ProfileLowPower tunes the node for Raspberry Pi-class hardware: 4 ARM64
+// cores, 2-4GB of memory and storage on a USB SSD. The values keep a full node
+// syncing there (see `make bench-lowpower` to measure the seal verification
+// throughput of a device) while leaving a quarter of the memory of a 2GB board
+// to the operating system.
+const ProfileLowPower = "lowpower"
+
+// profile is a set of tuned defaults for a class of hardware.
+type profile struct {
+	flags map[string]string // Defaults of the flags not explicitly set
+
+	datasetsInMem int // Minerva datasets kept in memory, two cover an epoch switch
+	verifyWorkers int // Concurrent header verifiers
+}
+
+var profiles = map[string]*profile{
+	ProfileLowPower: {
+		flags: map[string]string{
+			CacheFlag.Name:             "256",
+			MaxPeersFlag.Name:          "12",
+			MaxPendingPeersFlag.Name:   "4",
+			TxPoolGlobalSlotsFlag.Name: "4096",
+			TxPoolGlobalQueueFlag.Name: "1024",
+		},
+		datasetsInMem: 2,
+		verifyWorkers: 2,
+	},
+}
+
+// profileNames returns the names of the known profiles.
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetupProfile applies the defaults of the requested hardware profile to the
+// flags not explicitly set. It must run before the cache allowance is sized.
+func SetupProfile(ctx *cli.Context) {
+	name := ctx.GlobalString(ProfileFlag.Name)
+	if name == "" {
+		return
+	}
+	p, ok := profiles[name]
+	if !ok {
+		Fatalf("Unknown --%s %q, available: %s", ProfileFlag.Name, name, strings.Join(profileNames(), ", "))
+	}
+	for flag, value := range p.flags {
+		if !ctx.GlobalIsSet(flag) {
+			ctx.GlobalSet(flag, value)
+		}
+	}
+	log.Info("Applied hardware profile", "profile", name)
+}
+
+// setProfile applies the minerva settings of the requested hardware profile.
+func setProfile(ctx *cli.Context, cfg *abey.Config) {
+	p := profiles[ctx.GlobalString(ProfileFlag.Name)]
+	if p == nil {
+		return
+	}
+	cfg.MinervaHash.DatasetsInMem = p.datasetsInMem
+	cfg.MinervaHash.VerifyWorkers = p.verifyWorkers
+}
@@ -0,0 +1,291 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/rawdb"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+// stateDumpVersion is the version of the state dump format.
+const stateDumpVersion = 1
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// StateDumpHeader opens a state dump, identifying the block it was taken at.
+// It is followed by the accounts in the order of their hashed address, which
+// is the iteration order of the state trie and makes the dump of a state
+// byte-for-byte reproducible.
+type StateDumpHeader struct {
+	Version uint64
+	Number  uint64
+	Hash    common.Hash
+	Root    common.Hash
+}
+
+// StateDumpAccount is an account of a state dump with its code and storage.
+type StateDumpAccount struct {
+	Hash    common.Hash
+	Address []byte // Preimage of the hash, empty if unknown
+	Nonce   uint64
+	Balance *big.Int
+	Code    []byte
+	Storage []StateDumpSlot
+}
+
+// StateDumpSlot is a storage slot of an account in a state dump.
+type StateDumpSlot struct {
+	Hash  common.Hash
+	Key   []byte // Preimage of the hash, empty if unknown
+	Value []byte // RLP encoded value as stored in the trie
+}
+
+// ExportState writes the state at the given fast block as an RLP stream of a
+// StateDumpHeader followed by StateDumpAccounts, gzipped if the file name ends
+// with .gz.
+func ExportState(db abeydb.Database, header *types.Header, fn string) error {
+	log.Info("Exporting state", "number", header.Number, "root", header.Root, "file", fn)
+
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		gz := gzip.NewWriter(writer)
+		defer gz.Close()
+		writer = gz
+	}
+	accounts, err := writeState(writer, state.NewDatabase(db), header)
+	if err != nil {
+		return err
+	}
+	log.Info("Exported state", "accounts", accounts, "file", fn)
+	return nil
+}
+
+// writeState streams the state at header into w, returning the number of
+// accounts written.
+func writeState(w io.Writer, db state.Database, header *types.Header) (int, error) {
+	accTrie, err := db.OpenTrie(header.Root)
+	if err != nil {
+		return 0, err
+	}
+	if err := rlp.Encode(w, &StateDumpHeader{
+		Version: stateDumpVersion,
+		Number:  header.Number.Uint64(),
+		Hash:    header.Hash(),
+		Root:    header.Root,
+	}); err != nil {
+		return 0, err
+	}
+	var (
+		accounts int
+		logged   = time.Now()
+		it       = trie.NewIterator(accTrie.NodeIterator(nil))
+	)
+	for it.Next() {
+		var data state.Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return accounts, err
+		}
+		account := &StateDumpAccount{
+			Hash:    common.BytesToHash(it.Key),
+			Address: accTrie.GetKey(it.Key),
+			Nonce:   data.Nonce,
+			Balance: data.Balance,
+		}
+		if codeHash := common.BytesToHash(data.CodeHash); codeHash != emptyCodeHash {
+			if account.Code, err = db.ContractCode(account.Hash, codeHash); err != nil {
+				return accounts, fmt.Errorf("account %x: missing code: %v", account.Hash, err)
+			}
+		}
+		if data.Root != types.EmptyRootHash {
+			storageTrie, err := db.OpenStorageTrie(account.Hash, data.Root)
+			if err != nil {
+				return accounts, fmt.Errorf("account %x: missing storage: %v", account.Hash, err)
+			}
+			storageIt := trie.NewIterator(storageTrie.NodeIterator(nil))
+			for storageIt.Next() {
+				account.Storage = append(account.Storage, StateDumpSlot{
+					Hash:  common.BytesToHash(storageIt.Key),
+					Key:   storageTrie.GetKey(storageIt.Key),
+					Value: common.CopyBytes(storageIt.Value),
+				})
+			}
+			if storageIt.Err != nil {
+				return accounts, storageIt.Err
+			}
+		}
+		if err := rlp.Encode(w, account); err != nil {
+			return accounts, err
+		}
+		accounts++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state", "accounts", accounts)
+			logged = time.Now()
+		}
+	}
+	return accounts, it.Err
+}
+
+// ImportState rebuilds the state of a dump written by ExportState in db and
+// verifies it against the root recorded in the dump.
+func ImportState(db abeydb.Database, fn string) (*StateDumpHeader, error) {
+	log.Info("Importing state", "file", fn)
+
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	header, accounts, err := readState(reader, db)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Imported state", "number", header.Number, "root", header.Root, "accounts", accounts)
+	return header, nil
+}
+
+// readState rebuilds the state streamed from r in db, returning the dump
+// header and the number of accounts read.
+func readState(r io.Reader, db abeydb.Database) (*StateDumpHeader, int, error) {
+	stream := rlp.NewStream(r, 0)
+
+	header := new(StateDumpHeader)
+	if err := stream.Decode(header); err != nil {
+		return nil, 0, fmt.Errorf("invalid state dump header: %v", err)
+	}
+	if header.Version != stateDumpVersion {
+		return nil, 0, fmt.Errorf("unsupported state dump version %d", header.Version)
+	}
+	// The keys of the dump are hashed already, so the tries are rebuilt as
+	// plain ones and the known preimages stored separately
+	var (
+		triedb    = trie.NewDatabase(db)
+		preimages = make(map[common.Hash][]byte)
+		accounts  int
+		logged    = time.Now()
+	)
+	accTrie, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, 0, err
+	}
+	for {
+		account := new(StateDumpAccount)
+		if err := stream.Decode(account); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, accounts, fmt.Errorf("at account %d: %v", accounts, err)
+		}
+		data := state.Account{
+			Nonce:    account.Nonce,
+			Balance:  account.Balance,
+			Root:     types.EmptyRootHash,
+			CodeHash: emptyCodeHash.Bytes(),
+		}
+		if data.Balance == nil {
+			data.Balance = new(big.Int)
+		}
+		if len(account.Code) > 0 {
+			codeHash := crypto.Keccak256Hash(account.Code)
+			triedb.InsertBlob(codeHash, account.Code)
+			data.CodeHash = codeHash.Bytes()
+		}
+		if len(account.Storage) > 0 {
+			storageTrie, err := trie.New(common.Hash{}, triedb)
+			if err != nil {
+				return nil, accounts, err
+			}
+			for _, slot := range account.Storage {
+				if err := storageTrie.TryUpdate(slot.Hash[:], slot.Value); err != nil {
+					return nil, accounts, err
+				}
+				if len(slot.Key) > 0 {
+					preimages[slot.Hash] = slot.Key
+				}
+			}
+			if data.Root, err = storageTrie.Commit(nil); err != nil {
+				return nil, accounts, err
+			}
+			// Flush the storage to disk, keeping the memory bounded by the account trie
+			if err := triedb.Commit(data.Root, false); err != nil {
+				return nil, accounts, err
+			}
+		}
+		enc, err := rlp.EncodeToBytes(&data)
+		if err != nil {
+			return nil, accounts, err
+		}
+		if err := accTrie.TryUpdate(account.Hash[:], enc); err != nil {
+			return nil, accounts, err
+		}
+		if len(account.Address) > 0 {
+			preimages[account.Hash] = account.Address
+		}
+		accounts++
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state", "accounts", accounts)
+			logged = time.Now()
+		}
+	}
+	root, err := accTrie.Commit(func(leaf []byte, parent common.Hash) error {
+		var data state.Account
+		if err := rlp.DecodeBytes(leaf, &data); err != nil {
+			return nil
+		}
+		if code := common.BytesToHash(data.CodeHash); code != emptyCodeHash {
+			triedb.Reference(code, parent)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, accounts, err
+	}
+	if root != header.Root {
+		return nil, accounts, fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		return nil, accounts, err
+	}
+	rawdb.WritePreimages(db, header.Number, preimages)
+	return header, accounts, nil
+}
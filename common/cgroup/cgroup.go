@@ -0,0 +1,59 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package cgroup probes the control group and namespace the process runs in,
+// so that the node can pick sane defaults when started inside a container.
+package cgroup
+
+import (
+	"strconv"
+	"strings"
+)
+
+// unlimited is the threshold above which a cgroup memory limit is considered
+// to be unset. The kernel reports "no limit" as the largest page aligned int64.
+const unlimited = 1 << 62
+
+// containerMarkers are the substrings of /proc/1/cgroup entries that betray
+// a process running under a container runtime.
+var containerMarkers = []string{"docker", "kubepods", "containerd", "lxc", "libpod"}
+
+// parseLimit parses the contents of a cgroup v1 memory.limit_in_bytes or a
+// cgroup v2 memory.max file, returning false if no limit is in place.
+func parseLimit(data string) (uint64, bool) {
+	data = strings.TrimSpace(data)
+	if data == "" || data == "max" {
+		return 0, false
+	}
+	limit, err := strconv.ParseUint(data, 10, 64)
+	if err != nil || limit == 0 || limit >= unlimited {
+		return 0, false
+	}
+	return limit, true
+}
+
+// hasContainerMarker reports whether the contents of a /proc/<pid>/cgroup
+// file reference any known container runtime.
+func hasContainerMarker(data string) bool {
+	for _, line := range strings.Split(data, "\n") {
+		for _, marker := range containerMarkers {
+			if strings.Contains(line, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
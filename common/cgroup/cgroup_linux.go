@@ -0,0 +1,57 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// memoryLimitFiles are the locations of the memory limit of the current
+// cgroup, for the unified (v2) and the legacy (v1) hierarchies respectively.
+var memoryLimitFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// MemoryLimit returns the memory limit in bytes imposed on the process by its
+// cgroup. The boolean is false if no limit is configured or none could be read.
+func MemoryLimit() (uint64, bool) {
+	for _, path := range memoryLimitFiles {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		return parseLimit(string(data))
+	}
+	return 0, false
+}
+
+// InContainer reports whether the process is running inside a container, in
+// which case it lives in its own network and mount namespaces.
+func InContainer() bool {
+	for _, path := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	data, err := ioutil.ReadFile("/proc/1/cgroup")
+	if err != nil {
+		return false
+	}
+	return hasContainerMarker(string(data))
+}
@@ -0,0 +1,31 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux
+
+package cgroup
+
+// MemoryLimit returns the memory limit in bytes imposed on the process by its
+// cgroup. Control groups only exist on Linux, so this always reports no limit.
+func MemoryLimit() (uint64, bool) {
+	return 0, false
+}
+
+// InContainer reports whether the process is running inside a container. Only
+// Linux containers are detected.
+func InContainer() bool {
+	return false
+}
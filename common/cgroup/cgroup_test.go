@@ -0,0 +1,52 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package cgroup
+
+import "testing"
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		data  string
+		limit uint64
+		ok    bool
+	}{
+		{"max\n", 0, false},
+		{"", 0, false},
+		{"0", 0, false},
+		{"9223372036854771712\n", 0, false},
+		{"536870912\n", 536870912, true},
+		{"garbage", 0, false},
+	}
+	for i, tt := range tests {
+		limit, ok := parseLimit(tt.data)
+		if limit != tt.limit || ok != tt.ok {
+			t.Errorf("test %d: limit mismatch: have (%d, %v), want (%d, %v)", i, limit, ok, tt.limit, tt.ok)
+		}
+	}
+}
+
+func TestContainerMarker(t *testing.T) {
+	if !hasContainerMarker("12:memory:/docker/3f2a1b\n0::/\n") {
+		t.Errorf("docker cgroup not detected")
+	}
+	if !hasContainerMarker("0::/kubepods/burstable/pod1234\n") {
+		t.Errorf("kubernetes cgroup not detected")
+	}
+	if hasContainerMarker("0::/init.scope\n") {
+		t.Errorf("host cgroup detected as container")
+	}
+}
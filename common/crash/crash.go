@@ -0,0 +1,221 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package crash recovers panics of long running subsystems, writing crash
+// report bundles instead of taking down the whole node.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+)
+
+const (
+	logHistory  = 256 // Number of recent log records kept for the bundles
+	maxRestarts = 5   // Number of restarts of a subsystem before giving up
+)
+
+var (
+	restartWait = 3 * time.Second // Time to wait before restarting a crashed subsystem
+
+	lock    sync.Mutex
+	dir     string                                // Directory to write the bundles into, empty if disabled
+	restart bool                                  // Whether restartable subsystems are restarted
+	infos   = make(map[string]func() interface{}) // Node state providers included in the bundles
+	history *recorder                             // Recent log records
+)
+
+// Bundle is the crash report written for a recovered panic.
+type Bundle struct {
+	Module string                 `json:"module"`
+	Time   time.Time              `json:"time"`
+	Panic  string                 `json:"panic"`
+	Stack  string                 `json:"stack"`
+	Info   map[string]interface{} `json:"info"`
+	Logs   []string               `json:"logs"`
+}
+
+// recorder is a log handler keeping the most recent records.
+type recorder struct {
+	records []*log.Record
+	next    int
+	lock    sync.Mutex
+}
+
+func (r *recorder) Log(rec *log.Record) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.records) < logHistory {
+		r.records = append(r.records, rec)
+	} else {
+		r.records[r.next] = rec
+		r.next = (r.next + 1) % logHistory
+	}
+	return nil
+}
+
+// lines formats the recorded logs in chronological order.
+func (r *recorder) lines() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	format := log.TerminalFormat(false)
+	lines := make([]string, 0, len(r.records))
+	for i := range r.records {
+		rec := r.records[(r.next+i)%len(r.records)]
+		lines = append(lines, string(format.Format(rec)))
+	}
+	return lines
+}
+
+// Setup enables panic recovery, writing the crash bundles into the given
+// directory. Restartable subsystems are only restarted if requested.
+func Setup(bundleDir string, restartable bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if history == nil {
+		history = new(recorder)
+		log.Root().SetHandler(log.MultiHandler(log.Root().GetHandler(), log.LvlFilterHandler(log.LvlInfo, history)))
+	}
+	dir, restart = bundleDir, restartable
+}
+
+// RegisterInfo adds a provider of node state to be included in crash bundles.
+func RegisterInfo(name string, fn func() interface{}) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	infos[name] = fn
+}
+
+// Run executes a long running subsystem loop. If it panics, a crash bundle is
+// written and the subsystem is either restarted, if it is restartable and
+// restarts are enabled, or stopped, leaving the rest of the node running.
+// Without Setup panics are not recovered.
+func Run(module string, restartable bool, fn func()) {
+	for attempt := 1; ; attempt++ {
+		if !guard(module, fn) {
+			return
+		}
+		lock.Lock()
+		again := restartable && restart
+		lock.Unlock()
+
+		if !again || attempt > maxRestarts {
+			log.Error("Subsystem stopped after panic", "module", module, "restarts", attempt-1)
+			return
+		}
+		log.Warn("Restarting subsystem after panic", "module", module, "attempt", attempt)
+		time.Sleep(restartWait)
+	}
+}
+
+// guard runs fn, recovering and reporting a panic if crash bundles are enabled.
+func guard(module string, fn func()) (panicked bool) {
+	lock.Lock()
+	enabled := dir != ""
+	lock.Unlock()
+
+	if !enabled {
+		fn()
+		return false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			stack := string(debug.Stack())
+
+			log.Error("Subsystem panicked", "module", module, "err", r)
+			if path, err := write(module, r, stack); err != nil {
+				log.Error("Failed to write crash bundle", "module", module, "err", err)
+			} else {
+				log.Error("Crash bundle written, please report it to the developers", "path", path)
+			}
+		}
+	}()
+	fn()
+	return false
+}
+
+// write assembles and stores the crash bundle of a recovered panic.
+func write(module string, r interface{}, stack string) (string, error) {
+	lock.Lock()
+	bundleDir, logs := dir, history
+	fns := make(map[string]func() interface{}, len(infos))
+	for name, fn := range infos {
+		fns[name] = fn
+	}
+	lock.Unlock()
+
+	bundle := &Bundle{
+		Module: module,
+		Time:   time.Now(),
+		Panic:  fmt.Sprint(r),
+		Stack:  stack,
+		Info:   make(map[string]interface{}),
+	}
+	for name, fn := range fns {
+		bundle.Info[name] = collect(fn)
+	}
+	if logs != nil {
+		bundle.Logs = logs.lines()
+	}
+	blob, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(bundleDir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(bundleDir, fmt.Sprintf("%s-%d.json", sanitize(module), bundle.Time.UnixNano()))
+	return path, ioutil.WriteFile(path, blob, 0600)
+}
+
+// collect calls an info provider, which may itself fail on a broken node.
+func collect(fn func() interface{}) (info interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			info = fmt.Sprintf("unavailable: %v", r)
+		}
+	}()
+	// Encode right away, so one bad provider can't void the whole bundle
+	blob, err := json.Marshal(fn())
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v", err)
+	}
+	return json.RawMessage(blob)
+}
+
+// sanitize makes a module name usable as file name.
+func sanitize(module string) string {
+	name := []byte(module)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
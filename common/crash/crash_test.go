@@ -0,0 +1,79 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package crash
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abeychain/go-abey/log"
+)
+
+func TestRunRecovers(t *testing.T) {
+	bundleDir, err := ioutil.TempDir("", "crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(bundleDir)
+
+	restartWait = 0
+	Setup(bundleDir, true)
+	RegisterInfo("head", func() interface{} { return 42 })
+	RegisterInfo("broken", func() interface{} { panic("no head") })
+
+	// Restartable subsystems are restarted until they return
+	runs := 0
+	Run("test/loop", true, func() {
+		if runs++; runs < 3 {
+			log.Info("Crashing test loop", "run", runs)
+			panic("boom")
+		}
+	})
+	if runs != 3 {
+		t.Fatalf("run count mismatch: have %d, want 3", runs)
+	}
+	// Other subsystems are stopped after the first panic
+	runs = 0
+	Run("test/once", false, func() {
+		runs++
+		panic("boom")
+	})
+	if runs != 1 {
+		t.Fatalf("non restartable run count mismatch: have %d, want 1", runs)
+	}
+	files, _ := filepath.Glob(filepath.Join(bundleDir, "test_*.json"))
+	if len(files) != 3 {
+		t.Fatalf("bundle count mismatch: have %d, want 3", len(files))
+	}
+	blob, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(blob, &bundle); err != nil {
+		t.Fatalf("failed to decode bundle: %v", err)
+	}
+	if bundle.Panic != "boom" || bundle.Stack == "" || len(bundle.Logs) == 0 {
+		t.Fatalf("incomplete bundle: %+v", bundle)
+	}
+	if bundle.Info["head"] != float64(42) || bundle.Info["broken"] != "unavailable: no head" {
+		t.Fatalf("bundle info mismatch: %v", bundle.Info)
+	}
+}
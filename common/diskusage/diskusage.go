@@ -0,0 +1,294 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package diskusage implements a disk usage forecaster, tracking the growth of
+// the node storage and projecting when the volume holding it runs full.
+package diskusage
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+	"github.com/elastic/gosigar"
+)
+
+const (
+	// DefaultInterval is the time between two disk usage measurements.
+	DefaultInterval = 10 * time.Minute
+
+	// DefaultWarnDays is the projected number of days until the volume is full
+	// below which the disk usage is considered unhealthy.
+	DefaultWarnDays = 21
+
+	day        = 24 * time.Hour
+	window     = 7 * day   // Span of the measurements the growth is averaged over
+	minSpan    = time.Hour // Shortest span of measurements a growth is derived from
+	warnRepeat = day       // Time between two warnings while the disk stays unhealthy
+)
+
+var (
+	freeGauge       = metrics.NewRegisteredGauge("disk/free", nil)
+	growthGauge     = metrics.NewRegisteredGauge("disk/growth", nil)
+	daysToFullGauge = metrics.NewRegisteredGauge("disk/daystofull", nil)
+)
+
+// DirSize returns the total size of the files below a directory.
+func DirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files may be deleted by compactions while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// FreeSpace returns the bytes available to unprivileged users on the volume
+// holding the given path.
+func FreeSpace(path string) (uint64, error) {
+	var usage gosigar.FileSystemUsage
+	if err := usage.Get(path); err != nil {
+		return 0, err
+	}
+	return usage.Avail, nil
+}
+
+// Component is the usage of a single part of the node storage.
+type Component struct {
+	Name   string `json:"name"`
+	Size   uint64 `json:"size"`   // Bytes on disk
+	Growth int64  `json:"growth"` // Bytes per day, averaged over the last week
+}
+
+// Status is the outcome of the last disk usage measurement.
+type Status struct {
+	Path       string       `json:"path"`
+	Components []*Component `json:"components"`
+	Free       uint64       `json:"free"`       // Bytes available on the volume
+	Growth     int64        `json:"growth"`     // Bytes per day of all components
+	DaysToFull float64      `json:"daysToFull"` // Projected days until the volume is full, -1 if not growing
+	WarnDays   float64      `json:"warnDays"`
+	Healthy    bool         `json:"healthy"`
+	Checked    time.Time    `json:"checked"` // Zero until the first measurement finished
+	Error      string       `json:"error"`   // Error of the last measurement, empty on success
+}
+
+// sample is a single size measurement of a component.
+type sample struct {
+	time time.Time
+	size uint64
+}
+
+// component is a storage part registered to be tracked.
+type component struct {
+	name    string
+	size    func() (uint64, error)
+	samples []sample // Measurements of the last window, oldest first
+
+	sizeGauge   metrics.Gauge
+	growthGauge metrics.Gauge
+}
+
+// growth returns the average daily growth of the component over its samples,
+// false if they don't span long enough yet.
+func (c *component) growth() (int64, bool) {
+	if len(c.samples) < 2 {
+		return 0, false
+	}
+	first, last := c.samples[0], c.samples[len(c.samples)-1]
+	span := last.time.Sub(first.time)
+	if span < minSpan {
+		return 0, false
+	}
+	return int64(float64(int64(last.size)-int64(first.size)) * float64(day) / float64(span)), true
+}
+
+// Monitor periodically measures the size of the registered storage components
+// and the free space of the volume holding them, projecting the time until
+// the volume runs full at the growth rate of the last week.
+type Monitor struct {
+	path     string
+	warnDays float64
+	interval time.Duration
+	now      func() time.Time       // Current time, replaceable for tests
+	free     func() (uint64, error) // Free space measurement, replaceable for tests
+
+	components []*component
+	status     Status
+	warned     time.Time // Time of the last warning, limiting their frequency
+
+	lock sync.RWMutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMonitor creates a disk usage monitor for the volume holding path, warning
+// when it is projected to run full within warnDays.
+func NewMonitor(path string, warnDays int, interval time.Duration) *Monitor {
+	if warnDays <= 0 {
+		warnDays = DefaultWarnDays
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Monitor{
+		path:     path,
+		warnDays: float64(warnDays),
+		interval: interval,
+		now:      time.Now,
+		free:     func() (uint64, error) { return FreeSpace(path) },
+		status:   Status{Path: path, DaysToFull: -1, WarnDays: float64(warnDays), Healthy: true},
+		quit:     make(chan struct{}),
+	}
+}
+
+// Register adds a storage component whose size is tracked separately.
+func (m *Monitor) Register(name string, size func() (uint64, error)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.components = append(m.components, &component{
+		name:        name,
+		size:        size,
+		sizeGauge:   metrics.NewRegisteredGauge("disk/"+name+"/size", nil),
+		growthGauge: metrics.NewRegisteredGauge("disk/"+name+"/growth", nil),
+	})
+}
+
+// Start launches the background measurements.
+func (m *Monitor) Start() {
+	log.Info("Started disk usage monitor", "path", m.path, "warndays", m.warnDays)
+
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the background measurements.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Status returns the outcome of the last measurement.
+func (m *Monitor) Status() Status {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	status := m.status
+	status.Components = make([]*Component, len(m.status.Components))
+	for i, c := range m.status.Components {
+		cpy := *c
+		status.Components[i] = &cpy
+	}
+	return status
+}
+
+// loop measures the disk usage every interval until termination.
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check executes a single measurement of all components and the free space,
+// updating the projection and warning if the volume is about to run full.
+func (m *Monitor) check() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.now()
+	m.status.Checked = now
+
+	free, err := m.free()
+	if err != nil {
+		m.status.Error = err.Error()
+		log.Debug("Disk usage check failed", "path", m.path, "err", err)
+		return
+	}
+	var (
+		components = make([]*Component, 0, len(m.components))
+		total      int64
+		known      bool // Whether any component measured long enough for a growth
+	)
+	for _, c := range m.components {
+		size, err := c.size()
+		if err != nil {
+			m.status.Error = err.Error()
+			log.Debug("Disk usage check failed", "component", c.name, "err", err)
+			return
+		}
+		c.samples = append(c.samples, sample{time: now, size: size})
+		for len(c.samples) > 2 && now.Sub(c.samples[1].time) >= window {
+			c.samples = c.samples[1:]
+		}
+		growth, ok := c.growth()
+		if ok {
+			known = true
+			total += growth
+		}
+		c.sizeGauge.Update(int64(size))
+		c.growthGauge.Update(growth)
+
+		components = append(components, &Component{Name: c.name, Size: size, Growth: growth})
+	}
+	days := float64(-1)
+	if known && total > 0 {
+		days = float64(free) / float64(total)
+	}
+	m.status.Error = ""
+	m.status.Components = components
+	m.status.Free = free
+	m.status.Growth = total
+	m.status.DaysToFull = days
+	m.status.Healthy = days < 0 || days >= m.warnDays
+
+	freeGauge.Update(int64(free))
+	growthGauge.Update(total)
+	daysToFullGauge.Update(int64(days))
+
+	if m.status.Healthy {
+		m.warned = time.Time{}
+		log.Debug("Disk usage check done", "free", common.StorageSize(free), "growth", common.StorageSize(total), "days", days)
+		return
+	}
+	if now.Sub(m.warned) >= warnRepeat {
+		m.warned = now
+		log.Warn("Disk is projected to run full soon", "path", m.path, "free", common.StorageSize(free), "growth", common.StorageSize(total), "days", int(days))
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package diskusage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMonitorForecast(t *testing.T) {
+	var (
+		now   = time.Unix(1600000000, 0)
+		free  = uint64(1000)
+		chain = uint64(100)
+		state = uint64(500)
+	)
+	m := NewMonitor("test", 10, time.Minute)
+	m.now = func() time.Time { return now }
+	m.free = func() (uint64, error) { return free, nil }
+	m.Register("chain", func() (uint64, error) { return chain, nil })
+	m.Register("state", func() (uint64, error) { return state, nil })
+
+	tests := []struct {
+		elapsed      time.Duration
+		chain, state uint64
+		free         uint64
+		growth       int64
+		days         float64
+		healthy      bool
+	}{
+		{0, 100, 500, 1000, 0, -1, true},                 // First measurement, no growth known
+		{30 * time.Minute, 110, 510, 980, 0, -1, true},   // Too short a span to project
+		{30 * time.Minute, 120, 520, 960, 960, 1, false}, // 40 bytes per hour, a day left
+		{23 * time.Hour, 120, 520, 960, 40, 24, true},    // Growth averaged over a day
+		{7 * day, 120, 520, 960, 0, -1, true},            // Old growth dropped out of the window
+		{7 * day, 50, 520, 1030, -10, -1, true},          // Shrinking storage never runs full
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.elapsed)
+		chain, state, free = tt.chain, tt.state, tt.free
+		m.check()
+
+		status := m.Status()
+		if status.Error != "" {
+			t.Fatalf("test %d: measurement failed: %v", i, status.Error)
+		}
+		if status.Growth != tt.growth || status.DaysToFull != tt.days || status.Healthy != tt.healthy {
+			t.Errorf("test %d: growth/days/healthy mismatch: have %d/%v/%v, want %d/%v/%v", i, status.Growth, status.DaysToFull, status.Healthy, tt.growth, tt.days, tt.healthy)
+		}
+		if len(status.Components) != 2 || status.Components[0].Size != tt.chain || status.Components[1].Size != tt.state {
+			t.Errorf("test %d: component sizes mismatch: have %v", i, status.Components)
+		}
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskusage-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 50), 0600)
+
+	size, err := DirSize(dir)
+	if err != nil {
+		t.Fatalf("failed to measure directory: %v", err)
+	}
+	if size != 150 {
+		t.Errorf("size mismatch: have %d, want %d", size, 150)
+	}
+}
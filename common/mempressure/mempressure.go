@@ -0,0 +1,212 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package mempressure implements a memory pressure monitor, shedding caches
+// before the process grows large enough to be taken down by the OOM killer.
+package mempressure
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/cgroup"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+	"github.com/elastic/gosigar"
+)
+
+const (
+	// DefaultInterval is the time between two memory measurements.
+	DefaultInterval = 3 * time.Second
+
+	highWatermark = 90 // Percentage of the limit above which caches are shed
+	lowWatermark  = 75 // Percentage of the limit below which the pressure is relieved
+
+	shedCooldown = 30 * time.Second // Minimum time between two sheds while under pressure
+)
+
+var (
+	heapGauge     = metrics.NewRegisteredGauge("mempressure/heap", nil)
+	rssGauge      = metrics.NewRegisteredGauge("mempressure/rss", nil)
+	pressureGauge = metrics.NewRegisteredGauge("mempressure/active", nil)
+	shedMeter     = metrics.NewRegisteredMeter("mempressure/shed", nil)
+)
+
+// SystemLimit returns the amount of memory in bytes the process may use, the
+// limit of its cgroup if any or the physical memory of the host otherwise.
+// Zero is returned if neither can be detected.
+func SystemLimit() uint64 {
+	var mem gosigar.Mem
+	if err := mem.Get(); err != nil {
+		mem.Total = 0
+	}
+	if limit, ok := cgroup.MemoryLimit(); ok && (mem.Total == 0 || limit < mem.Total) {
+		return limit
+	}
+	return mem.Total
+}
+
+// Usage is a single memory measurement of the process.
+type Usage struct {
+	Heap uint64 // Bytes of allocated heap objects
+	RSS  uint64 // Bytes of resident memory, zero if unavailable
+}
+
+// total returns the memory consumption the pressure is judged by.
+func (u Usage) total() uint64 {
+	if u.RSS > u.Heap {
+		return u.RSS
+	}
+	return u.Heap
+}
+
+// measure reads the heap statistics of the runtime and the resident memory of
+// the process as reported by the operating system.
+func measure() Usage {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	usage := Usage{Heap: stats.HeapAlloc}
+
+	var mem gosigar.ProcMem
+	if err := mem.Get(os.Getpid()); err == nil {
+		usage.RSS = mem.Resident
+	}
+	return usage
+}
+
+// handler is a cache registered to give up memory under pressure.
+type handler struct {
+	name    string
+	shed    func()
+	relieve func()
+}
+
+// Monitor periodically measures the memory consumption of the process and
+// asks the registered caches to shed their content when it nears the limit.
+type Monitor struct {
+	limit    uint64
+	interval time.Duration
+	measure  func() Usage // Memory measurement, replaceable for tests
+
+	handlers []handler
+	pressure bool      // Whether the process is under memory pressure
+	shedTime time.Time // Time of the last shed, limiting their frequency
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMonitor creates a memory pressure monitor for the given limit in bytes.
+func NewMonitor(limit uint64, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Monitor{
+		limit:    limit,
+		interval: interval,
+		measure:  measure,
+		quit:     make(chan struct{}),
+	}
+}
+
+// Register adds a cache to shed under memory pressure. The optional relieve
+// callback is invoked once the pressure is gone, restoring any reduced limits.
+func (m *Monitor) Register(name string, shed func(), relieve func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.handlers = append(m.handlers, handler{name: name, shed: shed, relieve: relieve})
+}
+
+// Start launches the background measurements.
+func (m *Monitor) Start() {
+	log.Info("Started memory pressure monitor", "limit", common.StorageSize(m.limit))
+
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the background measurements.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// loop measures the memory consumption every interval until termination.
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check executes a single measurement, shedding the caches if the consumption
+// crossed the high watermark and relieving them once below the low one.
+func (m *Monitor) check() {
+	usage := m.measure()
+
+	heapGauge.Update(int64(usage.Heap))
+	rssGauge.Update(int64(usage.RSS))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	switch total := usage.total(); {
+	case total >= m.limit/100*highWatermark:
+		if m.pressure && time.Since(m.shedTime) < shedCooldown {
+			return
+		}
+		if !m.pressure {
+			log.Warn("Memory pressure detected, shedding caches", "heap", common.StorageSize(usage.Heap), "rss", common.StorageSize(usage.RSS), "limit", common.StorageSize(m.limit))
+		}
+		m.pressure, m.shedTime = true, time.Now()
+		pressureGauge.Update(1)
+		shedMeter.Mark(1)
+
+		for _, h := range m.handlers {
+			log.Debug("Shedding cache", "name", h.name)
+			h.shed()
+		}
+		// Hand the released memory back to the system right away
+		debug.FreeOSMemory()
+
+	case m.pressure && total < m.limit/100*lowWatermark:
+		log.Info("Memory pressure relieved", "heap", common.StorageSize(usage.Heap), "rss", common.StorageSize(usage.RSS), "limit", common.StorageSize(m.limit))
+		m.pressure = false
+		pressureGauge.Update(0)
+
+		for _, h := range m.handlers {
+			if h.relieve != nil {
+				h.relieve()
+			}
+		}
+	}
+}
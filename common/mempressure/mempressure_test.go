@@ -0,0 +1,63 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package mempressure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonitorShedding(t *testing.T) {
+	var (
+		usage    Usage
+		sheds    int
+		relieves int
+	)
+	m := NewMonitor(1000, time.Second)
+	m.measure = func() Usage { return usage }
+	m.Register("test", func() { sheds++ }, func() { relieves++ })
+
+	tests := []struct {
+		usage    Usage
+		sheds    int
+		relieves int
+	}{
+		{Usage{Heap: 500, RSS: 600}, 0, 0}, // Plenty of room left
+		{Usage{Heap: 500, RSS: 950}, 1, 0}, // Resident memory crossed the high watermark
+		{Usage{Heap: 500, RSS: 950}, 1, 0}, // Still under pressure, shedding is rate limited
+		{Usage{Heap: 500, RSS: 800}, 1, 0}, // Between the watermarks, pressure is kept
+		{Usage{Heap: 500, RSS: 700}, 1, 1}, // Dropped below the low watermark
+		{Usage{Heap: 700, RSS: 700}, 1, 1}, // Pressure is only relieved once
+		{Usage{Heap: 920, RSS: 0}, 2, 1},   // Heap crossed the high watermark, rss unavailable
+		{Usage{Heap: 100, RSS: 200}, 2, 2}, // Relieved again
+	}
+	for i, tt := range tests {
+		usage = tt.usage
+		m.check()
+		if sheds != tt.sheds || relieves != tt.relieves {
+			t.Errorf("test %d: sheds/relieves mismatch: have %d/%d, want %d/%d", i, sheds, relieves, tt.sheds, tt.relieves)
+		}
+	}
+	// Persisting pressure must shed again after the cooldown
+	usage = Usage{Heap: 950}
+	m.check()
+	m.shedTime = time.Now().Add(-shedCooldown)
+	m.check()
+	if sheds != 4 {
+		t.Errorf("persisting pressure shed count mismatch: have %d, want 4", sheds)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ntp implements a system clock drift detector based on the simple
+// network time protocol: https://tools.ietf.org/html/rfc4330
+package ntp
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+const (
+	// DefaultServer is the NTP server queried for the current time.
+	DefaultServer = "pool.ntp.org"
+
+	// DefaultThreshold is the drift above which the clock is considered off.
+	// Blocks are refused when more than 15 seconds in the future, half of it
+	// leaves room for the drift of the receiving nodes.
+	DefaultThreshold = 7 * time.Second
+
+	// DefaultInterval is the time between two drift measurements.
+	DefaultInterval = 10 * time.Minute
+
+	measurements = 3               // Number of measurements to do against the NTP server
+	timeout      = 5 * time.Second // Timeout of a single measurement
+)
+
+var (
+	errShortReply = errors.New("short NTP reply")
+
+	driftGauge = metrics.NewRegisteredGauge("ntp/drift", nil)
+)
+
+// durationSlice attaches the methods of sort.Interface to []time.Duration,
+// sorting in increasing order.
+type durationSlice []time.Duration
+
+func (s durationSlice) Len() int           { return len(s) }
+func (s durationSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s durationSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Drift does a naive time resolution against an NTP server and returns the
+// measured drift of the local clock, positive if it runs ahead. It's not
+// precise but should be fine for these purposes.
+//
+// Note, it executes two extra measurements compared to the number of requested
+// ones to be able to discard the two extremes as outliers.
+func Drift(server string, measurements int) (time.Duration, error) {
+	addr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return 0, err
+	}
+	drifts := make([]time.Duration, 0, measurements+2)
+	for i := 0; i < measurements+2; i++ {
+		drift, err := measure(addr)
+		if err != nil {
+			return 0, err
+		}
+		drifts = append(drifts, drift)
+	}
+	// Calculate average drift (drop two extremities to avoid outliers)
+	sort.Sort(durationSlice(drifts))
+
+	drift := time.Duration(0)
+	for i := 1; i < len(drifts)-1; i++ {
+		drift += drifts[i]
+	}
+	return drift / time.Duration(measurements), nil
+}
+
+// measure executes a single time request against an NTP server.
+func measure(addr *net.UDPAddr) (time.Duration, error) {
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// Construct the time request (empty package with only 2 fields set):
+	//   Bits 3-5: Protocol version, 3
+	//   Bits 6-8: Mode of operation, client, 3
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+
+	sent := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return 0, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	reply := make([]byte, 48)
+	n, err := conn.Read(reply)
+	if err != nil {
+		return 0, err
+	}
+	if n < len(reply) {
+		return 0, errShortReply
+	}
+	elapsed := time.Since(sent)
+
+	// Reconstruct the time from the transmit timestamp of the reply
+	sec := uint64(reply[43]) | uint64(reply[42])<<8 | uint64(reply[41])<<16 | uint64(reply[40])<<24
+	frac := uint64(reply[47]) | uint64(reply[46])<<8 | uint64(reply[45])<<16 | uint64(reply[44])<<24
+
+	nanosec := sec*1e9 + (frac*1e9)>>32
+	t := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(nanosec))
+
+	// Calculate the drift based on an assumed answer time of RRT/2
+	return sent.Sub(t) + elapsed/2, nil
+}
+
+// Status is the outcome of the last clock drift measurement.
+type Status struct {
+	Server    string
+	Drift     time.Duration
+	Threshold time.Duration
+	Healthy   bool
+	Checked   time.Time // Zero until the first measurement finished
+	Error     string    // Error of the last measurement, empty on success
+}
+
+// Monitor periodically measures the clock drift against an NTP server and
+// warns the user if one large enough is detected.
+type Monitor struct {
+	server    string
+	threshold time.Duration
+	interval  time.Duration
+	measure   func() (time.Duration, error) // Drift measurement, replaceable for tests
+
+	status Status
+	lock   sync.RWMutex
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a clock drift monitor querying the given NTP server.
+func NewMonitor(server string, threshold, interval time.Duration) *Monitor {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	m := &Monitor{
+		server:    server,
+		threshold: threshold,
+		interval:  interval,
+		status:    Status{Server: server, Threshold: threshold, Healthy: true},
+		quit:      make(chan struct{}),
+	}
+	m.measure = func() (time.Duration, error) {
+		return Drift(net.JoinHostPort(m.server, "123"), measurements)
+	}
+	return m
+}
+
+// Start launches the background measurements, the first one right away.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the background measurements.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Status returns the outcome of the last measurement.
+func (m *Monitor) Status() Status {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.status
+}
+
+// loop measures the drift every interval until termination.
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+		select {
+		case <-ticker.C:
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check executes a single drift measurement and reports the result.
+func (m *Monitor) check() {
+	drift, err := m.measure()
+
+	m.lock.Lock()
+	m.status.Checked = time.Now()
+	if err != nil {
+		// An unreachable server says nothing about the clock, keep the last drift
+		m.status.Error = err.Error()
+		m.lock.Unlock()
+
+		log.Debug("NTP drift check failed", "server", m.server, "err", err)
+		return
+	}
+	m.status.Error = ""
+	m.status.Drift = drift
+	m.status.Healthy = drift >= -m.threshold && drift <= m.threshold
+	healthy := m.status.Healthy
+	m.lock.Unlock()
+
+	driftGauge.Update(int64(drift / time.Millisecond))
+	if !healthy {
+		log.Warn("System clock seems off, blocks and fruits may be rejected as future ones", "drift", drift, "threshold", m.threshold)
+		log.Warn("Please enable network time synchronisation in system settings.")
+	} else {
+		log.Debug("NTP sanity check done", "drift", drift)
+	}
+}
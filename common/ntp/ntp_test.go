@@ -0,0 +1,90 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package ntp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveSNTP answers time requests with the local time shifted by offset.
+func serveSNTP(t *testing.T, offset time.Duration) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			since := time.Now().Add(offset).Sub(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+			sec := uint64(since / time.Second)
+			frac := uint64(since%time.Second) << 32 / uint64(time.Second)
+
+			reply := make([]byte, 48)
+			binary.BigEndian.PutUint32(reply[40:], uint32(sec))
+			binary.BigEndian.PutUint32(reply[44:], uint32(frac))
+			conn.WriteToUDP(reply, addr)
+		}
+	}()
+	return conn
+}
+
+func TestDrift(t *testing.T) {
+	server := serveSNTP(t, -time.Minute)
+	defer server.Close()
+
+	drift, err := Drift(server.LocalAddr().String(), measurements)
+	if err != nil {
+		t.Fatalf("failed to measure drift: %v", err)
+	}
+	if drift < time.Minute-time.Second || drift > time.Minute+time.Second {
+		t.Fatalf("drift mismatch: have %v, want ~%v", drift, time.Minute)
+	}
+}
+
+func TestMonitorStatus(t *testing.T) {
+	var (
+		drift time.Duration
+		err   error
+	)
+	m := NewMonitor("localhost", time.Second, 0)
+	m.measure = func() (time.Duration, error) { return drift, err }
+
+	drift = 500 * time.Millisecond
+	m.check()
+	if status := m.Status(); !status.Healthy || status.Drift != drift {
+		t.Fatalf("small drift status mismatch: %+v", status)
+	}
+	drift = -2 * time.Second
+	m.check()
+	if status := m.Status(); status.Healthy || status.Drift != drift {
+		t.Fatalf("large drift status mismatch: %+v", status)
+	}
+	// Failed measurements must keep the last known drift
+	err = errors.New("unreachable")
+	m.check()
+	if status := m.Status(); status.Healthy || status.Drift != drift || status.Error == "" {
+		t.Fatalf("failed measurement status mismatch: %+v", status)
+	}
+}
@@ -19,6 +19,7 @@ package consensus
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
@@ -30,6 +31,10 @@ import (
 	"github.com/abeychain/go-abey/rpc"
 )
 
+// AllowedFutureBlockTime is the maximum time a block timestamp may be ahead of
+// the local clock before the block is considered a future block.
+const AllowedFutureBlockTime = 15 * time.Second
+
 // ChainReader defines a small collection of methods needed to access the local
 // blockchain during header and/or uncle verification.
 type ChainReader interface {
@@ -232,8 +237,10 @@ func getEndOfOldEpoch(eid *big.Int, reader SnailChainReader) *big.Int {
 		return nil
 	}
 
-	fruits := block.Fruits()
-	lastFruitNumber := fruits[len(fruits)-1].FastNumber()
+	lastFruitNumber := block.MaxFruitNumber()
+	if lastFruitNumber == nil {
+		return nil
+	}
 	lastFastNumber := new(big.Int).Add(lastFruitNumber, params.ElectionSwitchoverNumber)
 
 	return lastFastNumber
@@ -268,8 +275,11 @@ func InitTIP8(config *params.ChainConfig, reader SnailChainReader) {
 			log.Error("InitTIP9 GetBlock failed.", "curSnailNumber", curSnailNumber, "keep", keep, "hash", header.Hash().Hex())
 			return
 		}
-		fruits := block.Fruits()
-		lastFruitNumber := fruits[len(fruits)-1].FastNumber()
+		lastFruitNumber := block.MaxFruitNumber()
+		if lastFruitNumber == nil {
+			log.Error("InitTIP9 snail block has no fruits", "keep", keep)
+			return
+		}
 		config.TIP9.FastNumber = new(big.Int).Add(lastFruitNumber, big.NewInt(10000))
 	}
 }
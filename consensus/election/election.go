@@ -18,15 +18,19 @@ package election
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
+	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/crash"
 	"github.com/abeychain/go-abey/consensus"
 	"github.com/abeychain/go-abey/core/snailchain"
 	"github.com/abeychain/go-abey/core/snailchain/rawdb"
@@ -43,6 +47,10 @@ import (
 const (
 	snailchainHeadSize  = 64
 	committeeCacheLimit = 256
+
+	// maxProofCommittees is the maximum number of committee transitions a
+	// single committee proof may span, longer ranges must use a checkpoint.
+	maxProofCommittees = 64
 )
 
 type ElectMode uint
@@ -63,6 +71,7 @@ var (
 	ErrCommittee     = errors.New("get committee failed")
 	ErrInvalidMember = errors.New("invalid committee member")
 	ErrInvalidSwitch = errors.New("invalid switch block info")
+	ErrProofTooLong  = errors.New("committee proof spans too many committees, use a later checkpoint")
 )
 
 type candidateMember struct {
@@ -86,6 +95,20 @@ type committee struct {
 	switches            []*big.Int // blocknumbers whose block include switchinfos
 }
 
+// switchCheckDue reports whether the snail block at number starts the switch
+// to the next committee: it is the switch check block, or a later one while
+// the end of the committee is still unknown because the check block's event
+// was dropped.
+func (c *committee) switchCheckDue(number *big.Int) bool {
+	switch number.Cmp(c.switchCheckNumber) {
+	case 0:
+		return true
+	case 1:
+		return c.endFastNumber == nil || c.endFastNumber.Sign() == 0
+	}
+	return false
+}
+
 // Members returns dump of the committee members
 func (c *committee) Members() []*types.CommitteeMember {
 	members := make([]*types.CommitteeMember, len(c.members))
@@ -131,6 +154,10 @@ type Election struct {
 
 	commiteeCache *lru.Cache
 	epochCache    *lru.Cache
+	indexCache    *lru.Cache // Materialized member sets of finished committees
+	switchCache   *lru.Cache // Switch numbers of epochs that can't be paused anymore
+
+	historyLock sync.Mutex // Serializes the committee history updates
 
 	electionMode    ElectMode
 	committee       *committee
@@ -151,6 +178,14 @@ type Election struct {
 	snailChainEventCh  chan types.SnailChainEvent
 	snailChainEventSub event.Subscription
 
+	lifecycle sync.Mutex         // Serializes starting and stopping the event loop
+	cancel    context.CancelFunc // Terminates the event loop, nil if not running
+	done      <-chan struct{}    // Closed once the event loop is told to stop, protected by mu
+	wg        sync.WaitGroup     // Tracks the running event loop
+
+	recoverMu    sync.Mutex // Serializes recomputations of the committee view
+	lastRecovery time.Time  // Time of the last recomputation, protected by recoverMu
+
 	fastchain  BlockChain
 	snailchain SnailBlockChain
 
@@ -216,6 +251,8 @@ func NewElection(chainConfig *params.ChainConfig, fastBlockChain BlockChain, sna
 
 	election.commiteeCache, _ = lru.New(committeeCacheLimit)
 	election.epochCache, _ = lru.New(committeeCacheLimit)
+	election.indexCache, _ = lru.New(committeeCacheLimit)
+	election.switchCache, _ = lru.New(committeeCacheLimit)
 
 	if election.singleNode {
 		committeeMember := election.getGenesisCommittee()
@@ -408,6 +445,18 @@ func (e *Election) VerifySigns(signs []*types.PbftSign) ([]*types.CommitteeMembe
 
 // VerifySwitchInfo verify committee members and it's state
 func (e *Election) VerifySwitchInfo(fastNumber *big.Int, info []*types.CommitteeMember) error {
+	err := e.verifySwitchInfo(fastNumber, info)
+	if err != ErrInvalidSwitch {
+		return err
+	}
+	// The local view may be the broken side, recompute it and check again
+	if e.recoverCommittee(fastNumber) {
+		err = e.verifySwitchInfo(fastNumber, info)
+	}
+	return err
+}
+
+func (e *Election) verifySwitchInfo(fastNumber *big.Int, info []*types.CommitteeMember) error {
 	if e.singleNode == true {
 		return nil
 	}
@@ -449,7 +498,11 @@ func (e *Election) getElectionMembers(snailBeginNumber *big.Int, snailEndNumber
 		return committee
 	}
 
-	blockNum := new(big.Int).Add(e.getLastNumber(snailBeginNumber, snailEndNumber), common.Big1).Uint64()
+	lastFastNumber := e.getLastNumber(snailBeginNumber, snailEndNumber)
+	if lastFastNumber == nil {
+		return nil
+	}
+	blockNum := new(big.Int).Add(lastFastNumber, common.Big1).Uint64()
 	block := e.fastchain.GetBlockByNumber(blockNum)
 	if block != nil {
 		var (
@@ -479,7 +532,16 @@ func (e *Election) getElectionMembers(snailBeginNumber *big.Int, snailEndNumber
 }
 
 func (e *Election) getValidators(fastNumber *big.Int) []*types.CommitteeMember {
-	epoch := types.GetEpochFromHeight(fastNumber.Uint64())
+	epoch, err := e.CommitteeEpoch(fastNumber.Uint64())
+	if err != nil {
+		log.Error("Failed to resolve committee epoch", "fast", fastNumber, "err", err)
+		return nil
+	}
+	return e.epochValidators(epoch, fastNumber)
+}
+
+// epochValidators returns the validators elected for the epoch.
+func (e *Election) epochValidators(epoch *types.EpochIDInfo, fastNumber *big.Int) []*types.CommitteeMember {
 	current := e.fastchain.CurrentBlock().Number()
 
 	if cache, ok := e.epochCache.Get(epoch.EpochID); ok {
@@ -673,8 +735,6 @@ func (e *Election) electedCommittee(fastNumber *big.Int) *committee {
 
 // GetCommittee gets committee members propose this fast block
 func (e *Election) GetCommittee(fastNumber *big.Int) []*types.CommitteeMember {
-	var members []*types.CommitteeMember
-
 	if e.IsTIP8(fastNumber) {
 		// Apply validators at stake from contract and blockchain
 		return e.getValidators(fastNumber)
@@ -688,47 +748,43 @@ func (e *Election) GetCommittee(fastNumber *big.Int) []*types.CommitteeMember {
 	if len(committee.switches) == 0 {
 		return committee.Members()
 	}
+	if members := e.indexedMembers(committee, fastNumber); members != nil {
+		return members
+	}
 
 	states := make(map[common.Address]uint32)
-	if fastNumber.Cmp(committee.switches[len(committee.switches)-1]) > 0 {
-		// Apply all committee state switches for latest block
-		for _, num := range committee.switches {
-			b := e.fastchain.GetBlockByNumber(num.Uint64())
-			if b == nil {
-				log.Warn("Switch block not exists", "number", num)
-				break
-			}
-			for _, s := range b.SwitchInfos() {
-				switch s.Flag {
-				case types.StateAppendFlag:
-					states[s.CommitteeBase] = types.StateAppendFlag
-				case types.StateRemovedFlag:
-					states[s.CommitteeBase] = types.StateRemovedFlag
-				}
-			}
+	for _, num := range committee.switches {
+		if num.Cmp(fastNumber) >= 0 {
+			break
 		}
-	} else {
-		for _, num := range committee.switches {
-			if num.Cmp(fastNumber) >= 0 {
-				break
-			}
-			b := e.fastchain.GetBlockByNumber(num.Uint64())
-			if b == nil {
-				log.Warn("Switch block not exists", "number", num)
-				break
-			}
-			for _, s := range b.SwitchInfos() {
-				switch s.Flag {
-				case types.StateAppendFlag:
-					states[s.CommitteeBase] = types.StateAppendFlag
-				case types.StateRemovedFlag:
-					states[s.CommitteeBase] = types.StateRemovedFlag
-				}
-			}
+		b := e.fastchain.GetBlockByNumber(num.Uint64())
+		if b == nil {
+			log.Warn("Switch block not exists", "number", num)
+			break
 		}
+		applySwitchInfos(states, b.SwitchInfos())
 	}
+	return switchedMembers(committee, states)
+}
 
-	for _, m := range committee.Members() {
+// applySwitchInfos records the member state changes of a switch block.
+func applySwitchInfos(states map[common.Address]uint32, infos []*types.CommitteeMember) {
+	for _, s := range infos {
+		switch s.Flag {
+		case types.StateAppendFlag:
+			states[s.CommitteeBase] = types.StateAppendFlag
+		case types.StateRemovedFlag:
+			states[s.CommitteeBase] = types.StateRemovedFlag
+		}
+	}
+}
+
+// switchedMembers returns the working members of a committee after applying
+// the given member state changes.
+func switchedMembers(c *committee, states map[common.Address]uint32) []*types.CommitteeMember {
+	var members []*types.CommitteeMember
+
+	for _, m := range c.Members() {
 		if flag, ok := states[m.CommitteeBase]; ok {
 			if flag != types.StateRemovedFlag {
 				members = append(members, m)
@@ -737,23 +793,91 @@ func (e *Election) GetCommittee(fastNumber *big.Int) []*types.CommitteeMember {
 			members = append(members, m)
 		}
 	}
-	for _, m := range committee.BackupMembers() {
+	for _, m := range c.BackupMembers() {
 		if flag, ok := states[m.CommitteeBase]; ok {
 			if flag == types.StateAppendFlag {
 				members = append(members, m)
 			}
 		}
 	}
+	return members
+}
 
+// indexedMembers returns the members of a finished committee at a fast block
+// from the persisted committee index, building the index on first use. It
+// returns nil for committees still running, as their switches may change.
+func (e *Election) indexedMembers(c *committee, fastNumber *big.Int) []*types.CommitteeMember {
+	if c.endFastNumber == nil || c.endFastNumber.Sign() == 0 || fastNumber.Cmp(c.endFastNumber) > 0 {
+		return nil
+	}
+	if e.fastchain.CurrentHeader().Number.Cmp(c.endFastNumber) <= 0 {
+		return nil
+	}
+	var index []*rawdb.CommitteeIndexEntry
+	if cached, ok := e.indexCache.Get(c.id.Uint64()); ok {
+		index = cached.([]*rawdb.CommitteeIndexEntry)
+	} else {
+		db := e.snailchain.GetDatabase()
+		if index = rawdb.ReadCommitteeIndex(db, c.id.Uint64()); index == nil {
+			if index = e.buildCommitteeIndex(c); index == nil {
+				return nil
+			}
+			rawdb.WriteCommitteeIndex(db, c.id.Uint64(), index)
+		}
+		e.indexCache.Add(c.id.Uint64(), index)
+	}
+	// Pick the last member set effective at the requested block
+	number := fastNumber.Uint64()
+	i := sort.Search(len(index), func(i int) bool { return index[i].Begin > number }) - 1
+	if i < 0 {
+		return nil
+	}
+	members := make([]*types.CommitteeMember, len(index[i].Members))
+	copy(members, index[i].Members)
 	return members
 }
 
+// writeCommitteeStates persists the switch block numbers of a committee and
+// drops its member index, which was built from the previous ones.
+func (e *Election) writeCommitteeStates(id *big.Int, switches []*big.Int) {
+	db := e.snailchain.GetDatabase()
+	rawdb.WriteCommitteeStates(db, id.Uint64(), switches)
+	rawdb.DeleteCommitteeIndex(db, id.Uint64())
+	if e.indexCache != nil {
+		e.indexCache.Remove(id.Uint64())
+	}
+}
+
+// buildCommitteeIndex materializes the member sets of a committee between its
+// switch blocks. A switch takes effect on the block following it.
+func (e *Election) buildCommitteeIndex(c *committee) []*rawdb.CommitteeIndexEntry {
+	states := make(map[common.Address]uint32)
+	index := []*rawdb.CommitteeIndexEntry{{
+		Begin:   c.beginFastNumber.Uint64(),
+		Members: switchedMembers(c, states),
+	}}
+	for _, num := range c.switches {
+		b := e.fastchain.GetBlockByNumber(num.Uint64())
+		if b == nil {
+			log.Warn("Switch block not exists", "number", num)
+			return nil
+		}
+		applySwitchInfos(states, b.SwitchInfos())
+		index = append(index, &rawdb.CommitteeIndexEntry{
+			Begin:   num.Uint64() + 1,
+			Members: switchedMembers(c, states),
+		})
+	}
+	log.Debug("Built committee index", "committee", c.id, "ranges", len(index))
+	return index
+}
+
 // GetCommitteeById return committee info sepecified by Committee ID
 func (e *Election) GetCommitteeById(id *big.Int) map[string]interface{} {
 	info := make(map[string]interface{})
 	if id.Cmp(e.chainConfig.TIP8.CID) >= 0 {
 		epoch := types.GetEpochFromID(id.Uint64())
-		members := e.getValidators(big.NewInt(int64(epoch.BeginHeight)))
+		members := e.epochValidators(epoch, big.NewInt(int64(epoch.BeginHeight)))
 		if members == nil {
 			log.Error("GetCommitteeById failed", "epoch", epoch)
 			return nil
@@ -792,7 +916,9 @@ func (e *Election) GetCommitteeById(id *big.Int) map[string]interface{} {
 				}
 			} else {
 				end := new(big.Int).Sub(params.ElectionPeriodNumber, params.SnailConfirmInterval)
-				info["endNumber"] = e.getLastNumber(big.NewInt(1), end).Uint64()
+				if endFast := e.getLastNumber(big.NewInt(1), end); endFast != nil {
+					info["endNumber"] = endFast.Uint64()
+				}
 			}
 			return info
 		}
@@ -812,7 +938,10 @@ func (e *Election) GetCommitteeById(id *big.Int) map[string]interface{} {
 			info["endSnailNumber"] = endElectionNumber.Uint64()
 			info["members"] = membersDisplay(elected.Members)
 			info["backups"] = membersDisplay(elected.Backups)
-			info["beginNumber"] = new(big.Int).Add(e.getLastNumber(beginElectionNumber, endElectionNumber), common.Big1).Uint64()
+			info["beginNumber"] = nil
+			if lastFast := e.getLastNumber(beginElectionNumber, endElectionNumber); lastFast != nil {
+				info["beginNumber"] = lastFast.Uint64() + 1
+			}
 			info["endNumber"] = nil
 			// Committee end fast number may be nil if current committee is working on
 			if currentCommittee != nil && currentCommittee.id.Cmp(id) == 0 {
@@ -823,7 +952,9 @@ func (e *Election) GetCommitteeById(id *big.Int) map[string]interface{} {
 			} else {
 				begin := new(big.Int).Add(beginElectionNumber, params.ElectionPeriodNumber)
 				end := new(big.Int).Add(endElectionNumber, params.ElectionPeriodNumber)
-				info["endNumber"] = e.getLastNumber(begin, end).Uint64()
+				if endFast := e.getLastNumber(begin, end); endFast != nil {
+					info["endNumber"] = endFast.Uint64()
+				}
 			}
 			return info
 		}
@@ -831,10 +962,110 @@ func (e *Election) GetCommitteeById(id *big.Int) map[string]interface{} {
 
 	return nil
 }
+
+// GetCommitteeProof returns the committee members of a fast block together
+// with every switch block and snail election header an external verifier needs
+// to derive them, starting at the committee active at the checkpoint block.
+// Without a checkpoint the proof chains back to the genesis committee.
+func (e *Election) GetCommitteeProof(fastNumber *big.Int, checkpoint *big.Int) (*types.CommitteeProof, error) {
+	if checkpoint == nil {
+		checkpoint = new(big.Int)
+	}
+	if checkpoint.Cmp(fastNumber) > 0 {
+		return nil, fmt.Errorf("checkpoint %v above block %v", checkpoint, fastNumber)
+	}
+	members := e.GetCommittee(fastNumber)
+	if members == nil {
+		return nil, ErrCommittee
+	}
+	var (
+		switches  []uint64 // Switch block numbers, collected from the target backwards
+		elections []*types.SnailHeader
+	)
+	number := new(big.Int).Set(fastNumber)
+	for i := 0; ; i++ {
+		if i == maxProofCommittees {
+			return nil, ErrProofTooLong
+		}
+		var begin *big.Int
+		if e.IsTIP8(number) {
+			// Validators are fixed for the whole epoch and written into the
+			// block switching to it, which a rotation pause may postpone
+			epoch, err := e.CommitteeEpoch(number.Uint64())
+			if err != nil {
+				return nil, err
+			}
+			switchNumber, err := e.switchNumber(epoch, number.Uint64())
+			if err != nil {
+				return nil, err
+			}
+			begin = new(big.Int).SetUint64(switchNumber)
+			switches = append(switches, switchNumber)
+		} else {
+			c := e.electedCommittee(number)
+			if c == nil {
+				return nil, ErrCommittee
+			}
+			begin = c.beginFastNumber
+			for j := len(c.switches) - 1; j >= 0; j-- {
+				if c.switches[j].Cmp(number) < 0 {
+					switches = append(switches, c.switches[j].Uint64())
+				}
+			}
+			if c.id.Sign() == 0 {
+				// Genesis committee is recorded in the genesis block
+				switches = append(switches, 0)
+				break
+			}
+			switches = append(switches, begin.Uint64())
+			for n := c.lastElectionNumber.Uint64(); n >= c.firstElectionNumber.Uint64() && n > 0; n-- {
+				header := e.snailchain.GetHeaderByNumber(n)
+				if header == nil {
+					return nil, fmt.Errorf("missing snail election header %d", n)
+				}
+				elections = append(elections, header)
+			}
+		}
+		if begin.Cmp(checkpoint) <= 0 || begin.Cmp(common.Big1) <= 0 {
+			break
+		}
+		number = new(big.Int).Sub(begin, common.Big1)
+	}
+	proof := &types.CommitteeProof{
+		Number:     new(big.Int).Set(fastNumber),
+		Checkpoint: new(big.Int).Set(checkpoint),
+		Members:    members,
+	}
+	for i := len(switches) - 1; i >= 0; i-- {
+		block := e.fastchain.GetBlockByNumber(switches[i])
+		if block == nil {
+			return nil, fmt.Errorf("missing switch block %d", switches[i])
+		}
+		proof.Switches = append(proof.Switches, &types.SwitchBlock{
+			Header: block.Header(),
+			Infos:  block.SwitchInfos(),
+			Signs:  block.Signs(),
+		})
+	}
+	for i := len(elections) - 1; i >= 0; i-- {
+		proof.Elections = append(proof.Elections, elections[i])
+	}
+	return proof, nil
+}
+
 func (e *Election) getMembers(fastNumber *big.Int) (*big.Int, []*types.CommitteeMember) {
 	if e.IsTIP8(fastNumber) {
-		epoch := types.GetEpochFromHeight(fastNumber.Uint64())
-		return new(big.Int).SetUint64(epoch.BeginHeight), e.getValidators(fastNumber)
+		epoch, err := e.CommitteeEpoch(fastNumber.Uint64())
+		if err != nil {
+			log.Error("Failed to resolve committee epoch", "fast", fastNumber, "err", err)
+			return nil, nil
+		}
+		switchNumber, err := e.switchNumber(epoch, fastNumber.Uint64())
+		if err != nil {
+			log.Error("Failed to resolve committee switch", "fast", fastNumber, "err", err)
+			return nil, nil
+		}
+		return new(big.Int).SetUint64(switchNumber), e.epochValidators(epoch, fastNumber)
 	} else {
 		committee := e.electedCommittee(fastNumber)
 		if committee == nil {
@@ -960,8 +1191,11 @@ func (e *Election) getLastNumber(beginSnail, endSnail *big.Int) *big.Int {
 		return nil
 	}
 
-	fruits := endElectionBlock.Fruits()
-	lastFruitNumber := fruits[len(fruits)-1].FastNumber()
+	lastFruitNumber := endElectionBlock.MaxFruitNumber()
+	if lastFruitNumber == nil {
+		log.Warn("Election end snail block has no fruits", "number", endSnail)
+		return nil
+	}
 	lastFastNumber := new(big.Int).Add(lastFruitNumber, params.ElectionSwitchoverNumber)
 
 	return lastFastNumber
@@ -1224,7 +1458,7 @@ func (e *Election) switchMembers(fastNumber *big.Int, infos []*types.CommitteeMe
 		if len(committee.switches) > 0 {
 			log.Info("Reset committee switchinfo on start block", "committee", committee.id, "current", fastNumber)
 			committee.switches = nil
-			rawdb.WriteCommitteeStates(e.snailchain.GetDatabase(), committee.id.Uint64(), nil)
+			e.writeCommitteeStates(committee.id, nil)
 		}
 		return
 	}
@@ -1232,7 +1466,7 @@ func (e *Election) switchMembers(fastNumber *big.Int, infos []*types.CommitteeMe
 	// Store all switch block number
 	log.Info("Election update committee member state", "block", fastNumber)
 	committee.switches = append(committee.switches, fastNumber)
-	rawdb.WriteCommitteeStates(e.snailchain.GetDatabase(), committee.id.Uint64(), committee.switches)
+	e.writeCommitteeStates(committee.id, committee.switches)
 
 	// Update pbft server's committee info via pbft agent proxy
 	members, backups := e.filterWithSwitchInfo(committee)
@@ -1240,7 +1474,7 @@ func (e *Election) switchMembers(fastNumber *big.Int, infos []*types.CommitteeMe
 	if endfast == nil {
 		endfast = big.NewInt(0)
 	}
-	e.electionFeed.Send(types.ElectionEvent{
+	e.sendElectionEvent(types.ElectionEvent{
 		Option:           types.CommitteeUpdate,
 		CommitteeID:      committee.id,
 		BeginFastNumber:  fastNumber,
@@ -1258,6 +1492,7 @@ func (e *Election) FinalizeCommittee(block *types.Block) error {
 	}
 	if e.IsTIP8(new(big.Int).Set(block.Number())) {
 		// No need to do retrieve election from PoW
+		e.recordEpoch(block.NumberU64())
 		return nil
 	}
 
@@ -1271,10 +1506,28 @@ func (e *Election) FinalizeCommittee(block *types.Block) error {
 		// Current committee completed, switch next
 		log.Info("****switchNext on Last fastblock in current epoch")
 		e.initCurrent()
-		e.switchNext <- struct{}{}
+		e.notifySwitch()
 	}
 	return nil
 }
+
+// notifySwitch hands the committee switch over to the event loop, dropping it
+// if the loop is not running instead of blocking the caller forever.
+func (e *Election) notifySwitch() {
+	e.mu.RLock()
+	done := e.done
+	e.mu.RUnlock()
+
+	if done == nil {
+		log.Warn("Election not running, committee switch dropped")
+		return
+	}
+	select {
+	case e.switchNext <- struct{}{}:
+	case <-done:
+		log.Warn("Election stopped, committee switch dropped")
+	}
+}
 func (e *Election) initCurrent() {
 	if e.committee != nil {
 		return
@@ -1286,12 +1539,15 @@ func (e *Election) initCurrent() {
 		log.Crit("Election faiiled to get committee on start")
 		return
 	}
+	// Repair switchinfo storage left inconsistent by an unclean shutdown
+	e.reconcileSwitches(currentCommittee, fastHeadNumber)
+
 	// Rewind committee swtichinfo storage if blockchain rollbacks
 	for i := 0; i < len(currentCommittee.switches); i++ {
 		if currentCommittee.switches[i].Cmp(fastHeadNumber) > 0 {
 			log.Info("Rewind committee switchinfo", "committee", currentCommittee.id, "current", fastHeadNumber)
 			currentCommittee.switches = currentCommittee.switches[:i]
-			rawdb.WriteCommitteeStates(e.snailchain.GetDatabase(), currentCommittee.id.Uint64(), currentCommittee.switches)
+			e.writeCommitteeStates(currentCommittee.id, currentCommittee.switches)
 			break
 		}
 	}
@@ -1304,7 +1560,7 @@ func (e *Election) initCurrent() {
 		if block != nil && len(block.SwitchInfos()) > 0 {
 			log.Info("Election append switch block height", "number", switchNum)
 			currentCommittee.switches = append(currentCommittee.switches, switchNum)
-			rawdb.WriteCommitteeStates(e.snailchain.GetDatabase(), currentCommittee.id.Uint64(), currentCommittee.switches)
+			e.writeCommitteeStates(currentCommittee.id, currentCommittee.switches)
 		}
 		switchNum = new(big.Int).Add(switchNum, common.Big1)
 	}
@@ -1323,8 +1579,51 @@ func (e *Election) initCurrent() {
 	}
 }
 
+// reconcileSwitches checks the stored switch block numbers of a committee up to
+// the fast head against the blocks carrying switch infos. If they differ, e.g.
+// an entry is out of order, points at a block without switch infos or was never
+// stored, the records are rebuilt from the scanned blocks.
+func (e *Election) reconcileSwitches(c *committee, fastHeadNumber *big.Int) {
+	last := fastHeadNumber
+	if c.endFastNumber != nil && c.endFastNumber.Sign() > 0 && c.endFastNumber.Cmp(last) < 0 {
+		last = c.endFastNumber
+	}
+	var switches []*big.Int
+	for num := new(big.Int).Add(c.beginFastNumber, common.Big1); num.Cmp(last) <= 0; num = new(big.Int).Add(num, common.Big1) {
+		if block := e.fastchain.GetBlockByNumber(num.Uint64()); block != nil && len(block.SwitchInfos()) > 0 {
+			switches = append(switches, num)
+		}
+	}
+	// Numbers beyond the head are left for the rollback rewind
+	stored, pending := c.switches, []*big.Int(nil)
+	for i, num := range c.switches {
+		if num.Cmp(last) > 0 {
+			stored, pending = c.switches[:i], c.switches[i:]
+			break
+		}
+	}
+	consistent := len(stored) == len(switches)
+	for i := 0; consistent && i < len(stored); i++ {
+		consistent = stored[i].Cmp(switches[i]) == 0
+	}
+	if consistent {
+		return
+	}
+	switches = append(switches, pending...)
+	log.Warn("Repaired committee switchinfo", "committee", c.id, "stored", c.switches, "repaired", switches)
+
+	c.switches = switches
+	e.writeCommitteeStates(c.id, c.switches)
+}
+
 // Start load current committ and starts election processing
 func (e *Election) Start() error {
+	e.lifecycle.Lock()
+	defer e.lifecycle.Unlock()
+
+	if e.cancel != nil {
+		return nil // Already running
+	}
 	// get current committee info
 	fastHeadNumber := e.fastchain.CurrentHeader().Number
 	// snailHeadNumber := e.snailchain.CurrentHeader().Number
@@ -1335,20 +1634,22 @@ func (e *Election) Start() error {
 		e.disabled = true
 		return nil
 	} else {
-		e.snailChainEventSub = e.snailchain.SubscribeChainEvent(e.snailChainEventCh)
+		// A stalled election must not block snail chain insertion, missed
+		// events are caught up on the next snail block
+		e.snailChainEventSub = event.SubscribeBounded("election/snailchain", e.snailChainEventCh, e.snailchain.SubscribeChainEvent)
 		e.initCurrent()
 		// send event to the subscripber
 		go func(e *Election) {
 			printCommittee(e.committee)
 			members, backups := e.filterWithSwitchInfo(e.committee)
-			e.electionFeed.Send(types.ElectionEvent{
+			e.sendElectionEvent(types.ElectionEvent{
 				Option:           types.CommitteeSwitchover,
 				CommitteeID:      e.committee.id,
 				CommitteeMembers: members,
 				BackupMembers:    backups,
 				BeginFastNumber:  e.committee.beginFastNumber,
 			})
-			e.electionFeed.Send(types.ElectionEvent{
+			e.sendElectionEvent(types.ElectionEvent{
 				Option:           types.CommitteeStart,
 				CommitteeID:      e.committee.id,
 				CommitteeMembers: members,
@@ -1358,20 +1659,53 @@ func (e *Election) Start() error {
 		}(e)
 	}
 	// Start the event loop and return
-	go e.loop()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.mu.Lock()
+	e.cancel, e.done = cancel, ctx.Done()
+	e.mu.Unlock()
 
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		crash.Run("election", true, func() { e.loop(ctx) })
+	}()
 	return nil
 }
 
-// Monitor both chains and trigger elections at the same time
-func (e *Election) loop() {
-	// Elect next committee on start
+// Stop terminates the event loop and releases the snail chain subscription.
+// A stopped election can be started again.
+func (e *Election) Stop() {
+	e.lifecycle.Lock()
+	defer e.lifecycle.Unlock()
+
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	e.wg.Wait()
+	e.snailChainEventSub.Unsubscribe()
+
+	e.mu.Lock()
+	e.cancel, e.done = nil, nil
+	e.snailChainEventSub = nil
+	e.mu.Unlock()
+
+	log.Info("Election stopped")
+}
+
+// Monitor both chains and trigger elections at the same time, until the
+// context is cancelled.
+func (e *Election) loop(ctx context.Context) {
+	// Elect next committee on start, only once across restarts
 	if e.prepare {
+		e.prepare = false
+
 		next := new(big.Int).Add(e.committee.id, common.Big1)
 		log.Info("Election calc next committee on start", "committee", next)
 		e.nextCommittee = e.calcCommittee(next)
 		e.startSwitchover = true
-		e.electionFeed.Send(types.ElectionEvent{
+		e.sendElectionEvent(types.ElectionEvent{
 			Option:           types.CommitteeOver,
 			CommitteeID:      e.committee.id,
 			CommitteeMembers: e.committee.Members(),
@@ -1382,7 +1716,7 @@ func (e *Election) loop() {
 		if e.isTIP8FromCID(e.committee.id.Uint64()) {
 			e.startSwitchover = false
 		} else {
-			e.electionFeed.Send(types.ElectionEvent{
+			e.sendElectionEvent(types.ElectionEvent{
 				Option:           types.CommitteeSwitchover,
 				CommitteeID:      e.nextCommittee.id,
 				CommitteeMembers: e.nextCommittee.Members(),
@@ -1404,10 +1738,10 @@ func (e *Election) loop() {
 			if e.committee == nil {
 				e.initCurrent()
 			}
-			if se.Block != nil && e.committee != nil && e.committee.switchCheckNumber.Cmp(se.Block.Number()) == 0 {
+			if se.Block != nil && e.committee != nil && e.committee.switchCheckDue(se.Block.Number()) {
 				//Record Numbers to open elections
 				e.committee.endFastNumber = e.getEndFast(e.committee.id)
-				e.electionFeed.Send(types.ElectionEvent{
+				e.sendElectionEvent(types.ElectionEvent{
 					Option:           types.CommitteeOver, //only update committee end fast black
 					CommitteeID:      e.committee.id,
 					CommitteeMembers: e.committee.Members(),
@@ -1430,7 +1764,7 @@ func (e *Election) loop() {
 
 				log.Info("Election switchover new committee", "id", e.nextCommittee.id, "startNumber", e.nextCommittee.beginFastNumber)
 				printCommittee(e.nextCommittee)
-				e.electionFeed.Send(types.ElectionEvent{
+				e.sendElectionEvent(types.ElectionEvent{
 					Option:           types.CommitteeSwitchover, //update next committee
 					CommitteeID:      e.nextCommittee.id,
 					CommitteeMembers: e.nextCommittee.Members(),
@@ -1438,10 +1772,13 @@ func (e *Election) loop() {
 					BeginFastNumber:  e.nextCommittee.beginFastNumber,
 				})
 			}
+		case <-ctx.Done():
+			return
+
 		case <-e.switchNext:
 			if e.startSwitchover && e.committee != nil {
 				log.Info("Election stop committee..", "id", e.committee.id)
-				e.electionFeed.Send(types.ElectionEvent{
+				e.sendElectionEvent(types.ElectionEvent{
 					Option:           types.CommitteeStop,
 					CommitteeID:      e.committee.id,
 					CommitteeMembers: e.committee.Members(),
@@ -1460,7 +1797,7 @@ func (e *Election) loop() {
 					continue
 				}
 				log.Info("Election start new BFT committee", "id", e.committee.id)
-				e.electionFeed.Send(types.ElectionEvent{
+				e.sendElectionEvent(types.ElectionEvent{
 					Option:           types.CommitteeStart,
 					CommitteeID:      e.committee.id,
 					CommitteeMembers: e.committee.Members(),
@@ -1482,6 +1819,10 @@ func (e *Election) SetEngine(engine consensus.Engine) {
 	e.engine = engine
 }
 func (e *Election) IsTIP8(fastHeadNumber *big.Int) bool {
+	if e.chainConfig == nil {
+		// Fake elections run a single fixed committee
+		return false
+	}
 	return consensus.IsTIP8(fastHeadNumber, e.chainConfig, e.getSnailChainReader())
 }
 func (e *Election) isTIP8FromCID(cid uint64) bool {
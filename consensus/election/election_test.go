@@ -19,16 +19,22 @@ package election
 import (
 	"math/big"
 	"bytes"
+	"crypto/ecdsa"
 	"testing"
+	"time"
 
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/consensus"
 	"github.com/abeychain/go-abey/consensus/minerva"
 	"github.com/abeychain/go-abey/core"
 	"github.com/abeychain/go-abey/core/snailchain"
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
 	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/event"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/params"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 var (
@@ -37,7 +43,7 @@ var (
 
 func makeTestBlock() *types.Block {
 	db := abeydb.NewMemDatabase()
-	BaseGenesis := new(core.Genesis)
+	BaseGenesis := &core.Genesis{Config: params.DevnetChainConfig}
 	genesis := BaseGenesis.MustFastCommit(db)
 	header := &types.Header{
 		ParentHash: genesis.Hash(),
@@ -146,4 +152,652 @@ func makeFast(parent *types.Block, n int, engine consensus.Engine, db abeydb.Dat
 // 	if int64(len(members)) > params.MaximumCommitteeNumber.Int64() {
 // 		t.Errorf("Elected members exceed MAX member num")
 // 	}
-// }
\ No newline at end of file
+// }
+
+// makeElectionSnapshot builds the snapshot of a committee election whose last
+// snail block packs a single fruit.
+func makeElectionSnapshot(config *params.ChainConfig, id uint64) *ElectionSnapshot {
+	begin, end := electionWindow(new(big.Int).SetUint64(id))
+	snap := &ElectionSnapshot{
+		Committee: id,
+		Begin:     begin.Uint64(),
+		End:       end.Uint64(),
+	}
+	parent := common.Hash{}
+	for number := snap.Begin; number <= snap.End; number++ {
+		var (
+			headers []*types.SnailHeader
+			fruits  []*types.SnailBlock
+		)
+		header := &types.SnailHeader{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			FruitsHash: types.EmptyRootHash,
+		}
+		if number == snap.End {
+			fruit := types.NewSnailBlockWithHeader(&types.SnailHeader{FastNumber: big.NewInt(1000), Number: big.NewInt(0)})
+			headers = append(headers, fruit.Header())
+			if config.IsTIP5(header.Number) {
+				header.FruitsHash = types.DeriveSha(types.FruitsHeaders(headers))
+			} else {
+				fruits = append(fruits, fruit)
+				header.FruitsHash = types.DeriveSha(types.Fruits(fruits))
+			}
+		}
+		snap.Headers = append(snap.Headers, header)
+		snap.Fruits = append(snap.Fruits, headers)
+		snap.FruitBlocks = append(snap.FruitBlocks, fruits)
+		parent = header.Hash()
+	}
+	snap.SwitchNumber = 1000 + params.ElectionSwitchoverNumber.Uint64() + 1
+	return snap
+}
+
+func TestVerifyElection(t *testing.T) {
+	tip5 := *params.TestChainConfig
+	tip5.TIP5 = &params.BlockConfig{SnailNumber: big.NewInt(0)}
+
+	for _, config := range []*params.ChainConfig{params.TestChainConfig, &tip5} {
+		defaults := NewFakeElection().committee.Members()
+		election := &Election{chainConfig: config, defaultMembers: defaults}
+
+		committee, err := election.VerifyElection(makeElectionSnapshot(config, 1))
+		if err != nil {
+			t.Fatalf("tip5 %v: failed to verify election: %v", config.TIP5 != nil, err)
+		}
+		// Without candidates the default members of the verifier keep the committee
+		infos := make([]*types.CommitteeMember, len(defaults))
+		for i, m := range defaults {
+			infos[i] = &types.CommitteeMember{Publickey: m.Publickey, Flag: types.StateUsedFlag}
+		}
+		if err := CompareSwitchInfos(committee, infos); err != nil {
+			t.Errorf("tip5 %v: switch infos mismatch: %v", config.TIP5 != nil, err)
+		}
+		if err := CompareSwitchInfos(committee, infos[1:]); err == nil {
+			t.Errorf("tip5 %v: missing switch info accepted", config.TIP5 != nil)
+		}
+		// Tampered snapshots must be rejected
+		tests := map[string]func(snap *ElectionSnapshot){
+			"header number": func(snap *ElectionSnapshot) { snap.Headers[10].Number = big.NewInt(0) },
+			"switch number": func(snap *ElectionSnapshot) { snap.SwitchNumber++ },
+			"missing fruits": func(snap *ElectionSnapshot) {
+				snap.Fruits[len(snap.Fruits)-1], snap.FruitBlocks[len(snap.FruitBlocks)-1] = nil, nil
+			},
+			"fruit header": func(snap *ElectionSnapshot) {
+				snap.Fruits[len(snap.Fruits)-1] = []*types.SnailHeader{{FastNumber: big.NewInt(2000), Number: big.NewInt(0)}}
+			},
+			"injected fruit": func(snap *ElectionSnapshot) {
+				snap.Fruits[5] = []*types.SnailHeader{{FastNumber: big.NewInt(500), Number: big.NewInt(0)}}
+			},
+			"fruit blocks": func(snap *ElectionSnapshot) {
+				snap.FruitBlocks = snap.FruitBlocks[1:]
+			},
+		}
+		for name, tamper := range tests {
+			snap := makeElectionSnapshot(config, 1)
+			tamper(snap)
+			if _, err := election.VerifyElection(snap); err == nil {
+				t.Errorf("tip5 %v: tampered %s accepted", config.TIP5 != nil, name)
+			}
+		}
+	}
+	// Committees chosen by stake since TIP8 have no election to re-run
+	tip8 := *params.TestChainConfig
+	tip8.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(0), CID: big.NewInt(2)}
+	election := &Election{chainConfig: &tip8, defaultMembers: NewFakeElection().committee.Members()}
+
+	if _, err := election.VerifyElection(makeElectionSnapshot(&tip8, 1)); err != nil {
+		t.Errorf("tip8: failed to verify election before the staked committees: %v", err)
+	}
+	for _, id := range []uint64{2, 3} {
+		if _, err := election.VerifyElection(makeElectionSnapshot(&tip8, id)); err != ErrStakedCommittee {
+			t.Errorf("tip8: committee %d verification error mismatch: have %v, want %v", id, err, ErrStakedCommittee)
+		}
+		if _, err := election.ExportElection(id); err != ErrStakedCommittee {
+			t.Errorf("tip8: committee %d export error mismatch: have %v, want %v", id, err, ErrStakedCommittee)
+		}
+	}
+}
+
+// fruitlessChain serves a fixed set of snail blocks to the election.
+type fruitlessChain struct {
+	SnailBlockChain
+	blocks map[uint64]*types.SnailBlock
+}
+
+func (c *fruitlessChain) GetBlockByNumber(number uint64) *types.SnailBlock {
+	return c.blocks[number]
+}
+
+func TestGetLastNumberFruitless(t *testing.T) {
+	fruit := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(0), FastNumber: big.NewInt(60)})
+	chain := &fruitlessChain{blocks: map[uint64]*types.SnailBlock{
+		0: types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(0)}),
+		1: types.NewSnailBlock(&types.SnailHeader{Number: big.NewInt(1)}, []*types.SnailBlock{fruit}, nil, nil, params.TestChainConfig),
+		2: types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(2)}),
+	}}
+	e := &Election{snailchain: chain}
+
+	want := new(big.Int).Add(big.NewInt(60), params.ElectionSwitchoverNumber)
+	if last := e.getLastNumber(big.NewInt(0), big.NewInt(1)); last == nil || last.Cmp(want) != 0 {
+		t.Errorf("last fast number mismatch: have %v, want %v", last, want)
+	}
+	// Neither the genesis block nor an invalid fruitless block may end an election
+	if last := e.getLastNumber(big.NewInt(0), big.NewInt(0)); last != nil {
+		t.Errorf("fruitless genesis returned last fast number %v", last)
+	}
+	if last := e.getLastNumber(big.NewInt(1), big.NewInt(2)); last != nil {
+		t.Errorf("fruitless block returned last fast number %v", last)
+	}
+}
+
+// lifecycleChain is a minimal fast and snail chain stub driving the election
+// event loop.
+type lifecycleChain struct {
+	SnailBlockChain
+	feed event.Feed
+	db   abeydb.Database
+}
+
+func (c *lifecycleChain) CurrentHeader() *types.SnailHeader {
+	return &types.SnailHeader{Number: common.Big0}
+}
+
+func (c *lifecycleChain) GetDatabase() abeydb.Database { return c.db }
+
+func (c *lifecycleChain) SubscribeChainEvent(ch chan<- types.SnailChainEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+type lifecycleFastChain struct {
+	BlockChain
+}
+
+func (c *lifecycleFastChain) CurrentHeader() *types.Header {
+	return &types.Header{Number: big.NewInt(10)}
+}
+
+func makeLifecycleElection(chain *lifecycleChain) *Election {
+	config := *params.TestChainConfig
+	config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(1000), CID: big.NewInt(100)}
+
+	current := NewFakeElection().committee
+	current.endFastNumber = big.NewInt(10)
+
+	next := NewFakeElection().committee
+	next.id = big.NewInt(1)
+	next.beginFastNumber = big.NewInt(11)
+
+	return &Election{
+		chainConfig:       &config,
+		fastchain:         &lifecycleFastChain{},
+		snailchain:        chain,
+		snailChainEventCh: make(chan types.SnailChainEvent, snailchainHeadSize),
+		switchNext:        make(chan struct{}),
+		committee:         current,
+		nextCommittee:     next,
+		startSwitchover:   true,
+	}
+}
+
+func TestElectionStartStop(t *testing.T) {
+	chain := &lifecycleChain{db: abeydb.NewMemDatabase()}
+	e := makeLifecycleElection(chain)
+
+	// Repeated starts must not spawn duplicate loops or subscriptions
+	for i := 0; i < 2; i++ {
+		if err := e.Start(); err != nil {
+			t.Fatalf("failed to start election: %v", err)
+		}
+	}
+	if n := chain.feed.Send(types.SnailChainEvent{}); n != 1 {
+		t.Fatalf("subscriber count mismatch: have %d, want 1", n)
+	}
+	e.Stop()
+	if n := chain.feed.Send(types.SnailChainEvent{}); n != 0 {
+		t.Fatalf("subscription leaked after stop: %d subscribers", n)
+	}
+	// Stopping again is a noop, restarting resubscribes
+	e.Stop()
+	if err := e.Start(); err != nil {
+		t.Fatalf("failed to restart election: %v", err)
+	}
+	if n := chain.feed.Send(types.SnailChainEvent{}); n != 1 {
+		t.Fatalf("subscriber count mismatch after restart: have %d, want 1", n)
+	}
+	e.Stop()
+}
+
+func TestElectionStopDuringSwitchover(t *testing.T) {
+	e := makeLifecycleElection(&lifecycleChain{db: abeydb.NewMemDatabase()})
+	if err := e.Start(); err != nil {
+		t.Fatalf("failed to start election: %v", err)
+	}
+	events := make(chan types.ElectionEvent, 16)
+	sub := e.SubscribeElectionEvent(events)
+	defer sub.Unsubscribe()
+
+	// Finalizing the last block of the epoch hands the switchover to the loop
+	last := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	if err := e.FinalizeCommittee(last); err != nil {
+		t.Fatalf("failed to finalize committee: %v", err)
+	}
+	e.Stop()
+
+	e.mu.RLock()
+	id := e.committee.id
+	e.mu.RUnlock()
+	if id.Cmp(common.Big1) != 0 {
+		t.Errorf("committee not switched: have %v, want 1", id)
+	}
+	// Once stopped, finalizing must not block on the dead loop
+	done := make(chan struct{})
+	go func() {
+		e.committee.endFastNumber = big.NewInt(20)
+		e.FinalizeCommittee(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(20)}))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("committee finalization blocked after stop")
+	}
+}
+
+// switchChain serves fast blocks for switchinfo reconciliation.
+type switchChain struct {
+	BlockChain
+	blocks map[uint64]*types.Block
+}
+
+func (c *switchChain) GetBlockByNumber(number uint64) *types.Block { return c.blocks[number] }
+func (c *switchChain) CurrentHeader() *types.Header                { return &types.Header{Number: big.NewInt(100)} }
+
+// databaseChain serves the database switchinfos are persisted into.
+type databaseChain struct {
+	SnailBlockChain
+	db abeydb.Database
+}
+
+func (c *databaseChain) GetDatabase() abeydb.Database { return c.db }
+
+func TestReconcileSwitches(t *testing.T) {
+	chain := &switchChain{blocks: make(map[uint64]*types.Block)}
+	for i := uint64(1); i <= 20; i++ {
+		var infos []*types.CommitteeMember
+		if i == 5 || i == 9 || i == 14 {
+			infos = []*types.CommitteeMember{{Flag: types.StateRemovedFlag}}
+		}
+		chain.blocks[i] = types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, nil, nil, nil, infos)
+	}
+	db := abeydb.NewMemDatabase()
+	e := &Election{fastchain: chain, snailchain: &databaseChain{db: db}}
+
+	tests := []struct {
+		stored []uint64
+		want   []uint64
+	}{
+		{stored: []uint64{5, 9, 14}, want: []uint64{5, 9, 14}},         // consistent, kept as is
+		{stored: []uint64{5, 9, 14, 30}, want: []uint64{5, 9, 14, 30}}, // beyond the head, left for rewind
+		{stored: []uint64{5, 9}, want: []uint64{5, 9, 14}},             // missing tail
+		{stored: []uint64{5, 14}, want: []uint64{5, 9, 14}},            // missing intermediate
+		{stored: []uint64{5, 9, 30}, want: []uint64{5, 9, 14, 30}},     // missing before the head
+		{stored: nil, want: []uint64{5, 9, 14}},                        // never stored
+		{stored: []uint64{9, 5}, want: []uint64{5, 9, 14}},             // out of order
+		{stored: []uint64{5, 5, 9}, want: []uint64{5, 9, 14}},          // duplicated
+		{stored: []uint64{5, 7}, want: []uint64{5, 9, 14}},             // not a switch block
+		{stored: []uint64{1, 5}, want: []uint64{5, 9, 14}},             // committee start block
+	}
+	for i, tt := range tests {
+		c := &committee{id: big.NewInt(1), beginFastNumber: big.NewInt(1)}
+		for _, n := range tt.stored {
+			c.switches = append(c.switches, new(big.Int).SetUint64(n))
+		}
+		rawdb.WriteCommitteeStates(db, 1, c.switches)
+		e.reconcileSwitches(c, big.NewInt(20))
+
+		stored := rawdb.ReadCommitteeStates(db, 1)
+		if len(c.switches) != len(tt.want) {
+			t.Errorf("test %d: switch count mismatch: have %v, want %v", i, c.switches, tt.want)
+			continue
+		}
+		for j, n := range tt.want {
+			if c.switches[j].Uint64() != n {
+				t.Errorf("test %d: switch %d mismatch: have %v, want %d", i, j, c.switches[j], n)
+			}
+		}
+		if len(stored) != len(tt.want) {
+			t.Errorf("test %d: repaired switches not persisted: have %v, want %v", i, stored, tt.want)
+		}
+	}
+}
+
+// Tests that a switch check missed through a dropped snail event is caught up
+// on the next snail block, but only once.
+func TestSwitchCheckDue(t *testing.T) {
+	tests := []struct {
+		number  int64
+		endFast *big.Int
+		due     bool
+	}{
+		{99, nil, false},
+		{100, nil, true},
+		{100, big.NewInt(500), true},
+		{101, nil, true},
+		{101, big.NewInt(0), true},
+		{101, big.NewInt(500), false},
+	}
+	for i, tt := range tests {
+		c := &committee{switchCheckNumber: big.NewInt(100), endFastNumber: tt.endFast}
+		if due := c.switchCheckDue(big.NewInt(tt.number)); due != tt.due {
+			t.Errorf("test %d: switch check mismatch: have %v, want %v", i, due, tt.due)
+		}
+	}
+}
+
+func TestCommitteeIndex(t *testing.T) {
+	var members, backups []*types.CommitteeMember
+	for i := 0; i < 4; i++ {
+		members = append(members, &types.CommitteeMember{CommitteeBase: common.Address{byte(i)}, Flag: types.StateUsedFlag})
+		backups = append(backups, &types.CommitteeMember{CommitteeBase: common.Address{byte(10 + i)}, Flag: types.StateUnusedFlag})
+	}
+	infos := map[uint64][]*types.CommitteeMember{
+		15: {{CommitteeBase: members[1].CommitteeBase, Flag: types.StateRemovedFlag}, {CommitteeBase: backups[0].CommitteeBase, Flag: types.StateAppendFlag}},
+		30: {{CommitteeBase: backups[0].CommitteeBase, Flag: types.StateRemovedFlag}},
+	}
+	chain := &switchChain{blocks: make(map[uint64]*types.Block)}
+	for i := uint64(1); i <= 100; i++ {
+		chain.blocks[i] = types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, nil, nil, nil, infos[i])
+	}
+	db := abeydb.NewMemDatabase()
+	e := &Election{fastchain: chain, snailchain: &databaseChain{db: db}}
+	e.indexCache, _ = lru.New(committeeCacheLimit)
+
+	c := &committee{
+		id:              big.NewInt(1),
+		beginFastNumber: big.NewInt(10),
+		endFastNumber:   big.NewInt(50),
+		members:         members,
+		backupMembers:   backups,
+		switches:        []*big.Int{big.NewInt(15), big.NewInt(30)},
+	}
+	for number := int64(10); number <= 50; number++ {
+		// Walk the switch blocks for the expected member set
+		states := make(map[common.Address]uint32)
+		for _, num := range c.switches {
+			if num.Int64() < number {
+				applySwitchInfos(states, infos[num.Uint64()])
+			}
+		}
+		want := switchedMembers(c, states)
+		have := e.indexedMembers(c, big.NewInt(number))
+		if len(have) != len(want) {
+			t.Fatalf("block %d: member count mismatch: have %d, want %d", number, len(have), len(want))
+		}
+		for i := range have {
+			if have[i].CommitteeBase != want[i].CommitteeBase {
+				t.Errorf("block %d: member %d mismatch: have %x, want %x", number, i, have[i].CommitteeBase, want[i].CommitteeBase)
+			}
+		}
+	}
+	if index := rawdb.ReadCommitteeIndex(db, 1); len(index) != 3 {
+		t.Errorf("committee index not persisted: %v", index)
+	}
+	// Running committees and blocks outside the committee are never indexed
+	if members := e.indexedMembers(c, big.NewInt(51)); members != nil {
+		t.Errorf("block beyond committee end indexed")
+	}
+	c.endFastNumber = big.NewInt(0)
+	if members := e.indexedMembers(c, big.NewInt(20)); members != nil {
+		t.Errorf("running committee indexed")
+	}
+}
+
+// Tests that rewriting the switches of a committee drops its member index, so
+// the members are served from the rewritten switches.
+func TestCommitteeIndexRewrite(t *testing.T) {
+	var members, backups []*types.CommitteeMember
+	for i := 0; i < 4; i++ {
+		members = append(members, &types.CommitteeMember{CommitteeBase: common.Address{byte(i)}, Flag: types.StateUsedFlag})
+		backups = append(backups, &types.CommitteeMember{CommitteeBase: common.Address{byte(10 + i)}, Flag: types.StateUnusedFlag})
+	}
+	infos := map[uint64][]*types.CommitteeMember{
+		15: {{CommitteeBase: backups[0].CommitteeBase, Flag: types.StateAppendFlag}},
+		30: {{CommitteeBase: backups[0].CommitteeBase, Flag: types.StateRemovedFlag}},
+	}
+	chain := &switchChain{blocks: make(map[uint64]*types.Block)}
+	for i := uint64(1); i <= 100; i++ {
+		chain.blocks[i] = types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, nil, nil, nil, infos[i])
+	}
+	db := abeydb.NewMemDatabase()
+	e := &Election{fastchain: chain, snailchain: &databaseChain{db: db}}
+	e.indexCache, _ = lru.New(committeeCacheLimit)
+
+	// Index the committee from corrupted switches missing the removal
+	c := &committee{
+		id:              big.NewInt(1),
+		beginFastNumber: big.NewInt(10),
+		endFastNumber:   big.NewInt(50),
+		members:         members,
+		backupMembers:   backups,
+		switches:        []*big.Int{big.NewInt(15), big.NewInt(20)},
+	}
+	if have := e.indexedMembers(c, big.NewInt(40)); len(have) != 5 {
+		t.Fatalf("member count before rewrite mismatch: have %d, want 5", len(have))
+	}
+	e.reconcileSwitches(c, big.NewInt(50))
+
+	if index := rawdb.ReadCommitteeIndex(db, 1); index != nil {
+		t.Errorf("stale committee index kept: %v", index)
+	}
+	have := e.indexedMembers(c, big.NewInt(40))
+	if len(have) != 4 {
+		t.Fatalf("member count after rewrite mismatch: have %d, want 4", len(have))
+	}
+	for i, m := range have {
+		if m.CommitteeBase != members[i].CommitteeBase {
+			t.Errorf("member %d mismatch: have %x, want %x", i, m.CommitteeBase, members[i].CommitteeBase)
+		}
+	}
+	// Rewinding the switches drops the index as well
+	e.writeCommitteeStates(c.id, c.switches[:1])
+	if index := rawdb.ReadCommitteeIndex(db, 1); index != nil {
+		t.Errorf("committee index kept after rewind: %v", index)
+	}
+	if _, ok := e.indexCache.Get(uint64(1)); ok {
+		t.Errorf("cached committee index kept after rewind")
+	}
+}
+
+func TestRecoverCommitteeRateLimit(t *testing.T) {
+	config := *params.TestChainConfig
+	config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(0), CID: big.NewInt(0)}
+
+	e := &Election{chainConfig: &config}
+	e.commiteeCache, _ = lru.New(committeeCacheLimit)
+	e.epochCache, _ = lru.New(committeeCacheLimit)
+	e.indexCache, _ = lru.New(committeeCacheLimit)
+	e.commiteeCache.Add(uint64(1), &types.ElectionCommittee{})
+	e.indexCache.Add(uint64(1), nil)
+
+	if !e.recoverCommittee(big.NewInt(10)) {
+		t.Fatalf("first recovery skipped")
+	}
+	if e.commiteeCache.Len() != 0 || e.indexCache.Len() != 0 {
+		t.Errorf("committee caches not purged")
+	}
+	// Recoveries triggered by a burst of invalid switch blocks are throttled
+	e.commiteeCache.Add(uint64(1), &types.ElectionCommittee{})
+	if e.recoverCommittee(big.NewInt(10)) {
+		t.Fatalf("recovery not rate limited")
+	}
+	if e.commiteeCache.Len() != 1 {
+		t.Errorf("committee cache purged while rate limited")
+	}
+	e.lastRecovery = time.Now().Add(-recoveryInterval)
+	if !e.recoverCommittee(big.NewInt(10)) {
+		t.Errorf("recovery skipped after the interval passed")
+	}
+	// Fake elections have nothing to recompute
+	if NewFakeElection().recoverCommittee(big.NewInt(10)) {
+		t.Errorf("fake election recovered")
+	}
+}
+
+// recoverChain is a snail chain stub still in the election of the genesis
+// committee.
+type recoverChain struct {
+	SnailBlockChain
+	db abeydb.Database
+}
+
+func (c *recoverChain) CurrentHeader() *types.SnailHeader {
+	return &types.SnailHeader{Number: big.NewInt(5)}
+}
+func (c *recoverChain) GetDatabase() abeydb.Database { return c.db }
+
+// Tests that recovering an elected committee repairs its switches, and that
+// the agent is notified without blocking the block validation triggering it.
+func TestRecoverCommitteeSwitches(t *testing.T) {
+	chain := &switchChain{blocks: make(map[uint64]*types.Block)}
+	for i := uint64(1); i <= 20; i++ {
+		var infos []*types.CommitteeMember
+		if i == 5 || i == 9 {
+			infos = []*types.CommitteeMember{{Flag: types.StateRemovedFlag}}
+		}
+		chain.blocks[i] = types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, nil, nil, nil, infos)
+	}
+	db := abeydb.NewMemDatabase()
+	rawdb.WriteCommitteeStates(db, 0, []*big.Int{big.NewInt(5), big.NewInt(7)})
+
+	config := *params.TestChainConfig
+	config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(1000), CID: big.NewInt(100)}
+	e := &Election{
+		chainConfig: &config,
+		fastchain:   chain,
+		snailchain:  &recoverChain{db: db},
+		committee:   NewFakeElection().committee,
+	}
+	e.commiteeCache, _ = lru.New(committeeCacheLimit)
+	e.epochCache, _ = lru.New(committeeCacheLimit)
+	e.indexCache, _ = lru.New(committeeCacheLimit)
+
+	// A subscriber never draining its events must not block the recovery
+	stalled := make(chan types.ElectionEvent)
+	stalledSub := e.SubscribeElectionEvent(stalled)
+	defer stalledSub.Unsubscribe()
+
+	done := make(chan bool)
+	go func() { done <- e.recoverCommittee(big.NewInt(10)) }()
+	select {
+	case recovered := <-done:
+		if !recovered {
+			t.Fatalf("recovery skipped")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("recovery blocked on the election event")
+	}
+	want := []uint64{5, 9}
+	e.mu.RLock()
+	switches := e.committee.switches
+	e.mu.RUnlock()
+	if len(switches) != len(want) {
+		t.Fatalf("recovered switches mismatch: have %v, want %v", switches, want)
+	}
+	for i, num := range rawdb.ReadCommitteeStates(db, 0) {
+		if num.Uint64() != want[i] || switches[i].Uint64() != want[i] {
+			t.Errorf("switch %d mismatch: have %v (stored %v), want %d", i, switches[i], num, want[i])
+		}
+	}
+	// The agent is still notified once it drains its events
+	select {
+	case ev := <-stalled:
+		if ev.Option != types.CommitteeUpdate || ev.CommitteeID.Sign() != 0 {
+			t.Errorf("election event mismatch: have option %d committee %v", ev.Option, ev.CommitteeID)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("committee update not sent")
+	}
+}
+
+// proofChain serves empty switch blocks for committee proofs.
+type proofChain struct {
+	*stakingChain
+}
+
+func (c *proofChain) GetBlockByNumber(number uint64) *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number)})
+}
+
+// Tests that committee proofs cover the switches from the checkpoint to the
+// block, chaining back to genesis without a checkpoint.
+func TestGetCommitteeProof(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	e := makePauseElection(t, []*ecdsa.PrivateKey{key}, []int64{400000})
+	e.fastchain = &proofChain{e.fastchain.(*stakingChain)}
+
+	first := types.GetFirstEpoch()
+	target := types.GetEpochFromID(first.EpochID + 3)
+	tooFar := types.GetEpochFromID(first.EpochID + maxProofCommittees)
+	members := []*types.CommitteeMember{{Publickey: crypto.FromECDSAPub(&key.PublicKey), Flag: types.StateUsedFlag}}
+	e.epochCache.Add(target.EpochID, &members)
+	e.epochCache.Add(tooFar.EpochID, &members)
+
+	number := new(big.Int).SetUint64(target.BeginHeight + 10)
+	tests := []struct {
+		number     *big.Int
+		checkpoint *big.Int
+		from       uint64
+		switches   int
+		err        error
+	}{
+		{number, nil, 0, 4, nil},
+		{number, new(big.Int).SetUint64(target.BeginHeight), target.BeginHeight, 1, nil},
+		{number, new(big.Int).SetUint64(target.BeginHeight - 1), target.BeginHeight - 1, 2, nil},
+		{number, new(big.Int).SetUint64(first.BeginHeight), first.BeginHeight, 4, nil},
+		{new(big.Int).SetUint64(tooFar.BeginHeight), new(big.Int).SetUint64(tooFar.BeginHeight), tooFar.BeginHeight, 1, nil},
+		{new(big.Int).SetUint64(tooFar.BeginHeight), nil, 0, 0, ErrProofTooLong},
+		{new(big.Int).SetUint64(tooFar.BeginHeight), new(big.Int).SetUint64(first.BeginHeight), 0, 0, ErrProofTooLong},
+	}
+	for i, tt := range tests {
+		proof, err := e.GetCommitteeProof(tt.number, tt.checkpoint)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if proof.Checkpoint.Uint64() != tt.from {
+			t.Errorf("test %d: checkpoint mismatch: have %v, want %d", i, proof.Checkpoint, tt.from)
+		}
+		if len(proof.Switches) != tt.switches {
+			t.Errorf("test %d: switch count mismatch: have %d, want %d", i, len(proof.Switches), tt.switches)
+		}
+		if last := proof.Switches[len(proof.Switches)-1].Header.Number.Uint64(); last != types.GetEpochFromHeight(tt.number.Uint64()).BeginHeight {
+			t.Errorf("test %d: latest switch mismatch: have %d", i, last)
+		}
+	}
+	if _, err := e.GetCommitteeProof(number, new(big.Int).Add(number, common.Big1)); err == nil {
+		t.Errorf("checkpoint above the block accepted")
+	}
+	// A paused switch is proven by the block actually switching the committee
+	until := target.BeginHeight + 100
+	statedb := e.fastchain.(*proofChain).state
+	statedb.SetState(types.StakingAddress, crypto.Keccak256Hash([]byte("committee-rotation-pause-"), new(big.Int).SetUint64(target.EpochID-1).Bytes()), common.BigToHash(new(big.Int).SetUint64(until)))
+
+	proof, err := e.GetCommitteeProof(new(big.Int).SetUint64(until+10), new(big.Int).SetUint64(until+1))
+	if err != nil {
+		t.Fatalf("failed to prove paused committee: %v", err)
+	}
+	if len(proof.Switches) != 1 || proof.Switches[0].Header.Number.Uint64() != until+1 {
+		t.Errorf("paused switch mismatch: have %v, want block %d", proof.Switches, until+1)
+	}
+	proof, err = e.GetCommitteeProof(new(big.Int).SetUint64(until+10), new(big.Int).SetUint64(until))
+	if err != nil {
+		t.Fatalf("failed to prove paused committee from before the switch: %v", err)
+	}
+	if len(proof.Switches) != 2 || proof.Switches[0].Header.Number.Uint64() != types.GetEpochFromID(target.EpochID-1).BeginHeight {
+		t.Errorf("paused committee proof mismatch: have %d switches", len(proof.Switches))
+	}
+}
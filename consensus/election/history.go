@@ -0,0 +1,187 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/params"
+)
+
+// committeeHistoryRange is the maximum number of committees a single history
+// query may span.
+const committeeHistoryRange = 128
+
+var ErrHistoryRange = errors.New("committee history range out of bounds")
+
+// CommitteeRecord is a lifecycle event of a committee as returned by the API.
+type CommitteeRecord struct {
+	Committee uint64           `json:"committee"`
+	Type      string           `json:"type"`   // start, stop, switchover, update or over
+	Number    uint64           `json:"number"` // Fast block the event takes effect at
+	Members   []common.Address `json:"members,omitempty"`
+	Added     []common.Address `json:"added,omitempty"`
+	Removed   []common.Address `json:"removed,omitempty"`
+}
+
+// committeeEventNames maps the committee event options to their API names.
+var committeeEventNames = map[uint64]string{
+	types.CommitteeStart:      "start",
+	types.CommitteeStop:       "stop",
+	types.CommitteeSwitchover: "switchover",
+	types.CommitteeUpdate:     "update",
+	types.CommitteeOver:       "over",
+}
+
+// sendElectionEvent records a committee event in the history and hands it to
+// the subscribers.
+func (e *Election) sendElectionEvent(ev types.ElectionEvent) {
+	number := ev.BeginFastNumber
+	if ev.Option == types.CommitteeStop || ev.Option == types.CommitteeOver {
+		number = ev.EndFastNumber
+	}
+	if ev.CommitteeID != nil {
+		var fast uint64
+		if number != nil {
+			fast = number.Uint64()
+		}
+		e.recordCommittee(uint64(ev.Option), ev.CommitteeID.Uint64(), fast, ev.CommitteeMembers)
+	}
+	e.electionFeed.Send(ev)
+}
+
+// recordEpoch records the committee switches of the epoch based validators,
+// which happen on fixed fast heights instead of through election events.
+func (e *Election) recordEpoch(number uint64) {
+	epoch := types.GetEpochFromHeight(number)
+	switchNumber, err := e.switchNumber(epoch, number)
+	if err != nil {
+		log.Warn("Failed to resolve committee switch", "number", number, "err", err)
+		return
+	}
+	if epoch.EpochID > 0 && number == switchNumber {
+		e.recordCommittee(types.CommitteeStop, epoch.EpochID-1, number-1, nil)
+		e.recordCommittee(types.CommitteeStart, epoch.EpochID, number, e.getValidators(new(big.Int).SetUint64(number)))
+	}
+	if number == epoch.EndHeight-params.ElectionPoint+1 {
+		next := types.GetEpochFromID(epoch.EpochID + 1)
+		members := e.epochValidators(next, new(big.Int).SetUint64(next.BeginHeight))
+		e.recordCommittee(types.CommitteeSwitchover, next.EpochID, next.BeginHeight, members)
+	}
+}
+
+// recordCommittee appends a committee event to the history, along with the
+// member changes compared to the last recorded member set. Events re-sent on
+// restarts or reorgs are recorded only once.
+func (e *Election) recordCommittee(option uint64, id uint64, number uint64, members []*types.CommitteeMember) {
+	if e.snailchain == nil {
+		return
+	}
+	db := e.snailchain.GetDatabase()
+
+	e.historyLock.Lock()
+	defer e.historyLock.Unlock()
+
+	history := rawdb.ReadCommitteeHistory(db, id)
+	for _, entry := range history {
+		if entry.Option == option && entry.Number == number {
+			return
+		}
+	}
+	entry := &rawdb.CommitteeHistoryEntry{Option: option, Number: number}
+	for _, m := range members {
+		entry.Members = append(entry.Members, m.CommitteeBase)
+	}
+	if len(entry.Members) > 0 {
+		entry.Added, entry.Removed = diffMembers(lastMembers(db, history, id), entry.Members)
+	}
+	rawdb.WriteCommitteeHistory(db, id, append(history, entry))
+}
+
+// lastMembers returns the last member set recorded for the committee, or for
+// the one before it if the committee has none yet.
+func lastMembers(db rawdb.DatabaseReader, history []*rawdb.CommitteeHistoryEntry, id uint64) []common.Address {
+	for i := len(history) - 1; i >= 0; i-- {
+		if len(history[i].Members) > 0 {
+			return history[i].Members
+		}
+	}
+	if id == 0 {
+		return nil
+	}
+	previous := rawdb.ReadCommitteeHistory(db, id-1)
+	for i := len(previous) - 1; i >= 0; i-- {
+		if len(previous[i].Members) > 0 {
+			return previous[i].Members
+		}
+	}
+	return nil
+}
+
+// diffMembers returns the members added to and removed from a member set.
+func diffMembers(old, cur []common.Address) (added, removed []common.Address) {
+	present := make(map[common.Address]bool, len(old))
+	for _, addr := range old {
+		present[addr] = true
+	}
+	for _, addr := range cur {
+		if !present[addr] {
+			added = append(added, addr)
+		}
+		delete(present, addr)
+	}
+	for _, addr := range old {
+		if present[addr] {
+			removed = append(removed, addr)
+		}
+	}
+	return added, removed
+}
+
+// CommitteeHistory returns the recorded lifecycle events of the committees
+// from one epoch to another, both inclusive.
+func (e *Election) CommitteeHistory(from, to uint64) ([]*CommitteeRecord, error) {
+	if to < from || to-from >= committeeHistoryRange {
+		return nil, ErrHistoryRange
+	}
+	records := make([]*CommitteeRecord, 0)
+	if e.snailchain == nil {
+		return records, nil
+	}
+	db := e.snailchain.GetDatabase()
+	for id := from; ; id++ {
+		for _, entry := range rawdb.ReadCommitteeHistory(db, id) {
+			records = append(records, &CommitteeRecord{
+				Committee: id,
+				Type:      committeeEventNames[entry.Option],
+				Number:    entry.Number,
+				Members:   entry.Members,
+				Added:     entry.Added,
+				Removed:   entry.Removed,
+			})
+		}
+		if id == to {
+			break
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+func historyMembers(bases ...byte) []*types.CommitteeMember {
+	var members []*types.CommitteeMember
+	for _, b := range bases {
+		members = append(members, &types.CommitteeMember{CommitteeBase: common.Address{b}, Flag: types.StateUsedFlag})
+	}
+	return members
+}
+
+// Tests that committee events are recorded once with their member changes and
+// can be queried by epoch range.
+func TestCommitteeHistory(t *testing.T) {
+	e := &Election{snailchain: &databaseChain{db: abeydb.NewMemDatabase()}}
+
+	events := []types.ElectionEvent{
+		{Option: types.CommitteeStart, CommitteeID: big.NewInt(1), BeginFastNumber: big.NewInt(100), CommitteeMembers: historyMembers(1, 2, 3)},
+		{Option: types.CommitteeStart, CommitteeID: big.NewInt(1), BeginFastNumber: big.NewInt(100), CommitteeMembers: historyMembers(1, 2, 3)},
+		{Option: types.CommitteeUpdate, CommitteeID: big.NewInt(1), BeginFastNumber: big.NewInt(150), CommitteeMembers: historyMembers(1, 3, 4)},
+		{Option: types.CommitteeOver, CommitteeID: big.NewInt(1), BeginFastNumber: big.NewInt(100), EndFastNumber: big.NewInt(199)},
+		{Option: types.CommitteeSwitchover, CommitteeID: big.NewInt(2), BeginFastNumber: big.NewInt(200), CommitteeMembers: historyMembers(3, 4, 5)},
+		{Option: types.CommitteeStop, CommitteeID: big.NewInt(1), BeginFastNumber: big.NewInt(100), EndFastNumber: big.NewInt(199), CommitteeMembers: historyMembers(1, 2, 3)},
+		{Option: types.CommitteeStart, CommitteeID: big.NewInt(2), BeginFastNumber: big.NewInt(200), CommitteeMembers: historyMembers(3, 4, 5)},
+	}
+	for _, ev := range events {
+		e.sendElectionEvent(ev)
+	}
+	records, err := e.CommitteeHistory(1, 2)
+	if err != nil {
+		t.Fatalf("failed to query history: %v", err)
+	}
+	want := []struct {
+		committee uint64
+		kind      string
+		number    uint64
+		added     []byte
+		removed   []byte
+	}{
+		{1, "start", 100, []byte{1, 2, 3}, nil},
+		{1, "update", 150, []byte{4}, []byte{2}},
+		{1, "over", 199, nil, nil},
+		{1, "stop", 199, []byte{2}, []byte{4}},
+		{2, "switchover", 200, []byte{5}, []byte{1}},
+		{2, "start", 200, nil, nil},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Committee != w.committee || r.Type != w.kind || r.Number != w.number {
+			t.Errorf("record %d: have %d/%s/%d, want %d/%s/%d", i, r.Committee, r.Type, r.Number, w.committee, w.kind, w.number)
+		}
+		if len(r.Added) != len(w.added) || len(r.Removed) != len(w.removed) {
+			t.Errorf("record %d: diff mismatch: have +%v -%v, want +%v -%v", i, r.Added, r.Removed, w.added, w.removed)
+			continue
+		}
+		for j, b := range w.added {
+			if r.Added[j] != (common.Address{b}) {
+				t.Errorf("record %d: added %d mismatch: have %x", i, j, r.Added[j])
+			}
+		}
+		for j, b := range w.removed {
+			if r.Removed[j] != (common.Address{b}) {
+				t.Errorf("record %d: removed %d mismatch: have %x", i, j, r.Removed[j])
+			}
+		}
+	}
+	if records, _ := e.CommitteeHistory(2, 2); len(records) != 2 {
+		t.Errorf("single committee record count mismatch: have %d, want 2", len(records))
+	}
+	if _, err := e.CommitteeHistory(2, 1); err != ErrHistoryRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, ErrHistoryRange)
+	}
+	if _, err := e.CommitteeHistory(0, committeeHistoryRange); err != ErrHistoryRange {
+		t.Errorf("oversized range error mismatch: have %v, want %v", err, ErrHistoryRange)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/core/vm"
+)
+
+// errMissingPauseState is returned if the state recording the rotation pauses
+// needed to resolve a committee isn't available.
+var errMissingPauseState = errors.New("rotation pause state unavailable")
+
+// Rotation pauses are recorded by the staking contract (see core/vm), the
+// election reads them from the state of the parent of the fast block whose
+// committee is resolved. A pause is only accepted while the committee is in
+// charge, so that state tells every node the same committee no matter how far
+// it has synced. Before TIP14 no pause can be recorded and no state is read.
+
+// pausable reports whether the switch to the committee of the epoch may have
+// been postponed, which needs a pause of a staked committee recorded from
+// TIP14 on before the switch.
+func (e *Election) pausable(epoch *types.EpochIDInfo) bool {
+	if epoch.EpochID == 0 || epoch.BeginHeight == 0 || e.chainConfig == nil || e.chainConfig.TIP8 == nil {
+		return false
+	}
+	if !e.isTIP8FromCID(epoch.EpochID - 1) {
+		return false
+	}
+	return e.chainConfig.IsTIP14(new(big.Int).SetUint64(epoch.BeginHeight - 1))
+}
+
+// pauseState returns the state holding the rotation pauses known to the parent
+// of the fast block, or to the head if the parent isn't imported yet, along
+// with the number of the block it belongs to.
+func (e *Election) pauseState(number uint64) (vm.StateDB, uint64, error) {
+	if e.fastchain == nil {
+		return nil, 0, errMissingPauseState
+	}
+	block := e.fastchain.CurrentBlock()
+	if number > 0 && number-1 < block.NumberU64() {
+		if block = e.fastchain.GetBlockByNumber(number - 1); block == nil {
+			return nil, 0, fmt.Errorf("%v: missing block %d", errMissingPauseState, number-1)
+		}
+	}
+	stateDb, err := e.fastchain.StateAt(block.Root())
+	if err != nil {
+		return nil, 0, fmt.Errorf("%v: block %d: %v", errMissingPauseState, block.NumberU64(), err)
+	}
+	return stateDb, block.NumberU64(), nil
+}
+
+// switchNumber returns the first fast block the committee of the epoch is in
+// charge of, as known to the parent of the given fast block. Switches that
+// happened by then can't be postponed anymore and are cached.
+func (e *Election) switchNumber(epoch *types.EpochIDInfo, number uint64) (uint64, error) {
+	if !e.pausable(epoch) {
+		return epoch.BeginHeight, nil
+	}
+	if cached, ok := e.switchCache.Get(epoch.EpochID); ok {
+		return cached.(uint64), nil
+	}
+	stateDb, height, err := e.pauseState(number)
+	if err != nil {
+		return 0, err
+	}
+	switchNumber := vm.RotationSwitchNumber(stateDb, epoch)
+	if height+1 >= switchNumber {
+		e.switchCache.Add(epoch.EpochID, switchNumber)
+	}
+	return switchNumber, nil
+}
+
+// RotationPause returns the pause recorded for a committee in the head state,
+// nil if there is none.
+func (e *Election) RotationPause(committeeID uint64) (*types.RotationPause, error) {
+	if e.chainConfig == nil || e.chainConfig.TIP14 == nil {
+		return nil, nil
+	}
+	stateDb, _, err := e.pauseState(math.MaxUint64)
+	if err != nil {
+		return nil, err
+	}
+	until := vm.RotationPauseUntil(stateDb, committeeID)
+	if until == 0 {
+		return nil, nil
+	}
+	return &types.RotationPause{
+		CommitteeID: new(big.Int).SetUint64(committeeID),
+		Until:       new(big.Int).SetUint64(until),
+	}, nil
+}
+
+// SwitchNumber returns the first fast block the committee of the epoch is in
+// charge of, which is later than the epoch's begin if the switch was paused.
+// A pause can't outlast the epoch, so its last block resolves the switch for good.
+func (e *Election) SwitchNumber(epoch *types.EpochIDInfo) (uint64, error) {
+	return e.switchNumber(epoch, epoch.EndHeight+1)
+}
+
+// CommitteeEpoch returns the epoch whose committee is in charge of a fast block.
+func (e *Election) CommitteeEpoch(fastNumber uint64) (*types.EpochIDInfo, error) {
+	epoch := types.GetEpochFromHeight(fastNumber)
+	switchNumber, err := e.switchNumber(epoch, fastNumber)
+	if err != nil {
+		return nil, err
+	}
+	if fastNumber < switchNumber {
+		return types.GetEpochFromID(epoch.EpochID - 1), nil
+	}
+	return epoch, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/accounts/abi"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/state"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/core/vm"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// stakingChain is a fast chain stub whose head state holds the staking of a
+// committee. States of other blocks are missing unless added.
+type stakingChain struct {
+	BlockChain
+	head   *types.Block
+	state  *state.StateDB
+	blocks map[uint64]*types.Block
+	states map[common.Hash]*state.StateDB
+}
+
+func (c *stakingChain) CurrentBlock() *types.Block { return c.head }
+
+func (c *stakingChain) GetBlockByNumber(number uint64) *types.Block {
+	if number == c.head.NumberU64() {
+		return c.head
+	}
+	return c.blocks[number]
+}
+
+func (c *stakingChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	if root == c.head.Root() && c.state != nil {
+		return c.state, nil
+	}
+	if statedb, ok := c.states[root]; ok {
+		return statedb, nil
+	}
+	return nil, errors.New("missing trie node")
+}
+
+// makePauseElection creates an election in the first epoch whose committee is
+// staked by the given keys with the given amounts.
+func makePauseElection(t *testing.T, keys []*ecdsa.PrivateKey, stakes []int64) *Election {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+
+	impawn := vm.NewImpawnImpl()
+	for i, key := range keys {
+		amount := new(big.Int).Mul(big.NewInt(stakes[i]), big.NewInt(1e18))
+		if err := impawn.InsertSAccount2(0, 0, crypto.PubkeyToAddress(key.PublicKey), crypto.FromECDSAPub(&key.PublicKey), amount, big.NewInt(50), true); err != nil {
+			t.Fatalf("failed to stake: %v", err)
+		}
+	}
+	if _, err := impawn.DoElections(1, 0); err != nil {
+		t.Fatalf("failed to elect: %v", err)
+	}
+	if err := impawn.Save(statedb, types.StakingAddress); err != nil {
+		t.Fatalf("failed to save staking: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(0), CID: big.NewInt(0)}
+	config.TIP14 = &params.BlockConfig{FastNumber: big.NewInt(0)}
+
+	epochCache, _ := lru.New(committeeCacheLimit)
+	switchCache, _ := lru.New(committeeCacheLimit)
+	return &Election{
+		chainConfig: &config,
+		fastchain:   &stakingChain{head: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100)}), state: statedb},
+		snailchain:  &databaseChain{db: abeydb.NewMemDatabase()},
+		epochCache:  epochCache,
+		switchCache: switchCache,
+	}
+}
+
+// Tests that the election resolves the committee in charge of fast blocks from
+// the rotation pauses recorded in the head state, and reports the postponed
+// switch consistently.
+func TestRotationPause(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+	}
+	e := makePauseElection(t, keys, []int64{400000, 300000, 200000, 200000})
+	statedb := e.fastchain.(*stakingChain).state
+
+	first := types.GetFirstEpoch()
+	next := types.GetEpochFromID(first.EpochID + 1)
+	if pause, err := e.RotationPause(first.EpochID); err != nil || pause != nil {
+		t.Fatalf("unrecorded pause reported: %v, %v", pause, err)
+	}
+	if n, err := e.SwitchNumber(next); err != nil || n != next.BeginHeight {
+		t.Fatalf("unpaused switch number mismatch: have %d (%v), want %d", n, err, next.BeginHeight)
+	}
+	// Record a pause through the staking contract
+	pause := &types.RotationPause{CommitteeID: new(big.Int).SetUint64(first.EpochID), Until: new(big.Int).SetUint64(first.EndHeight + 1000), Reason: "test"}
+	var sigs []byte
+	hash := pause.SigHash(e.chainConfig.ChainID)
+	for _, key := range keys[:3] {
+		sig, _ := crypto.Sign(hash[:], key)
+		sigs = append(sigs, sig...)
+	}
+	staking, _ := abi.JSON(strings.NewReader(vm.StakeABIJSON))
+	input, err := staking.Pack("pauseRotation", pause.CommitteeID, pause.Until, pause.Reason, sigs)
+	if err != nil {
+		t.Fatalf("failed to pack pause: %v", err)
+	}
+	evm := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(101), Time: big.NewInt(100)}, statedb, e.chainConfig, vm.Config{})
+	contract := vm.NewContract(vm.AccountRef(common.Address{}), vm.AccountRef(types.StakingAddress), big.NewInt(0), 0)
+	if _, err := vm.RunStaking(evm, contract, input); err != nil {
+		t.Fatalf("failed to pause rotation: %v", err)
+	}
+	if have, err := e.RotationPause(first.EpochID); err != nil || have == nil || have.Until.Cmp(pause.Until) != 0 {
+		t.Fatalf("recorded pause mismatch: have %v (%v), want until %v", have, err, pause.Until)
+	}
+	if n, err := e.SwitchNumber(next); err != nil || n != pause.Until.Uint64()+1 {
+		t.Fatalf("switch number mismatch: have %d (%v), want %d", n, err, pause.Until.Uint64()+1)
+	}
+	tests := []struct {
+		number uint64
+		epoch  uint64
+		begin  uint64
+	}{
+		{first.EndHeight, first.EpochID, first.BeginHeight},
+		{next.BeginHeight, first.EpochID, first.BeginHeight},
+		{pause.Until.Uint64(), first.EpochID, first.BeginHeight},
+		{pause.Until.Uint64() + 1, next.EpochID, pause.Until.Uint64() + 1},
+	}
+	for i, tt := range tests {
+		if epoch, err := e.CommitteeEpoch(tt.number); err != nil || epoch.EpochID != tt.epoch {
+			t.Errorf("test %d: committee in charge of %d mismatch: have %v (%v), want %d", i, tt.number, epoch, err, tt.epoch)
+		}
+		if begin, _ := e.getMembers(new(big.Int).SetUint64(tt.number)); begin.Uint64() != tt.begin {
+			t.Errorf("test %d: members of %d begin mismatch: have %d, want %d", i, tt.number, begin, tt.begin)
+		}
+	}
+}
+
+// Tests that the committee in charge of a fast block is resolved from the state
+// of its parent, and that a missing state fails instead of reporting no pause.
+func TestRotationPauseParentState(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	e := makePauseElection(t, []*ecdsa.PrivateKey{key}, []int64{400000})
+	chain := e.fastchain.(*stakingChain)
+
+	first := types.GetFirstEpoch()
+	next := types.GetEpochFromID(first.EpochID + 1)
+
+	// Move the head past the switch, the parent state of the switch block is missing
+	head := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(next.BeginHeight + 10), Root: common.Hash{0x01}})
+	parent := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(next.BeginHeight - 1), Root: common.Hash{0x02}})
+	chain.head, chain.state = head, nil
+	chain.blocks = map[uint64]*types.Block{parent.NumberU64(): parent}
+
+	if _, err := e.CommitteeEpoch(next.BeginHeight); err == nil {
+		t.Fatalf("committee resolved without the parent state")
+	}
+	if _, err := e.SwitchNumber(next); err == nil {
+		t.Fatalf("switch resolved without the state")
+	}
+	if members := e.GetCommittee(new(big.Int).SetUint64(next.BeginHeight)); members != nil {
+		t.Fatalf("committee members reported without the parent state: %v", members)
+	}
+	// A pause recorded in the parent state postpones the switch
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	until := first.EndHeight + 1000
+	statedb.SetState(types.StakingAddress, crypto.Keccak256Hash([]byte("committee-rotation-pause-"), new(big.Int).SetUint64(first.EpochID).Bytes()), common.BigToHash(new(big.Int).SetUint64(until)))
+	chain.states = map[common.Hash]*state.StateDB{parent.Root(): statedb}
+
+	if epoch, err := e.CommitteeEpoch(next.BeginHeight); err != nil || epoch.EpochID != first.EpochID {
+		t.Fatalf("paused committee mismatch: have %v (%v), want %d", epoch, err, first.EpochID)
+	}
+	// Without TIP14 no pause can be recorded and no state is needed
+	e.chainConfig.TIP14 = nil
+	e.switchCache.Purge()
+	if epoch, err := e.CommitteeEpoch(next.BeginHeight); err != nil || epoch.EpochID != next.EpochID {
+		t.Fatalf("committee before TIP14 mismatch: have %v (%v), want %d", epoch, err, next.EpochID)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+)
+
+// The committee caches and the stored switch records are derived from chain
+// data. If they get out of sync with it, e.g. after an unclean shutdown or a
+// reorg racing a switch, valid switch blocks are rejected and the pbft agent
+// keeps running with a stale member set until the node is restarted. When a
+// switch block disagrees with the local view, the derived data is dropped and
+// recomputed from the chain before the block is finally judged.
+
+// recoveryInterval limits how often the committee view is recomputed, as
+// invalid switch blocks from the network would trigger it too.
+const recoveryInterval = time.Minute
+
+// recoverCommittee drops the cached committee data and recomputes the current
+// and next committees from the chain. The pbft agent is asynchronously
+// notified of the recomputed current committee. It returns false if nothing
+// was recomputed, either because a recovery ran too recently or the election
+// is faked.
+func (e *Election) recoverCommittee(fastNumber *big.Int) bool {
+	if e.electionMode == ElectModeFake {
+		return false
+	}
+	e.recoverMu.Lock()
+	defer e.recoverMu.Unlock()
+
+	if time.Since(e.lastRecovery) < recoveryInterval {
+		return false
+	}
+	e.lastRecovery = time.Now()
+	log.Warn("Recomputing committee view from chain", "number", fastNumber)
+
+	e.commiteeCache.Purge()
+	e.epochCache.Purge()
+	e.indexCache.Purge()
+
+	if e.IsTIP8(fastNumber) {
+		return true
+	}
+	e.mu.RLock()
+	current, next := e.committee, e.nextCommittee
+	e.mu.RUnlock()
+
+	fastHeadNumber := e.fastchain.CurrentHeader().Number
+	snailHeadNumber := e.snailchain.CurrentHeader().Number
+
+	var fresh *committee
+	if current != nil {
+		fresh = e.getCommittee(fastHeadNumber, snailHeadNumber)
+		if fresh == nil || fresh.id.Cmp(current.id) != 0 {
+			log.Warn("Committee recomputation mismatch", "current", current.id, "recomputed", fresh)
+			fresh = nil
+		}
+	}
+	if fresh != nil {
+		// The persisted index may have been built from corrupted switches
+		rawdb.DeleteCommitteeIndex(e.snailchain.GetDatabase(), fresh.id.Uint64())
+		e.reconcileSwitches(fresh, fastHeadNumber)
+
+		// The end is only known once the election of the next committee started
+		if fresh.endFastNumber.Sign() == 0 && current.endFastNumber != nil {
+			fresh.endFastNumber = new(big.Int).Set(current.endFastNumber)
+		}
+		fresh.switchCheckNumber = current.switchCheckNumber
+	}
+	var freshNext *committee
+	if next != nil {
+		freshNext = e.calcCommittee(next.id)
+	}
+	e.mu.Lock()
+	if fresh != nil && e.committee == current {
+		e.committee = fresh
+	}
+	if freshNext != nil && e.nextCommittee == next {
+		e.nextCommittee = freshNext
+	}
+	e.mu.Unlock()
+
+	if fresh != nil {
+		// Recoveries run while validating blocks, the agent must not be able
+		// to stall the import by not draining its events
+		members, backups := e.filterWithSwitchInfo(fresh)
+		go e.sendElectionEvent(types.ElectionEvent{
+			Option:           types.CommitteeUpdate,
+			CommitteeID:      fresh.id,
+			BeginFastNumber:  fresh.beginFastNumber,
+			EndFastNumber:    fresh.endFastNumber,
+			CommitteeMembers: members,
+			BackupMembers:    backups,
+		})
+	}
+	return true
+}
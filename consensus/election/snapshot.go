@@ -0,0 +1,249 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package election
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+var (
+	// ErrGenesisElection is returned when exporting the genesis committee, which
+	// is defined in the genesis block rather than elected.
+	ErrGenesisElection = errors.New("genesis committee is not elected")
+
+	// ErrElectionUnfinished is returned when the election window of a committee
+	// is not yet part of the local snail chain.
+	ErrElectionUnfinished = errors.New("election window not reached")
+
+	// ErrSwitchInfoMismatch is returned if the re-elected committee differs from
+	// the switch infos recorded on chain.
+	ErrSwitchInfoMismatch = errors.New("elected committee mismatch with switch infos")
+
+	// ErrStakedCommittee is returned for committees chosen by the staking
+	// contract since TIP8, which have no fruit election to re-run.
+	ErrStakedCommittee = errors.New("committee is chosen by stake, not elected")
+)
+
+// ElectionSnapshot holds all inputs of a committee election: the snail headers
+// of the election window and the fruit headers they include. It allows anyone
+// to re-run the election offline and audit the committee selection.
+//
+// Before TIP5 the fruits hash of a snail header commits to the whole fruits,
+// which are kept for those headers so their fruit headers can be verified too.
+type ElectionSnapshot struct {
+	Committee    uint64                 // Id of the elected committee
+	Begin, End   uint64                 // Snail block window of the election
+	SwitchNumber uint64                 // Fast block recording the committee switch infos
+	Headers      []*types.SnailHeader   // Snail headers of the window, in ascending order
+	Fruits       [][]*types.SnailHeader // Fruit headers of each snail header
+	FruitBlocks  [][]*types.SnailBlock  // Whole fruits of each snail header before TIP5
+}
+
+// GetHeaderByNumber implements snailReader, returns nil outside the window.
+func (s *ElectionSnapshot) GetHeaderByNumber(number uint64) *types.SnailHeader {
+	if number < s.Begin || number > s.End || number-s.Begin >= uint64(len(s.Headers)) {
+		return nil
+	}
+	return s.Headers[number-s.Begin]
+}
+
+// GetFruitsHead implements snailReader, returns nil outside the window.
+func (s *ElectionSnapshot) GetFruitsHead(number uint64) []*types.SnailHeader {
+	if number < s.Begin || number > s.End || number-s.Begin >= uint64(len(s.Fruits)) {
+		return nil
+	}
+	return s.Fruits[number-s.Begin]
+}
+
+// stakedCommittee reports whether the committee is chosen by the staking
+// contract instead of being elected from fruits.
+func (e *Election) stakedCommittee(id uint64) bool {
+	return e.chainConfig.TIP8 != nil && e.isTIP8FromCID(id)
+}
+
+// electionWindow returns the snail blocks the given committee is elected from.
+func electionWindow(id *big.Int) (*big.Int, *big.Int) {
+	switchCheckNumber := new(big.Int).Mul(id, params.ElectionPeriodNumber)
+	end := new(big.Int).Sub(switchCheckNumber, params.SnailConfirmInterval)
+	begin := new(big.Int).Add(new(big.Int).Sub(end, params.ElectionPeriodNumber), common.Big1)
+	if begin.Cmp(common.Big0) <= 0 {
+		begin = new(big.Int).Set(common.Big1)
+	}
+	return begin, end
+}
+
+// ExportElection collects the election inputs of the given committee from the
+// local snail chain.
+func (e *Election) ExportElection(id uint64) (*ElectionSnapshot, error) {
+	if id == 0 {
+		return nil, ErrGenesisElection
+	}
+	if e.stakedCommittee(id) {
+		return nil, ErrStakedCommittee
+	}
+	begin, end := electionWindow(new(big.Int).SetUint64(id))
+	if e.snailchain.CurrentHeader().Number.Cmp(end) < 0 {
+		return nil, ErrElectionUnfinished
+	}
+	lastFast := e.getLastNumber(begin, end)
+	if lastFast == nil {
+		return nil, fmt.Errorf("missing snail blocks in election window %v-%v", begin, end)
+	}
+	snap := &ElectionSnapshot{
+		Committee:    id,
+		Begin:        begin.Uint64(),
+		End:          end.Uint64(),
+		SwitchNumber: lastFast.Uint64() + 1,
+	}
+	for number := snap.Begin; number <= snap.End; number++ {
+		header := e.snailchain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing snail header %d", number)
+		}
+		snap.Headers = append(snap.Headers, header)
+		snap.Fruits = append(snap.Fruits, e.snailchain.GetFruitsHead(number))
+
+		var fruits []*types.SnailBlock
+		if !e.chainConfig.IsTIP5(header.Number) {
+			block := e.snailchain.GetBlockByNumber(number)
+			if block == nil {
+				return nil, fmt.Errorf("missing snail block %d", number)
+			}
+			fruits = block.Fruits()
+		}
+		snap.FruitBlocks = append(snap.FruitBlocks, fruits)
+	}
+	return snap, nil
+}
+
+// VerifyElection checks the snapshot is a consistent piece of snail chain for
+// the election window of its committee and re-runs the election over it with
+// the default members of the local genesis.
+func (e *Election) VerifyElection(snap *ElectionSnapshot) (*types.ElectionCommittee, error) {
+	if snap.Committee == 0 {
+		return nil, ErrGenesisElection
+	}
+	if e.stakedCommittee(snap.Committee) {
+		return nil, ErrStakedCommittee
+	}
+	begin, end := electionWindow(new(big.Int).SetUint64(snap.Committee))
+	if snap.Begin != begin.Uint64() || snap.End != end.Uint64() {
+		return nil, fmt.Errorf("election window mismatch: have %d-%d, want %v-%v", snap.Begin, snap.End, begin, end)
+	}
+	if count := snap.End - snap.Begin + 1; uint64(len(snap.Headers)) != count || uint64(len(snap.Fruits)) != count || uint64(len(snap.FruitBlocks)) != count {
+		return nil, fmt.Errorf("snapshot size mismatch: have %d headers %d fruits %d fruit blocks, want %d", len(snap.Headers), len(snap.Fruits), len(snap.FruitBlocks), count)
+	}
+	for i, header := range snap.Headers {
+		if header.Number.Uint64() != snap.Begin+uint64(i) {
+			return nil, fmt.Errorf("snail header number mismatch: have %v, want %d", header.Number, snap.Begin+uint64(i))
+		}
+		if i > 0 && header.ParentHash != snap.Headers[i-1].Hash() {
+			return nil, fmt.Errorf("snail header %v not linked to its parent", header.Number)
+		}
+		if err := verifySnapshotFruits(e.chainConfig, header, snap.Fruits[i], snap.FruitBlocks[i]); err != nil {
+			return nil, err
+		}
+	}
+	fruits := snap.Fruits[len(snap.Fruits)-1]
+	if len(fruits) == 0 {
+		return nil, fmt.Errorf("snail header %d without fruits", snap.End)
+	}
+	lastFast := new(big.Int).Add(fruits[len(fruits)-1].FastNumber, params.ElectionSwitchoverNumber)
+	if snap.SwitchNumber != lastFast.Uint64()+1 {
+		return nil, fmt.Errorf("switch number mismatch: have %d, want %v", snap.SwitchNumber, lastFast.Uint64()+1)
+	}
+	return ElectCommittee(snap, e.defaultMembers, begin, end), nil
+}
+
+// verifySnapshotFruits checks the fruit headers of a snail header against its
+// fruits hash. Before TIP5 the hash covers the whole fruits, which must then be
+// present and match the fruit headers.
+func verifySnapshotFruits(config *params.ChainConfig, header *types.SnailHeader, headers []*types.SnailHeader, fruits []*types.SnailBlock) error {
+	hash := types.EmptyRootHash
+	if config.IsTIP5(header.Number) {
+		if len(fruits) > 0 {
+			return fmt.Errorf("unexpected fruit blocks in snail header %v", header.Number)
+		}
+		if len(headers) > 0 {
+			hash = types.DeriveSha(types.FruitsHeaders(headers))
+		}
+	} else {
+		if len(fruits) != len(headers) {
+			return fmt.Errorf("fruit blocks mismatch in snail header %v: have %d, want %d", header.Number, len(fruits), len(headers))
+		}
+		for i, fruit := range fruits {
+			if fruit.Hash() != headers[i].Hash() {
+				return fmt.Errorf("fruit %d mismatch in snail header %v", i, header.Number)
+			}
+		}
+		if len(fruits) > 0 {
+			hash = types.DeriveSha(types.Fruits(fruits))
+		}
+	}
+	if hash != header.FruitsHash {
+		return fmt.Errorf("fruits hash mismatch in snail header %v", header.Number)
+	}
+	return nil
+}
+
+// CompareSwitchInfos checks an elected committee against the switch infos of
+// its switch block.
+func CompareSwitchInfos(committee *types.ElectionCommittee, infos []*types.CommitteeMember) error {
+	var members, backups []*types.CommitteeMember
+	for _, m := range infos {
+		switch m.Flag {
+		case types.StateUsedFlag:
+			members = append(members, m)
+		case types.StateUnusedFlag:
+			backups = append(backups, m)
+		}
+	}
+	if !sameMembers(committee.Members, members) {
+		return fmt.Errorf("%v: members", ErrSwitchInfoMismatch)
+	}
+	if !sameMembers(committee.Backups, backups) {
+		return fmt.Errorf("%v: backups", ErrSwitchInfoMismatch)
+	}
+	return nil
+}
+
+// sameMembers reports whether both lists hold the same public keys.
+func sameMembers(a, b []*types.CommitteeMember) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		found := false
+		for _, n := range b {
+			if bytes.Equal(m.Publickey, n.Publickey) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -57,6 +57,10 @@ var (
 
 	ErrInvalidBlock = errors.New("invalid snail block")
 
+	// ErrNoFruits is returned if a snail block doesn't carry a valid number of
+	// fruits. Only the genesis snail block is allowed to be fruitless.
+	ErrNoFruits = errors.New("invalid fruits count")
+
 	ErrUnknownPointer = errors.New("unknown pointer hash")
 
 	ErrFreshness = errors.New("invalid fruit freshness")
@@ -24,6 +24,9 @@ import (
 
 const (
 	epochLength = 12000 // Blocks per epoch
+
+	// EpochLength is the number of snail blocks mined over the same dataset.
+	EpochLength = epochLength
 )
 
 //var trueInit int = 0;
@@ -26,6 +26,7 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/abeychain/go-abey/common"
@@ -107,6 +108,46 @@ func BenchmarkTruehashFullSmall(b *testing.B) {
 	}
 }
 
+// Benchmarks the seal verification throughput of a batch of snail headers
+// spread over a limited number of verifiers, as done when syncing. Run with
+// `make bench-lowpower` to judge whether a device keeps up with the chain.
+func BenchmarkSealVerification1(b *testing.B) { benchmarkSealVerification(b, 1) }
+func BenchmarkSealVerification2(b *testing.B) { benchmarkSealVerification(b, 2) }
+func BenchmarkSealVerification4(b *testing.B) { benchmarkSealVerification(b, 4) }
+
+func benchmarkSealVerification(b *testing.B, workers int) {
+	const batch = 128
+
+	dataset := make([]uint64, TBLSIZE*DATALENGTH*PMTSIZE*32)
+	hash := hexutil.MustDecode("0xc9149cc0386e689d789a1c2f3d5d169a61a6218ed30e74414dc736e442ef3d1f")
+
+	m := &Minerva{config: Config{VerifyWorkers: workers}}
+	workers = m.verifyWorkers(batch)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var (
+			nonces = make(chan uint64)
+			wg     sync.WaitGroup
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for nonce := range nonces {
+					truehashLight(dataset, hash, nonce)
+				}
+			}()
+		}
+		for nonce := uint64(0); nonce < batch; nonce++ {
+			nonces <- nonce
+		}
+		close(nonces)
+		wg.Wait()
+	}
+	b.ReportMetric(float64(b.N*batch)/b.Elapsed().Seconds(), "headers/s")
+}
+
 func makeDatasetHash(dataset []uint64) {
 	var datas []byte
 	tmp := make([]byte, 8)
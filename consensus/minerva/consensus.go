@@ -38,7 +38,7 @@ import (
 
 // Minerva protocol constants.
 var (
-	allowedFutureBlockTime = 15 * time.Second // Max time from current time allowed for blocks, before they're considered future blocks
+	allowedFutureBlockTime = consensus.AllowedFutureBlockTime // Max time from current time allowed for blocks, before they're considered future blocks
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -52,6 +52,9 @@ var (
 	errInvalidMixDigest  = errors.New("invalid mix digest")
 	errInvalidPoW        = errors.New("invalid proof-of-work")
 	errInvalidFast       = errors.New("invalid fast number")
+	errCommitteeHash     = errors.New("invalid committee set hash")
+	errSnailPointer      = errors.New("invalid snail pointer")
+	errUnknownCommittee  = errors.New("unknown committee")
 	//ErrRewardedBlock is returned if a block to import is already rewarded.
 	ErrRewardedBlock = errors.New("block already rewarded")
 	ErrRewardEnd     = errors.New("Reward end")
@@ -152,6 +155,19 @@ func (m *Minerva) VerifySnailHeader(chain consensus.SnailChainReader, fastchain
 	return m.verifySnailHeader(chain, fastchain, header, nil, parents, false, seal, isFruit)
 }
 
+// verifyWorkers returns the number of concurrent verifiers to spawn for a batch
+// of headers: one per allowed thread unless configured lower.
+func (m *Minerva) verifyWorkers(headers int) int {
+	workers := runtime.GOMAXPROCS(0)
+	if m.config.VerifyWorkers > 0 && m.config.VerifyWorkers < workers {
+		workers = m.config.VerifyWorkers
+	}
+	if headers < workers {
+		workers = headers
+	}
+	return workers
+}
+
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
 // concurrently. The method returns a quit channel to abort the operations and
 // a results channel to retrieve the async verifications.
@@ -167,10 +183,7 @@ func (m *Minerva) VerifyHeaders(chain consensus.ChainReader, headers []*types.He
 	}
 
 	// Spawn as many workers as allowed threads
-	workers := runtime.GOMAXPROCS(0)
-	if len(headers) < workers {
-		workers = len(headers)
-	}
+	workers := m.verifyWorkers(len(headers))
 
 	// Create a task channel and spawn the verifiers
 	var (
@@ -231,10 +244,7 @@ func (m *Minerva) VerifySnailHeaders(chain consensus.SnailChainReader, headers [
 	}
 
 	// Spawn as many workers as allowed threads
-	workers := runtime.GOMAXPROCS(0)
-	if len(headers) < workers {
-		workers = len(headers)
-	}
+	workers := m.verifyWorkers(len(headers))
 
 	// Create a task channel and spawn the verifiers
 	var (
@@ -379,8 +389,13 @@ func (m *Minerva) verifySnailHeaderWorker(chain consensus.SnailChainReader, head
 // verifyHeader checks whether a header conforms to the consensus rules of the
 // stock Abeychain minerva engine.
 func (m *Minerva) verifyHeader(chain consensus.ChainReader, header, parent *types.Header) error {
-	// Ensure that the header's extra-data section is of a reasonable size
-	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+	// Ensure that the header's extra-data section is of a reasonable size,
+	// after TIP10 it must hold the committee and snail commitment instead
+	if chain.Config().IsTIP10(header.Number) {
+		if err := m.verifyHeaderExtension(chain, header, parent); err != nil {
+			return err
+		}
+	} else if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
 	}
 	// Verify the header's timestamp
@@ -421,14 +436,71 @@ func (m *Minerva) verifyHeader(chain consensus.ChainReader, header, parent *type
 
 	return nil
 }
+
+// verifyHeaderExtension checks the TIP10 commitment of a fast header: the
+// committee set hash must match the committee elected for the block and the
+// snail pointer must never move backwards. The pointer is looked up by hash,
+// so snail reorgs don't invalidate fast headers committed to a block that left
+// the canonical chain. Pointers to snail blocks not known yet are accepted,
+// a known one must match the committed number.
+func (m *Minerva) verifyHeaderExtension(chain consensus.ChainReader, header, parent *types.Header) error {
+	ext, err := types.DecodeHeaderExtension(header.Extra)
+	if err != nil {
+		return err
+	}
+	if m.election != nil {
+		members := m.election.GetCommittee(header.Number)
+		if len(members) == 0 {
+			return errUnknownCommittee
+		}
+		if ext.CommitteeHash != types.CommitteeSetHash(members) {
+			return errCommitteeHash
+		}
+	}
+	if chain.Config().IsTIP10(parent.Number) {
+		prev, err := types.DecodeHeaderExtension(parent.Extra)
+		if err != nil {
+			return err
+		}
+		if ext.SnailNumber < prev.SnailNumber {
+			return errSnailPointer
+		}
+	}
+	if m.sbc != nil {
+		if snail := m.sbc.GetHeaderByHash(ext.SnailHash); snail != nil && snail.Number.Uint64() != ext.SnailNumber {
+			return errSnailPointer
+		}
+	}
+	return nil
+}
+
+// verifySnailExtra checks the size of a snail header's extra-data. After TIP13
+// it may be exceeded by a coinbase proof, which must then be valid. The miner
+// signs its work before knowing whether the seal makes a fruit or a block, so
+// the proof is accepted on both.
+func verifySnailExtra(config *params.ChainConfig, header *types.SnailHeader) error {
+	if uint64(len(header.Extra)) <= params.MaximumExtraDataSize {
+		return nil
+	}
+	if !config.IsTIP13(header.FastNumber) {
+		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
+	}
+	return types.VerifyCoinbaseProof(header)
+}
+
 func (m *Minerva) verifySnailHeader(chain consensus.SnailChainReader, fastchain consensus.ChainReader, header, pointer *types.SnailHeader,
 	parents []*types.SnailHeader, uncle bool, seal bool, isFruit bool) error {
+	if !isFruit {
+		if err := m.script.headerFailure(header.Number.Uint64()); err != nil {
+			return err
+		}
+	}
 	if !isFruit && m.sbc != nil && header.Number.Cmp(m.sbc.Config().TIP9.SnailNumber) > 0 {
 		return errors.New("snail block had disable")
 	}
 	// Ensure that the header's extra-data section is of a reasonable size
-	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
-		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
+	if err := verifySnailExtra(chain.Config(), header); err != nil {
+		return err
 	}
 	// Verify the header's timestamp
 	if uncle {
@@ -481,6 +553,11 @@ func (m *Minerva) verifySnailHeader(chain consensus.SnailChainReader, fastchain
 // the difficulty that a new block should have when created at time
 // given the parent block's time and difficulty.
 func (m *Minerva) CalcSnailDifficulty(chain consensus.SnailChainReader, time uint64, parents []*types.SnailHeader) *big.Int {
+	if len(parents) > 0 {
+		if diff := m.script.difficulty(parents[len(parents)-1].Number.Uint64() + 1); diff != nil {
+			return diff
+		}
+	}
 	return CalcDifficulty(chain.Config(), time, parents)
 }
 
@@ -489,7 +566,9 @@ func (m *Minerva) CalcFruitDifficulty(chain consensus.SnailChainReader, time uin
 	return CalcFruitDifficulty(chain.Config(), time, fastTime, pointer)
 }
 
-// VerifySigns check the sings included in fast block or fruit
+// VerifySigns check the sings included in fast block or fruit. The cheap quorum
+// count runs before any signer is recovered, so blocks lacking it are rejected
+// without touching the signatures.
 func (m *Minerva) VerifySigns(fastnumber *big.Int, fastHash common.Hash, signs []*types.PbftSign) error {
 	// validate the signatures of this fruit
 	ms := make(map[common.Address]uint)
@@ -711,6 +790,11 @@ func (m *Minerva) VerifySnailSeal(chain consensus.SnailChainReader, header *type
 		if m.fakeFail == header.Number.Uint64() {
 			return errInvalidPoW
 		}
+		if !isFruit {
+			if err := m.script.sealFailure(header.Number.Uint64()); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 	// If we're running a shared PoW, delegate verification to it
@@ -795,9 +879,25 @@ func (m *Minerva) VerifySnailSeal2(hight *big.Int, nonce string, headNoNoncehash
 // Prepare implements consensus.Engine, initializing the difficulty field of a
 // header to conform to the minerva protocol. The changes are done inline.
 func (m *Minerva) Prepare(chain consensus.ChainReader, header *types.Header) error {
-	if parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); parent == nil {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	if chain.Config().IsTIP10(header.Number) {
+		ext := new(types.HeaderExtension)
+		if m.election != nil {
+			ext.CommitteeHash = types.CommitteeSetHash(m.election.GetCommittee(header.Number))
+		}
+		if m.sbc != nil {
+			snail := m.sbc.CurrentHeader()
+			ext.SnailNumber, ext.SnailHash = snail.Number.Uint64(), snail.Hash()
+		}
+		// Keep the parent's pointer if a snail reorg moved the head backwards
+		if prev, err := types.DecodeHeaderExtension(parent.Extra); err == nil && prev.SnailNumber > ext.SnailNumber {
+			ext.SnailNumber, ext.SnailHash = prev.SnailNumber, prev.SnailHash
+		}
+		header.Extra = ext.Encode()
+	}
 	return nil
 }
 
@@ -809,6 +909,7 @@ func (m *Minerva) PrepareSnail(fastchain consensus.ChainReader, chain consensus.
 	if parents == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	m.script.offsetTime(header)
 	header.Difficulty = m.CalcSnailDifficulty(chain, header.Time.Uint64(), parents)
 
 	if header.FastNumber == nil {
@@ -837,6 +938,7 @@ func (m *Minerva) PrepareSnailWithParent(fastchain consensus.ChainReader, chain
 	if parents == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	m.script.offsetTime(header)
 	header.Difficulty = m.CalcSnailDifficulty(chain, header.Time.Uint64(), parents)
 
 	if header.FastNumber == nil {
@@ -1011,13 +1113,14 @@ func accumulateRewardsFast2(stateDB *state.StateDB, sBlock *types.SnailBlock, fa
 	impawn := vm.NewImpawnImpl()
 	impawn.Load(stateDB, types.StakingAddress)
 	defer impawn.Save(stateDB, types.StakingAddress)
+	impawn.SetRewardState(stateDB)
 
 	var (
 		blockFruits    = sBlock.Body().Fruits
 		blockFruitsLen = big.NewInt(int64(len(blockFruits)))
 	)
 	if blockFruitsLen.Uint64() == 0 {
-		return nil, consensus.ErrInvalidBlock
+		return nil, consensus.ErrNoFruits
 	}
 	var (
 		//fruit award amount
@@ -1068,6 +1171,7 @@ func accumulateRewardsFast3(stateDB *state.StateDB, fast, startRewardPos uint64)
 	impawn := vm.NewImpawnImpl()
 	impawn.Load(stateDB, types.StakingAddress)
 	defer impawn.Save(stateDB, types.StakingAddress)
+	impawn.SetRewardState(stateDB)
 
 	//committee reward
 	infos, err := impawn.Reward2(epoch.BeginHeight, epoch.EndHeight, 1, committeeCoin)
@@ -17,16 +17,22 @@
 package minerva
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/common/math"
+	"github.com/abeychain/go-abey/consensus"
+	"github.com/abeychain/go-abey/core/state"
 	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
 	"github.com/abeychain/go-abey/params"
 	osMath "math"
 	"math/big"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -271,3 +277,162 @@ func TestRewardWithTimes01(t *testing.T) {
 		}
 	}
 }
+
+func TestAccumulateRewardsEmptyFruits(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	block := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(1)})
+
+	if _, err := accumulateRewardsFast2(statedb, block, 1); err != consensus.ErrNoFruits {
+		t.Errorf("fruitless block reward error mismatch: have %v, want %v", err, consensus.ErrNoFruits)
+	}
+}
+
+// Tests that fast block signs are rejected without a quorum of agreeing signs
+// made for the block.
+func TestVerifySignsQuorum(t *testing.T) {
+	engine := NewFaker()
+	election := engine.election.(*fakeElection)
+
+	number, hash := big.NewInt(10), common.Hash{1}
+	makeSigns := func(n int, result uint32) []*types.PbftSign {
+		var signs []*types.PbftSign
+		for _, key := range election.privates[:n] {
+			sign := &types.PbftSign{Result: result, FastHeight: number, FastHash: hash}
+			sign.Sign, _ = crypto.Sign(sign.HashWithNoSign().Bytes(), key)
+			signs = append(signs, sign)
+		}
+		return signs
+	}
+	duplicate := makeSigns(5, types.VoteAgree)
+	duplicate[4] = duplicate[0]
+
+	// 7 members need 5 agreeing signs
+	tests := []struct {
+		hash  common.Hash
+		signs []*types.PbftSign
+		err   error
+	}{
+		{hash, makeSigns(5, types.VoteAgree), nil},
+		{hash, makeSigns(4, types.VoteAgree), consensus.ErrInvalidSign},
+		{hash, makeSigns(7, types.VoteAgreeAgainst), consensus.ErrInvalidSign},
+		{common.Hash{2}, makeSigns(7, types.VoteAgree), consensus.ErrInvalidSign},
+		{hash, duplicate, consensus.ErrInvalidSign},
+	}
+	for i, tt := range tests {
+		if err := engine.VerifySigns(number, tt.hash, tt.signs); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the configured verifier count caps the one derived from the CPUs.
+func TestVerifyWorkers(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	tests := []struct {
+		configured, headers, want int
+	}{
+		{0, 100, 4}, // One verifier per CPU by default
+		{2, 100, 2}, // Capped by the configuration
+		{8, 100, 4}, // Never more than the CPUs
+		{2, 1, 1},   // Never more than the headers
+	}
+	for i, tt := range tests {
+		m := &Minerva{config: Config{VerifyWorkers: tt.configured}}
+		if have := m.verifyWorkers(tt.headers); have != tt.want {
+			t.Errorf("test %d: workers mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that fruits and blocks past TIP13 may carry a proof signed by their coinbase.
+func TestVerifyCoinbaseProof(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := *params.TestChainConfig
+	config.TIP13 = &params.BlockConfig{FastNumber: big.NewInt(100)}
+
+	makeFruit := func(fastNumber int64, signer *ecdsa.PrivateKey) *types.SnailHeader {
+		header := &types.SnailHeader{
+			Coinbase:   crypto.PubkeyToAddress(key.PublicKey),
+			FastNumber: big.NewInt(fastNumber),
+			Number:     big.NewInt(1),
+			Extra:      []byte("vanity"),
+		}
+		if signer != nil {
+			proof, _ := crypto.Sign(types.CoinbaseProofHash(header).Bytes(), signer)
+			header.Extra, _ = types.AppendCoinbaseProof(header, proof)
+		}
+		return header
+	}
+	tests := []struct {
+		header *types.SnailHeader
+		ok     bool
+	}{
+		{makeFruit(100, nil), true},    // No proof
+		{makeFruit(100, key), true},    // Proof by the coinbase
+		{makeFruit(100, other), false}, // Proof by someone else
+		{makeFruit(99, key), false},    // Proof before the fork
+	}
+	for i, tt := range tests {
+		if err := verifySnailExtra(&config, tt.header); (err == nil) != tt.ok {
+			t.Errorf("test %d: verification mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+	// The signer is recovered from the proof, the vanity is kept intact
+	header := makeFruit(100, key)
+	if signer, err := types.CoinbaseProofSigner(header); err != nil || signer != header.Coinbase {
+		t.Errorf("signer mismatch: have %x (%v), want %x", signer, err, header.Coinbase)
+	}
+	if vanity, _, _ := types.SplitCoinbaseProof(header.Extra); string(vanity) != "vanity" {
+		t.Errorf("vanity mismatch: have %q, want %q", vanity, "vanity")
+	}
+	if _, err := types.CoinbaseProofSigner(makeFruit(100, nil)); err != types.ErrNoCoinbaseProof {
+		t.Errorf("missing proof: have error %v, want %v", err, types.ErrNoCoinbaseProof)
+	}
+}
+
+type extensionChain struct {
+	consensus.ChainReader
+	config *params.ChainConfig
+}
+
+func (c *extensionChain) Config() *params.ChainConfig { return c.config }
+
+type extensionSnail struct {
+	consensus.SnailChainReader
+	headers map[common.Hash]*types.SnailHeader
+}
+
+func (s *extensionSnail) GetHeaderByHash(hash common.Hash) *types.SnailHeader { return s.headers[hash] }
+
+// Tests that header extension snail pointers are checked by hash, accepting
+// pointers to unknown snail blocks but never backwards ones.
+func TestVerifyHeaderExtension(t *testing.T) {
+	known := &types.SnailHeader{Number: big.NewInt(5), Difficulty: big.NewInt(1)}
+	engine := &Minerva{sbc: &extensionSnail{headers: map[common.Hash]*types.SnailHeader{known.Hash(): known}}}
+	chain := &extensionChain{config: &params.ChainConfig{TIP10: &params.BlockConfig{FastNumber: big.NewInt(0)}}}
+
+	parent := &types.Header{Number: big.NewInt(1), Extra: (&types.HeaderExtension{SnailNumber: 4}).Encode()}
+	tests := []struct {
+		ext *types.HeaderExtension
+		err error
+	}{
+		{&types.HeaderExtension{SnailNumber: 5, SnailHash: known.Hash()}, nil},               // Known pointer
+		{&types.HeaderExtension{SnailNumber: 6, SnailHash: known.Hash()}, errSnailPointer},   // Known hash, wrong number
+		{&types.HeaderExtension{SnailNumber: 7, SnailHash: common.Hash{7}}, nil},             // Unknown pointer
+		{&types.HeaderExtension{SnailNumber: 4, SnailHash: common.Hash{4}}, nil},             // Pointer kept
+		{&types.HeaderExtension{SnailNumber: 3, SnailHash: common.Hash{3}}, errSnailPointer}, // Pointer moved back
+	}
+	for i, tt := range tests {
+		header := &types.Header{Number: big.NewInt(2), Extra: tt.ext.Encode()}
+		if err := engine.verifyHeaderExtension(chain, header, parent); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	header := &types.Header{Number: big.NewInt(2), Extra: []byte{0x01}}
+	if err := engine.verifyHeaderExtension(chain, header, parent); err == nil {
+		t.Errorf("malformed extension accepted")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package minerva
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// fakeScript holds the scripted outcomes of a fake minerva engine, keyed by
+// snail block number. It is never modified once the engine is built, so it is
+// safe to read from concurrent verifiers.
+type fakeScript struct {
+	difficulties map[uint64]*big.Int // Difficulty assigned to a block instead of the calculated one
+	timeOffsets  map[uint64]int64    // Seconds added to a block's time when it is prepared
+	headerFails  map[uint64]error    // Error returned when verifying a block's header
+	sealFails    map[uint64]error    // Error returned when verifying a block's seal
+}
+
+// difficulty returns the scripted difficulty of a block, nil if unscripted.
+func (s *fakeScript) difficulty(number uint64) *big.Int {
+	if s == nil {
+		return nil
+	}
+	if diff, ok := s.difficulties[number]; ok {
+		return new(big.Int).Set(diff)
+	}
+	return nil
+}
+
+// offsetTime shifts the time of a header being prepared by its scripted offset.
+func (s *fakeScript) offsetTime(header *types.SnailHeader) {
+	if s == nil || header.Number == nil || header.Time == nil {
+		return
+	}
+	if offset, ok := s.timeOffsets[header.Number.Uint64()]; ok {
+		header.Time = new(big.Int).Add(header.Time, big.NewInt(offset))
+	}
+}
+
+// headerFailure returns the scripted header verification error of a block.
+func (s *fakeScript) headerFailure(number uint64) error {
+	if s == nil {
+		return nil
+	}
+	return s.headerFails[number]
+}
+
+// sealFailure returns the scripted seal verification error of a block.
+func (s *fakeScript) sealFailure(number uint64) error {
+	if s == nil {
+		return nil
+	}
+	return s.sealFails[number]
+}
+
+// FakerBuilder assembles a fake minerva engine whose difficulty, timestamps
+// and verification results can be scripted per snail block. Engines built
+// from it behave like NewFaker for every block without a script entry.
+//
+// Scripted difficulties and time offsets are applied when headers are
+// prepared through the engine and honoured when they are verified, so chains
+// built by the engine stay valid under it.
+type FakerBuilder struct {
+	script fakeScript
+	delay  time.Duration
+}
+
+// NewFakerBuilder creates an empty fake engine builder.
+func NewFakerBuilder() *FakerBuilder {
+	return &FakerBuilder{
+		script: fakeScript{
+			difficulties: make(map[uint64]*big.Int),
+			timeOffsets:  make(map[uint64]int64),
+			headerFails:  make(map[uint64]error),
+			sealFails:    make(map[uint64]error),
+		},
+	}
+}
+
+// WithDifficulty makes the snail block at number carry the given difficulty.
+func (b *FakerBuilder) WithDifficulty(number uint64, diff *big.Int) *FakerBuilder {
+	b.script.difficulties[number] = new(big.Int).Set(diff)
+	return b
+}
+
+// WithTimeOffset shifts the timestamp of the snail block at number by the
+// given seconds when it is prepared. Offsets into the future beyond the
+// allowed drift make the block fail verification with ErrFutureBlock.
+func (b *FakerBuilder) WithTimeOffset(number uint64, seconds int64) *FakerBuilder {
+	b.script.timeOffsets[number] = seconds
+	return b
+}
+
+// WithHeaderFailure makes the header verification of the snail block at
+// number fail with err.
+func (b *FakerBuilder) WithHeaderFailure(number uint64, err error) *FakerBuilder {
+	b.script.headerFails[number] = err
+	return b
+}
+
+// WithSealFailure makes the seal verification of the snail block at number
+// fail as if its proof of work was invalid.
+func (b *FakerBuilder) WithSealFailure(number uint64) *FakerBuilder {
+	b.script.sealFails[number] = errInvalidPoW
+	return b
+}
+
+// WithDelay delays every seal verification by the given time.
+func (b *FakerBuilder) WithDelay(delay time.Duration) *FakerBuilder {
+	b.delay = delay
+	return b
+}
+
+// Build creates the fake engine. The builder may be reused, later changes do
+// not affect engines already built.
+func (b *FakerBuilder) Build() *Minerva {
+	script := &fakeScript{
+		difficulties: make(map[uint64]*big.Int, len(b.script.difficulties)),
+		timeOffsets:  make(map[uint64]int64, len(b.script.timeOffsets)),
+		headerFails:  make(map[uint64]error, len(b.script.headerFails)),
+		sealFails:    make(map[uint64]error, len(b.script.sealFails)),
+	}
+	for n, diff := range b.script.difficulties {
+		script.difficulties[n] = diff
+	}
+	for n, offset := range b.script.timeOffsets {
+		script.timeOffsets[n] = offset
+	}
+	for n, err := range b.script.headerFails {
+		script.headerFails[n] = err
+	}
+	for n, err := range b.script.sealFails {
+		script.sealFails[n] = err
+	}
+	m := NewFaker()
+	m.fakeDelay = b.delay
+	m.script = script
+	return m
+}
@@ -0,0 +1,73 @@
+package minerva
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/consensus"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+)
+
+// configReader is a snail chain stub serving only the chain config.
+type configReader struct {
+	consensus.SnailChainReader
+}
+
+func (configReader) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func TestFakerBuilder(t *testing.T) {
+	errScripted := errors.New("scripted failure")
+
+	builder := NewFakerBuilder().
+		WithDifficulty(3, big.NewInt(12345)).
+		WithTimeOffset(3, 100).
+		WithSealFailure(3).
+		WithHeaderFailure(4, errScripted)
+	engine := builder.Build()
+
+	chain := configReader{}
+	var parents []*types.SnailHeader
+	for i := int64(0); i < 3; i++ {
+		parents = append(parents, &types.SnailHeader{
+			Number:     big.NewInt(i),
+			Time:       big.NewInt(60 * (i + 1)),
+			Difficulty: big.NewInt(2000000),
+		})
+	}
+	// Scripted blocks are prepared with the scripted time and difficulty
+	header := &types.SnailHeader{Number: big.NewInt(3), Time: big.NewInt(240)}
+	if err := engine.PrepareSnailWithParent(nil, chain, header, parents); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if header.Time.Int64() != 340 {
+		t.Errorf("time mismatch: have %v, want %v", header.Time, 340)
+	}
+	if header.Difficulty.Int64() != 12345 {
+		t.Errorf("difficulty mismatch: have %v, want %v", header.Difficulty, 12345)
+	}
+	if err := engine.verifySnailHeader(chain, nil, header, nil, parents, false, false, false); err != nil {
+		t.Errorf("scripted header rejected: %v", err)
+	}
+	if err := engine.verifySnailHeader(chain, nil, header, nil, parents, false, true, false); err != errInvalidPoW {
+		t.Errorf("seal failure mismatch: have %v, want %v", err, errInvalidPoW)
+	}
+	// Unscripted blocks follow the regular difficulty rules
+	parents = append(parents, header)
+	next := &types.SnailHeader{Number: big.NewInt(4), Time: big.NewInt(400)}
+	if err := engine.PrepareSnailWithParent(nil, chain, next, parents); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if want := CalcDifficulty(params.TestChainConfig, 400, parents); next.Difficulty.Cmp(want) != 0 {
+		t.Errorf("difficulty mismatch: have %v, want %v", next.Difficulty, want)
+	}
+	if err := engine.verifySnailHeader(chain, nil, next, nil, parents, false, false, false); err != errScripted {
+		t.Errorf("header failure mismatch: have %v, want %v", err, errScripted)
+	}
+	// Engines already built are not affected by further scripting
+	builder.WithHeaderFailure(3, errScripted)
+	if err := engine.verifySnailHeader(chain, nil, header, nil, parents[:3], false, false, false); err != nil {
+		t.Errorf("built engine changed by builder: %v", err)
+	}
+}
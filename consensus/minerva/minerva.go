@@ -50,7 +50,7 @@ var (
 	maxUint128 = new(big.Int).Exp(big.NewInt(2), big.NewInt(128), big.NewInt(0))
 
 	// sharedMinerva is a full instance that can be shared between multiple users.
-	sharedMinerva = New(Config{"", 3, 0, "", 1, 0, ModeNormal})
+	sharedMinerva = New(Config{"", 3, 0, "", 1, 0, ModeNormal, 0})
 
 	//BaseBig ...
 	BaseBig = big.NewInt(1e18)
@@ -189,6 +189,8 @@ type Config struct {
 	DatasetsInMem  int
 	DatasetsOnDisk int
 	PowMode        Mode
+
+	VerifyWorkers int // Number of concurrent header verifiers, zero for one per CPU
 }
 
 // Minerva consensus
@@ -208,6 +210,7 @@ type Minerva struct {
 	shared    *Minerva      // Shared PoW verifier to avoid cache regeneration
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
 	fakeDelay time.Duration // Time delay to sleep for before returning from verify
+	script    *fakeScript   // Scripted per block outcomes, nil unless built by FakerBuilder
 
 	lock sync.Mutex // Ensures thread safety for the in-memory caches and mining fields
 
@@ -27,6 +27,8 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
 	"github.com/abeychain/go-abey/params"
 	"math/big"
 )
@@ -60,6 +62,51 @@ func TestTestMode(t *testing.T) {
 	}
 }
 
+// Tests that a coinbase proof signed into the work survives sealing it as a
+// snail block, and that the sealed block is accepted past TIP13.
+func TestSealCoinbaseProof(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	config := *params.TestChainConfig
+	config.TIP13 = &params.BlockConfig{FastNumber: big.NewInt(2)}
+
+	// Make fruits out of reach so that the seal can only make a block
+	header := &types.SnailHeader{
+		Number:          big.NewInt(1),
+		Coinbase:        crypto.PubkeyToAddress(key.PublicKey),
+		Difficulty:      params.MinimumFruitDifficulty,
+		FruitDifficulty: new(big.Int).Lsh(common.Big1, 127),
+		FastNumber:      big.NewInt(2),
+		Extra:           []byte("vanity"),
+	}
+	proof, _ := crypto.Sign(types.CoinbaseProofHash(header).Bytes(), key)
+	header.Extra, _ = types.AppendCoinbaseProof(header, proof)
+
+	block := types.NewSnailBlockWithHeader(header)
+	block.SetSnailBlockFruits(types.Fruits{types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(1), FastNumber: big.NewInt(1)})})
+
+	minerva := NewTester()
+	results := make(chan *types.SnailBlock)
+	go minerva.ConSeal(nil, block, nil, results)
+
+	select {
+	case sealed := <-results:
+		if sealed.IsFruit() {
+			t.Fatalf("sealed a fruit instead of a block")
+		}
+		if err := minerva.VerifySnailSeal(nil, sealed.Header(), false); err != nil {
+			t.Fatalf("invalid seal: %v", err)
+		}
+		if err := verifySnailExtra(&config, sealed.Header()); err != nil {
+			t.Fatalf("sealed block rejected: %v", err)
+		}
+		if signer, err := types.CoinbaseProofSigner(sealed.Header()); err != nil || signer != header.Coinbase {
+			t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, header.Coinbase)
+		}
+	case <-time.NewTimer(time.Second * 500).C:
+		t.Error("sealing result timeout")
+	}
+}
+
 // This test checks that cache lru logic doesn't crash under load.
 // It reproduces https://github.com/abeychain/go-abey/issues/14943
 func TestCacheFileEvict(t *testing.T) {
@@ -0,0 +1,188 @@
+package tbft
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	tcrypto "github.com/abeychain/go-abey/consensus/tbft/crypto"
+	"github.com/abeychain/go-abey/consensus/tbft/help"
+	"github.com/abeychain/go-abey/consensus/tbft/tp2p"
+	"github.com/abeychain/go-abey/crypto"
+)
+
+// replayWindow is the number of sequence numbers below the highest one seen
+// from a sender on a channel that are still accepted, to tolerate messages
+// overtaking each other.
+const replayWindow = 4096
+
+var (
+	errEnvelopeChain     = errors.New("envelope for another chain")
+	errEnvelopeCommittee = errors.New("envelope for another committee")
+	errEnvelopeRecipient = errors.New("envelope for another recipient")
+	errEnvelopeChannel   = errors.New("envelope for another channel")
+	errEnvelopeSignature = errors.New("invalid envelope signature")
+	errEnvelopeSender    = errors.New("envelope sender not in committee")
+	errEnvelopeReplay    = errors.New("replayed envelope")
+	errEnvelopeNested    = errors.New("nested envelope")
+	errEnvelopeRequired  = errors.New("unsigned consensus message")
+)
+
+// MessageEnvelope wraps an encoded consensus message with the signature of
+// the committee member that produced it. The signature binds the payload to
+// the chain, the committee, the recipient, the channel and a sequence number
+// counting the messages sent to the recipient on the channel, so a message
+// that was relayed or stored can be authenticated without trusting the
+// transport it arrived on.
+type MessageEnvelope struct {
+	ChainID     string
+	CommitteeID uint64
+	Recipient   string
+	Channel     byte
+	Sequence    uint64
+	Payload     []byte
+	Signature   []byte
+}
+
+// SignBytes returns the hash signed by the sender of the envelope.
+func (env *MessageEnvelope) SignBytes() []byte {
+	hash := help.RlpHash([]interface{}{env.ChainID, env.CommitteeID, env.Recipient, env.Channel, env.Sequence, env.Payload})
+	return hash[:]
+}
+
+// Sender recovers the address of the committee member that signed the envelope.
+func (env *MessageEnvelope) Sender() (help.Address, error) {
+	pub, err := crypto.SigToPub(env.SignBytes(), env.Signature)
+	if err != nil {
+		return nil, errEnvelopeSignature
+	}
+	return tcrypto.PubKeyTrue(*pub).Address(), nil
+}
+
+// envelopeSealer signs outgoing consensus messages of one committee.
+type envelopeSealer struct {
+	chainID string
+	cid     uint64
+	self    string // node id envelopes must be addressed to
+	priv    tcrypto.PrivKey
+
+	mu   sync.Mutex
+	seqs map[seqKey]uint64 // last sequence sent per recipient and channel
+}
+
+// seqKey identifies an independent stream of sequence numbers.
+type seqKey struct {
+	peer    string
+	channel byte
+}
+
+func newEnvelopeSealer(chainID string, cid uint64, priv tcrypto.PrivKey) *envelopeSealer {
+	return &envelopeSealer{
+		chainID: chainID,
+		cid:     cid,
+		self:    string(tp2p.PubKeyToID(priv.PubKey())),
+		priv:    priv,
+		seqs:    make(map[seqKey]uint64),
+	}
+}
+
+// next returns the sequence number of the next message sent to the recipient
+// on the channel.
+func (s *envelopeSealer) next(to string, chID byte) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := seqKey{to, chID}
+	seq, ok := s.seqs[key]
+	if !ok {
+		// Seed the sequence from the clock so that a restarted node does not
+		// reuse sequence numbers its peers have already seen.
+		seq = uint64(time.Now().UnixNano())
+	}
+	s.seqs[key] = seq + 1
+	return seq + 1
+}
+
+// seal wraps the encoded message sent to a recipient on a channel into a
+// signed envelope.
+func (s *envelopeSealer) seal(to string, chID byte, payload []byte) (*MessageEnvelope, error) {
+	env := &MessageEnvelope{
+		ChainID:     s.chainID,
+		CommitteeID: s.cid,
+		Recipient:   to,
+		Channel:     chID,
+		Sequence:    s.next(to, chID),
+		Payload:     payload,
+	}
+	sig, err := s.priv.Sign(env.SignBytes())
+	if err != nil {
+		return nil, err
+	}
+	env.Signature = sig
+	return env, nil
+}
+
+// verify checks that the envelope belongs to the committee of the sealer and
+// was addressed to it on the channel it arrived on, returning its sender.
+func (s *envelopeSealer) verify(env *MessageEnvelope, chID byte) (help.Address, error) {
+	if env.ChainID != s.chainID {
+		return nil, errEnvelopeChain
+	}
+	if env.CommitteeID != s.cid {
+		return nil, errEnvelopeCommittee
+	}
+	if env.Recipient != s.self {
+		return nil, errEnvelopeRecipient
+	}
+	if env.Channel != chID {
+		return nil, errEnvelopeChannel
+	}
+	return env.Sender()
+}
+
+// replayFilter tracks the sequence numbers recently seen from each sender on
+// each channel.
+type replayFilter struct {
+	mu      sync.Mutex
+	senders map[seqKey]*seqWindow
+}
+
+type seqWindow struct {
+	top  uint64
+	seen map[uint64]struct{}
+}
+
+func newReplayFilter() *replayFilter {
+	return &replayFilter{senders: make(map[seqKey]*seqWindow)}
+}
+
+// check reports whether seq is fresh for the sender on the channel and records it.
+func (f *replayFilter) check(sender help.Address, chID byte, seq uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := seqKey{string(sender), chID}
+	w := f.senders[key]
+	if w == nil {
+		w = &seqWindow{seen: make(map[uint64]struct{})}
+		f.senders[key] = w
+	}
+	if w.top >= replayWindow && seq <= w.top-replayWindow {
+		return false
+	}
+	if _, ok := w.seen[seq]; ok {
+		return false
+	}
+	w.seen[seq] = struct{}{}
+	if seq > w.top {
+		w.top = seq
+	}
+	if len(w.seen) > 2*replayWindow {
+		for n := range w.seen {
+			if w.top >= replayWindow && n <= w.top-replayWindow {
+				delete(w.seen, n)
+			}
+		}
+	}
+	return true
+}
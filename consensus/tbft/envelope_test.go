@@ -0,0 +1,167 @@
+package tbft
+
+import (
+	"testing"
+
+	tcrypto "github.com/abeychain/go-abey/consensus/tbft/crypto"
+	"github.com/abeychain/go-abey/consensus/tbft/tp2p"
+	ttypes "github.com/abeychain/go-abey/consensus/tbft/types"
+)
+
+func newAuthReactor(chainID string, cid uint64, priv tcrypto.PrivKey, sign, require bool, members ...tcrypto.PrivKey) *ConsensusReactor {
+	var vals []*ttypes.Validator
+	for _, m := range members {
+		vals = append(vals, ttypes.NewValidator(m.PubKey(), 1))
+	}
+	conS := new(ConsensusState)
+	conS.Validators = ttypes.NewValidatorSet(vals)
+	conR := &ConsensusReactor{conS: conS}
+	conR.SetMessageAuth(chainID, cid, priv, sign, require)
+	return conR
+}
+
+func nodeID(priv tcrypto.PrivKey) tp2p.ID {
+	return tp2p.PubKeyToID(priv.PubKey())
+}
+
+func TestEnvelopeRoundtrip(t *testing.T) {
+	alice, bob, mallory := tcrypto.GenPrivKey(), tcrypto.GenPrivKey(), tcrypto.GenPrivKey()
+	sender := newAuthReactor("1", 7, alice, true, true, alice, bob)
+	receiver := newAuthReactor("1", 7, bob, true, true, alice, bob)
+
+	msg := &HasVoteMessage{Height: 10, Round: 1, Type: ttypes.VoteTypePrevote, Index: 3}
+	bz := sender.encodeMsg(nodeID(bob), VoteChannel, msg)
+
+	dec, err := receiver.decodeMsg(VoteChannel, bz)
+	if err != nil {
+		t.Fatalf("failed to open envelope: %v", err)
+	}
+	if have, ok := dec.(*HasVoteMessage); !ok || *have != *msg {
+		t.Fatalf("message mismatch: have %v, want %v", dec, msg)
+	}
+	// The same envelope must not be accepted twice
+	if _, err := receiver.decodeMsg(VoteChannel, bz); err != errEnvelopeReplay {
+		t.Errorf("replayed envelope: have error %v, want %v", err, errEnvelopeReplay)
+	}
+	// Envelopes of other committees or chains are rejected
+	if _, err := newAuthReactor("1", 8, bob, true, true, alice, bob).decodeMsg(VoteChannel, bz); err != errEnvelopeCommittee {
+		t.Errorf("foreign committee: have error %v, want %v", err, errEnvelopeCommittee)
+	}
+	if _, err := newAuthReactor("2", 7, bob, true, true, alice, bob).decodeMsg(VoteChannel, bz); err != errEnvelopeChain {
+		t.Errorf("foreign chain: have error %v, want %v", err, errEnvelopeChain)
+	}
+	// Envelopes relayed to another node or channel are rejected
+	if _, err := newAuthReactor("1", 7, alice, true, true, alice, bob).decodeMsg(VoteChannel, bz); err != errEnvelopeRecipient {
+		t.Errorf("foreign recipient: have error %v, want %v", err, errEnvelopeRecipient)
+	}
+	if _, err := newAuthReactor("1", 7, bob, true, true, alice, bob).decodeMsg(StateChannel, bz); err != errEnvelopeChannel {
+		t.Errorf("foreign channel: have error %v, want %v", err, errEnvelopeChannel)
+	}
+	// Members outside of the committee are rejected
+	outsider := newAuthReactor("1", 7, mallory, true, true, mallory)
+	if _, err := receiver.decodeMsg(VoteChannel, outsider.encodeMsg(nodeID(bob), VoteChannel, msg)); err != errEnvelopeSender {
+		t.Errorf("outsider: have error %v, want %v", err, errEnvelopeSender)
+	}
+	// Tampered payloads fail the signature check
+	env, _ := sender.sealer.seal(string(nodeID(bob)), VoteChannel, encodeMsg(msg))
+	env.Payload = encodeMsg(&HasVoteMessage{Height: 11})
+	if _, err := receiver.decodeMsg(VoteChannel, encodeMsg(env)); err != errEnvelopeSender && err != errEnvelopeSignature {
+		t.Errorf("tampered payload: have error %v", err)
+	}
+}
+
+// Tests that messages overtaken by a long run of messages to other peers or on
+// other channels are not mistaken for replays.
+func TestEnvelopeSequenceStreams(t *testing.T) {
+	alice, bob, carol := tcrypto.GenPrivKey(), tcrypto.GenPrivKey(), tcrypto.GenPrivKey()
+	sender := newAuthReactor("1", 7, alice, true, true, alice, bob, carol)
+	receiver := newAuthReactor("1", 7, bob, true, true, alice, bob, carol)
+
+	msg := &HasVoteMessage{Height: 10, Round: 1, Type: ttypes.VoteTypePrevote, Index: 3}
+	late := sender.encodeMsg(nodeID(bob), VoteChannel, msg)
+	for i := 0; i <= replayWindow; i++ {
+		sender.encodeMsg(nodeID(carol), VoteChannel, msg)
+		if _, err := receiver.decodeMsg(StateChannel, sender.encodeMsg(nodeID(bob), StateChannel, msg)); err != nil {
+			t.Fatalf("message %d rejected: %v", i, err)
+		}
+	}
+	if _, err := receiver.decodeMsg(VoteChannel, late); err != nil {
+		t.Errorf("late message rejected: %v", err)
+	}
+}
+
+func TestEnvelopeRequired(t *testing.T) {
+	alice := tcrypto.GenPrivKey()
+	msg := &HasVoteMessage{Height: 10}
+
+	lenient := newAuthReactor("1", 7, alice, true, false, alice)
+	if _, err := lenient.decodeMsg(VoteChannel, encodeMsg(msg)); err != nil {
+		t.Errorf("unsigned message rejected in lenient mode: %v", err)
+	}
+	strict := newAuthReactor("1", 7, alice, true, true, alice)
+	if _, err := strict.decodeMsg(VoteChannel, encodeMsg(msg)); err != errEnvelopeRequired {
+		t.Errorf("unsigned message: have error %v, want %v", err, errEnvelopeRequired)
+	}
+}
+
+// Tests that a node not signing its own messages still accepts the envelopes
+// of signing peers, and that they accept its plain messages.
+func TestEnvelopeRollout(t *testing.T) {
+	alice, bob := tcrypto.GenPrivKey(), tcrypto.GenPrivKey()
+	signer := newAuthReactor("1", 7, alice, true, false, alice, bob)
+	plain := newAuthReactor("1", 7, bob, false, false, alice, bob)
+
+	msg := &HasVoteMessage{Height: 10, Round: 1, Type: ttypes.VoteTypePrevote, Index: 3}
+	bz := plain.encodeMsg(nodeID(alice), VoteChannel, msg)
+	if dec, err := decodeMsg(bz); err != nil {
+		t.Fatalf("failed to decode plain message: %v", err)
+	} else if _, ok := dec.(*MessageEnvelope); ok {
+		t.Fatalf("non-signing node wrapped its message into an envelope")
+	}
+	if dec, err := signer.decodeMsg(VoteChannel, bz); err != nil {
+		t.Errorf("plain message rejected by signing node: %v", err)
+	} else if have, ok := dec.(*HasVoteMessage); !ok || *have != *msg {
+		t.Errorf("plain message mismatch: have %v, want %v", dec, msg)
+	}
+	dec, err := plain.decodeMsg(VoteChannel, signer.encodeMsg(nodeID(bob), VoteChannel, msg))
+	if err != nil {
+		t.Fatalf("envelope rejected by non-signing node: %v", err)
+	}
+	if have, ok := dec.(*HasVoteMessage); !ok || *have != *msg {
+		t.Errorf("message mismatch: have %v, want %v", dec, msg)
+	}
+	// Envelopes are still authenticated by non-signing nodes
+	outsider := newAuthReactor("1", 7, tcrypto.GenPrivKey(), true, false)
+	if _, err := plain.decodeMsg(VoteChannel, outsider.encodeMsg(nodeID(bob), VoteChannel, msg)); err != errEnvelopeSender {
+		t.Errorf("outsider: have error %v, want %v", err, errEnvelopeSender)
+	}
+}
+
+func TestReplayFilterWindow(t *testing.T) {
+	f := newReplayFilter()
+	sender := []byte("sender")
+
+	// Out of order delivery within the window is accepted once
+	for _, seq := range []uint64{100, 98, 99, 101} {
+		if !f.check(sender, VoteChannel, seq) {
+			t.Fatalf("fresh sequence %d rejected", seq)
+		}
+	}
+	if f.check(sender, VoteChannel, 99) {
+		t.Fatalf("duplicate sequence accepted")
+	}
+	// Sequences that fell out of the window are rejected
+	if !f.check(sender, VoteChannel, 100+replayWindow) {
+		t.Fatalf("fresh sequence rejected")
+	}
+	if f.check(sender, VoteChannel, 97) {
+		t.Fatalf("stale sequence accepted")
+	}
+	// Senders and channels are tracked independently
+	if !f.check([]byte("other"), VoteChannel, 97) {
+		t.Fatalf("sequence of another sender rejected")
+	}
+	if !f.check(sender, StateChannel, 97) {
+		t.Fatalf("sequence of another channel rejected")
+	}
+}
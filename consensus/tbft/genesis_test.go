@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"github.com/abeychain/go-abey/crypto"
 	"io/ioutil"
-	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -100,6 +100,7 @@ func GetCommittee(cnt int) []map[string]interface{} {
 }
 
 func TestOutGenesisJson(t *testing.T) {
+	dir := t.TempDir()
 
 	b, _ := ioutil.ReadFile("./test/genesis.json")
 	out := make(map[string]interface{})
@@ -108,9 +109,9 @@ func TestOutGenesisJson(t *testing.T) {
 		out["committee"] = GetCommittee(i)
 		bytes, _ := json.Marshal(out)
 		bytes = []byte(strings.Replace(string(bytes), "9e+22", "90000000000000000000000", -1))
-		e := ioutil.WriteFile("./test/"+fmt.Sprintf("genesis_%s.json", strconv.Itoa(i)), bytes, os.ModePerm)
+		e := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("genesis_%s.json", strconv.Itoa(i))), bytes, 0644)
 		if e != nil {
-			fmt.Println(string(bytes))
+			t.Fatalf("failed to write genesis %d: %v", i, e)
 		}
 	}
 }
@@ -502,6 +502,8 @@ func (n *Node) PutCommittee(committeeInfo *types.CommitteeInfo) error {
 	service.sw.AddReactor("CONSENSUS", service.consensusReactor)
 	service.sw.SetAddrBook(service.addrBook)
 	service.consensusReactor.SetHealthMgr(service.healthMgr)
+	// Envelopes of signing peers are verified even if this node doesn't sign
+	service.consensusReactor.SetMessageAuth(n.chainID, cid, n.nodekey.PrivKey, n.config.Consensus.SignMessages, n.config.Consensus.RequireSignedMessages)
 	//service.consensusReactor.SetEventBus(service.eventBus)
 	service.selfID = n.nodekey.ID()
 	n.services[id.Uint64()] = service
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/abeychain/go-abey/log"
 	"github.com/tendermint/go-amino"
+	tcrypto "github.com/abeychain/go-abey/consensus/tbft/crypto"
 	"github.com/abeychain/go-abey/consensus/tbft/help"
 	"github.com/abeychain/go-abey/consensus/tbft/tp2p"
 	ttypes "github.com/abeychain/go-abey/consensus/tbft/types"
@@ -40,6 +41,11 @@ type ConsensusReactor struct {
 	fastSync bool
 	eventBus *ttypes.EventBus
 	hm       *ttypes.HealthMgr
+
+	sealer        *envelopeSealer // authenticates envelopes, nil if not set up
+	signMessages  bool            // wrap outgoing messages into envelopes
+	requireSigned bool            // reject messages not wrapped in an envelope
+	replay        *replayFilter
 }
 
 // NewConsensusReactor returns a new ConsensusReactor with the given
@@ -125,6 +131,9 @@ func (conR *ConsensusReactor) AddPeer(peer tp2p.Peer) {
 
 	// Create peerState for peer
 	peerState := NewPeerState(peer)
+	peerState.encode = func(chID byte, msg ConsensusMessage) []byte {
+		return conR.encodeMsg(peer.ID(), chID, msg)
+	}
 	peer.Set(ttypes.PeerStateKey, peerState)
 
 	// Begin routines for this peer.
@@ -153,6 +162,90 @@ func (conR *ConsensusReactor) SetHealthMgr(h *ttypes.HealthMgr) {
 	conR.hm = h
 }
 
+// SetMessageAuth sets up signed envelopes for the consensus messages of the
+// committee. Envelopes received are always authenticated, outgoing messages
+// are only wrapped if sign is set. If require is set, messages without a
+// valid envelope are rejected, otherwise they are still accepted to allow a
+// gradual rollout.
+func (conR *ConsensusReactor) SetMessageAuth(chainID string, cid uint64, priv tcrypto.PrivKey, sign, require bool) {
+	conR.sealer = newEnvelopeSealer(chainID, cid, priv)
+	conR.signMessages = sign || require
+	conR.requireSigned = require
+	conR.replay = newReplayFilter()
+}
+
+// encodeMsg encodes a consensus message sent to a peer on a channel, wrapping
+// it into a signed envelope if message authentication is enabled.
+func (conR *ConsensusReactor) encodeMsg(to tp2p.ID, chID byte, msg ConsensusMessage) []byte {
+	bz := cdc.MustMarshalBinaryBare(msg)
+	if conR.sealer == nil || !conR.signMessages {
+		return bz
+	}
+	env, err := conR.sealer.seal(string(to), chID, bz)
+	if err != nil {
+		log.Error("Failed to seal consensus message", "err", err)
+		return bz
+	}
+	return cdc.MustMarshalBinaryBare(env)
+}
+
+// broadcast sends a consensus message to all peers on a channel. Envelopes are
+// sealed for every peer separately, as they are bound to their recipient.
+func (conR *ConsensusReactor) broadcast(chID byte, msg ConsensusMessage) {
+	if conR.sealer == nil || !conR.signMessages {
+		conR.Switch.Broadcast(chID, encodeMsg(msg))
+		return
+	}
+	for _, peer := range conR.Switch.Peers().List() {
+		go peer.Send(chID, conR.encodeMsg(peer.ID(), chID, msg))
+	}
+}
+
+// decodeMsg decodes a consensus message received on a channel, opening and
+// authenticating its envelope if it has one.
+func (conR *ConsensusReactor) decodeMsg(chID byte, bz []byte) (ConsensusMessage, error) {
+	msg, err := decodeMsg(bz)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := msg.(*MessageEnvelope)
+	if !ok {
+		if conR.requireSigned {
+			return nil, errEnvelopeRequired
+		}
+		return msg, nil
+	}
+	if conR.sealer == nil {
+		return nil, errEnvelopeRequired
+	}
+	sender, err := conR.sealer.verify(env, chID)
+	if err != nil {
+		return nil, err
+	}
+	if !conR.isCommitteeMember(sender) {
+		return nil, errEnvelopeSender
+	}
+	if !conR.replay.check(sender, chID, env.Sequence) {
+		return nil, errEnvelopeReplay
+	}
+	if msg, err = decodeMsg(env.Payload); err != nil {
+		return nil, err
+	}
+	if _, ok := msg.(*MessageEnvelope); ok {
+		return nil, errEnvelopeNested
+	}
+	return msg, nil
+}
+
+// isCommitteeMember reports whether the address belongs to a validator of
+// the current committee.
+func (conR *ConsensusReactor) isCommitteeMember(addr help.Address) bool {
+	cs := conR.conS
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	return cs.Validators != nil && cs.Validators.HasAddress(addr)
+}
+
 // Receive implements Reactor
 // NOTE: We process these messages even when we're fast_syncing.
 // Messages affect either a peer state or the consensus state.
@@ -165,7 +258,12 @@ func (conR *ConsensusReactor) Receive(chID byte, src tp2p.Peer, msgBytes []byte)
 		log.Trace("Receive", "src", src, "chId", chID, "bytes", msgBytes)
 		return
 	}
-	msg, err := decodeMsg(msgBytes)
+	msg, err := conR.decodeMsg(chID, msgBytes)
+	if err == errEnvelopeReplay {
+		// Late deliveries look the same, don't punish the peer for them
+		log.Debug("Dropping replayed message", "src", src, "chId", chID)
+		return
+	}
 	if err != nil {
 		log.Debug("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
 		conR.Switch.StopPeerForError(src, err)
@@ -209,17 +307,13 @@ func (conR *ConsensusReactor) Receive(chID byte, src tp2p.Peer, msgBytes []byte)
 				log.Debug("Bad VoteSetBitsMessage field Type")
 				return
 			}
-			if d1, derr := cdc.MarshalBinaryBare(&VoteSetBitsMessage{
+			src.TrySend(VoteSetBitsChannel, conR.encodeMsg(src.ID(), VoteSetBitsChannel, &VoteSetBitsMessage{
 				Height:  msg.Height,
 				Round:   msg.Round,
 				Type:    msg.Type,
 				BlockID: msg.BlockID,
 				Votes:   ourVotes,
-			}); derr != nil {
-				panic(err)
-			} else {
-				src.TrySend(VoteSetBitsChannel, d1)
-			}
+			}))
 		default:
 			log.Debug(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
 		}
@@ -351,10 +445,10 @@ func (conR *ConsensusReactor) broadcastNewRoundStepMessages(rs *ttypes.RoundStat
 	log.Trace("broadcastNewRoundStepMessages", "makeRoundStepMessages", "in")
 	nrsMsg, csMsg := makeRoundStepMessages(rs)
 	if nrsMsg != nil {
-		conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(nrsMsg))
+		conR.broadcast(StateChannel, nrsMsg)
 	}
 	if csMsg != nil {
-		conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(csMsg))
+		conR.broadcast(StateChannel, csMsg)
 	}
 }
 
@@ -366,7 +460,7 @@ func (conR *ConsensusReactor) broadcastHasVoteMessage(vote *ttypes.Vote) {
 		Type:   vote.Type,
 		Index:  vote.ValidatorIndex,
 	}
-	conR.Switch.Broadcast(StateChannel, cdc.MustMarshalBinaryBare(msg))
+	conR.broadcast(StateChannel, msg)
 	/*
 		// TODO: Make this broadcast more selective.
 		for _, peer := range conR.Switch.Peers().List() {
@@ -407,10 +501,10 @@ func (conR *ConsensusReactor) sendNewRoundStepMessages(peer tp2p.Peer) {
 	rs := conR.conS.GetRoundState()
 	nrsMsg, csMsg := makeRoundStepMessages(rs)
 	if nrsMsg != nil {
-		peer.Send(StateChannel, cdc.MustMarshalBinaryBare(nrsMsg))
+		peer.Send(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, nrsMsg))
 	}
 	if csMsg != nil {
-		peer.Send(StateChannel, cdc.MustMarshalBinaryBare(csMsg))
+		peer.Send(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, csMsg))
 	}
 }
 
@@ -438,7 +532,7 @@ outerLoop:
 						Part:   part,
 					}
 					log.Trace("Sending block part", "height", prs.Height, "round", prs.Round)
-					if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+					if peer.Send(DataChannel, conR.encodeMsg(peer.ID(), DataChannel, msg)) {
 						ps.SetHasProposalBlockPart(prs.Height, uint(prs.Round), index)
 					}
 					continue outerLoop
@@ -485,7 +579,7 @@ outerLoop:
 			{
 				msg := &ProposalMessage{Proposal: rs.Proposal}
 				log.Trace("Sending proposal", "height", prs.Height, "round", prs.Round)
-				if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+				if peer.Send(DataChannel, conR.encodeMsg(peer.ID(), DataChannel, msg)) {
 					ps.SetHasProposal(rs.Proposal)
 				}
 			}
@@ -500,7 +594,7 @@ outerLoop:
 					ProposalPOL:      rs.Votes.Prevotes(int(rs.Proposal.POLRound)).BitArray(),
 				}
 				log.Trace("Sending POL", "height", prs.Height, "round", prs.Round)
-				peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg))
+				peer.Send(DataChannel, conR.encodeMsg(peer.ID(), DataChannel, msg))
 			}
 			continue outerLoop
 		}
@@ -522,7 +616,7 @@ func (conR *ConsensusReactor) gossipDataForCatchup(rs *ttypes.RoundState,
 		}
 		msg := &ProposalMessage{Proposal: blockMeta.Proposal}
 		log.Trace("Sending proposal", "height", prs.Height, "round", prs.Round)
-		if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+		if peer.Send(DataChannel, conR.encodeMsg(peer.ID(), DataChannel, msg)) {
 			ps.SetHasProposal(blockMeta.Proposal)
 		}
 	}
@@ -556,7 +650,7 @@ func (conR *ConsensusReactor) gossipDataForCatchup(rs *ttypes.RoundState,
 			Part:   part,
 		}
 		log.Trace("Sending block part for catchup", "round", prs.Round, "index", index)
-		if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+		if peer.Send(DataChannel, conR.encodeMsg(peer.ID(), DataChannel, msg)) {
 			ps.SetHasProposalBlockPart(prs.Height, uint(prs.Round), index)
 		} else {
 			log.Trace("Sending block part for catchup failed")
@@ -708,7 +802,7 @@ outerLoop:
 			prs := ps.GetRoundState()
 			if rs.Height == prs.Height {
 				if maj23, ok := rs.Votes.Prevotes(int(prs.Round)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					peer.TrySend(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, &VoteSetMaj23Message{
 						Height:  prs.Height,
 						Round:   uint(prs.Round),
 						Type:    ttypes.VoteTypePrevote,
@@ -725,7 +819,7 @@ outerLoop:
 			prs := ps.GetRoundState()
 			if rs.Height == prs.Height {
 				if maj23, ok := rs.Votes.Precommits(int(prs.Round)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					peer.TrySend(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, &VoteSetMaj23Message{
 						Height:  prs.Height,
 						Round:   uint(prs.Round),
 						Type:    ttypes.VoteTypePrecommit,
@@ -742,7 +836,7 @@ outerLoop:
 			prs := ps.GetRoundState()
 			if rs.Height == prs.Height && int(prs.ProposalPOLRound) >= 0 {
 				if maj23, ok := rs.Votes.Prevotes(int(prs.ProposalPOLRound)).TwoThirdsMajority(); ok {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					peer.TrySend(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, &VoteSetMaj23Message{
 						Height:  prs.Height,
 						Round:   uint(prs.ProposalPOLRound),
 						Type:    ttypes.VoteTypePrevote,
@@ -764,7 +858,7 @@ outerLoop:
 				// commit := conR.conS.LoadCommit(prs.Height)
 				commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
 				if commit != nil {
-					peer.TrySend(StateChannel, cdc.MustMarshalBinaryBare(&VoteSetMaj23Message{
+					peer.TrySend(StateChannel, conR.encodeMsg(peer.ID(), StateChannel, &VoteSetMaj23Message{
 						Height:  prs.Height,
 						Round:   uint(commit.Round()),
 						Type:    ttypes.VoteTypePrecommit,
@@ -821,6 +915,8 @@ type PeerState struct {
 	mtx   sync.Mutex            // NOTE: Modify below using setters, never directly.
 	PRS   ttypes.PeerRoundState `json:"round_state"` // Exposed.
 	Stats *peerStateStats       `json:"stats"`       // Exposed.
+
+	encode func(byte, ConsensusMessage) []byte // encodes messages sent to the peer on a channel
 }
 
 // peerStateStats holds internal statistics for a peer.
@@ -848,7 +944,8 @@ func NewPeerState(peer tp2p.Peer) *PeerState {
 			LastCommitRound:    ^uint(0),
 			CatchupCommitRound: -1,
 		},
-		Stats: &peerStateStats{},
+		Stats:  &peerStateStats{},
+		encode: func(_ byte, msg ConsensusMessage) []byte { return encodeMsg(msg) },
 	}
 }
 
@@ -935,7 +1032,7 @@ func (ps *PeerState) PickSendVote(votes ttypes.VoteSetReader) bool {
 	if vote, ok := ps.PickVoteToSend(votes); ok {
 		msg := &VoteMessage{Vote: vote.Copy()}
 		log.Debug("PickSendVote", "height", vote.Height, "round", vote.Round, "type", vote.Type)
-		return ps.peer.Send(VoteChannel, cdc.MustMarshalBinaryBare(msg))
+		return ps.peer.Send(VoteChannel, ps.encode(VoteChannel, msg))
 	}
 	return false
 }
@@ -1289,6 +1386,11 @@ func RegisterConsensusMessages(cdc *amino.Codec) {
 	cdc.RegisterConcrete(&VoteSetMaj23Message{}, "true/VoteSetMaj23", nil)
 	cdc.RegisterConcrete(&VoteSetBitsMessage{}, "true/VoteSetBits", nil)
 	cdc.RegisterConcrete(&ValidatorUpdateMessage{}, "true/ValidatorSet", nil)
+	cdc.RegisterConcrete(&MessageEnvelope{}, "true/Envelope", nil)
+}
+
+func encodeMsg(msg ConsensusMessage) []byte {
+	return cdc.MustMarshalBinaryBare(msg)
 }
 
 func decodeMsg(bz []byte) (msg ConsensusMessage, err error) {
@@ -51,7 +51,9 @@ func NewBlockValidator(config *params.ChainConfig, blockchain *BlockChain, engin
 
 // ValidateBody validates the given block's uncles and verifies the the block
 // header's transaction and uncle roots. The headers are assumed to be already
-// validated at this point.
+// validated at this point. If validateSign is set, the committee signs are
+// verified first, so that blocks without quorum are rejected before their body
+// is hashed or executed.
 func (fv *BlockValidator) ValidateBody(block *types.Block, validateSign bool) error {
 	// Check whether the block's known, and if not, that it's linkable
 	if fv.bc.HasBlockAndState(block.Hash(), block.NumberU64()) && fv.bc.CurrentBlock().NumberU64() >= block.NumberU64() {
@@ -65,6 +67,14 @@ func (fv *BlockValidator) ValidateBody(block *types.Block, validateSign bool) er
 		}
 		return consensus.ErrPrunedAncestor
 	}
+	// Reject blocks lacking a committee quorum before anything costly is done,
+	// the signs cover the header so they don't depend on the body
+	if validateSign {
+		if err := fv.bc.engine.VerifySigns(block.Number(), block.Hash(), block.Signs()); err != nil {
+			log.Info("Fast VerifySigns Err", "number", block.NumberU64(), "signs", block.Signs())
+			return err
+		}
+	}
 	// validate snail hash of the sign info for prev block
 	if fv.config.IsTIP9(block.Number()) && fv.config.IsTIP9(new(big.Int).Sub(block.Number(), big.NewInt(1))) {
 		pHash := block.GetSignHash()
@@ -109,11 +119,6 @@ func (fv *BlockValidator) ValidateBody(block *types.Block, validateSign bool) er
 	}
 
 	if validateSign {
-		if err := fv.bc.engine.VerifySigns(block.Number(), block.Hash(), block.Signs()); err != nil {
-			log.Info("Fast VerifySigns Err", "number", block.NumberU64(), "signs", block.Signs())
-			return err
-		}
-
 		if err := fv.bc.engine.VerifySwitchInfo(block.Number(), block.SwitchInfos()); err != nil {
 			log.Info("Fast VerifySwitchInfo Err", "number", block.NumberU64(), "signs", block.SwitchInfos())
 			return err
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -41,6 +42,7 @@ import (
 	"github.com/abeychain/go-abey/metrics"
 	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/tracing"
 	"github.com/abeychain/go-abey/trie"
 	"github.com/hashicorp/golang-lru"
 )
@@ -113,6 +115,7 @@ type BlockChain struct {
 	chainHeadFeed    event.Feed
 	logsFeed         event.Feed
 	blockProcFeed    event.Feed
+	finalizedFeed    event.Feed
 	RewardNumberFeed event.Feed
 	scope            event.SubscriptionScope
 	genesisBlock     *types.Block
@@ -131,7 +134,8 @@ type BlockChain struct {
 	bodyRLPCache     *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	receiptsCache    *lru.Cache     // Cache for the most recent receipts per block
 	blockCache       *lru.Cache     // Cache for the most recent entire blocks
-	futureBlocks     *lru.Cache     // future blocks are blocks added for later processing
+	futureBlocks     *FutureQueue   // future blocks are blocks waiting for their timestamp to become valid
+	side             *SideTracker   // blocks stored outside the canonical chain, pruned once out of reach
 	rewardCache      *lru.Cache
 	rewardinfoCache  *lru.Cache
 	balanceInfoCache *lru.Cache
@@ -172,7 +176,7 @@ func NewBlockChain(db abeydb.Database, cacheConfig *CacheConfig,
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
-	futureBlocks, _ := lru.New(maxFutureBlocks)
+	futureBlocks := NewFutureQueue(maxFutureBlocks, consensus.AllowedFutureBlockTime, "chain")
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
 	badBlocks, _ := lru.New(badBlockLimit)
 	signCache, _ := lru.New(bodyCacheLimit)
@@ -201,6 +205,7 @@ func NewBlockChain(db abeydb.Database, cacheConfig *CacheConfig,
 		badBlocks:        badBlocks,
 		isFallback:       false,
 	}
+	bc.side = NewSideTracker(db, sideStore{bc}, "chain")
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
 
@@ -408,6 +413,9 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash())
 
+	// Fast blocks are final once committed, any rewind drops finalized data
+	go bc.finalizedFeed.Send(types.FinalizedReorgEvent{Number: head + 1})
+
 	return bc.loadLastState()
 }
 
@@ -547,6 +555,19 @@ func (bc *BlockChain) StateCache() state.Database {
 	return bc.stateCache
 }
 
+// PurgeCaches drops the recently used blocks, bodies, receipts and clean trie
+// nodes from memory, releasing it under memory pressure. The content is
+// reloaded from disk on demand.
+func (bc *BlockChain) PurgeCaches() {
+	bc.bodyCache.Purge()
+	bc.bodyRLPCache.Purge()
+	bc.blockCache.Purge()
+	bc.receiptsCache.Purge()
+	bc.signCache.Purge()
+	bc.rewardCache.Purge()
+	bc.stateCache.TrieDB().ResetCleans()
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -846,20 +867,13 @@ func (bc *BlockChain) Stop() {
 	log.Info("Blockchain manager stopped")
 }
 
+// procFutureBlocks imports the queued future blocks whose timestamp became valid.
 func (bc *BlockChain) procFutureBlocks() {
-	blocks := make([]*types.Block, 0, bc.futureBlocks.Len())
-	for _, hash := range bc.futureBlocks.Keys() {
-		if block, exist := bc.futureBlocks.Peek(hash); exist {
-			blocks = append(blocks, block.(*types.Block))
-		}
-	}
-	if len(blocks) > 0 {
-		types.BlockBy(types.Number).Sort(blocks)
-
-		// Insert one by one as chain insertion needs contiguous ancestry between blocks
-		for i := range blocks {
-			bc.InsertChain(blocks[i : i+1])
-		}
+	// Insert one by one as chain insertion needs contiguous ancestry between blocks
+	for _, block := range bc.futureBlocks.Ready(time.Now()) {
+		block := block.(*types.Block)
+		log.Debug("Importing future block", "number", block.Number(), "hash", block.Hash())
+		bc.InsertChain(types.Blocks{block})
 	}
 }
 
@@ -1053,6 +1067,7 @@ func (bc *BlockChain) WriteBlockWithoutState(block *types.Block) (err error) {
 	defer bc.wg.Done()
 
 	rawdb.WriteBlock(bc.db, block)
+	bc.markSideBlocks(types.Blocks{block})
 
 	return nil
 }
@@ -1173,13 +1188,16 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 
 // addFutureBlock checks if the block is within the max allowed window to get
 // accepted for future processing, and returns an error if the block is too far
-// ahead and was not added.
+// ahead and was not added. Blocks not fitting into the full queue are skipped,
+// they will be fetched again once the chain catches up.
 func (bc *BlockChain) addFutureBlock(block *types.Block) error {
 	max := big.NewInt(time.Now().Unix() + maxTimeFutureBlocks)
 	if block.Time().Cmp(max) > 0 {
 		return fmt.Errorf("future block timestamp %v > allowed %v", block.Time(), max)
 	}
-	bc.futureBlocks.Add(block.Hash(), block)
+	if err := bc.futureBlocks.Add(block); err != nil {
+		log.Debug("Skipping future block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	}
 	return nil
 }
 
@@ -1207,12 +1225,19 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 				chain[i-1].Hash().Bytes()[:4], i, chain[i].NumberU64(), chain[i].Hash().Bytes()[:4], chain[i].ParentHash().Bytes()[:4])
 		}
 	}
-	// Pre-checks passed, start the full block imports
+	// Pre-checks passed, start the full block imports, joining the trace of
+	// the first block if it was packed locally
+	sc, _ := tracing.Recall(chain[0].Hash())
+	_, span := tracing.Start(tracing.ContextWithSpan(context.Background(), sc), "chain.insert",
+		tracing.Attr("blocks", len(chain)), tracing.Attr("first", chain[0].NumberU64()))
+	defer span.End()
+
 	bc.wg.Add(1)
 	bc.chainmu.Lock()
 	n, events, logs, err := bc.insertChain(chain, true)
 	bc.chainmu.Unlock()
 	bc.wg.Done()
+	span.SetError(err)
 
 	bc.postChainEvents(events, logs)
 	return n, err
@@ -1346,6 +1371,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if infos != nil {
 			bc.WriteRewardInfos(infos)
 		}
+		bc.updateEpochRewards(block, infos)
 		blockInsertTimer.UpdateSince(start)
 		blockExecutionTimer.Update(t1.Sub(t0))
 		blockValidationTimer.Update(t2.Sub(t1))
@@ -1596,6 +1622,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		rawdb.DeleteTxLookupEntry(batch, tx.Hash())
 	}
 	batch.Write()
+	bc.markSideBlocks(oldChain)
 
 	if len(deletedLogs) > 0 {
 		go bc.rmLogsFeed.Send(types.RemovedLogsEvent{Logs: deletedLogs})
@@ -1633,18 +1660,28 @@ func (bc *BlockChain) postChainEvents(events []interface{}, logs []*types.Log) {
 }
 
 func (bc *BlockChain) update() {
-	futureTimer := time.NewTicker(5 * time.Second)
+	gcTicker := time.NewTicker(5 * time.Second)
+	defer gcTicker.Stop()
+	futureTimer := time.NewTimer(0)
 	defer futureTimer.Stop()
+	pruneTicker := time.NewTicker(SideBlockPruneInterval)
+	defer pruneTicker.Stop()
 	for {
 		select {
-		case <-futureTimer.C:
+		case <-pruneTicker.C:
+			bc.pruneSideBlocks()
+		case <-gcTicker.C:
 			if bc.cacheConfig.Deleted {
 				number := bc.cacheConfig.HeightGcState.Load().(uint64)
 				if bc.GetBlockNumber() > number+blockDeleteHeight+blockDeleteLimite {
 					go bc.stateGcBodyAndReceipt(number)
 				}
 			}
+		case <-bc.futureBlocks.Wake():
+			bc.futureBlocks.Schedule(futureTimer)
+		case <-futureTimer.C:
 			bc.procFutureBlocks()
+			bc.futureBlocks.Schedule(futureTimer)
 		case <-bc.quit:
 			return
 		}
@@ -1798,6 +1835,11 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- types.FastChainSideEvent
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeFinalizedReorgEvent registers a subscription of types.FinalizedReorgEvent.
+func (bc *BlockChain) SubscribeFinalizedReorgEvent(ch chan<- types.FinalizedReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.finalizedFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -0,0 +1,65 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/abeychain/go-abey/core/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+)
+
+// updateEpochRewards accumulates the committee rewards paid out by a block into
+// the summary of its epoch, finalizing the summary at the last block of the epoch.
+func (bc *BlockChain) updateEpochRewards(block *types.Block, reward *types.ChainReward) {
+	number := block.NumberU64()
+	epoch := types.GetEpochFromHeight(number)
+	if reward == nil && number != epoch.EndHeight {
+		return
+	}
+	summary := rawdb.ReadEpochRewardSummary(bc.db, epoch.EpochID)
+	if summary == nil {
+		summary = types.NewEpochRewardSummary(epoch)
+	}
+	// Blocks imported again after a rewind were already accumulated
+	if summary.Final || (summary.Height >= number && summary.Height != 0) {
+		return
+	}
+	if reward != nil {
+		summary.Add(reward)
+	}
+	summary.Height = number
+
+	if number == epoch.EndHeight {
+		var begin uint64
+		if epoch.BeginHeight > 0 {
+			begin = epoch.BeginHeight - 1
+		}
+		if parent := bc.GetHeaderByNumber(begin); parent != nil && block.Time().Cmp(parent.Time) > 0 {
+			summary.Duration = block.Time().Uint64() - parent.Time.Uint64()
+		}
+		summary.Final = true
+		log.Info("Summarized epoch rewards", "epoch", epoch.EpochID, "validators", len(summary.Validators),
+			"delegators", summary.Delegators(), "reward", types.ToAbey(summary.TotalReward), "apr", summary.APR())
+	}
+	rawdb.WriteEpochRewardSummary(bc.db, summary)
+}
+
+// GetEpochRewardSummary retrieves the committee reward summary of an epoch, nil
+// if no block of the epoch was processed yet.
+func (bc *BlockChain) GetEpochRewardSummary(eid uint64) *types.EpochRewardSummary {
+	return rawdb.ReadEpochRewardSummary(bc.db, eid)
+}
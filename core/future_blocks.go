@@ -0,0 +1,194 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+// ErrFutureQueueFull is returned when a future block is later than all the
+// blocks already filling up the future queue.
+var ErrFutureQueueFull = errors.New("future block queue full")
+
+// FutureBlock is a block of either chain that can wait in a FutureQueue.
+type FutureBlock interface {
+	Hash() common.Hash
+	NumberU64() uint64
+	Time() *big.Int
+}
+
+// FutureQueue holds the blocks whose timestamp is too far ahead of the local
+// clock, releasing them for import once their timestamp becomes valid. The
+// queue is capped, making room for earlier blocks by dropping the latest ones.
+type FutureQueue struct {
+	limit     int
+	allowance time.Duration // Time a valid block timestamp may be ahead of the clock
+	blocks    map[common.Hash]FutureBlock
+	wake      chan struct{} // Notification channel for newly queued blocks
+
+	queuedGauge   metrics.Gauge // Number of blocks currently waiting
+	droppedMeter  metrics.Meter // Blocks dropped due to the queue being full
+	releasedMeter metrics.Meter // Blocks released for import
+
+	lock sync.Mutex
+}
+
+// NewFutureQueue creates a future block queue holding at most limit blocks,
+// which become valid once their timestamp is no more than allowance ahead of
+// the clock. Metrics are reported under the given prefix.
+func NewFutureQueue(limit int, allowance time.Duration, prefix string) *FutureQueue {
+	return &FutureQueue{
+		limit:         limit,
+		allowance:     allowance,
+		blocks:        make(map[common.Hash]FutureBlock),
+		wake:          make(chan struct{}, 1),
+		queuedGauge:   metrics.GetOrRegisterGauge(prefix+"/future/queued", nil),
+		droppedMeter:  metrics.GetOrRegisterMeter(prefix+"/future/dropped", nil),
+		releasedMeter: metrics.GetOrRegisterMeter(prefix+"/future/released", nil),
+	}
+}
+
+// Add queues a block until its timestamp becomes valid. If the queue is full,
+// the latest block is dropped to make room, unless the new block is the latest.
+func (q *FutureQueue) Add(block FutureBlock) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	hash := block.Hash()
+	if _, ok := q.blocks[hash]; ok {
+		return nil
+	}
+	if len(q.blocks) >= q.limit {
+		var latest FutureBlock
+		for _, queued := range q.blocks {
+			if latest == nil || queued.Time().Cmp(latest.Time()) > 0 {
+				latest = queued
+			}
+		}
+		if block.Time().Cmp(latest.Time()) >= 0 {
+			q.droppedMeter.Mark(1)
+			return ErrFutureQueueFull
+		}
+		delete(q.blocks, latest.Hash())
+		q.droppedMeter.Mark(1)
+	}
+	q.blocks[hash] = block
+	q.queuedGauge.Update(int64(len(q.blocks)))
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Wake returns a channel notified whenever a block is queued, signalling that
+// the import schedule might need to be brought forward.
+func (q *FutureQueue) Wake() <-chan struct{} {
+	return q.wake
+}
+
+// Contains reports whether the block is waiting in the queue.
+func (q *FutureQueue) Contains(hash common.Hash) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	_, ok := q.blocks[hash]
+	return ok
+}
+
+// Remove drops a block from the queue, if present.
+func (q *FutureQueue) Remove(hash common.Hash) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.blocks, hash)
+	q.queuedGauge.Update(int64(len(q.blocks)))
+}
+
+// Purge drops all the blocks from the queue.
+func (q *FutureQueue) Purge() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.blocks = make(map[common.Hash]FutureBlock)
+	q.queuedGauge.Update(0)
+}
+
+// Len returns the number of blocks waiting in the queue.
+func (q *FutureQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.blocks)
+}
+
+// Ready removes and returns the blocks whose timestamp became valid at the
+// given time, ordered by number for contiguous import.
+func (q *FutureQueue) Ready(now time.Time) []FutureBlock {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var ready []FutureBlock
+	for hash, block := range q.blocks {
+		if block.Time().Int64() <= now.Add(q.allowance).Unix() {
+			ready = append(ready, block)
+			delete(q.blocks, hash)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].NumberU64() < ready[j].NumberU64() })
+
+	q.releasedMeter.Mark(int64(len(ready)))
+	q.queuedGauge.Update(int64(len(q.blocks)))
+	return ready
+}
+
+// Next returns the time when the earliest queued block becomes importable,
+// or false if the queue is empty.
+func (q *FutureQueue) Next() (time.Time, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var earliest *big.Int
+	for _, block := range q.blocks {
+		if earliest == nil || block.Time().Cmp(earliest) < 0 {
+			earliest = block.Time()
+		}
+	}
+	if earliest == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(earliest.Int64(), 0).Add(-q.allowance), true
+}
+
+// Schedule resets the timer to fire when the earliest queued block becomes
+// importable, stopping it if the queue is empty.
+func (q *FutureQueue) Schedule(timer *time.Timer) {
+	next, ok := q.Next()
+	if !ok {
+		timer.Stop()
+		return
+	}
+	timer.Reset(time.Until(next))
+}
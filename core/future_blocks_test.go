@@ -0,0 +1,97 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// Tests that the future queue releases blocks once their timestamp becomes
+// valid, in number order, and makes room for earlier blocks when full.
+func TestFutureQueue(t *testing.T) {
+	now := time.Now()
+	block := func(number uint64, ahead time.Duration) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{
+			Number: new(big.Int).SetUint64(number),
+			Time:   big.NewInt(now.Add(ahead).Unix()),
+		})
+	}
+	allowance := 5 * time.Second
+	queue := NewFutureQueue(2, allowance, "test")
+
+	late, later := block(2, 20*time.Second), block(3, 30*time.Second)
+	if err := queue.Add(later); err != nil {
+		t.Fatalf("failed to queue block: %v", err)
+	}
+	if err := queue.Add(late); err != nil {
+		t.Fatalf("failed to queue block: %v", err)
+	}
+	if err := queue.Add(block(4, 40*time.Second)); err != ErrFutureQueueFull {
+		t.Fatalf("latest block queued into full queue: %v", err)
+	}
+	// An earlier block must evict the latest one
+	early := block(1, 10*time.Second)
+	if err := queue.Add(early); err != nil {
+		t.Fatalf("failed to queue earlier block: %v", err)
+	}
+	if queue.Contains(later.Hash()) || queue.Len() != 2 {
+		t.Fatalf("latest block not evicted")
+	}
+	// Blocks are importable as soon as they are within the allowance
+	want := early.Time().Int64() - int64(allowance/time.Second)
+	if next, ok := queue.Next(); !ok || next.Unix() != want {
+		t.Fatalf("next import time mismatch: have %v, want %v", next.Unix(), want)
+	}
+	if ready := queue.Ready(now.Add(4 * time.Second)); len(ready) != 0 {
+		t.Fatalf("blocks released early: %d", len(ready))
+	}
+	if ready := queue.Ready(now.Add(5 * time.Second)); len(ready) != 1 || ready[0].Hash() != early.Hash() {
+		t.Fatalf("released blocks mismatch: %v", ready)
+	}
+	queue.Add(early)
+	ready := queue.Ready(now.Add(time.Minute))
+	if len(ready) != 2 || ready[0].Hash() != early.Hash() || ready[1].Hash() != late.Hash() {
+		t.Fatalf("released blocks mismatch: %v", ready)
+	}
+	if _, ok := queue.Next(); ok || queue.Len() != 0 {
+		t.Fatalf("released blocks still queued")
+	}
+}
+
+// Tests that blocks not fitting into a full future queue are skipped instead of
+// failing the import of the whole batch.
+func TestAddFutureBlockQueueFull(t *testing.T) {
+	bc := &BlockChain{futureBlocks: NewFutureQueue(1, 0, "test")}
+
+	now := time.Now()
+	for i, ahead := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number: big.NewInt(int64(i + 1)),
+			Time:   big.NewInt(now.Add(ahead).Unix()),
+		})
+		if err := bc.addFutureBlock(block); err != nil {
+			t.Fatalf("block %d: failed to add future block: %v", i, err)
+		}
+	}
+	if bc.futureBlocks.Len() != 1 {
+		t.Fatalf("queued block count mismatch: have %d, want 1", bc.futureBlocks.Len())
+	}
+}
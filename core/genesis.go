@@ -66,6 +66,8 @@ type Genesis struct {
 	Number     uint64      `json:"number"`
 	GasUsed    uint64      `json:"gasUsed"`
 	ParentHash common.Hash `json:"parentHash"`
+
+	stateRoot common.Hash // State restored into the database beforehand, replacing the allocation
 }
 type LesGenesis struct {
 	Config    *params.ChainConfig      `json:"config"`
@@ -275,6 +277,37 @@ func (g *Genesis) CommitFast(db abeydb.Database) (*types.Block, error) {
 // ToFastBlock creates the genesis block and writes state of a genesis specification
 // to the given database (or discards it if nil).
 func (g *Genesis) ToFastBlock(db abeydb.Database) *types.Block {
+	root := g.stateRoot
+	if root == (common.Hash{}) {
+		root = g.commitAlloc(db)
+	}
+	head := &types.Header{
+		Number:     new(big.Int).SetUint64(g.Number),
+		Time:       new(big.Int).SetUint64(g.Timestamp),
+		ParentHash: g.ParentHash,
+		Extra:      g.ExtraData,
+		GasLimit:   g.GasLimit,
+		GasUsed:    g.GasUsed,
+		Root:       root,
+	}
+	if g.GasLimit == 0 {
+		head.GasLimit = params.GenesisGasLimit
+	}
+
+	// All genesis committee members are included in switchinfo of block #0
+	committee := &types.SwitchInfos{CID: common.Big0, Members: g.Committee, BackMembers: make([]*types.CommitteeMember, 0), Vals: make([]*types.SwitchEnter, 0)}
+	for _, member := range committee.Members {
+		pubkey, _ := crypto.UnmarshalPubkey(member.Publickey)
+		member.Flag = types.StateUsedFlag
+		member.MType = types.TypeFixed
+		member.CommitteeBase = crypto.PubkeyToAddress(*pubkey)
+	}
+	return types.NewBlock(head, nil, nil, nil, committee.Members)
+}
+
+// commitAlloc writes the genesis state of the allocation and the initial
+// staking to the given database (or discards it if nil), returning its root.
+func (g *Genesis) commitAlloc(db abeydb.Database) common.Hash {
 	if db == nil {
 		db = abeydb.NewMemDatabase()
 	}
@@ -326,31 +359,34 @@ func (g *Genesis) ToFastBlock(db abeydb.Database) *types.Block {
 	}
 
 	root := statedb.IntermediateRoot(false)
+	statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, true)
+	return root
+}
 
-	head := &types.Header{
-		Number:     new(big.Int).SetUint64(g.Number),
-		Time:       new(big.Int).SetUint64(g.Timestamp),
-		ParentHash: g.ParentHash,
-		Extra:      g.ExtraData,
-		GasLimit:   g.GasLimit,
-		GasUsed:    g.GasUsed,
-		Root:       root,
+// CommitRegenesis writes the genesis blocks of the specification on top of a
+// state restored into the database beforehand, which replaces the allocation
+// and the initial staking. It allows restarting a network from the state of
+// another one with the balances preserved.
+func (g *Genesis) CommitRegenesis(db abeydb.Database, root common.Hash) (*types.Block, *types.SnailBlock, error) {
+	if g.Config == nil {
+		return nil, nil, errGenesisNoConfig
 	}
-	if g.GasLimit == 0 {
-		head.GasLimit = params.GenesisGasLimit
+	if _, err := state.New(root, state.NewDatabase(db)); err != nil {
+		return nil, nil, fmt.Errorf("missing regenesis state: %v", err)
 	}
-	statedb.Commit(false)
-	statedb.Database().TrieDB().Commit(root, true)
+	g.stateRoot = root
+	defer func() { g.stateRoot = common.Hash{} }()
 
-	// All genesis committee members are included in switchinfo of block #0
-	committee := &types.SwitchInfos{CID: common.Big0, Members: g.Committee, BackMembers: make([]*types.CommitteeMember, 0), Vals: make([]*types.SwitchEnter, 0)}
-	for _, member := range committee.Members {
-		pubkey, _ := crypto.UnmarshalPubkey(member.Publickey)
-		member.Flag = types.StateUsedFlag
-		member.MType = types.TypeFixed
-		member.CommitteeBase = crypto.PubkeyToAddress(*pubkey)
+	fastBlock, err := g.CommitFast(db)
+	if err != nil {
+		return nil, nil, err
 	}
-	return types.NewBlock(head, nil, nil, nil, committee.Members)
+	snailBlock, err := g.CommitSnail(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fastBlock, snailBlock, nil
 }
 
 // MustFastCommit writes the genesis block and state to db, panicking on error.
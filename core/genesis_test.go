@@ -907,3 +907,42 @@ func displayLockedAsset(infos map[common.Address]*types.LockedValue, height uint
 		fmt.Println("address:", k.String(), "staking amount in locked info.................")
 	}
 }
+
+// Tests that a regenesis writes the genesis blocks on top of an existing state
+// instead of the allocation, and refuses a missing state.
+func TestCommitRegenesis(t *testing.T) {
+	var (
+		db   = abeydb.NewMemDatabase()
+		addr = common.HexToAddress("0x1234")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.AddBalance(addr, big.NewInt(1000))
+	root, _ := statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, false)
+
+	genesis := &Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1000),
+		Alloc:      types.GenesisAlloc{common.HexToAddress("0x5678"): {Balance: big.NewInt(1)}},
+	}
+	if _, _, err := genesis.CommitRegenesis(abeydb.NewMemDatabase(), root); err == nil {
+		t.Fatalf("regenesis succeeded without state")
+	}
+	fast, snail, err := genesis.CommitRegenesis(db, root)
+	if err != nil {
+		t.Fatalf("failed to commit regenesis: %v", err)
+	}
+	if fast.Root() != root {
+		t.Errorf("genesis root mismatch: have %x, want %x", fast.Root(), root)
+	}
+	if stored := rawdb.ReadCanonicalHash(db, 0); stored != fast.Hash() {
+		t.Errorf("canonical genesis mismatch: have %x, want %x", stored, fast.Hash())
+	}
+	if fruit := snail.Fruits()[0]; fruit.FastHash() != fast.Hash() {
+		t.Errorf("genesis fruit mismatch: have %x, want %x", fruit.FastHash(), fast.Hash())
+	}
+	restored, _ := state.New(fast.Root(), state.NewDatabase(db))
+	if balance := restored.GetBalance(addr); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1000", balance)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import "github.com/abeychain/go-abey/abeydb"
+
+// Migrations are the schema migrations of the chain database, applied on
+// startup in version order. Format changes of stored data must append a new
+// migration converting existing databases, listing the key prefixes it
+// rewrites so they are backed up while it runs. Released migrations must never
+// be modified or removed.
+var Migrations = []abeydb.Migration{
+	{
+		// Marks databases as tracking their schema, the baseline of all
+		// later migrations.
+		Version: 1,
+		Name:    "schema version tracking",
+		Migrate: func(abeydb.Database) error { return nil },
+	},
+}
@@ -91,6 +91,32 @@ func WriteHeadBlockHash(db DatabaseWriter, hash common.Hash) {
 	}
 }
 
+// ReadSideBlocks retrieves the hashes of the stored blocks outside the canonical
+// chain, as tracked for pruning.
+func ReadSideBlocks(db DatabaseReader) []common.Hash {
+	data, _ := db.Get(sideBlocksKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := rlp.DecodeBytes(data, &hashes); err != nil {
+		log.Error("Invalid side block list RLP", "err", err)
+		return nil
+	}
+	return hashes
+}
+
+// WriteSideBlocks stores the hashes of the stored blocks outside the canonical chain.
+func WriteSideBlocks(db DatabaseWriter, hashes []common.Hash) {
+	data, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		log.Crit("Failed to RLP encode side block list", "err", err)
+	}
+	if err := db.Put(sideBlocksKey, data); err != nil {
+		log.Crit("Failed to store side block list", "err", err)
+	}
+}
+
 // ReadHeadBlockHash retrieves the hash of the current canonical head block.
 func ReadHeadRewardNumber(db DatabaseReader) uint64 {
 	data, _ := db.Get(headRewardKey)
@@ -547,6 +573,31 @@ func DeleteRewardInfo(db DatabaseDeleter, snailHeight uint64) {
 	}
 }
 
+// WriteEpochRewardSummary stores the reward summary of an epoch.
+func WriteEpochRewardSummary(db DatabaseWriter, summary *types.EpochRewardSummary) {
+	data, err := rlp.EncodeToBytes(summary)
+	if err != nil {
+		log.Crit("Failed to RLP encode epoch reward summary", "err", err, "epoch", summary.EpochID)
+	}
+	if err := db.Put(epochRewardKey(summary.EpochID), data); err != nil {
+		log.Crit("Failed to store epoch reward summary", "err", err)
+	}
+}
+
+// ReadEpochRewardSummary retrieves the reward summary of an epoch.
+func ReadEpochRewardSummary(db DatabaseReader, eid uint64) *types.EpochRewardSummary {
+	data, _ := db.Get(epochRewardKey(eid))
+	if len(data) == 0 {
+		return nil
+	}
+	summary := new(types.EpochRewardSummary)
+	if err := rlp.Decode(bytes.NewReader(data), summary); err != nil {
+		log.Error("Invalid epoch reward summary RLP", "epoch", eid, "err", err)
+		return nil
+	}
+	return summary
+}
+
 func WriteBalanceInfo(db DatabaseWriter, height uint64, infos *types.BlockBalance) {
 	data, err := rlp.EncodeToBytes(infos)
 	if err != nil {
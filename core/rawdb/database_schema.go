@@ -0,0 +1,58 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/abeychain/go-abey/abeydb"
+	snaildb "github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/crypto"
+)
+
+// isHashKeyed checks whether an entry is keyed by the hash of its value, as the
+// state trie nodes and contract codes are.
+func isHashKeyed(key, value []byte) bool {
+	return bytes.Equal(key, crypto.Keccak256(value))
+}
+
+// auxiliarySchema lists the keys written to the chain database outside of the
+// rawdb packages, which can't be referenced from here.
+var auxiliarySchema = []abeydb.KeySchema{
+	{Name: "trie node or code", Owner: "trie", Length: 32, Layout: "hash", Match: isHashKeyed},
+	{Name: "cht root", Owner: "light", Prefix: []byte("chtRoot-"), Length: 48, Layout: "chtRoot- + section (uint64 big endian) + section head"},
+	{Name: "cht node", Owner: "light", Prefix: []byte("cht-"), Length: 36, Layout: "cht- + hash"},
+	{Name: "cht index", Owner: "light", Prefix: []byte("chtIndex-"), Layout: "chtIndex- + chain indexer key"},
+	{Name: "bloom trie root", Owner: "light", Prefix: []byte("bltRoot-"), Length: 48, Layout: "bltRoot- + section (uint64 big endian) + section head"},
+	{Name: "bloom trie node", Owner: "light", Prefix: []byte("blt-"), Length: 36, Layout: "blt- + hash"},
+	{Name: "bloom trie index", Owner: "light", Prefix: []byte("bltIndex-"), Layout: "bltIndex- + chain indexer key"},
+	{Name: "snail cht root", Owner: "light", Prefix: []byte("snailChtRoot-"), Length: 53, Layout: "snailChtRoot- + section (uint64 big endian) + section head"},
+	{Name: "snail cht node", Owner: "light", Prefix: []byte("scht-"), Length: 37, Layout: "scht- + hash"},
+	{Name: "snail cht index", Owner: "light", Prefix: []byte("schtIndex-"), Layout: "schtIndex- + chain indexer key"},
+	{Name: "request cost stats", Owner: "les", Prefix: []byte("_requestCostStats"), Length: 17, Layout: "_requestCostStats"},
+	{Name: "server pool", Owner: "les", Prefix: []byte("serverPool/"), Layout: "serverPool/ + topic"},
+	{Name: "chain statistics", Owner: "abey/chainstats", Prefix: []byte("chainstats-"), Length: 19, Layout: "chainstats- + day (uint64 big endian)"},
+}
+
+// DatabaseSchema returns the keys of all data stored in the chain database.
+func DatabaseSchema() []abeydb.KeySchema {
+	var schema []abeydb.KeySchema
+	schema = append(schema, abeydb.Schema...)
+	schema = append(schema, Schema...)
+	schema = append(schema, snaildb.Schema...)
+	return append(schema, auxiliarySchema...)
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+)
+
+// Tests that the keys written by the accessors are attributed to their key
+// family by a database inspection.
+func TestInspectDatabase(t *testing.T) {
+	db := abeydb.NewMemDatabase()
+	hash := common.HexToHash("0x01")
+
+	want := map[string][]byte{
+		"header":               headerKey(7, hash),
+		"total difficulty":     headerTDKey(7, hash),
+		"canonical hash":       headerHashKey(7),
+		"committee info":       headerCIKey(7, hash),
+		"header number":        headerNumberKey(hash),
+		"block reward":         blockRewardKey(7),
+		"block body":           blockBodyKey(7, hash),
+		"block receipts":       blockReceiptsKey(7, hash),
+		"tx lookup":            txLookupKey(hash),
+		"bloom bits":           bloomBitsKey(3, 7, hash),
+		"preimage":             preimageKey(hash),
+		"epoch reward summary": epochRewardKey(7),
+		"head block":           headBlockKey,
+	}
+	for _, key := range want {
+		db.Put(key, []byte{0x01})
+	}
+	node := []byte("trie node")
+	db.Put(crypto.Keccak256(node), node)
+	db.Put(common.HexToHash("0x02").Bytes(), node)
+
+	result := abeydb.Inspect(db, DatabaseSchema())
+	counts := make(map[string]uint64)
+	for _, stats := range result.Known {
+		counts[stats.Name] += stats.Count
+	}
+	for name := range want {
+		if counts[name] != 1 {
+			t.Errorf("%s: have %d entries, want 1", name, counts[name])
+		}
+	}
+	if counts["trie node or code"] != 1 {
+		t.Errorf("trie nodes: have %d entries, want 1", counts["trie node or code"])
+	}
+	if result.Unknown.Count != 1 || !bytes.Equal(result.UnknownSamples[0], common.HexToHash("0x02").Bytes()) {
+		t.Errorf("unknown entries mismatch: have %d, samples %v", result.Unknown.Count, result.UnknownSamples)
+	}
+}
+
+// Tests that the generated schema documentation doesn't depend on the order of
+// the registry.
+func TestSchemaDocDeterministic(t *testing.T) {
+	schema := DatabaseSchema()
+	reversed := make([]abeydb.KeySchema, len(schema))
+	for i := range schema {
+		reversed[len(schema)-1-i] = schema[i]
+	}
+	var a, b bytes.Buffer
+	if err := abeydb.WriteSchemaDoc(&a, schema); err != nil {
+		t.Fatalf("failed to write doc: %v", err)
+	}
+	abeydb.WriteSchemaDoc(&b, reversed)
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatalf("schema doc depends on registry order")
+	}
+}
@@ -20,6 +20,7 @@ package rawdb
 import (
 	"encoding/binary"
 
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/metrics"
 )
@@ -32,6 +33,9 @@ var (
 	// headBlockKey tracks the latest know full block's hash.
 	headBlockKey = []byte("LastBlock")
 
+	// sideBlocksKey tracks the hashes of the stored blocks outside the canonical chain.
+	sideBlocksKey = []byte("SideBlocks")
+
 	headRewardKey = []byte("LastReward")
 
 	lastBlockKey = []byte("LastBlockIndex")
@@ -64,6 +68,7 @@ var (
 	configPrefix      = []byte("abeychain-config-") // config prefix for the db
 	rewardInfoPrefix  = []byte("sri")
 	balanceInfoPrefix = []byte("srb")
+	epochRewardPrefix = []byte("sre") // epochRewardPrefix + epoch id (uint64 big endian) -> epoch reward summary
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
@@ -94,6 +99,11 @@ func balanceInfoKey(number uint64) []byte {
 	return append(balanceInfoPrefix, encodeBlockNumber(number)...)
 }
 
+// epochRewardKey = epochRewardPrefix + epoch id (uint64 big endian)
+func epochRewardKey(eid uint64) []byte {
+	return append(epochRewardPrefix, encodeBlockNumber(eid)...)
+}
+
 // headerKey = headerPrefix + num (uint64 big endian) + hash
 func headerKey(number uint64, hash common.Hash) []byte {
 	return append(append(headerPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
@@ -158,3 +168,36 @@ func configKey(hash common.Hash) []byte {
 func headerCIKey(number uint64, hash common.Hash) []byte {
 	return append(headerKey(number, hash), headerCISuffix...)
 }
+
+// singleKey returns the schema of a single key.
+func singleKey(name string, k []byte) abeydb.KeySchema {
+	return abeydb.KeySchema{Name: name, Owner: "core/rawdb", Prefix: k, Length: len(k), Layout: string(k)}
+}
+
+// Schema lists the keys of the fast chain data.
+var Schema = []abeydb.KeySchema{
+	singleKey("head header", headHeaderKey),
+	singleKey("head block", headBlockKey),
+	singleKey("side blocks", sideBlocksKey),
+	singleKey("head reward", headRewardKey),
+	singleKey("last block index", lastBlockKey),
+	singleKey("head fast block", headFastBlockKey),
+	singleKey("fast trie progress", fastTrieProgressKey),
+	singleKey("state gc progress", stateGcBodyReceiptKey),
+	{Name: "header", Owner: "core/rawdb", Prefix: headerPrefix, Length: 41, Layout: "h + num (uint64 big endian) + hash"},
+	{Name: "total difficulty", Owner: "core/rawdb", Prefix: headerPrefix, Suffix: headerTDSuffix, Length: 42, Layout: "h + num (uint64 big endian) + hash + t"},
+	{Name: "committee info", Owner: "core/rawdb", Prefix: headerPrefix, Suffix: headerCISuffix, Length: 42, Layout: "h + num (uint64 big endian) + hash + c"},
+	{Name: "canonical hash", Owner: "core/rawdb", Prefix: headerPrefix, Suffix: headerHashSuffix, Length: 10, Layout: "h + num (uint64 big endian) + n"},
+	{Name: "header number", Owner: "core/rawdb", Prefix: headerNumberPrefix, Length: 33, Layout: "H + hash"},
+	{Name: "block reward", Owner: "core/rawdb", Prefix: blockRewardPrefix, Suffix: blockRewardPrefix, Length: 22, Layout: "reward- + num (uint64 big endian) + reward-"},
+	{Name: "block body", Owner: "core/rawdb", Prefix: blockBodyPrefix, Length: 41, Layout: "b + num (uint64 big endian) + hash"},
+	{Name: "block receipts", Owner: "core/rawdb", Prefix: blockReceiptsPrefix, Length: 41, Layout: "r + num (uint64 big endian) + hash"},
+	{Name: "tx lookup", Owner: "core/rawdb", Prefix: txLookupPrefix, Length: 33, Layout: "l + hash"},
+	{Name: "bloom bits", Owner: "core/rawdb", Prefix: bloomBitsPrefix, Length: 43, Layout: "B + bit (uint16 big endian) + section (uint64 big endian) + hash"},
+	{Name: "preimage", Owner: "core/rawdb", Prefix: preimagePrefix, Length: len(preimagePrefix) + 32, Layout: "secure-key- + hash"},
+	{Name: "chain config", Owner: "core/rawdb", Prefix: configPrefix, Length: len(configPrefix) + 32, Layout: "abeychain-config- + genesis hash"},
+	{Name: "reward info", Owner: "core/rawdb", Prefix: rewardInfoPrefix, Length: 11, Layout: "sri + snail num (uint64 big endian)"},
+	{Name: "balance info", Owner: "core/rawdb", Prefix: balanceInfoPrefix, Length: 11, Layout: "srb + num (uint64 big endian)"},
+	{Name: "epoch reward summary", Owner: "core/rawdb", Prefix: epochRewardPrefix, Length: 11, Layout: "sre + epoch id (uint64 big endian)"},
+	{Name: "bloom bits index", Owner: "core/rawdb", Prefix: BloomBitsIndexPrefix, Layout: "iB + chain indexer key"},
+}
@@ -0,0 +1,255 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+const (
+	sideBlockRetention = 1024 // Number of blocks below the head to keep side-chain blocks for
+
+	// SideBlockPruneInterval is the time interval between side-chain prunings.
+	SideBlockPruneInterval = time.Minute
+)
+
+// SideHead describes the tip of a stored fork outside the canonical chain.
+type SideHead struct {
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	ForkNumber uint64      `json:"forkNumber"` // Number of the last canonical ancestor
+}
+
+// SortSideHeads orders side-chain heads from the highest to the lowest.
+func SortSideHeads(heads []SideHead) {
+	sort.Slice(heads, func(i, j int) bool {
+		if heads[i].Number != heads[j].Number {
+			return heads[i].Number > heads[j].Number
+		}
+		return heads[i].ForkNumber > heads[j].ForkNumber
+	})
+}
+
+// SideStore is the chain specific storage of the blocks a SideTracker tracks.
+type SideStore interface {
+	// ReadSideBlocks retrieves the hashes of the tracked blocks.
+	ReadSideBlocks() []common.Hash
+
+	// WriteSideBlocks stores the hashes of the tracked blocks.
+	WriteSideBlocks(db abeydb.Putter, hashes []common.Hash)
+
+	// SideBlock retrieves the number and the parent hash of a stored block.
+	SideBlock(hash common.Hash) (number uint64, parent common.Hash, ok bool)
+
+	// CanonicalHash retrieves the hash of the canonical block at number.
+	CanonicalHash(number uint64) common.Hash
+
+	// DeleteSideBlock deletes a stored block, dropping it from any cache.
+	DeleteSideBlock(db abeydb.Deleter, hash common.Hash, number uint64)
+}
+
+// SideTracker records the blocks of either chain stored outside the canonical
+// chain, e.g. dropped by a reorg or imported on a fork, so they can be pruned
+// once they fall out of the retention window.
+type SideTracker struct {
+	db    abeydb.Database
+	store SideStore
+
+	prunedMeter metrics.Meter // Side-chain blocks deleted
+
+	lock sync.Mutex
+}
+
+// NewSideTracker creates a side-chain block tracker on top of the chain store,
+// reporting its metrics under the given prefix.
+func NewSideTracker(db abeydb.Database, store SideStore, prefix string) *SideTracker {
+	return &SideTracker{
+		db:          db,
+		store:       store,
+		prunedMeter: metrics.GetOrRegisterMeter(prefix+"/side/pruned", nil),
+	}
+}
+
+// Mark starts tracking the given blocks stored outside the canonical chain.
+func (t *SideTracker) Mark(hashes ...common.Hash) {
+	if len(hashes) == 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tracked := t.store.ReadSideBlocks()
+	known := make(map[common.Hash]bool, len(tracked))
+	for _, hash := range tracked {
+		known[hash] = true
+	}
+	for _, hash := range hashes {
+		if !known[hash] {
+			tracked = append(tracked, hash)
+			known[hash] = true
+		}
+	}
+	t.store.WriteSideBlocks(t.db, tracked)
+}
+
+// Prune deletes the tracked blocks more than retention blocks below the head,
+// and stops tracking the ones that became canonical meanwhile. The caller must
+// hold the chain insertion lock, so no block becomes canonical while deleted.
+func (t *SideTracker) Prune(head uint64, retention uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hashes := t.store.ReadSideBlocks()
+	if len(hashes) == 0 {
+		return
+	}
+	var (
+		batch  = t.db.NewBatch()
+		keep   []common.Hash
+		pruned int
+	)
+	for _, hash := range hashes {
+		number, _, ok := t.store.SideBlock(hash)
+		switch {
+		case !ok || t.store.CanonicalHash(number) == hash:
+			// Block already gone or canonical again, stop tracking it
+		case number+retention > head:
+			keep = append(keep, hash)
+		default:
+			t.store.DeleteSideBlock(batch, hash, number)
+			pruned++
+		}
+	}
+	t.store.WriteSideBlocks(batch, keep)
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to prune side-chain blocks", "err", err)
+		return
+	}
+	if pruned > 0 {
+		t.prunedMeter.Mark(int64(pruned))
+		log.Info("Pruned side-chain blocks", "count", pruned, "head", head, "remaining", len(keep))
+	}
+}
+
+// Heads returns the tips of the side chains currently stored, highest first.
+func (t *SideTracker) Heads() []SideHead {
+	t.lock.Lock()
+	hashes := t.store.ReadSideBlocks()
+	t.lock.Unlock()
+
+	numbers := make(map[common.Hash]uint64)
+	parents := make(map[common.Hash]bool)
+	for _, hash := range hashes {
+		number, parent, ok := t.store.SideBlock(hash)
+		if !ok || t.store.CanonicalHash(number) == hash {
+			continue
+		}
+		numbers[hash] = number
+		parents[parent] = true
+	}
+	heads := make([]SideHead, 0, len(numbers))
+	for hash, number := range numbers {
+		if parents[hash] {
+			continue
+		}
+		head := SideHead{Number: number, Hash: hash}
+		for fork := hash; ; {
+			number, parent, ok := t.store.SideBlock(fork)
+			if !ok {
+				break
+			}
+			if t.store.CanonicalHash(number) == fork {
+				head.ForkNumber = number
+				break
+			}
+			fork = parent
+		}
+		heads = append(heads, head)
+	}
+	SortSideHeads(heads)
+	return heads
+}
+
+// sideStore is the storage of the fast chain side-chain blocks.
+type sideStore struct {
+	bc *BlockChain
+}
+
+func (s sideStore) ReadSideBlocks() []common.Hash {
+	return rawdb.ReadSideBlocks(s.bc.db)
+}
+
+func (s sideStore) WriteSideBlocks(db abeydb.Putter, hashes []common.Hash) {
+	rawdb.WriteSideBlocks(db, hashes)
+}
+
+func (s sideStore) SideBlock(hash common.Hash) (uint64, common.Hash, bool) {
+	number := rawdb.ReadHeaderNumber(s.bc.db, hash)
+	if number == nil {
+		return 0, common.Hash{}, false
+	}
+	header := s.bc.GetHeader(hash, *number)
+	if header == nil {
+		return 0, common.Hash{}, false
+	}
+	return *number, header.ParentHash, true
+}
+
+func (s sideStore) CanonicalHash(number uint64) common.Hash {
+	return rawdb.ReadCanonicalHash(s.bc.db, number)
+}
+
+func (s sideStore) DeleteSideBlock(db abeydb.Deleter, hash common.Hash, number uint64) {
+	rawdb.DeleteBlock(db, hash, number)
+	s.bc.blockCache.Remove(hash)
+	s.bc.bodyCache.Remove(hash)
+	s.bc.bodyRLPCache.Remove(hash)
+	s.bc.receiptsCache.Remove(hash)
+}
+
+// markSideBlocks records blocks stored outside the canonical chain, so they can
+// be pruned once they fall out of the retention window.
+func (bc *BlockChain) markSideBlocks(blocks types.Blocks) {
+	hashes := make([]common.Hash, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash()
+	}
+	bc.side.Mark(hashes...)
+}
+
+// pruneSideBlocks deletes the side-chain blocks that fell out of the retention
+// window, and stops tracking the ones that became canonical meanwhile.
+func (bc *BlockChain) pruneSideBlocks() {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.side.Prune(bc.CurrentBlock().NumberU64(), sideBlockRetention)
+}
+
+// SideHeads returns the tips of the side chains currently stored, highest first.
+func (bc *BlockChain) SideHeads() []SideHead {
+	return bc.side.Heads()
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// testSideBlock is a block stored in a testSideStore.
+type testSideBlock struct {
+	number uint64
+	parent common.Hash
+}
+
+// testSideStore is an in-memory chain store for the side-chain tracker.
+type testSideStore struct {
+	db        abeydb.Database
+	blocks    map[common.Hash]testSideBlock
+	canonical map[uint64]common.Hash
+	deleted   map[common.Hash]bool
+}
+
+func (s *testSideStore) ReadSideBlocks() []common.Hash { return rawdb.ReadSideBlocks(s.db) }
+func (s *testSideStore) WriteSideBlocks(db abeydb.Putter, hashes []common.Hash) {
+	rawdb.WriteSideBlocks(db, hashes)
+}
+func (s *testSideStore) CanonicalHash(number uint64) common.Hash { return s.canonical[number] }
+
+func (s *testSideStore) SideBlock(hash common.Hash) (uint64, common.Hash, bool) {
+	block, ok := s.blocks[hash]
+	return block.number, block.parent, ok && !s.deleted[hash]
+}
+
+func (s *testSideStore) DeleteSideBlock(db abeydb.Deleter, hash common.Hash, number uint64) {
+	s.deleted[hash] = true
+}
+
+// extend adds n blocks on top of parent, returning their hashes.
+func (s *testSideStore) extend(parent common.Hash, n int, canonical bool, seed byte) []common.Hash {
+	var hashes []common.Hash
+	for i := 0; i < n; i++ {
+		number := s.blocks[parent].number + 1
+		hash := common.Hash{seed, byte(number)}
+		s.blocks[hash] = testSideBlock{number: number, parent: parent}
+		if canonical {
+			s.canonical[number] = hash
+		}
+		hashes = append(hashes, hash)
+		parent = hash
+	}
+	return hashes
+}
+
+func newTestSideStore() *testSideStore {
+	genesis := common.Hash{0xff}
+	return &testSideStore{
+		db:        abeydb.NewMemDatabase(),
+		blocks:    map[common.Hash]testSideBlock{genesis: {}},
+		canonical: map[uint64]common.Hash{0: genesis},
+		deleted:   make(map[common.Hash]bool),
+	}
+}
+
+// Tests that side-chain heads are reported with their fork points, and that
+// pruning only deletes the blocks out of the retention window.
+func TestSideTrackerPrune(t *testing.T) {
+	store := newTestSideStore()
+	canon := store.extend(store.canonical[0], 10, true, 0)
+	long := store.extend(canon[1], 3, false, 1)  // blocks 3-5, forking at 2
+	short := store.extend(canon[6], 1, false, 2) // block 8, forking at 7
+
+	tracker := NewSideTracker(store.db, store, "test")
+	tracker.Mark(long...)
+	tracker.Mark(short[0], long[0])           // duplicates are tracked once
+	tracker.Mark(canon[4], common.Hash{0xee}) // canonical or unknown blocks are ignored
+
+	heads := tracker.Heads()
+	want := []SideHead{{Number: 8, Hash: short[0], ForkNumber: 7}, {Number: 5, Hash: long[2], ForkNumber: 2}}
+	if len(heads) != len(want) {
+		t.Fatalf("side head count mismatch: have %v, want %v", heads, want)
+	}
+	for i := range want {
+		if heads[i] != want[i] {
+			t.Errorf("side head %d mismatch: have %+v, want %+v", i, heads[i], want[i])
+		}
+	}
+	// Only the blocks more than the retention below the head are deleted
+	tracker.Prune(10, 6)
+
+	for _, hash := range long[:2] {
+		if !store.deleted[hash] {
+			t.Errorf("block %x not pruned", hash)
+		}
+	}
+	if len(store.deleted) != 2 {
+		t.Errorf("pruned block count mismatch: have %d, want 2", len(store.deleted))
+	}
+	tracked := store.ReadSideBlocks()
+	sort.Slice(tracked, func(i, j int) bool { return tracked[i][0] < tracked[j][0] })
+	if len(tracked) != 2 || tracked[0] != long[2] || tracked[1] != short[0] {
+		t.Errorf("tracked blocks mismatch: have %x", tracked)
+	}
+	// Blocks becoming canonical are no longer tracked
+	store.canonical[8] = short[0]
+	tracker.Prune(10, 6)
+	if tracked := store.ReadSideBlocks(); len(tracked) != 1 || tracked[0] != long[2] {
+		t.Errorf("tracked blocks mismatch: have %x", tracked)
+	}
+}
+
+// Tests that the blocks of a fast side chain written without state are tracked.
+func TestWriteBlockWithoutStateTracked(t *testing.T) {
+	bc := &BlockChain{db: abeydb.NewMemDatabase()}
+	bc.side = NewSideTracker(bc.db, sideStore{bc}, "test")
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)})
+	if err := bc.WriteBlockWithoutState(block); err != nil {
+		t.Fatalf("failed to write block: %v", err)
+	}
+	if tracked := rawdb.ReadSideBlocks(bc.db); len(tracked) != 1 || tracked[0] != block.Hash() {
+		t.Errorf("side block not tracked: have %x, want %x", tracked, block.Hash())
+	}
+}
@@ -40,7 +40,7 @@ var (
 	ErrInvalidFast = errors.New("invalid fast hash")
 
 	//ErrNoFruits is returned if the block not contains the exact fruit count
-	ErrNoFruits = errors.New("invalid fruits count")
+	ErrNoFruits = consensus.ErrNoFruits
 
 	//ErrInvalidFruits is returned if the fruits in block not continuity
 	ErrInvalidFruits = errors.New("invalid fruits number")
@@ -120,8 +120,11 @@ func (v *BlockValidator) ValidateBody(block *types.SnailBlock, verifyFruits bool
 		return consensus.ErrUnknownAncestor
 	}
 	if preBlock.Number().Cmp(common.Big0) > 0 {
-		localFruits := preBlock.Fruits()
-		temp = localFruits[len(localFruits)-1].FastNumber().Uint64()
+		maxFruitNumber := preBlock.MaxFruitNumber()
+		if maxFruitNumber == nil {
+			return ErrNoFruits
+		}
+		temp = maxFruitNumber.Uint64()
 	}
 	fruits := block.Fruits()
 	maxfb := v.fastchain.GetHeader(fruits[len(fruits)-1].FastHash(), fruits[len(fruits)-1].FastNumber().Uint64())
@@ -46,6 +46,8 @@ import (
 var (
 	blockInsertTimer = metrics.NewRegisteredTimer("snailchain/inserts", nil)
 	blockWriteTimer  = metrics.NewRegisteredTimer("snailchain/write", nil)
+
+	finalizedReorgMeter = metrics.NewRegisteredMeter("snailchain/reorg/finalized", nil)
 	//ErrNoGenesis is returned if the Genesis not found in chain.
 	ErrNoGenesis = errors.New("Genesis not found in chain")
 )
@@ -82,19 +84,23 @@ type SnailBlockChain struct {
 	chainHeadFeed event.Feed
 	fastBlockFeed event.Feed
 	fruitFeed     event.Feed // for worker mined fruit
+	finalizedFeed event.Feed // for reorgs beyond the finality barrier
 	scope         event.SubscriptionScope
 	genesisBlock  *types.SnailBlock
 
-	chainmu sync.RWMutex // blockchain insertion lock
-	procmu  sync.RWMutex // block processor lock
+	chainmu  sync.RWMutex // blockchain insertion lock
+	nextmu   sync.Mutex   // next access to the insertion lock, held briefly by all importers
+	importmu sync.Mutex   // serializes remote imports so locally mined blocks overtake them
+	procmu   sync.RWMutex // block processor lock
 
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
-	bodyCache    *lru.Cache // Cache for the most recent block bodies
-	bodyRLPCache *lru.Cache // Cache for the most recent block bodies in RLP encoded format
-	blockCache   *lru.Cache // Cache for the most recent entire blocks
-	futureBlocks *lru.Cache // future blocks are blocks added for later processing
+	bodyCache    *lru.Cache        // Cache for the most recent block bodies
+	bodyRLPCache *lru.Cache        // Cache for the most recent block bodies in RLP encoded format
+	blockCache   *lru.Cache        // Cache for the most recent entire blocks
+	futureBlocks *core.FutureQueue // future blocks are blocks waiting for their timestamp to become valid
+	side         *core.SideTracker // blocks stored outside the canonical chain, pruned once out of reach
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -108,6 +114,9 @@ type SnailBlockChain struct {
 	blockchain *core.BlockChain
 
 	badBlocks *lru.Cache // Bad block cache
+
+	finality         uint64 // Depth below which the canonical chain is immutable (0 = disabled)
+	finalityOverride int32  // Allows reorgs beyond the finality barrier (atomic)
 }
 
 // NewSnailBlockChain returns a fully initialised block chain using information
@@ -118,7 +127,7 @@ func NewSnailBlockChain(db abeydb.Database, chainConfig *params.ChainConfig, eng
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
-	futureBlocks, _ := lru.New(maxFutureBlocks)
+	futureBlocks := core.NewFutureQueue(maxFutureBlocks, consensus.AllowedFutureBlockTime, "snailchain")
 	badBlocks, _ := lru.New(badBlockLimit)
 
 	bc := &SnailBlockChain{
@@ -133,6 +142,7 @@ func NewSnailBlockChain(db abeydb.Database, chainConfig *params.ChainConfig, eng
 		badBlocks:    badBlocks,
 		blockchain:   blockchain,
 	}
+	bc.side = core.NewSideTracker(db, sideStore{bc}, "snailchain")
 	bc.SetValidator(NewBlockValidator(chainConfig, blockchain, bc, engine))
 
 	var err error
@@ -190,7 +200,7 @@ func (bc *SnailBlockChain) loadLastState() error {
 		return bc.Reset()
 	}
 	remove := make(types.Fruits, 0, len(currentBlock.Fruits()))
-	maxFruitNumber := currentBlock.Fruits()[len(currentBlock.Fruits())-1].FastNumber()
+	maxFruitNumber := currentBlock.MaxFruitNumber()
 	log.Info("begin snail loadLastState", "maxFruitNumber", maxFruitNumber, "Number", bc.blockchain.CurrentHeader().Number)
 	for maxFruitNumber != nil && maxFruitNumber.Cmp(bc.blockchain.CurrentHeader().Number) > 0 {
 		log.Debug("rollback snailBlock", "snailBlock number", currentBlock.Number(), "maxFruitNumber", maxFruitNumber, "current fastblock number", bc.blockchain.CurrentBlock().Number())
@@ -200,7 +210,7 @@ func (bc *SnailBlockChain) loadLastState() error {
 		}
 		rawdb.DeleteCanonicalHash(bc.db, currentBlock.NumberU64())
 		currentBlock = bc.GetBlockByHash(parentHash)
-		maxFruitNumber = currentBlock.Fruits()[len(currentBlock.Fruits())-1].FastNumber()
+		maxFruitNumber = currentBlock.MaxFruitNumber()
 	}
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadHeaderHash(bc.db, currentBlock.Header().Hash())
@@ -265,8 +275,16 @@ func (bc *SnailBlockChain) SetHead(head uint64) error {
 		}*/
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
+
+	if bc.beyondFinality(head) {
+		defer func() { go bc.finalizedFeed.Send(types.FinalizedReorgEvent{Number: head + 1}) }()
+	}
 	//retroversion fastchain
-	fastNumber := bc.GetBlockByNumber(head).Fruits()[len(bc.GetBlockByNumber(head).Fruits())-1].FastNumber()
+	// The genesis snail block carries no fruits, rewinding to it resets the fastchain
+	fastNumber := bc.GetBlockByNumber(head).MaxFruitNumber()
+	if fastNumber == nil {
+		fastNumber = new(big.Int)
+	}
 	if err := bc.blockchain.SetHead(fastNumber.Uint64()); err != nil {
 		return err
 	}
@@ -341,6 +359,51 @@ func (bc *SnailBlockChain) SetValidator(validator core.SnailValidator) {
 	bc.validator = validator
 }
 
+// SetFinality sets the number of snail blocks below the head beyond which the
+// chain refuses to reorganise. Zero disables the barrier.
+func (bc *SnailBlockChain) SetFinality(depth uint64) {
+	atomic.StoreUint64(&bc.finality, depth)
+}
+
+// SetFinalityOverride allows or forbids reorgs beyond the finality barrier.
+func (bc *SnailBlockChain) SetFinalityOverride(allow bool) {
+	if allow {
+		atomic.StoreInt32(&bc.finalityOverride, 1)
+	} else {
+		atomic.StoreInt32(&bc.finalityOverride, 0)
+	}
+}
+
+// beyondFinality reports whether dropping the canonical blocks above the given
+// fork point would cross the finality barrier.
+func (bc *SnailBlockChain) beyondFinality(forkNumber uint64) bool {
+	finality := atomic.LoadUint64(&bc.finality)
+	if finality == 0 {
+		return false
+	}
+	head := bc.CurrentBlock().NumberU64()
+	return head > forkNumber && head-forkNumber > finality
+}
+
+// checkFinality returns ErrFinalizedReorg if dropping the canonical blocks
+// above the given fork point would cross the finality barrier.
+func (bc *SnailBlockChain) checkFinality(forkNumber uint64, hash common.Hash) error {
+	if !bc.beyondFinality(forkNumber) {
+		return nil
+	}
+	var (
+		finality = atomic.LoadUint64(&bc.finality)
+		head     = bc.CurrentBlock().NumberU64()
+	)
+	finalizedReorgMeter.Mark(1)
+	if atomic.LoadInt32(&bc.finalityOverride) == 1 {
+		log.Warn("Allowing snail reorg beyond finality barrier", "head", head, "fork", forkNumber, "depth", head-forkNumber, "finality", finality, "hash", hash)
+		return nil
+	}
+	log.Error("Refusing snail reorg beyond finality barrier", "head", head, "fork", forkNumber, "depth", head-forkNumber, "finality", finality, "hash", hash)
+	return ErrFinalizedReorg
+}
+
 // Validator returns the current validator.
 func (bc *SnailBlockChain) Validator() core.SnailValidator {
 	bc.procmu.RLock()
@@ -443,6 +506,14 @@ func (bc *SnailBlockChain) Genesis() *types.SnailBlock {
 	return bc.genesisBlock
 }
 
+// PurgeCaches drops the recently used blocks and bodies from memory, releasing
+// it under memory pressure. The content is reloaded from disk on demand.
+func (bc *SnailBlockChain) PurgeCaches() {
+	bc.bodyCache.Purge()
+	bc.bodyRLPCache.Purge()
+	bc.blockCache.Purge()
+}
+
 // GetBody retrieves a block body (fruits and signs) from the database by
 // hash, caching it if found.
 func (bc *SnailBlockChain) GetBody(hash common.Hash) *types.SnailBody {
@@ -618,21 +689,13 @@ func (bc *SnailBlockChain) Stop() {
 	log.Info("Blockchain manager stopped")
 }
 
+// procFutureBlocks imports the queued future blocks whose timestamp became valid.
 func (bc *SnailBlockChain) procFutureBlocks() {
-	blocks := make([]*types.SnailBlock, 0, bc.futureBlocks.Len())
-	for _, hash := range bc.futureBlocks.Keys() {
-		if block, exist := bc.futureBlocks.Peek(hash); exist {
-			blocks = append(blocks, block.(*types.SnailBlock))
-		}
-	}
-	if len(blocks) > 0 {
-		types.SnailBlockBy(types.SnailNumber).Sort(blocks)
-
-		// Insert one by one as chain insertion needs contiguous ancestry between blocks
-		for i := range blocks {
-			log.Info("procFutureBlocks", "number", blocks[i].Number(), "hash", blocks[i].Hash())
-			bc.InsertChain(blocks[i : i+1])
-		}
+	// Insert one by one as chain insertion needs contiguous ancestry between blocks
+	for _, block := range bc.futureBlocks.Ready(time.Now()) {
+		block := block.(*types.SnailBlock)
+		log.Debug("Importing future block", "number", block.Number(), "hash", block.Hash())
+		bc.InsertChain(types.SnailBlocks{block})
 	}
 }
 
@@ -688,18 +751,45 @@ func (bc *SnailBlockChain) WriteBlock(block *types.SnailBlock, td *big.Int) (err
 		return err
 	}
 	rawdb.WriteBlock(bc.db, block)
+	bc.markSideBlocks(types.SnailBlocks{block})
 
 	return nil
 }
 
-// WriteMinedCanonicalBlock writes the minedblock to the database.
+// WriteMinedCanonicalBlock writes the minedblock to the database. It takes
+// priority over the remote imports waiting for the insertion lock.
 func (bc *SnailBlockChain) WriteMinedCanonicalBlock(block *types.SnailBlock) (status WriteStatus, err error) {
-	bc.chainmu.Lock()
-	bc.chainmu.Unlock()
+	bc.lockMined()
+	defer bc.chainmu.Unlock()
+
 	log.Debug("WriteMinedCanonicalBlock", "number", block.Number(), "hash", block.Hash())
 	return bc.writeCanonicalBlock(block)
 }
 
+// lockMined acquires the insertion lock for a locally mined block, ahead of the
+// queued remote imports.
+func (bc *SnailBlockChain) lockMined() {
+	bc.nextmu.Lock()
+	bc.chainmu.Lock()
+	bc.nextmu.Unlock()
+}
+
+// lockImport acquires the insertion lock for a remote import. Remote imports
+// line up behind each other before competing for the next access, so a mined
+// block only waits for the running import.
+func (bc *SnailBlockChain) lockImport() {
+	bc.importmu.Lock()
+	bc.nextmu.Lock()
+	bc.chainmu.Lock()
+	bc.nextmu.Unlock()
+}
+
+// unlockImport releases the insertion lock of a remote import.
+func (bc *SnailBlockChain) unlockImport() {
+	bc.chainmu.Unlock()
+	bc.importmu.Unlock()
+}
+
 func (bc *SnailBlockChain) writeCanonicalBlock(block *types.SnailBlock) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
@@ -745,6 +835,7 @@ func (bc *SnailBlockChain) writeCanonicalBlock(block *types.SnailBlock) (status
 		status = CanonStatTy
 	} else {
 		status = SideStatTy
+		bc.markSideBlocks(types.SnailBlocks{block})
 	}
 
 	//if err := batch.Write(); err != nil {
@@ -761,13 +852,16 @@ func (bc *SnailBlockChain) writeCanonicalBlock(block *types.SnailBlock) (status
 
 // addFutureBlock checks if the block is within the max allowed window to get
 // accepted for future processing, and returns an error if the block is too far
-// ahead and was not added.
+// ahead and was not added. Blocks not fitting into the full queue are skipped,
+// they will be fetched again once the chain catches up.
 func (bc *SnailBlockChain) addFutureBlock(block *types.SnailBlock) error {
 	max := big.NewInt(time.Now().Unix() + maxTimeFutureBlocks)
 	if block.Time().Cmp(max) > 0 {
 		return fmt.Errorf("future block timestamp %v > allowed %v", block.Time(), max)
 	}
-	bc.futureBlocks.Add(block.Hash(), block)
+	if err := bc.futureBlocks.Add(block); err != nil {
+		log.Debug("Skipping future block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	}
 	return nil
 }
 
@@ -793,18 +887,33 @@ func (bc *SnailBlockChain) InsertChain(chain types.SnailBlocks) (int, error) {
 				chain[i-1].Hash().Bytes()[:4], i, chain[i].NumberU64(), chain[i].Hash().Bytes()[:4], chain[i].ParentHash().Bytes()[:4])
 		}
 	}
+	// Reject side chains forking off below the finality barrier before importing them
+	if err := bc.checkChainFinality(chain); err != nil {
+		return 0, err
+	}
 	// Pre-checks passed, start the full block imports
 	bc.wg.Add(1)
-	bc.chainmu.Lock()
+	bc.lockImport()
 	log.Debug("InsertChain...", "start", chain[0].NumberU64(), "end", chain[len(chain)-1].NumberU64())
 	n, events, err := bc.insertChain(chain, true, true)
-	bc.chainmu.Unlock()
+	bc.unlockImport()
 	bc.wg.Done()
 
 	bc.PostChainEvents(events)
 	return n, err
 }
 
+// checkChainFinality locates the first block of the batch that is not part of
+// the canonical chain and checks that its parent is above the finality barrier.
+func (bc *SnailBlockChain) checkChainFinality(chain types.SnailBlocks) error {
+	for _, block := range chain {
+		if block.NumberU64() > 0 && rawdb.ReadCanonicalHash(bc.db, block.NumberU64()) != block.Hash() {
+			return bc.checkFinality(block.NumberU64()-1, block.Hash())
+		}
+	}
+	return nil
+}
+
 // FastInsertChain attempts to insert the given batch of blocks in to the canonical
 // chain or, otherwise, create a fork. If an error is returned it will return
 // the index number of the failing block as well an error describing what went
@@ -828,12 +937,15 @@ func (bc *SnailBlockChain) FastInsertChain(chain types.SnailBlocks) (int, error)
 				chain[i-1].Hash().Bytes()[:4], i, chain[i].NumberU64(), chain[i].Hash().Bytes()[:4], chain[i].ParentHash().Bytes()[:4])
 		}
 	}
+	if err := bc.checkChainFinality(chain); err != nil {
+		return 0, err
+	}
 	// Pre-checks passed, start the full block imports
 	bc.wg.Add(1)
-	bc.chainmu.Lock()
+	bc.lockImport()
 	log.Debug("InsertChain...", "start", chain[0].NumberU64(), "end", chain[len(chain)-1].NumberU64())
 	n, events, err := bc.insertChain(chain, true, false)
-	bc.chainmu.Unlock()
+	bc.unlockImport()
 	bc.wg.Done()
 
 	bc.PostChainEvents(events)
@@ -1158,6 +1270,14 @@ func (bc *SnailBlockChain) reorg(oldBlock, newBlock *types.SnailBlock) error {
 			return fmt.Errorf("Invalid new chain")
 		}
 	}
+	// Never drop blocks beyond the finality barrier unless the operator allowed it
+	var finalized bool
+	if len(newChain) > 0 {
+		if err := bc.checkFinality(commonBlock.NumberU64(), newChain[0].Hash()); err != nil {
+			return err
+		}
+		finalized = bc.beyondFinality(commonBlock.NumberU64())
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1189,6 +1309,7 @@ func (bc *SnailBlockChain) reorg(oldBlock, newBlock *types.SnailBlock) error {
 	}
 
 	batch.Write()
+	bc.markSideBlocks(oldChain)
 
 	if len(oldChain) > 0 {
 		go func() {
@@ -1206,6 +1327,9 @@ func (bc *SnailBlockChain) reorg(oldBlock, newBlock *types.SnailBlock) error {
 			}
 		}()
 	}
+	if finalized {
+		go bc.finalizedFeed.Send(types.FinalizedReorgEvent{Number: commonBlock.NumberU64() + 1})
+	}
 
 	return nil
 }
@@ -1253,12 +1377,19 @@ func (bc *SnailBlockChain) PostChainEvents(events []interface{}) {
 }
 
 func (bc *SnailBlockChain) update() {
-	futureTimer := time.NewTicker(5 * time.Second)
+	futureTimer := time.NewTimer(0)
 	defer futureTimer.Stop()
+	pruneTicker := time.NewTicker(core.SideBlockPruneInterval)
+	defer pruneTicker.Stop()
 	for {
 		select {
+		case <-pruneTicker.C:
+			bc.pruneSideBlocks()
+		case <-bc.futureBlocks.Wake():
+			bc.futureBlocks.Schedule(futureTimer)
 		case <-futureTimer.C:
 			bc.procFutureBlocks()
+			bc.futureBlocks.Schedule(futureTimer)
 		case <-bc.quit:
 			return
 		}
@@ -1447,6 +1578,11 @@ func (bc *SnailBlockChain) SubscribeFastBlockEvent(ch chan<- types.NewFastBlocks
 	return bc.scope.Track(bc.fastBlockFeed.Subscribe(ch))
 }
 
+// SubscribeFinalizedReorgEvent registers a subscription of types.FinalizedReorgEvent.
+func (bc *SnailBlockChain) SubscribeFinalizedReorgEvent(ch chan<- types.FinalizedReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.finalizedFeed.Subscribe(ch))
+}
+
 // SubscribeNewFruitEvent registers a subscription of fruits.
 func (bc *SnailBlockChain) SubscribeNewFruitEvent(ch chan<- types.NewMinedFruitEvent) event.Subscription {
 	return bc.scope.Track(bc.fruitFeed.Subscribe(ch))
@@ -54,7 +54,7 @@ func (st *insertSnailStats) report(chain []*types.SnailBlock, index int) {
 		)
 		context := []interface{}{
 			"blocks", st.processed, "fts", fts, "elapsed", common.PrettyDuration(elapsed),
-			"number", end.Number(), "hash", end.Hash(), "fruit", end.Fruits()[0].FastNumber(),
+			"number", end.Number(), "hash", end.Hash(), "fruit", end.MinFruitNumber(),
 		}
 		if st.queued > 0 {
 			context = append(context, []interface{}{"queued", st.queued}...)
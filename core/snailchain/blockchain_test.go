@@ -33,6 +33,7 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -1361,3 +1362,58 @@ func testRewardOrg(t *testing.T, n int) {
 }
 
 func TestReorgRward(t *testing.T) { testRewardOrg(t, 1) }
+
+// Tests that reorgs beyond the finality barrier are refused unless overridden.
+func TestCheckFinality(t *testing.T) {
+	bc := &SnailBlockChain{}
+	bc.currentBlock.Store(types.NewSnailBlockWithHeader(&types.SnailHeader{Number: big.NewInt(200)}))
+
+	if err := bc.checkFinality(10, common.Hash{}); err != nil {
+		t.Fatalf("disabled barrier refused reorg: %v", err)
+	}
+	bc.SetFinality(96)
+	if err := bc.checkFinality(104, common.Hash{}); err != nil {
+		t.Errorf("reorg at the barrier refused: %v", err)
+	}
+	if err := bc.checkFinality(103, common.Hash{}); err != ErrFinalizedReorg {
+		t.Errorf("reorg beyond the barrier error mismatch: have %v, want %v", err, ErrFinalizedReorg)
+	}
+	bc.SetFinalityOverride(true)
+	if err := bc.checkFinality(103, common.Hash{}); err != nil {
+		t.Errorf("overridden reorg refused: %v", err)
+	}
+}
+
+// Tests that locally mined blocks overtake the remote imports waiting for the
+// insertion lock.
+func TestMinedBlockPriority(t *testing.T) {
+	var (
+		bc    = new(SnailBlockChain)
+		order = make(chan string, 3)
+	)
+	bc.lockImport()
+
+	acquire := func(name string, lock func(), unlock func()) {
+		go func() {
+			lock()
+			order <- name
+			unlock()
+		}()
+		time.Sleep(50 * time.Millisecond) // Let the goroutine line up
+	}
+	acquire("import", bc.lockImport, bc.unlockImport)
+	acquire("import", bc.lockImport, bc.unlockImport)
+	acquire("mined", bc.lockMined, bc.chainmu.Unlock)
+	bc.unlockImport()
+
+	for i, want := range []string{"mined", "import", "import"} {
+		select {
+		case have := <-order:
+			if have != want {
+				t.Errorf("lock %d: have %s, want %s", i, have, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("lock %d: timeout waiting for %s", i, want)
+		}
+	}
+}
@@ -72,6 +72,17 @@ func (b *BlockGen) SetExtra(data []byte) {
 	b.header.Extra = data
 }
 
+// SetPointer sets the pointer of the generated block to the given header.
+func (b *BlockGen) SetPointer(pointer *types.SnailHeader) {
+	b.header.PointerHash = pointer.Hash()
+	b.header.PointerNumber = new(big.Int).Set(pointer.Number)
+}
+
+// SetFruitDifficulty sets the fruit difficulty field of the generated block.
+func (b *BlockGen) SetFruitDifficulty(diff *big.Int) {
+	b.header.FruitDifficulty = new(big.Int).Set(diff)
+}
+
 // Number returns the block number of the block being generated.
 func (b *BlockGen) Number() *big.Int {
 	return new(big.Int).Set(b.header.Number)
@@ -27,4 +27,8 @@ var (
 
 	//ErrRewardedBlock is returned if a block to import is already rewarded.
 	ErrRewardedBlock = errors.New("block already rewarded")
+
+	// ErrFinalizedReorg is returned if a block to import would reorganise the
+	// chain below the finality barrier.
+	ErrFinalizedReorg = errors.New("reorg beyond finality barrier")
 )
@@ -92,6 +92,32 @@ func WriteHeadBlockHash(db DatabaseWriter, hash common.Hash) {
 	}
 }
 
+// ReadSideBlocks retrieves the hashes of the stored blocks outside the canonical
+// chain, as tracked for pruning.
+func ReadSideBlocks(db DatabaseReader) []common.Hash {
+	data, _ := db.Get(sideBlocksKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := rlp.DecodeBytes(data, &hashes); err != nil {
+		log.Error("Invalid side snail block list RLP", "err", err)
+		return nil
+	}
+	return hashes
+}
+
+// WriteSideBlocks stores the hashes of the stored blocks outside the canonical chain.
+func WriteSideBlocks(db DatabaseWriter, hashes []common.Hash) {
+	data, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		log.Crit("Failed to RLP encode side snail block list", "err", err)
+	}
+	if err := db.Put(sideBlocksKey, data); err != nil {
+		log.Crit("Failed to store side snail block list", "err", err)
+	}
+}
+
 // ReadHeadFastBlockHash retrieves the hash of the current fast-sync head block.
 func ReadHeadFastBlockHash(db DatabaseReader) common.Hash {
 	data, _ := db.Get(headFastBlockKey)
@@ -361,6 +387,64 @@ func WriteCommitteeStates(db DatabaseWriter, committee uint64, changes []*big.In
 	}
 }
 
+// ReadCommitteeIndex returns the member sets of a finished committee ordered by
+// the fast block they become effective at.
+func ReadCommitteeIndex(db DatabaseReader, committee uint64) []*CommitteeIndexEntry {
+	data, _ := db.Get(committeeIndexKey(committee))
+	if len(data) == 0 {
+		return nil
+	}
+	var index []*CommitteeIndexEntry
+	if err := rlp.Decode(bytes.NewReader(data), &index); err != nil {
+		log.Error("Invalid committee index RLP", "committee", committee, "err", err)
+		return nil
+	}
+	return index
+}
+
+// WriteCommitteeIndex stores the member sets of a finished committee.
+func WriteCommitteeIndex(db DatabaseWriter, committee uint64, index []*CommitteeIndexEntry) {
+	data, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		log.Crit("Failed to RLP encode committee index", "err", err)
+	}
+	if err := db.Put(committeeIndexKey(committee), data); err != nil {
+		log.Crit("Failed to store committee index", "err", err)
+	}
+}
+
+// DeleteCommitteeIndex removes the member sets of a committee.
+func DeleteCommitteeIndex(db DatabaseDeleter, committee uint64) {
+	if err := db.Delete(committeeIndexKey(committee)); err != nil {
+		log.Crit("Failed to delete committee index", "err", err)
+	}
+}
+
+// ReadCommitteeHistory returns the recorded lifecycle events of a committee.
+func ReadCommitteeHistory(db DatabaseReader, committee uint64) []*CommitteeHistoryEntry {
+	data, _ := db.Get(committeeHistoryKey(committee))
+	if len(data) == 0 {
+		return nil
+	}
+	var history []*CommitteeHistoryEntry
+	if err := rlp.Decode(bytes.NewReader(data), &history); err != nil {
+		log.Error("Invalid committee history RLP", "committee", committee, "err", err)
+		return nil
+	}
+	return history
+}
+
+// WriteCommitteeHistory stores the lifecycle events of a committee.
+func WriteCommitteeHistory(db DatabaseWriter, committee uint64, history []*CommitteeHistoryEntry) {
+	data, err := rlp.EncodeToBytes(history)
+	if err != nil {
+		log.Crit("Failed to RLP encode committee history", "err", err)
+	}
+	if err := db.Put(committeeHistoryKey(committee), data); err != nil {
+		log.Crit("Failed to store committee history", "err", err)
+	}
+}
+
 // ReadFHsRLP retrieves the fruits head in RLP encoding.
 func ReadFHsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(fruitHeadsKey(number, hash))
@@ -256,6 +256,25 @@ func TestHeadStorage(t *testing.T) {
 	}
 }
 
+// Tests that the list of side-chain blocks tracked for pruning is stored and
+// retrieved correctly.
+func TestSideBlocksStorage(t *testing.T) {
+	db := abeydb.NewMemDatabase()
+
+	if entry := ReadSideBlocks(db); len(entry) != 0 {
+		t.Fatalf("Non existent side blocks returned: %v", entry)
+	}
+	hashes := []common.Hash{{0x01}, {0x02}}
+	WriteSideBlocks(db, hashes)
+	if entry := ReadSideBlocks(db); len(entry) != 2 || entry[0] != hashes[0] || entry[1] != hashes[1] {
+		t.Fatalf("Side blocks mismatch: have %v, want %v", entry, hashes)
+	}
+	WriteSideBlocks(db, nil)
+	if entry := ReadSideBlocks(db); len(entry) != 0 {
+		t.Fatalf("Cleared side blocks returned: %v", entry)
+	}
+}
+
 func TestCommitteeStates(t *testing.T) {
 	db := abeydb.NewMemDatabase()
 
@@ -292,3 +311,35 @@ func TestCommitteeStates(t *testing.T) {
 		}
 	}
 }
+
+func TestCommitteeIndex(t *testing.T) {
+	db := abeydb.NewMemDatabase()
+
+	if index := ReadCommitteeIndex(db, 1); index != nil {
+		t.Fatalf("Non existent committee index returned: %v", index)
+	}
+	index := []*CommitteeIndexEntry{
+		{Begin: 100, Members: []*types.CommitteeMember{{Coinbase: common.Address{1}, Publickey: []byte{1}}, {Coinbase: common.Address{2}, Publickey: []byte{2}}}},
+		{Begin: 150, Members: []*types.CommitteeMember{{Coinbase: common.Address{1}, Publickey: []byte{1}}}},
+	}
+	WriteCommitteeIndex(db, 1, index)
+
+	stored := ReadCommitteeIndex(db, 1)
+	if len(stored) != len(index) {
+		t.Fatalf("Read committee index invalid: %v", stored)
+	}
+	for i := range stored {
+		if stored[i].Begin != index[i].Begin || len(stored[i].Members) != len(index[i].Members) {
+			t.Fatalf("Read committee index error at %v", i)
+		}
+		for j, m := range stored[i].Members {
+			if m.Coinbase != index[i].Members[j].Coinbase {
+				t.Fatalf("Read committee index member error at %v/%v", i, j)
+			}
+		}
+	}
+	DeleteCommitteeIndex(db, 1)
+	if index := ReadCommitteeIndex(db, 1); index != nil {
+		t.Fatalf("Deleted committee index returned: %v", index)
+	}
+}
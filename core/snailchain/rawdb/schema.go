@@ -20,7 +20,9 @@ package rawdb
 import (
 	"encoding/binary"
 
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
 )
 
 // The fields below define the low level database schema prefixing.
@@ -34,6 +36,9 @@ var (
 	// headBlockKey tracks the latest know full block's hash.
 	headBlockKey = []byte("LastSnailBlock")
 
+	// sideBlocksKey tracks the hashes of the stored blocks outside the canonical chain.
+	sideBlocksKey = []byte("SnailSideBlocks")
+
 	// headFastBlockKey tracks the latest known incomplete block's hash duirng fast sync.
 	headFastBlockKey = []byte("LastSnailFast")
 
@@ -48,6 +53,8 @@ var (
 
 	committeePrefix      = []byte("c") // committeePrefix + num (uint64 big endian) -> committee
 	committeeStateSuffix = []byte("s") // committeePrefix + num (uint64 big endian) + committeeStateSuffix -> committeeStates
+	committeeIndexSuffix = []byte("i") // committeePrefix + num (uint64 big endian) + committeeIndexSuffix -> committee index
+	committeeHistSuffix  = []byte("h") // committeePrefix + num (uint64 big endian) + committeeHistSuffix -> committee history
 
 	blockBodyPrefix     = []byte("sb")  // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	fruitHeadsPrefix    = []byte("sbf") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
@@ -63,6 +70,23 @@ var (
 	headHashEpochSuffix = []byte("she") // headHashPrefix + num (uint64 big endian) + headHashEpochSuffix -> headHashEpoch
 )
 
+// CommitteeIndexEntry is the materialized member set of a committee, valid
+// from the Begin fast block until the next entry begins.
+type CommitteeIndexEntry struct {
+	Begin   uint64
+	Members []*types.CommitteeMember
+}
+
+// CommitteeHistoryEntry is a lifecycle event of a committee, with the change of
+// the member set it brought compared to the previously recorded one.
+type CommitteeHistoryEntry struct {
+	Option  uint64           // Committee event, types.CommitteeStart and siblings
+	Number  uint64           // Fast block the event takes effect at
+	Members []common.Address // Committee bases of the members, empty if the event carries none
+	Added   []common.Address // Members not in the previously recorded set
+	Removed []common.Address // Members of the previously recorded set left out
+}
+
 // FtLookupEntry is a positional metadata to help looking up the data content of
 // a fruit.
 type FtLookupEntry struct {
@@ -143,6 +167,16 @@ func committeeStateKey(number uint64) []byte {
 	return append(committeeKey(number), committeeStateSuffix...)
 }
 
+// committeeIndexKey = num (uint64 big endian) + committeePrefix + suffix
+func committeeIndexKey(number uint64) []byte {
+	return append(committeeKey(number), committeeIndexSuffix...)
+}
+
+// committeeHistoryKey = num (uint64 big endian) + committeePrefix + suffix
+func committeeHistoryKey(number uint64) []byte {
+	return append(committeeKey(number), committeeHistSuffix...)
+}
+
 // headHashKey = num (uint64 big endian) + committeePrefix
 func headHashKey(number uint64) []byte {
 	return append(headHashPrefix, encodeBlockNumber(number)...)
@@ -152,3 +186,34 @@ func headHashKey(number uint64) []byte {
 func headHashEpochKey(number uint64) []byte {
 	return append(headHashKey(number), headHashEpochSuffix...)
 }
+
+// singleKey returns the schema of a single key.
+func singleKey(name string, k []byte) abeydb.KeySchema {
+	return abeydb.KeySchema{Name: name, Owner: "core/snailchain/rawdb", Prefix: k, Length: len(k), Layout: string(k)}
+}
+
+// Schema lists the keys of the snail chain and committee data.
+var Schema = []abeydb.KeySchema{
+	singleKey("database version", databaseVerisionKey),
+	singleKey("snail head header", headHeaderKey),
+	singleKey("snail head block", headBlockKey),
+	singleKey("snail side blocks", sideBlocksKey),
+	singleKey("snail head fast block", headFastBlockKey),
+	singleKey("snail fast trie progress", fastTrieProgressKey),
+	{Name: "snail header", Owner: "core/snailchain/rawdb", Prefix: headerPrefix, Length: 42, Layout: "sh + num (uint64 big endian) + hash"},
+	{Name: "snail total difficulty", Owner: "core/snailchain/rawdb", Prefix: headerPrefix, Suffix: headerTDSuffix, Length: 44, Layout: "sh + num (uint64 big endian) + hash + st"},
+	{Name: "snail canonical hash", Owner: "core/snailchain/rawdb", Prefix: headerPrefix, Suffix: headerHashSuffix, Length: 12, Layout: "sh + num (uint64 big endian) + sn"},
+	{Name: "snail header number", Owner: "core/snailchain/rawdb", Prefix: headerNumberPrefix, Length: 34, Layout: "sH + hash"},
+	{Name: "committee", Owner: "core/snailchain/rawdb", Prefix: committeePrefix, Length: 9, Layout: "c + committee id (uint64 big endian)"},
+	{Name: "committee states", Owner: "core/snailchain/rawdb", Prefix: committeePrefix, Suffix: committeeStateSuffix, Length: 10, Layout: "c + committee id (uint64 big endian) + s"},
+	{Name: "committee index", Owner: "core/snailchain/rawdb", Prefix: committeePrefix, Suffix: committeeIndexSuffix, Length: 10, Layout: "c + committee id (uint64 big endian) + i"},
+	{Name: "committee history", Owner: "core/snailchain/rawdb", Prefix: committeePrefix, Suffix: committeeHistSuffix, Length: 10, Layout: "c + committee id (uint64 big endian) + h"},
+	{Name: "snail block body", Owner: "core/snailchain/rawdb", Prefix: blockBodyPrefix, Length: 42, Layout: "sb + num (uint64 big endian) + hash"},
+	{Name: "fruit heads", Owner: "core/snailchain/rawdb", Prefix: fruitHeadsPrefix, Length: 43, Layout: "sbf + num (uint64 big endian) + hash"},
+	{Name: "snail block receipts", Owner: "core/snailchain/rawdb", Prefix: blockReceiptsPrefix, Length: 42, Layout: "sr + num (uint64 big endian) + hash"},
+	{Name: "fruit lookup", Owner: "core/snailchain/rawdb", Prefix: ftLookupPrefix, Length: 34, Layout: "sl + hash"},
+	{Name: "snail bloom bits", Owner: "core/snailchain/rawdb", Prefix: bloomBitsPrefix, Length: 44, Layout: "sB + bit (uint16 big endian) + section (uint64 big endian) + hash"},
+	{Name: "snail chain config", Owner: "core/snailchain/rawdb", Prefix: configPrefix, Length: len(configPrefix) + 32, Layout: "snailchain-abeychain-config- + genesis hash"},
+	{Name: "head hash", Owner: "core/snailchain/rawdb", Prefix: headHashPrefix, Length: 11, Layout: "shh + num (uint64 big endian)"},
+	{Name: "head hash epoch", Owner: "core/snailchain/rawdb", Prefix: headHashPrefix, Suffix: headHashEpochSuffix, Length: 14, Layout: "shh + num (uint64 big endian) + she"},
+}
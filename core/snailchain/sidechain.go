@@ -0,0 +1,102 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package snailchain
+
+import (
+	"sync/atomic"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// sideBlockRetention is the number of blocks below the head to keep side-chain
+// blocks for if no finality barrier is set. With a barrier, side-chain blocks
+// are kept until they fall beyond it, as they can't become canonical anymore.
+const sideBlockRetention = 256
+
+// sideRetention returns the number of blocks below the head to keep side-chain
+// blocks for.
+func (bc *SnailBlockChain) sideRetention() uint64 {
+	if finality := atomic.LoadUint64(&bc.finality); finality != 0 {
+		return finality
+	}
+	return sideBlockRetention
+}
+
+// sideStore is the storage of the snail chain side-chain blocks.
+type sideStore struct {
+	bc *SnailBlockChain
+}
+
+func (s sideStore) ReadSideBlocks() []common.Hash {
+	return rawdb.ReadSideBlocks(s.bc.db)
+}
+
+func (s sideStore) WriteSideBlocks(db abeydb.Putter, hashes []common.Hash) {
+	rawdb.WriteSideBlocks(db, hashes)
+}
+
+func (s sideStore) SideBlock(hash common.Hash) (uint64, common.Hash, bool) {
+	number := rawdb.ReadHeaderNumber(s.bc.db, hash)
+	if number == nil {
+		return 0, common.Hash{}, false
+	}
+	header := s.bc.GetHeader(hash, *number)
+	if header == nil {
+		return 0, common.Hash{}, false
+	}
+	return *number, header.ParentHash, true
+}
+
+func (s sideStore) CanonicalHash(number uint64) common.Hash {
+	return rawdb.ReadCanonicalHash(s.bc.db, number)
+}
+
+func (s sideStore) DeleteSideBlock(db abeydb.Deleter, hash common.Hash, number uint64) {
+	rawdb.DeleteBlock(db, hash, number)
+	rawdb.DeleteFruitsHead(db, hash, number)
+	s.bc.blockCache.Remove(hash)
+	s.bc.bodyCache.Remove(hash)
+	s.bc.bodyRLPCache.Remove(hash)
+}
+
+// markSideBlocks records blocks stored outside the canonical chain, so they can
+// be pruned once they fall out of the retention window.
+func (bc *SnailBlockChain) markSideBlocks(blocks types.SnailBlocks) {
+	hashes := make([]common.Hash, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash()
+	}
+	bc.side.Mark(hashes...)
+}
+
+// pruneSideBlocks deletes the side-chain blocks that fell out of the retention
+// window, and stops tracking the ones that became canonical meanwhile.
+func (bc *SnailBlockChain) pruneSideBlocks() {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.side.Prune(bc.CurrentBlock().NumberU64(), bc.sideRetention())
+}
+
+// SideHeads returns the tips of the side chains currently stored, highest first.
+func (bc *SnailBlockChain) SideHeads() []core.SideHead {
+	return bc.side.Heads()
+}
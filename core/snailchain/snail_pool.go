@@ -272,9 +272,9 @@ func (pool *SnailPool) addFruit(fruit *types.SnailBlock) (error, bool) {
 
 	headSnailBlock := pool.chain.CurrentBlock()
 	if headSnailBlock.NumberU64() > 0 {
-		fruits := headSnailBlock.Fruits()
-		if fruits != nil && fruits[len(fruits)-1].FastNumber().Cmp(fruit.FastNumber()) >= 0 {
-			log.Debug("addFruit failed", "fruit's fastnumber", fruit.FastNumber(), "current snailblock's max fastnumber", fruits[len(fruits)-1].FastNumber())
+		maxFruitNumber := headSnailBlock.MaxFruitNumber()
+		if maxFruitNumber != nil && maxFruitNumber.Cmp(fruit.FastNumber()) >= 0 {
+			log.Debug("addFruit failed", "fruit's fastnumber", fruit.FastNumber(), "current snailblock's max fastnumber", maxFruitNumber)
 			return consensus.ErrTooOldBlock, false
 		}
 	}
@@ -706,9 +706,9 @@ func (pool *SnailPool) validateFruit(fruit *types.SnailBlock) error {
 	//check hight
 	headSnailBlock := pool.chain.CurrentBlock()
 	if headSnailBlock.NumberU64() > 0 {
-		fruits := headSnailBlock.Fruits()
-		if fruits != nil && fruits[len(fruits)-1].FastNumber().Cmp(fruit.FastNumber()) >= 0 {
-			log.Debug("validateFruit", "fruit's fastnumber", fruit.FastNumber(), "current snailblock's max fastnumber", fruits[len(fruits)-1].FastNumber())
+		maxFruitNumber := headSnailBlock.MaxFruitNumber()
+		if maxFruitNumber != nil && maxFruitNumber.Cmp(fruit.FastNumber()) >= 0 {
+			log.Debug("validateFruit", "fruit's fastnumber", fruit.FastNumber(), "current snailblock's max fastnumber", maxFruitNumber)
 			return consensus.ErrTooOldBlock
 		}
 	}
@@ -97,6 +97,19 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 )
 
+// Reasons reported to the subscribers of DroppedTxsEvent.
+const (
+	DropUnderpriced  = "underpriced"  // Outbid by better priced transactions in a full pool
+	DropReplaced     = "replaced"     // Replaced by a transaction with the same nonce
+	DropTimedOut     = "timedout"     // Queued for longer than the pool lifetime
+	DropInvalidNonce = "invalidnonce" // Nonce already used by the sending account
+	DropUnpayable    = "unpayable"    // Balance or block gas limit no longer cover it
+	DropRateLimited  = "ratelimited"  // Above the account or global pool slot limits
+	DropNonceGap     = "noncegap"     // Waited past the transaction lifetime for a missing nonce
+	DropLowPrice     = "lowprice"     // Priced below the pool minimum past the transaction lifetime
+	DropExpired      = "expired"      // Not mined within the transaction lifetime
+)
+
 var (
 	evictionInterval      = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval   = 8 * time.Second // Time interval to report transaction pool stats
@@ -161,7 +174,8 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+	Lifetime   time.Duration // Maximum amount of time non-executable transaction are queued
+	TxLifetime time.Duration // Maximum amount of time a non-local transaction stays in the pool, zero for unlimited
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -178,7 +192,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 64 * 5,
 	GlobalQueue:  1024 * 5,
 
-	Lifetime: 3 * time.Hour,
+	Lifetime:   3 * time.Hour,
+	TxLifetime: 0, // Executable transactions are only swept if asked for
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -217,6 +232,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.TxLifetime < 0 {
+		log.Warn("Sanitizing invalid txpool transaction lifetime", "provided", conf.TxLifetime, "updated", DefaultTxPoolConfig.TxLifetime)
+		conf.TxLifetime = DefaultTxPoolConfig.TxLifetime
+	}
 	return conf
 }
 
@@ -233,6 +252,7 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan types.FastChainHeadEvent
 	chainHeadSub event.Subscription
@@ -380,11 +400,14 @@ func (pool *TxPool) loop() {
 				}
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
-					for _, tx := range pool.queue[addr].Flatten() {
+					txs := pool.queue[addr].Flatten()
+					for _, tx := range txs {
 						pool.removeTx(tx.Hash(), true)
 					}
+					pool.dropped(DropTimedOut, txs...)
 				}
 			}
+			pool.sweep(time.Now())
 			pool.mu.Unlock()
 
 			// Handle local transaction journal rotation
@@ -516,6 +539,19 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- types.NewTxsEvent) event.Subs
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDroppedTxsEvent registers a subscription of DroppedTxsEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeDroppedTxsEvent(ch chan<- types.DroppedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// dropped notifies any subsystems of transactions evicted from the pool.
+func (pool *TxPool) dropped(reason string, txs ...*types.Transaction) {
+	if len(txs) > 0 {
+		go pool.dropFeed.Send(types.DroppedTxsEvent{Txs: txs, Reason: reason})
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -531,9 +567,11 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	drops := pool.priced.Cap(price, pool.locals)
+	for _, tx := range drops {
 		pool.removeTx(tx.Hash(), false)
 	}
+	pool.dropped(DropUnderpriced, drops...)
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
@@ -740,6 +778,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			underpricedTxCounter.Inc(1)
 			pool.removeTx(tx.Hash(), false)
 		}
+		pool.dropped(DropUnderpriced, drop...)
 		proctime = time.Since(start)
 		log.Trace("deal with drop", "proctime", proctime, "drop.Len()", drop.Len())
 	}
@@ -757,6 +796,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.dropped(DropReplaced, old)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
@@ -804,6 +844,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.dropped(DropReplaced, old)
 	}
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
@@ -842,6 +883,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.dropped(DropReplaced, tx)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -850,6 +892,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.dropped(DropReplaced, old)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
@@ -1056,12 +1099,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			continue // Just in case someone calls with a non existing account
 		}
 		// Drop all transactions that are deemed too old (low nonce)
-		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
+		olds := list.Forward(pool.currentState.GetNonce(addr))
+		for _, tx := range olds {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 		}
+		pool.dropped(DropInvalidNonce, olds...)
+
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetValidBalance(addr), pool.currentMaxGas, pool.signer, pool.currentState)
 		for _, tx := range drops {
@@ -1071,6 +1117,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
 		}
+		pool.dropped(DropUnpayable, drops...)
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
 			hash := tx.Hash()
@@ -1081,13 +1128,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 		// Drop all transactions over the allowed limit
 		if !pool.locals.contains(addr) {
-			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
+			caps := list.Cap(int(pool.config.AccountQueue))
+			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.dropped(DropRateLimited, caps...)
 		}
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
@@ -1130,7 +1179,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
 					for i := 0; i < len(offenders)-1; i++ {
 						list := pool.pending[offenders[i]]
-						for _, tx := range list.Cap(list.Len() - 1) {
+						caps := list.Cap(list.Len() - 1)
+						for _, tx := range caps {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
 							pool.all.Remove(hash)
@@ -1142,6 +1192,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							}
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 						}
+						pool.dropped(DropRateLimited, caps...)
 						pending--
 					}
 				}
@@ -1152,7 +1203,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
 				for _, addr := range offenders {
 					list := pool.pending[addr]
-					for _, tx := range list.Cap(list.Len() - 1) {
+					caps := list.Cap(list.Len() - 1)
+					for _, tx := range caps {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
@@ -1164,6 +1216,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						}
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
+					pool.dropped(DropRateLimited, caps...)
 					pending--
 				}
 			}
@@ -1194,9 +1247,11 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
-				for _, tx := range list.Flatten() {
+				txs := list.Flatten()
+				for _, tx := range txs {
 					pool.removeTx(tx.Hash(), true)
 				}
+				pool.dropped(DropRateLimited, txs...)
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
 				continue
@@ -1205,6 +1260,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 				pool.removeTx(txs[i].Hash(), true)
+				pool.dropped(DropRateLimited, txs[i])
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
@@ -1236,6 +1292,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
 		}
+		pool.dropped(DropUnpayable, drops...)
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -1315,14 +1372,16 @@ func (as *accountSet) add(addr common.Address) {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all   map[common.Hash]*types.Transaction
+	times map[common.Hash]time.Time // Arrival times of the transactions
+	lock  sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:   make(map[common.Hash]*types.Transaction),
+		times: make(map[common.Hash]time.Time),
 	}
 }
 
@@ -1359,7 +1418,20 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	t.all[hash] = tx
+	if _, ok := t.times[hash]; !ok {
+		t.times[hash] = time.Now()
+	}
+}
+
+// Arrived returns the time a transaction entered the lookup, or the zero time
+// if not found.
+func (t *txLookup) Arrived(hash common.Hash) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.times[hash]
 }
 
 // Remove removes a transaction from the lookup.
@@ -1368,4 +1440,5 @@ func (t *txLookup) Remove(hash common.Hash) {
 	defer t.lock.Unlock()
 
 	delete(t.all, hash)
+	delete(t.times, hash)
 }
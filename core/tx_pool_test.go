@@ -412,6 +412,127 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	}
 }
 
+// Tests that evicted transactions are reported with the reason of the eviction.
+func TestTransactionDroppedEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(addr, new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10)))
+
+	drops := make(chan types.DroppedTxsEvent, 8)
+	sub := pool.SubscribeDroppedTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	expect := func(tx *types.Transaction, reason string) {
+		t.Helper()
+		select {
+		case ev := <-drops:
+			if len(ev.Txs) != 1 || ev.Txs[0].Hash() != tx.Hash() || ev.Reason != reason {
+				t.Fatalf("drop mismatch: have %d txs (%s), want %x (%s)", len(ev.Txs), ev.Reason, tx.Hash(), reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop of %x (%s) not reported", tx.Hash(), reason)
+		}
+	}
+	// Replace an executable transaction with a better priced one
+	signer := types.NewTIP1Signer(params.TestChainConfig.ChainID)
+	tx1, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(10*params.GWei), nil), signer, key)
+	tx2, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(20*params.GWei), nil), signer, key)
+	if err := pool.AddRemote(tx1); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.AddRemote(tx2); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	expect(tx1, DropReplaced)
+
+	// Raise the price threshold above the remaining transaction
+	pool.SetGasPrice(big.NewInt(30 * params.GWei))
+	expect(tx2, DropUnderpriced)
+}
+
+// Tests that transactions staying in the pool past the transaction lifetime are
+// swept with the reason they got stuck for, and the queued ones listed oldest
+// first until then.
+func TestTransactionSweeping(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPool()
+	defer pool.Stop()
+
+	drops := make(chan types.DroppedTxsEvent, 8)
+	sub := pool.SubscribeDroppedTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	signer := types.NewTIP1Signer(params.TestChainConfig.ChainID)
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10)))
+	}
+	sign := func(nonce uint64, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 100000, big.NewInt(price*params.GWei), nil), signer, key)
+		return tx
+	}
+	var (
+		executable = sign(0, 20, keys[0])
+		gapped     = sign(3, 20, keys[0])
+		cheap      = sign(0, 10, keys[1])
+		fresh      = sign(0, 10, keys[2])
+	)
+	for _, tx := range []*types.Transaction{executable, gapped, cheap, fresh} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if oldest := pool.OldestQueued(1); len(oldest) != 1 || len(oldest[crypto.PubkeyToAddress(keys[0].PublicKey)]) != 1 {
+		t.Fatalf("oldest queued mismatch: have %v, want the gapped transaction", oldest)
+	} else if age := oldest[crypto.PubkeyToAddress(keys[0].PublicKey)][0]; age.Tx.Hash() != gapped.Hash() || age.Stuck != DropNonceGap {
+		t.Fatalf("oldest queued mismatch: have %x (%s), want %x (%s)", age.Tx.Hash(), age.Stuck, gapped.Hash(), DropNonceGap)
+	}
+	// Age all but the fresh transaction past the lifetime and raise the price floor
+	pool.mu.Lock()
+	for _, tx := range []*types.Transaction{executable, gapped, cheap} {
+		pool.all.times[tx.Hash()] = time.Now().Add(-48 * time.Hour)
+	}
+	pool.gasPrice = big.NewInt(15 * params.GWei)
+
+	// Nothing is swept unless a transaction lifetime is configured
+	pool.sweep(time.Now())
+	if pending, queued := pool.stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool size mismatch with sweeping disabled: have %d pending %d queued, want 3 pending 1 queued", pending, queued)
+	}
+	pool.config.TxLifetime = 24 * time.Hour
+	pool.sweep(time.Now())
+	pool.mu.Unlock()
+
+	want := map[string]common.Hash{
+		DropExpired:  executable.Hash(),
+		DropNonceGap: gapped.Hash(),
+		DropLowPrice: cheap.Hash(),
+	}
+	for len(want) > 0 {
+		select {
+		case ev := <-drops:
+			if len(ev.Txs) != 1 || ev.Txs[0].Hash() != want[ev.Reason] {
+				t.Fatalf("sweep mismatch: have %d txs (%s), want %x", len(ev.Txs), ev.Reason, want[ev.Reason])
+			}
+			delete(want, ev.Reason)
+		case <-time.After(time.Second):
+			t.Fatalf("sweeps not reported: %v", want)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool size mismatch: have %d pending %d queued, want 1 pending", pending, queued)
+	}
+	if pool.all.Get(fresh.Hash()) == nil {
+		t.Fatalf("fresh transaction swept")
+	}
+}
+
 func TestTransactionMissingNonce(t *testing.T) {
 	t.Parallel()
 
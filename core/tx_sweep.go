@@ -0,0 +1,109 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+var sweptTxCounter = metrics.NewRegisteredCounter("txpool/swept", nil) // Dropped past the transaction lifetime
+
+// TxAge is a pooled transaction with the time it arrived in the pool.
+type TxAge struct {
+	Tx      *types.Transaction
+	Arrived time.Time
+	Stuck   string // Reason the transaction would be swept for once expired
+}
+
+// stuckReason classifies why a transaction of addr hasn't been mined yet, as
+// reported when it is swept. The caller must hold the pool lock.
+func (pool *TxPool) stuckReason(addr common.Address, tx *types.Transaction, queued bool) string {
+	if queued && tx.Nonce() > pool.pendingState.GetNonce(addr) {
+		return DropNonceGap
+	}
+	if tx.GasPrice().Cmp(pool.gasPrice) < 0 {
+		return DropLowPrice
+	}
+	return DropExpired
+}
+
+// sweep drops the non-local transactions, executable or not, that have been in
+// the pool for longer than the transaction lifetime. The caller must hold the
+// pool lock.
+func (pool *TxPool) sweep(now time.Time) {
+	if pool.config.TxLifetime == 0 {
+		return
+	}
+	expired := make(map[string][]*types.Transaction)
+	collect := func(lists map[common.Address]*txList, queued bool) {
+		for addr, list := range lists {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			for _, tx := range list.Flatten() {
+				if now.Sub(pool.all.Arrived(tx.Hash())) <= pool.config.TxLifetime {
+					continue
+				}
+				reason := pool.stuckReason(addr, tx, queued)
+				expired[reason] = append(expired[reason], tx)
+			}
+		}
+	}
+	collect(pool.pending, false)
+	collect(pool.queue, true)
+
+	for reason, txs := range expired {
+		for _, tx := range txs {
+			pool.removeTx(tx.Hash(), true)
+		}
+		sweptTxCounter.Inc(int64(len(txs)))
+		pool.dropped(reason, txs...)
+		log.Debug("Swept stuck transactions", "reason", reason, "count", len(txs), "lifetime", pool.config.TxLifetime)
+	}
+}
+
+// OldestQueued retrieves up to n of the longest queued transactions of every
+// account with non-executable transactions, oldest first.
+func (pool *TxPool) OldestQueued(n int) map[common.Address][]*TxAge {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	oldest := make(map[common.Address][]*TxAge)
+	for addr, list := range pool.queue {
+		txs := list.Flatten()
+		ages := make([]*TxAge, 0, len(txs))
+		for _, tx := range txs {
+			ages = append(ages, &TxAge{
+				Tx:      tx,
+				Arrived: pool.all.Arrived(tx.Hash()),
+				Stuck:   pool.stuckReason(addr, tx, true),
+			})
+		}
+		sort.SliceStable(ages, func(i, j int) bool { return ages[i].Arrived.Before(ages[j].Arrived) })
+		if n > 0 && len(ages) > n {
+			ages = ages[:n]
+		}
+		oldest[addr] = ages
+	}
+	return oldest
+}
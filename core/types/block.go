@@ -45,6 +45,10 @@ var (
 	maxUint128 = new(big.Int).Exp(big.NewInt(2), big.NewInt(128), big.NewInt(0))
 )
 
+// maxSnailSigns is the sanity limit on the number of pbft signatures a
+// decoded snail block or fruit may carry.
+const maxSnailSigns = 1024
+
 // A BlockNonce is a 64-bit hash which proves (combined with the
 // mix-hash) that a sufficient amount of computation has been carried
 // out on a block.
@@ -546,7 +550,7 @@ func (b *Block) Hash() common.Hash {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-//go:generate gencodec -type SnailHeader -field-override headerMarshaling -out gen_header_json.go
+//go:generate gencodec -type SnailHeader -field-override snailHeaderMarshaling -out gen_snailheader_json.go
 
 // SnailHeader represents a block header in the abeychain.
 type SnailHeader struct {
@@ -568,6 +572,19 @@ type SnailHeader struct {
 	Nonce           BlockNonce     `json:"nonce"            gencodec:"required"`
 }
 
+// field type overrides for gencodec
+type snailHeaderMarshaling struct {
+	PointerNumber   *hexutil.Big
+	FastNumber      *hexutil.Big
+	Difficulty      *hexutil.Big
+	FruitDifficulty *hexutil.Big
+	Number          *hexutil.Big
+	Publickey       hexutil.Bytes
+	Time            *hexutil.Big
+	Extra           hexutil.Bytes
+	Hash            common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
+}
+
 type SnailBody struct {
 	Fruits []*SnailBlock
 	Signs  []*PbftSign
@@ -804,14 +821,65 @@ func CopyPbftSign(s *PbftSign) *PbftSign {
 	return &cpy
 }
 
+// decsnailblock is the decoding counterpart of extsnailblock. Fruits are
+// decoded through decfruit so that a fruit can never carry fruits of its
+// own, which would otherwise allow unbounded recursion from remote input.
+type decsnailblock struct {
+	Header *SnailHeader
+	Fruits []*decfruit
+	Signs  []*PbftSign
+	Td     *big.Int
+}
+
+// decfruit decodes a single fruit of a snail block.
+type decfruit struct {
+	block *SnailBlock
+}
+
+// DecodeRLP decodes a fruit, rejecting any nested fruits.
+func (f *decfruit) DecodeRLP(s *rlp.Stream) error {
+	var ef struct {
+		Header *SnailHeader
+		Fruits []rlp.RawValue
+		Signs  []*PbftSign
+		Td     *big.Int
+	}
+	_, size, _ := s.Kind()
+	if err := s.Decode(&ef); err != nil {
+		return err
+	}
+	if len(ef.Fruits) > 0 {
+		return ErrNestedFruits
+	}
+	if len(ef.Signs) > maxSnailSigns {
+		return ErrTooManySigns
+	}
+	f.block = &SnailBlock{header: ef.Header, td: ef.Td, signs: ef.Signs}
+	f.block.size.Store(common.StorageSize(rlp.ListSize(size)))
+	return nil
+}
+
 // DecodeRLP decodes the SnailBlock
 func (b *SnailBlock) DecodeRLP(s *rlp.Stream) error {
-	var eb extsnailblock
+	var eb decsnailblock
 	_, size, _ := s.Kind()
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.td, b.fruits, b.signs = eb.Header, eb.Td, eb.Fruits, eb.Signs
+	if len(eb.Fruits) > params.MaximumFruits {
+		return ErrTooManyFruits
+	}
+	if len(eb.Signs) > maxSnailSigns {
+		return ErrTooManySigns
+	}
+	var fruits []*SnailBlock
+	if eb.Fruits != nil {
+		fruits = make([]*SnailBlock, len(eb.Fruits))
+		for i, f := range eb.Fruits {
+			fruits[i] = f.block
+		}
+	}
+	b.header, b.td, b.fruits, b.signs = eb.Header, eb.Td, fruits, eb.Signs
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
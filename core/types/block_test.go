@@ -0,0 +1,83 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/abeychain/go-abey/common"
+)
+
+// Tests that snail headers survive a JSON round trip and that every field is
+// required when decoding.
+func TestSnailHeaderJSON(t *testing.T) {
+	header := &SnailHeader{
+		ParentHash:      common.Hash{1},
+		Coinbase:        common.Address{2},
+		PointerHash:     common.Hash{3},
+		PointerNumber:   big.NewInt(4),
+		FruitsHash:      common.Hash{5},
+		FastHash:        common.Hash{6},
+		FastNumber:      big.NewInt(7),
+		SignHash:        common.Hash{8},
+		Difficulty:      big.NewInt(9),
+		FruitDifficulty: big.NewInt(10),
+		Number:          big.NewInt(11),
+		Publickey:       []byte{0x04, 0x0c},
+		Time:            big.NewInt(13),
+		Extra:           []byte{0x0e},
+		MixDigest:       common.Hash{15},
+		Nonce:           EncodeNonce(16),
+	}
+	enc, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	dec := new(SnailHeader)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if !reflect.DeepEqual(dec, header) {
+		t.Fatalf("header mismatch:\nhave %+v\nwant %+v", dec, header)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &fields); err != nil {
+		t.Fatalf("failed to decode fields: %v", err)
+	}
+	var hash common.Hash
+	if err := json.Unmarshal(fields["hash"], &hash); err != nil || hash != header.Hash() {
+		t.Errorf("hash field mismatch: have %x, want %x", hash, header.Hash())
+	}
+	for name := range fields {
+		if name == "hash" {
+			continue
+		}
+		partial := make(map[string]json.RawMessage)
+		for key, value := range fields {
+			if key != name {
+				partial[key] = value
+			}
+		}
+		enc, _ := json.Marshal(partial)
+		if err := json.Unmarshal(enc, new(SnailHeader)); err == nil {
+			t.Errorf("header without %q accepted", name)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/params"
+)
+
+// CoinbaseProofSize is the size of the coinbase proof appended to the
+// extra-data of a fruit or snail block: a signature with recovery id.
+const CoinbaseProofSize = crypto.SignatureLength
+
+var (
+	// ErrNoCoinbaseProof is returned if the extra-data carries no coinbase proof.
+	ErrNoCoinbaseProof = errors.New("no coinbase proof")
+
+	// ErrInvalidCoinbaseProof is returned if the coinbase proof was not signed
+	// by the coinbase of the header.
+	ErrInvalidCoinbaseProof = errors.New("invalid coinbase proof")
+)
+
+// SplitCoinbaseProof separates the extra-data of a snail header into the miner
+// chosen part and the coinbase proof following it. Extra-data not exceeding the
+// plain size limit carries no proof.
+//
+// After the TIP13 fork a fruit or snail block may prove that its miner controls
+// the coinbase key by appending a signature over CoinbaseProofHash to its
+// extra-data, which keeps pools sharing infrastructure from claiming each
+// other's fruits.
+func SplitCoinbaseProof(extra []byte) (vanity []byte, proof []byte, err error) {
+	if uint64(len(extra)) <= params.MaximumExtraDataSize {
+		return extra, nil, ErrNoCoinbaseProof
+	}
+	if len(extra) < CoinbaseProofSize || uint64(len(extra)-CoinbaseProofSize) > params.MaximumExtraDataSize {
+		return nil, nil, ErrInvalidCoinbaseProof
+	}
+	split := len(extra) - CoinbaseProofSize
+	return extra[:split], extra[split:], nil
+}
+
+// CoinbaseProofHash returns the hash signed by a coinbase proof: the proof of
+// work hash of the header without the proof itself.
+func CoinbaseProofHash(header *SnailHeader) common.Hash {
+	cpy := *header
+	if vanity, _, err := SplitCoinbaseProof(header.Extra); err == nil {
+		cpy.Extra = vanity
+	}
+	return cpy.HashNoNonce()
+}
+
+// AppendCoinbaseProof returns the extra-data of the header with the given
+// coinbase proof appended.
+func AppendCoinbaseProof(header *SnailHeader, proof []byte) ([]byte, error) {
+	if len(proof) != CoinbaseProofSize || uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+		return nil, ErrInvalidCoinbaseProof
+	}
+	extra := make([]byte, 0, len(header.Extra)+CoinbaseProofSize)
+	extra = append(extra, header.Extra...)
+	return append(extra, proof...), nil
+}
+
+// CoinbaseProofSigner recovers the address that signed the coinbase proof of
+// the header.
+func CoinbaseProofSigner(header *SnailHeader) (common.Address, error) {
+	_, proof, err := SplitCoinbaseProof(header.Extra)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pubkey, err := crypto.SigToPub(CoinbaseProofHash(header).Bytes(), proof)
+	if err != nil {
+		return common.Address{}, ErrInvalidCoinbaseProof
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// VerifyCoinbaseProof checks that the coinbase proof of the header was signed
+// by its coinbase.
+func VerifyCoinbaseProof(header *SnailHeader) error {
+	signer, err := CoinbaseProofSigner(header)
+	if err != nil {
+		return err
+	}
+	if signer != header.Coinbase {
+		return ErrInvalidCoinbaseProof
+	}
+	return nil
+}
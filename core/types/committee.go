@@ -62,6 +62,7 @@ const (
 	SDownloaderFetchCall
 	SDownloaderLoopCall
 	SDownloaderPartCall
+	SFetcherSealCall
 )
 
 //CommitteeMembers committee members
@@ -81,6 +82,26 @@ type ElectionCommittee struct {
 	Backups []*CommitteeMember
 }
 
+// SwitchBlock is a fast block carrying committee switch infos, reduced to the
+// parts needed to verify them: the header commits to the infos through its
+// CommitteeHash and the signs attest the header.
+type SwitchBlock struct {
+	Header *Header            `json:"header"`
+	Infos  []*CommitteeMember `json:"infos"`
+	Signs  []*PbftSign        `json:"signs"`
+}
+
+// CommitteeProof contains the switch blocks and snail election headers needed
+// to derive the committee of a fast block from a trusted checkpoint, ordered
+// from the checkpoint towards the target block.
+type CommitteeProof struct {
+	Number     *big.Int           `json:"number"`
+	Checkpoint *big.Int           `json:"checkpoint"`
+	Members    []*CommitteeMember `json:"members"`
+	Switches   []*SwitchBlock     `json:"switches"`
+	Elections  []*SnailHeader     `json:"elections"`
+}
+
 func NewCommitteeMember(coinBase common.Address, publicKey []byte, flag, mType uint32) *CommitteeMember {
 	return &CommitteeMember{
 		Coinbase:      coinBase,
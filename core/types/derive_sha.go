@@ -19,6 +19,7 @@ package types
 import (
 	"bytes"
 
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/rlp"
 	"github.com/abeychain/go-abey/trie"
@@ -39,3 +40,20 @@ func DeriveSha(list DerivableList) common.Hash {
 	}
 	return trie.Hash()
 }
+
+// DeriveShaProof writes the Merkle proof of the list item at the given index
+// in the trie hashed by DeriveSha into proofDb.
+func DeriveShaProof(list DerivableList, index int, proofDb abeydb.Putter) error {
+	keybuf := new(bytes.Buffer)
+	trie := new(trie.Trie)
+	for i := 0; i < list.Len(); i++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(i))
+		trie.Update(keybuf.Bytes(), list.GetRlp(i))
+	}
+	key, err := rlp.EncodeToBytes(uint(index))
+	if err != nil {
+		return err
+	}
+	return trie.Prove(key, 0, proofDb)
+}
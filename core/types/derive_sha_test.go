@@ -0,0 +1,53 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+// Tests that the proofs of transactions verify against the transaction root of
+// their block, including indices encoded with more than one RLP byte.
+func TestDeriveShaProof(t *testing.T) {
+	txs := make([]*Transaction, 300)
+	for i := range txs {
+		txs[i] = NewTransaction(uint64(i), common.Address{byte(i)}, big.NewInt(int64(i)), 21000, big.NewInt(1), nil)
+	}
+	block := NewBlock(&Header{Number: big.NewInt(1)}, txs, nil, nil, nil)
+
+	for _, index := range []int{0, 1, 0x7f, 0x80, 0x81, 0xff, 0x100, len(txs) - 1} {
+		proof := abeydb.NewMemDatabase()
+		if err := DeriveShaProof(block.Transactions(), index, proof); err != nil {
+			t.Fatalf("index %d: failed to prove: %v", index, err)
+		}
+		key, _ := rlp.EncodeToBytes(uint(index))
+		value, _, err := trie.VerifyProof(block.TxHash(), key, proof)
+		if err != nil {
+			t.Fatalf("index %d: failed to verify proof: %v", index, err)
+		}
+		if want := block.Transactions().GetRlp(index); !bytes.Equal(value, want) {
+			t.Errorf("index %d: proven value mismatch: have %x, want %x", index, value, want)
+		}
+	}
+}
@@ -16,4 +16,13 @@ var (
 	ErrSnailBlockTooSlow = errors.New("Snail block too slow")
 
 	ErrPayersign = errors.New("signed_addr not equal tx.data.Payer")
+
+	// ErrNestedFruits is returned when a decoded fruit carries fruits itself.
+	ErrNestedFruits = errors.New("fruit contains nested fruits")
+
+	// ErrTooManyFruits is returned when a decoded snail block exceeds params.MaximumFruits.
+	ErrTooManyFruits = errors.New("snail block contains too many fruits")
+
+	// ErrTooManySigns is returned when a decoded snail block carries too many signs.
+	ErrTooManySigns = errors.New("snail block contains too many signs")
 )
@@ -24,6 +24,13 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*Transaction }
 
+// DroppedTxsEvent is posted when a batch of transactions is evicted from the
+// transaction pool for the same reason.
+type DroppedTxsEvent struct {
+	Txs    []*Transaction
+	Reason string
+}
+
 //NewFruitsEvent is posted when a fruit has been imported.
 type NewFruitsEvent struct{ Fruits []*SnailBlock }
 
@@ -68,6 +75,12 @@ type SnailChainSideEvent struct {
 
 type SnailChainHeadEvent struct{ Block *SnailBlock }
 
+// FinalizedReorgEvent is posted when canonical blocks considered final are
+// dropped, by rewinding the head or reorganising beyond the finality barrier.
+type FinalizedReorgEvent struct {
+	Number uint64 // Number of the first dropped block
+}
+
 // FruitEvent for fruit event,seems not used
 type FruitEvent struct {
 	Block *Block
@@ -92,5 +105,9 @@ type PbftSignEvent struct {
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *SnailBlock }
 
+// NewSealedBlockEvent is posted when a snail block has been sealed locally,
+// before it is written to the chain, so it can be propagated right away.
+type NewSealedBlockEvent struct{ Block *SnailBlock }
+
 // NodeInfoEvent is posted when nodeInfo send
 type NodeInfoEvent struct{ NodeInfo *EncryptNodeMessage }
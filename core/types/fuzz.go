@@ -0,0 +1,73 @@
+// +build gofuzz
+
+package types
+
+import (
+	"bytes"
+
+	"github.com/abeychain/go-abey/rlp"
+)
+
+// Fuzz decodes the input as one of the snail chain network types, selected
+// by the first byte, and checks that a successful decode re-encodes to the
+// same bytes. Use go-fuzz -func to target a single decoder.
+func Fuzz(input []byte) int {
+	if len(input) == 0 {
+		return -1
+	}
+	switch input[0] % 3 {
+	case 0:
+		return FuzzSnailBlock(input[1:])
+	case 1:
+		return FuzzFruit(input[1:])
+	default:
+		return FuzzPbftSign(input[1:])
+	}
+}
+
+// FuzzSnailBlock fuzzes the RLP decoding of snail blocks.
+func FuzzSnailBlock(input []byte) int {
+	block := new(SnailBlock)
+	if err := rlp.DecodeBytes(input, block); err != nil {
+		return 0
+	}
+	if len(block.Fruits()) > 0 {
+		for _, f := range block.Fruits() {
+			if len(f.Fruits()) > 0 {
+				panic("decoded fruit with nested fruits")
+			}
+		}
+	}
+	checkRoundtrip(input, block)
+	return 1
+}
+
+// FuzzFruit fuzzes the RLP decoding of fruits received on their own.
+func FuzzFruit(input []byte) int {
+	var fruits []*SnailBlock
+	if err := rlp.DecodeBytes(input, &fruits); err != nil {
+		return 0
+	}
+	checkRoundtrip(input, fruits)
+	return 1
+}
+
+// FuzzPbftSign fuzzes the RLP decoding of pbft signatures.
+func FuzzPbftSign(input []byte) int {
+	sign := new(PbftSign)
+	if err := rlp.DecodeBytes(input, sign); err != nil {
+		return 0
+	}
+	checkRoundtrip(input, sign)
+	return 1
+}
+
+func checkRoundtrip(input []byte, val interface{}) {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(enc, input) {
+		panic("re-encoding mismatch")
+	}
+}
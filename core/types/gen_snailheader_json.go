@@ -0,0 +1,147 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/common/hexutil"
+)
+
+var _ = (*snailHeaderMarshaling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (s SnailHeader) MarshalJSON() ([]byte, error) {
+	type SnailHeader struct {
+		ParentHash      common.Hash    `json:"parentHash"       gencodec:"required"`
+		Coinbase        common.Address `json:"miner"            gencodec:"required"`
+		PointerHash     common.Hash    `json:"pointerHash"      gencodec:"required"`
+		PointerNumber   *hexutil.Big   `json:"pointerNumber"    gencodec:"required"`
+		FruitsHash      common.Hash    `json:"fruitsHash"       gencodec:"required"`
+		FastHash        common.Hash    `json:"fastHash"         gencodec:"required"`
+		FastNumber      *hexutil.Big   `json:"fastNumber"       gencodec:"required"`
+		SignHash        common.Hash    `json:"signHash"         gencodec:"required"`
+		Difficulty      *hexutil.Big   `json:"difficulty"       gencodec:"required"`
+		FruitDifficulty *hexutil.Big   `json:"fruitDifficulty"  gencodec:"required"`
+		Number          *hexutil.Big   `json:"number"           gencodec:"required"`
+		Publickey       hexutil.Bytes  `json:"publicKey"        gencodec:"required"`
+		Time            *hexutil.Big   `json:"timestamp"        gencodec:"required"`
+		Extra           hexutil.Bytes  `json:"extraData"        gencodec:"required"`
+		MixDigest       common.Hash    `json:"mixHash"          gencodec:"required"`
+		Nonce           BlockNonce     `json:"nonce"            gencodec:"required"`
+		Hash            common.Hash    `json:"hash"`
+	}
+	var enc SnailHeader
+	enc.ParentHash = s.ParentHash
+	enc.Coinbase = s.Coinbase
+	enc.PointerHash = s.PointerHash
+	enc.PointerNumber = (*hexutil.Big)(s.PointerNumber)
+	enc.FruitsHash = s.FruitsHash
+	enc.FastHash = s.FastHash
+	enc.FastNumber = (*hexutil.Big)(s.FastNumber)
+	enc.SignHash = s.SignHash
+	enc.Difficulty = (*hexutil.Big)(s.Difficulty)
+	enc.FruitDifficulty = (*hexutil.Big)(s.FruitDifficulty)
+	enc.Number = (*hexutil.Big)(s.Number)
+	enc.Publickey = s.Publickey
+	enc.Time = (*hexutil.Big)(s.Time)
+	enc.Extra = s.Extra
+	enc.MixDigest = s.MixDigest
+	enc.Nonce = s.Nonce
+	enc.Hash = s.Hash()
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (s *SnailHeader) UnmarshalJSON(input []byte) error {
+	type SnailHeader struct {
+		ParentHash      *common.Hash    `json:"parentHash"       gencodec:"required"`
+		Coinbase        *common.Address `json:"miner"            gencodec:"required"`
+		PointerHash     *common.Hash    `json:"pointerHash"      gencodec:"required"`
+		PointerNumber   *hexutil.Big    `json:"pointerNumber"    gencodec:"required"`
+		FruitsHash      *common.Hash    `json:"fruitsHash"       gencodec:"required"`
+		FastHash        *common.Hash    `json:"fastHash"         gencodec:"required"`
+		FastNumber      *hexutil.Big    `json:"fastNumber"       gencodec:"required"`
+		SignHash        *common.Hash    `json:"signHash"         gencodec:"required"`
+		Difficulty      *hexutil.Big    `json:"difficulty"       gencodec:"required"`
+		FruitDifficulty *hexutil.Big    `json:"fruitDifficulty"  gencodec:"required"`
+		Number          *hexutil.Big    `json:"number"           gencodec:"required"`
+		Publickey       *hexutil.Bytes  `json:"publicKey"        gencodec:"required"`
+		Time            *hexutil.Big    `json:"timestamp"        gencodec:"required"`
+		Extra           *hexutil.Bytes  `json:"extraData"        gencodec:"required"`
+		MixDigest       *common.Hash    `json:"mixHash"          gencodec:"required"`
+		Nonce           *BlockNonce     `json:"nonce"            gencodec:"required"`
+	}
+	var dec SnailHeader
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.ParentHash == nil {
+		return errors.New("missing required field 'parentHash' for SnailHeader")
+	}
+	s.ParentHash = *dec.ParentHash
+	if dec.Coinbase == nil {
+		return errors.New("missing required field 'miner' for SnailHeader")
+	}
+	s.Coinbase = *dec.Coinbase
+	if dec.PointerHash == nil {
+		return errors.New("missing required field 'pointerHash' for SnailHeader")
+	}
+	s.PointerHash = *dec.PointerHash
+	if dec.PointerNumber == nil {
+		return errors.New("missing required field 'pointerNumber' for SnailHeader")
+	}
+	s.PointerNumber = (*big.Int)(dec.PointerNumber)
+	if dec.FruitsHash == nil {
+		return errors.New("missing required field 'fruitsHash' for SnailHeader")
+	}
+	s.FruitsHash = *dec.FruitsHash
+	if dec.FastHash == nil {
+		return errors.New("missing required field 'fastHash' for SnailHeader")
+	}
+	s.FastHash = *dec.FastHash
+	if dec.FastNumber == nil {
+		return errors.New("missing required field 'fastNumber' for SnailHeader")
+	}
+	s.FastNumber = (*big.Int)(dec.FastNumber)
+	if dec.SignHash == nil {
+		return errors.New("missing required field 'signHash' for SnailHeader")
+	}
+	s.SignHash = *dec.SignHash
+	if dec.Difficulty == nil {
+		return errors.New("missing required field 'difficulty' for SnailHeader")
+	}
+	s.Difficulty = (*big.Int)(dec.Difficulty)
+	if dec.FruitDifficulty == nil {
+		return errors.New("missing required field 'fruitDifficulty' for SnailHeader")
+	}
+	s.FruitDifficulty = (*big.Int)(dec.FruitDifficulty)
+	if dec.Number == nil {
+		return errors.New("missing required field 'number' for SnailHeader")
+	}
+	s.Number = (*big.Int)(dec.Number)
+	if dec.Publickey == nil {
+		return errors.New("missing required field 'publicKey' for SnailHeader")
+	}
+	s.Publickey = *dec.Publickey
+	if dec.Time == nil {
+		return errors.New("missing required field 'timestamp' for SnailHeader")
+	}
+	s.Time = (*big.Int)(dec.Time)
+	if dec.Extra == nil {
+		return errors.New("missing required field 'extraData' for SnailHeader")
+	}
+	s.Extra = *dec.Extra
+	if dec.MixDigest == nil {
+		return errors.New("missing required field 'mixHash' for SnailHeader")
+	}
+	s.MixDigest = *dec.MixDigest
+	if dec.Nonce == nil {
+		return errors.New("missing required field 'nonce' for SnailHeader")
+	}
+	s.Nonce = *dec.Nonce
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+)
+
+const (
+	// HeaderExtensionVersion is the leading byte of an encoded header extension.
+	HeaderExtensionVersion = 0x01
+
+	// HeaderExtensionSize is the exact size of an encoded header extension.
+	HeaderExtensionSize = 1 + common.HashLength + 8 + common.HashLength
+)
+
+var errInvalidHeaderExtension = errors.New("invalid header extension")
+
+// HeaderExtension is the commitment a fast header carries in its extra-data
+// after the TIP10 fork. It binds the block to the committee that proposed it
+// and to the snail chain, so committee transitions can be followed from the
+// headers alone.
+type HeaderExtension struct {
+	CommitteeHash common.Hash // Hash of the committee set proposing the block
+	SnailNumber   uint64      // Number of the snail head known to the proposer
+	SnailHash     common.Hash // Hash of the snail head known to the proposer
+}
+
+// Encode serializes the extension into its fixed size extra-data layout.
+func (e *HeaderExtension) Encode() []byte {
+	enc := make([]byte, HeaderExtensionSize)
+	enc[0] = HeaderExtensionVersion
+	copy(enc[1:], e.CommitteeHash[:])
+	binary.BigEndian.PutUint64(enc[1+common.HashLength:], e.SnailNumber)
+	copy(enc[1+common.HashLength+8:], e.SnailHash[:])
+	return enc
+}
+
+// DecodeHeaderExtension parses the extra-data of a fast header into a header
+// extension, failing if the layout or version does not match.
+func DecodeHeaderExtension(extra []byte) (*HeaderExtension, error) {
+	if len(extra) != HeaderExtensionSize || extra[0] != HeaderExtensionVersion {
+		return nil, errInvalidHeaderExtension
+	}
+	ext := &HeaderExtension{
+		SnailNumber: binary.BigEndian.Uint64(extra[1+common.HashLength:]),
+	}
+	copy(ext.CommitteeHash[:], extra[1:])
+	copy(ext.SnailHash[:], extra[1+common.HashLength+8:])
+	return ext, nil
+}
+
+// CommitteeSetHash returns the hash committed to by a header extension for the
+// given committee: the keccak256 of the member public keys in ascending order.
+func CommitteeSetHash(members []*CommitteeMember) common.Hash {
+	keys := make([][]byte, len(members))
+	for i, m := range members {
+		keys[i] = m.Publickey
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return crypto.Keccak256Hash(keys...)
+}
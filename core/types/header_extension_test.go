@@ -0,0 +1,72 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abeychain/go-abey/common"
+)
+
+func TestHeaderExtensionEncoding(t *testing.T) {
+	ext := &HeaderExtension{CommitteeHash: common.Hash{1}, SnailNumber: 1234, SnailHash: common.Hash{2}}
+	enc := ext.Encode()
+	if len(enc) != HeaderExtensionSize || enc[0] != HeaderExtensionVersion {
+		t.Fatalf("encoding layout mismatch: %x", enc)
+	}
+	dec, err := DecodeHeaderExtension(enc)
+	if err != nil {
+		t.Fatalf("failed to decode extension: %v", err)
+	}
+	if !reflect.DeepEqual(dec, ext) {
+		t.Fatalf("extension mismatch: have %+v, want %+v", dec, ext)
+	}
+	tests := map[string][]byte{
+		"empty":     nil,
+		"short":     enc[:HeaderExtensionSize-1],
+		"long":      append(append([]byte{}, enc...), 0x00),
+		"version":   append([]byte{HeaderExtensionVersion + 1}, enc[1:]...),
+		"plaintext": []byte("abey"),
+	}
+	for name, extra := range tests {
+		if _, err := DecodeHeaderExtension(extra); err != errInvalidHeaderExtension {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, errInvalidHeaderExtension)
+		}
+	}
+}
+
+func TestCommitteeSetHash(t *testing.T) {
+	a := &CommitteeMember{Publickey: []byte{0x04, 0x01}}
+	b := &CommitteeMember{Publickey: []byte{0x04, 0x02}}
+	c := &CommitteeMember{Publickey: []byte{0x04, 0x03}}
+
+	tests := []struct {
+		x, y  []*CommitteeMember
+		equal bool
+	}{
+		{[]*CommitteeMember{a, b, c}, []*CommitteeMember{c, a, b}, true}, // Order independent
+		{[]*CommitteeMember{a, b}, []*CommitteeMember{a, b, c}, false},   // Missing member
+		{[]*CommitteeMember{a, b}, []*CommitteeMember{a, c}, false},      // Other member
+		{nil, []*CommitteeMember{a}, false},
+	}
+	for i, tt := range tests {
+		if equal := CommitteeSetHash(tt.x) == CommitteeSetHash(tt.y); equal != tt.equal {
+			t.Errorf("test %d: hash equality mismatch: have %v, want %v", i, equal, tt.equal)
+		}
+	}
+}
@@ -381,6 +381,120 @@ func (e *EpochIDInfo) String() string {
 	return fmt.Sprintf("[id:%v,begin:%v,end:%v]", e.EpochID, e.BeginHeight, e.EndHeight)
 }
 
+// ValidatorReward is the committee reward of a validator and its delegators in an epoch.
+type ValidatorReward struct {
+	Address    common.Address
+	Reward     *big.Int // Reward of the validator and its delegators
+	Staking    *big.Int // Staking of the validator and its delegators, as of the last reward
+	Delegators uint64   // Delegators rewarded, as of the last reward
+}
+
+// EpochRewardSummary is the aggregate of the committee rewards paid out in an epoch.
+// It's final once the last block of the epoch was processed.
+type EpochRewardSummary struct {
+	EpochID     uint64
+	BeginHeight uint64
+	EndHeight   uint64
+	Height      uint64 // Last fast block accumulated
+	Duration    uint64 // Seconds between the end of the previous epoch and the end of this one
+	Final       bool
+	TotalReward *big.Int
+	Validators  []*ValidatorReward
+}
+
+// NewEpochRewardSummary creates an empty reward summary of an epoch.
+func NewEpochRewardSummary(epoch *EpochIDInfo) *EpochRewardSummary {
+	return &EpochRewardSummary{
+		EpochID:     epoch.EpochID,
+		BeginHeight: epoch.BeginHeight,
+		EndHeight:   epoch.EndHeight,
+		TotalReward: new(big.Int),
+	}
+}
+
+// Add accumulates the committee rewards of a chain reward.
+func (s *EpochRewardSummary) Add(reward *ChainReward) {
+	for _, sa := range reward.CommitteeBase {
+		if len(sa.Items) == 0 {
+			continue
+		}
+		var validator *ValidatorReward
+		for _, v := range s.Validators {
+			if v.Address == sa.getSaAddress() {
+				validator = v
+				break
+			}
+		}
+		if validator == nil {
+			validator = &ValidatorReward{Address: sa.getSaAddress(), Reward: new(big.Int)}
+			s.Validators = append(s.Validators, validator)
+		}
+		staking := new(big.Int)
+		for _, item := range sa.Items {
+			validator.Reward.Add(validator.Reward, item.Amount)
+			s.TotalReward.Add(s.TotalReward, item.Amount)
+			if item.Staking != nil {
+				staking.Add(staking, item.Staking)
+			}
+		}
+		validator.Staking = staking
+		validator.Delegators = uint64(len(sa.Items) - 1)
+	}
+}
+
+// TotalStaking returns the staking of all rewarded validators and delegators.
+func (s *EpochRewardSummary) TotalStaking() *big.Int {
+	all := new(big.Int)
+	for _, v := range s.Validators {
+		all.Add(all, v.Staking)
+	}
+	return all
+}
+
+// Delegators returns the number of delegators rewarded.
+func (s *EpochRewardSummary) Delegators() uint64 {
+	var all uint64
+	for _, v := range s.Validators {
+		all += v.Delegators
+	}
+	return all
+}
+
+// APR returns the effective annual percentage rate of the rewards to the
+// staking, extrapolated from the epoch duration. It's zero until final.
+func (s *EpochRewardSummary) APR() float64 {
+	staking := s.TotalStaking()
+	if !s.Final || s.Duration == 0 || staking.Sign() <= 0 {
+		return 0
+	}
+	rate, _ := new(big.Float).Quo(new(big.Float).SetInt(s.TotalReward), new(big.Float).SetInt(staking)).Float64()
+	return 100 * rate * float64(365*24*60*60) / float64(s.Duration)
+}
+
+// ToJSON returns the summary in its RPC representation.
+func (s *EpochRewardSummary) ToJSON() map[string]interface{} {
+	validators := make([]map[string]interface{}, 0, len(s.Validators))
+	for _, v := range s.Validators {
+		validators = append(validators, map[string]interface{}{
+			"address":    v.Address.StringToAbey(),
+			"reward":     (*hexutil.Big)(v.Reward),
+			"staking":    (*hexutil.Big)(v.Staking),
+			"delegators": v.Delegators,
+		})
+	}
+	return map[string]interface{}{
+		"epochID":      s.EpochID,
+		"beginHeight":  s.BeginHeight,
+		"endHeight":    s.EndHeight,
+		"final":        s.Final,
+		"totalReward":  (*hexutil.Big)(s.TotalReward),
+		"totalStaking": (*hexutil.Big)(s.TotalStaking()),
+		"delegators":   s.Delegators(),
+		"apr":          s.APR(),
+		"validators":   validators,
+	}
+}
+
 // the key is epochid if StakingValue as a locked asset,otherwise key is block height if StakingValue as a staking asset
 type StakingValue struct {
 	Value map[uint64]*big.Int
@@ -0,0 +1,49 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/crypto"
+)
+
+// rotationPausePrefix domain-separates rotation pause approvals from any other
+// message signed with committee keys.
+var rotationPausePrefix = []byte("\x19Abeychain Rotation Pause:\n")
+
+// RotationPause postpones the switch from a committee to the next one in an
+// emergency. The committee stays in charge of the fast blocks up to Until,
+// if a supermajority of its stake approves.
+type RotationPause struct {
+	CommitteeID *big.Int `json:"committeeId"` // Committee (epoch) staying in charge
+	Until       *big.Int `json:"until"`       // Last fast block the committee stays in charge of
+	Reason      string   `json:"reason"`
+}
+
+// SigHash returns the digest the committee members sign with their committee
+// keys to approve the pause on the chain with the given id.
+func (p *RotationPause) SigHash(chainID *big.Int) common.Hash {
+	hash := rlpHash([]interface{}{
+		p.CommitteeID,
+		p.Until,
+		p.Reason,
+		chainID,
+	})
+	return crypto.Keccak256Hash(rotationPausePrefix, hash.Bytes())
+}
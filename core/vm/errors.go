@@ -40,8 +40,19 @@ var (
 	ErrGasUintOverflow            = errors.New("gas uint64 overflow")
 	ErrInvalidRetsub              = errors.New("invalid retsub")
 	ErrReturnStackExceeded        = errors.New("return stack limit reached")
+	ErrStateReadLimit             = errors.New("state read limit reached")
 	ErrStakingInvalidInput        = errors.New("invalid input for staking")
 	ErrStakingInsufficientBalance = errors.New("insufficient balance for staking transfer")
+	ErrStakingExpiredApproval     = errors.New("expired delegation approval")
+	ErrStakingInvalidApproval     = errors.New("invalid delegation approval signature")
+	ErrStakingFeeCooldown         = errors.New("staking fee changed too recently")
+	ErrStakingMinCooldown         = errors.New("staking minimum delegation changed too recently")
+	ErrStakingBelowMinDelegation  = errors.New("delegation below the validator minimum")
+	ErrPauseUnsupported           = errors.New("rotation pause requires epoch committees")
+	ErrPauseNotCurrent            = errors.New("rotation pause of a committee not in charge")
+	ErrPauseRange                 = errors.New("rotation pause end out of range")
+	ErrPauseSignature             = errors.New("invalid rotation pause signature")
+	ErrPauseInsufficient          = errors.New("insufficient stake approving rotation pause")
 )
 
 // ErrStackUnderflow wraps an evm error when the items on the stack less
@@ -129,6 +129,8 @@ type EVM struct {
 	// abort is used to abort the EVM calling operations
 	// NOTE: must be set atomically
 	abort int32
+	// stateReads counts the state reading operations for the read budget
+	stateReads uint64
 	// callGasTemp holds the gas available for the current call. This is needed because the
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
@@ -166,6 +168,12 @@ func (evm *EVM) Cancelled() bool {
 	return atomic.LoadInt32(&evm.abort) == 1
 }
 
+// StateReadLimitReached returns true if the execution was aborted because it
+// exceeded the state read budget of the vm configuration.
+func (evm *EVM) StateReadLimitReached() bool {
+	return evm.vmConfig.StateReadLimit > 0 && evm.stateReads > evm.vmConfig.StateReadLimit
+}
+
 // Interpreter returns the current interpreter
 func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
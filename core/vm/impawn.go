@@ -596,6 +596,8 @@ type ImpawnImpl struct {
 	accounts   map[uint64]SAImpawns // key is epoch id,value is SA set
 	curEpochID uint64               // the new epochid of the current state
 	lastReward uint64               // the curnent reward height block
+
+	minDelegation func(validator common.Address, epochID uint64) *big.Int // minimum delegation sharing in the rewards, not persisted
 }
 
 func NewImpawnImpl() *ImpawnImpl {
@@ -606,6 +608,14 @@ func NewImpawnImpl() *ImpawnImpl {
 		accounts:   make(map[uint64]SAImpawns),
 	}
 }
+// SetRewardState makes the reward distribution honour the minimum delegations
+// the validators stored in db had at the start of the rewarded epoch: smaller
+// delegations don't share in the rewards, their part goes to the validator.
+func (i *ImpawnImpl) SetRewardState(db StateDB) {
+	i.minDelegation = func(validator common.Address, epochID uint64) *big.Int {
+		return ValidatorRewardMinDelegation(db, validator, epochID)
+	}
+}
 func CloneImpawnImpl(ori *ImpawnImpl) *ImpawnImpl {
 	if ori == nil {
 		return nil
@@ -779,11 +789,18 @@ func (i *ImpawnImpl) calcRewardInSa(target uint64, sa *StakingAccount, allReward
 	var items []*types.RewardInfo
 	fee := new(big.Int).Quo(new(big.Int).Mul(allReward, sa.Fee), types.Base)
 	all, left, left2 := new(big.Int).Sub(allReward, fee), big.NewInt(0), big.NewInt(0)
+	var min *big.Int
+	if i.minDelegation != nil {
+		min = i.minDelegation(sa.Unit.Address, types.GetEpochFromHeight(target).EpochID)
+	}
 	for _, v := range sa.Delegation {
 		daAll := v.getAllStaking(target)
 		if daAll.Sign() <= 0 {
 			continue
 		}
+		if min != nil && daAll.Cmp(min) < 0 {
+			continue
+		}
 		v1 := new(big.Int).Quo(new(big.Int).Mul(all, daAll), allStaking)
 		left = left.Add(left, v1)
 		left2 = left2.Add(left2, daAll)
@@ -1426,6 +1443,24 @@ func GetValidatorsByEpoch(state StateDB, eid, hh uint64) []*types.CommitteeMembe
 	}
 	return vv
 }
+
+// GetValidatorStakes returns the staking of the validators elected for the
+// epoch, keyed by the address of their committee key.
+func GetValidatorStakes(state StateDB, eid, hh uint64) map[common.Address]*big.Int {
+	i := NewImpawnImpl()
+	if err := i.Load(state, types.StakingAddress); err != nil {
+		return nil
+	}
+	stakes := make(map[common.Address]*big.Int)
+	for _, v := range i.getElections3(eid) {
+		pubkey, err := crypto.UnmarshalPubkey(v.Votepubkey)
+		if err != nil {
+			continue
+		}
+		stakes[crypto.PubkeyToAddress(*pubkey)] = v.getAllStaking(hh)
+	}
+	return stakes
+}
 func (i *ImpawnImpl) Counts() int {
 	pos := 0
 	for _, val := range i.accounts {
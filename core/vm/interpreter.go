@@ -38,6 +38,19 @@ type Config struct {
 	EVMInterpreter   string // External EVM interpreter options
 
 	ExtraEips []int // Additional EIPS that are to be enabled
+
+	StateReadLimit uint64 // Maximum number of state reading operations (0 = unlimited)
+}
+
+// readsState reports whether the opcode loads account or storage data from the
+// state database.
+func readsState(op OpCode) bool {
+	switch op {
+	case SLOAD, BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH,
+		CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		return true
+	}
+	return false
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -218,6 +231,12 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		} else if sLen > operation.maxStack {
 			return nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
 		}
+		// Enforce the state read budget of the call, if any
+		if in.cfg.StateReadLimit > 0 && readsState(op) {
+			if in.evm.stateReads++; in.evm.stateReads > in.cfg.StateReadLimit {
+				return nil, ErrStateReadLimit
+			}
+		}
 		// If the operation is valid, enforce and write restrictions
 		if in.readOnly {
 			// If the interpreter is operating in readonly mode, make sure no
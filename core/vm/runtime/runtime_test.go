@@ -124,6 +124,30 @@ func TestCall(t *testing.T) {
 	}
 }
 
+// Tests that calls exceeding the state read budget are aborted.
+func TestStateReadLimit(t *testing.T) {
+	state, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	address := common.HexToAddress("0xc0de")
+	// Code loads storage slot zero in an endless loop
+	state.SetCode(address, []byte{
+		byte(vm.JUMPDEST),
+		byte(vm.PUSH1), 0,
+		byte(vm.SLOAD),
+		byte(vm.POP),
+		byte(vm.PUSH1), 0,
+		byte(vm.JUMP),
+	})
+	_, _, err := Call(address, nil, &Config{State: state, GasLimit: 10000000, EVMConfig: vm.Config{StateReadLimit: 100}})
+	if err != vm.ErrStateReadLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrStateReadLimit)
+	}
+	// Without budget the loop runs out of gas instead
+	_, _, err = Call(address, nil, &Config{State: state, GasLimit: 10000000})
+	if err != vm.ErrOutOfGas {
+		t.Fatalf("error mismatch: have %v, want %v", err, vm.ErrOutOfGas)
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -34,12 +34,17 @@ var StakingGas = map[string]uint64{
 	"deposit":          2400000,
 	"append":           2400000,
 	"setFee":           2400000,
+	"setMinDelegation": 2400000,
+	"minDelegation":    30000,
 	"setPubkey":        2400000,
 	"withdraw":         2520000,
 	"cancel":           2400000,
 	"delegate":         1500000,
+	"delegateWithSig":  1530000,
+	"delegateNonce":    30000,
 	"undelegate":       1500000,
 	"withdrawDelegate": 1620000,
+	"pauseRotation":    2400000,
 }
 
 // Staking contract ABI
@@ -85,10 +90,40 @@ func RunStaking(evm *EVM, contract *Contract, input []byte) (ret []byte, err err
 		ret, err = setPubkey(evm, contract, data)
 	case "delegate":
 		ret, err = delegate(evm, contract, data)
+	case "delegateWithSig":
+		if !evm.chainConfig.IsTIP11(evm.BlockNumber) {
+			err = ErrStakingInvalidInput
+			break
+		}
+		ret, err = delegateWithSig(evm, contract, data)
+	case "delegateNonce":
+		if !evm.chainConfig.IsTIP11(evm.BlockNumber) {
+			err = ErrStakingInvalidInput
+			break
+		}
+		ret, err = getDelegateNonce(evm, contract, data)
+	case "setMinDelegation":
+		if !evm.chainConfig.IsTIP12(evm.BlockNumber) {
+			err = ErrStakingInvalidInput
+			break
+		}
+		ret, err = setMinDelegation(evm, contract, data)
+	case "minDelegation":
+		if !evm.chainConfig.IsTIP12(evm.BlockNumber) {
+			err = ErrStakingInvalidInput
+			break
+		}
+		ret, err = getMinDelegation(evm, contract, data)
 	case "undelegate":
 		ret, err = undelegate(evm, contract, data)
 	case "withdrawDelegate":
 		ret, err = withdrawDelegate(evm, contract, data)
+	case "pauseRotation":
+		if !evm.chainConfig.IsTIP14(evm.BlockNumber) {
+			err = ErrStakingInvalidInput
+			break
+		}
+		ret, err = pauseRotation(evm, contract, data)
 	default:
 		log.Warn("Staking call fallback function")
 		err = ErrStakingInvalidInput
@@ -255,6 +290,11 @@ func setFeeRate(evm *EVM, contract *Contract, input []byte) (ret []byte, err err
 	}
 
 	from := contract.caller.Address()
+	if evm.chainConfig.IsTIP12(evm.BlockNumber) {
+		if err := checkFeeCooldown(evm, from); err != nil {
+			return nil, err
+		}
+	}
 
 	log.Info("Staking set fee", "number", evm.Context.BlockNumber.Uint64(), "address", contract.caller.Address().StringToAbey(), "fee", fee)
 	impawn := NewImpawnImpl()
@@ -350,9 +390,13 @@ func delegate(evm *EVM, contract *Contract, input []byte) (ret []byte, err error
 		log.Error("Unpack deposit pubkey error", "err", err)
 		return nil, ErrStakingInvalidInput
 	}
-	from := contract.caller.Address()
-	if evm.StateDB.GetUnlockedBalance(from).Cmp(args.Value) < 0 {
-		log.Error("Staking balance insufficient", "address", contract.caller.Address().StringToAbey(), "value", args.Value)
+	return delegateFrom(evm, contract, contract.caller.Address(), args.Holder, args.Value, t0)
+}
+
+// delegateFrom delegates value of the from account to the holder.
+func delegateFrom(evm *EVM, contract *Contract, from, holder common.Address, value *big.Int, t0 time.Time) (ret []byte, err error) {
+	if evm.StateDB.GetUnlockedBalance(from).Cmp(value) < 0 {
+		log.Error("Staking balance insufficient", "address", from.StringToAbey(), "value", value)
 		return nil, ErrStakingInsufficientBalance
 	}
 
@@ -363,10 +407,15 @@ func delegate(evm *EVM, contract *Contract, input []byte) (ret []byte, err error
 		log.Error("Staking load error", "error", err)
 		return nil, err
 	}
+	if evm.chainConfig.IsTIP12(evm.BlockNumber) {
+		if err := checkMinDelegation(evm, impawn, holder, from, value); err != nil {
+			return nil, err
+		}
+	}
 	t2 := time.Now()
-	err = impawn.InsertDAccount2(evm.Context.BlockNumber.Uint64(), args.Holder, from, args.Value)
+	err = impawn.InsertDAccount2(evm.Context.BlockNumber.Uint64(), holder, from, value)
 	if err != nil {
-		log.Error("Staking delegate", "address", contract.caller.Address().StringToAbey(), "value", args.Value, "error", err)
+		log.Error("Staking delegate", "address", from.StringToAbey(), "value", value, "error", err)
 		return nil, err
 	}
 	t3 := time.Now()
@@ -375,11 +424,11 @@ func delegate(evm *EVM, contract *Contract, input []byte) (ret []byte, err error
 		log.Error("Staking save state error", "error", err)
 		return nil, err
 	}
-	addLockedBalance(evm.StateDB, from, args.Value)
+	addLockedBalance(evm.StateDB, from, value)
 
 	t4 := time.Now()
 	event := abiStaking.Events["Delegate"]
-	logData, err := event.Inputs.PackNonIndexed(args.Value)
+	logData, err := event.Inputs.PackNonIndexed(value)
 	if err != nil {
 		log.Error("Pack staking log error", "error", err)
 		return nil, err
@@ -387,11 +436,11 @@ func delegate(evm *EVM, contract *Contract, input []byte) (ret []byte, err error
 	topics := []common.Hash{
 		event.ID,
 		common.BytesToHash(from[:]),
-		common.BytesToHash(args.Holder[:]),
+		common.BytesToHash(holder[:]),
 	}
 	logN(evm, contract, topics, logData)
 	context := []interface{}{
-		"number", evm.Context.BlockNumber.Uint64(), "address", from, "holder", args.Holder, "value", args.Value,
+		"number", evm.Context.BlockNumber.Uint64(), "address", from, "holder", holder, "value", value,
 		"input", common.PrettyDuration(t1.Sub(t0)), "load", common.PrettyDuration(t2.Sub(t1)),
 		"insert", common.PrettyDuration(t3.Sub(t2)), "save", common.PrettyDuration(t4.Sub(t3)),
 		"log", common.PrettyDuration(time.Since(t4)), "elapsed", common.PrettyDuration(time.Since(t0)),
@@ -874,6 +923,45 @@ const StakeABIJSON = `
     "anonymous": false,
     "type": "event"
   },
+  {
+    "name": "PauseRotation",
+    "inputs": [
+      {
+        "type": "uint256",
+        "name": "committeeID",
+        "indexed": true
+      },
+      {
+        "type": "uint256",
+        "name": "until",
+        "indexed": false
+      },
+      {
+        "type": "string",
+        "name": "reason",
+        "indexed": false
+      }
+    ],
+    "anonymous": false,
+    "type": "event"
+  },
+  {
+    "name": "SetMinDelegation",
+    "inputs": [
+      {
+        "type": "address",
+        "name": "from",
+        "indexed": true
+      },
+      {
+        "type": "uint256",
+        "name": "value",
+        "indexed": false
+      }
+    ],
+    "anonymous": false,
+    "type": "event"
+  },
   {
     "name": "SetPubkey",
     "inputs": [
@@ -968,6 +1056,117 @@ const StakeABIJSON = `
     "payable": false,
     "type": "function"
   },
+  {
+    "name": "delegateWithSig",
+    "outputs": [],
+    "inputs": [
+      {
+        "type": "address",
+        "name": "delegator"
+      },
+      {
+        "type": "address",
+        "name": "holder"
+      },
+      {
+        "type": "uint256",
+        "name": "value"
+      },
+      {
+        "type": "uint256",
+        "name": "deadline"
+      },
+      {
+        "type": "uint8",
+        "name": "v"
+      },
+      {
+        "type": "bytes32",
+        "name": "r"
+      },
+      {
+        "type": "bytes32",
+        "name": "s"
+      }
+    ],
+    "constant": false,
+    "payable": false,
+    "type": "function"
+  },
+  {
+    "name": "delegateNonce",
+    "outputs": [
+      {
+        "type": "uint256",
+        "name": "nonce"
+      }
+    ],
+    "inputs": [
+      {
+        "type": "address",
+        "name": "delegator"
+      }
+    ],
+    "constant": true,
+    "payable": false,
+    "type": "function"
+  },
+  {
+    "name": "pauseRotation",
+    "outputs": [],
+    "inputs": [
+      {
+        "type": "uint256",
+        "name": "committeeID"
+      },
+      {
+        "type": "uint256",
+        "name": "until"
+      },
+      {
+        "type": "string",
+        "name": "reason"
+      },
+      {
+        "type": "bytes",
+        "name": "signatures"
+      }
+    ],
+    "constant": false,
+    "payable": false,
+    "type": "function"
+  },
+  {
+    "name": "setMinDelegation",
+    "outputs": [],
+    "inputs": [
+      {
+        "type": "uint256",
+        "name": "value"
+      }
+    ],
+    "constant": false,
+    "payable": false,
+    "type": "function"
+  },
+  {
+    "name": "minDelegation",
+    "outputs": [
+      {
+        "type": "uint256",
+        "name": "value"
+      }
+    ],
+    "inputs": [
+      {
+        "type": "address",
+        "name": "holder"
+      }
+    ],
+    "constant": true,
+    "payable": false,
+    "type": "function"
+  },
   {
     "name": "undelegate",
     "outputs": [],
@@ -0,0 +1,142 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+)
+
+// Delegations can be approved off-chain by the delegator signing an EIP-712
+// typed data message, which any relayer may submit to the staking contract
+// paying the gas. Each approval consumes the delegator's delegation nonce, so
+// it can't be replayed.
+var (
+	eip712DomainType = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	delegationType   = crypto.Keccak256Hash([]byte("Delegation(address delegator,address holder,uint256 value,uint256 nonce,uint256 deadline)"))
+
+	delegationDomainName    = crypto.Keccak256Hash([]byte("AbeyStaking"))
+	delegationDomainVersion = crypto.Keccak256Hash([]byte("1"))
+
+	// delegateNoncePrefix + delegator -> storage slot of the delegation nonce
+	delegateNoncePrefix = []byte("delegate-nonce-")
+)
+
+// delegateNonceKey returns the storage slot of the delegation nonce of an account.
+func delegateNonceKey(delegator common.Address) common.Hash {
+	return crypto.Keccak256Hash(delegateNoncePrefix, delegator[:])
+}
+
+// DelegateNonce returns the nonce the next signed delegation of an account must carry.
+func DelegateNonce(db StateDB, delegator common.Address) uint64 {
+	return db.GetState(types.StakingAddress, delegateNonceKey(delegator)).Big().Uint64()
+}
+
+// DelegationHash returns the EIP-712 digest the delegator signs to approve the
+// delegation of value to the holder, valid until the deadline (unix seconds).
+func DelegationHash(chainID *big.Int, delegator, holder common.Address, value *big.Int, nonce uint64, deadline *big.Int) common.Hash {
+	domain := crypto.Keccak256Hash(
+		eip712DomainType[:],
+		delegationDomainName[:],
+		delegationDomainVersion[:],
+		common.BigToHash(chainID).Bytes(),
+		common.BytesToHash(types.StakingAddress[:]).Bytes(),
+	)
+	message := crypto.Keccak256Hash(
+		delegationType[:],
+		common.BytesToHash(delegator[:]).Bytes(),
+		common.BytesToHash(holder[:]).Bytes(),
+		common.BigToHash(value).Bytes(),
+		common.BigToHash(new(big.Int).SetUint64(nonce)).Bytes(),
+		common.BigToHash(deadline).Bytes(),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domain[:], message[:])
+}
+
+// recoverApprover returns the account that signed an approval digest.
+func recoverApprover(hash common.Hash, v uint8, r, s [32]byte) (common.Address, error) {
+	if v < 27 || !crypto.ValidateSignatureValues(v-27, new(big.Int).SetBytes(r[:]), new(big.Int).SetBytes(s[:]), true) {
+		return common.Address{}, ErrStakingInvalidApproval
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[:32], r[:])
+	copy(sig[32:64], s[:])
+	sig[64] = v - 27
+
+	pub, err := crypto.Ecrecover(hash[:], sig)
+	if err != nil {
+		return common.Address{}, ErrStakingInvalidApproval
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pub[1:])[12:])
+	return signer, nil
+}
+
+// delegateWithSig delegates on behalf of a delegator that approved it off-chain.
+func delegateWithSig(evm *EVM, contract *Contract, input []byte) (ret []byte, err error) {
+	args := struct {
+		Delegator common.Address
+		Holder    common.Address
+		Value     *big.Int
+		Deadline  *big.Int
+		V         uint8
+		R         [32]byte
+		S         [32]byte
+	}{}
+
+	t0 := time.Now()
+	method, _ := abiStaking.Methods["delegateWithSig"]
+	err = method.Inputs.Unpack(&args, input)
+	if err != nil {
+		log.Error("Unpack delegate approval error", "err", err)
+		return nil, ErrStakingInvalidInput
+	}
+	if evm.Time.Cmp(args.Deadline) > 0 {
+		log.Error("Staking delegation approval expired", "delegator", args.Delegator.StringToAbey(), "deadline", args.Deadline)
+		return nil, ErrStakingExpiredApproval
+	}
+	nonce := DelegateNonce(evm.StateDB, args.Delegator)
+	hash := DelegationHash(evm.chainConfig.ChainID, args.Delegator, args.Holder, args.Value, nonce, args.Deadline)
+
+	signer, err := recoverApprover(hash, args.V, args.R, args.S)
+	if err != nil || signer != args.Delegator {
+		log.Error("Staking delegation approval invalid", "delegator", args.Delegator.StringToAbey(), "signer", signer.StringToAbey(), "nonce", nonce)
+		return nil, ErrStakingInvalidApproval
+	}
+	evm.StateDB.SetState(types.StakingAddress, delegateNonceKey(args.Delegator), common.BigToHash(new(big.Int).SetUint64(nonce+1)))
+
+	log.Info("Staking delegation approved", "delegator", args.Delegator.StringToAbey(), "relayer", contract.caller.Address().StringToAbey(), "nonce", nonce)
+	return delegateFrom(evm, contract, args.Delegator, args.Holder, args.Value, t0)
+}
+
+// getDelegateNonce returns the delegation nonce of an account.
+func getDelegateNonce(evm *EVM, contract *Contract, input []byte) (ret []byte, err error) {
+	var delegator common.Address
+
+	method, _ := abiStaking.Methods["delegateNonce"]
+	err = method.Inputs.Unpack(&delegator, input)
+	if err != nil {
+		log.Error("Unpack delegate_nonce input error")
+		return nil, ErrStakingInvalidInput
+	}
+	return method.Outputs.Pack(new(big.Int).SetUint64(DelegateNonce(evm.StateDB, delegator)))
+}
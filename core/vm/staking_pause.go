@@ -0,0 +1,174 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/params"
+)
+
+// When a critical bug is found close to a committee switch, a supermajority of
+// the validators in charge may postpone the switch by signing a RotationPause
+// with their committee keys. Anyone may submit the signatures to the staking
+// contract after TIP14, which records the end of the pause in a plain storage
+// slot. The committee in charge of a fast block is derived from that slot, so
+// every node agrees on it from chain data alone.
+var (
+	// rotationPausePrefix + committee id -> storage slot of the last fast block
+	// the committee stays in charge of
+	rotationPausePrefix = []byte("committee-rotation-pause-")
+)
+
+// rotationPauseSlot returns the storage slot of the pause of a committee.
+func rotationPauseSlot(committeeID uint64) common.Hash {
+	return crypto.Keccak256Hash(rotationPausePrefix, new(big.Int).SetUint64(committeeID).Bytes())
+}
+
+// RotationPauseUntil returns the last fast block a committee stays in charge of
+// under a rotation pause, zero if its rotation wasn't paused.
+func RotationPauseUntil(db StateDB, committeeID uint64) uint64 {
+	return db.GetState(types.StakingAddress, rotationPauseSlot(committeeID)).Big().Uint64()
+}
+
+// RotationSwitchNumber returns the first fast block the committee of the epoch
+// is in charge of, which is later than the epoch's begin if the switch was paused.
+//
+// A pause is only accepted while the committee is in charge and never shortens
+// its term, so the recorded pauses only grow: reading them from any state at
+// or after the block verified gives the same switch.
+func RotationSwitchNumber(db StateDB, epoch *types.EpochIDInfo) uint64 {
+	if epoch.EpochID == 0 {
+		return epoch.BeginHeight
+	}
+	if until := RotationPauseUntil(db, epoch.EpochID-1); until >= epoch.BeginHeight {
+		return until + 1
+	}
+	return epoch.BeginHeight
+}
+
+// RotationCommittee returns the epoch whose committee is in charge of a fast block.
+func RotationCommittee(db StateDB, number uint64) *types.EpochIDInfo {
+	epoch := types.GetEpochFromHeight(number)
+	if number < RotationSwitchNumber(db, epoch) {
+		return types.GetEpochFromID(epoch.EpochID - 1)
+	}
+	return epoch
+}
+
+// VerifyRotationPause checks a rotation pause submitted in a fast block against
+// the committee in charge of it, which must be approved by validators holding
+// more than two thirds of the committee's stake.
+func VerifyRotationPause(config *params.ChainConfig, db StateDB, number uint64, pause *types.RotationPause, sigs [][]byte) error {
+	if pause.CommitteeID == nil || pause.Until == nil || !pause.CommitteeID.IsUint64() || !pause.Until.IsUint64() {
+		return ErrPauseRange
+	}
+	if config.TIP8 == nil || pause.CommitteeID.Cmp(config.TIP8.CID) < 0 {
+		return ErrPauseUnsupported
+	}
+	current := RotationCommittee(db, number)
+	if current.EpochID != pause.CommitteeID.Uint64() {
+		return ErrPauseNotCurrent
+	}
+	// The pause must outlast the committee's current term, and end early enough
+	// for the committee after the next one to be prepared in time.
+	until, end := pause.Until.Uint64(), current.EndHeight
+	if prev := RotationPauseUntil(db, current.EpochID); prev > end {
+		end = prev
+	}
+	following := types.GetEpochFromID(current.EpochID + 1)
+	if until <= end || until > following.EndHeight-params.ElectionPoint {
+		return ErrPauseRange
+	}
+	stakes := GetValidatorStakes(db, current.EpochID, number)
+
+	total, signed := new(big.Int), new(big.Int)
+	for _, stake := range stakes {
+		total.Add(total, stake)
+	}
+	hash := pause.SigHash(config.ChainID)
+	approved := make(map[common.Address]bool)
+	for _, sig := range sigs {
+		pubkey, err := crypto.SigToPub(hash[:], sig)
+		if err != nil {
+			return ErrPauseSignature
+		}
+		signer := crypto.PubkeyToAddress(*pubkey)
+		stake, ok := stakes[signer]
+		if !ok {
+			log.Error("Rotation pause signed by a non validator", "committee", current.EpochID, "signer", signer.StringToAbey())
+			return ErrPauseSignature
+		}
+		if !approved[signer] {
+			approved[signer] = true
+			signed.Add(signed, stake)
+		}
+	}
+	if total.Sign() == 0 || new(big.Int).Mul(signed, big.NewInt(3)).Cmp(new(big.Int).Mul(total, big.NewInt(2))) <= 0 {
+		log.Warn("Rotation pause rejected", "committee", current.EpochID, "signed", signed, "total", total)
+		return ErrPauseInsufficient
+	}
+	return nil
+}
+
+// pauseRotation records a rotation pause approved by the committee in charge.
+// The signatures are the concatenated 65 byte [R || S || V] signatures of the
+// pause hash made with the committee keys.
+func pauseRotation(evm *EVM, contract *Contract, input []byte) (ret []byte, err error) {
+	args := struct {
+		CommitteeID *big.Int
+		Until       *big.Int
+		Reason      string
+		Signatures  []byte
+	}{}
+	method, _ := abiStaking.Methods["pauseRotation"]
+	err = method.Inputs.Unpack(&args, input)
+	if err != nil {
+		log.Error("Unpack rotation pause error", "err", err)
+		return nil, ErrStakingInvalidInput
+	}
+	if len(args.Signatures) == 0 || len(args.Signatures)%65 != 0 {
+		return nil, ErrPauseSignature
+	}
+	sigs := make([][]byte, 0, len(args.Signatures)/65)
+	for i := 0; i < len(args.Signatures); i += 65 {
+		sigs = append(sigs, args.Signatures[i:i+65])
+	}
+	pause := &types.RotationPause{CommitteeID: args.CommitteeID, Until: args.Until, Reason: args.Reason}
+	if err := VerifyRotationPause(evm.chainConfig, evm.StateDB, evm.Context.BlockNumber.Uint64(), pause, sigs); err != nil {
+		return nil, err
+	}
+	evm.StateDB.SetState(types.StakingAddress, rotationPauseSlot(pause.CommitteeID.Uint64()), common.BigToHash(pause.Until))
+	log.Warn("Committee rotation paused", "number", evm.Context.BlockNumber, "committee", pause.CommitteeID, "until", pause.Until, "signers", len(sigs), "reason", pause.Reason)
+
+	event := abiStaking.Events["PauseRotation"]
+	logData, err := event.Inputs.PackNonIndexed(pause.Until, pause.Reason)
+	if err != nil {
+		log.Error("Pack staking log error", "error", err)
+		return nil, err
+	}
+	topics := []common.Hash{
+		event.ID,
+		common.BigToHash(pause.CommitteeID),
+	}
+	logN(evm, contract, topics, logData)
+	return nil, nil
+}
@@ -1,14 +1,16 @@
 package vm
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/core/state"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/crypto"
-	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/log"
 	"github.com/abeychain/go-abey/params"
 )
@@ -34,3 +36,250 @@ func TestDeposit(t *testing.T) {
 	impawn1 := NewImpawnImpl()
 	impawn1.Load(evm.StateDB, types.StakingAddress)
 }
+
+func TestDelegateWithSig(t *testing.T) {
+	holderKey, _ := crypto.GenerateKey()
+	holder := crypto.PubkeyToAddress(holderKey.PublicKey)
+	delegatorKey, _ := crypto.GenerateKey()
+	delegator := crypto.PubkeyToAddress(delegatorKey.PublicKey)
+	relayer := common.BytesToAddress([]byte("relayer"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	statedb.GetOrNewStateObject(types.StakingAddress)
+	statedb.AddBalance(delegator, big.NewInt(1000))
+
+	config := *params.TestChainConfig
+	config.TIP11 = &params.BlockConfig{FastNumber: big.NewInt(0)}
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1000), Time: big.NewInt(100)}, statedb, &config, Config{})
+
+	impawn := NewImpawnImpl()
+	impawn.Load(evm.StateDB, types.StakingAddress)
+	impawn.SetCurrentEpoch(types.GetEpochFromHeight(1000).EpochID)
+	impawn.InsertSAccount2(1000, 0, holder, crypto.FromECDSAPub(&holderKey.PublicKey), big.NewInt(1000), big.NewInt(0), true)
+	impawn.Save(evm.StateDB, types.StakingAddress)
+
+	approve := func(key *ecdsa.PrivateKey, value *big.Int, nonce uint64, deadline *big.Int) []byte {
+		sig, _ := crypto.Sign(DelegationHash(config.ChainID, delegator, holder, value, nonce, deadline).Bytes(), key)
+		var r, s [32]byte
+		copy(r[:], sig[:32])
+		copy(s[:], sig[32:64])
+		input, err := abiStaking.Methods["delegateWithSig"].Inputs.Pack(delegator, holder, value, deadline, sig[64]+27, r, s)
+		if err != nil {
+			t.Fatalf("failed to pack approval: %v", err)
+		}
+		return input
+	}
+	contract := NewContract(AccountRef(relayer), AccountRef(types.StakingAddress), big.NewInt(0), 0)
+
+	// Approvals must be signed by the delegator and not expired
+	if _, err := delegateWithSig(evm, contract, approve(holderKey, big.NewInt(100), 0, big.NewInt(200))); err != ErrStakingInvalidApproval {
+		t.Fatalf("foreign approval: have %v, want %v", err, ErrStakingInvalidApproval)
+	}
+	if _, err := delegateWithSig(evm, contract, approve(delegatorKey, big.NewInt(100), 0, big.NewInt(99))); err != ErrStakingExpiredApproval {
+		t.Fatalf("expired approval: have %v, want %v", err, ErrStakingExpiredApproval)
+	}
+	// A valid approval delegates from the delegator and consumes the nonce
+	input := approve(delegatorKey, big.NewInt(100), 0, big.NewInt(200))
+	if _, err := delegateWithSig(evm, contract, input); err != nil {
+		t.Fatalf("failed to delegate with approval: %v", err)
+	}
+	if locked := statedb.GetPOSLocked(delegator); locked.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("delegator locked balance mismatch: have %v, want 100", locked)
+	}
+	if nonce := DelegateNonce(statedb, delegator); nonce != 1 {
+		t.Fatalf("delegation nonce mismatch: have %d, want 1", nonce)
+	}
+	// Replaying the approval must fail
+	if _, err := delegateWithSig(evm, contract, input); err != ErrStakingInvalidApproval {
+		t.Fatalf("replayed approval: have %v, want %v", err, ErrStakingInvalidApproval)
+	}
+}
+
+func TestValidatorSettings(t *testing.T) {
+	holderKey, _ := crypto.GenerateKey()
+	holder := crypto.PubkeyToAddress(holderKey.PublicKey)
+	delegator := common.BytesToAddress([]byte("delegator"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	statedb.GetOrNewStateObject(types.StakingAddress)
+	statedb.AddBalance(delegator, big.NewInt(1000))
+
+	config := *params.TestChainConfig
+	config.TIP12 = &params.BlockConfig{FastNumber: big.NewInt(0)}
+	evm := NewEVM(Context{BlockNumber: big.NewInt(1000), Time: big.NewInt(100)}, statedb, &config, Config{})
+
+	impawn := NewImpawnImpl()
+	impawn.Load(evm.StateDB, types.StakingAddress)
+	impawn.SetCurrentEpoch(types.GetEpochFromHeight(1000).EpochID)
+	impawn.InsertSAccount2(1000, 0, holder, crypto.FromECDSAPub(&holderKey.PublicKey), big.NewInt(1000), big.NewInt(0), true)
+	impawn.Save(evm.StateDB, types.StakingAddress)
+
+	validator := NewContract(AccountRef(holder), AccountRef(types.StakingAddress), big.NewInt(0), 0)
+	input, _ := abiStaking.Methods["setMinDelegation"].Inputs.Pack(big.NewInt(100))
+	if _, err := setMinDelegation(evm, validator, input); err != nil {
+		t.Fatalf("failed to set min delegation: %v", err)
+	}
+	// Delegations leaving the delegator below the minimum must be rejected
+	contract := NewContract(AccountRef(delegator), AccountRef(types.StakingAddress), big.NewInt(0), 0)
+	if _, err := delegateFrom(evm, contract, delegator, holder, big.NewInt(99), time.Now()); err != ErrStakingBelowMinDelegation {
+		t.Fatalf("small delegation: have %v, want %v", err, ErrStakingBelowMinDelegation)
+	}
+	if _, err := delegateFrom(evm, contract, delegator, holder, big.NewInt(100), time.Now()); err != nil {
+		t.Fatalf("failed to delegate: %v", err)
+	}
+	if _, err := delegateFrom(evm, contract, delegator, holder, big.NewInt(1), time.Now()); err != nil {
+		t.Fatalf("failed to top up delegation: %v", err)
+	}
+	// Minimum delegation changes must be cooled down
+	input, _ = abiStaking.Methods["setMinDelegation"].Inputs.Pack(big.NewInt(200))
+	if _, err := setMinDelegation(evm, validator, input); err != ErrStakingMinCooldown {
+		t.Fatalf("repeated min delegation change: have %v, want %v", err, ErrStakingMinCooldown)
+	}
+	impawn = NewImpawnImpl()
+	impawn.Load(evm.StateDB, types.StakingAddress)
+	impawn.SetCurrentEpoch(types.GetEpochFromHeight(1000 + params.FeeChangeCooldown).EpochID)
+	impawn.InsertSAccount2(1000+params.FeeChangeCooldown, 0, holder, crypto.FromECDSAPub(&holderKey.PublicKey), big.NewInt(1000), big.NewInt(0), true)
+	impawn.Save(evm.StateDB, types.StakingAddress)
+
+	evm.Context.BlockNumber = new(big.Int).SetUint64(1000 + params.FeeChangeCooldown)
+	if _, err := setMinDelegation(evm, validator, input); err != nil {
+		t.Fatalf("failed to raise min delegation: %v", err)
+	}
+	// Rewards honour the minimum of the start of the epoch, raising it only hits
+	// the delegations of the next epoch
+	rewarder := NewImpawnImpl()
+	rewarder.Load(evm.StateDB, types.StakingAddress)
+	rewarder.SetRewardState(evm.StateDB)
+	sa, _ := rewarder.GetStakingAccount(types.GetEpochFromHeight(1000).EpochID, holder)
+
+	tests := []struct {
+		target     uint64
+		delegators int
+		validator  int64
+	}{
+		{1000, 1, 1100 - 101},                            // no minimum yet
+		{1000 + params.FeeChangeCooldown, 1, 1100 - 101}, // minimum of 100
+		{1000 + 2*params.FeeChangeCooldown, 0, 1100},     // minimum raised to 200
+	}
+	for i, tt := range tests {
+		item := &types.RewardInfo{Address: holder}
+		items, err := rewarder.calcRewardInSa(tt.target, sa, big.NewInt(1100), big.NewInt(1100), item)
+		if err != nil || len(items) != tt.delegators || item.Amount.Int64() != tt.validator {
+			t.Errorf("test %d: reward mismatch: have %d delegators, validator %v (err %v), want %d, %d", i, len(items), item.Amount, err, tt.delegators, tt.validator)
+		}
+	}
+	evm.Context.BlockNumber = big.NewInt(1000)
+
+	// Commission changes must be cooled down
+	input, _ = abiStaking.Methods["setFee"].Inputs.Pack(big.NewInt(10))
+	if _, err := setFeeRate(evm, validator, input); err != nil {
+		t.Fatalf("failed to set fee: %v", err)
+	}
+	if _, err := setFeeRate(evm, validator, input); err != ErrStakingFeeCooldown {
+		t.Fatalf("repeated fee change: have %v, want %v", err, ErrStakingFeeCooldown)
+	}
+	evm.Context.BlockNumber = new(big.Int).SetUint64(1000 + params.FeeChangeCooldown)
+	if err := checkFeeCooldown(evm, holder); err != nil {
+		t.Fatalf("fee change after cool-down: %v", err)
+	}
+}
+
+func TestPauseRotation(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(abeydb.NewMemDatabase()))
+	statedb.GetOrNewStateObject(types.StakingAddress)
+
+	impawn := NewImpawnImpl()
+	for _, stake := range []int64{400000, 300000, 200000, 200000} {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		amount := new(big.Int).Mul(big.NewInt(stake), big.NewInt(1e18))
+		if err := impawn.InsertSAccount2(0, 0, crypto.PubkeyToAddress(key.PublicKey), crypto.FromECDSAPub(&key.PublicKey), amount, big.NewInt(50), true); err != nil {
+			t.Fatalf("failed to stake: %v", err)
+		}
+	}
+	if _, err := impawn.DoElections(1, 0); err != nil {
+		t.Fatalf("failed to elect: %v", err)
+	}
+	impawn.Save(statedb, types.StakingAddress)
+
+	config := *params.TestChainConfig
+	config.TIP8 = &params.BlockConfig{FastNumber: big.NewInt(0), CID: big.NewInt(0)}
+	evm := NewEVM(Context{BlockNumber: big.NewInt(101), Time: big.NewInt(100)}, statedb, &config, Config{})
+	contract := NewContract(AccountRef(common.BytesToAddress([]byte("relayer"))), AccountRef(types.StakingAddress), big.NewInt(0), 0)
+
+	packFor := func(chainID *big.Int, pause *types.RotationPause, signers ...*ecdsa.PrivateKey) []byte {
+		var sigs []byte
+		hash := pause.SigHash(chainID)
+		for _, key := range signers {
+			sig, _ := crypto.Sign(hash[:], key)
+			sigs = append(sigs, sig...)
+		}
+		input, err := abiStaking.Pack("pauseRotation", pause.CommitteeID, pause.Until, pause.Reason, sigs)
+		if err != nil {
+			t.Fatalf("failed to pack pause: %v", err)
+		}
+		return input
+	}
+	pack := func(pause *types.RotationPause, signers ...*ecdsa.PrivateKey) []byte {
+		return packFor(config.ChainID, pause, signers...)
+	}
+	first := types.GetFirstEpoch()
+	next := types.GetEpochFromID(first.EpochID + 1)
+	pause := &types.RotationPause{CommitteeID: new(big.Int).SetUint64(first.EpochID), Until: new(big.Int).SetUint64(first.EndHeight + 1000), Reason: "test"}
+	outsider, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		pause   *types.RotationPause
+		signers []*ecdsa.PrivateKey
+		err     error
+	}{
+		// Out of range and foreign committee pauses must be rejected
+		{&types.RotationPause{CommitteeID: pause.CommitteeID, Until: new(big.Int).SetUint64(first.EndHeight)}, keys, ErrPauseRange},
+		{&types.RotationPause{CommitteeID: pause.CommitteeID, Until: new(big.Int).SetUint64(next.EndHeight)}, keys, ErrPauseRange},
+		{&types.RotationPause{CommitteeID: new(big.Int).SetUint64(next.EpochID), Until: pause.Until}, keys, ErrPauseNotCurrent},
+		// Two thirds of the stake is not enough, neither are duplicate signatures
+		{pause, []*ecdsa.PrivateKey{keys[0], keys[2]}, ErrPauseInsufficient},
+		{pause, []*ecdsa.PrivateKey{keys[0], keys[0], keys[2]}, ErrPauseInsufficient},
+		{pause, []*ecdsa.PrivateKey{keys[0], outsider}, ErrPauseSignature},
+		{pause, nil, ErrPauseSignature},
+	}
+	for i, tt := range tests {
+		if _, err := pauseRotation(evm, contract, pack(tt.pause, tt.signers...)[4:]); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Approvals given on another chain must not be replayable
+	foreign := new(big.Int).Add(config.ChainID, big.NewInt(1))
+	if _, err := pauseRotation(evm, contract, packFor(foreign, pause, keys...)[4:]); err != ErrPauseSignature {
+		t.Errorf("foreign chain approval: have error %v, want %v", err, ErrPauseSignature)
+	}
+	if n := RotationSwitchNumber(statedb, next); n != next.BeginHeight {
+		t.Fatalf("switch moved by rejected pauses: have %d, want %d", n, next.BeginHeight)
+	}
+	// The method is only available after TIP14
+	if _, err := RunStaking(evm, contract, pack(pause, keys...)); err != ErrExecutionReverted {
+		t.Fatalf("pause before TIP14: have %v, want %v", err, ErrExecutionReverted)
+	}
+	// A supermajority postpones the switch in the state
+	config.TIP14 = &params.BlockConfig{FastNumber: big.NewInt(0)}
+	if _, err := RunStaking(evm, contract, pack(pause, keys[0], keys[1], keys[2])); err != nil {
+		t.Fatalf("failed to pause rotation: %v", err)
+	}
+	if n := RotationSwitchNumber(statedb, next); n != pause.Until.Uint64()+1 {
+		t.Fatalf("switch number mismatch: have %d, want %d", n, pause.Until.Uint64()+1)
+	}
+	if epoch := RotationCommittee(statedb, next.BeginHeight); epoch.EpochID != first.EpochID {
+		t.Fatalf("committee in charge of paused block mismatch: have %d, want %d", epoch.EpochID, first.EpochID)
+	}
+	if epoch := RotationCommittee(statedb, pause.Until.Uint64()+1); epoch.EpochID != next.EpochID {
+		t.Fatalf("committee in charge after pause mismatch: have %d, want %d", epoch.EpochID, next.EpochID)
+	}
+	if logs := statedb.Logs(); len(logs) != 1 || logs[0].Topics[1] != common.BigToHash(pause.CommitteeID) {
+		t.Fatalf("pause log mismatch: %v", logs)
+	}
+	// Extensions must go beyond the recorded pause
+	if _, err := pauseRotation(evm, contract, pack(pause, keys...)[4:]); err != ErrPauseRange {
+		t.Fatalf("repeated pause: have %v, want %v", err, ErrPauseRange)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/params"
+)
+
+// The validator settings introduced by TIP12 live in plain storage slots of the
+// staking contract, next to the encoded impawn state, so the encoding of the
+// staking accounts stays untouched.
+var (
+	// minDelegationPrefix + validator -> storage slot of the minimum delegation
+	minDelegationPrefix = []byte("validator-min-delegation-")
+
+	// minDelegationPrevPrefix + validator -> storage slot of the minimum delegation
+	// the rewards honour until the epoch of the last change has passed
+	minDelegationPrevPrefix = []byte("validator-min-delegation-prev-")
+
+	// minDelegationEpochPrefix + validator -> storage slot of the first epoch the
+	// rewards honour the minimum delegation in
+	minDelegationEpochPrefix = []byte("validator-min-delegation-epoch-")
+
+	// minDelegationChangedPrefix + validator -> storage slot of the last minimum
+	// delegation change height
+	minDelegationChangedPrefix = []byte("validator-min-delegation-changed-")
+
+	// feeChangedPrefix + validator -> storage slot of the last commission change height
+	feeChangedPrefix = []byte("validator-fee-changed-")
+)
+
+// validatorSlot returns the storage slot of a validator setting.
+func validatorSlot(prefix []byte, validator common.Address) common.Hash {
+	return crypto.Keccak256Hash(prefix, validator[:])
+}
+
+// validatorSetting returns the value of a validator setting.
+func validatorSetting(db StateDB, prefix []byte, validator common.Address) *big.Int {
+	return db.GetState(types.StakingAddress, validatorSlot(prefix, validator)).Big()
+}
+
+// ValidatorMinDelegation returns the minimum amount a single delegation to the
+// validator must have. Delegations below it are refused.
+func ValidatorMinDelegation(db StateDB, validator common.Address) *big.Int {
+	return validatorSetting(db, minDelegationPrefix, validator)
+}
+
+// ValidatorRewardMinDelegation returns the minimum delegation the validator had
+// at the start of the epoch. Delegations below it don't share in the rewards of
+// the epoch. The change cool-down keeps the epochs being rewarded covered by the
+// current and the previous minimum.
+func ValidatorRewardMinDelegation(db StateDB, validator common.Address, epochID uint64) *big.Int {
+	if epochID < validatorSetting(db, minDelegationEpochPrefix, validator).Uint64() {
+		return validatorSetting(db, minDelegationPrevPrefix, validator)
+	}
+	return ValidatorMinDelegation(db, validator)
+}
+
+// checkCooldown records a change of the validator setting tracked under prefix,
+// rejecting it if the previous one was less than the cool-down ago.
+func checkCooldown(evm *EVM, prefix []byte, validator common.Address, cooling error) error {
+	number := evm.Context.BlockNumber.Uint64()
+	if last := validatorSetting(evm.StateDB, prefix, validator).Uint64(); last != 0 && number < last+params.FeeChangeCooldown {
+		log.Error("Staking validator change cooling down", "address", validator.StringToAbey(), "last", last, "next", last+params.FeeChangeCooldown, "err", cooling)
+		return cooling
+	}
+	evm.StateDB.SetState(types.StakingAddress, validatorSlot(prefix, validator), common.BigToHash(evm.Context.BlockNumber))
+	return nil
+}
+
+// checkFeeCooldown records a commission change of the validator, rejecting it if
+// the previous one was less than the cool-down ago.
+func checkFeeCooldown(evm *EVM, validator common.Address) error {
+	return checkCooldown(evm, feeChangedPrefix, validator, ErrStakingFeeCooldown)
+}
+
+// checkMinDelegation rejects delegations leaving the total of the delegator with
+// the validator below its minimum.
+func checkMinDelegation(evm *EVM, impawn *ImpawnImpl, validator, delegator common.Address, value *big.Int) error {
+	height := evm.Context.BlockNumber.Uint64()
+	total := new(big.Int).Set(value)
+	if sa, err := impawn.GetStakingAccount(types.GetEpochFromHeight(height).EpochID, validator); err == nil {
+		if da := sa.getDA(delegator); da != nil {
+			total.Add(total, da.getAllStaking(height))
+		}
+	}
+	if min := ValidatorMinDelegation(evm.StateDB, validator); total.Cmp(min) < 0 {
+		log.Error("Staking delegation below minimum", "holder", validator.StringToAbey(), "value", value, "total", total, "min", min)
+		return ErrStakingBelowMinDelegation
+	}
+	return nil
+}
+
+// setMinDelegation sets the minimum delegation of the calling validator.
+func setMinDelegation(evm *EVM, contract *Contract, input []byte) (ret []byte, err error) {
+	value := big.NewInt(0)
+	method, _ := abiStaking.Methods["setMinDelegation"]
+
+	err = method.Inputs.Unpack(&value, input)
+	if err != nil {
+		log.Error("Unpack min delegation error", "err", err)
+		return nil, ErrStakingInvalidInput
+	}
+	from := contract.caller.Address()
+
+	log.Info("Staking set min delegation", "number", evm.Context.BlockNumber.Uint64(), "address", from.StringToAbey(), "value", value)
+	impawn := NewImpawnImpl()
+	err = impawn.Load(evm.StateDB, types.StakingAddress)
+	if err != nil {
+		log.Error("Staking load error", "error", err)
+		return nil, err
+	}
+	epoch := types.GetEpochFromHeight(evm.Context.BlockNumber.Uint64())
+	if _, err := impawn.GetStakingAccount(epoch.EpochID, from); err != nil {
+		log.Error("Staking min delegation", "address", from.StringToAbey(), "error", err)
+		return nil, err
+	}
+	if err := checkCooldown(evm, minDelegationChangedPrefix, from, ErrStakingMinCooldown); err != nil {
+		return nil, err
+	}
+	// The rewards of the current epoch keep honouring the minimum it started with
+	if validatorSetting(evm.StateDB, minDelegationEpochPrefix, from).Uint64() <= epoch.EpochID {
+		evm.StateDB.SetState(types.StakingAddress, validatorSlot(minDelegationPrevPrefix, from), common.BigToHash(ValidatorMinDelegation(evm.StateDB, from)))
+	}
+	next := new(big.Int).SetUint64(epoch.EpochID + 1)
+	evm.StateDB.SetState(types.StakingAddress, validatorSlot(minDelegationEpochPrefix, from), common.BigToHash(next))
+	evm.StateDB.SetState(types.StakingAddress, validatorSlot(minDelegationPrefix, from), common.BigToHash(value))
+
+	event := abiStaking.Events["SetMinDelegation"]
+	logData, err := event.Inputs.PackNonIndexed(value)
+	if err != nil {
+		log.Error("Pack staking log error", "error", err)
+		return nil, err
+	}
+	topics := []common.Hash{
+		event.ID,
+		common.BytesToHash(from[:]),
+	}
+	logN(evm, contract, topics, logData)
+	return nil, nil
+}
+
+// getMinDelegation returns the minimum delegation of a validator.
+func getMinDelegation(evm *EVM, contract *Contract, input []byte) (ret []byte, err error) {
+	var validator common.Address
+
+	method, _ := abiStaking.Methods["minDelegation"]
+	err = method.Inputs.Unpack(&validator, input)
+	if err != nil {
+		log.Error("Unpack min_delegation input error")
+		return nil, ErrStakingInvalidInput
+	}
+	return method.Outputs.Pack(ValidatorMinDelegation(evm.StateDB, validator))
+}
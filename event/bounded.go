@@ -0,0 +1,160 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+// boundedWarnInterval is the minimum time between two overflow warnings of the
+// same subscription.
+const boundedWarnInterval = time.Minute
+
+var (
+	errBadSubscribe  = errors.New("event: SubscribeBounded argument is not a func(chan<- T) Subscription")
+	errBadBoundedCap = errors.New("event: SubscribeBounded channel must be buffered")
+)
+
+// BoundedSubscription relays the events of a subscription into a consumer
+// channel without ever blocking the sender. When the consumer falls behind and
+// its channel is full, the oldest queued event is dropped to make room. Drops
+// are counted in the event/dropped/<name> metric and logged as warnings, so a
+// stalled subscriber shows up as event loss instead of blocking the feed.
+//
+// Only use it for subscribers that can tolerate missing events, e.g. the ones
+// that merely use an event as a trigger to look at the current chain state.
+type BoundedSubscription struct {
+	sub  Subscription
+	name string
+	in   reflect.Value // Channel the subscription delivers into
+	out  reflect.Value // Consumer channel, must be bidirectional to drop from
+
+	dropped  uint64 // Number of dropped events (atomic)
+	counter  metrics.Counter
+	lastWarn time.Time
+
+	quit chan struct{}
+	once sync.Once
+}
+
+// SubscribeBounded subscribes channel through subscribe, which is either a
+// Feed's Subscribe or a typed func(chan<- T) Subscription such as a chain's
+// SubscribeChainEvent. The channel must be bidirectional and buffered, its
+// capacity bounds the number of events queued for the consumer.
+func SubscribeBounded(name string, channel interface{}, subscribe interface{}) *BoundedSubscription {
+	out := reflect.ValueOf(channel)
+	if out.Kind() != reflect.Chan || out.Type().ChanDir() != reflect.BothDir {
+		panic(errBadChannel)
+	}
+	fn := reflect.ValueOf(subscribe)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 || fn.Type().NumOut() != 1 {
+		panic(errBadSubscribe)
+	}
+	if out.Cap() == 0 {
+		panic(errBadBoundedCap)
+	}
+	in := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, out.Type().Elem()), out.Cap())
+	if !in.Type().AssignableTo(fn.Type().In(0)) {
+		panic(errBadSubscribe)
+	}
+	sub, ok := fn.Call([]reflect.Value{in})[0].Interface().(Subscription)
+	if !ok {
+		panic(errBadSubscribe)
+	}
+	b := &BoundedSubscription{
+		sub:     sub,
+		name:    name,
+		in:      in,
+		out:     out,
+		counter: metrics.GetOrRegisterCounter("event/dropped/"+name, nil),
+		quit:    make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// SubscribeBounded adds a bounded relay for channel to the feed, see the
+// package level SubscribeBounded.
+func (f *Feed) SubscribeBounded(name string, channel interface{}) *BoundedSubscription {
+	return SubscribeBounded(name, channel, f.Subscribe)
+}
+
+// loop forwards the subscribed events to the consumer until unsubscribed.
+func (b *BoundedSubscription) loop() {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: b.in},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(b.quit)},
+	}
+	for {
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 1 || !ok {
+			return
+		}
+		b.deliver(value)
+	}
+}
+
+// deliver queues an event for the consumer, dropping the oldest queued one
+// if there is no room left.
+func (b *BoundedSubscription) deliver(value reflect.Value) {
+	if b.out.TrySend(value) {
+		return
+	}
+	if _, ok := b.out.TryRecv(); ok {
+		b.drop()
+	}
+	if !b.out.TrySend(value) {
+		// The consumer can't have refilled its own channel, but be safe
+		b.drop()
+	}
+}
+
+// drop accounts a lost event and warns about it, rate limited.
+func (b *BoundedSubscription) drop() {
+	dropped := atomic.AddUint64(&b.dropped, 1)
+	b.counter.Inc(1)
+
+	if time.Since(b.lastWarn) >= boundedWarnInterval {
+		b.lastWarn = time.Now()
+		log.Warn("Event subscriber too slow, dropping oldest events", "name", b.name, "dropped", dropped, "queue", b.out.Cap())
+	}
+}
+
+// Dropped returns the number of events dropped so far.
+func (b *BoundedSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Unsubscribe stops the underlying subscription and the relay.
+func (b *BoundedSubscription) Unsubscribe() {
+	b.once.Do(func() {
+		b.sub.Unsubscribe()
+		close(b.quit)
+	})
+}
+
+// Err returns the error channel of the underlying subscription.
+func (b *BoundedSubscription) Err() <-chan error {
+	return b.sub.Err()
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until the relay queued the given number of events and
+// dropped the given number of older ones.
+func waitQueued(t *testing.T, sub *BoundedSubscription, ch chan int, queued int, dropped uint64) {
+	for deadline := time.Now().Add(time.Second); len(ch) != queued || sub.Dropped() != dropped; {
+		if time.Now().After(deadline) {
+			t.Fatalf("relay mismatch: queued %d, want %d; dropped %d, want %d", len(ch), queued, sub.Dropped(), dropped)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Tests that a stalled consumer keeps the newest events, in order, and that
+// the overflow is counted while the sender never blocks.
+func TestBoundedDropOldest(t *testing.T) {
+	tests := []struct {
+		size, sent int
+		want       []int
+	}{
+		{3, 2, []int{1, 2}},
+		{3, 3, []int{1, 2, 3}},
+		{3, 5, []int{3, 4, 5}},
+		{1, 4, []int{4}},
+	}
+	for i, tt := range tests {
+		var feed Feed
+		ch := make(chan int, tt.size)
+		sub := feed.SubscribeBounded("test", ch)
+
+		for n := 1; n <= tt.sent; n++ {
+			feed.Send(n)
+		}
+		waitQueued(t, sub, ch, len(tt.want), uint64(tt.sent-len(tt.want)))
+
+		var have []int
+		for len(ch) > 0 {
+			have = append(have, <-ch)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: events mismatch: have %v, want %v", i, have, tt.want)
+		}
+		sub.Unsubscribe()
+	}
+}
+
+// Tests that an unsubscribed relay detaches from the feed and delivers nothing.
+func TestBoundedUnsubscribe(t *testing.T) {
+	var feed Feed
+	ch := make(chan int, 2)
+	sub := feed.SubscribeBounded("test", ch)
+
+	feed.Send(1)
+	waitQueued(t, sub, ch, 1, 0)
+	<-ch
+
+	sub.Unsubscribe()
+	sub.Unsubscribe() // must not panic
+
+	if n := feed.Send(2); n != 0 {
+		t.Fatalf("send after unsubscribe reached %d subscribers", n)
+	}
+	select {
+	case ev := <-ch:
+		t.Fatalf("event %d delivered after unsubscribe", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, ok := <-sub.Err(); ok {
+		t.Fatal("error channel not closed after unsubscribe")
+	}
+}
+
+// Tests that invalid consumer channels and subscribe functions are rejected.
+func TestBoundedBadArguments(t *testing.T) {
+	var feed Feed
+	tests := []struct {
+		channel   interface{}
+		subscribe interface{}
+		err       error
+	}{
+		{make(chan int), feed.Subscribe, errBadBoundedCap},
+		{make(<-chan int, 1), feed.Subscribe, errBadChannel},
+		{1, feed.Subscribe, errBadChannel},
+		{make(chan int, 1), func() {}, errBadSubscribe},
+		{make(chan int, 1), func(chan<- string) Subscription { return nil }, errBadSubscribe},
+	}
+	for i, tt := range tests {
+		func() {
+			defer func() {
+				if err := recover(); err != tt.err {
+					t.Errorf("test %d: panic mismatch: have %v, want %v", i, err, tt.err)
+				}
+			}()
+			SubscribeBounded("test", tt.channel, tt.subscribe)
+		}()
+	}
+}
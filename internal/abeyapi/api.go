@@ -19,6 +19,7 @@ package abeyapi
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/abeychain/go-abey/accounts/abi"
@@ -50,10 +51,18 @@ import (
 
 const (
 	defaultGasPrice = 10 * params.GWei
+
+	// maxHeaderRange is the maximum number of headers served by a single range
+	// request, bounding the work of a call.
+	maxHeaderRange = 1024
 )
 
 var (
 	LocalTxMetrics = metrics.NewRegisteredMeter("abey/prop/local_tx/in", nil)
+
+	// errExecutionCanceled is returned if the client went away before an EVM
+	// call completed.
+	errExecutionCanceled = errors.New("execution aborted (request canceled)")
 )
 
 // PublicABEYAPI provides an API to access True related information.
@@ -186,6 +195,43 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCQueuedTransaction is a queued transaction with the time it has been waiting
+// in the pool.
+type RPCQueuedTransaction struct {
+	Hash     common.Hash    `json:"hash"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	GasPrice *hexutil.Big   `json:"gasPrice"`
+	Arrived  time.Time      `json:"arrived"`
+	Age      hexutil.Uint64 `json:"age"`   // Seconds spent in the pool
+	Stuck    string         `json:"stuck"` // Reason it will be swept for once expired
+}
+
+// Oldest returns the longest queued transactions of every account, oldest first
+// and at most count per account, ten by default.
+func (s *PublicTxPoolAPI) Oldest(count *int) map[string][]*RPCQueuedTransaction {
+	n := 10
+	if count != nil {
+		n = *count
+	}
+	now := time.Now()
+	oldest := make(map[string][]*RPCQueuedTransaction)
+	for account, ages := range s.b.TxPoolOldest(n) {
+		dump := make([]*RPCQueuedTransaction, 0, len(ages))
+		for _, age := range ages {
+			dump = append(dump, &RPCQueuedTransaction{
+				Hash:     age.Tx.Hash(),
+				Nonce:    hexutil.Uint64(age.Tx.Nonce()),
+				GasPrice: (*hexutil.Big)(age.Tx.GasPrice()),
+				Arrived:  age.Arrived,
+				Age:      hexutil.Uint64(now.Sub(age.Arrived) / time.Second),
+				Stuck:    age.Stuck,
+			})
+		}
+		oldest[account.Hex()] = dump
+	}
+	return oldest
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -593,11 +639,15 @@ func (s *PublicBlockChainAPI) SnailBlockNumber() hexutil.Uint64 {
 
 func (s *PublicBlockChainAPI) FruitNumber() hexutil.Uint64 {
 	block, _ := s.b.SnailBlockByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
-	if rpc.BlockNumber(block.NumberU64()) == rpc.EarliestBlockNumber {
+	if block == nil {
 		return 0
 	}
-	fruits := block.Fruits()
-	return hexutil.Uint64(fruits[len(fruits)-1].FastNumber().Uint64())
+	// The genesis snail block is the only block allowed to carry no fruits
+	maxFruitNumber := block.MaxFruitNumber()
+	if maxFruitNumber == nil {
+		return 0
+	}
+	return hexutil.Uint64(maxFruitNumber.Uint64())
 }
 
 // BlockNumber returns the block number of the chain head.
@@ -612,7 +662,7 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return (*hexutil.Big)(state.GetUnlockedBalance(address)), state.Error()
 }
@@ -623,7 +673,7 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 func (s *PublicBlockChainAPI) GetLockBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return (*hexutil.Big)(state.GetPOSLocked(address)), state.Error()
 }
@@ -640,7 +690,7 @@ func (s *PublicBlockChainAPI) Balance(ctx context.Context, address string, block
 
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return (*hexutil.Big)(state.GetUnlockedBalance(addr)), state.Error()
 }
@@ -651,7 +701,7 @@ func (s *PublicBlockChainAPI) Balance(ctx context.Context, address string, block
 func (s *PublicBlockChainAPI) GetTotalBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	return (*hexutil.Big)(state.GetBalance(address)), state.Error()
 }
@@ -683,6 +733,84 @@ func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash comm
 	return nil, err
 }
 
+// GetHeaderByNumber returns the requested fast header without loading the block
+// body. When blockNr is -1 the chain head is returned.
+func (s *PublicBlockChainAPI) GetHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header != nil {
+		return RPCMarshalHeader(header), nil
+	}
+	return nil, err
+}
+
+// GetHeadersByRange returns up to count consecutive fast headers starting at the
+// requested one, stopping early at the chain head.
+func (s *PublicBlockChainAPI) GetHeadersByRange(ctx context.Context, from rpc.BlockNumber, count hexutil.Uint64) ([]map[string]interface{}, error) {
+	if count > maxHeaderRange {
+		return nil, fmt.Errorf("header range too large: have %d, max %d", count, maxHeaderRange)
+	}
+	// Never look beyond the head, light clients would go to the network for it
+	head, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, err
+	}
+	headers := make([]map[string]interface{}, 0, count)
+	for i := uint64(0); i < uint64(count); i++ {
+		header, err := s.b.HeaderByNumber(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		headers = append(headers, RPCMarshalHeader(header))
+		if header.Number.Cmp(head.Number) >= 0 {
+			break
+		}
+		from = rpc.BlockNumber(header.Number.Int64() + 1)
+	}
+	return headers, nil
+}
+
+// GetSnailHeaderByNumber returns the requested snail header without loading the
+// fruits. When blockNr is -1 the chain head is returned.
+func (s *PublicBlockChainAPI) GetSnailHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	header, err := s.b.SnailHeaderByNumber(ctx, blockNr)
+	if header != nil {
+		return RPCMarshalSnailHeader(header), nil
+	}
+	return nil, err
+}
+
+// GetSnailHeadersByRange returns up to count consecutive snail headers starting
+// at the requested one, stopping early at the chain head.
+func (s *PublicBlockChainAPI) GetSnailHeadersByRange(ctx context.Context, from rpc.BlockNumber, count hexutil.Uint64) ([]map[string]interface{}, error) {
+	if count > maxHeaderRange {
+		return nil, fmt.Errorf("header range too large: have %d, max %d", count, maxHeaderRange)
+	}
+	// Never look beyond the head, light clients would go to the network for it
+	head, err := s.b.SnailHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, err
+	}
+	headers := make([]map[string]interface{}, 0, count)
+	for i := uint64(0); i < uint64(count); i++ {
+		header, err := s.b.SnailHeaderByNumber(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		headers = append(headers, RPCMarshalSnailHeader(header))
+		if header.Number.Cmp(head.Number) >= 0 {
+			break
+		}
+		from = rpc.BlockNumber(header.Number.Int64() + 1)
+	}
+	return headers, nil
+}
+
 // GetSnailBlockByNumber returns the requested snail block. When blockNr is -1 the chain head is returned.
 func (s *PublicBlockChainAPI) GetSnailBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, inclFruit bool) (map[string]interface{}, error) {
 	block, err := s.b.SnailBlockByNumber(ctx, blockNr)
@@ -731,8 +859,12 @@ func (s *PublicBlockChainAPI) GetBalanceChangeBySnailNumber(snailNumber rpc.Bloc
 
 func (s *PublicBlockChainAPI) GetFruitByNumber(ctx context.Context, fastblockNr rpc.BlockNumber, fullSigns bool) (map[string]interface{}, error) {
 	if fastblockNr == rpc.LatestBlockNumber {
-		fastHash := s.b.CurrentSnailBlock().Fruits()[len(s.b.CurrentSnailBlock().Fruits())-1].FastHash()
-		return s.GetFruitByHash(ctx, fastHash, fullSigns)
+		current := s.b.CurrentSnailBlock()
+		if current == nil || len(current.Fruits()) == 0 {
+			return nil, nil
+		}
+		fruits := current.Fruits()
+		return s.GetFruitByHash(ctx, fruits[len(fruits)-1].FastHash(), fullSigns)
 	}
 	block, err := s.b.BlockByNumber(ctx, fastblockNr)
 	if block != nil {
@@ -751,6 +883,37 @@ func (s *PublicBlockChainAPI) GetFruitByHash(ctx context.Context, blockHash comm
 	return nil, err
 }
 
+// GetFruitCoinbaseProof returns the coinbase proof carried by the fruit of the
+// given fast block: the signer recovered from it and whether it matches the
+// fruit's coinbase. Fruits without proof report no signer.
+func (s *PublicBlockChainAPI) GetFruitCoinbaseProof(ctx context.Context, fastblockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	block, err := s.b.BlockByNumber(ctx, fastblockNr)
+	if block == nil {
+		return nil, err
+	}
+	fruit, err := s.b.GetFruit(ctx, block.Hash())
+	if fruit == nil {
+		return nil, err
+	}
+	header := fruit.Header()
+	fields := map[string]interface{}{
+		"fruitHash": fruit.Hash(),
+		"miner":     header.Coinbase.StringToAbey(),
+		"signer":    nil,
+		"valid":     false,
+	}
+	signer, err := types.CoinbaseProofSigner(header)
+	if err == types.ErrNoCoinbaseProof {
+		return fields, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields["signer"] = signer.StringToAbey()
+	fields["valid"] = signer == header.Coinbase
+	return fields, nil
+}
+
 // GetUncleByBlockNumberAndIndex returns the uncle block for the given block hash and index. When fullTx is true
 // all transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
@@ -785,7 +948,7 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	code := state.GetCode(address)
 	return code, state.Error()
@@ -797,7 +960,7 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	res := state.GetState(address, common.HexToHash(key))
 	return res[:], state.Error()
@@ -850,7 +1013,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockHr
 
 	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockHr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	// Set sender address or use a default if none specified
 	addr := args.From
@@ -866,6 +1029,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockHr
 	if gas == 0 {
 		gas = math.MaxUint64 / 2
 	}
+	gasCap := s.b.RPCGasCap()
+	capped := gasCap != 0 && gas > gasCap
+	if capped {
+		log.Debug("Caller gas above allowance, capping", "requested", gas, "cap", gasCap)
+		gas = gasCap
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
@@ -885,7 +1054,8 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockHr
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
-	// Get a new instance of the EVM.
+	// Get a new instance of the EVM, bounding the state it may read.
+	vmCfg.StateReadLimit = s.b.RPCStateReadLimit()
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
 		return nil, err
@@ -904,10 +1074,21 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockHr
 	if err := vmError(); err != nil {
 		return nil, err
 	}
-	// If the timer caused an abort, return an appropriate error message
+	// If the timer or a client disconnect caused an abort, return an appropriate error message
 	if evm.Cancelled() {
+		if ctx.Err() == context.Canceled {
+			return nil, errExecutionCanceled
+		}
 		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
 	}
+	// If the read budget caused an abort, report the cap instead of the revert
+	if evm.StateReadLimitReached() {
+		return nil, fmt.Errorf("execution aborted (state read limit = %d)", vmCfg.StateReadLimit)
+	}
+	// If the call ran out of the capped gas, report the cap instead of the failure
+	if capped && result != nil && errors.Is(result.Err, vm.ErrOutOfGas) {
+		return nil, fmt.Errorf("gas required exceeds allowance (%d)", gasCap)
+	}
 	if err != nil {
 		return result, fmt.Errorf("err: %w (supplied gas %d)", err, msg.Gas())
 	}
@@ -917,7 +1098,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockHr
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockHr rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	result, err := s.doCall(ctx, args, blockHr, vm.Config{}, 5*time.Second)
+	result, err := s.doCall(ctx, args, blockHr, vm.Config{}, s.b.RPCEVMTimeout())
 	if err != nil {
 		return nil, err
 	}
@@ -950,13 +1131,18 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 		}
 		hi = block.GasLimit()
 	}
+	// Recap the highest gas allowance with the RPC gas cap
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		log.Debug("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
+		hi = gasCap
+	}
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
 	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = hexutil.Uint64(gas)
 		blockhr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-		result, err := s.doCall(ctx, args, blockhr, vm.Config{}, 0)
+		result, err := s.doCall(ctx, args, blockhr, vm.Config{}, s.b.RPCEVMTimeout())
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				return true, nil, nil // Special case, raise gas limit
@@ -1007,6 +1193,61 @@ func (s *PublicBlockChainAPI) GetCommittee(id rpc.BlockNumber) (map[string]inter
 	return detail, err
 }
 
+// GetCommitteeProof returns the committee members of a fast block together with
+// the switch blocks and snail election headers needed to verify them, starting
+// from the committee active at the checkpoint block. If omitted, the proof
+// chains back to the genesis committee.
+func (s *PublicBlockChainAPI) GetCommitteeProof(ctx context.Context, number rpc.BlockNumber, checkpoint *hexutil.Uint64) (*types.CommitteeProof, error) {
+	var from *big.Int
+	if checkpoint != nil {
+		from = new(big.Int).SetUint64(uint64(*checkpoint))
+	}
+	proof, err := s.b.GetCommitteeProof(ctx, number, from)
+	return proof, wrapError(err)
+}
+
+// VerifyPbftSign checks the given sign against the committee at its fast height
+// and returns the committee member who made it, together with the hash it signs.
+func (s *PublicBlockChainAPI) VerifyPbftSign(sign types.PbftSign) (map[string]interface{}, error) {
+	if sign.FastHeight == nil || len(sign.Sign) == 0 {
+		return nil, errors.New("incomplete sign")
+	}
+	member, err := s.b.VerifyPbftSign(&sign)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if member == nil {
+		return nil, fmt.Errorf("signer is not a committee member at height %v", sign.FastHeight)
+	}
+	return map[string]interface{}{
+		"signHash":      sign.HashWithNoSign(),
+		"coinbase":      member.Coinbase,
+		"committeebase": member.CommitteeBase,
+		"PKey":          hex.EncodeToString(member.Publickey),
+		"flag":          member.Flag,
+		"type":          member.MType,
+	}, nil
+}
+
+// GetElectionParams returns the protocol constants driving committee elections
+// and the committee id staking based elections activate at.
+func (s *PublicBlockChainAPI) GetElectionParams() map[string]interface{} {
+	var tip8 *hexutil.Big
+	if config := s.b.ChainConfig(); config.TIP8 != nil && config.TIP8.CID != nil {
+		tip8 = (*hexutil.Big)(config.TIP8.CID)
+	}
+	return map[string]interface{}{
+		"electionPeriodNumber":     (*hexutil.Big)(params.ElectionPeriodNumber),
+		"snailConfirmInterval":     (*hexutil.Big)(params.SnailConfirmInterval),
+		"electionFruitsThreshold":  hexutil.Uint64(params.ElectionFruitsThreshold),
+		"electionSwitchoverNumber": (*hexutil.Big)(params.ElectionSwitchoverNumber),
+		"minimumCommitteeNumber":   hexutil.Uint64(params.MinimumCommitteeNumber),
+		"proposalCommitteeNumber":  hexutil.Uint64(params.ProposalCommitteeNumber),
+		"maximumCommitteeNumber":   (*hexutil.Big)(params.MaximumCommitteeNumber),
+		"tip8CommitteeId":          tip8,
+	}
+}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
@@ -1068,14 +1309,12 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	return formatted
 }
 
-// RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
-// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
-// transaction hashes.
-func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
-	head := b.Header() // copies the header once
-	fields := map[string]interface{}{
+// RPCMarshalHeader converts the given fast header to the RPC output, without
+// any of the fields requiring the block body.
+func RPCMarshalHeader(head *types.Header) map[string]interface{} {
+	return map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
-		"hash":             b.Hash(),
+		"hash":             head.Hash(),
 		"parentHash":       head.ParentHash,
 		"committeeRoot":    head.CommitteeHash,
 		"miner":            head.Proposer.StringToAbey(),
@@ -1084,13 +1323,20 @@ func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]inter
 		"snailHash":        head.SnailHash,
 		"snailNumber":      (*hexutil.Big)(head.SnailNumber),
 		"extraData":        hexutil.Bytes(head.Extra),
-		"size":             hexutil.Uint64(b.Size()),
 		"gasLimit":         hexutil.Uint64(head.GasLimit),
 		"gasUsed":          hexutil.Uint64(head.GasUsed),
 		"timestamp":        (*hexutil.Big)(head.Time),
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+}
+
+// RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
+// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
+// transaction hashes.
+func RPCMarshalBlock(b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields := RPCMarshalHeader(b.Header())
+	fields["size"] = hexutil.Uint64(b.Size())
 
 	formatSign := func(sign *types.PbftSign) (map[string]interface{}, error) {
 		signmap := map[string]interface{}{
@@ -1166,22 +1412,41 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 	return fields, err
 }
 
+// SnailRPCVersion is the version of the RPC representation of snail headers,
+// blocks and fruits. It is bumped whenever fields are renamed or removed, so
+// clients can rely on the field names of a given version.
+const SnailRPCVersion = 1
+
+// RPCMarshalSnailHeader converts the given snail header to the RPC output,
+// without any of the fields requiring the block body. The field names match
+// the JSON encoding of types.SnailHeader.
+func RPCMarshalSnailHeader(head *types.SnailHeader) map[string]interface{} {
+	return map[string]interface{}{
+		"version":         hexutil.Uint(SnailRPCVersion),
+		"number":          (*hexutil.Big)(head.Number),
+		"hash":            head.Hash(),
+		"parentHash":      head.ParentHash,
+		"fruitsHash":      head.FruitsHash,
+		"pointerHash":     head.PointerHash,
+		"pointerNumber":   (*hexutil.Big)(head.PointerNumber),
+		"fastHash":        head.FastHash,
+		"fastNumber":      (*hexutil.Big)(head.FastNumber),
+		"signHash":        head.SignHash,
+		"nonce":           head.Nonce,
+		"mixHash":         head.MixDigest,
+		"miner":           head.Coinbase.StringToAbey(),
+		"publicKey":       hexutil.Bytes(head.Publickey),
+		"difficulty":      (*hexutil.Big)(head.Difficulty),
+		"fruitDifficulty": (*hexutil.Big)(head.FruitDifficulty),
+		"extraData":       hexutil.Bytes(head.Extra),
+		"timestamp":       (*hexutil.Big)(head.Time),
+	}
+}
+
 // RPCMarshalSnailBlock converts the given snail block to the RPC output.
 func RPCMarshalSnailBlock(b *types.SnailBlock, inclFruit bool) (map[string]interface{}, error) {
-	head := b.Header() // copies the header once
-	fields := map[string]interface{}{
-		"number":     (*hexutil.Big)(head.Number),
-		"hash":       b.Hash(),
-		"parentHash": head.ParentHash,
-		"fruitsHash": head.FruitsHash,
-		"nonce":      head.Nonce,
-		"mixHash":    head.MixDigest,
-		"miner":      head.Coinbase.StringToAbey(),
-		"difficulty": (*hexutil.Big)(head.Difficulty),
-		"extraData":  hexutil.Bytes(head.Extra),
-		"size":       hexutil.Uint64(b.Size()),
-		"timestamp":  (*hexutil.Big)(head.Time),
-	}
+	fields := RPCMarshalSnailHeader(b.Header())
+	fields["size"] = hexutil.Uint64(b.Size())
 
 	fs := b.Fruits()
 	if inclFruit {
@@ -1213,24 +1478,15 @@ func RPCMarshalSnailBlock(b *types.SnailBlock, inclFruit bool) (map[string]inter
 	return fields, nil
 }
 
+// RPCMarshalFruit converts the given fruit to the RPC output. If fullSigns is
+// true the committee signs are returned, otherwise only their count.
 func RPCMarshalFruit(fruit *types.SnailBlock, fullSigns bool) (map[string]interface{}, error) {
-	head := fruit.Header() // copies the header once
-	fields := map[string]interface{}{
-		"number":          (*hexutil.Big)(head.Number),
-		"hash":            fruit.Hash(),
-		"fastHash":        head.FastHash,
-		"fastNumber":      (*hexutil.Big)(head.FastNumber),
-		"nonce":           head.Nonce,
-		"mixHash":         head.MixDigest,
-		"miner":           head.Coinbase.StringToAbey(),
-		"publicKey":       hexutil.Bytes(head.Publickey),
-		"fruitDifficulty": (*hexutil.Big)(head.FruitDifficulty),
-		"extraData":       hexutil.Bytes(head.Extra),
-		"size":            hexutil.Uint64(fruit.Size()),
-		"timestamp":       (*hexutil.Big)(head.Time),
-		"PointerHash":     head.PointerHash,
-		"pointerNumber":   (*hexutil.Big)(head.PointerNumber),
-	}
+	fields := RPCMarshalSnailHeader(fruit.Header())
+	fields["size"] = hexutil.Uint64(fruit.Size())
+	// Fruits were served with a capitalized pointer hash key before the header
+	// fields were unified, keep it for existing clients
+	fields["PointerHash"] = fruit.Header().PointerHash
+
 	signs := fruit.Signs()
 	if fullSigns {
 		pbftSigns := make([]interface{}, len(signs))
@@ -1614,7 +1870,7 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
 	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	nonce := state.GetNonce(address)
 	return (*hexutil.Uint64)(&nonce), state.Error()
@@ -1655,14 +1911,10 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if tx == nil {
 		return nil, nil
 	}
-	receipts, err := s.b.GetReceipts(ctx, blockHash)
-	if err != nil {
+	receipt, err := s.b.GetReceipt(ctx, blockHash, index)
+	if receipt == nil {
 		return nil, err
 	}
-	if len(receipts) <= int(index) {
-		return nil, nil
-	}
-	receipt := receipts[index]
 
 	var signer types.Signer = types.NewTIP1Signer(tx.ChainId())
 	from, _ := types.Sender(signer, tx)
@@ -1696,6 +1948,76 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	return fields, nil
 }
 
+// proofList collects the trie nodes of a Merkle proof.
+type proofList []hexutil.Bytes
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// GetTransactionProof returns the Merkle proof of the transaction in the transaction
+// trie of its fast block and, once the block's fruit is packed, the proof of the
+// fruit in the fruits trie of its snail block.
+func (s *PublicTransactionPoolAPI) GetTransactionProof(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
+	if tx == nil {
+		return nil, nil
+	}
+	block, err := s.b.GetBlock(ctx, blockHash)
+	if block == nil {
+		return nil, err
+	}
+	var txProof proofList
+	if err := types.DeriveShaProof(block.Transactions(), int(index), &txProof); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{
+		"transactionHash":  hash,
+		"transactionIndex": hexutil.Uint64(index),
+		"blockHash":        blockHash,
+		"blockNumber":      hexutil.Uint64(blockNumber),
+		"transactionsRoot": block.TxHash(),
+		"proof":            txProof,
+		"fruit":            nil,
+	}
+	snailBlock, fruitIndex, err := s.b.GetSnailBlockByFastHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if snailBlock == nil || int(fruitIndex) >= len(snailBlock.Fruits()) {
+		return fields, nil
+	}
+	// The fruits trie holds fruit headers since TIP5 and whole fruits before it
+	var (
+		fruits = snailBlock.Fruits()
+		list   types.DerivableList
+	)
+	if s.b.ChainConfig().IsTIP5(snailBlock.Number()) {
+		headers := make(types.FruitsHeaders, len(fruits))
+		for i, fruit := range fruits {
+			headers[i] = fruit.Header()
+		}
+		list = headers
+	} else {
+		list = types.Fruits(fruits)
+	}
+	var fruitProof proofList
+	if err := types.DeriveShaProof(list, int(fruitIndex), &fruitProof); err != nil {
+		return nil, err
+	}
+	fields["fruit"] = map[string]interface{}{
+		"hash":        fruits[fruitIndex].Hash(),
+		"fruitIndex":  hexutil.Uint64(fruitIndex),
+		"fruit":       hexutil.Bytes(list.GetRlp(int(fruitIndex))),
+		"snailHash":   snailBlock.Hash(),
+		"snailNumber": (*hexutil.Big)(snailBlock.Number()),
+		"fruitsRoot":  snailBlock.FruitsHash(),
+		"proof":       fruitProof,
+	}
+	return fields, nil
+}
+
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 	// Look up the wallet containing the requested signer
@@ -1808,19 +2130,19 @@ func (args *SendTxArgs) toRawTransaction() *types.RawTransaction {
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	LocalTxMetrics.Mark(1)
 	if err := b.SendTx(ctx, tx); err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, wrapError(err)
 	}
 	signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
 	//print message
 	from, err := types.Sender(signer, tx)
 	if err != nil {
 		log.Error("submitTransaction error", "from", from.String())
-		return common.Hash{}, err
+		return common.Hash{}, wrapError(err)
 	}
 	payment, err := types.Payer(signer, tx)
 	if err != nil {
 		log.Error("submitTransaction signature error", "payemnt", payment.String())
-		return common.Hash{}, err
+		return common.Hash{}, wrapError(err)
 	}
 	//fmt.Printf("submitTransaction:from=%v,payemnt=%v\n", from.String(), payment.String())
 	//end
@@ -1828,7 +2150,7 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
 		from, err := types.Sender(signer, tx)
 		if err != nil {
-			return common.Hash{}, err
+			return common.Hash{}, wrapError(err)
 		}
 		addr := crypto.CreateAddress(from, tx.Nonce())
 		log.Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "contract", addr.Hex())
@@ -2209,13 +2531,13 @@ func NewPublicImpawnAPI(b Backend) *PublicImpawnAPI {
 func (s *PublicImpawnAPI) GetAllStakingAccount(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return impawn.GetAllStakingAccountRPC(uint64(blockNr)), nil
@@ -2225,13 +2547,13 @@ func (s *PublicImpawnAPI) GetAllStakingAccount(ctx context.Context, blockNr rpc.
 func (s *PublicImpawnAPI) GetStakingAsset(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) ([]vm.StakingAsset, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return impawn.GetStakingAssetRPC(addr), nil
@@ -2241,13 +2563,13 @@ func (s *PublicImpawnAPI) GetStakingAsset(ctx context.Context, addr common.Addre
 func (s *PublicImpawnAPI) GetLockedAsset(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) ([]vm.LockedAsset, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return impawn.GetLockedAssetRPC(addr, uint64(blockNr)), nil
@@ -2257,13 +2579,13 @@ func (s *PublicImpawnAPI) GetLockedAsset(ctx context.Context, addr common.Addres
 func (s *PublicImpawnAPI) GetAllCancelableAsset(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) ([]vm.CancelableAsset, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return impawn.GetAllCancelableAssetRPC(addr), nil
@@ -2273,13 +2595,13 @@ func (s *PublicImpawnAPI) GetAllCancelableAsset(ctx context.Context, addr common
 func (s *PublicImpawnAPI) GetStakingAccount(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return impawn.GetStakingAccountRPC(uint64(blockNr), addr), nil
@@ -2287,18 +2609,77 @@ func (s *PublicImpawnAPI) GetStakingAccount(ctx context.Context, addr common.Add
 func (s *PublicImpawnAPI) GetImpawnSummay(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	impawn := vm.NewImpawnImpl()
 	err = impawn.Load(state, types.StakingAddress)
 	if err != nil {
 		log.Error("Staking load error", "error", err)
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return types.ToJSON(impawn.Summay()), nil
 }
 
+// GetDelegateNonce returns the nonce the next signed delegation of an account must carry.
+func (s *PublicImpawnAPI) GetDelegateNonce(ctx context.Context, addr common.Address, blockNr rpc.BlockNumber) (hexutil.Uint64, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return 0, wrapError(err)
+	}
+	return hexutil.Uint64(vm.DelegateNonce(state, addr)), nil
+}
+
+// DelegationTypedData returns the EIP-712 typed data, and its digest, a delegator
+// signs off-chain to let a relayer delegate value to the holder on its behalf
+// until the deadline (unix seconds). The approval carries the delegator's
+// current delegation nonce, it's void once another approval is submitted.
+func (s *PublicImpawnAPI) DelegationTypedData(ctx context.Context, delegator, holder common.Address, value *hexutil.Big, deadline hexutil.Uint64) (map[string]interface{}, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, wrapError(err)
+	}
+	var (
+		chainID = s.b.ChainConfig().ChainID
+		nonce   = vm.DelegateNonce(state, delegator)
+		amount  = (*big.Int)(value)
+		until   = new(big.Int).SetUint64(uint64(deadline))
+	)
+	field := func(name, kind string) map[string]string {
+		return map[string]string{"name": name, "type": kind}
+	}
+	typedData := map[string]interface{}{
+		"types": map[string]interface{}{
+			"EIP712Domain": []map[string]string{
+				field("name", "string"), field("version", "string"),
+				field("chainId", "uint256"), field("verifyingContract", "address"),
+			},
+			"Delegation": []map[string]string{
+				field("delegator", "address"), field("holder", "address"),
+				field("value", "uint256"), field("nonce", "uint256"), field("deadline", "uint256"),
+			},
+		},
+		"primaryType": "Delegation",
+		"domain": map[string]interface{}{
+			"name":              "AbeyStaking",
+			"version":           "1",
+			"chainId":           (*hexutil.Big)(chainID),
+			"verifyingContract": types.StakingAddress,
+		},
+		"message": map[string]interface{}{
+			"delegator": delegator,
+			"holder":    holder,
+			"value":     value,
+			"nonce":     hexutil.Uint64(nonce),
+			"deadline":  deadline,
+		},
+	}
+	return map[string]interface{}{
+		"typedData": typedData,
+		"hash":      vm.DelegationHash(chainID, delegator, holder, amount, nonce, until),
+	}, nil
+}
+
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
 func NewPublicTransactionPoolAPI2(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI2 {
 	return &PublicTransactionPoolAPI2{b, nonceLock}
@@ -2340,14 +2721,10 @@ func (s *PublicTransactionPoolAPI2) GetTransactionReceipt(ctx context.Context, h
 	if tx == nil {
 		return nil, nil
 	}
-	receipts, err := s.b.GetReceipts(ctx, blockHash)
-	if err != nil {
+	receipt, err := s.b.GetReceipt(ctx, blockHash, index)
+	if receipt == nil {
 		return nil, err
 	}
-	if len(receipts) <= int(index) {
-		return nil, nil
-	}
-	receipt := receipts[index]
 
 	var signer types.Signer = types.NewTIP1Signer(tx.ChainId())
 	from, _ := types.Sender(signer, tx)
@@ -2419,7 +2796,7 @@ func (s *PublicTransactionPoolAPI2) GetRawTransactionByBlockHashAndIndex(ctx con
 func (s *PublicTransactionPoolAPI2) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	nonce := state.GetNonce(address)
 	return (*hexutil.Uint64)(&nonce), state.Error()
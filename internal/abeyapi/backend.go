@@ -20,6 +20,7 @@ package abeyapi
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/common"
@@ -44,6 +45,9 @@ type Backend interface {
 	ChainDb() abeydb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
+	RPCEVMTimeout() time.Duration // global timeout for abey_call and estimateGas over RPC: 0 is unlimited
+	RPCGasCap() uint64            // global gas cap for abey_call and estimateGas over RPC: 0 is unlimited
+	RPCStateReadLimit() uint64    // global state read budget for abey_call and estimateGas over RPC: 0 is unlimited
 
 	// BlockChain API
 	SetHead(number uint64)
@@ -53,12 +57,14 @@ type Backend interface {
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	SnailBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.SnailBlock, error)
 	GetFruit(ctx context.Context, fastblockHash common.Hash) (*types.SnailBlock, error)
+	GetSnailBlockByFastHash(ctx context.Context, fastblockHash common.Hash) (*types.SnailBlock, uint64, error)
 	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
 	StateAndHeaderByHash(ctx context.Context, hash common.Hash) (*state.StateDB, *types.Header, error)
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetSnailBlock(ctx context.Context, blockHash common.Hash) (*types.SnailBlock, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	GetReceipt(ctx context.Context, blockHash common.Hash, index uint64) (*types.Receipt, error)
 	GetTd(blockHash common.Hash) *big.Int
 	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error)
 	SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription
@@ -67,6 +73,8 @@ type Backend interface {
 	GetReward(number int64) *types.BlockReward
 	GetCommittee(id rpc.BlockNumber) (map[string]interface{}, error)
 	GetCurrentCommitteeNumber() *big.Int
+	GetCommitteeProof(ctx context.Context, number rpc.BlockNumber, checkpoint *big.Int) (*types.CommitteeProof, error)
+	VerifyPbftSign(sign *types.PbftSign) (*types.CommitteeMember, error)
 
 	GetStateChangeByFastNumber(fastNumber rpc.BlockNumber) *types.BlockBalance
 	GetBalanceChangeBySnailNumber(snailNumber rpc.BlockNumber) *types.BalanceChangeContent
@@ -81,6 +89,7 @@ type Backend interface {
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	TxPoolOldest(n int) map[common.Address][]*core.TxAge
 	SubscribeNewTxsEvent(chan<- types.NewTxsEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
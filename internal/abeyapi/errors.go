@@ -0,0 +1,177 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package abeyapi
+
+import (
+	"github.com/abeychain/go-abey/consensus"
+	"github.com/abeychain/go-abey/consensus/election"
+	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/core/vm"
+	"github.com/abeychain/go-abey/light"
+)
+
+// Error codes returned in the data field of JSON-RPC errors. The codes are
+// stable across releases, wallets should branch on them instead of messages.
+const (
+	// Sync errors, the node lacks the data to serve the request
+	ErrCodeNoPeers              = 1001
+	ErrCodeNoTrustedCht         = 1002
+	ErrCodeNoTrustedBloomTrie   = 1003
+	ErrCodeNoTrustedSnailCht    = 1004
+	ErrCodeHeaderNotFound       = 1005
+	ErrCodeStateFallingBack     = 1006
+	ErrCodeNumberExceedsAllowed = 1007
+
+	// Transaction pool rejections
+	ErrCodeInvalidSender          = 2001
+	ErrCodeInvalidPayer           = 2002
+	ErrCodeNonceTooLow            = 2003
+	ErrCodeNonceTooHigh           = 2004
+	ErrCodeUnderpriced            = 2005
+	ErrCodeReplaceUnderpriced     = 2006
+	ErrCodeInsufficientFunds      = 2007
+	ErrCodeInsufficientPayerFunds = 2008
+	ErrCodeInsufficientValue      = 2009
+	ErrCodeIntrinsicGas           = 2010
+	ErrCodeGasLimit               = 2011
+	ErrCodeNegativeValue          = 2012
+	ErrCodeNegativeFee            = 2013
+	ErrCodeOversizedData          = 2014
+	ErrCodeInvalidSignature       = 2015
+	ErrCodeInvalidChainId         = 2016
+	ErrCodeInvalidPayerSignature  = 2017
+
+	// Consensus rejections
+	ErrCodeUnknownAncestor   = 3001
+	ErrCodeFutureBlock       = 3002
+	ErrCodeInvalidNumber     = 3003
+	ErrCodeInvalidSign       = 3004
+	ErrCodeInvalidSwitchInfo = 3005
+	ErrCodeUnknownFast       = 3006
+	ErrCodeKnownBlock        = 3007
+	ErrCodeCommittee         = 3008
+	ErrCodeInvalidMember     = 3009
+	ErrCodeProofTooLong      = 3010
+
+	// Staking failures
+	ErrCodeStakingInvalidInput   = 4001
+	ErrCodeStakingInsufficient   = 4002
+	ErrCodeStakingInvalidParam   = 4003
+	ErrCodeStakingInvalidEpoch   = 4004
+	ErrCodeStakingNotFoundEpoch  = 4005
+	ErrCodeStakingInvalidAccount = 4006
+	ErrCodeStakingNotStaking     = 4007
+	ErrCodeStakingNotDelegation  = 4008
+	ErrCodeStakingAmountOver     = 4009
+	ErrCodeStakingDelegateSelf   = 4010
+	ErrCodeStakingRedeemAmount   = 4011
+	ErrCodeStakingForbidden      = 4012
+	ErrCodeStakingRepeatPk       = 4013
+)
+
+// errorCode is the machine readable identity of a known error.
+type errorCode struct {
+	code   int
+	reason string
+}
+
+// errorCodes maps the errors surfacing through the API to their codes.
+var errorCodes = map[error]errorCode{
+	light.ErrNoPeers:            {ErrCodeNoPeers, "NO_PEERS"},
+	light.ErrNoTrustedCht:       {ErrCodeNoTrustedCht, "NO_TRUSTED_CHT"},
+	light.ErrNoTrustedBloomTrie: {ErrCodeNoTrustedBloomTrie, "NO_TRUSTED_BLOOM_TRIE"},
+	light.ErrNoTrustedSnailCht:  {ErrCodeNoTrustedSnailCht, "NO_TRUSTED_SNAIL_CHT"},
+	light.ErrNoHeader:           {ErrCodeHeaderNotFound, "HEADER_NOT_FOUND"},
+	core.ErrIsFallback:          {ErrCodeStateFallingBack, "STATE_FALLING_BACK"},
+	core.ErrExceedNumber:        {ErrCodeNumberExceedsAllowed, "NUMBER_EXCEEDS_ALLOWED"},
+
+	core.ErrInvalidSender:              {ErrCodeInvalidSender, "INVALID_SENDER"},
+	core.ErrInvalidPayer:               {ErrCodeInvalidPayer, "INVALID_PAYER"},
+	core.ErrNonceTooLow:                {ErrCodeNonceTooLow, "NONCE_TOO_LOW"},
+	core.ErrNonceTooHigh:               {ErrCodeNonceTooHigh, "NONCE_TOO_HIGH"},
+	core.ErrUnderpriced:                {ErrCodeUnderpriced, "UNDERPRICED"},
+	core.ErrReplaceUnderpriced:         {ErrCodeReplaceUnderpriced, "REPLACE_UNDERPRICED"},
+	core.ErrInsufficientFunds:          {ErrCodeInsufficientFunds, "INSUFFICIENT_FUNDS"},
+	core.ErrInsufficientFundsForPayer:  {ErrCodeInsufficientPayerFunds, "INSUFFICIENT_PAYER_FUNDS"},
+	core.ErrInsufficientFundsForSender: {ErrCodeInsufficientValue, "INSUFFICIENT_VALUE"},
+	core.ErrIntrinsicGas:               {ErrCodeIntrinsicGas, "INTRINSIC_GAS"},
+	core.ErrGasLimit:                   {ErrCodeGasLimit, "GAS_LIMIT"},
+	core.ErrNegativeValue:              {ErrCodeNegativeValue, "NEGATIVE_VALUE"},
+	core.ErrNegativeFee:                {ErrCodeNegativeFee, "NEGATIVE_FEE"},
+	core.ErrOversizedData:              {ErrCodeOversizedData, "OVERSIZED_DATA"},
+	types.ErrInvalidSig:                {ErrCodeInvalidSignature, "INVALID_SIGNATURE"},
+	types.ErrInvalidChainId:            {ErrCodeInvalidChainId, "INVALID_CHAIN_ID"},
+	types.ErrPayersign:                 {ErrCodeInvalidPayerSignature, "INVALID_PAYER_SIGNATURE"},
+
+	consensus.ErrUnknownAncestor:   {ErrCodeUnknownAncestor, "UNKNOWN_ANCESTOR"},
+	consensus.ErrFutureBlock:       {ErrCodeFutureBlock, "FUTURE_BLOCK"},
+	consensus.ErrInvalidNumber:     {ErrCodeInvalidNumber, "INVALID_NUMBER"},
+	consensus.ErrInvalidSign:       {ErrCodeInvalidSign, "INVALID_SIGN"},
+	consensus.ErrInvalidSwitchInfo: {ErrCodeInvalidSwitchInfo, "INVALID_SWITCH_INFO"},
+	consensus.ErrUnknownFast:       {ErrCodeUnknownFast, "UNKNOWN_FAST"},
+	core.ErrKnownBlock:             {ErrCodeKnownBlock, "KNOWN_BLOCK"},
+	election.ErrCommittee:          {ErrCodeCommittee, "COMMITTEE_NOT_FOUND"},
+	election.ErrInvalidMember:      {ErrCodeInvalidMember, "INVALID_MEMBER"},
+	election.ErrProofTooLong:       {ErrCodeProofTooLong, "PROOF_TOO_LONG"},
+
+	vm.ErrStakingInvalidInput:        {ErrCodeStakingInvalidInput, "STAKING_INVALID_INPUT"},
+	vm.ErrStakingInsufficientBalance: {ErrCodeStakingInsufficient, "STAKING_INSUFFICIENT_BALANCE"},
+	types.ErrInvalidParam:            {ErrCodeStakingInvalidParam, "STAKING_INVALID_PARAM"},
+	types.ErrInvalidEpochInfo:        {ErrCodeStakingInvalidEpoch, "STAKING_INVALID_EPOCH"},
+	types.ErrNotFoundEpoch:           {ErrCodeStakingNotFoundEpoch, "STAKING_EPOCH_NOT_FOUND"},
+	types.ErrInvalidStaking:          {ErrCodeStakingInvalidAccount, "STAKING_INVALID_ACCOUNT"},
+	types.ErrNotStaking:              {ErrCodeStakingNotStaking, "STAKING_NOT_STAKING"},
+	types.ErrNotDelegation:           {ErrCodeStakingNotDelegation, "STAKING_NOT_DELEGATION"},
+	types.ErrAmountOver:              {ErrCodeStakingAmountOver, "STAKING_AMOUNT_OVER"},
+	types.ErrDelegationSelf:          {ErrCodeStakingDelegateSelf, "STAKING_DELEGATE_SELF"},
+	types.ErrRedeemAmount:            {ErrCodeStakingRedeemAmount, "STAKING_REDEEM_AMOUNT"},
+	types.ErrForbidAddress:           {ErrCodeStakingForbidden, "STAKING_FORBIDDEN_ADDRESS"},
+	types.ErrRepeatPk:                {ErrCodeStakingRepeatPk, "STAKING_REPEAT_PK"},
+}
+
+// codedError is an API error carrying a stable code in its JSON-RPC data.
+type codedError struct {
+	error
+	code errorCode
+}
+
+// ErrorCode returns the JSON-RPC server error code, the stable code is kept in
+// the error data so generic clients keep working.
+func (e *codedError) ErrorCode() int {
+	return -32000
+}
+
+// ErrorData returns the stable code and reason of the error.
+func (e *codedError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"code":   e.code.code,
+		"reason": e.code.reason,
+	}
+}
+
+// wrapError attaches the stable error code to a known error, other errors are
+// returned unchanged.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if code, ok := errorCodes[err]; ok {
+		return &codedError{error: err, code: code}
+	}
+	return err
+}
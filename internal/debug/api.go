@@ -49,6 +49,29 @@ type HandlerT struct {
 	cpuFile   string
 	traceW    io.WriteCloser
 	traceFile string
+	cache     *CacheAllocation
+}
+
+// CacheAllocation describes how the memory allowance of the node was derived
+// from the system resources and split between the internal caches. Memory
+// sizes are in bytes, cache sizes in megabytes.
+type CacheAllocation struct {
+	SystemMemory    uint64 `json:"systemMemory"`
+	AvailableMemory uint64 `json:"availableMemory"`
+	CgroupLimit     uint64 `json:"cgroupLimit,omitempty"`
+	Auto            bool   `json:"auto"`
+	Allowance       int    `json:"allowance"`
+	Database        int    `json:"database"`
+	Trie            int    `json:"trie"`
+	Downloader      int    `json:"downloader"`
+}
+
+// SetCacheAllocation records the final cache allocation of the node so it can
+// be queried later through the debug API.
+func SetCacheAllocation(alloc CacheAllocation) {
+	Handler.mu.Lock()
+	defer Handler.mu.Unlock()
+	Handler.cache = &alloc
 }
 
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
@@ -76,6 +99,14 @@ func (*HandlerT) MemStats() *runtime.MemStats {
 	return s
 }
 
+// CacheAllocation returns the memory allowance and cache split the node was
+// started with, or nil if it was not recorded.
+func (h *HandlerT) CacheAllocation() *CacheAllocation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.cache
+}
+
 // GcStats returns GC statistics.
 func (*HandlerT) GcStats() *debug.GCStats {
 	s := new(debug.GCStats)
@@ -23,6 +23,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/fjl/memsize/memsizeui"
 	colorable "github.com/mattn/go-colorable"
@@ -55,6 +56,29 @@ var (
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
 	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "Write logs to the given file as well, rotating it by size and age",
+	}
+	logMaxSizeFlag = cli.IntFlag{
+		Name:  "log.maxsize",
+		Usage: "Megabytes after which the log file is rotated (0 = unlimited)",
+		Value: 100,
+	}
+	logMaxAgeFlag = cli.DurationFlag{
+		Name:  "log.maxage",
+		Usage: "Age after which the log file is rotated (0 = unlimited)",
+		Value: 24 * time.Hour,
+	}
+	logMaxBackupsFlag = cli.IntFlag{
+		Name:  "log.maxbackups",
+		Usage: "Number of rotated log files retained (0 = all)",
+		Value: 30,
+	}
+	logCompressFlag = cli.BoolFlag{
+		Name:  "log.compress",
+		Usage: "Compress rotated log files with gzip",
+	}
 	pprofFlag = cli.BoolFlag{
 		Name:  "pprof",
 		Usage: "Enable the pprof HTTP server",
@@ -91,6 +115,7 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	logFileFlag, logMaxSizeFlag, logMaxAgeFlag, logMaxBackupsFlag, logCompressFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -98,6 +123,7 @@ var Flags = []cli.Flag{
 var (
 	ostream log.Handler
 	glogger *log.GlogHandler
+	logfile *log.RotatingWriter // Rotated log file, nil if file logging is disabled
 )
 
 func init() {
@@ -115,6 +141,7 @@ func init() {
 func Setup(ctx *cli.Context, logdir string) error {
 	// logging
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
+	handlers := []log.Handler{ostream}
 	if logdir != "" {
 		rfh, err := log.RotatingFileHandler(
 			logdir,
@@ -124,7 +151,24 @@ func Setup(ctx *cli.Context, logdir string) error {
 		if err != nil {
 			return err
 		}
-		glogger.SetHandler(log.MultiHandler(ostream, rfh))
+		handlers = append(handlers, rfh)
+	}
+	if path := ctx.GlobalString(logFileFlag.Name); path != "" {
+		writer, err := log.NewRotatingWriter(
+			path,
+			int64(ctx.GlobalInt(logMaxSizeFlag.Name))*1024*1024,
+			ctx.GlobalDuration(logMaxAgeFlag.Name),
+			ctx.GlobalInt(logMaxBackupsFlag.Name),
+			ctx.GlobalBool(logCompressFlag.Name),
+		)
+		if err != nil {
+			return err
+		}
+		logfile = writer
+		handlers = append(handlers, log.StreamHandler(writer, log.TerminalFormat(false)))
+	}
+	if len(handlers) > 1 {
+		glogger.SetHandler(log.MultiHandler(handlers...))
 	}
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
@@ -171,4 +215,7 @@ func StartPProf(address string) {
 func Exit() {
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
+	if logfile != nil {
+		logfile.Close()
+	}
 }
@@ -0,0 +1,116 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorerkit
+
+import (
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// FastBlock is the document of a fast block, joined with its receipts, its
+// committee and the snail chain data referring to it.
+type FastBlock struct {
+	Number     uint64         `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Time       uint64         `json:"timestamp"`
+	Proposer   common.Address `json:"proposer"`
+	GasUsed    uint64         `json:"gasUsed"`
+	GasLimit   uint64         `json:"gasLimit"`
+	Size       uint64         `json:"size"`
+
+	Transactions []*Transaction `json:"transactions"`
+
+	Committee []*Member        `json:"committee"` // Members in charge of the block
+	Signers   []common.Address `json:"signers"`   // Committee keys of the members that signed it
+
+	Fruit         *Inclusion `json:"fruit"`         // Snail block the fruit of the block was mined into, nil if not yet
+	RewardedSnail *SnailRef  `json:"rewardedSnail"` // Snail block whose rewards the block pays out, nil if none
+}
+
+// Transaction is the document of a transaction joined with its receipt.
+type Transaction struct {
+	Hash            common.Hash     `json:"hash"`
+	Index           uint64          `json:"index"`
+	From            common.Address  `json:"from"`
+	To              *common.Address `json:"to"`
+	Payer           *common.Address `json:"payer"` // Sponsor paying the gas, nil if the sender pays
+	Value           *big.Int        `json:"value"`
+	Fee             *big.Int        `json:"fee"`
+	Nonce           uint64          `json:"nonce"`
+	Gas             uint64          `json:"gas"`
+	GasPrice        *big.Int        `json:"gasPrice"`
+	GasUsed         uint64          `json:"gasUsed"`
+	Status          uint64          `json:"status"`
+	ContractAddress *common.Address `json:"contractAddress"`
+	Logs            []*types.Log    `json:"logs"`
+}
+
+// Member is a committee member in charge of fast blocks.
+type Member struct {
+	Coinbase      common.Address `json:"coinbase"`
+	CommitteeBase common.Address `json:"committeeBase"`
+	Type          uint32         `json:"type"`
+}
+
+// Inclusion locates a fruit within the snail chain.
+type Inclusion struct {
+	SnailNumber uint64      `json:"snailNumber"`
+	SnailHash   common.Hash `json:"snailHash"`
+	Index       uint64      `json:"index"`
+}
+
+// SnailRef references a snail block.
+type SnailRef struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SnailBlock is the document of a snail block, joined with its fruits and the
+// rewards it earned.
+type SnailBlock struct {
+	Number          uint64         `json:"number"`
+	Hash            common.Hash    `json:"hash"`
+	ParentHash      common.Hash    `json:"parentHash"`
+	Time            uint64         `json:"timestamp"`
+	Miner           common.Address `json:"miner"`
+	Difficulty      *big.Int       `json:"difficulty"`
+	FruitDifficulty *big.Int       `json:"fruitDifficulty"`
+
+	Fruits []*Fruit `json:"fruits"`
+
+	Reward *Reward `json:"reward"` // Rewards of the block, nil until paid out
+}
+
+// Fruit is a fruit mined into a snail block, each pointing to a fast block.
+type Fruit struct {
+	Hash       common.Hash    `json:"hash"`
+	Miner      common.Address `json:"miner"`
+	FastNumber uint64         `json:"fastNumber"`
+	FastHash   common.Hash    `json:"fastHash"`
+}
+
+// Reward is the payout of a snail block.
+type Reward struct {
+	FastNumber uint64              `json:"fastNumber"` // Fast block paying the rewards
+	FastHash   common.Hash         `json:"fastHash"`
+	Miner      *types.RewardInfo   `json:"miner"`
+	Fruits     []*types.RewardInfo `json:"fruits"`
+	Committee  []*types.RewardInfo `json:"committee"` // Validators and delegators, flattened
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package explorerkit joins the fast chain, the snail chain and the committee
+// data into denormalized documents, the way block explorers present them.
+//
+// Every fast block is proposed and signed by a committee, packed into a fruit
+// that gets mined into a snail block, and pays out the rewards of an earlier
+// snail block. The documents resolve these references on both sides, so
+// consumers don't have to follow them between the two chains themselves.
+package explorerkit
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/params"
+)
+
+var (
+	errUnknownFastBlock  = errors.New("unknown fast block")
+	errUnknownSnailBlock = errors.New("unknown snail block")
+)
+
+// FastChain is the fast chain data the documents are built from, implemented
+// by core.BlockChain.
+type FastChain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	GetBlockReward(snailNumber uint64) *types.BlockReward
+	GetRewardInfos(snailNumber uint64) *types.ChainReward
+	SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription
+}
+
+// SnailChain is the snail chain data the documents are built from, implemented
+// by snailchain.SnailBlockChain.
+type SnailChain interface {
+	CurrentBlock() *types.SnailBlock
+	GetBlockByNumber(number uint64) *types.SnailBlock
+	GetFruitByFastHash(fastHash common.Hash) (*types.SnailBlock, uint64)
+	SubscribeChainEvent(ch chan<- types.SnailChainEvent) event.Subscription
+}
+
+// Committee resolves the committee in charge of fast blocks, implemented by
+// election.Election.
+type Committee interface {
+	GetCommittee(fastNumber *big.Int) []*types.CommitteeMember
+	VerifySigns(signs []*types.PbftSign) ([]*types.CommitteeMember, []error)
+}
+
+// Kit builds the explorer documents of a node.
+type Kit struct {
+	fast      FastChain
+	snail     SnailChain
+	committee Committee
+}
+
+// New creates a kit building documents from the given chains.
+func New(fast FastChain, snail SnailChain, committee Committee) *Kit {
+	return &Kit{fast: fast, snail: snail, committee: committee}
+}
+
+// FastBlock returns the document of the canonical fast block with the number.
+func (k *Kit) FastBlock(number uint64) (*FastBlock, error) {
+	block := k.fast.GetBlockByNumber(number)
+	if block == nil {
+		return nil, errUnknownFastBlock
+	}
+	doc := &FastBlock{
+		Number:     block.NumberU64(),
+		Hash:       block.Hash(),
+		ParentHash: block.ParentHash(),
+		Time:       block.Time().Uint64(),
+		Proposer:   block.Proposer(),
+		GasUsed:    block.GasUsed(),
+		GasLimit:   block.GasLimit(),
+		Size:       uint64(block.Size()),
+	}
+	receipts := k.fast.GetReceiptsByHash(block.Hash())
+	signer := types.MakeSigner(k.fast.Config(), block.Number())
+
+	for i, tx := range block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		txDoc := &Transaction{
+			Hash:     tx.Hash(),
+			Index:    uint64(i),
+			From:     from,
+			To:       tx.To(),
+			Payer:    tx.Payer(),
+			Value:    tx.Value(),
+			Fee:      tx.Fee(),
+			Nonce:    tx.Nonce(),
+			Gas:      tx.Gas(),
+			GasPrice: tx.GasPrice(),
+		}
+		if i < len(receipts) {
+			receipt := receipts[i]
+			txDoc.GasUsed = receipt.GasUsed
+			txDoc.Status = receipt.Status
+			txDoc.Logs = receipt.Logs
+			if tx.To() == nil {
+				contract := receipt.ContractAddress
+				txDoc.ContractAddress = &contract
+			}
+		}
+		doc.Transactions = append(doc.Transactions, txDoc)
+	}
+	if k.committee != nil {
+		for _, m := range k.committee.GetCommittee(block.Number()) {
+			doc.Committee = append(doc.Committee, &Member{Coinbase: m.Coinbase, CommitteeBase: m.CommitteeBase, Type: m.MType})
+		}
+		if signs := block.Signs(); len(signs) > 0 {
+			members, errs := k.committee.VerifySigns(signs)
+			for i, m := range members {
+				if m != nil && errs[i] == nil && signs[i].Result == types.VoteAgree {
+					doc.Signers = append(doc.Signers, m.CommitteeBase)
+				}
+			}
+		}
+	}
+	if snail, index := k.snail.GetFruitByFastHash(block.Hash()); snail != nil {
+		doc.Fruit = &Inclusion{SnailNumber: snail.NumberU64(), SnailHash: snail.Hash(), Index: index}
+	}
+	if snailNumber := block.SnailNumber(); snailNumber != nil && snailNumber.Sign() > 0 {
+		doc.RewardedSnail = &SnailRef{Number: snailNumber.Uint64(), Hash: block.SnailHash()}
+	}
+	return doc, nil
+}
+
+// SnailBlock returns the document of the canonical snail block with the number.
+func (k *Kit) SnailBlock(number uint64) (*SnailBlock, error) {
+	block := k.snail.GetBlockByNumber(number)
+	if block == nil {
+		return nil, errUnknownSnailBlock
+	}
+	doc := &SnailBlock{
+		Number:          block.NumberU64(),
+		Hash:            block.Hash(),
+		ParentHash:      block.ParentHash(),
+		Time:            block.Time().Uint64(),
+		Miner:           block.Coinbase(),
+		Difficulty:      block.BlockDifficulty(),
+		FruitDifficulty: block.FruitDifficulty(),
+	}
+	for _, fruit := range block.Fruits() {
+		doc.Fruits = append(doc.Fruits, &Fruit{
+			Hash:       fruit.Hash(),
+			Miner:      fruit.Coinbase(),
+			FastNumber: fruit.FastNumber().Uint64(),
+			FastHash:   fruit.FastHash(),
+		})
+	}
+	if paid := k.fast.GetBlockReward(number); paid != nil {
+		doc.Reward = &Reward{FastNumber: paid.FastNumber.Uint64(), FastHash: paid.FastHash}
+		if infos := k.fast.GetRewardInfos(number); infos != nil {
+			doc.Reward.Miner = infos.CoinBase
+			doc.Reward.Fruits = infos.FruitBase
+			for _, sa := range infos.CommitteeBase {
+				doc.Reward.Committee = append(doc.Reward.Committee, sa.Items...)
+			}
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorerkit
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/event"
+	"github.com/abeychain/go-abey/params"
+)
+
+// testFastChain is an in-memory fast chain.
+type testFastChain struct {
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	rewards  map[uint64]*types.BlockReward
+	infos    map[uint64]*types.ChainReward
+	feed     event.Feed
+}
+
+func (c *testFastChain) Config() *params.ChainConfig { return params.TestChainConfig }
+func (c *testFastChain) CurrentBlock() *types.Block  { return c.blocks[len(c.blocks)-1] }
+func (c *testFastChain) GetBlockByNumber(number uint64) *types.Block {
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+func (c *testFastChain) GetReceiptsByHash(hash common.Hash) types.Receipts { return c.receipts[hash] }
+func (c *testFastChain) GetBlockReward(number uint64) *types.BlockReward   { return c.rewards[number] }
+func (c *testFastChain) GetRewardInfos(number uint64) *types.ChainReward   { return c.infos[number] }
+func (c *testFastChain) SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// testSnailChain is an in-memory snail chain whose canonical blocks can be
+// replaced to simulate reorgs.
+type testSnailChain struct {
+	lock   sync.Mutex
+	blocks []*types.SnailBlock
+	feed   event.Feed
+}
+
+func (c *testSnailChain) CurrentBlock() *types.SnailBlock {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.blocks[len(c.blocks)-1]
+}
+func (c *testSnailChain) GetBlockByNumber(number uint64) *types.SnailBlock {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+func (c *testSnailChain) GetFruitByFastHash(hash common.Hash) (*types.SnailBlock, uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, block := range c.blocks {
+		for i, fruit := range block.Fruits() {
+			if fruit.FastHash() == hash {
+				return block, uint64(i)
+			}
+		}
+	}
+	return nil, 0
+}
+func (c *testSnailChain) SubscribeChainEvent(ch chan<- types.SnailChainEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// extend replaces the snail blocks from the given number on with new ones.
+func (c *testSnailChain) extend(from uint64, n int, seed byte, fruits ...*types.SnailBlock) {
+	c.lock.Lock()
+	c.blocks = c.blocks[:from]
+	for i := 0; i < n; i++ {
+		parent := c.blocks[len(c.blocks)-1]
+		header := &types.SnailHeader{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number(), common.Big1),
+			Extra:      []byte{seed},
+		}
+		c.blocks = append(c.blocks, types.NewSnailBlock(header, fruits, nil, nil, params.TestChainConfig))
+		fruits = nil
+	}
+	c.lock.Unlock()
+	c.feed.Send(types.SnailChainEvent{})
+}
+
+func newSnailChain() *testSnailChain {
+	genesis := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: common.Big0})
+	return &testSnailChain{blocks: []*types.SnailBlock{genesis}}
+}
+
+// Tests that fast block documents join the receipts, the fruit and the reward
+// references, and snail block documents their fruits and rewards.
+func TestDocuments(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.MakeSigner(params.TestChainConfig, common.Big1)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(10), 21000, big.NewInt(1), nil), signer, key)
+
+	genesis := types.NewBlockWithHeader(&types.Header{Number: common.Big0, SnailNumber: common.Big0, Time: common.Big0})
+	block := types.NewBlock(&types.Header{ParentHash: genesis.Hash(), Number: common.Big1, SnailNumber: common.Big1, Time: big.NewInt(10)}, []*types.Transaction{tx}, nil, nil, nil)
+
+	fast := &testFastChain{
+		blocks:   []*types.Block{genesis, block},
+		receipts: map[common.Hash]types.Receipts{block.Hash(): {{Status: types.ReceiptStatusSuccessful, GasUsed: 21000}}},
+		rewards:  map[uint64]*types.BlockReward{1: {FastHash: block.Hash(), FastNumber: common.Big1, SnailNumber: common.Big1}},
+		infos: map[uint64]*types.ChainReward{1: {
+			CoinBase:      &types.RewardInfo{Address: common.Address{0x02}, Amount: big.NewInt(5)},
+			CommitteeBase: []*types.SARewardInfos{{Items: []*types.RewardInfo{{Address: common.Address{0x03}, Amount: big.NewInt(2)}}}},
+		}},
+	}
+	snail := newSnailChain()
+	fruit := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: common.Big0, FastNumber: common.Big1, FastHash: block.Hash()})
+	snail.extend(1, 1, 0, fruit)
+
+	kit := New(fast, snail, nil)
+	doc, err := kit.FastBlock(1)
+	if err != nil {
+		t.Fatalf("failed to build fast document: %v", err)
+	}
+	if len(doc.Transactions) != 1 || doc.Transactions[0].From != crypto.PubkeyToAddress(key.PublicKey) || doc.Transactions[0].GasUsed != 21000 {
+		t.Fatalf("transaction mismatch: %+v", doc.Transactions)
+	}
+	if doc.Fruit == nil || doc.Fruit.SnailNumber != 1 || doc.Fruit.Index != 0 {
+		t.Fatalf("fruit inclusion mismatch: %+v", doc.Fruit)
+	}
+	if doc.RewardedSnail == nil || doc.RewardedSnail.Number != 1 {
+		t.Fatalf("rewarded snail mismatch: %+v", doc.RewardedSnail)
+	}
+	sdoc, err := kit.SnailBlock(1)
+	if err != nil {
+		t.Fatalf("failed to build snail document: %v", err)
+	}
+	if len(sdoc.Fruits) != 1 || sdoc.Fruits[0].FastHash != block.Hash() {
+		t.Fatalf("fruits mismatch: %+v", sdoc.Fruits)
+	}
+	if sdoc.Reward == nil || sdoc.Reward.FastNumber != 1 || sdoc.Reward.Miner.Address != (common.Address{0x02}) || len(sdoc.Reward.Committee) != 1 {
+		t.Fatalf("reward mismatch: %+v", sdoc.Reward)
+	}
+	if _, err := kit.FastBlock(2); err != errUnknownFastBlock {
+		t.Fatalf("unknown block error mismatch: have %v, want %v", err, errUnknownFastBlock)
+	}
+}
+
+// Tests that the snail stream follows the chain, re-sending the new canonical
+// blocks from the fork point on reorgs.
+func TestStreamSnailReorg(t *testing.T) {
+	snail := newSnailChain()
+	snail.extend(1, 4, 0)
+
+	kit := New(&testFastChain{}, snail, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	docs := make(chan *SnailBlock)
+	go kit.StreamSnailBlocks(ctx, 1, docs)
+
+	receive := func(want uint64) *SnailBlock {
+		select {
+		case doc := <-docs:
+			if doc.Number != want {
+				t.Fatalf("document number mismatch: have %d, want %d", doc.Number, want)
+			}
+			return doc
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for block %d", want)
+		}
+		return nil
+	}
+	for i := uint64(1); i <= 4; i++ {
+		receive(i)
+	}
+	// Replace blocks 3 and 4 with a longer fork
+	snail.extend(3, 3, 1)
+	for i := uint64(3); i <= 5; i++ {
+		if doc := receive(i); doc.Hash != snail.GetBlockByNumber(i).Hash() {
+			t.Fatalf("block %d not from the new fork", i)
+		}
+	}
+}
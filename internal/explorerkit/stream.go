@@ -0,0 +1,120 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package explorerkit
+
+import (
+	"context"
+
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+const (
+	// chainEventChanSize is the size of the channels listening to chain events.
+	chainEventChanSize = 16
+
+	// snailReorgWindow is the number of streamed snail blocks remembered to
+	// detect reorgs. Reorgs going deeper are re-streamed from the window start.
+	snailReorgWindow = 256
+)
+
+// StreamFastBlocks sends the documents of the fast blocks from the given number
+// on, first catching up with the head, then following the chain as it grows.
+// Fast blocks are final once signed by the committee, so every block is sent
+// exactly once. It returns when the context is cancelled or a document can't
+// be built.
+func (k *Kit) StreamFastBlocks(ctx context.Context, from uint64, docs chan<- *FastBlock) error {
+	events := make(chan types.FastChainEvent, chainEventChanSize)
+	sub := k.fast.SubscribeChainEvent(events)
+	defer sub.Unsubscribe()
+
+	next := from
+	for {
+		for head := k.fast.CurrentBlock().NumberU64(); next <= head; next++ {
+			doc, err := k.FastBlock(next)
+			if err != nil {
+				return err
+			}
+			select {
+			case docs <- doc:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-events:
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// StreamSnailBlocks sends the documents of the snail blocks from the given
+// number on, first catching up with the head, then following the chain as it
+// grows. If the snail chain reorganizes, the documents of the new canonical
+// blocks are sent again from the fork point, so consumers should store them by
+// number, replacing earlier ones.
+func (k *Kit) StreamSnailBlocks(ctx context.Context, from uint64, docs chan<- *SnailBlock) error {
+	events := make(chan types.SnailChainEvent, chainEventChanSize)
+	sub := k.snail.SubscribeChainEvent(events)
+	defer sub.Unsubscribe()
+
+	var (
+		next = from
+		sent = make(map[uint64]common.Hash) // Hashes of the recently sent blocks
+	)
+	for {
+		for head := k.snail.CurrentBlock().NumberU64(); next <= head; next++ {
+			doc, err := k.SnailBlock(next)
+			if err != nil {
+				return err
+			}
+			// Rewind to the fork point if the block doesn't extend the sent ones
+			if parent, ok := sent[next-1]; ok && next > from && parent != doc.ParentHash {
+				for next > from {
+					hash, ok := sent[next-1]
+					if !ok {
+						break
+					}
+					if block := k.snail.GetBlockByNumber(next - 1); block != nil && block.Hash() == hash {
+						break
+					}
+					next--
+				}
+				if doc, err = k.SnailBlock(next); err != nil {
+					return err
+				}
+			}
+			select {
+			case docs <- doc:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			sent[next] = doc.Hash
+			delete(sent, next-snailReorgWindow)
+		}
+		select {
+		case <-events:
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package servicemgr integrates the node with the native service managers: it
+// reports readiness, watchdog pings and shutdown to systemd through sd_notify
+// and runs the node as a Windows service controlled by the service control
+// manager.
+package servicemgr
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+)
+
+// ErrUnsupported is returned by the service management operations on platforms
+// without a supported service manager.
+var ErrUnsupported = errors.New("service management not supported on this platform")
+
+var (
+	shutdown     = make(chan struct{}) // Closed when the service manager requests a stop
+	shutdownOnce sync.Once
+
+	watchdogOnce sync.Once
+)
+
+// Shutdown returns a channel closed when the service manager requested the node
+// to stop. Managers delivering signals instead, like systemd, never close it.
+func Shutdown() <-chan struct{} {
+	return shutdown
+}
+
+// requestShutdown closes the shutdown channel.
+func requestShutdown() {
+	shutdownOnce.Do(func() { close(shutdown) })
+}
+
+// Ready tells the service manager that the node finished starting up. Under
+// systemd with a watchdog configured, it also starts pinging the watchdog.
+func Ready() {
+	if ok, err := Notify("READY=1"); err != nil {
+		log.Warn("Failed to notify systemd", "err", err)
+	} else if ok {
+		log.Debug("Notified systemd of readiness")
+	}
+	if interval := watchdogInterval(); interval > 0 {
+		watchdogOnce.Do(func() { go watchdog(interval / 2) })
+	}
+	reportRunning()
+}
+
+// Stopping tells the service manager that the node is shutting down, so it
+// waits for the shutdown to finish instead of treating the node as hung.
+func Stopping() {
+	if _, err := Notify("STOPPING=1"); err != nil {
+		log.Warn("Failed to notify systemd", "err", err)
+	}
+	reportStopping()
+}
+
+// watchdog pings the systemd watchdog every interval until the node stops.
+func watchdog(interval time.Duration) {
+	log.Info("Pinging systemd watchdog", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := Notify("WATCHDOG=1"); err != nil {
+				log.Warn("Failed to ping systemd watchdog", "err", err)
+			}
+		case <-shutdown:
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !windows
+
+package servicemgr
+
+// Run executes main. Outside of Windows the node is never started by a service
+// control manager, service managers like systemd run it as a plain process.
+func Run(name string, main func() error) error {
+	return main()
+}
+
+// Install registers the executable as a Windows service.
+func Install(name, exepath string, args ...string) error {
+	return ErrUnsupported
+}
+
+// Remove deletes a registered Windows service.
+func Remove(name string) error {
+	return ErrUnsupported
+}
+
+// Start starts a registered Windows service.
+func Start(name string) error {
+	return ErrUnsupported
+}
+
+// Stop stops a running Windows service, waiting for it to shut down.
+func Stop(name string) error {
+	return ErrUnsupported
+}
+
+func reportRunning()  {}
+func reportStopping() {}
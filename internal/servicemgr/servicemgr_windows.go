@@ -0,0 +1,201 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build windows
+
+package servicemgr
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	accepted    = svc.AcceptStop | svc.AcceptShutdown
+	stopTimeout = 5 * time.Minute // Time a stopping service is given to shut down cleanly
+)
+
+// statusCh delivers the state changes of the node to the service control
+// manager, nil if not running as a Windows service.
+var statusCh chan svc.Status
+
+// handler runs the node under the service control manager.
+type handler struct {
+	main func() error
+	err  error
+}
+
+// Execute implements svc.Handler, running main until it returns and asking it
+// to shut down once the service is stopped or the system shuts down.
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	s <- svc.Status{State: svc.StartPending}
+
+	errc := make(chan error, 1)
+	go func() { errc <- h.main() }()
+
+	for {
+		select {
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				s <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				s <- svc.Status{State: svc.StopPending}
+				requestShutdown()
+			}
+		case status := <-statusCh:
+			s <- status
+		case err := <-errc:
+			h.err = err
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		}
+	}
+}
+
+// Run executes main, under the service control manager if the process was
+// started as a Windows service.
+func Run(name string, main func() error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return main()
+	}
+	statusCh = make(chan svc.Status, 1)
+
+	h := &handler{main: main}
+	if err := svc.Run(name, h); err != nil {
+		return err
+	}
+	return h.err
+}
+
+// reportRunning tells the service control manager that the node started.
+func reportRunning() {
+	if statusCh != nil {
+		statusCh <- svc.Status{State: svc.Running, Accepts: accepted}
+	}
+}
+
+// reportStopping tells the service control manager that the node shuts down.
+func reportStopping() {
+	if statusCh != nil {
+		select {
+		case statusCh <- svc.Status{State: svc.StopPending}:
+		default:
+		}
+	}
+}
+
+// Install registers the executable as an automatically started Windows
+// service, run with the given command line arguments.
+func Install(name, exepath string, args ...string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+	s, err := m.CreateService(name, exepath, mgr.Config{
+		DisplayName: name,
+		Description: "Abeychain node",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Restart the node if it crashes, a clean stop is not restarted
+	return s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+	}, uint32((24 * time.Hour).Seconds()))
+}
+
+// Remove deletes a registered Windows service.
+func Remove(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %v", name, err)
+	}
+	defer s.Close()
+
+	return s.Delete()
+}
+
+// Start starts a registered Windows service.
+func Start(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %v", name, err)
+	}
+	defer s.Close()
+
+	return s.Start()
+}
+
+// Stop stops a running Windows service, waiting for it to shut down.
+func Stop(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %v", name, err)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(stopTimeout); status.State != svc.Stopped; {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not stop within %v", name, stopTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
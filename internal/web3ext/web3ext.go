@@ -31,6 +31,7 @@ var Modules = map[string]string{
 	"txpool":    TxPool_JS,
 	"fruitpool": FruitPool_JS,
 	"impawn":    Impawn_JS,
+	"committee": Committee_JS,
 }
 
 const Clique_JS = `
@@ -104,6 +105,16 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'compareChain',
+			call: 'admin_compareChain',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'allowDeepReorg',
+			call: 'admin_allowDeepReorg',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -119,6 +130,19 @@ web3._extend({
 			name: 'stopRPC',
 			call: 'admin_stopRPC'
 		}),
+		new web3._extend.Method({
+			name: 'rpcUsage',
+			call: 'admin_rpcUsage'
+		}),
+		new web3._extend.Method({
+			name: 'listBans',
+			call: 'admin_listBans'
+		}),
+		new web3._extend.Method({
+			name: 'unban',
+			call: 'admin_unban',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'startWS',
 			call: 'admin_startWS',
@@ -139,6 +163,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerLatency',
+			getter: 'admin_peerLatency'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -176,6 +204,11 @@ web3._extend({
 			call: 'debug_dumpBlock',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'dbSchema',
+			call: 'debug_dbSchema',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -232,6 +265,11 @@ web3._extend({
 			call: 'debug_gcStats',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'cacheAllocation',
+			call: 'debug_cacheAllocation',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'cpuProfile',
 			call: 'debug_cpuProfile',
@@ -338,6 +376,16 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getSideHeads',
+			call: 'debug_getSideHeads',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'getSnailSideHeads',
+			call: 'debug_getSnailSideHeads',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -398,6 +446,63 @@ web3._extend({
 			call: 'abey_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getTransactionProof',
+			call: 'abey_getTransactionProof',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'clockDrift',
+			call: 'abey_clockDrift',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'diskUsage',
+			call: 'abey_diskUsage',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'fruitLag',
+			call: 'abey_fruitLag',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'chainStats',
+			call: 'abey_chainStats',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getNetworkHashrate',
+			call: 'abey_getNetworkHashrate',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getHashrateHistory',
+			call: 'abey_getHashrateHistory',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getEpochRewardSummary',
+			call: 'abey_getEpochRewardSummary',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getRotationPause',
+			call: 'abey_getRotationPause',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'rotationPauseHash',
+			call: 'abey_rotationPauseHash',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getCommitteeHistory',
+			call: 'abey_getCommitteeHistory',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -406,6 +511,52 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getCommitteeProof',
+			call: 'abey_getCommitteeProof',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getFruitCoinbaseProof',
+			call: 'abey_getFruitCoinbaseProof',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'verifyPbftSign',
+			call: 'abey_verifyPbftSign',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getElectionParams',
+			call: 'abey_getElectionParams',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getHeaderByNumber',
+			call: 'abey_getHeaderByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getHeadersByRange',
+			call: 'abey_getHeadersByRange',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getSnailHeaderByNumber',
+			call: 'abey_getSnailHeaderByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSnailHeadersByRange',
+			call: 'abey_getSnailHeadersByRange',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -477,6 +628,10 @@ web3._extend({
 			name: 'getHashRate',
 			call: 'miner_getHashRate'
 		}),
+		new web3._extend.Method({
+			name: 'getStaleReport',
+			call: 'miner_getStaleReport'
+		}),
 	],
 	properties: []
 });
@@ -601,7 +756,14 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'oldest',
+			call: 'txpool_oldest',
+			params: 1,
+			inputFormatter: [null]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -806,6 +968,33 @@ web3._extend({
 				return infos;
 			}
 		}),
+		new web3._extend.Method({
+			name: 'getDelegateNonce',
+			call: 'impawn_getDelegateNonce',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'delegationTypedData',
+			call: 'impawn_delegationTypedData',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+	]
+});
+`
+
+const Committee_JS = `
+web3._extend({
+	property: 'committee',
+	methods: [
+		new web3._extend.Method({
+			name: 'selfStatus',
+			call: 'committee_selfStatus',
+			params: 1,
+			inputFormatter: [null]
+		}),
 	]
 });
 `
@@ -23,6 +23,7 @@ import (
 	"github.com/abeychain/go-abey/abey/fastdownloader"
 	"github.com/abeychain/go-abey/light"
 	"math/big"
+	"time"
 
 	"github.com/abeychain/go-abey/abey/gasprice"
 	"github.com/abeychain/go-abey/abeydb"
@@ -54,7 +55,10 @@ func (b *LesApiBackend) SetSnailHead(number uint64) {
 
 }
 func (b *LesApiBackend) SnailHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.SnailHeader, error) {
-	return nil, NotSupportOnLes
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return nil, NotSupportOnLes
+	}
+	return b.abey.blockchain.GetSnailHeaderByNumberOdr(ctx, uint64(blockNr))
 }
 func (b *LesApiBackend) SnailBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.SnailBlock, error) {
 	return nil, NotSupportOnLes
@@ -62,6 +66,9 @@ func (b *LesApiBackend) SnailBlockByNumber(ctx context.Context, blockNr rpc.Bloc
 func (b *LesApiBackend) GetFruit(ctx context.Context, fastblockHash common.Hash) (*types.SnailBlock, error) {
 	return nil, NotSupportOnLes
 }
+func (b *LesApiBackend) GetSnailBlockByFastHash(ctx context.Context, fastblockHash common.Hash) (*types.SnailBlock, uint64, error) {
+	return nil, 0, NotSupportOnLes
+}
 func (b *LesApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
 	return nil, nil, NotSupportOnLes
 }
@@ -77,6 +84,12 @@ func (b *LesApiBackend) GetReward(number int64) *types.BlockReward {
 func (b *LesApiBackend) GetCommittee(id rpc.BlockNumber) (map[string]interface{}, error) {
 	return nil, NotSupportOnLes
 }
+func (b *LesApiBackend) GetCommitteeProof(ctx context.Context, number rpc.BlockNumber, checkpoint *big.Int) (*types.CommitteeProof, error) {
+	return nil, NotSupportOnLes
+}
+func (b *LesApiBackend) VerifyPbftSign(sign *types.PbftSign) (*types.CommitteeMember, error) {
+	return nil, NotSupportOnLes
+}
 func (b *LesApiBackend) GetCurrentCommitteeNumber() *big.Int {
 	return nil
 }
@@ -113,6 +126,18 @@ func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
 	return b.abey.chainConfig
 }
 
+func (b *LesApiBackend) RPCEVMTimeout() time.Duration {
+	return b.abey.config.RPCEVMTimeout
+}
+
+func (b *LesApiBackend) RPCGasCap() uint64 {
+	return b.abey.config.RPCGasCap
+}
+
+func (b *LesApiBackend) RPCStateReadLimit() uint64 {
+	return b.abey.config.RPCStateReadLimit
+}
+
 func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.abey.blockchain.CurrentHeader())
 }
@@ -160,6 +185,31 @@ func (b *LesApiBackend) GetReceipts(ctx context.Context, hash common.Hash) (type
 	return nil, nil
 }
 
+// GetReceipt retrieves a single receipt proven against the receipt hash of its
+// block, instead of downloading all the receipts of the block. Without LES/3
+// servers connected, the whole block's receipts are retrieved.
+func (b *LesApiBackend) GetReceipt(ctx context.Context, hash common.Hash, index uint64) (*types.Receipt, error) {
+	number := rawdb.ReadHeaderNumber(b.abey.chainDb, hash)
+	if number == nil {
+		return nil, nil
+	}
+	if receipts := rawdb.ReadReceipts(b.abey.chainDb, hash, *number); receipts == nil {
+		header := b.abey.blockchain.GetHeader(hash, *number)
+		if header == nil {
+			return nil, nil
+		}
+		receipt, err := light.GetReceiptByProof(ctx, b.abey.odr, header, uint(index))
+		if err != light.ErrNoPeers {
+			return receipt, err
+		}
+	}
+	receipts, err := light.GetBlockReceipts(ctx, b.abey.odr, hash, *number)
+	if err != nil || uint64(len(receipts)) <= index {
+		return nil, err
+	}
+	return receipts[index], nil
+}
+
 func (b *LesApiBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	if number := rawdb.ReadHeaderNumber(b.abey.chainDb, hash); number != nil {
 		return light.GetBlockLogs(ctx, b.abey.odr, hash, *number)
@@ -206,10 +256,32 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.abey.txPool.Content()
 }
 
+// TxPoolOldest returns nothing, the light pool doesn't queue transactions.
+func (b *LesApiBackend) TxPoolOldest(n int) map[common.Address][]*core.TxAge {
+	return nil
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- types.NewTxsEvent) event.Subscription {
 	return b.abey.txPool.SubscribeNewTxsEvent(ch)
 }
 
+// SubscribeDroppedTxsEvent never fires, as the light pool doesn't evict
+// transactions before they are mined.
+func (b *LesApiBackend) SubscribeDroppedTxsEvent(ch chan<- types.DroppedTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// SubscribeNewFruitEvent never fires, as light clients don't track fruits.
+func (b *LesApiBackend) SubscribeNewFruitEvent(ch chan<- types.NewFruitsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- types.FastChainEvent) event.Subscription {
 	return b.abey.blockchain.SubscribeChainEvent(ch)
 }
@@ -113,7 +113,11 @@ func New(ctx *node.ServiceContext, config *abey.Config) (*LightAbey, error) {
 	}
 
 	labey.relay = NewLesTxRelay(peers, labey.reqDist)
-	labey.serverPool = newServerPool(chainDb, quitSync, &labey.wg, nil)
+	var trustedServers []string
+	if config.ULC != nil {
+		trustedServers = config.ULC.TrustedServers
+	}
+	labey.serverPool = newServerPool(chainDb, quitSync, &labey.wg, trustedServers)
 	labey.retriever = newRetrieveManager(peers, labey.reqDist, labey.serverPool)
 
 	labey.odr = NewLesOdr(chainDb, light.DefaultClientIndexerConfig, labey.retriever)
@@ -145,9 +149,12 @@ func New(ctx *node.ServiceContext, config *abey.Config) (*LightAbey, error) {
 	labey.txPool = light.NewTxPool(labey.chainConfig, labey.blockchain, labey.relay)
 	if labey.protocolManager, err = NewProtocolManager(labey.chainConfig, light.DefaultClientIndexerConfig, true,
 		config.NetworkId, labey.eventMux, labey.engine, labey.peers, labey.blockchain, nil,
-		chainDb, labey.odr, labey.relay, labey.serverPool, quitSync, &labey.wg, labey.genesisHash); err != nil {
+		chainDb, labey.odr, labey.relay, labey.serverPool, config.ULC, quitSync, &labey.wg, labey.genesisHash); err != nil {
 		return nil, err
 	}
+	if labey.protocolManager.isULCEnabled() {
+		log.Info("Running in ultra light client mode", "servers", len(labey.protocolManager.ulc.trustedKeys), "fraction", labey.protocolManager.ulc.minTrustedFraction)
+	}
 	labey.ApiBackend = &LesApiBackend{labey, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -19,6 +19,7 @@ package les
 import (
 	"fmt"
 	"github.com/abeychain/go-abey/core"
+	"github.com/abeychain/go-abey/core/snailchain"
 	"math/big"
 
 	"github.com/abeychain/go-abey/abey"
@@ -43,6 +44,7 @@ type lesCommons struct {
 	chainDb                      abeydb.Database
 	protocolManager              *ProtocolManager
 	chtIndexer, bloomTrieIndexer *core.ChainIndexer
+	snailChtIndexer              *snailchain.ChainIndexer // nil if our node is client only
 }
 
 // NodeInfo represents a short summary of the Ethereum sub-protocol metadata
@@ -111,6 +113,15 @@ func (c *lesCommons) nodeInfo() interface{} {
 			BloomRoot:    light.GetBloomTrieRoot(c.chainDb, sectionIndex, sectionHead),
 		}
 	}
+	if c.snailChtIndexer != nil {
+		if sections, _, _ := c.snailChtIndexer.Sections(); sections > 0 {
+			sectionIndex := sections - 1
+			sectionHead := c.snailChtIndexer.SectionHead(sectionIndex)
+			cht.SnailSectionIndex = sectionIndex
+			cht.SnailSectionHead = sectionHead
+			cht.SnailCHTRoot = light.GetSnailChtRoot(c.chainDb, sectionIndex, sectionHead)
+		}
+	}
 
 	chain := c.protocolManager.blockchain
 	head := chain.CurrentHeader()
@@ -26,11 +26,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/abeychain/go-abey/abey"
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/consensus"
 	"github.com/abeychain/go-abey/core"
 	"github.com/abeychain/go-abey/core/rawdb"
+	snailrawdb "github.com/abeychain/go-abey/core/snailchain/rawdb"
 	"github.com/abeychain/go-abey/core/state"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/event"
@@ -59,6 +61,7 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxReceiptProofsFetch    = 64  // Amount of receipt merkle proofs to be fetched per retrieval request
 
 	disableClientRemovePeer = false
 )
@@ -101,6 +104,7 @@ type ProtocolManager struct {
 	odr         *LesOdr
 	server      *LesServer
 	serverPool  *serverPool
+	ulc         *ulc // nil if the ultra light client mode is disabled
 	lesTopic    discv5.Topic
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
@@ -127,7 +131,7 @@ type ProtocolManager struct {
 // with the abeychain network.
 func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.IndexerConfig, lightSync bool, networkId uint64,
 	mux *event.TypeMux, engine consensus.Engine, peers *peerSet, blockchain BlockChain, txpool txPool, chainDb abeydb.Database,
-	odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, quitSync chan struct{}, wg *sync.WaitGroup, genesisHash common.Hash) (*ProtocolManager, error) {
+	odr *LesOdr, txrelay *LesTxRelay, serverPool *serverPool, ulcConfig *abey.ULCConfig, quitSync chan struct{}, wg *sync.WaitGroup, genesisHash common.Hash) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		lightSync:   lightSync,
@@ -147,6 +151,7 @@ func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.In
 		wg:          wg,
 		noMorePeers: make(chan struct{}),
 		genesisHash: genesisHash,
+		ulc:         newULC(ulcConfig),
 	}
 	if odr != nil {
 		manager.retriever = odr.retriever
@@ -167,6 +172,11 @@ func NewProtocolManager(chainConfig *params.ChainConfig, indexerConfig *light.In
 	return manager, nil
 }
 
+// isULCEnabled tells if the node runs in ultra light client mode
+func (pm *ProtocolManager) isULCEnabled() bool {
+	return pm.ulc != nil
+}
+
 // removePeer initiates disconnection from a peer by removing it from the peer set
 func (pm *ProtocolManager) removePeer(id string, call uint32) {
 	pm.peers.Unregister(id)
@@ -263,6 +273,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
+	if pm.isULCEnabled() {
+		p.isTrusted = pm.ulc.isTrusted(p.ID())
+	}
 	// Register the peer locally
 	if err := pm.peers.Register(p); err != nil {
 		p.Log().Error("Light Abeychain peer registration failed", "err", err)
@@ -312,9 +325,10 @@ func (pm *ProtocolManager) handle(p *peer) error {
 }
 
 var (
-	reqList   = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+	reqList   = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, GetReceiptProofsMsg}
 	reqListV1 = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, GetHeaderProofsMsg}
 	reqListV2 = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, SendTxV2Msg, GetTxStatusMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+	reqListV3 = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, SendTxV2Msg, GetTxStatusMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, GetReceiptProofsMsg}
 )
 
 // handleMsg is invoked whenever an inbound message is received from a remote
@@ -1127,6 +1141,71 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 
+	case GetReceiptProofsMsg:
+		p.Log().Trace("Received receipt proofs request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID uint64
+			Reqs  []ReceiptProofReq
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Gather receipt proofs until the fetch or network limits is reached
+		var (
+			lastBHash common.Hash
+			receipts  *trie.Trie
+		)
+		reqCnt := len(req.Reqs)
+		if reject(uint64(reqCnt), MaxReceiptProofsFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		nodes := light.NewNodeSet()
+		for _, req := range req.Reqs {
+			// Rebuild the receipt trie of the requested block
+			if receipts == nil || req.BHash != lastBHash {
+				receipts, lastBHash = nil, req.BHash
+
+				if number := rawdb.ReadHeaderNumber(pm.chainDb, req.BHash); number != nil {
+					if results := rawdb.ReadReceipts(pm.chainDb, req.BHash, *number); results != nil {
+						receipts = receiptTrie(results)
+					}
+				}
+			}
+			if receipts == nil {
+				continue
+			}
+			key, _ := rlp.EncodeToBytes(req.Index)
+			receipts.Prove(key, 0, nodes)
+			if nodes.DataSize() >= softResponseLimit {
+				break
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendReceiptProofs(req.ReqID, bv, nodes.NodeList())
+
+	case ReceiptProofsMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received receipt proofs response")
+		// A batch of merkle proofs arrived to one of our previous requests
+		var resp struct {
+			ReqID, BV uint64
+			Data      light.NodeList
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgReceiptProofs,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -1161,6 +1240,17 @@ func (pm *ProtocolManager) getAccount(statedb *state.StateDB, root, hash common.
 	return account, nil
 }
 
+// receiptTrie builds the receipt trie of a block, the root of which equals the
+// receipt hash of its header.
+func receiptTrie(receipts types.Receipts) *trie.Trie {
+	t := new(trie.Trie)
+	for i := 0; i < receipts.Len(); i++ {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		t.Update(key, receipts.GetRlp(i))
+	}
+	return t
+}
+
 // getHelperTrie returns the post-processed trie root for the given trie ID and section index
 func (pm *ProtocolManager) getHelperTrie(id uint, idx uint64) (common.Hash, string) {
 	switch id {
@@ -1171,6 +1261,9 @@ func (pm *ProtocolManager) getHelperTrie(id uint, idx uint64) (common.Hash, stri
 	case htBloomBits:
 		sectionHead := rawdb.ReadCanonicalHash(pm.chainDb, (idx+1)*pm.iConfig.BloomTrieSize-1)
 		return light.GetBloomTrieRoot(pm.chainDb, idx, sectionHead), light.BloomTrieTablePrefix
+	case htSnailCanonical:
+		sectionHead := snailrawdb.ReadCanonicalHash(pm.chainDb, (idx+1)*params.SnailCHTFrequency-1)
+		return light.GetSnailChtRoot(pm.chainDb, idx, sectionHead), light.SnailChtTablePrefix
 	}
 	return common.Hash{}, ""
 }
@@ -1182,6 +1275,11 @@ func (pm *ProtocolManager) getHelperTrieAuxData(req HelperTrieReq) []byte {
 		hash := rawdb.ReadCanonicalHash(pm.chainDb, blockNum)
 		return rawdb.ReadHeaderRLP(pm.chainDb, hash, blockNum)
 	}
+	if req.Type == htSnailCanonical && req.AuxReq == auxHeader && len(req.Key) == 8 {
+		blockNum := binary.BigEndian.Uint64(req.Key)
+		hash := snailrawdb.ReadCanonicalHash(pm.chainDb, blockNum)
+		return snailrawdb.ReadHeaderRLP(pm.chainDb, hash, blockNum)
+	}
 	return nil
 }
 
@@ -39,6 +39,7 @@ var (
 	errTxHashMismatch      = errors.New("transaction hash mismatch")
 	errUncleHashMismatch   = errors.New("uncle hash mismatch")
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
+	errReceiptUnavailable  = errors.New("receipt unavailable")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
 	errCHTNumberMismatch   = errors.New("cht number mismatch")
@@ -66,6 +67,10 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
 		return (*BloomRequest)(r)
+	case *light.SnailChtRequest:
+		return (*SnailChtRequest)(r)
+	case *light.ReceiptProofRequest:
+		return (*ReceiptProofRequest)(r)
 	default:
 		return nil
 	}
@@ -188,7 +193,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -304,14 +309,78 @@ func (r *CodeRequest) Validate(db abeydb.Database, msg *Msg) error {
 	return nil
 }
 
+type ReceiptProofReq struct {
+	BHash common.Hash
+	Index uint
+}
+
+// ODR request type for a single receipt proven against the receipt hash of its
+// header, see LesOdrRequest interface
+type ReceiptProofRequest light.ReceiptProofRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *ReceiptProofRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetReceiptProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *ReceiptProofRequest) CanSend(peer *peer) bool {
+	return peer.SupportsReceiptProofs() && peer.HasBlock(r.Header.Hash(), r.Header.Number.Uint64(), false)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *ReceiptProofRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting receipt proof", "hash", r.Header.Hash(), "index", r.Index)
+	req := ReceiptProofReq{
+		BHash: r.Header.Hash(),
+		Index: r.Index,
+	}
+	return peer.RequestReceiptProofs(reqID, r.GetCost(peer), []ReceiptProofReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *ReceiptProofRequest) Validate(db abeydb.Database, msg *Msg) error {
+	log.Debug("Validating receipt proof", "hash", r.Header.Hash(), "index", r.Index)
+
+	if msg.MsgType != MsgReceiptProofs {
+		return errInvalidMessageType
+	}
+	nodeSet := msg.Obj.(light.NodeList).NodeSet()
+	key, _ := rlp.EncodeToBytes(r.Index)
+
+	reads := &readTraceDB{db: nodeSet}
+	value, _, err := trie.VerifyProof(r.Header.ReceiptHash, key, reads)
+	if err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	if value == nil {
+		return errReceiptUnavailable
+	}
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(value, receipt); err != nil {
+		return err
+	}
+	// Verifications passed, store and return
+	r.Receipt = receipt
+	r.Proof = nodeSet
+	return nil
+}
+
 const (
 	// helper trie type constants
-	htCanonical = iota // Canonical hash trie
-	htBloomBits        // BloomBits trie
+	htCanonical      = iota // Canonical hash trie
+	htBloomBits             // BloomBits trie
+	htSnailCanonical        // Snail canonical hash trie
 
 	// applicable for all helper trie requests
 	auxRoot = 1
-	// applicable for htCanonical
+	// applicable for htCanonical and htSnailCanonical
 	auxHeader = 2
 )
 
@@ -348,7 +417,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -384,7 +453,7 @@ func (r *ChtRequest) Request(reqID uint64, peer *peer) error {
 		// convert HelperTrie request to old CHT request
 		reqsV1 = ChtReq{ChtNum: (req.TrieIdx + 1) * (r.Config.ChtSize / r.Config.PairChtSize), BlockNum: blockNum, FromLevel: req.FromLevel}
 		return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []ChtReq{reqsV1})
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []HelperTrieReq{req})
 	default:
 		panic(nil)
@@ -472,6 +541,88 @@ func (r *ChtRequest) Validate(db abeydb.Database, msg *Msg) error {
 	return nil
 }
 
+// ODR request type for requesting snail headers by snail Canonical Hash Trie, see LesOdrRequest interface
+type SnailChtRequest light.SnailChtRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *SnailChtRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request.
+// Snail CHTs are only served through helper trie requests.
+func (r *SnailChtRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv2
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *SnailChtRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting snail CHT", "cht", r.ChtNum, "block", r.BlockNum)
+	var encNum [8]byte
+	binary.BigEndian.PutUint64(encNum[:], r.BlockNum)
+	req := HelperTrieReq{
+		Type:    htSnailCanonical,
+		TrieIdx: r.ChtNum,
+		Key:     encNum[:],
+		AuxReq:  auxHeader,
+	}
+	return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []HelperTrieReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *SnailChtRequest) Validate(db abeydb.Database, msg *Msg) error {
+	log.Debug("Validating snail CHT", "cht", r.ChtNum, "block", r.BlockNum)
+
+	if msg.MsgType != MsgHelperTrieProofs {
+		return errInvalidMessageType
+	}
+	resp := msg.Obj.(HelperTrieResps)
+	if len(resp.AuxData) != 1 {
+		return errInvalidEntryCount
+	}
+	nodeSet := resp.Proofs.NodeSet()
+	headerEnc := resp.AuxData[0]
+	if len(headerEnc) == 0 {
+		return errHeaderUnavailable
+	}
+	header := new(types.SnailHeader)
+	if err := rlp.DecodeBytes(headerEnc, header); err != nil {
+		return errHeaderUnavailable
+	}
+
+	// Verify the snail CHT
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], r.BlockNum)
+
+	reads := &readTraceDB{db: nodeSet}
+	value, _, err := trie.VerifyProof(r.ChtRoot, encNumber[:], reads)
+	if err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+
+	var node light.ChtNode
+	if err := rlp.DecodeBytes(value, &node); err != nil {
+		return err
+	}
+	if node.Hash != header.Hash() {
+		return errCHTHashMismatch
+	}
+	if r.BlockNum != header.Number.Uint64() {
+		return errCHTNumberMismatch
+	}
+	// Verifications passed, store and return
+	r.Header = header
+	r.Proof = nodeSet
+	r.Td = node.Td
+	return nil
+}
+
 type BloomReq struct {
 	BloomTrieNum, BitIdx, SectionIndex, FromLevel uint64
 }
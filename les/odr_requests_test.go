@@ -0,0 +1,179 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/light"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+// Tests that snail CHT replies are only accepted with a minimal proof of the
+// requested header against the trusted root.
+func TestSnailChtRequestValidate(t *testing.T) {
+	var headers []*types.SnailHeader
+	for i := 0; i < 4; i++ {
+		headers = append(headers, &types.SnailHeader{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(int64(1000 + i))})
+	}
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(abeydb.NewMemDatabase()))
+	for i, header := range headers {
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], uint64(i))
+		data, _ := rlp.EncodeToBytes(light.ChtNode{Hash: header.Hash(), Td: big.NewInt(int64(1000 * (i + 1)))})
+		tr.Update(key[:], data)
+	}
+	root, _ := tr.Commit(nil)
+
+	reply := func(number uint64, header *types.SnailHeader, extra bool) HelperTrieResps {
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], number)
+		proof := light.NewNodeSet()
+		tr.Prove(key[:], 0, proof)
+		if extra {
+			proof.Put(common.Hash{1}.Bytes(), []byte{0x01})
+		}
+		resp := HelperTrieResps{Proofs: proof.NodeList()}
+		if header != nil {
+			enc, _ := rlp.EncodeToBytes(header)
+			resp.AuxData = [][]byte{enc}
+		} else {
+			resp.AuxData = [][]byte{nil}
+		}
+		return resp
+	}
+	tests := []struct {
+		msg *Msg
+		err error
+	}{
+		{&Msg{MsgType: MsgHelperTrieProofs, Obj: reply(2, headers[2], false)}, nil},
+		{&Msg{MsgType: MsgProofsV2, Obj: reply(2, headers[2], false)}, errInvalidMessageType},
+		{&Msg{MsgType: MsgHelperTrieProofs, Obj: reply(2, nil, false)}, errHeaderUnavailable},
+		{&Msg{MsgType: MsgHelperTrieProofs, Obj: reply(2, headers[1], false)}, errCHTHashMismatch},
+		{&Msg{MsgType: MsgHelperTrieProofs, Obj: reply(2, headers[2], true)}, errUselessNodes},
+	}
+	for i, tt := range tests {
+		req := &SnailChtRequest{ChtRoot: root, ChtNum: 0, BlockNum: 2}
+		if err := req.Validate(abeydb.NewMemDatabase(), tt.msg); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.err == nil && (req.Header.Hash() != headers[2].Hash() || req.Td.Int64() != 3000) {
+			t.Errorf("test %d: result mismatch: have %x/%v, want %x/3000", i, req.Header.Hash(), req.Td, headers[2].Hash())
+		}
+	}
+}
+
+// Tests that receipt proof replies are only accepted with a minimal proof of
+// the requested receipt against the receipt hash of its header.
+func TestReceiptProofRequestValidate(t *testing.T) {
+	var receipts types.Receipts
+	for i := 0; i < 4; i++ {
+		receipt := types.NewReceipt(nil, i%2 == 1, uint64(21000*(i+1)))
+		receipt.Logs = []*types.Log{{Address: common.Address{byte(i)}, Topics: []common.Hash{crypto.Keccak256Hash([]byte{byte(i)})}}}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts = append(receipts, receipt)
+	}
+	tr := receiptTrie(receipts)
+	if root := types.DeriveSha(receipts); tr.Hash() != root {
+		t.Fatalf("receipt trie root mismatch: have %x, want %x", tr.Hash(), root)
+	}
+	header := &types.Header{Number: big.NewInt(1), ReceiptHash: tr.Hash()}
+
+	reply := func(index uint, extra bool) light.NodeList {
+		key, _ := rlp.EncodeToBytes(index)
+		proof := light.NewNodeSet()
+		tr.Prove(key, 0, proof)
+		if extra {
+			proof.Put(common.Hash{1}.Bytes(), []byte{0x01})
+		}
+		return proof.NodeList()
+	}
+	tests := []struct {
+		msg *Msg
+		err error
+	}{
+		{&Msg{MsgType: MsgReceiptProofs, Obj: reply(2, false)}, nil},
+		{&Msg{MsgType: MsgProofsV2, Obj: reply(2, false)}, errInvalidMessageType},
+		{&Msg{MsgType: MsgReceiptProofs, Obj: reply(2, true)}, errUselessNodes},
+	}
+	for i, tt := range tests {
+		req := &ReceiptProofRequest{Header: header, Index: 2}
+		if err := req.Validate(abeydb.NewMemDatabase(), tt.msg); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.err == nil {
+			have, _ := rlp.EncodeToBytes(req.Receipt)
+			want, _ := rlp.EncodeToBytes(receipts[2])
+			if string(have) != string(want) {
+				t.Errorf("test %d: receipt mismatch: have %x, want %x", i, have, want)
+			}
+		}
+	}
+	// The proof of a sibling receipt shares the root but misses the leaf
+	req := &ReceiptProofRequest{Header: header, Index: 2}
+	if err := req.Validate(abeydb.NewMemDatabase(), &Msg{MsgType: MsgReceiptProofs, Obj: reply(3, false)}); err == nil {
+		t.Errorf("proof of another receipt accepted")
+	}
+	// Receipts beyond the block are proven absent
+	req = &ReceiptProofRequest{Header: header, Index: 4}
+	if err := req.Validate(abeydb.NewMemDatabase(), &Msg{MsgType: MsgReceiptProofs, Obj: reply(4, false)}); err != errReceiptUnavailable {
+		t.Errorf("missing receipt: have error %v, want %v", err, errReceiptUnavailable)
+	}
+	// Proofs against another root are rejected
+	req = &ReceiptProofRequest{Header: &types.Header{Number: big.NewInt(1), ReceiptHash: common.Hash{1}}, Index: 2}
+	if err := req.Validate(abeydb.NewMemDatabase(), &Msg{MsgType: MsgReceiptProofs, Obj: reply(2, false)}); err == nil {
+		t.Errorf("proof against another root accepted")
+	}
+}
+
+// Tests that receipt proofs are only requested from and checked on LES/3 peers.
+func TestReceiptProofVersions(t *testing.T) {
+	tests := []struct {
+		version  int
+		checks   []uint64
+		supports bool
+	}{
+		{lpv1, reqListV1, false},
+		{lpv2, reqListV2, false},
+		{lpv3, reqListV3, true},
+	}
+	for _, tt := range tests {
+		p := &peer{version: tt.version}
+		if p.SupportsReceiptProofs() != tt.supports {
+			t.Errorf("les/%d: receipt proof support mismatch: have %v, want %v", tt.version, !tt.supports, tt.supports)
+		}
+		checked := false
+		for _, code := range tt.checks {
+			if code >= ProtocolLengths[uint(tt.version)] {
+				t.Errorf("les/%d: message %d beyond protocol length %d", tt.version, code, ProtocolLengths[uint(tt.version)])
+			}
+			checked = checked || code == GetReceiptProofsMsg
+		}
+		if checked != tt.supports {
+			t.Errorf("les/%d: receipt proof handshake check mismatch: have %v, want %v", tt.version, checked, tt.supports)
+		}
+	}
+}
@@ -78,6 +78,8 @@ type peer struct {
 	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
+
+	isTrusted bool // set if the peer is one of the ultra light client's trusted servers
 }
 
 func newPeer(version int, network uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -186,7 +188,7 @@ func (p *peer) GetTxRelayCost(amount, size int) uint64 {
 	switch p.version {
 	case lpv1:
 		msgcode = SendTxMsg
-	case lpv2:
+	case lpv2, lpv3:
 		msgcode = SendTxV2Msg
 	default:
 		panic(nil)
@@ -268,6 +270,11 @@ func (p *peer) SendHelperTrieProofs(reqID, bv uint64, resp HelperTrieResps) erro
 	return sendResponse(p.rw, HelperTrieProofsMsg, reqID, bv, resp)
 }
 
+// SendReceiptProofs sends a batch of receipt merkle proofs, corresponding to the ones requested.
+func (p *peer) SendReceiptProofs(reqID, bv uint64, proofs light.NodeList) error {
+	return sendResponse(p.rw, ReceiptProofsMsg, reqID, bv, proofs)
+}
+
 // SendTxStatus sends a batch of transaction status records, corresponding to the ones requested.
 func (p *peer) SendTxStatus(reqID, bv uint64, stats []txStatus) error {
 	return sendResponse(p.rw, TxStatusMsg, reqID, bv, stats)
@@ -313,7 +320,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -330,7 +337,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, data interface{}) err
 		}
 		p.Log().Debug("Fetching batch of header proofs", "count", len(reqs))
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		reqs, ok := data.([]HelperTrieReq)
 		if !ok {
 			return errInvalidHelpTrieReq
@@ -342,6 +349,18 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, data interface{}) err
 	}
 }
 
+// RequestReceiptProofs fetches a batch of receipt merkle proofs from a remote node.
+func (p *peer) RequestReceiptProofs(reqID, cost uint64, reqs []ReceiptProofReq) error {
+	p.Log().Debug("Fetching batch of receipt proofs", "count", len(reqs))
+	return sendRequest(p.rw, GetReceiptProofsMsg, reqID, cost, reqs)
+}
+
+// SupportsReceiptProofs tells if receipt proofs were negotiated with the
+// remote server, they were introduced in LES/3.
+func (p *peer) SupportsReceiptProofs() bool {
+	return p.version >= lpv3
+}
+
 // RequestTxStatus fetches a batch of transaction status records from a remote node.
 func (p *peer) RequestTxStatus(reqID, cost uint64, txHashes []common.Hash) error {
 	p.Log().Debug("Requesting transaction status", "count", len(txHashes))
@@ -354,7 +373,7 @@ func (p *peer) SendTxs(reqID, cost uint64, txs rlp.RawValue) error {
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
@@ -532,6 +551,8 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 			checkList = reqListV1
 		case lpv2:
 			checkList = reqListV2
+		case lpv3:
+			checkList = reqListV3
 		default:
 			panic(nil)
 		}
@@ -58,7 +58,7 @@ func NewLesServer(abey *abey.Abeychain, config *abey.Config) (*LesServer, error)
 	genesisHash := getGenesisHash(abey)
 	pm, err := NewProtocolManager(abey.BlockChain().Config(), light.DefaultServerIndexerConfig, false,
 		config.NetworkId, abey.EventMux(), abey.Engine(), newPeerSet(), abey.BlockChain(),
-		abey.TxPool(), abey.ChainDb(), nil, nil, nil, quitSync, new(sync.WaitGroup), genesisHash)
+		abey.TxPool(), abey.ChainDb(), nil, nil, nil, nil, quitSync, new(sync.WaitGroup), genesisHash)
 
 	if err != nil {
 		return nil, err
@@ -76,6 +76,7 @@ func NewLesServer(abey *abey.Abeychain, config *abey.Config) (*LesServer, error)
 			iConfig:          light.DefaultServerIndexerConfig,
 			chtIndexer:       light.NewChtIndexer(abey.ChainDb(), nil, params.CHTFrequencyServer, params.HelperTrieProcessConfirmations),
 			bloomTrieIndexer: light.NewBloomTrieIndexer(abey.ChainDb(), nil, params.BloomBitsBlocks, params.BloomTrieFrequency),
+			snailChtIndexer:  light.NewSnailChtIndexer(abey.ChainDb(), params.SnailCHTFrequency, params.HelperTrieProcessConfirmations),
 			protocolManager:  pm,
 		},
 		quitSync:  quitSync,
@@ -102,8 +103,16 @@ func NewLesServer(abey *abey.Abeychain, config *abey.Config) (*LesServer, error)
 		bloomTrieRoot := light.GetBloomTrieRoot(pm.chainDb, bloomTrieLastSection, bloomTrieSectionHead)
 		logger.Info("Loaded bloom trie", "section", bloomTrieLastSection, "head", bloomTrieSectionHead, "root", bloomTrieRoot)
 	}
+	snailChtSectionCount, _, _ := srv.snailChtIndexer.Sections()
+	if snailChtSectionCount != 0 {
+		snailChtLastSection := snailChtSectionCount - 1
+		snailChtSectionHead := srv.snailChtIndexer.SectionHead(snailChtLastSection)
+		snailChtRoot := light.GetSnailChtRoot(pm.chainDb, snailChtLastSection, snailChtSectionHead)
+		logger.Info("Loaded snail CHT", "section", snailChtLastSection, "head", snailChtSectionHead, "root", snailChtRoot)
+	}
 
 	srv.chtIndexer.Start(abey.BlockChain())
+	srv.snailChtIndexer.Start(abey.SnailBlockChain())
 	pm.server = srv
 
 	srv.defParams = &flowcontrol.ServerParams{
@@ -145,6 +154,7 @@ func (s *LesServer) SetBloomBitsIndexer(bloomIndexer *core.ChainIndexer) {
 // Stop stops the LES service
 func (s *LesServer) Stop() {
 	s.chtIndexer.Close()
+	s.snailChtIndexer.Close()
 	// bloom trie indexer is closed by parent bloombits indexer
 	s.fcCostStats.store()
 	s.fcManager.Stop()
@@ -136,6 +136,8 @@ type serverPool struct {
 	connCh                     chan *connReq
 	disconnCh                  chan *disconnReq
 	registerCh                 chan *registerReq
+
+	trustedNodes []*enode.Node // ultra light client servers, always kept connected
 }
 
 // newServerPool creates a new serverPool instance
@@ -154,6 +156,7 @@ func newServerPool(db abeydb.Database, quit chan struct{}, wg *sync.WaitGroup, t
 		knownSelect:  newWeightedRandomSelect(),
 		newSelect:    newWeightedRandomSelect(),
 		fastDiscover: true,
+		trustedNodes: parseTrustedNodes(trustedNodes),
 	}
 
 	pool.knownQueue = newPoolEntryQueue(maxKnownEntries, pool.removeEntry)
@@ -167,6 +170,7 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	pool.dbKey = append([]byte("serverPool/"), []byte(topic)...)
 	pool.wg.Add(1)
 	pool.loadNodes()
+	pool.connectToTrustedNodes()
 
 	if pool.server.DiscV5 != nil {
 		pool.discSetPeriod = make(chan time.Duration, 1)
@@ -179,6 +183,30 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	go pool.eventLoop()
 }
 
+// connectToTrustedNodes adds the ultra light client servers as trusted static
+// peers, so they are dialed regardless of the pool's selection.
+func (pool *serverPool) connectToTrustedNodes() {
+	for _, node := range pool.trustedNodes {
+		pool.server.AddTrustedPeer(node)
+		pool.server.AddPeer(node)
+		log.Debug("Added trusted node", "id", node.ID().String())
+	}
+}
+
+// parseTrustedNodes parses the enode URLs of the ultra light client servers
+func parseTrustedNodes(trustedNodes []string) []*enode.Node {
+	nodes := make([]*enode.Node, 0, len(trustedNodes))
+	for _, url := range trustedNodes {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			log.Warn("Trusted node URL invalid", "enode", url, "err", err)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
 // discoverNodes wraps SearchTopic, converting result nodes to enode.Node.
 func (pool *serverPool) discoverNodes() {
 	ch := make(chan *discv5.Node)
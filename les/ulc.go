@@ -0,0 +1,71 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/p2p/enode"
+)
+
+// ulc holds the settings of the ultra light client mode, in which a head is
+// only accepted once enough of the configured trusted servers announced it.
+type ulc struct {
+	trustedKeys        map[enode.ID]struct{}
+	minTrustedFraction int
+}
+
+// newULC creates the ultra light client settings, returns nil if the mode is
+// not configured.
+func newULC(ulcConfig *abey.ULCConfig) *ulc {
+	if ulcConfig == nil {
+		return nil
+	}
+	trustedKeys := make(map[enode.ID]struct{})
+	for _, url := range ulcConfig.TrustedServers {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			log.Error("Invalid trusted server", "url", url, "err", err)
+			continue
+		}
+		trustedKeys[node.ID()] = struct{}{}
+	}
+	if len(trustedKeys) == 0 {
+		return nil
+	}
+	fraction := ulcConfig.MinTrustedFraction
+	if fraction <= 0 || fraction > 100 {
+		log.Warn("Invalid minimum trusted fraction, using default", "fraction", fraction, "default", abey.DefaultULCMinTrustedFraction)
+		fraction = abey.DefaultULCMinTrustedFraction
+	}
+	return &ulc{
+		trustedKeys:        trustedKeys,
+		minTrustedFraction: fraction,
+	}
+}
+
+// isTrusted tells if the given node is one of the configured trusted servers.
+func (u *ulc) isTrusted(id enode.ID) bool {
+	_, ok := u.trustedKeys[id]
+	return ok
+}
+
+// quorum tells if the given number of agreeing trusted servers is enough to
+// accept a head.
+func (u *ulc) quorum(agreed int) bool {
+	return 100*agreed >= u.minTrustedFraction*len(u.trustedKeys)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"net"
+	"testing"
+
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/p2p/enode"
+)
+
+func testNodeURL(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	node := enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), 30313, 30313)
+	return key, node.String()
+}
+
+func TestULCDisabled(t *testing.T) {
+	if u := newULC(nil); u != nil {
+		t.Fatalf("ulc enabled without config")
+	}
+	if u := newULC(&abey.ULCConfig{TrustedServers: []string{"invalid"}}); u != nil {
+		t.Fatalf("ulc enabled without valid servers")
+	}
+}
+
+func TestULCQuorum(t *testing.T) {
+	var (
+		urls []string
+		ids  []enode.ID
+	)
+	for i := 0; i < 4; i++ {
+		key, url := testNodeURL(t)
+		urls = append(urls, url)
+		ids = append(ids, enode.PubkeyToIDV4(&key.PublicKey))
+	}
+	u := newULC(&abey.ULCConfig{TrustedServers: urls, MinTrustedFraction: 75})
+	if u == nil {
+		t.Fatalf("ulc not enabled")
+	}
+	for i, id := range ids {
+		if !u.isTrusted(id) {
+			t.Errorf("server %d not trusted", i)
+		}
+	}
+	if _, url := testNodeURL(t); u.isTrusted(enode.MustParseV4(url).ID()) {
+		t.Errorf("unknown server trusted")
+	}
+	for agreed, want := range []bool{false, false, false, true, true} {
+		if have := u.quorum(agreed); have != want {
+			t.Errorf("quorum(%d) mismatch: have %v, want %v", agreed, have, want)
+		}
+	}
+}
+
+func TestULCDefaultFraction(t *testing.T) {
+	_, url := testNodeURL(t)
+	u := newULC(&abey.ULCConfig{TrustedServers: []string{url}, MinTrustedFraction: 101})
+	if u.minTrustedFraction != abey.DefaultULCMinTrustedFraction {
+		t.Fatalf("fraction mismatch: have %d, want %d", u.minTrustedFraction, abey.DefaultULCMinTrustedFraction)
+	}
+}
@@ -59,6 +59,8 @@ type LightChain struct {
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
+	snailCheckpoint *params.TrustedCheckpoint // Checkpoint proving snail headers, nil if not trusted
+
 	bodyCache    *lru.Cache // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache // Cache for the most recent entire blocks
@@ -128,6 +130,9 @@ func (lc *LightChain) AddTrustedCheckpoint(cp *params.TrustedCheckpoint) {
 	if lc.odr.BloomIndexer() != nil {
 		lc.odr.BloomIndexer().AddCheckpoint(cp.SectionIndex, cp.SectionHead)
 	}
+	if !cp.SnailEmpty() {
+		lc.snailCheckpoint = cp
+	}
 	log.Info("Added trusted checkpoint", "block", (cp.SectionIndex+1)*lc.indexerConfig.ChtSize-1, "hash", cp.SectionHead)
 }
 
@@ -463,6 +468,12 @@ func (lc *LightChain) GetHeaderByNumberOdr(ctx context.Context, number uint64) (
 	return GetHeaderByNumber(ctx, lc.odr, number)
 }
 
+// GetSnailHeaderByNumberOdr retrieves a snail header from the database or the
+// network by number, proven against the snail CHT of the trusted checkpoint.
+func (lc *LightChain) GetSnailHeaderByNumberOdr(ctx context.Context, number uint64) (*types.SnailHeader, error) {
+	return GetSnailHeaderByNumber(ctx, lc.odr, lc.snailCheckpoint, number)
+}
+
 // Config retrieves the header chain's chain configuration.
 func (lc *LightChain) Config() *params.ChainConfig { return lc.hc.Config() }
 
@@ -27,6 +27,7 @@ import (
 	"github.com/abeychain/go-abey/abeydb"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/core/rawdb"
+	snailrawdb "github.com/abeychain/go-abey/core/snailchain/rawdb"
 	"github.com/abeychain/go-abey/core/types"
 )
 
@@ -134,6 +135,21 @@ func (req *ReceiptsRequest) StoreResult(db abeydb.Database) {
 	rawdb.WriteReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
+// ReceiptProofRequest is the ODR request type for a single receipt proven
+// against the receipt hash of its header
+type ReceiptProofRequest struct {
+	OdrRequest
+	Header  *types.Header
+	Index   uint
+	Receipt *types.Receipt
+	Proof   *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *ReceiptProofRequest) StoreResult(db abeydb.Database) {
+	req.Proof.Store(db)
+}
+
 // ChtRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
@@ -154,6 +170,25 @@ func (req *ChtRequest) StoreResult(db abeydb.Database) {
 	rawdb.WriteCanonicalHash(db, hash, num)
 }
 
+// SnailChtRequest is the ODR request type for snail headers proven by a snail CHT
+type SnailChtRequest struct {
+	OdrRequest
+	ChtNum, BlockNum uint64
+	ChtRoot          common.Hash
+	Header           *types.SnailHeader
+	Td               *big.Int
+	Proof            *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *SnailChtRequest) StoreResult(db abeydb.Database) {
+	hash, num := req.Header.Hash(), req.Header.Number.Uint64()
+
+	snailrawdb.WriteHeader(db, req.Header)
+	snailrawdb.WriteTd(db, hash, num, req.Td)
+	snailrawdb.WriteCanonicalHash(db, hash, num)
+}
+
 // BloomRequest is the ODR request type for retrieving bloom filters from a CHT structure
 type BloomRequest struct {
 	OdrRequest
@@ -24,8 +24,10 @@ import (
 
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/core/rawdb"
+	snailrawdb "github.com/abeychain/go-abey/core/snailchain/rawdb"
 	"github.com/abeychain/go-abey/core/types"
 	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/params"
 	"github.com/abeychain/go-abey/rlp"
 )
 
@@ -71,6 +73,38 @@ func GetHeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*typ
 	return r.Header, nil
 }
 
+// GetReceiptByProof retrieves the receipt of the transaction at the given index
+// of a block, verified against the receipt hash of the block header.
+func GetReceiptByProof(ctx context.Context, odr OdrBackend, header *types.Header, index uint) (*types.Receipt, error) {
+	r := &ReceiptProofRequest{Header: header, Index: index}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Receipt, nil
+}
+
+// GetSnailHeaderByNumber retrieves a snail header proven against the snail CHT
+// root of the given trusted checkpoint, or reads it locally if already known.
+func GetSnailHeaderByNumber(ctx context.Context, odr OdrBackend, checkpoint *params.TrustedCheckpoint, number uint64) (*types.SnailHeader, error) {
+	db := odr.Database()
+	if hash := snailrawdb.ReadCanonicalHash(db, number); hash != (common.Hash{}) {
+		if header := snailrawdb.ReadHeader(db, hash, number); header != nil {
+			return header, nil
+		}
+	}
+	if checkpoint == nil || checkpoint.SnailEmpty() {
+		return nil, ErrNoTrustedSnailCht
+	}
+	if number >= (checkpoint.SnailSectionIndex+1)*params.SnailCHTFrequency {
+		return nil, ErrNoTrustedSnailCht
+	}
+	r := &SnailChtRequest{ChtRoot: checkpoint.SnailCHTRoot, ChtNum: checkpoint.SnailSectionIndex, BlockNum: number}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Header, nil
+}
+
 func GetCanonicalHash(ctx context.Context, odr OdrBackend, number uint64) (common.Hash, error) {
 	hash := rawdb.ReadCanonicalHash(odr.Database(), number)
 	if (hash != common.Hash{}) {
@@ -0,0 +1,118 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/snailchain"
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+var (
+	ErrNoTrustedSnailCht = errors.New("no trusted snail canonical hash trie")
+	snailChtPrefix       = []byte("snailChtRoot-") // snailChtPrefix + chtNum (uint64 big endian) + sectionHead -> trie root hash
+	SnailChtTablePrefix  = "scht-"
+)
+
+// GetSnailChtRoot reads the snail CHT root associated to the given section from the database
+func GetSnailChtRoot(db abeydb.Database, sectionIdx uint64, sectionHead common.Hash) common.Hash {
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], sectionIdx)
+	data, _ := db.Get(append(append(snailChtPrefix, encNumber[:]...), sectionHead.Bytes()...))
+	return common.BytesToHash(data)
+}
+
+// StoreSnailChtRoot writes the snail CHT root associated to the given section into the database
+func StoreSnailChtRoot(db abeydb.Database, sectionIdx uint64, sectionHead, root common.Hash) {
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], sectionIdx)
+	db.Put(append(append(snailChtPrefix, encNumber[:]...), sectionHead.Bytes()...), root.Bytes())
+}
+
+// SnailChtIndexerBackend implements snailchain.ChainIndexerBackend. It builds a
+// canonical hash trie over snail headers, so light clients can verify any snail
+// header against a trusted root without downloading the whole snail chain.
+type SnailChtIndexerBackend struct {
+	diskdb, trieTable abeydb.Database
+	triedb            *trie.Database
+	section           uint64
+	lastHash          common.Hash
+	trie              *trie.Trie
+}
+
+// NewSnailChtIndexer creates a snail Cht chain indexer
+func NewSnailChtIndexer(db abeydb.Database, size, confirms uint64) *snailchain.ChainIndexer {
+	trieTable := abeydb.NewTable(db, SnailChtTablePrefix)
+	backend := &SnailChtIndexerBackend{
+		diskdb:    db,
+		trieTable: trieTable,
+		triedb:    trie.NewDatabaseWithCache(trieTable, 1), // Use a tiny cache only to keep memory down
+	}
+	return snailchain.NewChainIndexer(db, abeydb.NewTable(db, "schtIndex-"), backend, size, confirms, time.Millisecond*100, "scht")
+}
+
+// Reset implements snailchain.ChainIndexerBackend
+func (c *SnailChtIndexerBackend) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	var root common.Hash
+	if section > 0 {
+		root = GetSnailChtRoot(c.diskdb, section-1, lastSectionHead)
+	}
+	var err error
+	c.trie, err = trie.New(root, c.triedb)
+	c.section = section
+	return err
+}
+
+// Process implements snailchain.ChainIndexerBackend
+func (c *SnailChtIndexerBackend) Process(ctx context.Context, header *types.SnailHeader) error {
+	hash, num := header.Hash(), header.Number.Uint64()
+	c.lastHash = hash
+
+	td := rawdb.ReadTd(c.diskdb, hash, num)
+	if td == nil {
+		return fmt.Errorf("snail td not found: number %d, hash %x", num, hash)
+	}
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], num)
+	data, _ := rlp.EncodeToBytes(ChtNode{hash, td})
+	c.trie.Update(encNumber[:], data)
+	return nil
+}
+
+// Commit implements snailchain.ChainIndexerBackend
+func (c *SnailChtIndexerBackend) Commit() error {
+	root, err := c.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	c.triedb.Commit(root, false)
+
+	log.Info("Storing snail CHT", "section", c.section, "head", fmt.Sprintf("%064x", c.lastHash), "root", fmt.Sprintf("%064x", root))
+	StoreSnailChtRoot(c.diskdb, c.section, c.lastHash, root)
+	return nil
+}
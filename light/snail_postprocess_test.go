@@ -0,0 +1,194 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/snailchain/rawdb"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/rlp"
+	"github.com/abeychain/go-abey/trie"
+)
+
+// makeSnailHeaders writes a canonical chain of snail headers with their total
+// difficulties into the database.
+func makeSnailHeaders(db abeydb.Database, count int) []*types.SnailHeader {
+	var (
+		headers []*types.SnailHeader
+		parent  common.Hash
+		td      = new(big.Int)
+	)
+	for i := 0; i < count; i++ {
+		header := &types.SnailHeader{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(int64(1000 + i)),
+			Time:       big.NewInt(int64(600 * i)),
+		}
+		td.Add(td, header.Difficulty)
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteTd(db, header.Hash(), header.Number.Uint64(), td)
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	return headers
+}
+
+// indexSnailSection runs the snail CHT indexer backend over a section.
+func indexSnailSection(t *testing.T, backend *SnailChtIndexerBackend, section uint64, lastHead common.Hash, headers []*types.SnailHeader) {
+	if err := backend.Reset(context.Background(), section, lastHead); err != nil {
+		t.Fatalf("section %d: failed to reset: %v", section, err)
+	}
+	for _, header := range headers {
+		if err := backend.Process(context.Background(), header); err != nil {
+			t.Fatalf("section %d: failed to process header %d: %v", section, header.Number, err)
+		}
+	}
+	if err := backend.Commit(); err != nil {
+		t.Fatalf("section %d: failed to commit: %v", section, err)
+	}
+}
+
+func newSnailChtBackend(db abeydb.Database) *SnailChtIndexerBackend {
+	trieTable := abeydb.NewTable(db, SnailChtTablePrefix)
+	return &SnailChtIndexerBackend{diskdb: db, trieTable: trieTable, triedb: trie.NewDatabase(trieTable)}
+}
+
+// proveSnailHeader proves the CHT entry of a snail header against a root.
+func proveSnailHeader(triedb *trie.Database, root common.Hash, number uint64) (*ChtNode, error) {
+	tr, err := trie.New(root, triedb)
+	if err != nil {
+		return nil, err
+	}
+	var key [8]byte
+	binary.BigEndian.PutUint64(key[:], number)
+
+	proof := NewNodeSet()
+	if err := tr.Prove(key[:], 0, proof); err != nil {
+		return nil, err
+	}
+	value, _, err := trie.VerifyProof(root, key[:], proof)
+	if err != nil {
+		return nil, err
+	}
+	node := new(ChtNode)
+	if err := rlp.DecodeBytes(value, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Tests that the snail CHT of every section proves the hash and the total
+// difficulty of all the snail headers up to its head.
+func TestSnailChtIndexer(t *testing.T) {
+	db := abeydb.NewMemDatabase()
+	headers := makeSnailHeaders(db, 8)
+	backend := newSnailChtBackend(db)
+
+	indexSnailSection(t, backend, 0, common.Hash{}, headers[:4])
+	indexSnailSection(t, backend, 1, headers[3].Hash(), headers[4:])
+
+	for section, head := range []*types.SnailHeader{headers[3], headers[7]} {
+		root := GetSnailChtRoot(db, uint64(section), head.Hash())
+		if root == (common.Hash{}) {
+			t.Fatalf("section %d: missing root", section)
+		}
+		for _, header := range headers[:head.Number.Uint64()+1] {
+			node, err := proveSnailHeader(backend.triedb, root, header.Number.Uint64())
+			if err != nil {
+				t.Fatalf("section %d: failed to prove header %d: %v", section, header.Number, err)
+			}
+			if td := rawdb.ReadTd(db, header.Hash(), header.Number.Uint64()); node.Hash != header.Hash() || node.Td.Cmp(td) != 0 {
+				t.Errorf("section %d: header %d entry mismatch: have %x/%v, want %x/%v", section, header.Number, node.Hash, node.Td, header.Hash(), td)
+			}
+		}
+	}
+	// Headers without a total difficulty can't be indexed
+	orphan := &types.SnailHeader{Number: big.NewInt(8), Difficulty: common.Big1}
+	if err := backend.Process(context.Background(), orphan); err == nil {
+		t.Errorf("header without total difficulty indexed")
+	}
+}
+
+// snailOdr serves snail CHT requests from the database of a server, the way
+// the les retrieval would after validating the proof.
+type snailOdr struct {
+	OdrBackend
+	db, server abeydb.Database
+	triedb     *trie.Database
+	requests   int
+}
+
+func (o *snailOdr) Database() abeydb.Database { return o.db }
+
+func (o *snailOdr) Retrieve(ctx context.Context, req OdrRequest) error {
+	o.requests++
+
+	r := req.(*SnailChtRequest)
+	node, err := proveSnailHeader(o.triedb, r.ChtRoot, r.BlockNum)
+	if err != nil {
+		return err
+	}
+	r.Header = rawdb.ReadHeader(o.server, node.Hash, r.BlockNum)
+	r.Td = node.Td
+	r.StoreResult(o.db)
+	return nil
+}
+
+// Tests that light clients retrieve unknown snail headers proven against the
+// trusted snail CHT, and only within it.
+func TestGetSnailHeaderByNumber(t *testing.T) {
+	server := abeydb.NewMemDatabase()
+	headers := makeSnailHeaders(server, 8)
+	backend := newSnailChtBackend(server)
+	indexSnailSection(t, backend, 0, common.Hash{}, headers)
+
+	head := headers[len(headers)-1]
+	checkpoint := &params.TrustedCheckpoint{
+		SnailSectionIndex: 0,
+		SnailSectionHead:  head.Hash(),
+		SnailCHTRoot:      GetSnailChtRoot(server, 0, head.Hash()),
+	}
+	odr := &snailOdr{db: abeydb.NewMemDatabase(), server: server, triedb: backend.triedb}
+
+	if _, err := GetSnailHeaderByNumber(context.Background(), odr, nil, 1); err != ErrNoTrustedSnailCht {
+		t.Fatalf("retrieval without checkpoint: have %v, want %v", err, ErrNoTrustedSnailCht)
+	}
+	if _, err := GetSnailHeaderByNumber(context.Background(), odr, checkpoint, params.SnailCHTFrequency); err != ErrNoTrustedSnailCht {
+		t.Fatalf("retrieval beyond checkpoint: have %v, want %v", err, ErrNoTrustedSnailCht)
+	}
+	header, err := GetSnailHeaderByNumber(context.Background(), odr, checkpoint, 5)
+	if err != nil {
+		t.Fatalf("failed to retrieve header: %v", err)
+	}
+	if header.Hash() != headers[5].Hash() {
+		t.Fatalf("retrieved header mismatch: have %x, want %x", header.Hash(), headers[5].Hash())
+	}
+	// Retrieved headers are stored and served locally afterwards
+	if _, err := GetSnailHeaderByNumber(context.Background(), odr, nil, 5); err != nil || odr.requests != 1 {
+		t.Fatalf("stored header not served locally: err %v, requests %d", err, odr.requests)
+	}
+}
@@ -0,0 +1,243 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp format of rotated log file names, sorting
+// lexicographically by the time of the rotation.
+const backupTimeFormat = "20060102-150405.000"
+
+// RotatingWriter is an io.WriteCloser writing to a log file, rotating it once
+// it grows beyond a size limit or gets older than an age limit. Rotated files
+// are renamed after the time of their rotation, optionally compressed, and
+// only the most recent ones are retained.
+//
+// The writer appends to an existing log file on restart, so a node does not
+// need an external logrotate setup to keep its logs bounded.
+type RotatingWriter struct {
+	path       string
+	maxSize    int64         // Bytes after which the file is rotated, zero for no limit
+	maxAge     time.Duration // Age after which the file is rotated, zero for no limit
+	maxBackups int           // Number of rotated files retained, zero to retain all
+	compress   bool          // Whether rotated files are gzipped
+
+	file    *os.File
+	size    int64     // Bytes written to the current file
+	opened  time.Time // Time the current file started to be written to
+	closed  bool      // Whether the writer was closed
+	archive chan struct{}
+	wg      sync.WaitGroup
+	lock    sync.Mutex
+}
+
+// NewRotatingWriter opens the log file at path for appending, rotating it by
+// size and age and retaining up to maxBackups rotated files.
+func NewRotatingWriter(path string, maxSize int64, maxAge time.Duration, maxBackups int, compress bool) (*RotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	w := &RotatingWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+		compress:   compress,
+		archive:    make(chan struct{}, 1),
+	}
+	// Continue the file of a previous run unless it is already due for rotation
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	case (maxSize > 0 && info.Size() >= maxSize) || (maxAge > 0 && time.Since(info.ModTime()) >= maxAge):
+		if err := os.Rename(path, w.backupName(time.Now())); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go w.archiveLoop()
+	w.archive <- struct{}{} // Apply the retention to files left by previous runs
+
+	return w, nil
+}
+
+// Write implements io.Writer, rotating the file before the write if needed.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && ((w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize) || (w.maxAge > 0 && time.Since(w.opened) >= w.maxAge)) {
+		w.rotate()
+	}
+	if w.file == nil {
+		// A failed rotation lost the file, keep trying to reopen it
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer, closing the current file and waiting for the
+// pending rotated files to be archived.
+func (w *RotatingWriter) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.closed = true
+	close(w.archive)
+	w.lock.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+// open opens the log file for appending.
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size, w.opened = f, info.Size(), time.Now()
+	return nil
+}
+
+// rotate moves the current file aside and starts a new one. The rotated file is
+// compressed and the retention applied in the background. If the file can't be
+// moved, writing continues to it.
+func (w *RotatingWriter) rotate() {
+	w.file.Close()
+	w.file = nil
+
+	if err := os.Rename(w.path, w.backupName(time.Now())); err != nil {
+		w.open()
+		w.opened = time.Now() // Don't retry on every write
+		return
+	}
+	w.open()
+	select {
+	case w.archive <- struct{}{}:
+	default:
+	}
+}
+
+// backupName returns the name of the log file rotated at the given time.
+func (w *RotatingWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// backups returns the rotated log files, oldest first.
+func (w *RotatingWriter) backups() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ext    = filepath.Ext(w.path)
+		prefix = strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+		names  []string
+	)
+	for _, file := range files {
+		name := file.Name()
+		if !file.Mode().IsRegular() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(filepath.Dir(w.path), name))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// archiveLoop compresses the rotated files and removes the ones beyond the
+// retention count whenever a rotation happened, until the writer is closed.
+func (w *RotatingWriter) archiveLoop() {
+	defer w.wg.Done()
+
+	for range w.archive {
+		backups, err := w.backups()
+		if err != nil {
+			continue
+		}
+		if w.maxBackups > 0 && len(backups) > w.maxBackups {
+			for _, name := range backups[:len(backups)-w.maxBackups] {
+				os.Remove(name)
+			}
+			backups = backups[len(backups)-w.maxBackups:]
+		}
+		if !w.compress {
+			continue
+		}
+		// Failures can't be logged from here, it would recurse into the writer
+		for _, name := range backups {
+			if !strings.HasSuffix(name, ".gz") {
+				compressFile(name)
+			}
+		}
+	}
+}
+
+// compressFile gzips a file, replacing it with the compressed one.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
@@ -322,6 +322,18 @@ func (miner *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetCoinbaseProof enables signing a proof of controlling the coinbase key into
+// the extra-data of mined fruits and blocks, requiring the coinbase account to be unlocked.
+func (miner *Miner) SetCoinbaseProof(enabled bool) {
+	miner.worker.setCoinbaseProof(enabled)
+}
+
+// StaleReport returns how many locally mined snail blocks and fruits ended up
+// non-canonical, along with the most recent of them.
+func (miner *Miner) StaleReport() *StaleReport {
+	return miner.worker.stale.report()
+}
+
 // Pending returns the currently pending block and associated state.
 func (miner *Miner) Pending() (*types.Block, *state.StateDB) {
 	return miner.worker.pending()
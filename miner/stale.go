@@ -0,0 +1,302 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+const (
+	// staleBlockDepth is the number of snail blocks after which a locally
+	// mined block is judged canonical or orphaned.
+	staleBlockDepth = 12
+
+	// staleReportLimit is the number of stale items kept in the report.
+	staleReportLimit = 128
+)
+
+// staleReportKey is the database key the stale report is persisted under.
+var staleReportKey = []byte("miner-stale-report")
+
+// staleChain is the part of the snail chain the stale tracker judges the
+// locally mined items against.
+type staleChain interface {
+	CurrentBlock() *types.SnailBlock
+	GetHeaderByNumber(number uint64) *types.SnailHeader
+	GetFruitByFastHash(fastHash common.Hash) (*types.SnailBlock, uint64)
+}
+
+// staleItem is a locally mined block or fruit waiting to be settled.
+type staleItem struct {
+	number  uint64 // Snail number of blocks, fast number of fruits
+	pointer uint64 // Pointer number of fruits, for the freshness check
+	hash    common.Hash
+	fast    common.Hash
+	time    uint64
+	sealed  time.Time
+}
+
+// staleRecord is a settled item that did not make it into the canonical chain.
+type staleRecord struct {
+	Fruit      bool
+	Number     uint64
+	Hash       common.Hash
+	Winner     common.Hash // Competing item included instead, zero if expired
+	Time       uint64
+	WinnerTime uint64
+	Sealed     uint64
+	Settled    uint64
+}
+
+// staleRLP is the persisted form of the stale report.
+type staleRLP struct {
+	Blocks      uint64
+	StaleBlocks uint64
+	Fruits      uint64
+	StaleFruits uint64
+	Recent      []staleRecord
+}
+
+// StaleEntry is a locally mined block or fruit that ended up non-canonical.
+type StaleEntry struct {
+	Kind    string      `json:"kind"`    // "block" or "fruit"
+	Number  uint64      `json:"number"`  // Snail number of blocks, fast number of fruits
+	Hash    common.Hash `json:"hash"`    // Hash of the locally mined item
+	Winner  common.Hash `json:"winner"`  // Competing item included instead, zero if expired
+	Delta   int64       `json:"delta"`   // Winner timestamp minus ours in seconds
+	Sealed  uint64      `json:"sealed"`  // Local time the item was sealed
+	Settled uint64      `json:"settled"` // Local time the item was judged stale
+}
+
+// StaleReport summarizes how many locally mined items made it into the
+// canonical chain.
+type StaleReport struct {
+	Blocks         uint64        `json:"blocks"`
+	StaleBlocks    uint64        `json:"staleBlocks"`
+	BlockStaleRate float64       `json:"blockStaleRate"`
+	Fruits         uint64        `json:"fruits"`
+	StaleFruits    uint64        `json:"staleFruits"`
+	FruitStaleRate float64       `json:"fruitStaleRate"`
+	PendingBlocks  int           `json:"pendingBlocks"`
+	PendingFruits  int           `json:"pendingFruits"`
+	Recent         []*StaleEntry `json:"recent"`
+}
+
+// staleTracker follows locally mined blocks and fruits until they are either
+// settled in the canonical chain or lost to a competing item, and keeps a
+// persistent report of the lost ones.
+type staleTracker struct {
+	chain staleChain
+	db    abeydb.Database
+
+	blocks map[common.Hash]*staleItem
+	fruits map[common.Hash]*staleItem
+	stats  staleRLP
+	lock   sync.Mutex
+}
+
+// newStaleTracker creates a tracker, loading any previously persisted report.
+func newStaleTracker(chain staleChain, db abeydb.Database) *staleTracker {
+	t := &staleTracker{
+		chain:  chain,
+		db:     db,
+		blocks: make(map[common.Hash]*staleItem),
+		fruits: make(map[common.Hash]*staleItem),
+	}
+	if db == nil {
+		return t
+	}
+	if data, err := db.Get(staleReportKey); err == nil && len(data) > 0 {
+		if err := rlp.DecodeBytes(data, &t.stats); err != nil {
+			log.Warn("Failed to load stale report", "err", err)
+			t.stats = staleRLP{}
+		}
+	}
+	return t
+}
+
+// insertBlock starts tracking a locally mined snail block.
+func (t *staleTracker) insertBlock(block *types.SnailBlock) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.blocks[block.Hash()] = &staleItem{
+		number: block.NumberU64(),
+		hash:   block.Hash(),
+		time:   block.Time().Uint64(),
+		sealed: time.Now(),
+	}
+}
+
+// insertFruit starts tracking a locally mined fruit.
+func (t *staleTracker) insertFruit(fruit *types.SnailBlock) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.fruits[fruit.Hash()] = &staleItem{
+		number:  fruit.FastNumber().Uint64(),
+		pointer: fruit.PointNumber().Uint64(),
+		hash:    fruit.Hash(),
+		fast:    fruit.FastHash(),
+		time:    fruit.Time().Uint64(),
+		sealed:  time.Now(),
+	}
+}
+
+// settle judges the tracked items against the current canonical chain and
+// persists the report if any of them got settled.
+func (t *staleTracker) settle() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var (
+		head    = t.chain.CurrentBlock().NumberU64()
+		settled int
+	)
+	for hash, item := range t.blocks {
+		if head < item.number+staleBlockDepth {
+			continue
+		}
+		delete(t.blocks, hash)
+		settled++
+
+		t.stats.Blocks++
+		canon := t.chain.GetHeaderByNumber(item.number)
+		if canon != nil && canon.Hash() == hash {
+			continue
+		}
+		record := staleRecord{Number: item.number, Hash: hash, Time: item.time}
+		if canon != nil {
+			record.Winner, record.WinnerTime = canon.Hash(), canon.Time.Uint64()
+		}
+		t.stale(record, item)
+		log.Info("🔨 mined block became stale", "number", item.number, "hash", hash, "winner", record.Winner)
+	}
+	for hash, item := range t.fruits {
+		// Fruits are settled once their fast block is covered by any fruit,
+		// or lost when they got too stale to be included at all
+		record := staleRecord{Fruit: true, Number: item.number, Hash: hash, Time: item.time}
+		if block, index := t.chain.GetFruitByFastHash(item.fast); block != nil {
+			fruits := block.Fruits()
+			if int(index) >= len(fruits) {
+				continue
+			}
+			winner := fruits[index]
+			delete(t.fruits, hash)
+			settled++
+
+			t.stats.Fruits++
+			if winner.Hash() == hash {
+				continue
+			}
+			record.Winner, record.WinnerTime = winner.Hash(), winner.Time().Uint64()
+		} else {
+			if head+1 < item.pointer || head+1-item.pointer <= params.FruitFreshness.Uint64() {
+				continue
+			}
+			delete(t.fruits, hash)
+			settled++
+
+			t.stats.Fruits++
+		}
+		t.stale(record, item)
+		log.Info("🍒 mined fruit became stale", "number", item.number, "hash", hash, "winner", record.Winner)
+	}
+	if settled > 0 {
+		t.save()
+	}
+}
+
+// stale accounts a lost item in the report. The caller must hold the lock.
+func (t *staleTracker) stale(record staleRecord, item *staleItem) {
+	if record.Fruit {
+		t.stats.StaleFruits++
+	} else {
+		t.stats.StaleBlocks++
+	}
+	record.Sealed = uint64(item.sealed.Unix())
+	record.Settled = uint64(time.Now().Unix())
+
+	t.stats.Recent = append(t.stats.Recent, record)
+	if len(t.stats.Recent) > staleReportLimit {
+		t.stats.Recent = t.stats.Recent[len(t.stats.Recent)-staleReportLimit:]
+	}
+}
+
+// save persists the report. The caller must hold the lock.
+func (t *staleTracker) save() {
+	if t.db == nil {
+		return
+	}
+	data, err := rlp.EncodeToBytes(&t.stats)
+	if err != nil {
+		log.Warn("Failed to encode stale report", "err", err)
+		return
+	}
+	if err := t.db.Put(staleReportKey, data); err != nil {
+		log.Warn("Failed to store stale report", "err", err)
+	}
+}
+
+// report assembles the current stale report, most recent entries first.
+func (t *staleTracker) report() *StaleReport {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	report := &StaleReport{
+		Blocks:        t.stats.Blocks,
+		StaleBlocks:   t.stats.StaleBlocks,
+		Fruits:        t.stats.Fruits,
+		StaleFruits:   t.stats.StaleFruits,
+		PendingBlocks: len(t.blocks),
+		PendingFruits: len(t.fruits),
+		Recent:        make([]*StaleEntry, 0, len(t.stats.Recent)),
+	}
+	if report.Blocks > 0 {
+		report.BlockStaleRate = float64(report.StaleBlocks) / float64(report.Blocks)
+	}
+	if report.Fruits > 0 {
+		report.FruitStaleRate = float64(report.StaleFruits) / float64(report.Fruits)
+	}
+	for i := len(t.stats.Recent) - 1; i >= 0; i-- {
+		record := t.stats.Recent[i]
+		entry := &StaleEntry{
+			Kind:    "block",
+			Number:  record.Number,
+			Hash:    record.Hash,
+			Winner:  record.Winner,
+			Sealed:  record.Sealed,
+			Settled: record.Settled,
+		}
+		if record.Fruit {
+			entry.Kind = "fruit"
+		}
+		if record.Winner != (common.Hash{}) {
+			entry.Delta = int64(record.WinnerTime) - int64(record.Time)
+		}
+		report.Recent = append(report.Recent, entry)
+	}
+	return report
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/common"
+	"github.com/abeychain/go-abey/core/types"
+)
+
+// staleTestChain is a canonical chain of snail headers, with fruits indexed by
+// the fast block they cover.
+type staleTestChain struct {
+	head    uint64
+	headers map[uint64]*types.SnailHeader
+	fruits  map[common.Hash]*types.SnailBlock
+}
+
+func (c *staleTestChain) CurrentBlock() *types.SnailBlock {
+	return types.NewSnailBlockWithHeader(&types.SnailHeader{Number: new(big.Int).SetUint64(c.head)})
+}
+
+func (c *staleTestChain) GetHeaderByNumber(number uint64) *types.SnailHeader {
+	return c.headers[number]
+}
+
+func (c *staleTestChain) GetFruitByFastHash(fastHash common.Hash) (*types.SnailBlock, uint64) {
+	fruit := c.fruits[fastHash]
+	if fruit == nil {
+		return nil, 0
+	}
+	block := types.NewSnailBlockWithHeader(&types.SnailHeader{Number: new(big.Int).SetUint64(c.head)})
+	return block.WithBody([]*types.SnailBlock{fruit}, nil), 0
+}
+
+func newStaleTestItem(number, fast, pointer, time uint64, nonce byte) *types.SnailBlock {
+	return types.NewSnailBlockWithHeader(&types.SnailHeader{
+		Number:        new(big.Int).SetUint64(number),
+		FastNumber:    new(big.Int).SetUint64(fast),
+		FastHash:      common.BytesToHash([]byte{byte(fast)}),
+		PointerNumber: new(big.Int).SetUint64(pointer),
+		Time:          new(big.Int).SetUint64(time),
+		Extra:         []byte{nonce},
+	})
+}
+
+// Tests that locally mined blocks and fruits are judged once settled and that
+// the stale ones are persisted in the report.
+func TestStaleReport(t *testing.T) {
+	var (
+		db    = abeydb.NewMemDatabase()
+		chain = &staleTestChain{
+			head:    10,
+			headers: make(map[uint64]*types.SnailHeader),
+			fruits:  make(map[common.Hash]*types.SnailBlock),
+		}
+		tracker = newStaleTracker(chain, db)
+
+		canonBlock = newStaleTestItem(5, 0, 0, 100, 0)
+		staleBlock = newStaleTestItem(6, 0, 0, 110, 0)
+		winner     = newStaleTestItem(6, 0, 0, 113, 1)
+
+		ownFruit     = newStaleTestItem(0, 1, 8, 100, 0)
+		lostFruit    = newStaleTestItem(0, 2, 8, 100, 0)
+		rivalFruit   = newStaleTestItem(0, 2, 8, 98, 1)
+		expiredFruit = newStaleTestItem(0, 3, 8, 100, 0)
+	)
+	chain.headers[5] = canonBlock.Header()
+	chain.headers[6] = winner.Header()
+	chain.fruits[ownFruit.FastHash()] = ownFruit
+	chain.fruits[lostFruit.FastHash()] = rivalFruit
+
+	tracker.insertBlock(canonBlock)
+	tracker.insertBlock(staleBlock)
+	tracker.insertFruit(ownFruit)
+	tracker.insertFruit(lostFruit)
+	tracker.insertFruit(expiredFruit)
+
+	// Blocks are not deep enough yet, the expired fruit is still fresh
+	tracker.settle()
+	report := tracker.report()
+	if report.Fruits != 2 || report.StaleFruits != 1 || report.PendingFruits != 1 {
+		t.Fatalf("fruits mismatch: have %d/%d pending %d, want 2/1 pending 1", report.StaleFruits, report.Fruits, report.PendingFruits)
+	}
+	if report.Blocks != 0 || report.PendingBlocks != 2 {
+		t.Fatalf("blocks mismatch: have %d pending %d, want 0 pending 2", report.Blocks, report.PendingBlocks)
+	}
+	if len(report.Recent) != 1 || report.Recent[0].Winner != rivalFruit.Hash() || report.Recent[0].Delta != -2 {
+		t.Fatalf("stale fruit mismatch: have %+v", report.Recent)
+	}
+	// Move the head past both the block depth and the fruit freshness
+	chain.head = 30
+	tracker.settle()
+
+	report = newStaleTracker(chain, db).report()
+	if report.Blocks != 2 || report.StaleBlocks != 1 || report.BlockStaleRate != 0.5 {
+		t.Fatalf("blocks mismatch: have %d/%d rate %v, want 1/2 rate 0.5", report.StaleBlocks, report.Blocks, report.BlockStaleRate)
+	}
+	if report.Fruits != 3 || report.StaleFruits != 2 {
+		t.Fatalf("fruits mismatch: have %d/%d, want 2/3", report.StaleFruits, report.Fruits)
+	}
+	if len(report.Recent) != 3 {
+		t.Fatalf("recent entries mismatch: have %d, want 3", len(report.Recent))
+	}
+	for _, entry := range report.Recent {
+		switch entry.Hash {
+		case staleBlock.Hash():
+			if entry.Kind != "block" || entry.Winner != winner.Hash() || entry.Delta != 3 {
+				t.Errorf("stale block mismatch: have %+v", entry)
+			}
+		case expiredFruit.Hash():
+			if entry.Kind != "fruit" || entry.Winner != (common.Hash{}) || entry.Delta != 0 {
+				t.Errorf("expired fruit mismatch: have %+v", entry)
+			}
+		case lostFruit.Hash():
+		default:
+			t.Errorf("unexpected stale entry %x", entry.Hash)
+		}
+	}
+}
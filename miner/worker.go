@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/abeychain/go-abey/abeydb"
+	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/common"
 	"github.com/abeychain/go-abey/consensus"
 	"github.com/abeychain/go-abey/core"
@@ -131,6 +132,7 @@ type worker struct {
 	coinbase  common.Address
 	extra     []byte
 	fruitOnly bool   // only miner fruit
+	signProof bool   // sign the coinbase proof into the extra-data after TIP13
 	publickey []byte // for publickey
 
 	currentMu sync.Mutex
@@ -144,6 +146,7 @@ type worker struct {
 	possibleUncles map[common.Hash]*types.SnailBlock
 
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
+	stale       *staleTracker      // locally mined blocks and fruits tracked for the stale report
 
 	// atomic status counters
 	mining            int32
@@ -177,17 +180,20 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		coinbase:          coinbase,
 		agents:            make(map[Agent]struct{}),
 		unconfirmed:       newUnconfirmedBlocks(abey.SnailBlockChain(), miningLogAtDepth),
+		stale:             newStaleTracker(abey.SnailBlockChain(), abey.ChainDb()),
 		fastBlockNumber:   big.NewInt(0),
 		atCommintNewWoker: false,
 		fruitPoolMap:      make(map[uint64]*types.SnailBlock),
 	}
-	// Subscribe events for blockchain
-	worker.chainHeadSub = abey.SnailBlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
-	worker.chainSideSub = abey.SnailBlockChain().SubscribeChainSideEvent(worker.chainSideCh)
-	worker.minedfruitSub = abey.SnailBlockChain().SubscribeNewFruitEvent(worker.minedfruitCh)
+	// Subscribe events for blockchain. The events only trigger a look at the
+	// current chain, so a stalled worker drops the oldest ones instead of
+	// blocking the chain insertion.
+	worker.chainHeadSub = event.SubscribeBounded("miner/snailhead", worker.chainHeadCh, abey.SnailBlockChain().SubscribeChainHeadEvent)
+	worker.chainSideSub = event.SubscribeBounded("miner/snailside", worker.chainSideCh, abey.SnailBlockChain().SubscribeChainSideEvent)
+	worker.minedfruitSub = event.SubscribeBounded("miner/minedfruit", worker.minedfruitCh, abey.SnailBlockChain().SubscribeNewFruitEvent)
 
-	worker.fruitSub = abey.SnailPool().SubscribeNewFruitEvent(worker.fruitCh)
-	worker.fastchainEventSub = worker.fastchain.SubscribeChainEvent(worker.fastchainEventCh)
+	worker.fruitSub = event.SubscribeBounded("miner/fruitpool", worker.fruitCh, abey.SnailPool().SubscribeNewFruitEvent)
+	worker.fastchainEventSub = event.SubscribeBounded("miner/fastchain", worker.fastchainEventCh, worker.fastchain.SubscribeChainEvent)
 
 	go worker.update()
 	go worker.wait()
@@ -234,6 +240,12 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+func (w *worker) setCoinbaseProof(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.signProof = enabled
+}
+
 func (w *worker) pending() (*types.Block, *state.StateDB) {
 	if atomic.LoadInt32(&w.mining) == 0 {
 		// return a snapshot to avoid contention on currentMu mutex
@@ -350,6 +362,7 @@ func (w *worker) update() {
 		select {
 		// Handle ChainHeadEvent
 		case ev := <-w.chainHeadCh:
+			w.stale.settle()
 			if !w.atCommintNewWoker {
 				log.Debug("star commit new work  chainHeadCh", "chain block number", ev.Block.Number())
 				if atomic.LoadInt32(&w.mining) == 1 {
@@ -447,8 +460,10 @@ func (w *worker) wait() {
 					var newFruits []*types.SnailBlock
 					newFruits = append(newFruits, block)
 					w.abey.SnailPool().AddRemoteFruits(newFruits, true)
+					w.mux.Post(types.NewMinedFruitEvent{Block: block})
 					// store the mined fruit to woker.minedfruit
 					w.minedFruit = types.CopyFruit(block)
+					w.stale.insertFruit(block)
 				} else {
 					if w.minedFruit.FastNumber().Cmp(block.FastNumber()) != 0 {
 
@@ -456,8 +471,10 @@ func (w *worker) wait() {
 						var newFruits []*types.SnailBlock
 						newFruits = append(newFruits, block)
 						w.abey.SnailPool().AddRemoteFruits(newFruits, true)
+						w.mux.Post(types.NewMinedFruitEvent{Block: block})
 						// store the mined fruit to woker.minedfruit
 						w.minedFruit = types.CopyFruit(block)
+						w.stale.insertFruit(block)
 					}
 				}
 
@@ -480,6 +497,10 @@ func (w *worker) wait() {
 				fruits := block.Fruits()
 				log.Info("+++++ mined block  ---  ", "block number", block.Number(), "fruits", len(fruits), "first", fruits[0].FastNumber(), "end", fruits[len(fruits)-1].FastNumber())
 
+				// Propagate the sealed block before writing it, the write may
+				// wait for a remote import to finish
+				w.mux.Post(types.NewSealedBlockEvent{Block: block})
+
 				stat, err := w.chain.WriteMinedCanonicalBlock(block)
 				if err != nil {
 					log.Error("Failed writing block to chain", "err", err)
@@ -503,6 +524,7 @@ func (w *worker) wait() {
 
 				// Insert the block into the set of pending ones to wait for confirmations
 				w.unconfirmed.Insert(block.NumberU64(), block.Hash())
+				w.stale.insertBlock(block)
 
 			}
 		}
@@ -695,6 +717,11 @@ func (w *worker) commitNewWork() {
 		w.atCommintNewWoker = false
 		return
 	}
+	if w.signProof && atomic.LoadInt32(&w.mining) == 1 && w.config.IsTIP13(work.Block.FastNumber()) {
+		if err := w.signCoinbaseProof(work); err != nil {
+			log.Warn("Failed to sign coinbase proof", "coinbase", work.Block.Coinbase(), "err", err)
+		}
+	}
 
 	// We only care about logging if we're actually mining.
 	if atomic.LoadInt32(&w.mining) == 1 {
@@ -706,6 +733,26 @@ func (w *worker) commitNewWork() {
 	w.updateSnapshot()
 }
 
+// signCoinbaseProof appends a proof of controlling the coinbase key to the
+// extra-data of the work block, signed by the unlocked coinbase account.
+func (w *worker) signCoinbaseProof(work *Work) error {
+	header := work.Block.Header()
+	account := accounts.Account{Address: header.Coinbase}
+	wallet, err := w.abey.AccountManager().Find(account)
+	if err != nil {
+		return err
+	}
+	proof, err := wallet.SignHash(account, types.CoinbaseProofHash(header).Bytes())
+	if err != nil {
+		return err
+	}
+	if header.Extra, err = types.AppendCoinbaseProof(header, proof); err != nil {
+		return err
+	}
+	work.Block = work.Block.WithSeal(header)
+	return nil
+}
+
 func (w *worker) commitUncle(work *Work, uncle *types.SnailHeader) error {
 	hash := uncle.Hash()
 	if work.uncles.Has(hash) {
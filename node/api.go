@@ -75,6 +75,35 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// ListBans returns the nodes currently refused as peers for misbehaving.
+func (api *PrivateAdminAPI) ListBans() ([]*p2p.BanRecord, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bans(), nil
+}
+
+// Unban lifts the ban of a node, given by its enode URL or node ID, and forgets
+// its past offenses.
+func (api *PrivateAdminAPI) Unban(node string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, err := enode.ParseID(node)
+	if err != nil {
+		n, perr := enode.ParseV4(node)
+		if perr != nil {
+			return false, fmt.Errorf("invalid node ID or enode: %v", perr)
+		}
+		id = n.ID()
+	}
+	return server.Unban(id), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -175,6 +204,15 @@ func (api *PrivateAdminAPI) StopRPC() (bool, error) {
 	return true, nil
 }
 
+// RPCUsage returns the consumption of all API keys of the HTTP RPC endpoint in
+// the current quota period.
+func (api *PrivateAdminAPI) RPCUsage() ([]*rpc.UsageReport, error) {
+	if api.node.httpMetering == nil {
+		return nil, fmt.Errorf("HTTP RPC metering disabled")
+	}
+	return api.node.httpMetering.Reports()
+}
+
 // StartWS starts the websocket RPC API server.
 func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *string, apis *string) (bool, error) {
 	api.node.lock.Lock()
@@ -251,6 +289,16 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerLatency retrieves the round trip time and latency tier of each peer,
+// fastest first.
+func (api *PublicAdminAPI) PeerLatency() (*p2p.LatencyMap, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.LatencyMap(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
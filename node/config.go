@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/abeychain/go-abey/accounts"
 	"github.com/abeychain/go-abey/accounts/keystore"
@@ -33,6 +34,7 @@ import (
 	"github.com/abeychain/go-abey/log"
 	"github.com/abeychain/go-abey/p2p"
 	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/rpc"
 )
 
 const (
@@ -67,6 +69,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// ReadOnly opens the databases in the data directory read-only, keeping any
+	// writes in memory, and disables peer-to-peer networking. It is meant for
+	// serving RPC queries from a snapshot of another node's data directory.
+	ReadOnly bool `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -83,6 +90,15 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt KDF parameters of
+	// the key store when non-zero.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
+	// KeyCacheTTL is how long decrypted keys stay cached after use, so that
+	// unlocking them again skips the scrypt KDF. Zero disables the cache.
+	KeyCacheTTL time.Duration `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
@@ -120,6 +136,22 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPAPIKeys is the list of API keys accepted by the HTTP RPC interface, sent
+	// in the X-API-Key header or the apikey query parameter. If the list is empty,
+	// requests are accepted without a key but still metered by the one they carry.
+	HTTPAPIKeys []string `toml:",omitempty"`
+
+	// HTTPQuotas is the number of calls every API key may make to a method of the
+	// HTTP RPC interface within a quota period, "*" applying to all other methods.
+	HTTPQuotas map[string]uint64 `toml:",omitempty"`
+
+	// HTTPQuotaPeriod is the length of the period the HTTP RPC quotas apply to.
+	HTTPQuotaPeriod time.Duration `toml:",omitempty"`
+
+	// HTTPUsageStore keeps the request counters of the API keys. If nil, they are
+	// kept in memory and lost on restart.
+	HTTPUsageStore rpc.UsageStore `toml:"-"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -146,6 +178,19 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSMaxConnections is the maximum number of concurrent websocket clients,
+	// zero meaning no limit.
+	WSMaxConnections int `toml:",omitempty"`
+
+	// WSMaxSubscriptions is the maximum number of subscriptions a websocket client
+	// may hold open, zero meaning no limit.
+	WSMaxSubscriptions int `toml:",omitempty"`
+
+	// WSSendQueue is the number of notifications queued for a websocket client
+	// before it is considered unable to keep up and disconnected. If zero, the
+	// notifications are sent synchronously, stalling their producers instead.
+	WSSendQueue int `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -204,6 +249,29 @@ func (c *Config) HTTPEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.HTTPHost, c.HTTPPort)
 }
 
+// HTTPMetering creates the per API key request metering of the HTTP endpoint,
+// nil if neither API keys nor quotas are configured.
+func (c *Config) HTTPMetering() *rpc.Metering {
+	if len(c.HTTPAPIKeys) == 0 && len(c.HTTPQuotas) == 0 {
+		return nil
+	}
+	return rpc.NewMetering(rpc.MeteringConfig{
+		Keys:   c.HTTPAPIKeys,
+		Quotas: c.HTTPQuotas,
+		Period: c.HTTPQuotaPeriod,
+		Store:  c.HTTPUsageStore,
+	})
+}
+
+// WSLimits returns the resource limits of the websocket clients.
+func (c *Config) WSLimits() rpc.WebsocketLimits {
+	return rpc.WebsocketLimits{
+		MaxConnections:   c.WSMaxConnections,
+		MaxSubscriptions: c.WSMaxSubscriptions,
+		SendQueue:        c.WSSendQueue,
+	}
+}
+
 // DefaultHTTPEndpoint returns the HTTP endpoint used by default.
 func DefaultHTTPEndpoint() string {
 	config := &Config{HTTPHost: DefaultHTTPHost, HTTPPort: DefaultHTTPPort}
@@ -381,6 +449,12 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KeyStoreScryptN != 0 {
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP != 0 {
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -417,9 +491,9 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		return nil, "", err
 	}
 	// Assemble the account manager and supported backends
-	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
-	}
+	ks := keystore.NewKeyStore(keydir, scryptN, scryptP)
+	ks.SetKeyCacheTTL(conf.KeyCacheTTL)
+	backends := []accounts.Backend{ks}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -35,12 +35,14 @@ const (
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:          DefaultDataDir(),
-	HTTPPort:         DefaultHTTPPort,
-	HTTPModules:      []string{"net", "web3"},
-	HTTPVirtualHosts: []string{"localhost"},
-	WSPort:           DefaultWSPort,
-	WSModules:        []string{"net", "web3"},
+	DataDir:            DefaultDataDir(),
+	HTTPPort:           DefaultHTTPPort,
+	HTTPModules:        []string{"net", "web3"},
+	HTTPVirtualHosts:   []string{"localhost"},
+	WSPort:             DefaultWSPort,
+	WSModules:          []string{"net", "web3"},
+	WSMaxSubscriptions: 256,
+	WSSendQueue:        1024,
 	P2P: p2p.Config{
 		ListenAddr: ":30313",
 		MaxPeers:   100,
@@ -51,17 +51,19 @@ type Node struct {
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
 
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	rpcAPIs       []rpc.API          // List of APIs currently provided by the node
+	rpcCache      *rpc.ResponseCache // Cache of the responses about immutable data (nil = disabled)
+	inprocHandler *rpc.Server        // In-process RPC request handler to process the API requests
 
 	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
-	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
-	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
-	httpListener  net.Listener // HTTP RPC listener socket to server API requests
-	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpEndpoint  string        // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
+	httpWhitelist []string      // HTTP RPC modules to allow through this endpoint
+	httpListener  net.Listener  // HTTP RPC listener socket to server API requests
+	httpHandler   *rpc.Server   // HTTP RPC request handler to process the API requests
+	httpMetering  *rpc.Metering // HTTP RPC request metering by API key (nil = disabled)
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
@@ -115,6 +117,7 @@ func New(conf *Config) (*Node, error) {
 		serviceFuncs:      []ServiceConstructor{},
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
+		httpMetering:      conf.HTTPMetering(),
 		wsEndpoint:        conf.WSEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
@@ -185,6 +188,13 @@ func (n *Node) Start() error {
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
+	// A read-only node serves RPC only, it must not sync or relay anything
+	if n.config.ReadOnly {
+		n.serverConfig.MaxPeers, n.serverConfig.NoDial, n.serverConfig.NoDiscovery = 0, true, true
+		n.serverConfig.DiscoveryV5, n.serverConfig.ListenAddr = false, ""
+		n.serverConfig.StaticNodes, n.serverConfig.TrustedNodes = nil, nil
+		n.log.Info("Running in read-only mode, peer-to-peer networking disabled")
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
@@ -277,6 +287,9 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	apis := n.apis()
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
+		if cacher, ok := service.(ResponseCacher); ok && n.rpcCache == nil {
+			n.rpcCache = cacher.ResponseCache()
+		}
 	}
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
@@ -359,7 +372,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts)
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, n.httpMetering, n.rpcCache)
 	if err != nil {
 		return err
 	}
@@ -392,7 +405,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, n.rpcCache, n.config.WSLimits())
 	if err != nil {
 		return err
 	}
@@ -434,7 +447,7 @@ func (n *Node) Stop() error {
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
-	n.rpcAPIs = nil
+	n.rpcAPIs, n.rpcCache = nil, nil
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
 	}
@@ -598,9 +611,22 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (abeydb.Database, e
 	if n.config.DataDir == "" {
 		return abeydb.NewMemDatabase(), nil
 	}
+	if n.config.ReadOnly {
+		return openReadOnlyDatabase(n.config.ResolvePath(name), cache, handles)
+	}
 	return abeydb.NewLDBDatabase(n.config.ResolvePath(name), cache, handles)
 }
 
+// openReadOnlyDatabase opens an existing database read-only, keeping any write
+// done by the node in memory on top of it.
+func openReadOnlyDatabase(file string, cache, handles int) (abeydb.Database, error) {
+	db, err := abeydb.NewReadOnlyLDBDatabase(file, cache, handles)
+	if err != nil {
+		return nil, err
+	}
+	return abeydb.NewReadOnlyDatabase(db), nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
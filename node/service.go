@@ -43,6 +43,9 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (ab
 	if ctx.config.DataDir == "" {
 		return abeydb.NewMemDatabase(), nil
 	}
+	if ctx.config.ReadOnly {
+		return openReadOnlyDatabase(ctx.config.ResolvePath(name), cache, handles)
+	}
 	db, err := abeydb.NewLDBDatabase(ctx.config.ResolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
@@ -96,3 +99,10 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// ResponseCacher is implemented by services knowing which of their RPC results
+// only depend on immutable data. The HTTP and websocket endpoints serve these
+// from the returned cache, which may be nil if caching is disabled.
+type ResponseCacher interface {
+	ResponseCache() *rpc.ResponseCache
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+const (
+	// DefaultBanDuration is the cool-down of a first offense if none is configured.
+	DefaultBanDuration = 10 * time.Minute
+
+	maxBanDuration = 24 * time.Hour     // Cool-down cap for repeat offenders
+	banMemory      = 7 * 24 * time.Hour // Time after a ban expired until past offenses are forgotten
+)
+
+var (
+	errBanned = errors.New("banned")
+
+	banMeter         = metrics.NewRegisteredMeter("p2p/bans", nil)
+	bannedConnsMeter = metrics.NewRegisteredMeter("p2p/bans/refused", nil)
+)
+
+// BanRecord describes why and until when a node is refused as a peer.
+type BanRecord struct {
+	ID       enode.ID  `json:"id"`
+	Reason   string    `json:"reason"`   // Reason of the last offense
+	Offenses uint64    `json:"offenses"` // Number of offenses not yet forgotten
+	Banned   time.Time `json:"banned"`   // Time of the last offense
+	Expires  time.Time `json:"expires"`  // End of the cool-down of the last offense
+}
+
+// storedBan is the RLP encoding of a ban record in the node database.
+type storedBan struct {
+	Reason   string
+	Offenses uint64
+	Banned   uint64
+	Expires  uint64
+}
+
+// banList tracks the nodes banned for misbehaving, persisting the records in
+// the node database so they survive restarts. Every repeat offense doubles the
+// cool-down of the previous one.
+type banList struct {
+	db      *enode.DB
+	base    time.Duration
+	records map[enode.ID]*BanRecord
+
+	now  func() time.Time // Current time, replaceable for tests
+	lock sync.Mutex
+}
+
+// newBanList creates a ban list with the given cool-down for a first offense,
+// loading the records stored in the node database.
+func newBanList(db *enode.DB, base time.Duration) *banList {
+	if base <= 0 {
+		base = DefaultBanDuration
+	}
+	b := &banList{
+		db:      db,
+		base:    base,
+		records: make(map[enode.ID]*BanRecord),
+		now:     time.Now,
+	}
+	for id, blob := range db.Bans() {
+		var stored storedBan
+		if err := rlp.DecodeBytes(blob, &stored); err != nil {
+			log.Debug("Dropping invalid ban record", "id", id, "err", err)
+			db.DeleteBan(id)
+			continue
+		}
+		b.records[id] = &BanRecord{
+			ID:       id,
+			Reason:   stored.Reason,
+			Offenses: stored.Offenses,
+			Banned:   time.Unix(int64(stored.Banned), 0),
+			Expires:  time.Unix(int64(stored.Expires), 0),
+		}
+	}
+	b.forget()
+	return b
+}
+
+// add records an offense of the node, banning it for a cool-down growing with
+// the number of its past offenses.
+func (b *banList) add(id enode.ID, reason string) BanRecord {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	record, ok := b.records[id]
+	if !ok || now.After(record.Expires.Add(banMemory)) {
+		record = &BanRecord{ID: id}
+		b.records[id] = record
+	}
+	record.Offenses++
+
+	duration := b.base
+	for i := uint64(1); i < record.Offenses && duration < maxBanDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxBanDuration {
+		duration = maxBanDuration
+	}
+	record.Reason, record.Banned, record.Expires = reason, now, now.Add(duration)
+	banMeter.Mark(1)
+
+	blob, err := rlp.EncodeToBytes(&storedBan{
+		Reason:   record.Reason,
+		Offenses: record.Offenses,
+		Banned:   uint64(record.Banned.Unix()),
+		Expires:  uint64(record.Expires.Unix()),
+	})
+	if err == nil {
+		err = b.db.UpdateBan(id, blob)
+	}
+	if err != nil {
+		log.Warn("Failed to store ban record", "id", id, "err", err)
+	}
+	return *record
+}
+
+// banned reports whether the node is currently refused.
+func (b *banList) banned(id enode.ID) bool {
+	if b == nil {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	record, ok := b.records[id]
+	return ok && b.now().Before(record.Expires)
+}
+
+// remove lifts the ban of the node and forgets its past offenses, returning
+// whether it was banned.
+func (b *banList) remove(id enode.ID) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	record, ok := b.records[id]
+	if !ok {
+		return false
+	}
+	delete(b.records, id)
+	if err := b.db.DeleteBan(id); err != nil {
+		log.Warn("Failed to delete ban record", "id", id, "err", err)
+	}
+	return b.now().Before(record.Expires)
+}
+
+// list returns the currently banned nodes, sorted by the end of their ban.
+func (b *banList) list() []*BanRecord {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.forget()
+
+	now := b.now()
+	bans := make([]*BanRecord, 0, len(b.records))
+	for _, record := range b.records {
+		if now.Before(record.Expires) {
+			cpy := *record
+			bans = append(bans, &cpy)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Expires.Before(bans[j].Expires) })
+	return bans
+}
+
+// forget drops the records of the nodes that behaved for long enough since
+// their last ban expired. The caller must hold the lock, if needed.
+func (b *banList) forget() {
+	now := b.now()
+	for id, record := range b.records {
+		if now.After(record.Expires.Add(banMemory)) {
+			delete(b.records, id)
+			b.db.DeleteBan(id)
+		}
+	}
+}
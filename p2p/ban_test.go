@@ -0,0 +1,71 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abeychain/go-abey/p2p/enode"
+)
+
+// Tests that bans escalate with repeat offenses, survive reloading from the
+// node database, and are forgotten after behaving long enough.
+func TestBanList(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	now := time.Unix(time.Now().Unix(), 0) // Loading forgets records against the wall clock
+	bans := newBanList(db, time.Minute)
+	bans.now = func() time.Time { return now }
+
+	id := enode.ID{1}
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		record := bans.add(id, "test")
+		if record.Offenses != uint64(i+1) || record.Expires.Sub(now) != want {
+			t.Fatalf("offense %d: record mismatch: have %d offenses for %v, want %d for %v", i, record.Offenses, record.Expires.Sub(now), i+1, want)
+		}
+	}
+	if !bans.banned(id) || bans.banned(enode.ID{2}) {
+		t.Fatalf("ban state mismatch")
+	}
+	// Reloading from the database must restore the ban
+	reloaded := newBanList(db, time.Minute)
+	reloaded.now = bans.now
+	if list := reloaded.list(); len(list) != 1 || list[0].ID != id || list[0].Offenses != 3 || list[0].Reason != "test" {
+		t.Fatalf("reloaded bans mismatch: have %v", list)
+	}
+	// Expired bans are lifted, but the offenses remembered for a while
+	now = now.Add(5 * time.Minute)
+	if bans.banned(id) || len(bans.list()) != 0 {
+		t.Fatalf("expired ban still active")
+	}
+	if record := bans.add(id, "test"); record.Offenses != 4 {
+		t.Fatalf("repeat offense not escalated: have %d offenses", record.Offenses)
+	}
+	now = now.Add(maxBanDuration + banMemory + time.Second)
+	if record := bans.add(id, "test"); record.Offenses != 1 {
+		t.Fatalf("old offenses not forgotten: have %d offenses", record.Offenses)
+	}
+	// Unbanning must lift the ban and clear the database
+	if !bans.remove(id) || bans.banned(id) {
+		t.Fatalf("ban not lifted")
+	}
+	if stored := db.Bans(); len(stored) != 0 {
+		t.Fatalf("ban record not deleted: have %d records", len(stored))
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package capture records the protocol messages received from peers into a
+// capture file and replays them against a fresh node, reproducing the exact
+// message sequence a node observed on the network.
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/rlp"
+)
+
+var errNoRecords = errors.New("no captured messages")
+
+// Record is a single protocol message received from a peer.
+type Record struct {
+	Time    uint64   // Unix time of the reception in nanoseconds
+	Peer    enode.ID // Node the message was received from
+	Version uint     // Protocol version negotiated with the peer
+	Code    uint64   // Message code within the protocol
+	Payload []byte   // Raw RLP payload of the message
+}
+
+// ReceivedAt returns the time the message was received.
+func (r *Record) ReceivedAt() time.Time {
+	return time.Unix(0, int64(r.Time))
+}
+
+// Recorder appends the messages received from peers to a capture file. It is
+// safe for concurrent use by all peers.
+type Recorder struct {
+	file *os.File
+	lock sync.Mutex
+	err  error // First write error, recording stops after it
+}
+
+// NewRecorder creates a recorder appending to the capture file at path.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file}, nil
+}
+
+// Wrap returns a message stream of the given peer recording every message read
+// from it. Messages sent to the peer are not recorded.
+func (r *Recorder) Wrap(peer enode.ID, version uint, rw p2p.MsgReadWriter) p2p.MsgReadWriter {
+	return &recordingRW{MsgReadWriter: rw, recorder: r, peer: peer, version: version}
+}
+
+// record appends a message to the capture file.
+func (r *Recorder) record(rec *Record) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.err != nil || r.file == nil {
+		return
+	}
+	// Each record is written at once, so a crash leaves the previous ones intact
+	blob, err := rlp.EncodeToBytes(rec)
+	if err == nil {
+		_, err = r.file.Write(blob)
+	}
+	if err != nil {
+		r.err = err
+		log.Error("Message capture failed, recording stopped", "err", err)
+	}
+}
+
+// Close stops recording and closes the capture file.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// recordingRW is the message stream of a peer whose messages are recorded.
+type recordingRW struct {
+	p2p.MsgReadWriter
+	recorder *Recorder
+	peer     enode.ID
+	version  uint
+}
+
+// ReadMsg implements p2p.MsgReader, recording the message before handing it on
+// with its payload buffered.
+func (rw *recordingRW) ReadMsg() (p2p.Msg, error) {
+	msg, err := rw.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	received := msg.ReceivedAt
+	if received.IsZero() {
+		received = time.Now()
+	}
+	rw.recorder.record(&Record{
+		Time:    uint64(received.UnixNano()),
+		Peer:    rw.peer,
+		Version: rw.version,
+		Code:    msg.Code,
+		Payload: payload,
+	})
+	msg.Payload = bytes.NewReader(payload)
+	return msg, nil
+}
+
+// Load reads all messages of a capture file. A record truncated by a crash
+// while recording ends the capture.
+func Load(path string) ([]*Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		stream  = rlp.NewStream(file, 0)
+		records []*Record
+	)
+	for {
+		rec := new(Record)
+		if err := stream.Decode(rec); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				log.Warn("Truncated message capture", "path", path, "records", len(records))
+				return records, nil
+			}
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// Replayer delivers captured messages to a node, each peer of the capture
+// connecting through its own message pipe.
+//
+// Messages are delivered one at a time in capture order across all peers, the
+// next one only after the node consumed the previous, so the node observes the
+// same interleaving on every replay. Messages sent by the node are drained.
+type Replayer struct {
+	// Connect is invoked with the node end of a peer's pipe before its first
+	// message is delivered. It must serve the pipe in the background, e.g. by
+	// running the protocol handler in a goroutine, and close the pipe once the
+	// handler returns to disconnect the peer.
+	Connect func(peer enode.ID, version uint, rw *p2p.MsgPipeRW)
+
+	// Timing spaces the messages as they were received instead of delivering
+	// them as fast as the node consumes them.
+	Timing bool
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	Delivered    int                    // Messages consumed by the node
+	Dropped      int                    // Messages not delivered as the node disconnected the peer
+	Disconnected map[enode.ID]bool      // Peers the node disconnected during the replay
+	Sent         map[enode.ID][]p2p.Msg // Messages sent by the node, with buffered payloads
+}
+
+// replayPeer is the remote end of a replayed peer.
+type replayPeer struct {
+	rw     *p2p.MsgPipeRW
+	closed bool
+	sent   []p2p.Msg
+	done   chan struct{} // Closed when the node's messages stopped being drained
+}
+
+// Replay delivers the records to the node and closes all pipes afterwards.
+func (r *Replayer) Replay(records []*Record) (*ReplayResult, error) {
+	if len(records) == 0 {
+		return nil, errNoRecords
+	}
+	var (
+		peers  = make(map[enode.ID]*replayPeer)
+		result = &ReplayResult{
+			Disconnected: make(map[enode.ID]bool),
+			Sent:         make(map[enode.ID][]p2p.Msg),
+		}
+		start = records[0].ReceivedAt()
+		began = time.Now()
+	)
+	for _, rec := range records {
+		peer := peers[rec.Peer]
+		if peer == nil {
+			local, remote := p2p.MsgPipe()
+			peer = &replayPeer{rw: remote, done: make(chan struct{})}
+			peers[rec.Peer] = peer
+
+			go peer.drain()
+			r.Connect(rec.Peer, rec.Version, local)
+		}
+		if peer.closed {
+			result.Dropped++
+			continue
+		}
+		if r.Timing {
+			if wait := rec.ReceivedAt().Sub(start) - time.Since(began); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		msg := p2p.Msg{Code: rec.Code, Size: uint32(len(rec.Payload)), Payload: bytes.NewReader(rec.Payload), ReceivedAt: time.Now()}
+		if err := peer.rw.WriteMsg(msg); err != nil {
+			peer.closed = true
+			result.Disconnected[rec.Peer] = true
+			result.Dropped++
+			continue
+		}
+		result.Delivered++
+	}
+	for id, peer := range peers {
+		peer.rw.Close()
+		<-peer.done
+		result.Sent[id] = peer.sent
+	}
+	return result, nil
+}
+
+// drain reads the messages the node sends to the peer until the pipe closes.
+func (p *replayPeer) drain() {
+	defer close(p.done)
+
+	for {
+		msg, err := p.rw.ReadMsg()
+		if err != nil {
+			return
+		}
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return
+		}
+		msg.Payload = bytes.NewReader(payload)
+		p.sent = append(p.sent, msg)
+	}
+}
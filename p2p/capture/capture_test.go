@@ -0,0 +1,149 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package capture
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/enode"
+)
+
+const (
+	pingMsg = 0x01 // Answered by the echo handler with the same payload
+	quitMsg = 0x02 // Makes the echo handler disconnect the peer
+)
+
+// echoHandler answers pings until a quit message arrives, recording the order
+// of the messages it consumed.
+func echoHandler(id enode.ID, rw *p2p.MsgPipeRW, seen chan<- enode.ID) {
+	defer rw.Close()
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return
+		}
+		// Note the message before consuming it, which unblocks the next delivery
+		seen <- id
+
+		var n uint
+		if err := msg.Decode(&n); err != nil {
+			return
+		}
+		if msg.Code == quitMsg {
+			return
+		}
+		if err := p2p.Send(rw, pingMsg, n); err != nil {
+			return
+		}
+	}
+}
+
+func TestCaptureReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "capture.rlp")
+
+	// Record the messages of two peers, the second one quitting midway
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	var (
+		peerA = enode.ID{0x0a}
+		peerB = enode.ID{0x0b}
+	)
+	script := []struct {
+		peer enode.ID
+		code uint64
+		n    uint
+	}{
+		{peerA, pingMsg, 1}, {peerB, pingMsg, 2}, {peerA, pingMsg, 3},
+		{peerB, quitMsg, 4}, {peerA, pingMsg, 5}, {peerB, pingMsg, 6},
+	}
+	for _, s := range script {
+		local, remote := p2p.MsgPipe()
+		go p2p.Send(remote, s.code, s.n)
+
+		rw := recorder.Wrap(s.peer, 64, local)
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		var n uint
+		if err := msg.Decode(&n); err != nil || n != s.n {
+			t.Fatalf("recorded message corrupted: have %d (%v), want %d", n, err, s.n)
+		}
+	}
+	recorder.Close()
+
+	records, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load capture: %v", err)
+	}
+	if len(records) != len(script) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), len(script))
+	}
+	for i, rec := range records {
+		if rec.Peer != script[i].peer || rec.Code != script[i].code || rec.Version != 64 {
+			t.Errorf("record %d: have peer %x code %d version %d, want peer %x code %d", i, rec.Peer[:1], rec.Code, rec.Version, script[i].peer[:1], script[i].code)
+		}
+	}
+	// Replay against fresh handlers, the order must be kept across peers
+	seen := make(chan enode.ID, len(records))
+	replayer := &Replayer{
+		Connect: func(peer enode.ID, version uint, rw *p2p.MsgPipeRW) {
+			go echoHandler(peer, rw, seen)
+		},
+	}
+	result, err := replayer.Replay(records)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	close(seen)
+
+	var order []enode.ID
+	for id := range seen {
+		order = append(order, id)
+	}
+	want := []enode.ID{peerA, peerB, peerA, peerB, peerA}
+	if len(order) != len(want) {
+		t.Fatalf("consumed message count mismatch: have %d, want %d", len(order), len(want))
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("message %d consumed from peer %x, want %x", i, order[i][:1], want[i][:1])
+		}
+	}
+	if result.Delivered != 5 || result.Dropped != 1 || !result.Disconnected[peerB] || result.Disconnected[peerA] {
+		t.Errorf("result mismatch: delivered %d, dropped %d, disconnected %v", result.Delivered, result.Dropped, result.Disconnected)
+	}
+	// The answer to the last delivered message may race with closing the pipes
+	if len(result.Sent[peerA]) < 2 || len(result.Sent[peerB]) != 1 {
+		t.Fatalf("sent message counts mismatch: have %d/%d, want 2+/1", len(result.Sent[peerA]), len(result.Sent[peerB]))
+	}
+	var n uint
+	if err := result.Sent[peerA][1].Decode(&n); err != nil || n != 3 {
+		t.Errorf("sent message mismatch: have %d (%v), want 3", n, err)
+	}
+}
@@ -82,6 +82,7 @@ type dialstate struct {
 	randomNodes   []*enode.Node // filled from Table
 	static        map[enode.ID]*dialTask
 	hist          expHeap
+	bans          *banList // Nodes not to dial while banned, nil if not tracked
 }
 
 type discoverTable interface {
@@ -265,6 +266,8 @@ func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 		return errNotWhitelisted
 	case s.hist.contains(string(n.ID().Bytes())):
 		return errRecentlyDialed
+	case s.bans.banned(n.ID()):
+		return errBanned
 	}
 	return nil
 }
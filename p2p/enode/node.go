@@ -236,6 +236,12 @@ func HexID(in string) ID {
 	return id
 }
 
+// ParseID converts a hex string to an ID, returning an error if the string is
+// not a valid ID. The string may be prefixed with 0x.
+func ParseID(in string) (ID, error) {
+	return parseID(in)
+}
+
 func parseID(in string) (ID, error) {
 	var id ID
 	b, err := hex.DecodeString(strings.TrimPrefix(in, "0x"))
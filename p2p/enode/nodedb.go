@@ -40,6 +40,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbBanPrefix    = "ban:" // Identifier to prefix ban records with, kept apart from expiring node entries
 	dbDiscoverRoot = "v4"
 
 	// These fields are stored per ID and IP, the full key is "n:<ID>:v4:<IP>:findfail".
@@ -440,6 +441,38 @@ func nextNode(it iterator.Iterator) *Node {
 	return nil
 }
 
+// banKey returns the database key for the ban record of a node.
+func banKey(id ID) []byte {
+	return append([]byte(dbBanPrefix), id[:]...)
+}
+
+// Bans retrieves the encoded ban records of all nodes.
+func (db *DB) Bans() map[ID][]byte {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	bans := make(map[ID][]byte)
+	for it.Next() {
+		var id ID
+		if len(it.Key()) != len(dbBanPrefix)+len(id) {
+			continue
+		}
+		copy(id[:], it.Key()[len(dbBanPrefix):])
+		bans[id] = append([]byte{}, it.Value()...)
+	}
+	return bans
+}
+
+// UpdateBan stores the encoded ban record of a node.
+func (db *DB) UpdateBan(id ID, blob []byte) error {
+	return db.lvl.Put(banKey(id), blob, nil)
+}
+
+// DeleteBan removes the ban record of a node.
+func (db *DB) DeleteBan(id ID) error {
+	return db.lvl.Delete(banKey(id), nil)
+}
+
 // close flushes and closes the database files.
 func (db *DB) Close() {
 	close(db.quit)
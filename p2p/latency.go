@@ -0,0 +1,124 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Latency tiers peers are classified into by their round trip time.
+const (
+	LatencyNear     = "near"     // Same region, below 50ms
+	LatencyRegional = "regional" // Neighbouring regions, below 150ms
+	LatencyFar      = "far"      // Other continents
+	LatencyUnknown  = "unknown"  // Not measured yet
+)
+
+const (
+	latencyNearLimit     = 50 * time.Millisecond
+	latencyRegionalLimit = 150 * time.Millisecond
+)
+
+// LatencyTier classifies a round trip time, zero being unknown.
+func LatencyTier(rtt time.Duration) string {
+	switch {
+	case rtt <= 0:
+		return LatencyUnknown
+	case rtt < latencyNearLimit:
+		return LatencyNear
+	case rtt < latencyRegionalLimit:
+		return LatencyRegional
+	default:
+		return LatencyFar
+	}
+}
+
+// latency measures the round trip time of a peer from the base protocol
+// pings, smoothed over the recent ones.
+type latency struct {
+	sentAt int64 // Unix nanoseconds of the outstanding ping, zero if none (atomic)
+	rtt    int64 // Smoothed round trip time in nanoseconds, zero if unknown (atomic)
+}
+
+// ping records a ping sent to the peer.
+func (l *latency) ping(now time.Time) {
+	atomic.StoreInt64(&l.sentAt, now.UnixNano())
+}
+
+// pong accounts the answer to the outstanding ping, ignoring unsolicited ones.
+func (l *latency) pong(now time.Time) {
+	sent := atomic.SwapInt64(&l.sentAt, 0)
+	if sent == 0 || now.UnixNano() <= sent {
+		return
+	}
+	sample := now.UnixNano() - sent
+	if old := atomic.LoadInt64(&l.rtt); old > 0 {
+		sample = (7*old + sample) / 8
+	}
+	atomic.StoreInt64(&l.rtt, sample)
+}
+
+// RTT returns the smoothed round trip time to the peer, zero if not measured
+// yet.
+func (p *Peer) RTT() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.latency.rtt))
+}
+
+// PeerLatency is the measured latency of a connected peer.
+type PeerLatency struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	RemoteAddress string  `json:"remoteAddress"`
+	RTT           float64 `json:"rtt"` // Milliseconds, zero if unknown
+	Tier          string  `json:"tier"`
+}
+
+// LatencyMap is the latency of all connected peers, fastest first.
+type LatencyMap struct {
+	Tiers map[string]int `json:"tiers"` // Number of peers per tier
+	Peers []*PeerLatency `json:"peers"`
+}
+
+// LatencyMap returns the latency of all connected peers.
+func (srv *Server) LatencyMap() *LatencyMap {
+	lm := &LatencyMap{
+		Tiers: map[string]int{LatencyNear: 0, LatencyRegional: 0, LatencyFar: 0, LatencyUnknown: 0},
+		Peers: make([]*PeerLatency, 0, srv.PeerCount()),
+	}
+	for _, peer := range srv.Peers() {
+		rtt := peer.RTT()
+		entry := &PeerLatency{
+			ID:            peer.ID().String(),
+			Name:          peer.Name(),
+			RemoteAddress: peer.RemoteAddr().String(),
+			RTT:           float64(rtt) / float64(time.Millisecond),
+			Tier:          LatencyTier(rtt),
+		}
+		lm.Tiers[entry.Tier]++
+		lm.Peers = append(lm.Peers, entry)
+	}
+	sort.SliceStable(lm.Peers, func(i, j int) bool {
+		a, b := lm.Peers[i].RTT, lm.Peers[j].RTT
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		return a < b
+	})
+	return lm
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that round trip times are smoothed and unsolicited pongs ignored.
+func TestLatencySmoothing(t *testing.T) {
+	var (
+		l   latency
+		now = time.Now()
+	)
+	l.pong(now)
+	if rtt := time.Duration(l.rtt); rtt != 0 {
+		t.Fatalf("unsolicited pong measured: %v", rtt)
+	}
+	l.ping(now)
+	l.pong(now.Add(80 * time.Millisecond))
+	if rtt := time.Duration(l.rtt); rtt != 80*time.Millisecond {
+		t.Fatalf("first rtt mismatch: have %v, want %v", rtt, 80*time.Millisecond)
+	}
+	l.ping(now)
+	l.pong(now.Add(160 * time.Millisecond))
+	if rtt := time.Duration(l.rtt); rtt != 90*time.Millisecond {
+		t.Fatalf("smoothed rtt mismatch: have %v, want %v", rtt, 90*time.Millisecond)
+	}
+	l.pong(now.Add(time.Second))
+	if rtt := time.Duration(l.rtt); rtt != 90*time.Millisecond {
+		t.Fatalf("duplicate pong measured: have %v, want %v", rtt, 90*time.Millisecond)
+	}
+	for rtt, tier := range map[time.Duration]string{
+		0:                      LatencyUnknown,
+		10 * time.Millisecond:  LatencyNear,
+		90 * time.Millisecond:  LatencyRegional,
+		300 * time.Millisecond: LatencyFar,
+	} {
+		if have := LatencyTier(rtt); have != tier {
+			t.Errorf("tier mismatch for %v: have %s, want %s", rtt, have, tier)
+		}
+	}
+}
+
+// Tests that the pong of a remote peer is measured.
+func TestPeerRTT(t *testing.T) {
+	closer, rw, p, _ := testPeer(nil)
+	defer closer()
+
+	p.latency.ping(time.Now())
+	if err := SendItems(rw, pongMsg); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && p.RTT() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.RTT() == 0 {
+		t.Fatalf("pong not measured")
+	}
+}
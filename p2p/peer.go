@@ -119,6 +119,11 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// bans records the offenses of the peer, nil if not tracked
+	bans *banList
+
+	latency latency // Round trip time measured by the pings
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -171,6 +176,16 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Ban disconnects the peer for misbehaving and refuses to reconnect to it for a
+// cool-down period, growing with every repeat offense.
+func (p *Peer) Ban(reason string) {
+	if p.bans != nil {
+		record := p.bans.add(p.ID(), reason)
+		p.log.Info("Banning peer", "reason", reason, "offenses", record.Offenses, "expires", record.Expires)
+	}
+	p.Disconnect(DiscUselessPeer)
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	id := p.ID()
@@ -258,6 +273,7 @@ func (p *Peer) pingLoop() {
 	for {
 		select {
 		case <-ping.C:
+			p.latency.ping(time.Now())
 			if err := SendItems(p.rw, pingMsg); err != nil {
 				p.protoErr <- err
 				return
@@ -293,6 +309,9 @@ func (p *Peer) handle(msg Msg) error {
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
+	case msg.Code == pongMsg:
+		p.latency.pong(msg.ReceivedAt)
+		return msg.Discard()
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
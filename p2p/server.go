@@ -121,6 +121,11 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// BanDuration is the time a peer banned for misbehaving is refused after its
+	// first offense, doubling with every repeat offense. Bans are stored in the
+	// node database. Zero defaults to DefaultBanDuration.
+	BanDuration time.Duration `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -173,6 +178,7 @@ type Server struct {
 	running bool
 
 	nodedb       *enode.DB
+	bans         *banList
 	localnode    *enode.LocalNode
 	ntab         discoverTable
 	listener     net.Listener
@@ -476,6 +482,7 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), srv.ntab, dynPeers, &srv.Config)
+	dialer.bans = srv.bans
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -497,6 +504,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.bans = newBanList(db, srv.BanDuration)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -750,6 +758,7 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(srv.log, c, srv.Protocols)
+				p.bans = srv.bans
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
@@ -812,6 +821,9 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn) && srv.bans.banned(c.node.ID()):
+		bannedConnsMeter.Mark(1)
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -1053,6 +1065,29 @@ func (srv *Server) runPeer(p *Peer) {
 	srv.delpeer <- peerDrop{p, err, remoteRequested}
 }
 
+// Bans returns the nodes currently refused for misbehaving.
+func (srv *Server) Bans() []*BanRecord {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running {
+		return nil
+	}
+	return srv.bans.list()
+}
+
+// Unban lifts the ban of a node and forgets its past offenses, returning
+// whether it was banned.
+func (srv *Server) Unban(id enode.ID) bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running {
+		return false
+	}
+	return srv.bans.remove(id)
+}
+
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
@@ -189,6 +189,12 @@ type TrustedCheckpoint struct {
 	SectionBHead  common.Hash `json:"sectionBHead"`
 	BloomRoot     common.Hash `json:"bloomRoot"`
 	DSRoot        common.Hash `json:"datasetRoot"`
+
+	// Snail CHT checkpoint, not part of the checkpoint hash so that existing
+	// checkpoint oracles keep verifying.
+	SnailSectionIndex uint64      `json:"snailSectionIndex,omitempty"`
+	SnailSectionHead  common.Hash `json:"snailSectionHead,omitempty"`
+	SnailCHTRoot      common.Hash `json:"snailChtRoot,omitempty"`
 }
 
 // HashEqual returns an indicator comparing the itself hash with given one.
@@ -214,6 +220,11 @@ func (c *TrustedCheckpoint) Empty() bool {
 	return c.SectionHead == (common.Hash{}) || c.CHTRoot == (common.Hash{}) || c.BloomRoot == (common.Hash{})
 }
 
+// SnailEmpty returns an indicator whether the snail CHT part of the checkpoint is empty.
+func (c *TrustedCheckpoint) SnailEmpty() bool {
+	return c.SnailSectionHead == (common.Hash{}) || c.SnailCHTRoot == (common.Hash{})
+}
+
 // CheckpointOracleConfig represents a set of checkpoint contract(which acts as an oracle)
 // config which used for light client checkpoint syncing.
 type CheckpointOracleConfig struct {
@@ -241,6 +252,21 @@ type ChainConfig struct {
 	TIP8 *BlockConfig `json:"tip8"`
 	TIP9 *BlockConfig `json:"tip9"`
 
+	// TIP10 commits to the committee set and the snail head in fast headers
+	TIP10 *BlockConfig `json:"tip10,omitempty"`
+
+	// TIP11 enables delegations approved by EIP-712 signatures in the staking contract
+	TIP11 *BlockConfig `json:"tip11,omitempty"`
+
+	// TIP12 enables validator minimum delegations and commission change cool-downs
+	TIP12 *BlockConfig `json:"tip12,omitempty"`
+
+	// TIP13 allows fruits and snail blocks to carry a signature of their coinbase in extra-data
+	TIP13 *BlockConfig `json:"tip13,omitempty"`
+
+	// TIP14 records committee rotation pauses in the staking contract
+	TIP14 *BlockConfig `json:"tip14,omitempty"`
+
 	TIPStake *BlockConfig `json:"tipstake"`
 }
 
@@ -492,3 +518,50 @@ func (c *ChainConfig) IsTIP9(num *big.Int) bool {
 	}
 	return isForked(c.TIP9.FastNumber, num)
 }
+
+// IsTIP10 returns whether num is either equal to the TIP10 fork block or greater.
+// Fast headers past the fork carry a types.HeaderExtension in their extra-data.
+func (c *ChainConfig) IsTIP10(num *big.Int) bool {
+	if c.TIP10 == nil {
+		return false
+	}
+	return isForked(c.TIP10.FastNumber, num)
+}
+
+// IsTIP11 returns whether num is either equal to the TIP11 fork block or greater.
+// The staking contract accepts signed delegations submitted by relayers past the fork.
+func (c *ChainConfig) IsTIP11(num *big.Int) bool {
+	if c.TIP11 == nil {
+		return false
+	}
+	return isForked(c.TIP11.FastNumber, num)
+}
+
+// IsTIP12 returns whether num is either equal to the TIP12 fork block or greater.
+// Validators may require minimum delegations past the fork, and their commission
+// changes are subject to a cool-down.
+func (c *ChainConfig) IsTIP12(num *big.Int) bool {
+	if c.TIP12 == nil {
+		return false
+	}
+	return isForked(c.TIP12.FastNumber, num)
+}
+
+// IsTIP13 returns whether num is either equal to the TIP13 fork block or greater.
+// Fruits and snail blocks of fast blocks past the fork may append a coinbase proof
+// to their extra-data.
+func (c *ChainConfig) IsTIP13(num *big.Int) bool {
+	if c.TIP13 == nil {
+		return false
+	}
+	return isForked(c.TIP13.FastNumber, num)
+}
+
+// IsTIP14 returns whether num is either equal to the TIP14 fork block or greater.
+// Committee rotation pauses approved by the validators are accepted past the fork.
+func (c *ChainConfig) IsTIP14(num *big.Int) bool {
+	if c.TIP14 == nil {
+		return false
+	}
+	return isForked(c.TIP14.FastNumber, num)
+}
@@ -54,4 +54,12 @@ const (
 	// Eventually this can be merged back with the client version, but that requires a
 	// full database upgrade, so that should be left for a suitable moment.
 	CHTFrequencyServer = 4096
+
+	// SnailCHTFrequency is the snail block frequency for creating snail CHTs on both
+	// server/client sides.
+	SnailCHTFrequency = 32768
+
+	// SnailFinalityThreshold is the default number of snail blocks below the head
+	// beyond which the snail chain refuses to reorganise.
+	SnailFinalityThreshold = 96
 )
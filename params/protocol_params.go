@@ -199,6 +199,7 @@ var (
 	FirstNewEpochID            uint64 = 1
 	DposForkPoint              uint64 = 0
 	ElectionMinLimitForStaking        = new(big.Int).Mul(big.NewInt(200000), big.NewInt(1e18))
+	FeeChangeCooldown          uint64 = 25000 // fast blocks between commission changes of a validator, about an epoch
 )
 var (
 	// 361 epoch begin=9000001,end=9025000
@@ -237,6 +237,12 @@ type ConsensusConfig struct {
 	// Reactor sleep duration parameters are in milliseconds
 	PeerGossipSleepDuration     int `mapstructure:"peer_gossip_sleep_duration"`
 	PeerQueryMaj23SleepDuration int `mapstructure:"peer_query_maj23_sleep_duration"`
+
+	// Wrap consensus messages into envelopes signed by the committee member,
+	// and optionally reject messages from peers that do not do so. Envelopes
+	// received are verified either way
+	SignMessages          bool `mapstructure:"sign_messages"`
+	RequireSignedMessages bool `mapstructure:"require_signed_messages"`
 }
 
 // DefaultConsensusConfig returns a default configuration for the consensus service
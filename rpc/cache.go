@@ -0,0 +1,137 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// maxCachedResponses caps the number of cached responses independent of their
+// size, bounding the bookkeeping overhead of many tiny ones.
+const maxCachedResponses = 1 << 20
+
+var (
+	cacheHitMeter   = metrics.NewRegisteredMeter("rpc/cache/hit", nil)
+	cacheMissMeter  = metrics.NewRegisteredMeter("rpc/cache/miss", nil)
+	cacheSizeGauge  = metrics.NewRegisteredGauge("rpc/cache/size", nil)
+	cachePurgeMeter = metrics.NewRegisteredMeter("rpc/cache/purge", nil)
+)
+
+// CachePolicy reports whether the result of a call only depends on immutable
+// data, so that it may be served from the response cache.
+type CachePolicy func(method string, args []interface{}) bool
+
+// ResponseCache keeps the encoded results of calls about immutable data, keyed
+// by the method and its parameters. The least recently used responses are
+// evicted once the cache grows beyond its size limit.
+type ResponseCache struct {
+	policy  CachePolicy
+	limit   int            // Maximum total size of the cached responses in bytes
+	size    int            // Current total size of the cached responses in bytes
+	entries *simplelru.LRU // Encoded responses by call key
+
+	lock sync.Mutex
+}
+
+// NewResponseCache creates a response cache of the given size in bytes, caching
+// the calls accepted by the policy.
+func NewResponseCache(limit int, policy CachePolicy) *ResponseCache {
+	c := &ResponseCache{
+		policy: policy,
+		limit:  limit,
+	}
+	c.entries, _ = simplelru.NewLRU(maxCachedResponses, func(key, value interface{}) {
+		c.size -= len(key.(string)) + len(value.(json.RawMessage))
+	})
+	return c
+}
+
+// key returns the cache key of a call, or false if its result may not be cached.
+func (c *ResponseCache) key(method string, args []reflect.Value) (string, bool) {
+	params := make([]interface{}, len(args))
+	for i, arg := range args {
+		params[i] = arg.Interface()
+	}
+	if !c.policy(method, params) {
+		return "", false
+	}
+	blob, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return method + string(blob), true
+}
+
+// get retrieves the cached response of a call.
+func (c *ResponseCache) get(key string) (json.RawMessage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if result, ok := c.entries.Get(key); ok {
+		cacheHitMeter.Mark(1)
+		return result.(json.RawMessage), true
+	}
+	cacheMissMeter.Mark(1)
+	return nil, false
+}
+
+// add encodes and caches the result of a call, returning the encoded result or
+// nil if it could not be encoded. Empty results are never cached as the data
+// they were looked up for may still appear.
+func (c *ResponseCache) add(key string, result interface{}) json.RawMessage {
+	blob, err := json.Marshal(result)
+	if err != nil {
+		log.Debug("Failed to encode cached RPC response", "err", err)
+		return nil
+	}
+	if bytes.Equal(blob, []byte("null")) || len(key)+len(blob) > c.limit {
+		return blob
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries.Contains(key) {
+		return blob
+	}
+	c.entries.Add(key, json.RawMessage(blob))
+	c.size += len(key) + len(blob)
+	for c.size > c.limit {
+		c.entries.RemoveOldest()
+	}
+	cacheSizeGauge.Update(int64(c.size))
+	return blob
+}
+
+// Purge drops all cached responses, used when data considered immutable was
+// changed after all.
+func (c *ResponseCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries.Purge()
+	c.size = 0
+
+	cacheSizeGauge.Update(0)
+	cachePurgeMeter.Mark(1)
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"strings"
+	"testing"
+)
+
+// CacheService counts the calls reaching it, to detect cached responses.
+type CacheService struct {
+	calls int
+}
+
+func (s *CacheService) Block(number int) (string, error) {
+	s.calls++
+	if number < 0 {
+		return "", nil
+	}
+	return strings.Repeat("x", number), nil
+}
+
+func (s *CacheService) Missing(number int) *string {
+	s.calls++
+	return nil
+}
+
+// Tests that the responses accepted by the cache policy are served from the
+// cache, evicted by size and dropped on purge.
+func TestResponseCache(t *testing.T) {
+	service := new(CacheService)
+	server := newTestServer("test", service)
+	defer server.Stop()
+
+	cache := NewResponseCache(256, func(method string, args []interface{}) bool {
+		return method != "test_block" || args[0].(int) >= 0
+	})
+	server.SetResponseCache(cache)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	call := func(method string, number int, calls int) {
+		t.Helper()
+		var result *string
+		if err := client.Call(&result, method, number); err != nil {
+			t.Fatalf("%s(%d) failed: %v", method, number, err)
+		}
+		if service.calls != calls {
+			t.Fatalf("%s(%d): call count mismatch: have %d, want %d", method, number, service.calls, calls)
+		}
+	}
+	call("test_block", 10, 1)
+	call("test_block", 10, 1) // Served from the cache
+	call("test_block", 11, 2) // Different parameters
+	call("test_block", -1, 3) // Rejected by the policy
+	call("test_block", -1, 4)
+	call("test_missing", 1, 5) // Empty results are never cached
+	call("test_missing", 1, 6)
+
+	// Responses exceeding the size limit evict the least recently used ones
+	call("test_block", 200, 7)
+	call("test_block", 200, 7)
+	call("test_block", 11, 7)
+	call("test_block", 10, 8)
+	if cache.size > cache.limit {
+		t.Errorf("cache size above limit: have %d, limit %d", cache.size, cache.limit)
+	}
+	// Purging must drop everything
+	cache.Purge()
+	call("test_block", 11, 9)
+}
@@ -49,6 +49,39 @@ func TestClientRequest(t *testing.T) {
 	}
 }
 
+// dataError is a coded callback error carrying additional data.
+type dataError struct{}
+
+func (e *dataError) Error() string          { return "data error" }
+func (e *dataError) ErrorCode() int         { return 3 }
+func (e *dataError) ErrorData() interface{} { return "0x01" }
+
+type ErrorService struct{}
+
+func (s *ErrorService) Fail() (string, error) { return "", &dataError{} }
+
+func TestClientErrorData(t *testing.T) {
+	server := newTestServer("service", new(ErrorService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var resp string
+	err := client.Call(&resp, "service_fail")
+	if err == nil {
+		t.Fatal("no error returned")
+	}
+	if err.Error() != "data error" {
+		t.Errorf("wrong error message: %q", err.Error())
+	}
+	if ec, ok := err.(Error); !ok || ec.ErrorCode() != 3 {
+		t.Errorf("wrong error code: %v", err)
+	}
+	if de, ok := err.(DataError); !ok || de.ErrorData() != "0x01" {
+		t.Errorf("wrong error data: %v", err)
+	}
+}
+
 func TestClientBatchRequest(t *testing.T) {
 	server := newTestServer("service", new(Service))
 	defer server.Stop()
@@ -64,3 +64,40 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// client reached the maximum number of subscriptions of its connection
+type subscriptionLimitError struct{ limit int }
+
+func (e *subscriptionLimitError) ErrorCode() int { return -32005 }
+
+func (e *subscriptionLimitError) Error() string {
+	return fmt.Sprintf("subscription limit of %d reached", e.limit)
+}
+
+// server reached the maximum number of websocket connections
+type connectionLimitError struct{ limit int }
+
+func (e *connectionLimitError) ErrorCode() int { return -32005 }
+
+func (e *connectionLimitError) Error() string {
+	return fmt.Sprintf("connection limit of %d reached", e.limit)
+}
+
+// API key missing from the request or not accepted
+type apiKeyError struct{}
+
+func (e *apiKeyError) ErrorCode() int { return -32000 }
+
+func (e *apiKeyError) Error() string { return "invalid or missing API key" }
+
+// quota of the API key for the method used up in the current period
+type quotaExceededError struct {
+	method string
+	quota  uint64
+}
+
+func (e *quotaExceededError) ErrorCode() int { return -32005 }
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("quota of %d calls to %s exceeded, retry in the next period", e.quota, e.method)
+}
@@ -31,6 +31,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/abeychain/go-abey/tracing"
 	"github.com/rs/cors"
 )
 
@@ -190,6 +191,14 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, "remote", r.RemoteAddr)
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		ctx = context.WithValue(ctx, apiKeyQuery, key)
+	} else {
+		ctx = context.WithValue(ctx, apiKeyQuery, r.URL.Query().Get(apiKeyQuery))
+	}
+	if sc, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+		ctx = tracing.ContextWithSpan(ctx, sc)
+	}
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
@@ -0,0 +1,256 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+const (
+	// DefaultQuotaPeriod is the length of a quota period if none is configured.
+	DefaultQuotaPeriod = 24 * time.Hour
+
+	// AnyMethod is the quota key applying to all methods without an own quota.
+	AnyMethod = "*"
+
+	apiKeyHeader = "X-API-Key" // HTTP header carrying the API key of a request
+	apiKeyQuery  = "apikey"    // URL query parameter carrying the API key of a request
+)
+
+var (
+	meteredCallMeter  = metrics.NewRegisteredMeter("rpc/metering/calls", nil)
+	rejectedKeyMeter  = metrics.NewRegisteredMeter("rpc/metering/rejected/key", nil)
+	exceededCallMeter = metrics.NewRegisteredMeter("rpc/metering/rejected/quota", nil)
+)
+
+// UsageStore keeps the request counters of the API keys, split into quota
+// periods. Implementations must be safe for concurrent use.
+type UsageStore interface {
+	// Increment adds a call of the method to the counter of the key in the given
+	// period, returning the updated count.
+	Increment(key, method string, period uint64) (uint64, error)
+
+	// Usage returns the call counts of the key in the given period by method.
+	Usage(key string, period uint64) (map[string]uint64, error)
+
+	// Keys returns all keys with any usage in the given period.
+	Keys(period uint64) ([]string, error)
+}
+
+// MemoryUsageStore is a UsageStore keeping the counters in memory. Only the
+// current and the previous period are retained.
+type MemoryUsageStore struct {
+	counters map[uint64]map[string]map[string]uint64 // Period -> key -> method -> calls
+	lock     sync.Mutex
+}
+
+// NewMemoryUsageStore creates an empty in-memory usage store.
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		counters: make(map[uint64]map[string]map[string]uint64),
+	}
+}
+
+// Increment implements UsageStore, adding a call to the counter of the method.
+func (s *MemoryUsageStore) Increment(key, method string, period uint64) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys, ok := s.counters[period]
+	if !ok {
+		// New period started, drop everything older than the previous one
+		for old := range s.counters {
+			if old+1 < period {
+				delete(s.counters, old)
+			}
+		}
+		keys = make(map[string]map[string]uint64)
+		s.counters[period] = keys
+	}
+	methods, ok := keys[key]
+	if !ok {
+		methods = make(map[string]uint64)
+		keys[key] = methods
+	}
+	methods[method]++
+	return methods[method], nil
+}
+
+// Usage implements UsageStore, returning a copy of the counters of the key.
+func (s *MemoryUsageStore) Usage(key string, period uint64) (map[string]uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	usage := make(map[string]uint64)
+	for method, calls := range s.counters[period][key] {
+		usage[method] = calls
+	}
+	return usage, nil
+}
+
+// Keys implements UsageStore, returning the keys used in the period.
+func (s *MemoryUsageStore) Keys(period uint64) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]string, 0, len(s.counters[period]))
+	for key := range s.counters[period] {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// MeteringConfig contains the settings of the per API key request metering.
+type MeteringConfig struct {
+	Keys   []string          // API keys accepted, any key (or none) is accepted and metered if empty
+	Quotas map[string]uint64 // Calls allowed per key and period by method, AnyMethod covering the others
+	Period time.Duration     // Length of a quota period, DefaultQuotaPeriod if zero
+	Store  UsageStore        // Storage of the counters, kept in memory if nil
+}
+
+// Metering counts the requests of every API key by method, rejecting them once
+// the quota of the method is used up in the current period.
+type Metering struct {
+	keys   map[string]struct{}
+	quotas map[string]uint64
+	period time.Duration
+	store  UsageStore
+
+	now func() time.Time // Current time, replaceable for tests
+}
+
+// NewMetering creates a request metering with the given settings.
+func NewMetering(config MeteringConfig) *Metering {
+	m := &Metering{
+		keys:   make(map[string]struct{}),
+		quotas: make(map[string]uint64),
+		period: config.Period,
+		store:  config.Store,
+		now:    time.Now,
+	}
+	for _, key := range config.Keys {
+		m.keys[key] = struct{}{}
+	}
+	for method, quota := range config.Quotas {
+		m.quotas[method] = quota
+	}
+	if m.period <= 0 {
+		m.period = DefaultQuotaPeriod
+	}
+	if m.store == nil {
+		m.store = NewMemoryUsageStore()
+	}
+	return m
+}
+
+// currentPeriod returns the index of the running quota period.
+func (m *Metering) currentPeriod() uint64 {
+	return uint64(m.now().UnixNano() / int64(m.period))
+}
+
+// quota returns the number of calls allowed for the method, zero meaning no limit.
+func (m *Metering) quota(method string) uint64 {
+	if quota, ok := m.quotas[method]; ok {
+		return quota
+	}
+	return m.quotas[AnyMethod]
+}
+
+// admit counts a call of the method by the key, returning an error if the key
+// is unknown or its quota for the method is used up.
+func (m *Metering) admit(key, method string) Error {
+	if len(m.keys) > 0 {
+		if _, ok := m.keys[key]; !ok {
+			rejectedKeyMeter.Mark(1)
+			return &apiKeyError{}
+		}
+	}
+	meteredCallMeter.Mark(1)
+
+	calls, err := m.store.Increment(key, method, m.currentPeriod())
+	if err != nil {
+		// Never take the endpoint down for a broken counter storage
+		log.Warn("Failed to meter RPC call", "method", method, "err", err)
+		return nil
+	}
+	if quota := m.quota(method); quota > 0 && calls > quota {
+		exceededCallMeter.Mark(1)
+		return &quotaExceededError{method: method, quota: quota}
+	}
+	return nil
+}
+
+// UsageReport is the consumption of a single API key in the current period.
+type UsageReport struct {
+	Key    string            `json:"key"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Usage  map[string]uint64 `json:"usage"`
+	Quotas map[string]uint64 `json:"quotas"`
+}
+
+// Report returns the usage of the key in the current period.
+func (m *Metering) Report(key string) (*UsageReport, error) {
+	period := m.currentPeriod()
+
+	usage, err := m.store.Usage(key, period)
+	if err != nil {
+		return nil, err
+	}
+	report := &UsageReport{
+		Key:    key,
+		Start:  time.Unix(0, int64(period)*int64(m.period)),
+		End:    time.Unix(0, int64(period+1)*int64(m.period)),
+		Usage:  usage,
+		Quotas: make(map[string]uint64),
+	}
+	for method, quota := range m.quotas {
+		report.Quotas[method] = quota
+	}
+	return report, nil
+}
+
+// Reports returns the usage of all keys in the current period, sorted by key.
+func (m *Metering) Reports() ([]*UsageReport, error) {
+	keys, err := m.store.Keys(m.currentPeriod())
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	reports := make([]*UsageReport, 0, len(keys))
+	for _, key := range keys {
+		report, err := m.Report(key)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// apiKeyFromContext returns the API key the request was made with.
+func apiKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(apiKeyQuery).(string)
+	return key
+}
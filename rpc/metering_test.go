@@ -0,0 +1,113 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Tests that the metering enforces the method quotas of every key separately
+// and resets them once a new period starts.
+func TestMeteringQuotas(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	m := NewMetering(MeteringConfig{
+		Quotas: map[string]uint64{"abey_call": 2, AnyMethod: 3},
+		Period: time.Hour,
+	})
+	m.now = func() time.Time { return now }
+
+	tests := []struct {
+		key    string
+		method string
+		fail   bool
+	}{
+		{"alice", "abey_call", false},
+		{"alice", "abey_call", false},
+		{"alice", "abey_call", true},         // Method quota used up
+		{"bob", "abey_call", false},          // Quotas are tracked per key
+		{"alice", "abey_blockNumber", false}, // Other methods fall back to the default quota
+		{"alice", "abey_blockNumber", false},
+		{"alice", "abey_blockNumber", false},
+		{"alice", "abey_blockNumber", true},
+	}
+	for i, tt := range tests {
+		if err := m.admit(tt.key, tt.method); (err != nil) != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.fail)
+		}
+	}
+	report, err := m.Report("alice")
+	if err != nil {
+		t.Fatalf("failed to report usage: %v", err)
+	}
+	if report.Usage["abey_call"] != 3 || report.Usage["abey_blockNumber"] != 4 {
+		t.Errorf("usage mismatch: have %v", report.Usage)
+	}
+	if reports, _ := m.Reports(); len(reports) != 2 || reports[0].Key != "alice" || reports[1].Key != "bob" {
+		t.Errorf("reported keys mismatch: have %v", reports)
+	}
+	// A new period must restore the quotas
+	now = now.Add(time.Hour)
+	if err := m.admit("alice", "abey_call"); err != nil {
+		t.Errorf("quota not reset in new period: %v", err)
+	}
+}
+
+// Tests that requests over HTTP are rejected without an accepted API key and
+// that every key can query its own usage.
+func TestMeteringHTTP(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.SetMetering(NewMetering(MeteringConfig{
+		Keys:   []string{"alice"},
+		Quotas: map[string]uint64{"service_echo": 1},
+	}))
+	defer server.Stop()
+
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	// Requests without a valid key must be rejected
+	for _, url := range []string{hs.URL, hs.URL + "/?apikey=mallory"} {
+		client, _ := DialHTTP(url)
+		var result Result
+		err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"})
+		if ec, ok := err.(Error); !ok || ec.ErrorCode() != (&apiKeyError{}).ErrorCode() {
+			t.Errorf("%s: unauthorized request not rejected: %v", url, err)
+		}
+		client.Close()
+	}
+	// Requests with a valid key must be served until the quota is used up
+	client, _ := DialHTTP(hs.URL + "/?apikey=alice")
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("authorized request failed: %v", err)
+	}
+	err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"})
+	if ec, ok := err.(Error); !ok || ec.ErrorCode() != (&quotaExceededError{}).ErrorCode() {
+		t.Fatalf("request over quota not rejected: %v", err)
+	}
+	var report UsageReport
+	if err := client.Call(&report, "rpc_usage"); err != nil {
+		t.Fatalf("failed to query usage: %v", err)
+	}
+	if report.Key != "alice" || report.Usage["service_echo"] != 2 || report.Quotas["service_echo"] != 1 {
+		t.Errorf("usage report mismatch: have %+v", report)
+	}
+}
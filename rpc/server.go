@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -26,6 +27,7 @@ import (
 	"sync/atomic"
 
 	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/tracing"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -73,6 +75,27 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
+// Usage returns the consumption of the API key the request was made with in
+// the current quota period.
+func (s *RPCService) Usage(ctx context.Context) (*UsageReport, error) {
+	if s.server.metering == nil {
+		return nil, errors.New("usage metering disabled")
+	}
+	return s.server.metering.Report(apiKeyFromContext(ctx))
+}
+
+// SetMetering enables the per API key request metering of the server. It must
+// be called before the server starts serving requests.
+func (s *Server) SetMetering(metering *Metering) {
+	s.metering = metering
+}
+
+// SetResponseCache enables caching the responses of calls about immutable data.
+// It must be called before the server starts serving requests.
+func (s *Server) SetResponseCache(cache *ResponseCache) {
+	s.cache = cache
+}
+
 // RegisterName will create a service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
@@ -148,7 +171,7 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 	// to send notification to clients. It is tied to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
 	if options&OptionSubscriptions == OptionSubscriptions {
-		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
+		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec, s.wsLimits))
 	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
@@ -167,7 +190,9 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 				log.Debug(fmt.Sprintf("read error %v\n", err))
 				codec.Write(codec.CreateErrorResponse(nil, err))
 			}
-			// Error or end of stream, wait for requests and tear down
+			// Error or end of stream, nobody is left to read the responses.
+			// Cancel the pending requests and wait for them to tear down.
+			cancel()
 			pend.Wait()
 			return nil
 		}
@@ -277,7 +302,18 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	// Count the call against the API key, rejecting it if out of quota
+	if s.metering != nil {
+		method := req.svcname + serviceMethodSeparator + formatName(req.callb.method.Name)
+		if err := s.metering.admit(apiKeyFromContext(ctx), method); err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	}
+
 	if req.callb.isSubscribe {
+		if notifier, ok := NotifierFromContext(ctx); ok && !notifier.canSubscribe() {
+			return codec.CreateErrorResponse(&req.id, &subscriptionLimitError{notifier.maxSubs}), nil
+		}
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
 			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
@@ -300,6 +336,21 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// Serve calls about immutable data from the cache if possible
+	var cacheKey string
+	if s.cache != nil {
+		key, ok := s.cache.key(req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name), req.args)
+		if ok {
+			if result, hit := s.cache.get(key); hit {
+				return codec.CreateResponse(req.id, result), nil
+			}
+			cacheKey = key
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, "rpc."+req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name))
+	defer span.End()
+
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -325,9 +376,21 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			span.SetError(e)
 			//fmt.Println("--------------reply",e.Error())
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			var rpcErr Error = &callbackError{e.Error()}
+			if ec, ok := e.(Error); ok {
+				rpcErr = ec
+			}
+			if de, ok := e.(DataError); ok {
+				return codec.CreateErrorResponseWithInfo(&req.id, rpcErr, de.ErrorData()), nil
+			}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
+		}
+	}
+	if cacheKey != "" {
+		if result := s.cache.add(cacheKey, reply[0].Interface()); result != nil {
+			return codec.CreateResponse(req.id, result), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
@@ -160,3 +160,35 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+// Tests that requests still executing when the client disconnects are canceled
+// instead of being run to completion.
+func TestServerCancelOnDisconnect(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	clientConn, serverConn := net.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+		close(done)
+	}()
+	request := map[string]interface{}{
+		"id":      1,
+		"method":  "test_sleep",
+		"version": "2.0",
+		"params":  []interface{}{time.Minute},
+	}
+	if err := json.NewEncoder(clientConn).Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending request not canceled after disconnect")
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"sync"
+
+	"github.com/abeychain/go-abey/log"
 )
 
 var (
@@ -27,6 +29,8 @@ var (
 	ErrNotificationsUnsupported = errors.New("notifications not supported")
 	// ErrNotificationNotFound is returned when the notification for the given id is not found
 	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrNotificationQueueFull is returned when a client can't keep up with its notifications
+	ErrNotificationQueueFull = errors.New("notification queue full")
 )
 
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
@@ -55,16 +59,26 @@ type Notifier struct {
 	subMu    sync.RWMutex // guards active and inactive maps
 	active   map[ID]*Subscription
 	inactive map[ID]*Subscription
+
+	maxSubs int              // Maximum number of subscriptions (0 = unlimited)
+	queue   chan interface{} // Notifications waiting to be sent, nil if sent synchronously
 }
 
 // newNotifier creates a new notifier that can be used to send subscription
-// notifications to the client.
-func newNotifier(codec ServerCodec) *Notifier {
-	return &Notifier{
+// notifications to the client. If the connection has a send queue, the client
+// is evicted once it falls so far behind that the queue is full.
+func newNotifier(codec ServerCodec, limits WebsocketLimits) *Notifier {
+	n := &Notifier{
 		codec:    codec,
 		active:   make(map[ID]*Subscription),
 		inactive: make(map[ID]*Subscription),
+		maxSubs:  limits.MaxSubscriptions,
 	}
+	if limits.SendQueue > 0 {
+		n.queue = make(chan interface{}, limits.SendQueue)
+		go n.sendLoop()
+	}
+	return n
 }
 
 // NotifierFromContext returns the Notifier value stored in ctx, if any.
@@ -94,14 +108,53 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	sub, active := n.active[id]
 	if active {
 		notification := n.codec.CreateNotification(string(id), sub.namespace, data)
-		if err := n.codec.Write(notification); err != nil {
+		if n.queue == nil {
+			if err := n.codec.Write(notification); err != nil {
+				n.codec.Close()
+				return err
+			}
+			return nil
+		}
+		select {
+		case n.queue <- notification:
+		default:
+			// The client can't keep up, drop it instead of stalling the sender
+			log.Warn("Evicting slow RPC client", "queued", cap(n.queue), "subscriptions", len(n.active))
+			wsEvictionMeter.Mark(1)
 			n.codec.Close()
-			return err
+			return ErrNotificationQueueFull
 		}
 	}
 	return nil
 }
 
+// sendLoop writes the queued notifications to the client until the connection
+// is closed.
+func (n *Notifier) sendLoop() {
+	for {
+		select {
+		case notification := <-n.queue:
+			if err := n.codec.Write(notification); err != nil {
+				n.codec.Close()
+				return
+			}
+		case <-n.codec.Closed():
+			return
+		}
+	}
+}
+
+// canSubscribe reports whether the client may create another subscription.
+func (n *Notifier) canSubscribe() bool {
+	if n.maxSubs <= 0 {
+		return true
+	}
+	n.subMu.RLock()
+	defer n.subMu.RUnlock()
+
+	return len(n.active)+len(n.inactive) < n.maxSubs
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
@@ -328,3 +328,51 @@ func TestSubscriptionMultipleNamespaces(t *testing.T) {
 		}
 	}
 }
+
+// Tests that clients are refused further subscriptions once they hold the
+// maximum allowed number of them.
+func TestSubscriptionLimit(t *testing.T) {
+	server := newTestServer("abey", new(NotificationTestService))
+	server.SetWebsocketLimits(WebsocketLimits{MaxSubscriptions: 1})
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "abey", ch, "someSubscription", 0, 1)
+	if err != nil {
+		t.Fatalf("first subscription failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := client.Subscribe(context.Background(), "abey", ch, "someSubscription", 0, 1); err == nil {
+		t.Fatal("subscription beyond the limit accepted")
+	}
+}
+
+// Tests that clients unable to keep up with their notifications are evicted
+// once their send queue is full, without blocking the sender.
+func TestSlowClientEviction(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	codec := NewJSONCodec(serverConn)
+	notifier := newNotifier(codec, WebsocketLimits{SendQueue: 2})
+	sub := notifier.CreateSubscription()
+	notifier.activate(sub.ID, "abey")
+
+	// Nobody reads the client side, so the queue must fill up and evict the client
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = notifier.Notify(sub.ID, i)
+	}
+	if err != ErrNotificationQueueFull {
+		t.Fatalf("slow client not evicted: %v", err)
+	}
+	select {
+	case <-notifier.Closed():
+	case <-time.After(time.Second):
+		t.Fatal("connection of evicted client not closed")
+	}
+}
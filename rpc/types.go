@@ -78,6 +78,12 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	metering *Metering      // Per API key request metering, nil if disabled
+	cache    *ResponseCache // Cache of the responses about immutable data, nil if disabled
+
+	wsLimits WebsocketLimits // Resource limits of the websocket clients
+	wsConns  int32           // Number of open websocket connections (atomic)
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -96,6 +102,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError wraps RPC errors, which carry machine readable data in addition to
+// the message.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
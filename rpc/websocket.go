@@ -27,13 +27,35 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
 	"golang.org/x/net/websocket"
 	"gopkg.in/fatih/set.v0"
 )
 
+var (
+	wsConnectionGauge = metrics.NewRegisteredGauge("rpc/ws/connections", nil)
+	wsRejectionMeter  = metrics.NewRegisteredMeter("rpc/ws/rejected", nil)
+	wsEvictionMeter   = metrics.NewRegisteredMeter("rpc/ws/evicted", nil)
+)
+
+// WebsocketLimits bounds the resources the websocket clients may consume, zero
+// values meaning no limit.
+type WebsocketLimits struct {
+	MaxConnections   int // Maximum number of concurrently open connections
+	MaxSubscriptions int // Maximum number of subscriptions per connection
+	SendQueue        int // Notifications queued per connection before the client is evicted
+}
+
+// SetWebsocketLimits bounds the resources of the websocket clients. It must be
+// called before the server starts serving requests.
+func (srv *Server) SetWebsocketLimits(limits WebsocketLimits) {
+	srv.wsLimits = limits
+}
+
 // websocketJSONCodec is a custom JSON codec with payload size enforcement and
 // special number parsing.
 var websocketJSONCodec = websocket.Codec{
@@ -59,6 +81,20 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
+			// Refuse the connection if too many clients are connected already
+			conns := atomic.AddInt32(&srv.wsConns, 1)
+			defer func() { wsConnectionGauge.Update(int64(atomic.AddInt32(&srv.wsConns, -1))) }()
+
+			if limit := srv.wsLimits.MaxConnections; limit > 0 && int(conns) > limit {
+				log.Debug("Rejecting websocket connection", "remote", conn.Request().RemoteAddr, "limit", limit)
+				wsRejectionMeter.Mark(1)
+				err := &connectionLimitError{limit}
+				websocketJSONCodec.Send(conn, &jsonErrResponse{Version: jsonrpcVersion, Error: jsonError{Code: err.ErrorCode(), Message: err.Error()}})
+				conn.Close()
+				return
+			}
+			wsConnectionGauge.Update(int64(conns))
+
 			// Create a custom encode/decode pair to enforce payload size and number encoding
 			conn.MaxPayloadBytes = maxRequestContentLength
 
@@ -0,0 +1,54 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Tests that websocket connections beyond the limit are refused while the
+// admitted ones keep being served.
+func TestWebsocketConnectionLimit(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.SetWebsocketLimits(WebsocketLimits{MaxConnections: 1})
+	defer server.Stop()
+
+	client, hs := httpTestClient(server, "ws", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("admitted client failed: %v", err)
+	}
+	refused, err := DialWebsocket(context.Background(), "ws://"+hs.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("failed to dial second client: %v", err)
+	}
+	defer refused.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := refused.CallContext(ctx, &result, "service_echo", "hello", 10, &Args{"world"}); err == nil {
+		t.Fatal("client beyond the connection limit served")
+	}
+	if err := client.Call(&result, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("admitted client failed after refusal: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/abeystats"
+	"golang.org/x/net/websocket"
+)
+
+// httpEmitter posts signed reports as JSON documents to an HTTP endpoint.
+type httpEmitter struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPEmitter(url string) *httpEmitter {
+	return &httpEmitter{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (e *httpEmitter) emit(signed *SignedReport, report *Report) error {
+	blob, err := json.Marshal(signed)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *httpEmitter) close() {}
+
+// ethstatsEmitter speaks the ethstats websocket protocol, so the reports can
+// be displayed by unmodified ethstats dashboards. The signed report travels
+// along in the stats message, which dashboards unaware of it ignore.
+type ethstatsEmitter struct {
+	name string // Name of the node to display, defaults to its short id
+	pass string // Password to authorize access to the dashboard
+	host string // Remote address of the dashboard
+
+	lock sync.Mutex
+	conn *websocket.Conn
+}
+
+func newEthstatsEmitter(url string) (*ethstatsEmitter, error) {
+	name, pass, host, err := abeystats.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &ethstatsEmitter{name: name, pass: pass, host: host}, nil
+}
+
+func (e *ethstatsEmitter) emit(signed *SignedReport, report *Report) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	name := e.name
+	if name == "" {
+		name = shortID(report.ID)
+	}
+	// Reconnect lazily on the next report if the connection broke
+	if e.conn == nil {
+		conn, err := abeystats.Connect(e.host, name, e.pass, abeystats.NodeInfo{
+			Name:     name,
+			Node:     report.Version,
+			Network:  fmt.Sprintf("%d", report.Network),
+			Protocol: report.Protocol,
+			API:      "No",
+			Os:       report.OS,
+			Client:   "0.1.1",
+		})
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+		go e.drain(conn)
+	}
+	msgs := []map[string][]interface{}{
+		{"emit": {"block", map[string]interface{}{
+			"id":    name,
+			"block": map[string]interface{}{"number": report.FastHeight},
+		}}},
+		{"emit": {"stats", map[string]interface{}{
+			"id": name,
+			"stats": map[string]interface{}{
+				"active":  true,
+				"syncing": report.Syncing,
+				"peers":   report.Peers,
+				"uptime":  100,
+			},
+			"report": signed,
+		}}},
+	}
+	for _, msg := range msgs {
+		if err := websocket.JSON.Send(e.conn, msg); err != nil {
+			e.conn.Close()
+			e.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// drain discards the requests of the dashboard until the connection breaks,
+// telemetry only pushes its periodic reports.
+func (e *ethstatsEmitter) drain(conn *websocket.Conn) {
+	for {
+		var msg json.RawMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			return
+		}
+	}
+}
+
+func (e *ethstatsEmitter) close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
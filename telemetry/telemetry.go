@@ -0,0 +1,314 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package telemetry implements an opt-in service periodically reporting
+// anonymized node health statistics signed by a key derived from the node key.
+package telemetry
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/abeychain/go-abey/abey"
+	"github.com/abeychain/go-abey/common/hexutil"
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/les"
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/p2p"
+	"github.com/abeychain/go-abey/p2p/enode"
+	"github.com/abeychain/go-abey/params"
+	"github.com/abeychain/go-abey/rpc"
+)
+
+const (
+	// FormatJSON posts signed reports as JSON documents over HTTP.
+	FormatJSON = "json"
+
+	// FormatEthstats streams reports to an ethstats compatible server.
+	FormatEthstats = "ethstats"
+
+	// minInterval is the shortest accepted reporting interval.
+	minInterval = 10 * time.Second
+
+	// SaltFile is the name of the datadir file holding the per-install salt
+	// the report key is derived with.
+	SaltFile = "telemetry-salt"
+)
+
+var (
+	errInvalidSignature = errors.New("invalid report signature")
+	errInvalidSalt      = errors.New("invalid telemetry salt")
+)
+
+// Config contains the settings of the telemetry service.
+type Config struct {
+	Endpoint string        `toml:",omitempty"` // Reporting endpoint, empty disables telemetry
+	Format   string        `toml:",omitempty"` // Wire format of the endpoint
+	Interval time.Duration `toml:",omitempty"` // Time between two reports
+}
+
+// DefaultConfig contains the default telemetry settings.
+var DefaultConfig = Config{
+	Format:   FormatJSON,
+	Interval: time.Minute,
+}
+
+// Report is the anonymized snapshot of the node health. It deliberately holds
+// no names, addresses or accounts, the node is only identified by the id of its
+// report key. That key is derived from the node key and a per-install salt, so
+// reports can't be linked to the identity of the node on the p2p network.
+type Report struct {
+	ID          string `json:"id"`
+	Version     string `json:"version"`
+	OS          string `json:"os"`
+	Network     uint64 `json:"network"`
+	Protocol    string `json:"protocol"`
+	FastHeight  uint64 `json:"fastHeight"`
+	SnailHeight uint64 `json:"snailHeight"`
+	Peers       int    `json:"peers"`
+	Syncing     bool   `json:"syncing"`
+	Time        int64  `json:"time"`
+}
+
+// SignedReport is a report together with the signature of the report key over
+// the Keccak256 hash of the exact report bytes.
+type SignedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Signature hexutil.Bytes   `json:"signature"`
+}
+
+// signReport encodes and signs a report with the report key.
+func signReport(report *Report, key *ecdsa.PrivateKey) (*SignedReport, error) {
+	blob, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(blob), key)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedReport{Report: blob, Signature: sig}, nil
+}
+
+// VerifyReport checks that a signed report was produced by the report key it
+// claims to originate from and returns the decoded report.
+func VerifyReport(signed *SignedReport) (*Report, error) {
+	var report Report
+	if err := json.Unmarshal(signed.Report, &report); err != nil {
+		return nil, err
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(signed.Report), signed.Signature)
+	if err != nil {
+		return nil, errInvalidSignature
+	}
+	if enode.PubkeyToIDV4(pubkey).String() != report.ID {
+		return nil, errInvalidSignature
+	}
+	return &report, nil
+}
+
+// emitter delivers reports to a telemetry endpoint.
+type emitter interface {
+	emit(signed *SignedReport, report *Report) error
+	close()
+}
+
+// Service implements the telemetry reporting daemon.
+type Service struct {
+	config Config
+	server *p2p.Server     // Peer-to-peer server to retrieve networking infos
+	abey   *abey.Abeychain // Full Abeychain service if monitoring a full node
+	les    *les.LightAbey  // Light Abeychain service if monitoring a light node
+
+	salt    []byte            // Per-install salt of the report key
+	key     *ecdsa.PrivateKey // Report key derived from the node key
+	emitter emitter
+	quit    chan struct{}
+}
+
+// New returns a telemetry service ready for reporting. The report key salt is
+// loaded from saltPath, or only kept in memory if the path is empty.
+func New(config Config, saltPath string, abeyServ *abey.Abeychain, lesServ *les.LightAbey) (*Service, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("no telemetry endpoint configured")
+	}
+	if config.Interval < minInterval {
+		log.Warn("Sanitizing telemetry interval", "provided", config.Interval, "updated", minInterval)
+		config.Interval = minInterval
+	}
+	var em emitter
+	switch config.Format {
+	case FormatJSON, "":
+		em = newHTTPEmitter(config.Endpoint)
+	case FormatEthstats:
+		e, err := newEthstatsEmitter(config.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		em = e
+	default:
+		return nil, fmt.Errorf("unknown telemetry format %q", config.Format)
+	}
+	salt, err := loadSalt(saltPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		config:  config,
+		salt:    salt,
+		abey:    abeyServ,
+		les:     lesServ,
+		emitter: em,
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the telemetry service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// telemetry service (nil as it doesn't provide any user callable APIs).
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start implements node.Service, starting up the reporting daemon.
+func (s *Service) Start(server *p2p.Server) error {
+	key, err := reportKey(server.PrivateKey, s.salt)
+	if err != nil {
+		return err
+	}
+	s.server, s.key = server, key
+	go s.loop()
+
+	log.Info("Telemetry reporting started", "endpoint", s.config.Endpoint, "format", s.config.Format, "interval", s.config.Interval)
+	return nil
+}
+
+// Stop implements node.Service, terminating the reporting daemon.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.emitter.close()
+	log.Info("Telemetry reporting stopped")
+	return nil
+}
+
+// loop sends a report every interval until termination.
+func (s *Service) loop() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.send(); err != nil {
+			log.Debug("Telemetry report failed", "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// send assembles, signs and emits a single report.
+func (s *Service) send() error {
+	report := s.assemble()
+	signed, err := signReport(report, s.key)
+	if err != nil {
+		return err
+	}
+	return s.emitter.emit(signed, report)
+}
+
+// assemble collects the current node statistics.
+func (s *Service) assemble() *Report {
+	report := &Report{
+		ID:      enode.PubkeyToIDV4(&s.key.PublicKey).String(),
+		Version: params.VersionWithMeta,
+		OS:      runtime.GOOS + "/" + runtime.GOARCH,
+		Peers:   s.server.PeerCount(),
+		Time:    time.Now().Unix(),
+	}
+	if s.abey != nil {
+		report.Network = s.abey.NetVersion()
+		report.Protocol = fmt.Sprintf("abey/%d", abey.ProtocolVersions[0])
+		report.FastHeight = s.abey.BlockChain().CurrentBlock().NumberU64()
+		report.SnailHeight = s.abey.SnailBlockChain().CurrentBlock().NumberU64()
+
+		progress := s.abey.Downloader().Progress()
+		report.Syncing = report.FastHeight < progress.HighestFastBlock
+	} else {
+		if info, ok := s.server.NodeInfo().Protocols["les"].(*les.NodeInfo); ok {
+			report.Network = info.Network
+		}
+		report.Protocol = fmt.Sprintf("les/%d", les.ClientProtocolVersions[0])
+		report.FastHeight = s.les.BlockChain().CurrentHeader().Number.Uint64()
+
+		progress := s.les.Downloader().Progress()
+		report.Syncing = report.FastHeight < progress.HighestFastBlock
+	}
+	return report
+}
+
+// shortID returns the abbreviated node id used as display name on dashboards.
+func shortID(id string) string {
+	return "node-" + strings.TrimPrefix(id, "0x")[:16]
+}
+
+// loadSalt reads the salt of the report key from path, generating and storing
+// it on first use.
+func loadSalt(path string) ([]byte, error) {
+	if path != "" {
+		blob, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			salt, err := hex.DecodeString(strings.TrimSpace(string(blob)))
+			if err != nil || len(salt) != 32 {
+				return nil, errInvalidSalt
+			}
+			return salt, nil
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(salt)), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return salt, nil
+}
+
+// reportKey derives the key signing the reports from the node key and salt.
+func reportKey(nodeKey *ecdsa.PrivateKey, salt []byte) (*ecdsa.PrivateKey, error) {
+	return crypto.ToECDSA(crypto.Keccak256(salt, crypto.FromECDSA(nodeKey)))
+}
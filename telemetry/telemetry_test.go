@@ -0,0 +1,120 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abeychain/go-abey/crypto"
+	"github.com/abeychain/go-abey/p2p/enode"
+)
+
+func TestSignedReport(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	report := &Report{
+		ID:          enode.PubkeyToIDV4(&key.PublicKey).String(),
+		Network:     179,
+		FastHeight:  100,
+		SnailHeight: 10,
+		Peers:       5,
+	}
+	signed, err := signReport(report, key)
+	if err != nil {
+		t.Fatalf("failed to sign report: %v", err)
+	}
+	// Deliver the report through the HTTP emitter and verify it on the other side
+	received := make(chan *SignedReport, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signed SignedReport
+		if err := json.NewDecoder(r.Body).Decode(&signed); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received <- &signed
+	}))
+	defer server.Close()
+
+	if err := newHTTPEmitter(server.URL).emit(signed, report); err != nil {
+		t.Fatalf("failed to emit report: %v", err)
+	}
+	got, err := VerifyReport(<-received)
+	if err != nil {
+		t.Fatalf("failed to verify report: %v", err)
+	}
+	if *got != *report {
+		t.Fatalf("report mismatch: have %+v, want %+v", got, report)
+	}
+	// Reports claiming another node id must be rejected
+	other, _ := crypto.GenerateKey()
+	report.ID = enode.PubkeyToIDV4(&other.PublicKey).String()
+	if forged, _ := signReport(report, key); forged != nil {
+		if _, err := VerifyReport(forged); err != errInvalidSignature {
+			t.Fatalf("forged report error mismatch: have %v, want %v", err, errInvalidSignature)
+		}
+	}
+	// Tampered reports must be rejected
+	signed.Report = []byte(`{"id":"` + report.ID + `","peers":50}`)
+	if _, err := VerifyReport(signed); err != errInvalidSignature {
+		t.Fatalf("tampered report error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+}
+
+// Tests that the report key is stable for an install but can't be linked to the
+// node key, and that the salt survives restarts.
+func TestReportKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telemetry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gabey", SaltFile)
+
+	salt, err := loadSalt(path)
+	if err != nil {
+		t.Fatalf("failed to create salt: %v", err)
+	}
+	if reloaded, err := loadSalt(path); err != nil || !bytes.Equal(reloaded, salt) {
+		t.Fatalf("salt mismatch after reload: have %x (%v), want %x", reloaded, err, salt)
+	}
+	nodeKey, _ := crypto.GenerateKey()
+	key, err := reportKey(nodeKey, salt)
+	if err != nil {
+		t.Fatalf("failed to derive report key: %v", err)
+	}
+	if again, _ := reportKey(nodeKey, salt); again.D.Cmp(key.D) != 0 {
+		t.Errorf("report key not stable for the same salt")
+	}
+	if key.D.Cmp(nodeKey.D) == 0 {
+		t.Errorf("report key equals node key")
+	}
+	other, _ := loadSalt("")
+	if otherKey, _ := reportKey(nodeKey, other); otherKey.D.Cmp(key.D) == 0 {
+		t.Errorf("report key independent of the salt")
+	}
+	// Corrupted salts must be refused instead of silently replaced
+	ioutil.WriteFile(path, []byte("zz"), 0600)
+	if _, err := loadSalt(path); err != errInvalidSalt {
+		t.Errorf("corrupted salt error mismatch: have %v, want %v", err, errInvalidSalt)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/log"
+	"github.com/abeychain/go-abey/metrics"
+)
+
+const (
+	batchSize     = 512              // Number of spans to export in one request
+	queueLimit    = 8 * batchSize    // Number of spans buffered before dropping
+	flushInterval = 5 * time.Second  // Time between two exports
+	exportTimeout = 10 * time.Second // Time limit of a single export request
+
+	// instrumentation names the library recording the spans.
+	instrumentation = "github.com/abeychain/go-abey"
+)
+
+var droppedSpanMeter = metrics.NewRegisteredMeter("tracing/dropped", nil)
+
+// exporter batches completed spans and posts them to an OTLP/HTTP collector.
+type exporter struct {
+	url     string
+	service string
+	client  *http.Client
+
+	mu    sync.Mutex
+	queue []*Span
+	flush chan struct{}
+	quit  chan chan struct{}
+}
+
+var (
+	active   *exporter
+	activeMu sync.Mutex
+)
+
+// Setup enables tracing and starts exporting spans to the OTLP/HTTP collector
+// at endpoint, e.g. http://localhost:4318. Only the given ratio of new traces
+// is recorded.
+func Setup(endpoint string, ratio float64, service string) error {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return fmt.Errorf("invalid tracing endpoint %q, expected http(s) URL", endpoint)
+	}
+	if ratio <= 0 || ratio > 1 {
+		return fmt.Errorf("invalid tracing sample ratio %v, expected (0, 1]", ratio)
+	}
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	if active != nil {
+		return fmt.Errorf("tracing already enabled")
+	}
+	active = &exporter{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: exportTimeout},
+		flush:   make(chan struct{}, 1),
+		quit:    make(chan chan struct{}),
+	}
+	go active.loop()
+
+	sampleRatio, Enabled = ratio, true
+	log.Info("Enabled request tracing", "url", url, "ratio", ratio)
+	return nil
+}
+
+// Stop exports all pending spans and disables tracing.
+func Stop() {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	if active == nil {
+		return
+	}
+	Enabled = false
+
+	done := make(chan struct{})
+	active.quit <- done
+	<-done
+	active = nil
+}
+
+// export queues a completed span for the active exporter.
+func export(span *Span) {
+	activeMu.Lock()
+	e := active
+	activeMu.Unlock()
+
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) >= queueLimit {
+		droppedSpanMeter.Mark(1)
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= batchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) loop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.export()
+		case <-e.flush:
+			e.export()
+		case done := <-e.quit:
+			e.export()
+			close(done)
+			return
+		}
+	}
+}
+
+// export posts all queued spans to the collector in batches.
+func (e *exporter) export() {
+	for {
+		e.mu.Lock()
+		n := len(e.queue)
+		if n > batchSize {
+			n = batchSize
+		}
+		batch := e.queue[:n]
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			droppedSpanMeter.Mark(int64(len(batch)))
+			log.Debug("Failed to export trace spans", "spans", len(batch), "err", err)
+			return
+		}
+	}
+}
+
+func (e *exporter) post(batch []*Span) error {
+	body, err := json.Marshal(encodeSpans(e.service, batch))
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", res.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON encoding of the trace export request, see the opentelemetry-proto
+// trace and common definitions.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Links             []otlpLink     `json:"links,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpLink struct {
+		TraceID string `json:"traceId"`
+		SpanID  string `json:"spanId"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+func encodeSpans(service string, batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           s.ctx.TraceID.String(),
+			SpanID:            s.ctx.SpanID.String(),
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(s.attrs),
+		}
+		if s.parent != (SpanID{}) {
+			span.ParentSpanID = s.parent.String()
+		}
+		for _, l := range s.links {
+			span.Links = append(span.Links, otlpLink{TraceID: l.TraceID.String(), SpanID: l.SpanID.String()})
+		}
+		if s.err != nil {
+			span.Status = &otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+		}
+		s.mu.Unlock()
+
+		spans = append(spans, span)
+	}
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes([]Attribute{Attr("service.name", service)})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: instrumentation},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var value map[string]interface{}
+		switch v := a.Value.(type) {
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(int64(v), 10)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case uint64:
+			value = map[string]interface{}{"intValue": strconv.FormatUint(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case fmt.Stringer:
+			value = map[string]interface{}{"stringValue": v.String()}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: value})
+	}
+	return kvs
+}
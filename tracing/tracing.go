@@ -0,0 +1,273 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing implements lightweight request tracing. Spans recorded for
+// RPC handling, pool admission, block packing, pbft rounds and block import are
+// exported to an OpenTelemetry collector over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abeychain/go-abey/common"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	correlationLimit = 4096 // Number of transaction and block trace correlations retained
+	linkLimit        = 64   // Number of links recorded on a single span
+)
+
+// Enabled is checked before recording any span. It is switched on by Setup,
+// all tracing calls are cheap noops otherwise.
+var Enabled = false
+
+var (
+	correlations, _ = lru.New(correlationLimit) // Transaction or block hash -> SpanContext
+	sampleRatio     = 1.0
+)
+
+// TraceID identifies a trace spanning multiple components.
+type TraceID [16]byte
+
+// String returns the hex encoding of the trace id.
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// SpanID identifies a single span within a trace.
+type SpanID [8]byte
+
+// String returns the hex encoding of the span id.
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext is the correlation identity of a span, propagated between
+// components to join their spans into one trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Attribute is a key value pair annotating a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates a span attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a timed operation within a trace. All methods are safe to call on a
+// nil span, which is returned while tracing is disabled or not sampled.
+type Span struct {
+	name   string
+	ctx    SpanContext
+	parent SpanID
+	start  time.Time
+
+	mu    sync.Mutex
+	links []SpanContext
+	attrs []Attribute
+	err   error
+	end   time.Time
+}
+
+type spanKey struct{}
+
+// Start creates a span as child of the span carried by ctx, or as the root of a
+// new trace if there is none, and returns a context carrying the new span.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	if !Enabled {
+		return ctx, nil
+	}
+	parent := FromContext(ctx)
+	span := newSpan(name, parent, nil, attrs)
+	if span == nil {
+		return ctx, nil
+	}
+	return ContextWithSpan(ctx, span.ctx), span
+}
+
+// StartLinked creates the root span of a new trace linked to the given spans.
+// It is used for operations aggregating the work of many traces, like packing
+// transactions into a block.
+func StartLinked(name string, links []SpanContext, attrs ...Attribute) *Span {
+	if !Enabled {
+		return nil
+	}
+	return newSpan(name, SpanContext{}, links, attrs)
+}
+
+func newSpan(name string, parent SpanContext, links []SpanContext, attrs []Attribute) *Span {
+	span := &Span{
+		name:  name,
+		links: links,
+		attrs: attrs,
+		start: time.Now(),
+	}
+	if parent.IsValid() {
+		span.ctx.TraceID, span.parent = parent.TraceID, parent.SpanID
+	} else {
+		if !sampled() {
+			return nil
+		}
+		rand.Read(span.ctx.TraceID[:])
+	}
+	rand.Read(span.ctx.SpanID[:])
+	return span
+}
+
+// sampled decides whether a new trace is recorded.
+func sampled() bool {
+	if sampleRatio >= 1 {
+		return true
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	n := uint64(0)
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return float64(n>>11)/(1<<53) < sampleRatio
+}
+
+// Context returns the correlation identity of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// AddLink links the span to a span of another trace, links beyond linkLimit
+// are dropped.
+func (s *Span) AddLink(sc SpanContext) {
+	if s == nil || !sc.IsValid() {
+		return
+	}
+	s.mu.Lock()
+	if len(s.links) < linkLimit {
+		s.links = append(s.links, sc)
+	}
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed, nil errors are ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End completes the span and queues it for export.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	export(s)
+}
+
+// FromContext returns the span context carried by ctx.
+func FromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(spanKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithSpan returns a context carrying the given span context, so spans
+// started from it join its trace.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// Remember correlates a transaction or block hash with the trace it was
+// submitted or proposed in, so later asynchronous stages can join or link it.
+func Remember(hash common.Hash, sc SpanContext) {
+	if !Enabled || !sc.IsValid() {
+		return
+	}
+	correlations.Add(hash, sc)
+}
+
+// Recall returns the trace a transaction or block hash was correlated with.
+func Recall(hash common.Hash) (SpanContext, bool) {
+	if !Enabled {
+		return SpanContext{}, false
+	}
+	sc, ok := correlations.Get(hash)
+	if !ok {
+		return SpanContext{}, false
+	}
+	return sc.(SpanContext), true
+}
+
+// ParseTraceparent decodes a W3C trace context traceparent header, allowing
+// callers to join node side spans to their own traces.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.TraceID) {
+		return sc, false
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.SpanID) {
+		return sc, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	return sc, sc.IsValid()
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 The AbeyChain Authors
+// This file is part of the abey library.
+//
+// The abey library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The abey library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the abey library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/abeychain/go-abey/common"
+)
+
+func TestDisabledTracing(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil {
+		t.Fatalf("span recorded while disabled")
+	}
+	if FromContext(ctx).IsValid() {
+		t.Fatalf("span context propagated while disabled")
+	}
+	// Nil spans must be safe to use
+	span.SetAttributes(Attr("key", 1))
+	span.SetError(errors.New("failure"))
+	span.End()
+}
+
+func TestSpanExport(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []otlpRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected export path %q", r.URL.Path)
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode export: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	if err := Setup(server.URL, 1, "gabey"); err != nil {
+		t.Fatalf("failed to setup tracing: %v", err)
+	}
+	// Record a request trace submitting a transaction, then pack it
+	ctx, root := Start(context.Background(), "rpc.abey_sendRawTransaction")
+	_, child := Start(ctx, "txpool.add", Attr("nonce", uint64(1)))
+	child.SetError(errors.New("nonce too low"))
+	child.End()
+	root.End()
+
+	hash := common.HexToHash("0x01")
+	Remember(hash, child.Context())
+	link, ok := Recall(hash)
+	if !ok || link != child.Context() {
+		t.Fatalf("transaction correlation lost")
+	}
+	pack := StartLinked("pbft.fetchBlock", []SpanContext{link})
+	pack.End()
+	Stop()
+
+	var spans []otlpSpan
+	for _, req := range requests {
+		spans = append(spans, req.ResourceSpans[0].ScopeSpans[0].Spans...)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("exported span count mismatch: have %d, want 3", len(spans))
+	}
+	if spans[0].TraceID != spans[1].TraceID || spans[0].ParentSpanID != spans[1].SpanID {
+		t.Errorf("child span not joined to parent trace")
+	}
+	if spans[0].Status == nil || spans[0].Status.Code != otlpStatusError {
+		t.Errorf("span error not exported")
+	}
+	if spans[2].TraceID == spans[1].TraceID || len(spans[2].Links) != 1 || spans[2].Links[0].SpanID != spans[0].SpanID {
+		t.Errorf("packing span not linked to transaction trace")
+	}
+	if _, span := Start(context.Background(), "stopped"); span != nil {
+		t.Errorf("span recorded after stop")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false},
+		{"garbage", false},
+	}
+	for _, tt := range tests {
+		sc, ok := ParseTraceparent(tt.header)
+		if ok != tt.valid {
+			t.Errorf("%q: validity mismatch: have %v, want %v", tt.header, ok, tt.valid)
+		}
+		if ok && sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%q: trace id mismatch: have %v", tt.header, sc.TraceID)
+		}
+	}
+}
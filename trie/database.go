@@ -787,6 +787,14 @@ func (db *Database) uncache(hash common.Hash) {
 	db.dirtiesSize -= common.StorageSize(common.HashLength + int(node.size))
 }
 
+// ResetCleans drops all nodes held by the clean cache, releasing its memory.
+// Nodes are reloaded from disk on demand afterwards.
+func (db *Database) ResetCleans() {
+	if db.cleans != nil {
+		db.cleans.Reset()
+	}
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 func (db *Database) Size() (common.StorageSize, common.StorageSize) {